@@ -4,20 +4,26 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/handler/http/clientconfig"
 	"secureconnect-backend/internal/middleware"
+	"secureconnect-backend/pkg/config"
+	pkgDatabase "secureconnect-backend/pkg/database"
 	"secureconnect-backend/pkg/env"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/jwt"
 	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/shutdown"
 )
 
 func main() {
@@ -25,6 +31,19 @@ func main() {
 	logger.InitDefault("api-gateway")
 	defer logger.Sync()
 
+	// Load shared server settings (shutdown timeout)
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+	config.LogEffective(cfg)
+	jsonbind.SetOptions(jsonbind.Options{
+		MaxBodyBytes:          cfg.JSON.MaxBodyBytes,
+		MaxDepth:              cfg.JSON.MaxDepth,
+		MaxArrayLen:           cfg.JSON.MaxArrayLen,
+		DisallowUnknownFields: cfg.JSON.DisallowUnknownFields,
+	})
+
 	// 1. Connect to Redis (for rate limiting)
 	redisConfig := &database.RedisConfig{
 		Host:     env.GetString("REDIS_HOST", "localhost"),
@@ -35,40 +54,119 @@ func main() {
 		Timeout:  5 * time.Second,
 	}
 
-	redisDB, err := database.NewRedisDB(redisConfig)
+	// Dependencies are registered with the shutdown sequence as they are created
+	// so they are closed only after the server has drained
+	stopSeq := shutdown.NewSequence()
+	ctx := stopSeq.Background(context.Background())
+
+	redisDB, err := pkgDatabase.ConnectWithRetry(ctx, "Redis", pkgDatabase.RetryConfigFromEnv(), func(ctx context.Context) (*database.RedisClient, error) {
+		return database.ConnectRedisDB(ctx, redisConfig)
+	})
 	if err != nil {
-		logger.Fatal("Failed to connect to Redis")
+		logger.Fatal("Failed to connect to Redis", zap.Error(err))
 	}
-	defer redisDB.Close()
+	stopSeq.OnClose("redis", func() error {
+		redisDB.Close()
+		return nil
+	})
 
 	logger.Info("API Gateway connected to Redis")
 
 	// Start background Redis health check
-	go redisDB.StartHealthCheck(context.Background(), 10*time.Second)
+	go redisDB.StartHealthCheck(ctx, 10*time.Second)
 	logger.Info("Redis health check started (10s interval)")
 
 	// 2. Setup JWT Manager (for optional auth in gateway)
 	jwtSecret := env.GetString("JWT_SECRET", "")
-	if jwtSecret == "" {
-		logger.Fatal("JWT_SECRET environment variable is required")
+	if cfg.JWT.Algorithm == jwt.AlgorithmHS256 {
+		if jwtSecret == "" {
+			logger.Fatal("JWT_SECRET environment variable is required")
+		}
+		if len(jwtSecret) < 32 {
+			logger.Fatal("JWT_SECRET must be at least 32 characters")
+		}
 	}
-	if len(jwtSecret) < 32 {
-		logger.Fatal("JWT_SECRET must be at least 32 characters")
+	jwtManager, err := jwt.NewJWTManagerFromConfig(jwt.KeyConfig{
+		Algorithm:              cfg.JWT.Algorithm,
+		Secret:                 jwtSecret,
+		PrivateKeyFile:         cfg.JWT.PrivateKeyFile,
+		PublicKeyFile:          cfg.JWT.PublicKeyFile,
+		KeyID:                  cfg.JWT.KeyID,
+		PreviousSecrets:        cfg.JWT.PreviousSecrets,
+		PreviousPublicKeyFiles: cfg.JWT.PreviousPublicKeyFiles,
+	}, 15*time.Minute, 30*24*time.Hour)
+	if err != nil {
+		logger.Fatal("Failed to set up JWT manager", zap.Error(err))
 	}
-	jwtManager := jwt.NewJWTManager(jwtSecret, 15*time.Minute, 30*24*time.Hour)
 
 	// 3. Setup advanced rate limiter with per-endpoint configuration and degraded mode support
 	// DEGRADED MODE: Enable in-memory fallback when Redis is unavailable
+	// Signed-in users are limited per user across IPs, so shared NATs are not
+	// throttled together and one user cannot spread load over many addresses
 	rateLimiter := middleware.NewRateLimiterWithFallback(middleware.RateLimiterConfig{
 		RedisClient:            redisDB,
-		RequestsPerMin:         100,
-		Window:                 time.Minute,
+		RequestsPerMin:         env.GetInt("RATE_LIMIT_REQUESTS", 100),
+		UserRequestsPerMin:     env.GetInt("RATE_LIMIT_USER_REQUESTS", 100),
+		UserIdentity:           middleware.BearerTokenUser(jwtManager),
+		EndpointOverrides:      middleware.NewRateLimitConfigManager().EndpointOverrides(),
+		Window:                 time.Duration(env.GetInt("RATE_LIMIT_WINDOW", 60)) * time.Second,
 		EnableInMemoryFallback: true, // Enable in-memory rate limiting when Redis is degraded
 	})
 
+	// Internal callers and health checks are not counted against user rate limits
+	rateLimitBypass, err := middleware.NewRateLimitBypass(middleware.RateLimitBypassConfig{
+		CIDRs:  cfg.Gateway.RateLimitBypassCIDRs,
+		APIKey: cfg.Gateway.InternalAPIKey,
+	})
+	if err != nil {
+		logger.Fatal("Invalid rate limit bypass configuration", zap.Error(err))
+	}
+	rateLimiter.SetBypass(rateLimitBypass)
+
+	// Cap in-flight proxied requests per service so one slow service cannot starve the others
+	proxyLimiter := middleware.NewProxyConcurrencyLimiter(
+		env.GetInt("GATEWAY_PROXY_MAX_IN_FLIGHT", middleware.DefaultProxyMaxInFlight),
+		middleware.ParseProxyLimits(env.GetString("GATEWAY_PROXY_SERVICE_LIMITS", "")),
+	)
+	proxyLimiter.SetRetryAfter(env.GetDuration("GATEWAY_PROXY_RETRY_AFTER", time.Second))
+
+	// Retry GET/HEAD and configured idempotent routes when a service is briefly unreachable
+	proxyRetrier := middleware.NewProxyRetrier(middleware.ProxyRetryConfig{
+		MaxAttempts:      env.GetInt("GATEWAY_PROXY_RETRY_MAX_ATTEMPTS", middleware.DefaultProxyRetryMaxAttempts),
+		BaseDelay:        env.GetDuration("GATEWAY_PROXY_RETRY_BASE_DELAY", 50*time.Millisecond),
+		MaxDelay:         env.GetDuration("GATEWAY_PROXY_RETRY_MAX_DELAY", time.Second),
+		MaxBodyBytes:     int64(env.GetInt("GATEWAY_PROXY_RETRY_MAX_BODY_BYTES", middleware.DefaultProxyRetryMaxBodyBytes)),
+		DeadlineMargin:   env.GetDuration("GATEWAY_PROXY_RETRY_DEADLINE_MARGIN", 100*time.Millisecond),
+		IdempotentRoutes: middleware.ParseRouteList(env.GetString("GATEWAY_PROXY_RETRY_ROUTES", "")),
+	})
+
+	// One proxy per service over a shared, pooled transport
+	proxyRegistry := middleware.NewProxyRegistry(middleware.NewProxyTransport(middleware.ProxyTransportConfig{
+		MaxIdleConns:        env.GetInt("GATEWAY_PROXY_MAX_IDLE_CONNS", middleware.DefaultProxyMaxIdleConns),
+		MaxIdleConnsPerHost: env.GetInt("GATEWAY_PROXY_MAX_IDLE_CONNS_PER_HOST", middleware.DefaultProxyMaxIdleConnsPerHost),
+		IdleConnTimeout:     env.GetDuration("GATEWAY_PROXY_IDLE_CONN_TIMEOUT", middleware.DefaultProxyIdleConnTimeout),
+	}), proxyRetrier)
+	proxyToService := (&serviceProxies{
+		limiter:  proxyLimiter,
+		retrier:  proxyRetrier,
+		registry: proxyRegistry,
+	}).handler
+
 	// 4. Initialize Metrics
 	appMetrics := metrics.NewMetrics("api-gateway")
 	prometheusMiddleware := middleware.NewPrometheusMiddleware(appMetrics)
+	rateLimiter.SetMetrics(appMetrics)
+
+	// Shed part of the traffic under overload, keeping signed-in users and sign-in routes longest
+	loadShedder := middleware.NewLoadShedder(middleware.LoadShedConfig{
+		MaxInFlight:      env.GetInt("GATEWAY_LOAD_SHED_MAX_IN_FLIGHT", middleware.DefaultLoadShedMaxInFlight),
+		LatencyThreshold: env.GetDuration("GATEWAY_LOAD_SHED_LATENCY_THRESHOLD", 0),
+		PriorityHeadroom: middleware.DefaultLoadShedPriorityHeadroom,
+		RetryAfter:       env.GetDuration("GATEWAY_LOAD_SHED_RETRY_AFTER", 2*time.Second),
+		ExemptRoutes:     middleware.DefaultLoadShedExemptRoutes,
+		CriticalRoutes:   middleware.DefaultLoadShedCriticalRoutes,
+		UserIdentity:     middleware.BearerTokenUser(jwtManager),
+	})
 
 	// 5. Setup Gin router
 	router := gin.New() // Don't use Default() to have full control
@@ -94,11 +192,29 @@ func main() {
 
 	// 6. Apply global middleware
 	router.Use(middleware.Recovery())
-	router.Use(middleware.RequestLogger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestLogger(cfg.Log.AccessLevel))
 	router.Use(middleware.CORSMiddleware())
+	router.Use(loadShedder.Middleware())
 	router.Use(rateLimiter.Middleware())
 	router.Use(prometheusMiddleware.Handler())
 
+	// Time out requests that hang upstream; WebSocket and streaming routes are exempt
+	timeoutMiddleware := middleware.NewTimeoutMiddleware(&middleware.TimeoutConfig{
+		DefaultTimeout: env.GetDuration("GATEWAY_REQUEST_TIMEOUT", 30*time.Second),
+		ExemptRoutes: append(append([]string(nil), middleware.DefaultTimeoutExemptRoutes...),
+			middleware.ParseRouteList(env.GetString("GATEWAY_TIMEOUT_EXEMPT_ROUTES", ""))...),
+	})
+	router.Use(timeoutMiddleware.Middleware())
+
+	// Track in-flight requests so a timed-out shutdown can report them
+	inFlight := shutdown.NewInFlightTracker()
+	router.Use(inFlight.Middleware())
+
+	// Consolidated readiness of every downstream service for operators
+	systemStatus := middleware.NewSystemStatusChecker(systemStatusTargets(),
+		env.GetDuration("GATEWAY_SYSTEM_STATUS_TIMEOUT", middleware.DefaultSystemStatusTimeout))
+
 	// Revocation checker
 	revocationChecker := middleware.NewRedisRevocationChecker(redisDB.Client)
 
@@ -111,22 +227,52 @@ func main() {
 		})
 	})
 
+	// Liveness and readiness probes
+	health := middleware.NewHealthChecker("api-gateway", env.GetDuration("READINESS_TIMEOUT", middleware.DefaultReadinessTimeout))
+	health.AddCheck("redis", redisDB.HealthCheck)
+	health.Register(router)
+
 	// 7. Metrics endpoint (for Prometheus scraping - no auth required)
 	router.GET("/metrics", middleware.MetricsHandler(appMetrics))
 
+	// Token verification keys published by auth-service
+	router.GET("/.well-known/jwks.json", proxyToService("auth-service", 8080))
+
+	// SLO summary for dashboards, gated by the operator metrics token
+	sloAggregator := metrics.NewSLOAggregator(prometheus.DefaultGatherer, "api-gateway", map[string]metrics.DependencyGauge{
+		"redis": {Metric: "redis_degraded_mode", Inverted: true},
+	})
+	router.GET("/admin/slo", middleware.MetricsAuth(env.GetString("METRICS_AUTH_TOKEN", "")), middleware.SLOHandler(sloAggregator))
+
 	// 8. Swagger documentation
 	router.GET("/swagger", func(c *gin.Context) {
 		c.File("./api/swagger/openapi.yaml")
 	})
 
 	// 10. API version 1 routes
+	// Public runtime config for apps, served by the gateway itself
+	clientConfigHdlr, err := clientconfig.NewHandler(clientconfig.BuildDocument(cfg))
+	if err != nil {
+		logger.Fatal("Failed to build client config", zap.Error(err))
+	}
+
+	// Outdated apps are told to upgrade; exempt routes stay reachable
+	clientVersionGate, err := middleware.NewClientVersionGate(clientVersionConfig(cfg))
+	if err != nil {
+		logger.Fatal("Invalid minimum client version", zap.Error(err))
+	}
+
 	v1 := router.Group("/v1")
+	v1.Use(clientVersionGate.Middleware())
 	{
 		// Auth Service routes (public)
+		v1.GET("/config", clientConfigHdlr.GetConfig)
+
 		authGroup := v1.Group("/auth")
 		{
 			authGroup.POST("/register", proxyToService("auth-service", 8080))
 			authGroup.POST("/login", proxyToService("auth-service", 8080))
+			authGroup.POST("/login/totp", proxyToService("auth-service", 8080))
 			authGroup.POST("/refresh", proxyToService("auth-service", 8080))
 
 			// Protected auth routes
@@ -134,7 +280,13 @@ func main() {
 			authProtected.Use(middleware.AuthMiddleware(jwtManager, revocationChecker))
 			{
 				authProtected.POST("/logout", proxyToService("auth-service", 8080))
+				authProtected.POST("/logout-all", proxyToService("auth-service", 8080))
 				authProtected.GET("/profile", proxyToService("auth-service", 8080))
+				authProtected.GET("/sessions", proxyToService("auth-service", 8080))
+				authProtected.DELETE("/sessions/:id", proxyToService("auth-service", 8080))
+				authProtected.POST("/totp/enable", proxyToService("auth-service", 8080))
+				authProtected.POST("/totp/confirm", proxyToService("auth-service", 8080))
+				authProtected.POST("/totp/disable", proxyToService("auth-service", 8080))
 			}
 		}
 
@@ -170,12 +322,40 @@ func main() {
 			conversationsGroup.POST("", proxyToService("auth-service", 8080))
 			conversationsGroup.GET("", proxyToService("auth-service", 8080))
 			conversationsGroup.GET("/:id", proxyToService("auth-service", 8080))
+			conversationsGroup.GET("/:id/export", proxyToService("chat-service", 8082))
+			conversationsGroup.POST("/:id/read", proxyToService("chat-service", 8082))
+			conversationsGroup.POST("/:id/pins", proxyToService("chat-service", 8082))
+			conversationsGroup.GET("/:id/pins", proxyToService("chat-service", 8082))
+			conversationsGroup.DELETE("/:id/pins/:message_id", proxyToService("chat-service", 8082))
+			conversationsGroup.PUT("/:id/pins/order", proxyToService("chat-service", 8082))
+			conversationsGroup.POST("/read-all", proxyToService("chat-service", 8082))
+			conversationsGroup.GET("/unread", proxyToService("chat-service", 8082))
 			conversationsGroup.PATCH("/:id", proxyToService("auth-service", 8080))
 			conversationsGroup.DELETE("/:id", proxyToService("auth-service", 8080))
 			conversationsGroup.PUT("/:id/settings", proxyToService("auth-service", 8080))
 			conversationsGroup.POST("/:id/participants", proxyToService("auth-service", 8080))
 			conversationsGroup.GET("/:id/participants", proxyToService("auth-service", 8080))
 			conversationsGroup.DELETE("/:id/participants/:userId", proxyToService("auth-service", 8080))
+			conversationsGroup.POST("/:id/participants/:userId/mute", proxyToService("auth-service", 8080))
+			conversationsGroup.DELETE("/:id/participants/:userId/mute", proxyToService("auth-service", 8080))
+			conversationsGroup.PUT("/:id/call-mute", proxyToService("auth-service", 8080))
+			conversationsGroup.POST("/:id/bots", proxyToService("auth-service", 8080))
+			conversationsGroup.GET("/:id/bots", proxyToService("auth-service", 8080))
+			conversationsGroup.DELETE("/:id/bots/:botId", proxyToService("auth-service", 8080))
+		}
+
+		// Admin routes - require authentication, admin role is enforced by the service behind each route
+		adminGroup := v1.Group("/admin")
+		adminGroup.Use(middleware.AuthMiddleware(jwtManager, revocationChecker))
+		{
+			adminGroup.GET("/users/:email/lock", proxyToService("auth-service", 8080))
+			adminGroup.DELETE("/users/:email/lock", proxyToService("auth-service", 8080))
+			adminGroup.GET("/push-tokens/:userId", proxyToService("auth-service", 8080))
+			adminGroup.DELETE("/push-tokens/:userId", proxyToService("auth-service", 8080))
+			adminGroup.GET("/audit", proxyToService("auth-service", 8080))
+			adminGroup.POST("/storage/users/:user_id/recalculate-usage", proxyToService("storage-service", 8080))
+			// Answered by the gateway itself; the auth service confirms the caller is an admin
+			adminGroup.GET("/system/status", middleware.RequireAdminFrom(serviceURL("auth-service", 8080)+"/v1/admin/check", 0), systemStatus.Handler())
 		}
 
 		// Keys Service routes (E2EE) - all require authentication
@@ -193,8 +373,17 @@ func main() {
 		{
 			chatGroup.POST("", proxyToService("chat-service", 8082))
 			chatGroup.GET("", proxyToService("chat-service", 8082))
+			chatGroup.GET("/search", proxyToService("chat-service", 8082))
+			chatGroup.POST("/batch", proxyToService("chat-service", 8082))
+			chatGroup.PATCH("/:id", proxyToService("chat-service", 8082))
+			chatGroup.DELETE("/:id", proxyToService("chat-service", 8082))
+			chatGroup.POST("/:id/reactions", proxyToService("chat-service", 8082))
+			chatGroup.DELETE("/:id/reactions/:emoji", proxyToService("chat-service", 8082))
 		}
 
+		// Bot replies - authenticated by the chat service with the bot token
+		v1.POST("/bots/messages", proxyToService("chat-service", 8082))
+
 		// Presence endpoint - require authentication
 		presenceGroup := v1.Group("/presence")
 		presenceGroup.Use(middleware.AuthMiddleware(jwtManager, revocationChecker))
@@ -202,6 +391,9 @@ func main() {
 			presenceGroup.POST("", proxyToService("chat-service", 8082))
 		}
 
+		// Link previews - require authentication
+		v1.GET("/link-preview", middleware.AuthMiddleware(jwtManager, revocationChecker), proxyToService("chat-service", 8082))
+
 		// WebSocket chat - will be handled by chat service directly
 		v1.GET("/ws/chat", proxyToService("chat-service", 8082))
 
@@ -211,6 +403,7 @@ func main() {
 		{
 			callsGroup.POST("/initiate", proxyToService("video-service", 8083))
 			callsGroup.POST("/:id/end", proxyToService("video-service", 8083))
+			callsGroup.POST("/:id/reject", proxyToService("video-service", 8083))
 		}
 
 		// WebSocket signaling
@@ -221,6 +414,7 @@ func main() {
 		storageGroup.Use(middleware.AuthMiddleware(jwtManager, revocationChecker))
 		{
 			storageGroup.POST("/upload-url", proxyToService("storage-service", 8080))
+			storageGroup.POST("/upload-post", proxyToService("storage-service", 8080))
 			storageGroup.POST("/upload-complete", proxyToService("storage-service", 8080))
 			storageGroup.GET("/download-url/:file_id", proxyToService("storage-service", 8080))
 			storageGroup.DELETE("/files/:file_id", proxyToService("storage-service", 8080))
@@ -245,49 +439,97 @@ func main() {
 		zap.String("storage", "/v1/storage/*"),
 	)
 
-	if err := router.Run(addr); err != nil {
-		logger.Fatal("Failed to start API Gateway")
+	server := &http.Server{
+		Addr:    addr,
+		Handler: router,
 	}
-}
+	stopSeq.StopServer(server, cfg.Server.ShutdownTimeout, inFlight)
 
-// proxyToService creates a reverse proxy handler for a microservice
-func proxyToService(serviceName string, port int) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Build target URL
-		targetURL := fmt.Sprintf("http://%s:%d", getServiceHost(serviceName), port)
-
-		// Parse URL
-		remote, err := url.Parse(targetURL)
-		if err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Service unavailable"})
-			return
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start API Gateway", zap.Error(err))
 		}
-
-		// Create reverse proxy
-		proxy := httputil.NewSingleHostReverseProxy(remote)
-
-		// Modify request
-		proxy.Director = func(req *http.Request) {
-			req.Header = c.Request.Header
-			req.Host = remote.Host
-			req.URL.Scheme = remote.Scheme
-			req.URL.Host = remote.Host
-			req.URL.Path = c.Request.URL.Path
-			req.URL.RawQuery = c.Request.URL.RawQuery
+	}()
+
+	// SIGHUP rereads the config and applies a new minimum client version
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			reloaded, err := config.Load()
+			if err != nil {
+				logger.Error("Failed to reload config", zap.Error(err))
+				continue
+			}
+			if err := clientVersionGate.Update(clientVersionConfig(reloaded)); err != nil {
+				logger.Error("Keeping previous minimum client version", zap.Error(err))
+				continue
+			}
+			logger.Info("Minimum client version reloaded", zap.String("min_version", clientVersionGate.MinVersion()))
 		}
+	}()
 
-		// Handle errors - write directly to response writer
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			logger.Error("Proxy error",
-				zap.String("service", serviceName),
-				zap.Error(err),
-			)
-			w.WriteHeader(http.StatusBadGateway)
-			w.Write([]byte(`{"error":"Service unavailable","service":"` + serviceName + `"}`))
-		}
+	// 12. Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down API Gateway...", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+	if err := stopSeq.Run(); err != nil {
+		logger.Error("API Gateway shutdown finished with errors", zap.Error(err))
+	}
+
+	logger.Info("API Gateway exited")
+}
+
+// clientVersionConfig builds the minimum client version settings from cfg
+func clientVersionConfig(cfg *config.Config) middleware.ClientVersionConfig {
+	return middleware.ClientVersionConfig{
+		MinVersion:   cfg.Client.MinClientVersion,
+		UpgradeURL:   cfg.Client.UpgradeURL,
+		AllowMissing: cfg.Client.AllowMissingClientVersion,
+		ExemptRoutes: middleware.DefaultClientVersionExemptRoutes,
+	}
+}
+
+// serviceProxies builds the handlers that proxy routes to microservices
+type serviceProxies struct {
+	// limiter caps concurrent requests proxied to each service
+	limiter *middleware.ProxyConcurrencyLimiter
+	// retrier retries idempotent requests on transient upstream failures
+	retrier *middleware.ProxyRetrier
+	// registry holds one reverse proxy per service over a shared connection pool
+	registry *middleware.ProxyRegistry
+}
+
+// handler creates a reverse proxy handler for a microservice. The service's
+// proxy is built once and shared by all of its routes. Requests over the
+// service's concurrency limit get 503 without being proxied, and idempotent
+// requests are retried on transient upstream failures.
+func (p *serviceProxies) handler(serviceName string, port int) gin.HandlerFunc {
+	proxy, err := p.registry.Register(serviceName, serviceURL(serviceName, port))
+	if err != nil {
+		logger.Fatal("Failed to create service proxy", zap.String("service", serviceName), zap.Error(err))
+	}
+
+	return p.limiter.Wrap(serviceName, func(c *gin.Context) {
+		proxy.ServeHTTP(c.Writer, p.retrier.Prepare(c))
+	})
+}
+
+// serviceURL returns the base URL requests to a service are proxied to
+func serviceURL(serviceName string, port int) string {
+	return fmt.Sprintf("http://%s:%d", getServiceHost(serviceName), port)
+}
 
-		// Serve
-		proxy.ServeHTTP(c.Writer, c.Request)
+// systemStatusTargets lists the readiness endpoints behind GET
+// /v1/admin/system/status
+func systemStatusTargets() []middleware.StatusTarget {
+	return []middleware.StatusTarget{
+		{Service: "auth-service", URL: serviceURL("auth-service", 8080) + "/health/ready"},
+		{Service: "chat-service", URL: serviceURL("chat-service", 8082) + "/health/ready"},
+		{Service: "storage-service", URL: serviceURL("storage-service", 8080) + "/health/ready"},
+		{Service: "video-service", URL: serviceURL("video-service", 8083) + "/health/ready"},
 	}
 }
 
@@ -10,25 +10,38 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/database"
+	adminHandler "secureconnect-backend/internal/handler/http/admin"
 	authHandler "secureconnect-backend/internal/handler/http/auth"
 	"secureconnect-backend/internal/handler/http/conversation"
 	userHandler "secureconnect-backend/internal/handler/http/user"
 	"secureconnect-backend/internal/middleware"
 	"secureconnect-backend/internal/repository/cockroach"
 	"secureconnect-backend/internal/repository/redis"
+	adminService "secureconnect-backend/internal/service/admin"
 	authService "secureconnect-backend/internal/service/auth"
+	chatService "secureconnect-backend/internal/service/chat"
 	conversationService "secureconnect-backend/internal/service/conversation"
+	pollService "secureconnect-backend/internal/service/poll"
 	userService "secureconnect-backend/internal/service/user"
+	"secureconnect-backend/pkg/audit"
 	"secureconnect-backend/pkg/config"
-	"secureconnect-backend/pkg/constants"
 	pkgDatabase "secureconnect-backend/pkg/database"
 	"secureconnect-backend/pkg/email"
+	"secureconnect-backend/pkg/env"
+	"secureconnect-backend/pkg/geoip"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/jwt"
 	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/password"
+	"secureconnect-backend/pkg/shutdown"
+	"secureconnect-backend/pkg/tlsconfig"
+	"secureconnect-backend/pkg/urlguard"
+	"secureconnect-backend/pkg/workerpool"
 )
 
 func main() {
@@ -36,22 +49,34 @@ func main() {
 	logger.InitDefault("auth-service")
 	defer logger.Sync()
 
-	// Initialize context
-	ctx := context.Background()
+	// Dependencies are registered with the shutdown sequence as they are created
+	// so they are closed only after the server has drained. ctx is cancelled once
+	// the server has stopped, ending background goroutines.
+	stopSeq := shutdown.NewSequence()
+	ctx := stopSeq.Background(context.Background())
 
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		logger.Fatal("Failed to load config")
+		logger.Fatal("Failed to load config", zap.Error(err))
 	}
+	config.LogEffective(cfg)
+	jsonbind.SetOptions(jsonbind.Options{
+		MaxBodyBytes:          cfg.JSON.MaxBodyBytes,
+		MaxDepth:              cfg.JSON.MaxDepth,
+		MaxArrayLen:           cfg.JSON.MaxArrayLen,
+		DisallowUnknownFields: cfg.JSON.DisallowUnknownFields,
+	})
 
 	// Validate JWT secret in production
 	if cfg.Server.Environment == "production" {
-		if cfg.JWT.Secret == "" {
-			logger.Fatal("JWT_SECRET environment variable is required in production")
-		}
-		if len(cfg.JWT.Secret) < 32 {
-			logger.Fatal("JWT_SECRET must be at least 32 characters")
+		if cfg.JWT.Algorithm == jwt.AlgorithmHS256 {
+			if cfg.JWT.Secret == "" {
+				logger.Fatal("JWT_SECRET environment variable is required in production")
+			}
+			if len(cfg.JWT.Secret) < 32 {
+				logger.Fatal("JWT_SECRET must be at least 32 characters")
+			}
 		}
 		// Validate SMTP configuration in production
 		if cfg.SMTP.Username == "" || cfg.SMTP.Password == "" {
@@ -60,41 +85,61 @@ func main() {
 	}
 
 	// 1. Setup JWT Manager
-	jwtManager := jwt.NewJWTManager(
-		cfg.JWT.Secret,
-		cfg.JWT.AccessTokenExpiry,
-		cfg.JWT.RefreshTokenExpiry,
-	)
+	jwtManager, err := jwt.NewJWTManagerFromConfig(jwt.KeyConfig{
+		Algorithm:              cfg.JWT.Algorithm,
+		Secret:                 cfg.JWT.Secret,
+		PrivateKeyFile:         cfg.JWT.PrivateKeyFile,
+		PublicKeyFile:          cfg.JWT.PublicKeyFile,
+		KeyID:                  cfg.JWT.KeyID,
+		PreviousSecrets:        cfg.JWT.PreviousSecrets,
+		PreviousPublicKeyFiles: cfg.JWT.PreviousPublicKeyFiles,
+	}, cfg.JWT.AccessTokenExpiry, cfg.JWT.RefreshTokenExpiry)
+	if err != nil {
+		logger.Fatal("Failed to set up JWT manager", zap.Error(err))
+	}
+
+	// Dependencies may still be starting up, so retry connections with backoff
+	retryConfig := pkgDatabase.RetryConfigFromEnv()
 
 	// 2. Connect to CockroachDB
-	cockroachDB, err := pkgDatabase.NewCockroachDB(ctx, &pkgDatabase.CockroachConfig{
-		Host:     cfg.Database.Host,
-		Port:     cfg.Database.Port,
-		User:     cfg.Database.User,
-		Password: cfg.Database.Password,
-		Database: cfg.Database.Database,
-		SSLMode:  cfg.Database.SSLMode,
+	cockroachDB, err := pkgDatabase.ConnectWithRetry(ctx, "CockroachDB", retryConfig, func(ctx context.Context) (*pkgDatabase.CockroachDB, error) {
+		return pkgDatabase.NewCockroachDB(ctx, &pkgDatabase.CockroachConfig{
+			Host:     cfg.Database.Host,
+			Port:     cfg.Database.Port,
+			User:     cfg.Database.User,
+			Password: cfg.Database.Password,
+			Database: cfg.Database.Database,
+			SSLMode:  cfg.Database.SSLMode,
+		})
 	})
 	if err != nil {
-		logger.Fatal("Failed to connect to CockroachDB")
+		logger.Fatal("Failed to connect to CockroachDB", zap.Error(err))
 	}
-	defer cockroachDB.Close()
+	stopSeq.OnClose("cockroachdb", func() error {
+		cockroachDB.Close()
+		return nil
+	})
 
 	logger.Info("Connected to CockroachDB")
 
 	// 3. Connect to Redis with degraded mode support
-	redisDB, err := database.NewRedisDB(&database.RedisConfig{
-		Host:     cfg.Redis.Host,
-		Port:     cfg.Redis.Port,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-		PoolSize: cfg.Redis.PoolSize,
-		Timeout:  cfg.Redis.Timeout,
+	redisDB, err := pkgDatabase.ConnectWithRetry(ctx, "Redis", retryConfig, func(ctx context.Context) (*database.RedisClient, error) {
+		return database.ConnectRedisDB(ctx, &database.RedisConfig{
+			Host:     cfg.Redis.Host,
+			Port:     cfg.Redis.Port,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+			PoolSize: cfg.Redis.PoolSize,
+			Timeout:  cfg.Redis.Timeout,
+		})
 	})
 	if err != nil {
-		logger.Fatal("Failed to connect to Redis")
+		logger.Fatal("Failed to connect to Redis", zap.Error(err))
 	}
-	defer redisDB.Close()
+	stopSeq.OnClose("redis", func() error {
+		redisDB.Close()
+		return nil
+	})
 
 	logger.Info("Connected to Redis")
 
@@ -107,7 +152,12 @@ func main() {
 	blockedUserRepo := cockroach.NewBlockedUserRepository(cockroachDB.Pool)
 	emailVerificationRepo := cockroach.NewEmailVerificationRepository(cockroachDB.Pool)
 	conversationRepo := cockroach.NewConversationRepository(cockroachDB.Pool)
+	conversationRepo.SetDefaultE2EE(cfg.Conversation.E2EEDefault)
+	conversationRepo.SetConversationLimit(cfg.Conversation.MaxPerUser)
+	pollRepo := cockroach.NewPollRepository(cockroachDB.Pool)
+	adminRepo := cockroach.NewAdminRepository(cockroachDB.Pool)
 	directoryRepo := redis.NewDirectoryRepository(redisDB.Client)
+	directoryRepo.SetEmailNormalization(cfg.Auth.NormalizeGmailAliases)
 	sessionRepo := redis.NewSessionRepository(redisDB)
 	presenceRepo := redis.NewPresenceRepository(redisDB)
 
@@ -120,12 +170,17 @@ func main() {
 
 	if smtpConfigured {
 		// Use real SMTP sender
+		smtpTLS, err := tlsconfig.New(cfg.TLS.Policy())
+		if err != nil {
+			logger.Fatal("Invalid outbound TLS policy", zap.Error(err))
+		}
 		emailSender = email.NewSMTPSender(&email.SMTPConfig{
 			Host:     cfg.SMTP.Host,
 			Port:     cfg.SMTP.Port,
 			Username: cfg.SMTP.Username,
 			Password: cfg.SMTP.Password,
 			From:     cfg.SMTP.From,
+			TLS:      smtpTLS,
 		})
 		logger.Info("Using SMTP email provider")
 	} else {
@@ -137,13 +192,99 @@ func main() {
 		logger.Info("Using Mock email sender (development)")
 	}
 	emailSvc := email.NewService(emailSender)
+	emailSvc.SetAppURL(cfg.Server.AppURL)
+	emailSvc.SetURLAllowlist(urlguard.NewAllowlist(cfg.Server.TrustedURLHosts))
 
 	authSvc := authService.NewService(userRepo, directoryRepo, sessionRepo, presenceRepo, emailVerificationRepo, emailSvc, jwtManager)
+	authSvc.SetEmailDomainPolicy(cfg.Auth.AllowedEmailDomains, cfg.Auth.BlockDisposableEmails)
+	authSvc.SetEmailNormalization(cfg.Auth.NormalizeGmailAliases)
+	passwordHasher, err := password.NewHasher(cfg.Auth.PasswordAlgorithm, cfg.Auth.BcryptCost)
+	if err != nil {
+		logger.Fatal("Invalid password hashing config", zap.Error(err))
+	}
+	authSvc.SetPasswordHasher(passwordHasher)
+	passwordPolicy, err := authService.NewPasswordPolicy(cfg.Auth.PasswordMinCharClasses, cfg.Auth.PasswordDenylistFile)
+	if err != nil {
+		logger.Fatal("Invalid password policy config", zap.Error(err))
+	}
+	authSvc.SetPasswordPolicy(passwordPolicy)
+	authSvc.SetTOTP(cockroach.NewTOTPRepository(cockroachDB.Pool), redis.NewMFAChallengeRepository(redisDB), cfg.Auth.TOTPIssuer)
+	authSvc.SetSlidingSessions(authService.SlidingSessionConfig{
+		Window:           cfg.Auth.SessionWindow,
+		RememberMeWindow: cfg.Auth.RememberMeWindow,
+		AbsoluteMax:      cfg.Auth.SessionAbsoluteMax,
+	})
+	if cfg.Auth.LockoutEmailEnabled {
+		authSvc.SetLockoutNotification(emailSvc, sessionRepo)
+	}
+
+	// Security audit log of logins, logouts, registrations and password resets
+	auditLogger := audit.NewAuditLogger(redisDB.Client)
+	if cfg.Audit.Store == "cockroach" {
+		auditLogger.SetStore(cockroach.NewAuditRepository(cockroachDB.Pool))
+	}
+	if cfg.Audit.HashChain {
+		auditLogger.EnableHashChain([]byte(cfg.Audit.HMACKey))
+	}
+	geoResolver, err := geoip.Open(cfg.Audit.GeoIPCityDBPath, cfg.Audit.GeoIPASNDBPath)
+	if err != nil {
+		logger.Warn("GeoIP databases unavailable, audit events will not be geo-enriched", zap.Error(err))
+	} else {
+		auditLogger.SetGeoIP(geoip.NewCache(geoResolver, 0, 0))
+	}
+	authSvc.SetAuditLogger(auditLogger)
 
 	// Note: emailSvc now initialized above before authSvc
 
 	userSvc := userService.NewService(userRepo, blockedUserRepo, emailVerificationRepo, emailSvc)
-	conversationSvc := conversationService.NewService(conversationRepo, userRepo)
+	userSvc.SetEmailNormalization(cfg.Auth.NormalizeGmailAliases)
+	userSvc.SetPasswordHasher(passwordHasher)
+	userSvc.SetPasswordValidator(passwordPolicy)
+	userSvc.SetAuditLogger(auditLogger)
+	var conversationPublisher pollService.Publisher = &pollService.RedisAdapter{Client: redisDB.Client}
+	if cfg.Conversation.EventStreamEnabled {
+		// Chat hubs read conversation events from the stream; append poll and moderation events to it
+		conversationPublisher = redis.NewConversationEventStream(redisDB, int64(cfg.Conversation.EventStreamMaxLen), cfg.Conversation.EventStreamTTL)
+	}
+	pollSvc := pollService.NewService(pollRepo, conversationRepo, userRepo, conversationPublisher)
+	pollSvc.SetVoteLocker(redis.NewLockRepository(redisDB))
+	pollPublishPool := workerpool.New("poll_publish",
+		env.GetInt("POLL_PUBLISH_WORKERS", pollService.DefaultPublishWorkers),
+		env.GetInt("POLL_PUBLISH_QUEUE_SIZE", pollService.DefaultPublishQueueSize))
+	pollSvc.SetPublishPool(pollPublishPool)
+	// Closed before Redis, so events queued by the last requests still go out
+	stopSeq.OnClose("poll publish pool", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		return pollPublishPool.Shutdown(ctx)
+	})
+	conversationSvc := conversationService.NewService(conversationRepo, userRepo, pollSvc)
+	conversationSvc.SetPublisher(conversationPublisher)
+	conversationSvc.SetE2EEPolicy(conversationService.E2EEPolicy{
+		DefaultEnabled: cfg.Conversation.E2EEDefault,
+		AllowDowngrade: cfg.Conversation.AllowE2EEDowngrade,
+	})
+	conversationSvc.SetMembershipCache(redis.NewMembershipCache(redisDB), cfg.Conversation.MembershipCacheTTL)
+	pollSvc.SetMembershipChecker(conversationSvc)
+	if env.GetBool("MESSAGE_SEARCH_ENABLED", true) {
+		// Consumed by the chat service's search index sync, which runs under the same setting
+		conversationSvc.SetSearchIndexSyncer(redis.NewSearchIndexSyncQueue(redisDB))
+	}
+	conversationSvc.SetBotRepository(cockroach.NewBotRepository(cockroachDB.Pool))
+	if cfg.Conversation.InitialMessageEnabled {
+		// Initial messages go through the chat service like any other message
+		conversationSvc.SetMessageSender(conversationService.NewChatServiceSender(cfg.Conversation.ChatServiceURL, 0))
+	}
+	adminSvc := adminService.NewService(adminRepo)
+	adminSvc.SetPushTokenRepository(redis.NewPushTokenRepository(redisDB.Client))
+	adminSvc.SetAuditEventSearcher(auditLogger, auditLogger)
+	adminSvc.SetPublisher(&chatService.RedisAdapter{Client: redisDB.Client})
+
+	// Delete used and long-expired email tokens (one instance runs it under a Redis lock)
+	authService.NewTokenCleanup(emailVerificationRepo, redis.NewLockRepository(redisDB), authService.TokenCleanupConfig{
+		Interval:  cfg.Auth.TokenCleanupInterval,
+		Retention: cfg.Auth.TokenRetention,
+	}).Start(ctx)
 
 	// 6. Initialize Metrics
 	appMetrics := metrics.NewMetrics("auth-service")
@@ -153,6 +294,7 @@ func main() {
 	authHdlr := authHandler.NewHandler(authSvc)
 	userHdlr := userHandler.NewHandler(userSvc)
 	conversationHdlr := conversation.NewHandler(conversationSvc)
+	adminHdlr := adminHandler.NewHandler(adminSvc, authSvc)
 
 	// 8. Setup Gin Router
 	router := gin.New() // Don't use Default() to have full control
@@ -178,11 +320,16 @@ func main() {
 
 	// Apply middleware
 	router.Use(middleware.Recovery())
-	router.Use(middleware.RequestLogger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestLogger(cfg.Log.AccessLevel))
 	router.Use(middleware.SecurityHeaders())
 	router.Use(middleware.CORSMiddleware())
 	router.Use(prometheusMiddleware.Handler())
 
+	// Track in-flight requests so a timed-out shutdown can report them
+	inFlight := shutdown.NewInFlightTracker()
+	router.Use(inFlight.Middleware())
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -192,9 +339,24 @@ func main() {
 		})
 	})
 
+	// Liveness and readiness probes
+	health := middleware.NewHealthChecker("auth-service", env.GetDuration("READINESS_TIMEOUT", middleware.DefaultReadinessTimeout))
+	health.AddCheck("cockroach", cockroachDB.Ping)
+	health.AddCheck("redis", redisDB.HealthCheck)
+	health.Register(router)
+
 	// Metrics endpoint (for Prometheus scraping - no auth required)
 	router.GET("/metrics", middleware.MetricsHandler(appMetrics))
 
+	// Public token verification keys (empty unless JWT_ALGORITHM is RS256)
+	router.GET("/.well-known/jwks.json", authHandler.JWKS(jwtManager))
+
+	// SLO summary for dashboards, gated by the operator metrics token
+	sloAggregator := metrics.NewSLOAggregator(prometheus.DefaultGatherer, "auth-service", map[string]metrics.DependencyGauge{
+		"redis": {Metric: "redis_degraded_mode", Inverted: true},
+	})
+	router.GET("/admin/slo", middleware.MetricsAuth(env.GetString("METRICS_AUTH_TOKEN", "")), middleware.SLOHandler(sloAggregator))
+
 	// API version 1 routes
 	v1 := router.Group("/v1")
 	{
@@ -203,6 +365,7 @@ func main() {
 		{
 			auth.POST("/register", authHdlr.Register)
 			auth.POST("/login", authHdlr.Login)
+			auth.POST("/login/totp", authHdlr.VerifyTOTP)
 			auth.POST("/refresh", authHdlr.RefreshToken)
 			auth.POST("/password-reset/request", authHdlr.RequestPasswordReset)
 			auth.POST("/password-reset/confirm", authHdlr.ResetPassword)
@@ -212,7 +375,13 @@ func main() {
 			authenticated.Use(middleware.AuthMiddleware(jwtManager, authSvc))
 			{
 				authenticated.POST("/logout", authHdlr.Logout)
+				authenticated.POST("/logout-all", authHdlr.LogoutAll)
 				authenticated.GET("/profile", authHdlr.GetProfile)
+				authenticated.GET("/sessions", authHdlr.GetSessions)
+				authenticated.DELETE("/sessions/:id", authHdlr.RevokeSession)
+				authenticated.POST("/totp/enable", authHdlr.EnableTOTP)
+				authenticated.POST("/totp/confirm", authHdlr.ConfirmTOTP)
+				authenticated.POST("/totp/disable", authHdlr.DisableTOTP)
 			}
 		}
 
@@ -254,6 +423,25 @@ func main() {
 			conversations.POST("/:id/participants", conversationHdlr.AddParticipants)
 			conversations.GET("/:id/participants", conversationHdlr.GetParticipants)
 			conversations.DELETE("/:id/participants/:userId", conversationHdlr.RemoveParticipant)
+			conversations.POST("/:id/participants/:userId/mute", conversationHdlr.MuteParticipant)
+			conversations.DELETE("/:id/participants/:userId/mute", conversationHdlr.UnmuteParticipant)
+			conversations.PUT("/:id/call-mute", conversationHdlr.SetCallMute)
+			conversations.POST("/:id/bots", conversationHdlr.RegisterBot)
+			conversations.GET("/:id/bots", conversationHdlr.ListBots)
+			conversations.DELETE("/:id/bots/:botId", conversationHdlr.RemoveBot)
+		}
+
+		// Admin routes (require authentication and admin role)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(jwtManager, authSvc))
+		admin.Use(adminHdlr.RequireAdmin())
+		{
+			admin.GET("/users/:email/lock", adminHdlr.GetAccountLock)
+			admin.DELETE("/users/:email/lock", adminHdlr.ClearAccountLock)
+			admin.GET("/push-tokens/:userId", adminHdlr.ListPushTokens)
+			admin.DELETE("/push-tokens/:userId", adminHdlr.PurgePushTokens)
+			admin.GET("/audit", adminHdlr.SearchAuditEvents)
+			admin.GET("/check", adminHdlr.CheckAdmin)
 		}
 	}
 
@@ -263,6 +451,7 @@ func main() {
 		Addr:    addr,
 		Handler: router,
 	}
+	stopSeq.StopServer(server, cfg.Server.ShutdownTimeout, inFlight)
 
 	go func() {
 		logger.Info("Auth Service starting",
@@ -283,12 +472,9 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("Shutting down server...")
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.GracefulShutdownTimeout)
-	defer cancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Error("Server forced to shutdown")
+	logger.Info("Shutting down server...", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+	if err := stopSeq.Run(); err != nil {
+		logger.Error("Shutdown finished with errors", zap.Error(err))
 	}
 
 	logger.Info("Server exited")
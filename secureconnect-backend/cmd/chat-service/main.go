@@ -7,12 +7,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 
 	intDatabase "secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
 	chatHandler "secureconnect-backend/internal/handler/http/chat"
 	wsHandler "secureconnect-backend/internal/handler/ws"
 	"secureconnect-backend/internal/middleware"
@@ -20,37 +24,93 @@ import (
 	"secureconnect-backend/internal/repository/cockroach"
 	"secureconnect-backend/internal/repository/redis"
 	chatService "secureconnect-backend/internal/service/chat"
+	conversationService "secureconnect-backend/internal/service/conversation"
 	notificationService "secureconnect-backend/internal/service/notification"
+	"secureconnect-backend/pkg/config"
 	pkgDatabase "secureconnect-backend/pkg/database"
 	"secureconnect-backend/pkg/env"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/shutdown"
+	"secureconnect-backend/pkg/tlsconfig"
+	"secureconnect-backend/pkg/webhook"
+	"secureconnect-backend/pkg/workerpool"
 )
 
 func main() {
+	// Initialize structured logger used by repositories and database wrappers
+	logger.InitDefault("chat-service")
+	defer logger.Sync()
+
+	// Load shared server settings (shutdown timeout)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	config.LogEffective(cfg)
+	jsonbind.SetOptions(jsonbind.Options{
+		MaxBodyBytes:          cfg.JSON.MaxBodyBytes,
+		MaxDepth:              cfg.JSON.MaxDepth,
+		MaxArrayLen:           cfg.JSON.MaxArrayLen,
+		DisallowUnknownFields: cfg.JSON.DisallowUnknownFields,
+	})
+
 	// 1. Setup JWT Manager
 	jwtSecret := env.GetString("JWT_SECRET", "")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is required")
+	if cfg.JWT.Algorithm == jwt.AlgorithmHS256 {
+		if jwtSecret == "" {
+			log.Fatal("JWT_SECRET environment variable is required")
+		}
+		if len(jwtSecret) < 32 {
+			log.Fatal("JWT_SECRET must be at least 32 characters")
+		}
 	}
-	if len(jwtSecret) < 32 {
-		log.Fatal("JWT_SECRET must be at least 32 characters")
+
+	jwtManager, err := jwt.NewJWTManagerFromConfig(jwt.KeyConfig{
+		Algorithm:              cfg.JWT.Algorithm,
+		Secret:                 jwtSecret,
+		PrivateKeyFile:         cfg.JWT.PrivateKeyFile,
+		PublicKeyFile:          cfg.JWT.PublicKeyFile,
+		KeyID:                  cfg.JWT.KeyID,
+		PreviousSecrets:        cfg.JWT.PreviousSecrets,
+		PreviousPublicKeyFiles: cfg.JWT.PreviousPublicKeyFiles,
+	}, 15*time.Minute, 30*24*time.Hour)
+	if err != nil {
+		log.Fatalf("Failed to set up JWT manager: %v", err)
 	}
 
-	jwtManager := jwt.NewJWTManager(jwtSecret, 15*time.Minute, 30*24*time.Hour)
+	// Dependencies are registered with the shutdown sequence as they are created
+	// so they are closed only after the server has drained. ctx is cancelled once
+	// the server has stopped, ending health checks and the retention purge.
+	stopSeq := shutdown.NewSequence()
+	ctx := stopSeq.Background(context.Background())
+
+	// Dependencies may still be starting up, so retry connections with backoff
+	retryConfig := pkgDatabase.RetryConfigFromEnv()
 
 	// 2. Connect to Cassandra
-	cassandraDB, err := intDatabase.NewCassandraDB(
-		[]string{env.GetString("CASSANDRA_HOST", "localhost")},
-		"secureconnect_ks",
-	)
+	cassandraDB, err := pkgDatabase.ConnectWithRetry(ctx, "Cassandra", retryConfig, func(ctx context.Context) (*intDatabase.CassandraDB, error) {
+		return intDatabase.NewCassandraDB(
+			[]string{env.GetString("CASSANDRA_HOST", "localhost")},
+			"secureconnect_ks",
+		)
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Cassandra: %v", err)
 	}
-	defer cassandraDB.Close()
+	stopSeq.OnClose("cassandra", func() error {
+		cassandraDB.Close()
+		return nil
+	})
 
 	log.Println("✅ Connected to Cassandra")
 
+	// Start background Cassandra health check (rebuilds the session after sustained failures)
+	cassandraDB.StartHealthCheck(ctx, 10*time.Second)
+	log.Println("✅ Cassandra health check started (10s interval)")
+
 	// 3. Connect to Redis with degraded mode support
 	redisConfig := &intDatabase.RedisConfig{
 		Host:     env.GetString("REDIS_HOST", "localhost"),
@@ -61,16 +121,21 @@ func main() {
 		Timeout:  5 * time.Second,
 	}
 
-	redisDB, err := intDatabase.NewRedisDB(redisConfig)
+	redisDB, err := pkgDatabase.ConnectWithRetry(ctx, "Redis", retryConfig, func(ctx context.Context) (*intDatabase.RedisClient, error) {
+		return intDatabase.ConnectRedisDB(ctx, redisConfig)
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
-	defer redisDB.Close()
+	stopSeq.OnClose("redis", func() error {
+		redisDB.Close()
+		return nil
+	})
 
 	log.Println("✅ Connected to Redis")
 
 	// Start background Redis health check
-	go redisDB.StartHealthCheck(context.Background(), 10*time.Second)
+	go redisDB.StartHealthCheck(ctx, 10*time.Second)
 	log.Println("✅ Redis health check started (10s interval)")
 
 	// 4. Connect to CockroachDB
@@ -83,11 +148,16 @@ func main() {
 		SSLMode:  env.GetString("COCKROACH_SSLMODE", "disable"),
 	}
 
-	cockroachDB, err := pkgDatabase.NewCockroachDB(context.Background(), cockroachConfig)
+	cockroachDB, err := pkgDatabase.ConnectWithRetry(ctx, "CockroachDB", retryConfig, func(ctx context.Context) (*pkgDatabase.CockroachDB, error) {
+		return pkgDatabase.NewCockroachDB(ctx, cockroachConfig)
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to CockroachDB: %v", err)
 	}
-	defer cockroachDB.Close()
+	stopSeq.OnClose("cockroachdb", func() error {
+		cockroachDB.Close()
+		return nil
+	})
 
 	log.Println("✅ Connected to CockroachDB")
 
@@ -96,11 +166,79 @@ func main() {
 	presenceRepo := redis.NewPresenceRepository(redisDB)
 	userRepo := cockroach.NewUserRepository(cockroachDB.Pool)
 	conversationRepo := cockroach.NewConversationRepository(cockroachDB.Pool)
+	conversationRepo.SetDefaultE2EE(cfg.Conversation.E2EEDefault)
 	notificationRepo := cockroach.NewNotificationRepository(cockroachDB.Pool)
 	// 6. Initialize Services
-	redisPublisher := &chatService.RedisAdapter{Client: redisDB.Client}
+	var redisPublisher chatService.Publisher = &chatService.RedisAdapter{Client: redisDB.Client}
+	var eventStream *redis.ConversationEventStream
+	if cfg.Conversation.EventStreamEnabled {
+		// Messages are appended to the conversation's stream as well as published
+		eventStream = redis.NewConversationEventStream(redisDB, int64(cfg.Conversation.EventStreamMaxLen), cfg.Conversation.EventStreamTTL)
+		redisPublisher = eventStream
+	}
 	notificationSvc := notificationService.NewService(notificationRepo)
 	chatSvc := chatService.NewService(messageRepo, presenceRepo, redisPublisher, notificationSvc, conversationRepo, userRepo)
+	notifyPool := workerpool.New("chat_notify",
+		env.GetInt("CHAT_NOTIFY_WORKERS", chatService.DefaultNotifyWorkers),
+		env.GetInt("CHAT_NOTIFY_QUEUE_SIZE", chatService.DefaultNotifyQueueSize))
+	chatSvc.SetNotificationPool(notifyPool)
+	// Closed before Redis, so notifications queued by the last requests still go out
+	stopSeq.OnClose("notification pool", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		return notifyPool.Shutdown(ctx)
+	})
+	chatSvc.SetReadPositionStore(redis.NewReadPositionRepository(redisDB), env.GetDuration("CHAT_READ_RECEIPT_WINDOW", chatService.DefaultReadReceiptWindow))
+	chatSvc.SetUnreadCounter(redis.NewUnreadRepository(redisDB))
+	chatSvc.SetSequenceAllocator(redis.NewMessageSequenceRepository(redisDB))
+	chatSvc.SetClientMessageStore(redis.NewClientMessageRepository(redisDB))
+	chatSvc.SetRecentMessageCache(redis.NewRecentMessageRepository(redisDB), chatService.RecentMessagesConfig{
+		Size: env.GetInt("CHAT_RECENT_MESSAGES_SIZE", chatService.DefaultRecentMessagesSize),
+		TTL:  env.GetDuration("CHAT_RECENT_MESSAGES_TTL", chatService.DefaultRecentMessagesTTL),
+	})
+	chatSvc.SetMessageEditWindow(env.GetDuration("CHAT_MESSAGE_EDIT_WINDOW", chatService.DefaultMessageEditWindow))
+	chatSvc.SetReactions(cassandra.NewReactionRepository(cassandraDB), strings.Split(env.GetString("CHAT_REACTION_EMOJIS", ""), ","))
+	chatSvc.SetPins(cockroach.NewPinnedMessageRepository(cockroachDB.Pool), env.GetInt("CHAT_MAX_PINNED_MESSAGES", domain.DefaultMaxPinnedMessages))
+	outboundTLS, err := tlsconfig.New(cfg.TLS.Policy())
+	if err != nil {
+		log.Fatalf("Invalid outbound TLS policy: %v", err)
+	}
+	botWebhooks := webhook.NewSender(env.GetDuration("BOT_WEBHOOK_TIMEOUT", webhook.DefaultTimeout))
+	botWebhooks.SetTLSConfig(outboundTLS)
+	chatSvc.SetBots(cockroach.NewBotRepository(cockroachDB.Pool), conversationRepo, botWebhooks)
+	// Server-side search over plaintext messages; E2EE conversations are never indexed
+	var searchIndex *cockroach.MessageSearchRepository
+	if env.GetBool("MESSAGE_SEARCH_ENABLED", true) {
+		searchIndex = cockroach.NewMessageSearchRepository(cockroachDB.Pool)
+		chatSvc.SetSearchIndex(searchIndex, conversationRepo)
+		chatSvc.StartSearchIndexSync(ctx, redis.NewSearchIndexSyncQueue(redisDB), env.GetDuration("MESSAGE_SEARCH_SYNC_INTERVAL", 30*time.Second))
+	}
+	// Registered after Redis so coalesced reads are written before it closes
+	stopSeq.OnClose("read receipts", func() error {
+		chatSvc.FlushPendingReads()
+		return nil
+	})
+	membership := conversationService.NewService(conversationRepo, userRepo, nil)
+	membership.SetMembershipCache(redis.NewMembershipCache(redisDB), cfg.Conversation.MembershipCacheTTL)
+	chatSvc.SetMembershipChecker(membership)
+
+	// Message retention purge (opt-in; one instance runs it under a Redis lock)
+	if env.GetBool("RETENTION_PURGE_ENABLED", false) {
+		retentionConfig := chatService.RetentionConfig{
+			DefaultDays:      env.GetInt("RETENTION_DEFAULT_DAYS", 0),
+			Interval:         env.GetDuration("RETENTION_PURGE_INTERVAL", 24*time.Hour),
+			DeletesPerSecond: env.GetInt("RETENTION_PURGE_RATE", 10),
+			DryRun:           env.GetBool("RETENTION_PURGE_DRY_RUN", false),
+		}
+		retentionPurger := chatService.NewRetentionPurger(messageRepo, conversationRepo, redis.NewLockRepository(redisDB), retentionConfig)
+		if searchIndex != nil {
+			retentionPurger.SetSearchIndex(searchIndex)
+		}
+		retentionPurger.Start(ctx)
+		logger.Info("Message retention purge scheduled",
+			zap.Duration("interval", retentionConfig.Interval),
+			zap.Bool("dry_run", retentionConfig.DryRun))
+	}
 
 	// 7. Initialize Metrics
 	appMetrics := metrics.NewMetrics("chat-service")
@@ -108,9 +246,32 @@ func main() {
 
 	// 8. Initialize Handlers
 	chatHdlr := chatHandler.NewHandler(chatSvc)
+	if env.GetBool("LINK_PREVIEW_ENABLED", true) {
+		chatHdlr.SetLinkPreviewer(chatService.NewLinkPreviewer(redis.NewLinkPreviewCache(redisDB), redis.NewWindowLimiter(redisDB), chatService.LinkPreviewConfig{
+			UserRequests:     env.GetInt("LINK_PREVIEW_USER_LIMIT", 30),
+			UserWindow:       env.GetDuration("LINK_PREVIEW_USER_WINDOW", time.Minute),
+			MaxConcurrent:    env.GetInt("LINK_PREVIEW_MAX_CONCURRENT", 20),
+			Timeout:          env.GetDuration("LINK_PREVIEW_TIMEOUT", 5*time.Second),
+			MaxBodyBytes:     int64(env.GetInt("LINK_PREVIEW_MAX_BODY_BYTES", 512*1024)),
+			CacheTTL:         env.GetDuration("LINK_PREVIEW_CACHE_TTL", 24*time.Hour),
+			NegativeCacheTTL: env.GetDuration("LINK_PREVIEW_NEGATIVE_CACHE_TTL", 10*time.Minute),
+		}))
+	}
 
 	// 9. Initialize WebSocket Hub
 	chatHub := wsHandler.NewChatHub(redisDB.Client)
+	go chatHub.FollowUserDisconnects(ctx)
+	// Closed before Redis, so subscriptions end without logging errors from a closed client
+	stopSeq.OnClose("chat hub", func() error {
+		chatHub.Stop()
+		return nil
+	})
+	chatHub.SetMessageHistory(messageRepo)
+	chatHub.SetTypingStore(redis.NewTypingRepository(redisDB))
+	chatHub.SetRelayGate(chatSvc)
+	if eventStream != nil {
+		chatHub.SetEventStream(eventStream)
+	}
 
 	// 10. Setup Gin Router
 	router := gin.New() // Don't use Default() to have full control
@@ -136,10 +297,15 @@ func main() {
 
 	// Apply global middleware
 	router.Use(middleware.Recovery())
-	router.Use(middleware.RequestLogger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestLogger(cfg.Log.AccessLevel))
 	router.Use(middleware.CORSMiddleware())
 	router.Use(prometheusMiddleware.Handler())
 
+	// Track in-flight requests so a timed-out shutdown can report them
+	inFlight := shutdown.NewInFlightTracker()
+	router.Use(inFlight.Middleware())
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -149,26 +315,63 @@ func main() {
 		})
 	})
 
+	// Liveness and readiness probes. /ready is kept for existing checks.
+	health := middleware.NewHealthChecker("chat-service", env.GetDuration("READINESS_TIMEOUT", middleware.DefaultReadinessTimeout))
+	health.AddCheck("cassandra", cassandraDB.Ping)
+	health.AddCheck("cockroach", cockroachDB.Ping)
+	health.AddCheck("redis", redisDB.HealthCheck)
+	health.Register(router)
+	router.GET("/ready", health.ReadyHandler())
+
 	// Metrics endpoint (for Prometheus scraping)
 	router.GET("/metrics", middleware.MetricsHandler(appMetrics))
 
+	// SLO summary for dashboards, gated by the operator metrics token
+	sloAggregator := metrics.NewSLOAggregator(prometheus.DefaultGatherer, "chat-service", map[string]metrics.DependencyGauge{
+		"cassandra": {Metric: "cassandra_session_healthy"},
+		"redis":     {Metric: "redis_degraded_mode", Inverted: true},
+	})
+	router.GET("/admin/slo", middleware.MetricsAuth(env.GetString("METRICS_AUTH_TOKEN", "")), middleware.SLOHandler(sloAggregator))
+
 	// Revocation checker
 	revocationChecker := middleware.NewRedisRevocationChecker(redisDB.Client)
 
+	// Bot replies authenticate with the bot's token instead of a user JWT
+	router.POST("/v1/bots/messages", chatHdlr.PostBotMessage)
+
 	// Chat routes (all require authentication)
 	v1 := router.Group("/v1")
 	v1.Use(middleware.AuthMiddleware(jwtManager, revocationChecker))
 	{
 		// Message endpoints
 		v1.POST("/messages", chatHdlr.SendMessage)
+		v1.POST("/messages/batch", chatHdlr.SendMessages)
 		v1.GET("/messages", chatHdlr.GetMessages)
+		v1.GET("/messages/search", chatHdlr.SearchMessages)
+		v1.PATCH("/messages/:id", chatHdlr.EditMessage)
+		v1.DELETE("/messages/:id", chatHdlr.DeleteMessage)
+		v1.POST("/messages/:id/reactions", chatHdlr.AddReaction)
+		v1.DELETE("/messages/:id/reactions/:emoji", chatHdlr.RemoveReaction)
+
+		// Conversation export
+		v1.GET("/conversations/:id/export", chatHdlr.ExportConversation)
+		v1.POST("/conversations/:id/read", chatHdlr.MarkRead)
+		v1.POST("/conversations/:id/pins", chatHdlr.PinMessage)
+		v1.GET("/conversations/:id/pins", chatHdlr.GetPinnedMessages)
+		v1.DELETE("/conversations/:id/pins/:message_id", chatHdlr.UnpinMessage)
+		v1.PUT("/conversations/:id/pins/order", chatHdlr.ReorderPins)
+		v1.POST("/conversations/read-all", chatHdlr.MarkAllRead)
+		v1.GET("/conversations/unread", chatHdlr.GetTotalUnread)
 
 		// Presence endpoint
 		v1.POST("/presence", chatHdlr.UpdatePresence)
 
+		// Link previews (rate limited per user, fetched only from public addresses)
+		v1.GET("/link-preview", chatHdlr.GetLinkPreview)
+
 		// WebSocket endpoint (real-time chat)
 		v1.GET("/ws/chat", func(c *gin.Context) {
-			chatHub.ServeWS(c, conversationRepo)
+			chatHub.ServeWS(c, membership)
 		})
 	}
 
@@ -179,6 +382,9 @@ func main() {
 		Addr:    addr,
 		Handler: router,
 	}
+	stopSeq.StopServer(server, cfg.Server.ShutdownTimeout, inFlight)
+	// Hijacked WebSocket connections are not drained by Shutdown, so close them explicitly
+	server.RegisterOnShutdown(chatHub.CloseAll)
 
 	go func() {
 		log.Printf("🚀 Chat Service starting on port %s\n", port)
@@ -193,12 +399,9 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+	log.Printf("Shutting down server (timeout %s)...", cfg.Server.ShutdownTimeout)
+	if err := stopSeq.Run(); err != nil {
+		log.Printf("Shutdown finished with errors: %v", err)
 	}
 
 	log.Println("Server exited")
@@ -11,29 +11,49 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 
+	intDatabase "secureconnect-backend/internal/database"
 	storageHandler "secureconnect-backend/internal/handler/http/storage"
 	"secureconnect-backend/internal/middleware"
 	"secureconnect-backend/internal/repository/cockroach"
+	redisRepo "secureconnect-backend/internal/repository/redis"
 	storageService "secureconnect-backend/internal/service/storage"
 	"secureconnect-backend/pkg/config"
 	"secureconnect-backend/pkg/database"
+	"secureconnect-backend/pkg/env"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/shutdown"
 )
 
 func main() {
-	// Initialize context
-	ctx := context.Background()
+	// Initialize logger
+	logger.InitDefault("storage-service")
+	defer logger.Sync()
+
+	// Dependencies are registered with the shutdown sequence as they are created
+	// so they are closed only after the server has drained
+	stopSeq := shutdown.NewSequence()
+	ctx := stopSeq.Background(context.Background())
 
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	config.LogEffective(cfg)
+	jsonbind.SetOptions(jsonbind.Options{
+		MaxBodyBytes:          cfg.JSON.MaxBodyBytes,
+		MaxDepth:              cfg.JSON.MaxDepth,
+		MaxArrayLen:           cfg.JSON.MaxArrayLen,
+		DisallowUnknownFields: cfg.JSON.DisallowUnknownFields,
+	})
 
 	// Validate JWT secret in production
-	if cfg.Server.Environment == "production" {
+	if cfg.Server.Environment == "production" && cfg.JWT.Algorithm == jwt.AlgorithmHS256 {
 		if cfg.JWT.Secret == "" {
 			log.Fatal("JWT_SECRET environment variable is required in production")
 		}
@@ -43,25 +63,40 @@ func main() {
 	}
 
 	// 1. Setup JWT Manager
-	jwtManager := jwt.NewJWTManager(
-		cfg.JWT.Secret,
-		cfg.JWT.AccessTokenExpiry,
-		cfg.JWT.RefreshTokenExpiry,
-	)
+	jwtManager, err := jwt.NewJWTManagerFromConfig(jwt.KeyConfig{
+		Algorithm:              cfg.JWT.Algorithm,
+		Secret:                 cfg.JWT.Secret,
+		PrivateKeyFile:         cfg.JWT.PrivateKeyFile,
+		PublicKeyFile:          cfg.JWT.PublicKeyFile,
+		KeyID:                  cfg.JWT.KeyID,
+		PreviousSecrets:        cfg.JWT.PreviousSecrets,
+		PreviousPublicKeyFiles: cfg.JWT.PreviousPublicKeyFiles,
+	}, cfg.JWT.AccessTokenExpiry, cfg.JWT.RefreshTokenExpiry)
+	if err != nil {
+		log.Fatalf("Failed to set up JWT manager: %v", err)
+	}
+
+	// Dependencies may still be starting up, so retry connections with backoff
+	retryConfig := database.RetryConfigFromEnv()
 
 	// 2. Connect to CockroachDB
-	crdb, err := database.NewCockroachDB(ctx, &database.CockroachConfig{
-		Host:     cfg.Database.Host,
-		Port:     cfg.Database.Port,
-		User:     cfg.Database.User,
-		Password: cfg.Database.Password,
-		Database: cfg.Database.Database,
-		SSLMode:  cfg.Database.SSLMode,
+	crdb, err := database.ConnectWithRetry(ctx, "CockroachDB", retryConfig, func(ctx context.Context) (*database.CockroachDB, error) {
+		return database.NewCockroachDB(ctx, &database.CockroachConfig{
+			Host:     cfg.Database.Host,
+			Port:     cfg.Database.Port,
+			User:     cfg.Database.User,
+			Password: cfg.Database.Password,
+			Database: cfg.Database.Database,
+			SSLMode:  cfg.Database.SSLMode,
+		})
 	})
 	if err != nil {
 		log.Fatalf("Failed to connect to CockroachDB: %v", err)
 	}
-	defer crdb.Close()
+	stopSeq.OnClose("cockroachdb", func() error {
+		crdb.Close()
+		return nil
+	})
 	log.Println("✅ Connected to CockroachDB")
 
 	// Initialize Repository
@@ -94,21 +129,33 @@ func main() {
 	storageHdlr := storageHandler.NewHandler(storageSvc)
 
 	// 6. Connect to Redis
-	redisDB, err := database.NewRedisDB(&database.RedisConfig{
-		Host:     cfg.Redis.Host,
-		Port:     cfg.Redis.Port,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-		PoolSize: cfg.Redis.PoolSize,
-		Timeout:  cfg.Redis.Timeout,
+	redisDB, err := database.ConnectWithRetry(ctx, "Redis", retryConfig, func(ctx context.Context) (*intDatabase.RedisClient, error) {
+		return intDatabase.ConnectRedisDB(ctx, &intDatabase.RedisConfig{
+			Host:     cfg.Redis.Host,
+			Port:     cfg.Redis.Port,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+			PoolSize: cfg.Redis.PoolSize,
+			Timeout:  cfg.Redis.Timeout,
+		})
 	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
-	defer redisDB.Close()
+	stopSeq.OnClose("redis", func() error {
+		redisDB.Close()
+		return nil
+	})
+	go redisDB.StartHealthCheck(ctx, 10*time.Second)
 
 	log.Println("✅ Connected to Redis")
 
+	// Periodically repair quota usage that drifted from the stored objects
+	usageReconciler := storageService.NewUsageReconciler(storageSvc, redisRepo.NewLockRepository(redisDB), storageService.UsageReconcilerConfig{
+		Interval: env.GetDuration("STORAGE_USAGE_RECONCILE_INTERVAL", 24*time.Hour),
+	})
+	usageReconciler.Start(ctx)
+
 	// Revocation checker
 	revocationChecker := middleware.NewRedisRevocationChecker(redisDB.Client)
 
@@ -136,10 +183,15 @@ func main() {
 
 	// Apply global middleware
 	router.Use(middleware.Recovery())
-	router.Use(middleware.RequestLogger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestLogger(cfg.Log.AccessLevel))
 	router.Use(middleware.CORSMiddleware())
 	router.Use(prometheusMiddleware.Handler())
 
+	// Track in-flight requests so a timed-out shutdown can report them
+	inFlight := shutdown.NewInFlightTracker()
+	router.Use(inFlight.Middleware())
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -149,14 +201,32 @@ func main() {
 		})
 	})
 
+	// Liveness and readiness probes
+	health := middleware.NewHealthChecker("storage-service", env.GetDuration("READINESS_TIMEOUT", middleware.DefaultReadinessTimeout))
+	health.AddCheck("cockroach", crdb.Ping)
+	health.AddCheck("redis", redisDB.HealthCheck)
+	health.Register(router)
+
 	// Metrics endpoint (for Prometheus scraping)
 	router.GET("/metrics", middleware.MetricsHandler(appMetrics))
 
+	// SLO summary for dashboards, gated by the operator metrics token
+	sloAggregator := metrics.NewSLOAggregator(prometheus.DefaultGatherer, "storage-service", nil)
+	router.GET("/admin/slo", middleware.MetricsAuth(env.GetString("METRICS_AUTH_TOKEN", "")), middleware.SLOHandler(sloAggregator))
+
+	// Admin repair of a user's storage usage
+	admin := router.Group("/v1/admin/storage")
+	admin.Use(middleware.AuthMiddleware(jwtManager, revocationChecker), middleware.RequireAdmin(cockroach.NewAdminRepository(crdb.Pool)))
+	{
+		admin.POST("/users/:user_id/recalculate-usage", storageHdlr.RecalculateUsage)
+	}
+
 	// Storage routes (all require authentication)
 	v1 := router.Group("/v1/storage")
 	v1.Use(middleware.AuthMiddleware(jwtManager, revocationChecker))
 	{
 		v1.POST("/upload-url", storageHdlr.GenerateUploadURL)
+		v1.POST("/upload-post", storageHdlr.GeneratePresignedPost)
 		v1.GET("/download-url/:file_id", storageHdlr.GenerateDownloadURL)
 		v1.DELETE("/files/:file_id", storageHdlr.DeleteFile)
 
@@ -171,6 +241,7 @@ func main() {
 		Addr:    addr,
 		Handler: router,
 	}
+	stopSeq.StopServer(server, cfg.Server.ShutdownTimeout, inFlight)
 
 	go func() {
 		log.Printf("🚀 Storage Service starting on port %d\n", cfg.Server.Port)
@@ -184,12 +255,9 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+	log.Printf("Shutting down server (timeout %s)...", cfg.Server.ShutdownTimeout)
+	if err := stopSeq.Run(); err != nil {
+		log.Printf("Shutdown finished with errors: %v", err)
 	}
 
 	log.Println("Server exited")
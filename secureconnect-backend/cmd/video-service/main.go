@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 
 	intDatabase "secureconnect-backend/internal/database"
 	videoHandler "secureconnect-backend/internal/handler/http/video"
@@ -16,28 +19,66 @@ import (
 	"secureconnect-backend/internal/middleware"
 	"secureconnect-backend/internal/repository/cockroach"
 	redisRepo "secureconnect-backend/internal/repository/redis"
+	conversationService "secureconnect-backend/internal/service/conversation"
 	videoService "secureconnect-backend/internal/service/video"
+	"secureconnect-backend/pkg/config"
 	pkgDatabase "secureconnect-backend/pkg/database"
 	"secureconnect-backend/pkg/env"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
 	"secureconnect-backend/pkg/push"
+	"secureconnect-backend/pkg/shutdown"
 )
 
 func main() {
-	// Create context for database operations
-	ctx := context.Background()
+	// Initialize logger
+	logger.InitDefault("video-service")
+	defer logger.Sync()
+
+	// Load shared server settings (shutdown timeout)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	config.LogEffective(cfg)
+	jsonbind.SetOptions(jsonbind.Options{
+		MaxBodyBytes:          cfg.JSON.MaxBodyBytes,
+		MaxDepth:              cfg.JSON.MaxDepth,
+		MaxArrayLen:           cfg.JSON.MaxArrayLen,
+		DisallowUnknownFields: cfg.JSON.DisallowUnknownFields,
+	})
+
+	// Dependencies are registered with the shutdown sequence as they are created
+	// so they are closed only after the server has drained. ctx is cancelled once
+	// the server has stopped, ending the health check and push probe.
+	stopSeq := shutdown.NewSequence()
+	ctx := stopSeq.Background(context.Background())
 
 	// 1. Setup JWT Manager
 	jwtSecret := env.GetString("JWT_SECRET", "")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is required")
-	}
-	if len(jwtSecret) < 32 {
-		log.Fatal("JWT_SECRET must be at least 32 characters")
+	if cfg.JWT.Algorithm == jwt.AlgorithmHS256 {
+		if jwtSecret == "" {
+			log.Fatal("JWT_SECRET environment variable is required")
+		}
+		if len(jwtSecret) < 32 {
+			log.Fatal("JWT_SECRET must be at least 32 characters")
+		}
 	}
 
-	jwtManager := jwt.NewJWTManager(jwtSecret, 15*time.Minute, 30*24*time.Hour)
+	jwtManager, err := jwt.NewJWTManagerFromConfig(jwt.KeyConfig{
+		Algorithm:              cfg.JWT.Algorithm,
+		Secret:                 jwtSecret,
+		PrivateKeyFile:         cfg.JWT.PrivateKeyFile,
+		PublicKeyFile:          cfg.JWT.PublicKeyFile,
+		KeyID:                  cfg.JWT.KeyID,
+		PreviousSecrets:        cfg.JWT.PreviousSecrets,
+		PreviousPublicKeyFiles: cfg.JWT.PreviousPublicKeyFiles,
+	}, 15*time.Minute, 30*24*time.Hour)
+	if err != nil {
+		log.Fatalf("Failed to set up JWT manager: %v", err)
+	}
 
 	// Validate production mode
 	productionMode := os.Getenv("ENV") == "production"
@@ -53,38 +94,12 @@ func main() {
 	}
 
 	// Connect to CockroachDB with exponential backoff retry
-	var db *pkgDatabase.CockroachDB
-	var err error
-
-	maxRetries := 5
-	baseDelay := 1 * time.Second
-	maxDelay := 30 * time.Second
-
-	// Execute first connection attempt
-	db, err = pkgDatabase.NewCockroachDB(ctx, dbConfig)
-	if err == nil {
-		log.Println("✅ Connected to CockroachDB")
-	} else {
-		// Retry with exponential backoff
-		for attempt := 2; attempt <= maxRetries; attempt++ {
-			delay := time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt-1)))
-			if delay > maxDelay {
-				delay = maxDelay
-			}
-			log.Printf("⚠️  CockroachDB connection attempt %d failed: %v. Retrying in %v...", attempt, err, delay)
-			time.Sleep(delay)
-
-			// Retry connection
-			db, err = pkgDatabase.NewCockroachDB(ctx, dbConfig)
-			if err == nil {
-				log.Printf("✅ Connected to CockroachDB (attempt %d/%d)", attempt, maxRetries)
-				break
-			}
-		}
-	}
-
+	retryConfig := pkgDatabase.RetryConfigFromEnv()
+	db, err := pkgDatabase.ConnectWithRetry(ctx, "CockroachDB", retryConfig, func(ctx context.Context) (*pkgDatabase.CockroachDB, error) {
+		return pkgDatabase.NewCockroachDB(ctx, dbConfig)
+	})
 	if err != nil {
-		log.Printf("Warning: Failed to connect to CockroachDB after %d attempts: %v", maxRetries, err)
+		log.Printf("Warning: %v", err)
 		log.Println("Running in limited mode without call logs persistence")
 	}
 
@@ -92,7 +107,10 @@ func main() {
 	var conversationRepo *cockroach.ConversationRepository
 	var userRepo *cockroach.UserRepository
 	if db != nil {
-		defer db.Close()
+		stopSeq.OnClose("cockroachdb", func() error {
+			db.Close()
+			return nil
+		})
 		callRepo = cockroach.NewCallRepository(db.Pool)
 		conversationRepo = cockroach.NewConversationRepository(db.Pool)
 		userRepo = cockroach.NewUserRepository(db.Pool)
@@ -109,13 +127,20 @@ func main() {
 		Timeout:  5 * time.Second,
 	}
 
-	redisDB, err := intDatabase.NewRedisDB(redisConfig)
+	redisDB, err := pkgDatabase.ConnectWithRetry(ctx, "Redis", retryConfig, func(ctx context.Context) (*intDatabase.RedisClient, error) {
+		return intDatabase.ConnectRedisDB(ctx, redisConfig)
+	})
 	if err != nil {
-		log.Printf("Warning: Failed to connect to Redis: %v", err)
+		// Fall back to an unverified client; the health check below keeps it in degraded mode until Redis is reachable
+		log.Printf("Warning: %v", err)
+		redisDB, _ = intDatabase.NewRedisDB(redisConfig)
 	} else {
 		log.Println("✅ Connected to Redis")
 	}
-	defer redisDB.Close()
+	stopSeq.OnClose("redis", func() error {
+		redisDB.Close()
+		return nil
+	})
 
 	// Start background Redis health check
 	go redisDB.StartHealthCheck(ctx, 10*time.Second)
@@ -177,8 +202,27 @@ func main() {
 
 	pushSvc := push.NewService(pushProvider, pushTokenRepo)
 
+	// Probe the push provider periodically so credential or network problems surface
+	// before sends start failing. The mock provider has nothing to probe.
+	var pushProbe *push.HealthProbe
+	if checker, ok := pushProvider.(push.HealthChecker); ok {
+		pushProbe = push.NewHealthProbe(pushProviderType, checker)
+		pushProbe.Start(ctx, env.GetDuration("PUSH_HEALTH_CHECK_INTERVAL", push.DefaultHealthProbeInterval))
+	}
+
 	// 5. Initialize Video Service
-	videoSvc := videoService.NewService(callRepo, conversationRepo, userRepo, pushSvc)
+	var membership videoService.MembershipChecker
+	if conversationRepo != nil {
+		conversationSvc := conversationService.NewService(conversationRepo, userRepo, nil)
+		conversationSvc.SetMembershipCache(redisRepo.NewMembershipCache(redisDB), cfg.Conversation.MembershipCacheTTL)
+		membership = conversationSvc
+	}
+	videoSvc := videoService.NewService(callRepo, membership, userRepo, pushSvc)
+	if conversationRepo != nil {
+		videoSvc.SetCallMutes(conversationRepo)
+	}
+	videoSvc.SetActiveCallLimit(redisRepo.NewActiveCallRepository(redisDB), cfg.Video.MaxActiveCallsPerUser, cfg.Video.ActiveCallTTL)
+	videoSvc.SetRingTimeout(cfg.Video.RingTimeout)
 
 	// 6. Initialize Metrics
 	appMetrics := metrics.NewMetrics("video-service")
@@ -189,6 +233,7 @@ func main() {
 
 	// 8. Initialize WebRTC Signaling Hub
 	signalingHub := wsHandler.NewSignalingHub(redisDB)
+	go signalingHub.FollowUserDisconnects(ctx)
 
 	// 9. Setup Gin Router
 	router := gin.New() // Don't use Default() to have full control
@@ -214,10 +259,15 @@ func main() {
 
 	// Apply global middleware
 	router.Use(middleware.Recovery())
-	router.Use(middleware.RequestLogger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestLogger(cfg.Log.AccessLevel))
 	router.Use(middleware.CORSMiddleware())
 	router.Use(prometheusMiddleware.Handler())
 
+	// Track in-flight requests so a timed-out shutdown can report them
+	inFlight := shutdown.NewInFlightTracker()
+	router.Use(inFlight.Middleware())
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -227,9 +277,30 @@ func main() {
 		})
 	})
 
+	// Liveness and readiness probes. /ready is kept for existing checks.
+	// Push degradation is reported but does not fail readiness, since calls
+	// still work without notifications, and CockroachDB is only checked when
+	// call logs are persisted.
+	health := middleware.NewHealthChecker("video-service", env.GetDuration("READINESS_TIMEOUT", middleware.DefaultReadinessTimeout))
+	health.AddCheck("redis", redisDB.HealthCheck)
+	if db != nil {
+		health.AddCheck("cockroach", db.Ping)
+	}
+	if pushProbe != nil {
+		health.AddReport("push", func() interface{} { return pushProbe.Status() })
+	}
+	health.Register(router)
+	router.GET("/ready", health.ReadyHandler())
+
 	// Metrics endpoint (for Prometheus scraping)
 	router.GET("/metrics", middleware.MetricsHandler(appMetrics))
 
+	// SLO summary for dashboards, gated by the operator metrics token
+	sloAggregator := metrics.NewSLOAggregator(prometheus.DefaultGatherer, "video-service", map[string]metrics.DependencyGauge{
+		"redis": {Metric: "redis_degraded_mode", Inverted: true},
+	})
+	router.GET("/admin/slo", middleware.MetricsAuth(env.GetString("METRICS_AUTH_TOKEN", "")), middleware.SLOHandler(sloAggregator))
+
 	// Revocation checker
 	revocationChecker := middleware.NewRedisRevocationChecker(redisDB.Client)
 
@@ -241,6 +312,7 @@ func main() {
 		v1.POST("/initiate", videoHdlr.InitiateCall)
 		v1.POST("/:id/end", videoHdlr.EndCall)
 		v1.POST("/:id/join", videoHdlr.JoinCall)
+		v1.POST("/:id/reject", videoHdlr.RejectCall)
 		v1.GET("/:id", videoHdlr.GetCallStatus)
 
 		// WebSocket endpoint for WebRTC signaling
@@ -251,9 +323,31 @@ func main() {
 	port := env.GetString("PORT", "8083")
 	addr := fmt.Sprintf(":%s", port)
 
-	log.Printf("🚀 Video Service starting on port %s\n", port)
-	log.Println("📡 WebRTC Signaling: /v1/calls/ws/signaling")
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:    addr,
+		Handler: router,
 	}
+	stopSeq.StopServer(server, cfg.Server.ShutdownTimeout, inFlight)
+	// Hijacked WebSocket connections are not drained by Shutdown, so close them explicitly
+	server.RegisterOnShutdown(signalingHub.CloseAll)
+
+	go func() {
+		log.Printf("🚀 Video Service starting on port %s\n", port)
+		log.Println("📡 WebRTC Signaling: /v1/calls/ws/signaling")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// 11. Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Printf("Shutting down server (timeout %s)...", cfg.Server.ShutdownTimeout)
+	if err := stopSeq.Run(); err != nil {
+		log.Printf("Shutdown finished with errors: %v", err)
+	}
+
+	log.Println("Server exited")
 }
@@ -2,43 +2,224 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
 )
 
 // DefaultCassandraQueryTimeout is the default timeout for Cassandra queries
 const DefaultCassandraQueryTimeout = 5 * time.Second
 
-// CassandraDB wraps the gocql Session with context support
+const (
+	// DefaultCassandraFailureThreshold is the number of consecutive failed health checks
+	// after which the session is considered broken and rebuilt
+	DefaultCassandraFailureThreshold = 3
+	// DefaultCassandraReconnectInterval is the minimum time between two session rebuilds
+	DefaultCassandraReconnectInterval = 5 * time.Second
+)
+
+// ErrCassandraReconnectThrottled is returned by Reconnect when a rebuild happened too recently
+var ErrCassandraReconnectThrottled = errors.New("cassandra reconnect throttled")
+
+// CassandraSessionFactory creates a new gocql session.
+// It is used for the initial connection and whenever the session has to be rebuilt.
+type CassandraSessionFactory func() (*gocql.Session, error)
+
+// CassandraDB wraps the gocql Session with context support and session-level recovery
 type CassandraDB struct {
-	Session *gocql.Session
+	session   *gocql.Session
+	sessionMu sync.RWMutex
+
+	factory           CassandraSessionFactory
+	reconnectMu       sync.Mutex
+	reconnectInterval time.Duration
+	lastReconnect     time.Time
+
+	failureThreshold    int32
+	consecutiveFailures atomic.Int32
+	healthy             atomic.Bool
 }
 
 // NewCassandraDB creates a new CassandraDB instance with configured timeouts
 func NewCassandraDB(hosts []string, keyspace string) (*CassandraDB, error) {
-	cluster := gocql.NewCluster(hosts...)
-	cluster.Keyspace = keyspace
-	cluster.Consistency = gocql.Quorum
+	return NewCassandraDBWithFactory(func() (*gocql.Session, error) {
+		cluster := gocql.NewCluster(hosts...)
+		cluster.Keyspace = keyspace
+		cluster.Consistency = gocql.Quorum
 
-	// Set default timeout for all queries
-	cluster.Timeout = DefaultCassandraQueryTimeout
+		// Set default timeout for all queries
+		cluster.Timeout = DefaultCassandraQueryTimeout
 
-	session, err := cluster.CreateSession()
+		return cluster.CreateSession()
+	})
+}
+
+// NewCassandraDBWithFactory creates a CassandraDB whose sessions are built by factory
+func NewCassandraDBWithFactory(factory CassandraSessionFactory) (*CassandraDB, error) {
+	session, err := factory()
 	if err != nil {
 		return nil, err
 	}
-	return &CassandraDB{Session: session}, nil
+
+	db := &CassandraDB{
+		session:           session,
+		factory:           factory,
+		reconnectInterval: DefaultCassandraReconnectInterval,
+		failureThreshold:  DefaultCassandraFailureThreshold,
+	}
+	db.setHealthy(true)
+	return db, nil
+}
+
+// Session returns the current gocql session
+func (c *CassandraDB) Session() *gocql.Session {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.session
 }
 
 // Close closes the Cassandra session
 func (c *CassandraDB) Close() {
-	c.Session.Close()
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if c.session != nil {
+		c.session.Close()
+	}
+}
+
+// IsHealthy reports whether the last health check (or reconnect) succeeded
+func (c *CassandraDB) IsHealthy() bool {
+	return c.healthy.Load()
+}
+
+func (c *CassandraDB) setHealthy(healthy bool) {
+	c.healthy.Store(healthy)
+	metrics.SetCassandraSessionHealthy(healthy)
+}
+
+// StartHealthCheck starts a background goroutine that periodically checks Cassandra health
+// and rebuilds the session after sustained failures
+func (c *CassandraDB) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.HealthCheck(ctx)
+			}
+		}
+	}()
+}
+
+// HealthCheck runs a lightweight query against the cluster. After
+// failureThreshold consecutive failures the session is rebuilt.
+func (c *CassandraDB) HealthCheck(ctx context.Context) error {
+	healthCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := c.Ping(healthCtx); err != nil {
+		return c.recordHealthCheckFailure(err)
+	}
+
+	c.consecutiveFailures.Store(0)
+	c.setHealthy(true)
+	return nil
+}
+
+// Ping runs the health check query once. Unlike HealthCheck it leaves the
+// failure count alone, so frequent readiness probes cannot force a rebuild.
+func (c *CassandraDB) Ping(ctx context.Context) error {
+	session := c.Session()
+	if session == nil {
+		return errors.New("cassandra session not initialized")
+	}
+
+	var now time.Time
+	return session.Query("SELECT now() FROM system.local").WithContext(ctx).Scan(&now)
+}
+
+func (c *CassandraDB) recordHealthCheckFailure(err error) error {
+	c.setHealthy(false)
+	failures := c.consecutiveFailures.Add(1)
+
+	logger.Warn("Cassandra health check failed",
+		zap.Int32("consecutive_failures", failures),
+		zap.Error(err))
+
+	if failures >= c.failureThreshold {
+		if rerr := c.Reconnect(); rerr != nil && !errors.Is(rerr, ErrCassandraReconnectThrottled) {
+			logger.Error("Cassandra session rebuild failed", zap.Error(rerr))
+		}
+	}
+
+	return fmt.Errorf("cassandra health check failed: %w", err)
+}
+
+// Reconnect rebuilds the session through the factory and swaps it in.
+// Concurrent callers are serialized and rebuilds are throttled to one per reconnectInterval.
+func (c *CassandraDB) Reconnect() error {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+
+	if !c.lastReconnect.IsZero() && time.Since(c.lastReconnect) < c.reconnectInterval {
+		return ErrCassandraReconnectThrottled
+	}
+	c.lastReconnect = time.Now()
+
+	logger.Info("Rebuilding Cassandra session")
+
+	session, err := c.factory()
+	if err != nil {
+		metrics.RecordCassandraReconnect("failure")
+		c.setHealthy(false)
+		return fmt.Errorf("failed to rebuild Cassandra session: %w", err)
+	}
+
+	c.sessionMu.Lock()
+	old := c.session
+	c.session = session
+	c.sessionMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	metrics.RecordCassandraReconnect("success")
+	c.consecutiveFailures.Store(0)
+	c.setHealthy(true)
+	logger.Info("Cassandra session rebuilt")
+	return nil
+}
+
+// IsNoHostsError reports whether err means the session has no live connections left
+func IsNoHostsError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gocql.ErrNoConnections) {
+		return true
+	}
+	return strings.Contains(err.Error(), "no hosts available")
 }
 
 // QueryWithContext executes a query with context-based timeout
 // This ensures that queries respect context cancellation and don't hang indefinitely
 func (c *CassandraDB) QueryWithContext(ctx context.Context, stmt string, values ...interface{}) *gocql.Query {
+	session := c.Session()
+
 	// Check if context has a deadline
 	if deadline, ok := ctx.Deadline(); ok {
 		// Calculate timeout from context deadline
@@ -48,11 +229,11 @@ func (c *CassandraDB) QueryWithContext(ctx context.Context, stmt string, values
 		}
 
 		// Create query with timeout
-		return c.Session.Query(stmt, values...).WithContext(ctx)
+		return session.Query(stmt, values...).WithContext(ctx)
 	}
 
 	// No deadline in context, use default timeout
-	return c.Session.Query(stmt, values...).WithContext(ctx)
+	return session.Query(stmt, values...).WithContext(ctx)
 }
 
 // ExecWithContext executes a query without returning results, with context-based timeout
@@ -60,3 +241,13 @@ func (c *CassandraDB) ExecWithContext(ctx context.Context, stmt string, values .
 	query := c.QueryWithContext(ctx, stmt, values...)
 	return query.Exec()
 }
+
+// ExecBatchWithContext executes stmt once per row in a single batch of the given type
+func (c *CassandraDB) ExecBatchWithContext(ctx context.Context, batchType gocql.BatchType, stmt string, rows [][]interface{}) error {
+	session := c.Session()
+	batch := session.NewBatch(batchType).WithContext(ctx)
+	for _, values := range rows {
+		batch.Query(stmt, values...)
+	}
+	return session.ExecuteBatch(batch)
+}
@@ -96,6 +96,22 @@ func NewRedisDB(cfg *RedisConfig) (*RedisClient, error) {
 	}, nil
 }
 
+// ConnectRedisDB creates a Redis client like NewRedisDB but verifies the
+// connection with a PING, so it can be used with database.ConnectWithRetry
+func ConnectRedisDB(ctx context.Context, cfg *RedisConfig) (*RedisClient, error) {
+	r, err := NewRedisDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.Client.Ping(ctx).Err(); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	return r, nil
+}
+
 // Close closes the Redis client connection
 func (r *RedisClient) Close() {
 	r.Client.Close()
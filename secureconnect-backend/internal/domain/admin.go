@@ -23,6 +23,7 @@ type SystemStats struct {
 type UserListRequest struct {
 	Limit     int    `json:"limit"`
 	Offset    int    `json:"offset"`
+	Cursor    string `json:"cursor"`     // Keyset cursor from a previous page; overrides offset and sorting
 	Search    string `json:"search"`     // Search by email or username
 	Status    string `json:"status"`     // Filter by status: online, offline, all
 	SortBy    string `json:"sort_by"`    // Sort field: created_at, email, username
@@ -34,6 +35,7 @@ type UserListResponse struct {
 	Users      []UserInfo `json:"users"`
 	TotalCount int        `json:"total_count"`
 	HasMore    bool       `json:"has_more"`
+	NextCursor string     `json:"next_cursor,omitempty"`
 }
 
 // UserInfo represents user information for admin view
@@ -81,6 +83,18 @@ type SystemHealth struct {
 	CheckedAt     time.Time                `json:"checked_at"`
 }
 
+// PushTokenInfo describes a user's push token for support, with the token masked
+type PushTokenInfo struct {
+	ID          uuid.UUID `json:"id"`
+	Type        string    `json:"type"`
+	Platform    string    `json:"platform,omitempty"`
+	DeviceID    string    `json:"device_id,omitempty"`
+	MaskedToken string    `json:"masked_token"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"` // Last registration or refresh
+}
+
 // AuditLog represents an administrative action
 type AuditLog struct {
 	AuditID    uuid.UUID `json:"audit_id"`
@@ -104,3 +118,14 @@ type AuditLogRequest struct {
 	StartDate  *time.Time `json:"start_date"`
 	EndDate    *time.Time `json:"end_date"`
 }
+
+// UserDisconnectChannel is the pub/sub channel on which services ask the
+// WebSocket hubs to close a user's connections
+const UserDisconnectChannel = "users:disconnect"
+
+// UserDisconnectEvent asks the WebSocket hubs to close every connection of
+// UserID with the close reason named by Reason (e.g. "banned")
+type UserDisconnectEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Reason string    `json:"reason"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBotsPerConversation caps how many bots one conversation can register
+const MaxBotsPerConversation = 5
+
+// Bot is a webhook integration registered to a conversation. New plaintext
+// messages are delivered to WebhookURL signed with Secret, and the bot posts
+// replies with a token whose SHA-256 hash is TokenHash.
+// Maps to CockroachDB conversation_bots table
+type Bot struct {
+	BotID          uuid.UUID `json:"bot_id" db:"bot_id"`
+	ConversationID uuid.UUID `json:"conversation_id" db:"conversation_id"`
+	WebhookURL     string    `json:"webhook_url" db:"webhook_url"`
+	Secret         string    `json:"-" db:"secret"`
+	TokenHash      string    `json:"-" db:"token_hash"`
+	CreatedBy      uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// HashBotToken returns the stored form of a bot token
+func HashBotToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Bot errors
+var (
+	ErrBotLimitReached     = NewError("BOT_LIMIT_REACHED", "This conversation already has the maximum number of bots")
+	ErrBotE2EEConversation = NewError("BOT_E2EE_CONVERSATION", "Bots cannot be added to end-to-end encrypted conversations")
+	ErrBotNotFound         = NewError("BOT_NOT_FOUND", "Bot not found")
+	ErrInvalidBotToken     = NewError("INVALID_BOT_TOKEN", "Invalid bot token")
+)
@@ -27,3 +27,9 @@ type CallParticipant struct {
 	IsMuted   bool       `json:"is_muted"`
 	IsVideoOn bool       `json:"is_video_on"`
 }
+
+// Call errors
+var (
+	ErrTooManyActiveCalls = NewError("TOO_MANY_ACTIVE_CALLS", "Too many calls in progress; end one before starting another")
+	ErrCallNotRinging     = NewError("CALL_NOT_RINGING", "The call is not ringing for you")
+)
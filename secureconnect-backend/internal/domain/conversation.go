@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,28 +18,40 @@ type Conversation struct {
 	CreatedBy      uuid.UUID `json:"created_by" db:"created_by"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// Computed fields (not stored in the conversations table)
+	ActivePollCount int                `json:"active_poll_count"`
+	ActivePoll      *ActivePollSummary `json:"active_poll,omitempty"` // Most recent active poll
 }
 
 // ConversationParticipant represents a user in a conversation
 // Maps to CockroachDB conversation_participants table
 type ConversationParticipant struct {
-	ConversationID uuid.UUID `json:"conversation_id" db:"conversation_id"`
-	UserID         uuid.UUID `json:"user_id" db:"user_id"`
-	Role           string    `json:"role" db:"role"` // admin, member
-	JoinedAt       time.Time `json:"joined_at" db:"joined_at"`
+	ConversationID uuid.UUID  `json:"conversation_id" db:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	Role           string     `json:"role" db:"role"` // admin, member
+	JoinedAt       time.Time  `json:"joined_at" db:"joined_at"`
+	MutedUntil     *time.Time `json:"muted_until,omitempty" db:"muted_until"` // set by admins to stop the user posting
+	CallsMuted     bool       `json:"calls_muted" db:"calls_muted"`           // set by the user to stop group calls ringing
+}
+
+// IsMuted reports whether the participant is muted at now
+func (p *ConversationParticipant) IsMuted(now time.Time) bool {
+	return p.MutedUntil != nil && p.MutedUntil.After(now)
 }
 
 // ConversationParticipantDetail represents a user in a conversation with user details
 type ConversationParticipantDetail struct {
-	ConversationID uuid.UUID `json:"conversation_id"`
-	UserID         uuid.UUID `json:"user_id"`
-	Role           string    `json:"role"`
-	JoinedAt       time.Time `json:"joined_at"`
-	Email          string    `json:"email"`
-	Username       string    `json:"username"`
-	DisplayName    string    `json:"display_name"`
-	AvatarURL      *string   `json:"avatar_url,omitempty"`
-	Status         string    `json:"status"`
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Role           string     `json:"role"`
+	JoinedAt       time.Time  `json:"joined_at"`
+	Email          string     `json:"email"`
+	Username       string     `json:"username"`
+	DisplayName    string     `json:"display_name"`
+	AvatarURL      *string    `json:"avatar_url,omitempty"`
+	Status         string     `json:"status"`
+	MutedUntil     *time.Time `json:"muted_until,omitempty"`
 }
 
 // ConversationSettings represents security and AI settings for a conversation
@@ -51,6 +64,29 @@ type ConversationSettings struct {
 	RecordingEnabled     bool      `json:"recording_enabled" db:"recording_enabled"`
 	MessageRetentionDays int       `json:"message_retention_days" db:"message_retention_days"`
 	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+	// E2EEDisabledBy and E2EEDisabledAt record the last admin who turned E2EE off
+	E2EEDisabledBy *uuid.UUID `json:"e2ee_disabled_by,omitempty" db:"e2ee_disabled_by"`
+	E2EEDisabledAt *time.Time `json:"e2ee_disabled_at,omitempty" db:"e2ee_disabled_at"`
+	// SearchIndexing opts the conversation in or out of server-side message
+	// search; nil means the conversation has not chosen and is indexed
+	SearchIndexing *bool `json:"search_indexing,omitempty" db:"search_indexing"`
+}
+
+// IsSearchIndexable reports whether the conversation's messages may be in
+// the server-side search index. E2EE conversations never are, whatever
+// SearchIndexing says.
+func (s *ConversationSettings) IsSearchIndexable() bool {
+	if s.IsE2EEEnabled {
+		return false
+	}
+	return s.SearchIndexing == nil || *s.SearchIndexing
+}
+
+// ConversationRetention is a conversation's message retention setting.
+// RetentionDays is 0 when the conversation has no setting of its own.
+type ConversationRetention struct {
+	ConversationID uuid.UUID
+	RetentionDays  int
 }
 
 // ConversationCreate represents data to create a new conversation
@@ -72,3 +108,65 @@ type ConversationResponse struct {
 	UnreadCount    int                   `json:"unread_count"`
 	CreatedAt      time.Time             `json:"created_at"`
 }
+
+// Participant moderation errors
+var (
+	ErrNotConversationAdmin = NewError("NOT_CONVERSATION_ADMIN", "Only conversation admins can perform this action")
+	ErrParticipantMuted     = NewError("PARTICIPANT_MUTED", "You are muted in this conversation")
+	ErrCannotMuteAdmin      = NewError("CANNOT_MUTE_ADMIN", "Conversation admins cannot be muted")
+	ErrInvalidMuteDuration  = NewError("INVALID_MUTE_DURATION", "Mute must end in the future")
+	ErrE2EEDowngradeBlocked = NewError("E2EE_DOWNGRADE_BLOCKED", "End-to-end encryption cannot be disabled once enabled")
+	ErrSearchIndexingE2EE   = NewError("SEARCH_INDEXING_E2EE", "End-to-end encrypted conversations cannot be search indexed")
+)
+
+// MutedError is returned when a muted participant tries to post. It matches
+// ErrParticipantMuted with errors.Is.
+type MutedError struct {
+	Until time.Time
+}
+
+// Error implements the error interface
+func (e *MutedError) Error() string {
+	return fmt.Sprintf("%s for another %s", ErrParticipantMuted.Message, e.Remaining(time.Now()).Round(time.Second))
+}
+
+// Is reports whether target is ErrParticipantMuted
+func (e *MutedError) Is(target error) bool {
+	return target == ErrParticipantMuted
+}
+
+// Remaining returns how long the mute still lasts at now
+func (e *MutedError) Remaining(now time.Time) time.Duration {
+	if d := e.Until.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// ErrConversationLimitReached is returned when a user is already in the
+// maximum number of conversations
+var ErrConversationLimitReached = NewError("CONVERSATION_LIMIT_REACHED", "The conversation limit has been reached")
+
+// ConversationLimitError names the users who cannot join another
+// conversation. It matches ErrConversationLimitReached with errors.Is.
+type ConversationLimitError struct {
+	Limit   int
+	UserIDs []uuid.UUID
+}
+
+// Error implements the error interface
+func (e *ConversationLimitError) Error() string {
+	return fmt.Sprintf("%s: users %v are already in %d conversations", ErrConversationLimitReached.Message, e.UserIDs, e.Limit)
+}
+
+// Is reports whether target is ErrConversationLimitReached
+func (e *ConversationLimitError) Is(target error) bool {
+	return target == ErrConversationLimitReached
+}
+
+// ConversationEvent is an entry in a conversation's replayable event stream.
+// ID is the stream entry ID, which clients keep as their replay cursor.
+type ConversationEvent struct {
+	ID      string
+	Payload []byte
+}
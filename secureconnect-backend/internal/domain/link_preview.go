@@ -0,0 +1,20 @@
+package domain
+
+// LinkPreview is the unfurled summary of a URL shared in a message
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	// Unavailable marks a cached failure so the URL is not fetched again until it expires
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// Link preview errors
+var (
+	ErrLinkPreviewRateLimited = NewError("LINK_PREVIEW_RATE_LIMITED", "Too many link preview requests")
+	ErrLinkPreviewBusy        = NewError("LINK_PREVIEW_BUSY", "Link previews are temporarily unavailable")
+	ErrLinkPreviewBlocked     = NewError("LINK_PREVIEW_BLOCKED", "This URL cannot be previewed")
+	ErrLinkPreviewUnavailable = NewError("LINK_PREVIEW_UNAVAILABLE", "No preview is available for this URL")
+)
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,23 @@ type Message struct {
 	MessageType    string                 `json:"message_type" cql:"message_type"`   // text, image, video, file
 	Metadata       map[string]interface{} `json:"metadata,omitempty" cql:"metadata"` // AI results or file info
 	SentAt         time.Time              `json:"sent_at" cql:"sent_at"`
+	Seq            int64                  `json:"seq,omitempty" cql:"seq"`               // Per-conversation send order; 0 when unassigned
+	EditedAt       *time.Time             `json:"edited_at,omitempty" cql:"edited_at"`   // Set when the sender edits the content
+	DeletedAt      *time.Time             `json:"deleted_at,omitempty" cql:"deleted_at"` // Set when deleted for everyone; content is cleared
+	DeletedBy      *uuid.UUID             `json:"deleted_by,omitempty" cql:"deleted_by"` // The sender or admin who deleted it
+}
+
+// IsDeleted reports whether the message was deleted for everyone and only
+// its tombstone remains
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// MessageKey locates a message within its conversation by its clustering
+// columns, so several messages can be read in one query
+type MessageKey struct {
+	SentAt    time.Time
+	MessageID uuid.UUID
 }
 
 // MessageCreate represents data needed to send a message
@@ -40,6 +58,86 @@ type MessageResponse struct {
 	MessageType    string                 `json:"message_type"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"` // AI metadata only if is_encrypted=false
 	SentAt         time.Time              `json:"sent_at"`
+	Seq            int64                  `json:"seq,omitempty"`           // Total order within the conversation
+	ClientMsgID    string                 `json:"client_msg_id,omitempty"` // Echoed so the client can match the ack to its send
+	EditedAt       *time.Time             `json:"edited_at,omitempty"`
+	Deleted        bool                   `json:"deleted"` // Tombstone of a message deleted for everyone; content is empty
+	DeletedAt      *time.Time             `json:"deleted_at,omitempty"`
+	Reactions      []ReactionCount        `json:"reactions,omitempty"` // Most used first
+}
+
+// Reaction is one user's emoji reaction to a message
+type Reaction struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Emoji          string    `json:"emoji"`
+}
+
+// ReactionCount is how many users reacted to a message with one emoji
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	// ReactedByMe is set when the user reading the message is one of them
+	ReactedByMe bool `json:"reacted_by_me,omitempty"`
+}
+
+// MessageSearchHit is a message matched by a server-side search, with its
+// relevance to the query
+type MessageSearchHit struct {
+	Message *Message
+	Rank    float64
+}
+
+// MessageSearchCursor is the position of the last result of a search page.
+// Results are ordered by rank, then newest first.
+type MessageSearchCursor struct {
+	Rank      float64
+	SentAt    time.Time
+	MessageID uuid.UUID
+}
+
+// Message editing errors
+var (
+	ErrMessageNotFound      = NewError("MESSAGE_NOT_FOUND", "Message not found")
+	ErrNotMessageSender     = NewError("NOT_MESSAGE_SENDER", "Only the sender can edit this message")
+	ErrEditWindowExpired    = NewError("EDIT_WINDOW_EXPIRED", "This message is too old to edit")
+	ErrEncryptedMessageEdit = NewError("ENCRYPTED_MESSAGE_NOT_EDITABLE", "Encrypted messages cannot be edited")
+	ErrMessageDeleted       = NewError("MESSAGE_DELETED", "This message has been deleted")
+)
+
+// Message reaction errors
+var (
+	ErrReactionNotAllowed = NewError("REACTION_NOT_ALLOWED", "This emoji cannot be used as a reaction")
+)
+
+// Message deletion errors
+var (
+	ErrMessageDeleteForbidden = NewError("MESSAGE_DELETE_FORBIDDEN", "Only the sender or a conversation admin can delete this message for everyone")
+)
+
+// ErrMessageBlocked is matched by every *BlockedMessageError
+var ErrMessageBlocked = NewError("MESSAGE_BLOCKED", "Message was blocked by content moderation")
+
+// BlockedMessageError is returned when content moderation rejects a message.
+// It matches ErrMessageBlocked with errors.Is.
+type BlockedMessageError struct {
+	Reason string
+	// Quarantined is set when the message was kept for review instead of dropped
+	Quarantined bool
+}
+
+// Error implements the error interface
+func (e *BlockedMessageError) Error() string {
+	if e.Reason == "" {
+		return ErrMessageBlocked.Message
+	}
+	return fmt.Sprintf("%s: %s", ErrMessageBlocked.Message, e.Reason)
+}
+
+// Is reports whether target is ErrMessageBlocked
+func (e *BlockedMessageError) Is(target error) bool {
+	return target == ErrMessageBlocked
 }
 
 // Cassandra-related errors
@@ -67,3 +165,12 @@ func NewCassandraError(code, message string) *CassandraError {
 func (e *CassandraError) Error() string {
 	return e.Message
 }
+
+// ReadPosition is the furthest message a user has read in a conversation.
+// Messages are ordered by SentAt, so the position only moves forward in time.
+type ReadPosition struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+	SentAt         time.Time `json:"sent_at"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxPinnedMessages caps how many messages one conversation can pin
+// when no limit is configured
+const DefaultMaxPinnedMessages = 50
+
+// PinnedMessage records that a participant pinned a message of a conversation.
+// Pins are listed by ascending DisplayOrder; a new pin goes first.
+// Maps to CockroachDB pinned_messages table
+type PinnedMessage struct {
+	ConversationID uuid.UUID `json:"conversation_id" db:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id" db:"message_id"`
+	PinnedBy       uuid.UUID `json:"pinned_by" db:"pinned_by"`
+	PinnedAt       time.Time `json:"pinned_at" db:"pinned_at"`
+	DisplayOrder   int       `json:"display_order" db:"display_order"`
+
+	// MessageSentAt locates the message in Cassandra; nil for pins stored
+	// before it was recorded
+	MessageSentAt *time.Time `json:"-" db:"message_sent_at"`
+}
+
+// PinnedMessageResponse is a pinned message returned to clients
+type PinnedMessageResponse struct {
+	Message      *MessageResponse `json:"message"`
+	PinnedBy     uuid.UUID        `json:"pinned_by"`
+	PinnedAt     time.Time        `json:"pinned_at"`
+	DisplayOrder int              `json:"display_order"`
+}
+
+// Pin errors
+var (
+	ErrPinLimitReached  = NewError("PIN_LIMIT_REACHED", "This conversation already has the maximum number of pinned messages")
+	ErrMessageNotPinned = NewError("MESSAGE_NOT_PINNED", "Message is not pinned")
+	ErrUnpinForbidden   = NewError("UNPIN_FORBIDDEN", "Only a conversation admin can unpin a message pinned by someone else")
+	ErrInvalidPinOrder  = NewError("INVALID_PIN_ORDER", "The new order must list every pinned message exactly once")
+)
@@ -36,6 +36,21 @@ type Poll struct {
 	UserVoteOptions []uuid.UUID `json:"user_vote_options,omitempty"` // Computed field for current user
 }
 
+// ActivePollSummary is a compact view of a poll shown on conversation views
+type ActivePollSummary struct {
+	PollID    uuid.UUID  `json:"poll_id"`
+	Question  string     `json:"question"`
+	PollType  PollType   `json:"poll_type"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ActivePolls is how many polls of a conversation are active, and the latest of them
+type ActivePolls struct {
+	Count  int
+	Latest *ActivePollSummary
+}
+
 // PollCreate represents data needed to create a new poll
 type PollCreate struct {
 	ConversationID  uuid.UUID  `json:"conversation_id" binding:"required"`
@@ -212,11 +227,13 @@ var (
 	ErrPollExpired               = NewError("POLL_EXPIRED", "Poll has expired")
 	ErrPollClosed                = NewError("POLL_CLOSED", "Poll is closed")
 	ErrAlreadyVoted              = NewError("ALREADY_VOTED", "You have already voted on this poll")
+	ErrVoteInProgress            = NewError("VOTE_IN_PROGRESS", "Another vote of yours on this poll is still being processed")
 	ErrMultipleOptionsNotAllowed = NewError("MULTIPLE_OPTIONS_NOT_ALLOWED", "Multiple options not allowed for single-choice polls")
 	ErrAtLeastOneOptionRequired  = NewError("AT_LEAST_ONE_OPTION_REQUIRED", "At least one option must be selected")
 	ErrOptionNotFound            = NewError("OPTION_NOT_FOUND", "Poll option not found")
 	ErrNotPollCreator            = NewError("NOT_POLL_CREATOR", "Only the poll creator can perform this action")
 	ErrInvalidPollType           = NewError("INVALID_POLL_TYPE", "Invalid poll type")
+	ErrNotParticipant            = NewError("NOT_PARTICIPANT", "You are not a participant in this conversation")
 )
 
 // Error represents a domain error
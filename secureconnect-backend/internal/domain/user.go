@@ -57,3 +57,20 @@ func (u *User) ToResponse() *UserResponse {
 		CreatedAt:   u.CreatedAt,
 	}
 }
+
+// UserTOTP is a user's two-factor authentication enrollment
+type UserTOTP struct {
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Secret string    `json:"-" db:"secret"` // base32
+	// RecoveryCodeHashes are SHA-256 hashes of the unused recovery codes
+	RecoveryCodeHashes []string `json:"-" db:"recovery_code_hashes"`
+	// LastUsedStep is the time step of the last accepted code
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+}
+
+// Enabled reports whether the enrollment is confirmed and enforced at login
+func (t *UserTOTP) Enabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
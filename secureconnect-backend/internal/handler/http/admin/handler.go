@@ -1,31 +1,43 @@
 package admin
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/admin"
+	"secureconnect-backend/internal/service/auth"
+	"secureconnect-backend/pkg/audit"
+	"secureconnect-backend/pkg/jsonbind"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/pagination"
 	"secureconnect-backend/pkg/response"
 )
 
 // Handler handles admin HTTP requests
 type Handler struct {
 	adminService *admin.Service
+	authService  *auth.Service
 }
 
 // NewHandler creates a new admin handler
-func NewHandler(adminService *admin.Service) *Handler {
+func NewHandler(adminService *admin.Service, authService *auth.Service) *Handler {
 	return &Handler{
 		adminService: adminService,
+		authService:  authService,
 	}
 }
 
-// requireAdmin is middleware to check if user is admin
-func (h *Handler) requireAdmin() gin.HandlerFunc {
+// RequireAdmin is middleware to check if user is admin
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userIDVal, exists := c.Get("user_id")
 		if !exists {
@@ -52,6 +64,13 @@ func (h *Handler) requireAdmin() gin.HandlerFunc {
 	}
 }
 
+// CheckAdmin answers 204 to admins; RequireAdmin rejects everyone else. The
+// gateway calls it before serving admin endpoints it answers itself.
+// GET /v1/admin/check
+func (h *Handler) CheckAdmin(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
 // GetSystemStats retrieves system statistics
 // GET /v1/admin/stats
 func (h *Handler) GetSystemStats(c *gin.Context) {
@@ -65,7 +84,8 @@ func (h *Handler) GetSystemStats(c *gin.Context) {
 }
 
 // GetUsers retrieves list of users
-// GET /v1/admin/users
+// GET /v1/admin/users?limit=50&offset=0
+// GET /v1/admin/users?limit=50&cursor=... (keyset pagination, newest first)
 func (h *Handler) GetUsers(c *gin.Context) {
 	// Parse query parameters
 	req := &domain.UserListRequest{
@@ -87,6 +107,10 @@ func (h *Handler) GetUsers(c *gin.Context) {
 		}
 	}
 
+	if cursor := c.Query("cursor"); cursor != "" {
+		req.Cursor = cursor
+	}
+
 	if search := c.Query("search"); search != "" {
 		req.Search = search
 	}
@@ -105,6 +129,10 @@ func (h *Handler) GetUsers(c *gin.Context) {
 
 	users, err := h.adminService.GetUsers(c.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) || errors.Is(err, pagination.ErrOffsetTooLarge) || errors.Is(err, pagination.ErrNegativeOffset) {
+			response.ValidationError(c, err.Error())
+			return
+		}
 		response.InternalError(c, "Failed to get users")
 		return
 	}
@@ -116,7 +144,7 @@ func (h *Handler) GetUsers(c *gin.Context) {
 // POST /v1/admin/users/ban
 func (h *Handler) BanUser(c *gin.Context) {
 	var req domain.BanUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -156,7 +184,7 @@ func (h *Handler) BanUser(c *gin.Context) {
 // POST /v1/admin/users/unban
 func (h *Handler) UnbanUser(c *gin.Context) {
 	var req domain.UnbanUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -219,6 +247,10 @@ func (h *Handler) GetAuditLogs(c *gin.Context) {
 
 	logs, totalCount, err := h.adminService.GetAuditLogs(c.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, pagination.ErrOffsetTooLarge) || errors.Is(err, pagination.ErrNegativeOffset) {
+			response.ValidationError(c, err.Error())
+			return
+		}
 		response.InternalError(c, "Failed to get audit logs")
 		return
 	}
@@ -231,6 +263,141 @@ func (h *Handler) GetAuditLogs(c *gin.Context) {
 	})
 }
 
+// SearchAuditEvents searches the security audit log (logins, password
+// changes, blocks, ...), newest first. Every filter is optional; from and to
+// are RFC 3339. Pages continue from the previous page's next_cursor, and
+// format=csv downloads every match instead. Each search is itself audited.
+// GET /v1/admin/audit?user_id=&event_type=&success=&ip_address=&from=&to=&limit=50&cursor=
+// GET /v1/admin/audit?format=csv&...
+func (h *Handler) SearchAuditEvents(c *gin.Context) {
+	adminIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	adminID, ok := adminIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	req := admin.AuditSearchRequest{
+		AdminID:   adminID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Filter: audit.EventQuery{
+			EventType: audit.AuditEventType(c.Query("event_type")),
+			IPAddress: c.Query("ip_address"),
+		},
+	}
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			response.ValidationError(c, "Invalid user_id")
+			return
+		}
+		req.Filter.UserID = userID
+	}
+
+	if successStr := c.Query("success"); successStr != "" {
+		success, err := strconv.ParseBool(successStr)
+		if err != nil {
+			response.ValidationError(c, "Invalid success, expected true or false")
+			return
+		}
+		req.Filter.Success = &success
+	}
+
+	for param, dst := range map[string]*time.Time{"from": &req.Filter.From, "to": &req.Filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				response.ValidationError(c, fmt.Sprintf("Invalid %s, expected an RFC 3339 time", param))
+				return
+			}
+			*dst = t
+		}
+	}
+
+	switch format := c.Query("format"); format {
+	case "", "json":
+	case "csv":
+		h.exportAuditEvents(c, req)
+		return
+	default:
+		response.ValidationError(c, "Invalid format, expected json or csv")
+		return
+	}
+
+	limit := admin.DefaultAuditEventLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = min(l, admin.MaxAuditEventLimit)
+		}
+	}
+
+	page, err := h.adminService.SearchAuditEvents(c.Request.Context(), req, limit, c.Query("cursor"))
+	if err != nil {
+		auditSearchError(c, err, "Failed to search audit events")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"events":      page.Events,
+		"limit":       limit,
+		"next_cursor": page.NextCursor,
+	})
+}
+
+// exportAuditEvents streams the audit events matching req as a CSV download
+func (h *Handler) exportAuditEvents(c *gin.Context, req admin.AuditSearchRequest) {
+	w := &csvResponseWriter{c: c, filename: fmt.Sprintf("audit-events-%s.csv", time.Now().UTC().Format("20060102T150405Z"))}
+	err := h.adminService.ExportAuditEvents(c.Request.Context(), req, w)
+	if err == nil {
+		return
+	}
+
+	if w.started {
+		// Headers are gone; all we can do is cut the stream short
+		logger.Error("Audit event export failed mid-stream",
+			zap.String("admin_id", req.AdminID.String()),
+			zap.Error(err))
+		return
+	}
+	auditSearchError(c, err, "Failed to export audit events")
+}
+
+// auditSearchError writes the response for an audit search error
+func auditSearchError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, admin.ErrInvalidAuditRange), errors.Is(err, pagination.ErrInvalidCursor):
+		response.ValidationError(c, err.Error())
+	case errors.Is(err, admin.ErrAuditEventsNotConfigured), errors.Is(err, audit.ErrSearchNotSupported):
+		response.Error(c, http.StatusNotImplemented, "AUDIT_EVENTS_NOT_CONFIGURED", err.Error())
+	default:
+		response.InternalError(c, fallback)
+	}
+}
+
+// csvResponseWriter sets the download headers on the first write, so errors
+// raised before any output can still be sent as a normal JSON error
+type csvResponseWriter struct {
+	c        *gin.Context
+	filename string
+	started  bool
+}
+
+func (w *csvResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "text/csv; charset=utf-8")
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
 // GetSystemHealth retrieves system health status
 // GET /v1/admin/health
 func (h *Handler) GetSystemHealth(c *gin.Context) {
@@ -253,3 +420,153 @@ func (h *Handler) GetSystemHealth(c *gin.Context) {
 		"data":    health,
 	})
 }
+
+// GetAccountLock returns the failed login attempts and lock state of an account
+// GET /v1/admin/users/:email/lock
+func (h *Handler) GetAccountLock(c *gin.Context) {
+	adminID, ok := adminIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.authService.GetLockStatus(c.Request.Context(), c.Param("email"))
+	if err != nil {
+		response.InternalError(c, "Failed to get account lock status")
+		return
+	}
+
+	targetType, targetID, details := lockAuditTarget(status)
+	h.audit(c, adminID, "view_account_lock", targetType, targetID, details)
+
+	response.Success(c, http.StatusOK, status)
+}
+
+// ClearAccountLock unlocks an account and resets its failed login attempts
+// DELETE /v1/admin/users/:email/lock
+func (h *Handler) ClearAccountLock(c *gin.Context) {
+	adminID, ok := adminIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	email := c.Param("email")
+	status, err := h.authService.GetLockStatus(c.Request.Context(), email)
+	if err != nil {
+		response.InternalError(c, "Failed to get account lock status")
+		return
+	}
+
+	if err := h.authService.ClearLock(c.Request.Context(), email); err != nil {
+		response.InternalError(c, "Failed to clear account lock")
+		return
+	}
+
+	targetType, targetID, details := lockAuditTarget(status)
+	h.audit(c, adminID, "clear_account_lock", targetType, targetID, details)
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Account lock cleared successfully",
+	})
+}
+
+// ListPushTokens returns a user's push tokens, masked, for notification support
+// GET /v1/admin/push-tokens/:userId
+func (h *Handler) ListPushTokens(c *gin.Context) {
+	adminID, ok := adminIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		response.ValidationError(c, "Invalid user ID")
+		return
+	}
+
+	tokens, err := h.adminService.ListPushTokens(c.Request.Context(), userID)
+	if err != nil {
+		pushTokensError(c, err, "Failed to get push tokens")
+		return
+	}
+
+	h.audit(c, adminID, "view_push_tokens", "user", userID, fmt.Sprintf("%d tokens", len(tokens)))
+
+	response.Success(c, http.StatusOK, gin.H{
+		"push_tokens": tokens,
+	})
+}
+
+// PurgePushTokens deletes all of a user's push tokens, forcing their devices to register again
+// DELETE /v1/admin/push-tokens/:userId
+func (h *Handler) PurgePushTokens(c *gin.Context) {
+	adminID, ok := adminIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		response.ValidationError(c, "Invalid user ID")
+		return
+	}
+
+	purged, err := h.adminService.PurgePushTokens(c.Request.Context(), userID)
+	if err != nil {
+		pushTokensError(c, err, "Failed to purge push tokens")
+		return
+	}
+
+	h.audit(c, adminID, "purge_push_tokens", "user", userID, fmt.Sprintf("%d tokens", purged))
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Push tokens purged successfully",
+		"purged":  purged,
+	})
+}
+
+// pushTokensError writes the response for a push token admin error
+func pushTokensError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, admin.ErrPushTokensNotConfigured) {
+		response.Error(c, http.StatusNotImplemented, "PUSH_TOKENS_NOT_CONFIGURED", err.Error())
+		return
+	}
+	response.InternalError(c, fallback)
+}
+
+// adminIDFromContext extracts the authenticated admin ID, writing an error response if missing
+func adminIDFromContext(c *gin.Context) (uuid.UUID, bool) {
+	adminIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return uuid.Nil, false
+	}
+
+	adminID, ok := adminIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return adminID, true
+}
+
+// lockAuditTarget returns what an account lock audit entry refers to: the
+// account when the email belongs to one, otherwise a name-based UUID of the
+// email, so the entry neither points at the nil user nor stores the address
+func lockAuditTarget(status *auth.LockStatus) (targetType string, targetID uuid.UUID, details string) {
+	if status.UserID != nil {
+		return "user", *status.UserID, status.Email
+	}
+	return "email", uuid.NewSHA1(uuid.NameSpaceURL, []byte("mailto:"+strings.ToLower(status.Email))), ""
+}
+
+// audit records an admin action; failures are logged and do not fail the request
+func (h *Handler) audit(c *gin.Context, adminID uuid.UUID, action, targetType string, targetID uuid.UUID, details string) {
+	err := h.adminService.LogAction(c.Request.Context(), adminID, action, targetType, targetID,
+		c.ClientIP(), c.GetHeader("User-Agent"), details)
+	if err != nil {
+		logger.Warn("Failed to record admin audit log",
+			zap.String("action", action),
+			zap.String("admin_id", adminID.String()),
+			zap.Error(err))
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 
 	"secureconnect-backend/internal/service/auth"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -36,6 +38,8 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// RememberMe keeps an active session signed in for longer
+	RememberMe bool `json:"remember_me"`
 }
 
 // RefreshTokenRequest represents refresh token request
@@ -58,7 +62,7 @@ type ResetPasswordRequest struct {
 // POST /v1/auth/register
 func (h *Handler) Register(c *gin.Context) {
 	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -69,6 +73,8 @@ func (h *Handler) Register(c *gin.Context) {
 		Username:    req.Username,
 		Password:    req.Password,
 		DisplayName: req.DisplayName,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
 	})
 
 	if err != nil {
@@ -78,6 +84,11 @@ func (h *Handler) Register(c *gin.Context) {
 			response.Conflict(c, errMsg)
 			return
 		}
+		var weak *auth.PasswordStrengthError
+		if errors.As(err, &weak) {
+			response.Error(c, http.StatusBadRequest, weak.Code(), weak.Message)
+			return
+		}
 		if strings.Contains(errMsg, "validation failed") {
 			response.ValidationError(c, errMsg)
 			return
@@ -109,7 +120,7 @@ func (h *Handler) Register(c *gin.Context) {
 // POST /v1/auth/login
 func (h *Handler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -119,24 +130,35 @@ func (h *Handler) Login(c *gin.Context) {
 
 	// Call service with IP
 	output, err := h.authService.Login(c.Request.Context(), &auth.LoginInput{
-		Email:    req.Email,
-		Password: req.Password,
-		IP:       clientIP, // NEW: Pass IP to service
+		Email:      req.Email,
+		Password:   req.Password,
+		IP:         clientIP, // NEW: Pass IP to service
+		UserAgent:  c.Request.UserAgent(),
+		RememberMe: req.RememberMe,
 	})
 
 	if err != nil {
-		if err.Error() == "invalid credentials" {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
 			response.Unauthorized(c, "Invalid email or password")
 			return
 		}
-		if err.Error() == "account temporarily locked due to too many failed attempts" {
-			response.Unauthorized(c, "Account temporarily locked due to too many failed attempts. Please try again later.")
+		if errors.Is(err, auth.ErrAccountLocked) {
+			response.Error(c, http.StatusLocked, "ACCOUNT_LOCKED", "Account temporarily locked due to too many failed attempts. Please try again later.")
 			return
 		}
 		response.InternalError(c, "Failed to login")
 		return
 	}
 
+	// A second factor is required before any tokens are issued
+	if output.MFARequired {
+		response.Success(c, http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    output.MFAToken,
+		})
+		return
+	}
+
 	// Return response
 	response.Success(c, http.StatusOK, gin.H{
 		"user":          output.User,
@@ -149,7 +171,7 @@ func (h *Handler) Login(c *gin.Context) {
 // POST /v1/auth/refresh
 func (h *Handler) RefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -160,6 +182,14 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 	})
 
 	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			response.Error(c, http.StatusUnauthorized, "REFRESH_TOKEN_REUSED", "Refresh token was already used; all sessions have been signed out")
+			return
+		}
+		if errors.Is(err, auth.ErrSessionExpired) {
+			response.Error(c, http.StatusUnauthorized, "SESSION_EXPIRED", "Session has reached its maximum lifetime; please log in again")
+			return
+		}
 		response.Unauthorized(c, "Invalid or expired refresh token")
 		return
 	}
@@ -194,7 +224,7 @@ func (h *Handler) Logout(c *gin.Context) {
 		var req struct {
 			SessionID string `json:"session_id"`
 		}
-		c.ShouldBindJSON(&req)
+		jsonbind.Bind(c, &req)
 		sessionID = req.SessionID
 	}
 
@@ -207,7 +237,13 @@ func (h *Handler) Logout(c *gin.Context) {
 	}
 
 	// Call service
-	if err := h.authService.Logout(c.Request.Context(), sessionID, userID, tokenString); err != nil {
+	if err := h.authService.Logout(c.Request.Context(), &auth.LogoutInput{
+		SessionID: sessionID,
+		UserID:    userID,
+		Token:     tokenString,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}); err != nil {
 		response.InternalError(c, "Failed to logout")
 		return
 	}
@@ -240,7 +276,7 @@ func (h *Handler) GetProfile(c *gin.Context) {
 // POST /v1/auth/password-reset/request
 func (h *Handler) RequestPasswordReset(c *gin.Context) {
 	var req RequestPasswordResetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -265,7 +301,7 @@ func (h *Handler) RequestPasswordReset(c *gin.Context) {
 // POST /v1/auth/password-reset/confirm
 func (h *Handler) ResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -274,9 +310,16 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 	err := h.authService.ResetPassword(c.Request.Context(), &auth.ResetPasswordInput{
 		Token:       req.Token,
 		NewPassword: req.NewPassword,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
 	})
 
 	if err != nil {
+		var weak *auth.PasswordStrengthError
+		if errors.As(err, &weak) {
+			response.Error(c, http.StatusBadRequest, weak.Code(), weak.Message)
+			return
+		}
 		response.Unauthorized(c, "Invalid or expired reset token")
 		return
 	}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/pkg/jwt"
+)
+
+// JWKS serves the public keys access tokens can be verified with, so other
+// services can validate tokens without holding the signing key. The set is
+// returned as-is (RFC 7517), not wrapped in the API response envelope.
+// GET /.well-known/jwks.json
+func JWKS(manager *jwt.JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, manager.JWKS())
+	}
+}
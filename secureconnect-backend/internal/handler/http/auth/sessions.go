@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/internal/service/auth"
+	"secureconnect-backend/pkg/response"
+)
+
+// GetSessions lists where the current user is logged in
+// GET /v1/auth/sessions
+func (h *Handler) GetSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.GetSessions(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, "Failed to get sessions")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession logs one of the current user's sessions out
+// DELETE /v1/auth/sessions/:id
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			response.NotFound(c, "Session not found")
+			return
+		}
+		response.InternalError(c, "Failed to revoke session")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// LogoutAll signs the current user out of every device
+// POST /v1/auth/logout-all
+func (h *Handler) LogoutAll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.authService.LogoutAll(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, "Failed to logout")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message":          "Logged out of all devices",
+		"sessions_revoked": count,
+	})
+}
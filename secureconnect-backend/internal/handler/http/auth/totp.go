@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/service/auth"
+	"secureconnect-backend/pkg/jsonbind"
+	"secureconnect-backend/pkg/response"
+)
+
+// TOTPCodeRequest carries an authenticator or recovery code
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyTOTPRequest completes a login that required a second factor
+type VerifyTOTPRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+	// RememberMe repeats the choice made at login
+	RememberMe bool `json:"remember_me"`
+}
+
+// EnableTOTP starts two-factor enrollment
+// POST /v1/auth/totp/enable
+func (h *Handler) EnableTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.authService.EnableTOTP(c.Request.Context(), userID)
+	if err != nil {
+		totpError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"secret":         output.Secret,
+		"qr_url":         output.QRURL,
+		"recovery_codes": output.RecoveryCodes,
+	})
+}
+
+// ConfirmTOTP turns on a pending enrollment
+// POST /v1/auth/totp/confirm
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req TOTPCodeRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := h.authService.ConfirmTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		totpError(c, err, "Failed to confirm two-factor authentication")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled",
+	})
+}
+
+// DisableTOTP turns off two-factor authentication
+// POST /v1/auth/totp/disable
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req TOTPCodeRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := h.authService.DisableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		totpError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// VerifyTOTP exchanges an mfa_token from Login and a code for tokens
+// POST /v1/auth/login/totp
+func (h *Handler) VerifyTOTP(c *gin.Context) {
+	var req VerifyTOTPRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	output, err := h.authService.VerifyTOTP(c.Request.Context(), &auth.VerifyTOTPInput{
+		MFAToken:   req.MFAToken,
+		Code:       req.Code,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		RememberMe: req.RememberMe,
+	})
+	if err != nil {
+		totpError(c, err, "Failed to verify two-factor code")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"user":          output.User,
+		"access_token":  output.AccessToken,
+		"refresh_token": output.RefreshToken,
+	})
+}
+
+// currentUserID reads the authenticated user, writing an error response if absent
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return uuid.Nil, false
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// totpError maps two-factor errors to responses
+func totpError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTOTPCode), errors.Is(err, auth.ErrInvalidMFAToken):
+		response.Unauthorized(c, err.Error())
+	case errors.Is(err, auth.ErrTOTPAlreadyEnabled), errors.Is(err, auth.ErrTOTPNotEnabled):
+		response.Error(c, http.StatusConflict, "TOTP_STATE_CONFLICT", err.Error())
+	case errors.Is(err, auth.ErrTOTPNotConfigured):
+		response.Error(c, http.StatusNotImplemented, "TOTP_NOT_CONFIGURED", err.Error())
+	case errors.Is(err, auth.ErrAccountLocked):
+		response.Error(c, http.StatusLocked, "ACCOUNT_LOCKED", "Too many wrong two-factor codes. Please try again later.")
+	default:
+		response.InternalError(c, fallback)
+	}
+}
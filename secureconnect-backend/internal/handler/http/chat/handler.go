@@ -2,18 +2,30 @@ package chat
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
+	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/chat"
+	"secureconnect-backend/pkg/jsonbind"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/pagination"
 	"secureconnect-backend/pkg/response"
 )
 
 // Handler handles chat HTTP requests
 type Handler struct {
-	chatService *chat.Service
+	chatService   *chat.Service
+	linkPreviewer *chat.LinkPreviewer
 }
 
 // NewHandler creates a new chat handler
@@ -23,6 +35,11 @@ func NewHandler(chatService *chat.Service) *Handler {
 	}
 }
 
+// SetLinkPreviewer enables the link preview endpoint
+func (h *Handler) SetLinkPreviewer(previewer *chat.LinkPreviewer) {
+	h.linkPreviewer = previewer
+}
+
 // SendMessageRequest represents send message request
 type SendMessageRequest struct {
 	ConversationID string                 `json:"conversation_id" binding:"required,uuid"`
@@ -30,20 +47,54 @@ type SendMessageRequest struct {
 	IsEncrypted    bool                   `json:"is_encrypted"`
 	MessageType    string                 `json:"message_type" binding:"required,oneof=text image video file"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	// ClientMsgID makes retries of POST /v1/messages safe; batch items ignore it
+	ClientMsgID string `json:"client_msg_id,omitempty" binding:"omitempty,max=64"`
+}
+
+// SendMessagesRequest represents a batched send request.
+// Every item must pass the same validation as a single send; max mirrors chat.MaxSendBatchSize.
+type SendMessagesRequest struct {
+	Messages []SendMessageRequest `json:"messages" binding:"required,min=1,max=100,dive"`
 }
 
 // GetMessagesQuery represents query parameters for listing messages
 type GetMessagesQuery struct {
 	ConversationID string `form:"conversation_id" binding:"required,uuid"`
 	Limit          int    `form:"limit"`
-	PageState      string `form:"page_state"` // Base64 encoded
+	Cursor         string `form:"cursor"`     // next_cursor of the previous page
+	PageState      string `form:"page_state"` // Deprecated: standard base64 of the same state; cursor takes precedence
+}
+
+// maxMessageCursorBytes bounds a decoded history cursor. Cassandra page
+// states are a few dozen bytes.
+const maxMessageCursorBytes = 1024
+
+// encodeMessageCursor makes a Cassandra page state safe to pass in a URL
+func encodeMessageCursor(pageState []byte) string {
+	if len(pageState) == 0 {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(pageState)
+}
+
+// decodeMessageCursor returns the page state of a cursor made by
+// encodeMessageCursor, or of a legacy standard base64 page_state
+func decodeMessageCursor(cursor string, encoding *base64.Encoding) ([]byte, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	pageState, err := encoding.DecodeString(cursor)
+	if err != nil || len(pageState) > maxMessageCursorBytes {
+		return nil, errors.New("invalid cursor")
+	}
+	return pageState, nil
 }
 
 // SendMessage handles sending a new message
 // POST /v1/messages
 func (h *Handler) SendMessage(c *gin.Context) {
 	var req SendMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -76,18 +127,133 @@ func (h *Handler) SendMessage(c *gin.Context) {
 		IsEncrypted:    req.IsEncrypted,
 		MessageType:    req.MessageType,
 		Metadata:       req.Metadata,
+		ClientMsgID:    req.ClientMsgID,
 	})
 
 	if err != nil {
-		response.InternalError(c, "Failed to send message")
+		var muted *domain.MutedError
+		switch {
+		case errors.Is(err, chat.ErrSendInProgress):
+			c.Header("Retry-After", "1")
+			response.Error(c, http.StatusConflict, "SEND_IN_PROGRESS", err.Error())
+		case errors.As(err, &muted):
+			retryAfter := int64(math.Ceil(muted.Remaining(time.Now()).Seconds()))
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			response.Error(c, http.StatusForbidden, domain.ErrParticipantMuted.Code, muted.Error())
+		case errors.Is(err, domain.ErrNotParticipant):
+			response.Forbidden(c, "You are not a participant in this conversation")
+		case errors.Is(err, domain.ErrMessageBlocked):
+			response.Error(c, http.StatusUnprocessableEntity, domain.ErrMessageBlocked.Code, err.Error())
+		default:
+			response.InternalError(c, "Failed to send message")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, output.Message)
+}
+
+// BotMessageRequest represents a message posted by a bot
+type BotMessageRequest struct {
+	Content     string                 `json:"content" binding:"required"`
+	MessageType string                 `json:"message_type" binding:"omitempty,oneof=text image video file"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// PostBotMessage posts a message as the bot identified by the
+// "Authorization: Bot <token>" header, into the bot's conversation
+// POST /v1/bots/messages
+func (h *Handler) PostBotMessage(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bot ")
+	if !ok || token == "" {
+		response.Unauthorized(c, "Bot token required")
+		return
+	}
+
+	var req BotMessageRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	output, err := h.chatService.PostBotMessage(c.Request.Context(), &chat.BotMessageInput{
+		Token:       token,
+		Content:     req.Content,
+		MessageType: req.MessageType,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidBotToken):
+			response.Error(c, http.StatusUnauthorized, domain.ErrInvalidBotToken.Code, domain.ErrInvalidBotToken.Message)
+		case errors.Is(err, domain.ErrBotE2EEConversation):
+			response.Error(c, http.StatusConflict, domain.ErrBotE2EEConversation.Code, domain.ErrBotE2EEConversation.Message)
+		case errors.Is(err, domain.ErrMessageBlocked):
+			response.Error(c, http.StatusUnprocessableEntity, domain.ErrMessageBlocked.Code, err.Error())
+		default:
+			response.InternalError(c, "Failed to send message")
+		}
 		return
 	}
 
 	response.Success(c, http.StatusCreated, output.Message)
 }
 
+// SendMessages handles sending a batch of messages
+// POST /v1/messages/batch
+func (h *Handler) SendMessages(c *gin.Context) {
+	var req SendMessagesRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	// Get sender ID from context (set by auth middleware)
+	senderIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+
+	senderID, ok := senderIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	inputs := make([]*chat.SendMessageInput, len(req.Messages))
+	for i, item := range req.Messages {
+		inputs[i] = &chat.SendMessageInput{
+			ConversationID: uuid.MustParse(item.ConversationID), // validated by the uuid binding tag
+			SenderID:       senderID,
+			Content:        item.Content,
+			IsEncrypted:    item.IsEncrypted,
+			MessageType:    item.MessageType,
+			Metadata:       item.Metadata,
+		}
+	}
+
+	// Call service
+	output, err := h.chatService.SendMessages(c.Request.Context(), inputs)
+	if err != nil {
+		response.InternalError(c, "Failed to send messages")
+		return
+	}
+
+	status := http.StatusCreated
+	if output.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	response.Success(c, status, gin.H{
+		"results":   output.Results,
+		"succeeded": output.Succeeded,
+		"failed":    output.Failed,
+	})
+}
+
 // GetMessages retrieves conversation messages
-// GET /v1/messages?conversation_id=uuid&limit=20&page_state=base64
+// GET /v1/messages?conversation_id=uuid&limit=20&cursor=
 func (h *Handler) GetMessages(c *gin.Context) {
 	var query GetMessagesQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
@@ -110,29 +276,46 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		query.Limit = 100 // Max limit
 	}
 
-	// Decode page state
+	// Decode the cursor into Cassandra's page state
 	var pageState []byte
-	if query.PageState != "" {
-		pageState, err = base64.StdEncoding.DecodeString(query.PageState)
-		if err != nil {
-			response.ValidationError(c, "Invalid page state")
-			return
-		}
+	if query.Cursor != "" {
+		pageState, err = decodeMessageCursor(query.Cursor, base64.RawURLEncoding)
+	} else {
+		pageState, err = decodeMessageCursor(query.PageState, base64.StdEncoding)
+	}
+	if err != nil {
+		response.ValidationError(c, "Invalid cursor")
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
 	}
 
 	// Call service
 	output, err := h.chatService.GetMessages(c.Request.Context(), &chat.GetMessagesInput{
 		ConversationID: conversationID,
+		UserID:         userID,
 		Limit:          query.Limit,
 		PageState:      pageState,
 	})
 
 	if err != nil {
+		if errors.Is(err, domain.ErrNotParticipant) {
+			response.Forbidden(c, "You are not a participant in this conversation")
+			return
+		}
+		if errors.Is(err, chat.ErrMessagesUnavailable) {
+			c.Header("Retry-After", "5")
+			response.Error(c, http.StatusServiceUnavailable, "MESSAGES_UNAVAILABLE", "Messages are temporarily unavailable, please retry")
+			return
+		}
 		response.InternalError(c, "Failed to get messages")
 		return
 	}
 
-	// Encode next page state
+	// Encode next page state; next_page_state is kept for older clients
 	var nextPageStateEncoded string
 	if len(output.NextPageState) > 0 {
 		nextPageStateEncoded = base64.StdEncoding.EncodeToString(output.NextPageState)
@@ -140,8 +323,10 @@ func (h *Handler) GetMessages(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, gin.H{
 		"messages":        output.Messages,
+		"next_cursor":     encodeMessageCursor(output.NextPageState),
 		"next_page_state": nextPageStateEncoded,
 		"has_more":        output.HasMore,
+		"degraded":        output.Degraded,
 	})
 }
 
@@ -152,7 +337,7 @@ func (h *Handler) UpdatePresence(c *gin.Context) {
 		Online bool `json:"online"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -180,3 +365,653 @@ func (h *Handler) UpdatePresence(c *gin.Context) {
 		"message": "Presence updated",
 	})
 }
+
+// ExportConversation streams an export of a conversation the caller participates in
+// GET /v1/conversations/:id/export?format=json|text
+func (h *Handler) ExportConversation(c *gin.Context) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	format := c.DefaultQuery("format", chat.ExportFormatJSON)
+	contentType, ext := "application/json", "json"
+	if format == chat.ExportFormatText {
+		contentType, ext = "text/plain; charset=utf-8", "txt"
+	}
+
+	w := &exportResponseWriter{
+		c:           c,
+		contentType: contentType,
+		filename:    fmt.Sprintf("conversation-%s.%s", conversationID, ext),
+	}
+	err = h.chatService.ExportConversation(c.Request.Context(), conversationID, userID, format, w)
+	if err == nil {
+		return
+	}
+
+	if w.started {
+		// Headers are gone; all we can do is cut the stream short
+		logger.Error("Conversation export failed mid-stream",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return
+	}
+	switch {
+	case errors.Is(err, chat.ErrInvalidExportFormat):
+		response.ValidationError(c, err.Error())
+	case errors.Is(err, domain.ErrNotParticipant):
+		response.Forbidden(c, "You are not a participant in this conversation")
+	default:
+		response.InternalError(c, "Failed to export conversation")
+	}
+}
+
+// MarkReadRequest is the furthest message the caller has seen in a conversation
+type MarkReadRequest struct {
+	MessageID string    `json:"message_id" binding:"required,uuid"`
+	SentAt    time.Time `json:"sent_at" binding:"required"`
+}
+
+// MarkRead records the caller's read position in a conversation. Rapid calls
+// are coalesced, so the position is stored shortly after the response.
+// POST /v1/conversations/:id/read
+func (h *Handler) MarkRead(c *gin.Context) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	var req MarkReadRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	err = h.chatService.MarkRead(c.Request.Context(), &chat.MarkReadInput{
+		ConversationID: conversationID,
+		UserID:         userID,
+		MessageID:      uuid.MustParse(req.MessageID),
+		SentAt:         req.SentAt,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrNotParticipant) {
+			response.Forbidden(c, "You are not a participant in this conversation")
+			return
+		}
+		if errors.Is(err, chat.ErrReadPositionInFuture) {
+			response.ValidationError(c, err.Error())
+			return
+		}
+		response.InternalError(c, "Failed to mark conversation read")
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, gin.H{
+		"message_id": req.MessageID,
+	})
+}
+
+// EditMessageRequest represents an edit of a sent message
+type EditMessageRequest struct {
+	ConversationID string `json:"conversation_id" binding:"required,uuid"`
+	Content        string `json:"content" binding:"required"`
+}
+
+// EditMessage replaces the content of one of the caller's messages
+// PATCH /v1/messages/:id
+func (h *Handler) EditMessage(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid message ID")
+		return
+	}
+
+	var req EditMessageRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	message, err := h.chatService.EditMessage(c.Request.Context(), uuid.MustParse(req.ConversationID), messageID, userID, req.Content)
+	if err != nil {
+		var muted *domain.MutedError
+		switch {
+		case errors.As(err, &muted):
+			response.Error(c, http.StatusForbidden, domain.ErrParticipantMuted.Code, muted.Error())
+		case errors.Is(err, domain.ErrNotParticipant):
+			response.Forbidden(c, "You are not a participant in this conversation")
+		case errors.Is(err, domain.ErrMessageNotFound):
+			response.NotFound(c, domain.ErrMessageNotFound.Message)
+		case errors.Is(err, domain.ErrNotMessageSender):
+			response.Error(c, http.StatusForbidden, domain.ErrNotMessageSender.Code, domain.ErrNotMessageSender.Message)
+		case errors.Is(err, domain.ErrMessageDeleted):
+			response.Error(c, http.StatusConflict, domain.ErrMessageDeleted.Code, domain.ErrMessageDeleted.Message)
+		case errors.Is(err, domain.ErrEditWindowExpired):
+			response.Error(c, http.StatusConflict, domain.ErrEditWindowExpired.Code, domain.ErrEditWindowExpired.Message)
+		case errors.Is(err, domain.ErrEncryptedMessageEdit):
+			response.Error(c, http.StatusUnprocessableEntity, domain.ErrEncryptedMessageEdit.Code, domain.ErrEncryptedMessageEdit.Message)
+		case errors.Is(err, domain.ErrMessageBlocked):
+			response.Error(c, http.StatusUnprocessableEntity, domain.ErrMessageBlocked.Code, err.Error())
+		default:
+			response.InternalError(c, "Failed to edit message")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, message)
+}
+
+// DeleteMessageQuery selects the message's conversation and who it is deleted for
+type DeleteMessageQuery struct {
+	ConversationID string `form:"conversation_id" binding:"required,uuid"`
+	Scope          string `form:"scope" binding:"omitempty,oneof=me everyone"`
+}
+
+// DeleteMessage deletes a message for the caller only (scope=me, the
+// default) or for every participant (scope=everyone)
+// DELETE /v1/messages/:id?conversation_id=uuid&scope=me|everyone
+func (h *Handler) DeleteMessage(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid message ID")
+		return
+	}
+
+	var query DeleteMessageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	conversationID := uuid.MustParse(query.ConversationID)
+	if query.Scope == "everyone" {
+		err = h.chatService.DeleteForEveryone(c.Request.Context(), conversationID, messageID, userID)
+	} else {
+		err = h.chatService.DeleteForMe(c.Request.Context(), conversationID, messageID, userID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotParticipant):
+			response.Forbidden(c, "You are not a participant in this conversation")
+		case errors.Is(err, domain.ErrMessageNotFound):
+			response.NotFound(c, domain.ErrMessageNotFound.Message)
+		case errors.Is(err, domain.ErrMessageDeleteForbidden):
+			response.Error(c, http.StatusForbidden, domain.ErrMessageDeleteForbidden.Code, domain.ErrMessageDeleteForbidden.Message)
+		default:
+			response.InternalError(c, "Failed to delete message")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Message deleted successfully",
+	})
+}
+
+// ReactionRequest represents a reaction to a message
+type ReactionRequest struct {
+	ConversationID string `json:"conversation_id" binding:"required,uuid"`
+	Emoji          string `json:"emoji" binding:"required"`
+}
+
+// ReactionQuery selects the conversation of the message a reaction is removed from
+type ReactionQuery struct {
+	ConversationID string `form:"conversation_id" binding:"required,uuid"`
+}
+
+// AddReaction reacts to a message with an emoji
+// POST /v1/messages/:id/reactions
+func (h *Handler) AddReaction(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid message ID")
+		return
+	}
+
+	var req ReactionRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.AddReaction(c.Request.Context(), uuid.MustParse(req.ConversationID), messageID, userID, req.Emoji); err != nil {
+		reactionError(c, err, "Failed to add reaction")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Reaction added",
+	})
+}
+
+// RemoveReaction takes back one of the caller's reactions to a message
+// DELETE /v1/messages/:id/reactions/:emoji?conversation_id=uuid
+func (h *Handler) RemoveReaction(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid message ID")
+		return
+	}
+
+	var query ReactionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.RemoveReaction(c.Request.Context(), uuid.MustParse(query.ConversationID), messageID, userID, c.Param("emoji")); err != nil {
+		reactionError(c, err, "Failed to remove reaction")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Reaction removed",
+	})
+}
+
+// reactionError maps reaction errors to responses
+func reactionError(c *gin.Context, err error, fallback string) {
+	var muted *domain.MutedError
+	switch {
+	case errors.As(err, &muted):
+		response.Error(c, http.StatusForbidden, domain.ErrParticipantMuted.Code, muted.Error())
+	case errors.Is(err, domain.ErrNotParticipant):
+		response.Forbidden(c, "You are not a participant in this conversation")
+	case errors.Is(err, domain.ErrMessageNotFound):
+		response.NotFound(c, domain.ErrMessageNotFound.Message)
+	case errors.Is(err, domain.ErrMessageDeleted):
+		response.Error(c, http.StatusConflict, domain.ErrMessageDeleted.Code, domain.ErrMessageDeleted.Message)
+	case errors.Is(err, domain.ErrReactionNotAllowed):
+		response.Error(c, http.StatusUnprocessableEntity, domain.ErrReactionNotAllowed.Code, domain.ErrReactionNotAllowed.Message)
+	default:
+		response.InternalError(c, fallback)
+	}
+}
+
+// PinRequest selects the message to pin
+type PinRequest struct {
+	MessageID string `json:"message_id" binding:"required,uuid"`
+}
+
+// PinMessage pins a message of the conversation
+// POST /v1/conversations/:id/pins
+func (h *Handler) PinMessage(c *gin.Context) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	var req PinRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	pin, err := h.chatService.PinMessage(c.Request.Context(), conversationID, uuid.MustParse(req.MessageID), userID)
+	if err != nil {
+		pinError(c, err, "Failed to pin message")
+		return
+	}
+
+	response.Success(c, http.StatusOK, pin)
+}
+
+// UnpinMessage unpins a message of the conversation
+// DELETE /v1/conversations/:id/pins/:message_id
+func (h *Handler) UnpinMessage(c *gin.Context) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+	messageID, err := uuid.Parse(c.Param("message_id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid message ID")
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.UnpinMessage(c.Request.Context(), conversationID, messageID, userID); err != nil {
+		pinError(c, err, "Failed to unpin message")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Message unpinned",
+	})
+}
+
+// ReorderPinsRequest lists every pinned message of the conversation in the new order
+type ReorderPinsRequest struct {
+	MessageIDs []string `json:"message_ids" binding:"required,min=1,dive,uuid"`
+}
+
+// ReorderPins sets the display order of the conversation's pinned messages
+// PUT /v1/conversations/:id/pins/order
+func (h *Handler) ReorderPins(c *gin.Context) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	var req ReorderPinsRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	messageIDs := make([]uuid.UUID, len(req.MessageIDs))
+	for i, id := range req.MessageIDs {
+		messageIDs[i] = uuid.MustParse(id)
+	}
+	if err := h.chatService.ReorderPins(c.Request.Context(), conversationID, userID, messageIDs); err != nil {
+		pinError(c, err, "Failed to reorder pins")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Pins reordered",
+	})
+}
+
+// GetPinnedMessages lists the conversation's pinned messages in display order
+// GET /v1/conversations/:id/pins
+func (h *Handler) GetPinnedMessages(c *gin.Context) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	pins, err := h.chatService.GetPinnedMessages(c.Request.Context(), conversationID, userID)
+	if err != nil {
+		pinError(c, err, "Failed to get pinned messages")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"pins": pins,
+	})
+}
+
+// pinError maps pinned message errors to responses
+func pinError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrNotParticipant):
+		response.Forbidden(c, "You are not a participant in this conversation")
+	case errors.Is(err, domain.ErrMessageNotFound):
+		response.NotFound(c, domain.ErrMessageNotFound.Message)
+	case errors.Is(err, domain.ErrMessageNotPinned):
+		response.Error(c, http.StatusNotFound, domain.ErrMessageNotPinned.Code, domain.ErrMessageNotPinned.Message)
+	case errors.Is(err, domain.ErrMessageDeleted):
+		response.Error(c, http.StatusConflict, domain.ErrMessageDeleted.Code, domain.ErrMessageDeleted.Message)
+	case errors.Is(err, domain.ErrPinLimitReached):
+		response.Error(c, http.StatusConflict, domain.ErrPinLimitReached.Code, domain.ErrPinLimitReached.Message)
+	case errors.Is(err, domain.ErrUnpinForbidden):
+		response.Error(c, http.StatusForbidden, domain.ErrUnpinForbidden.Code, domain.ErrUnpinForbidden.Message)
+	case errors.Is(err, domain.ErrNotConversationAdmin):
+		response.Error(c, http.StatusForbidden, domain.ErrNotConversationAdmin.Code, domain.ErrNotConversationAdmin.Message)
+	case errors.Is(err, domain.ErrInvalidPinOrder):
+		response.Error(c, http.StatusUnprocessableEntity, domain.ErrInvalidPinOrder.Code, domain.ErrInvalidPinOrder.Message)
+	case errors.Is(err, chat.ErrPinsUnavailable):
+		response.Error(c, http.StatusNotImplemented, "PINS_NOT_CONFIGURED", err.Error())
+	default:
+		response.InternalError(c, fallback)
+	}
+}
+
+// SearchMessagesQuery represents query parameters for message search
+type SearchMessagesQuery struct {
+	Query          string `form:"q" binding:"required,max=256"`
+	ConversationID string `form:"conversation_id" binding:"omitempty,uuid"`
+	Limit          int    `form:"limit"`
+	Cursor         string `form:"cursor"`
+}
+
+// SearchMessages searches the caller's plaintext messages, most relevant
+// first. Messages of E2EE conversations are never found.
+// GET /v1/messages/search?q=&conversation_id=&limit=20&cursor=
+func (h *Handler) SearchMessages(c *gin.Context) {
+	var query SearchMessagesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var conversationID *uuid.UUID
+	if query.ConversationID != "" {
+		id, err := uuid.Parse(query.ConversationID)
+		if err != nil {
+			response.ValidationError(c, "Invalid conversation ID")
+			return
+		}
+		conversationID = &id
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+	if query.Limit > 100 {
+		query.Limit = 100 // Max limit
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.chatService.SearchMessages(c.Request.Context(), userID, query.Query, conversationID, query.Limit, query.Cursor)
+	if err != nil {
+		switch {
+		case errors.Is(err, pagination.ErrInvalidCursor):
+			response.ValidationError(c, err.Error())
+		case errors.Is(err, domain.ErrNotParticipant):
+			response.Forbidden(c, "You are not a participant in this conversation")
+		case errors.Is(err, chat.ErrSearchUnavailable):
+			response.Error(c, http.StatusNotImplemented, "SEARCH_NOT_CONFIGURED", err.Error())
+		default:
+			response.InternalError(c, "Failed to search messages")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"messages":    output.Messages,
+		"next_cursor": output.NextCursor,
+		"has_more":    output.HasMore,
+	})
+}
+
+// MarkAllRead clears the caller's unread counts in every conversation
+// POST /v1/conversations/read-all
+func (h *Handler) MarkAllRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.MarkAllRead(c.Request.Context(), userID); err != nil {
+		unreadError(c, err, "Failed to mark conversations read")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"total_unread": 0,
+	})
+}
+
+// GetTotalUnread returns the caller's unread messages across all conversations,
+// the count shown on the app badge
+// GET /v1/conversations/unread
+func (h *Handler) GetTotalUnread(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	total, err := h.chatService.GetTotalUnread(c.Request.Context(), userID)
+	if err != nil {
+		unreadError(c, err, "Failed to get unread count")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"total_unread": total,
+	})
+}
+
+// currentUserID reads the authenticated user, writing an error response if absent
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return uuid.Nil, false
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// unreadError writes the response for an unread count error
+func unreadError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, chat.ErrUnreadNotConfigured) {
+		response.Error(c, http.StatusNotImplemented, "UNREAD_NOT_CONFIGURED", err.Error())
+		return
+	}
+	response.InternalError(c, fallback)
+}
+
+// GetLinkPreview unfurls a URL shared in a message
+// GET /v1/link-preview?url=
+func (h *Handler) GetLinkPreview(c *gin.Context) {
+	if h.linkPreviewer == nil {
+		response.NotFound(c, "Link previews are disabled")
+		return
+	}
+
+	rawURL := c.Query("url")
+	if rawURL == "" {
+		response.ValidationError(c, "url is required")
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	preview, err := h.linkPreviewer.Preview(c.Request.Context(), userID, rawURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrLinkPreviewRateLimited):
+			c.Header("Retry-After", "60")
+			response.Error(c, http.StatusTooManyRequests, domain.ErrLinkPreviewRateLimited.Code, domain.ErrLinkPreviewRateLimited.Message)
+		case errors.Is(err, domain.ErrLinkPreviewBusy):
+			c.Header("Retry-After", "5")
+			response.Error(c, http.StatusServiceUnavailable, domain.ErrLinkPreviewBusy.Code, domain.ErrLinkPreviewBusy.Message)
+		case errors.Is(err, domain.ErrLinkPreviewBlocked):
+			response.Error(c, http.StatusBadRequest, domain.ErrLinkPreviewBlocked.Code, domain.ErrLinkPreviewBlocked.Message)
+		case errors.Is(err, domain.ErrLinkPreviewUnavailable):
+			response.Error(c, http.StatusNotFound, domain.ErrLinkPreviewUnavailable.Code, domain.ErrLinkPreviewUnavailable.Message)
+		default:
+			response.InternalError(c, "Failed to get link preview")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, preview)
+}
+
+// exportResponseWriter sets the download headers on the first write, so errors
+// raised before any output can still be sent as a normal JSON error
+type exportResponseWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *exportResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 
 	"secureconnect-backend/internal/service/chat"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -73,7 +74,7 @@ type MarkMessagesAsReadRequest struct {
 // POST /v1/messages/read
 func (h *ExtendedHandler) MarkMessagesAsRead(c *gin.Context) {
 	var req MarkMessagesAsReadRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -116,72 +117,6 @@ func (h *ExtendedHandler) MarkMessagesAsRead(c *gin.Context) {
 	})
 }
 
-// SearchMessagesQuery represents search query parameters
-type SearchMessagesQuery struct {
-	ConversationID string `form:"conversation_id" binding:"required,uuid"`
-	Query          string `form:"query" binding:"required,min=1"`
-	Limit          int    `form:"limit"`
-	PageState      string `form:"page_state"`
-}
-
-// SearchMessages searches for messages in a conversation
-// GET /v1/messages/search
-func (h *ExtendedHandler) SearchMessages(c *gin.Context) {
-	var query SearchMessagesQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		response.ValidationError(c, err.Error())
-		return
-	}
-
-	// Get user ID from context
-	userIDVal, exists := c.Get("user_id")
-	if !exists {
-		response.Unauthorized(c, "Not authenticated")
-		return
-	}
-
-	userID, ok := userIDVal.(uuid.UUID)
-	if !ok {
-		response.InternalError(c, "Invalid user ID")
-		return
-	}
-
-	// Parse conversation ID
-	conversationID, err := uuid.Parse(query.ConversationID)
-	if err != nil {
-		response.ValidationError(c, "Invalid conversation ID")
-		return
-	}
-
-	// Set default limit
-	if query.Limit == 0 {
-		query.Limit = 20
-	}
-	if query.Limit > 100 {
-		query.Limit = 100
-	}
-
-	// Search messages
-	output, err := h.chatService.SearchMessages(c.Request.Context(), &chat.SearchMessagesInput{
-		ConversationID: conversationID,
-		UserID:         userID,
-		Query:          query.Query,
-		Limit:          query.Limit,
-		PageState:      []byte(query.PageState),
-	})
-
-	if err != nil {
-		response.InternalError(c, "Failed to search messages")
-		return
-	}
-
-	response.Success(c, http.StatusOK, gin.H{
-		"messages":        output.Messages,
-		"next_page_state": output.NextPageState,
-		"has_more":        output.HasMore,
-	})
-}
-
 // ForwardMessageRequest represents forward message request
 type ForwardMessageRequest struct {
 	MessageID      string `json:"message_id" binding:"required,uuid"`
@@ -192,7 +127,7 @@ type ForwardMessageRequest struct {
 // POST /v1/messages/forward
 func (h *ExtendedHandler) ForwardMessage(c *gin.Context) {
 	var req ForwardMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
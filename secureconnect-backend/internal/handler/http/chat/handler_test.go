@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/internal/service/chat"
+	"secureconnect-backend/pkg/logger"
+)
+
+// pagedMessages is a message history that pages like Cassandra: the page
+// state is an offset, and a full page always has a page state, even when no
+// messages follow it
+type pagedMessages struct {
+	messages []*domain.Message
+}
+
+func (p *pagedMessages) GetByConversation(ctx context.Context, conversationID uuid.UUID, limit int, pageState []byte) ([]*domain.Message, []byte, error) {
+	offset := 0
+	if len(pageState) > 0 {
+		if len(pageState) != 8 {
+			return nil, nil, errors.New("bad page state")
+		}
+		offset = int(binary.BigEndian.Uint64(pageState))
+	}
+	end := offset + limit
+	if end > len(p.messages) {
+		return p.messages[offset:], nil, nil
+	}
+	next := make([]byte, 8)
+	binary.BigEndian.PutUint64(next, uint64(end))
+	return p.messages[offset:end], next, nil
+}
+
+func (p *pagedMessages) GetHiddenMessageIDs(ctx context.Context, conversationID, userID uuid.UUID, messageIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	return nil, nil
+}
+
+func (p *pagedMessages) Save(ctx context.Context, message *domain.Message) error         { return nil }
+func (p *pagedMessages) SaveBatch(ctx context.Context, messages []*domain.Message) error { return nil }
+func (p *pagedMessages) Update(ctx context.Context, message *domain.Message) error       { return nil }
+func (p *pagedMessages) GetByID(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.Message, error) {
+	return nil, domain.ErrMessageNotFound
+}
+func (p *pagedMessages) GetByKeys(ctx context.Context, conversationID uuid.UUID, keys []domain.MessageKey) ([]*domain.Message, error) {
+	return nil, nil
+}
+func (p *pagedMessages) SoftDelete(ctx context.Context, conversationID, messageID, byUserID uuid.UUID) error {
+	return nil
+}
+func (p *pagedMessages) HideForUser(ctx context.Context, conversationID, messageID, userID uuid.UUID) error {
+	return nil
+}
+
+// members answers IsMember for every conversation from a fixed set of users
+type members map[uuid.UUID]bool
+
+func (m members) IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error) {
+	return m[userID], nil
+}
+
+type messagesPage struct {
+	Data struct {
+		Messages   []*domain.MessageResponse `json:"messages"`
+		NextCursor string                    `json:"next_cursor"`
+		HasMore    bool                      `json:"has_more"`
+	} `json:"data"`
+}
+
+func newMessagesRouter(t *testing.T, count int) (*gin.Engine, uuid.UUID) {
+	t.Helper()
+	return newMessagesRouterFor(t, count, false)
+}
+
+// newMessagesRouterFor serves a history of count messages to a reader who is
+// a participant unless outsider is set
+func newMessagesRouterFor(t *testing.T, count int, outsider bool) (*gin.Engine, uuid.UUID) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger.InitDefault("test")
+
+	conversationID := uuid.New()
+	history := &pagedMessages{}
+	for i := 0; i < count; i++ {
+		history.messages = append(history.messages, &domain.Message{
+			MessageID:      uuid.New(),
+			ConversationID: conversationID,
+			Content:        "message",
+			SentAt:         time.Now().Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	readerID := uuid.New()
+	service := chat.NewService(history, nil, nil, nil, nil, nil)
+	service.SetMembershipChecker(members{readerID: !outsider})
+	handler := NewHandler(service)
+	router := gin.New()
+	router.GET("/v1/messages", func(c *gin.Context) {
+		c.Set("user_id", readerID)
+		handler.GetMessages(c)
+	})
+	return router, conversationID
+}
+
+func getMessages(t *testing.T, router *gin.Engine, query url.Values) (*httptest.ResponseRecorder, *messagesPage) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/messages?"+query.Encode(), nil))
+	page := &messagesPage{}
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), page))
+	}
+	return w, page
+}
+
+func TestGetMessages_CursorTraversesHistory(t *testing.T) {
+	router, conversationID := newMessagesRouter(t, 4)
+
+	seen := map[uuid.UUID]bool{}
+	var sizes []int
+	cursor := ""
+	for {
+		require.Less(t, len(sizes), 5, "the cursor must advance")
+		query := url.Values{"conversation_id": {conversationID.String()}, "limit": {"2"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		w, page := getMessages(t, router, query)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		sizes = append(sizes, len(page.Data.Messages))
+		for _, msg := range page.Data.Messages {
+			assert.False(t, seen[msg.MessageID], "no message is returned twice")
+			seen[msg.MessageID] = true
+		}
+		assert.Equal(t, page.Data.NextCursor != "", page.Data.HasMore)
+		assert.Equal(t, url.QueryEscape(page.Data.NextCursor), page.Data.NextCursor, "cursors are URL safe")
+		if !page.Data.HasMore {
+			break
+		}
+		cursor = page.Data.NextCursor
+	}
+
+	assert.Len(t, seen, 4)
+	assert.Equal(t, []int{2, 2, 0}, sizes, "a full last page is followed by an empty one")
+}
+
+func TestGetMessages_ForbiddenForNonParticipant(t *testing.T) {
+	router, conversationID := newMessagesRouterFor(t, 1, true)
+
+	w, _ := getMessages(t, router, url.Values{"conversation_id": {conversationID.String()}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetMessages_RejectsInvalidCursor(t *testing.T) {
+	router, conversationID := newMessagesRouter(t, 1)
+
+	for name, cursor := range map[string]string{
+		"not base64":     "!!!",
+		"standard alpha": "ab+/",
+		"too long":       base64.RawURLEncoding.EncodeToString(make([]byte, maxMessageCursorBytes+1)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			w, _ := getMessages(t, router, url.Values{"conversation_id": {conversationID.String()}, "cursor": {cursor}})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestSendMessages_RejectsBatchWithInvalidItem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitDefault("test")
+
+	handler := NewHandler(chat.NewService(nil, nil, nil, nil, nil, nil))
+	router := gin.New()
+	router.POST("/v1/messages/batch", func(c *gin.Context) {
+		c.Set("user_id", uuid.New())
+		handler.SendMessages(c)
+	})
+
+	valid := `{"conversation_id":"` + uuid.NewString() + `","content":"hi","message_type":"text"}`
+	for name, items := range map[string][]string{
+		"empty":             {},
+		"too many":          repeatItem(valid, chat.MaxSendBatchSize+1),
+		"missing content":   {valid, `{"conversation_id":"` + uuid.NewString() + `","message_type":"text"}`},
+		"bad message type":  {valid, `{"conversation_id":"` + uuid.NewString() + `","content":"hi","message_type":"audio"}`},
+		"bad conversation":  {valid, `{"conversation_id":"nope","content":"hi","message_type":"text"}`},
+		"long client msgid": {valid, `{"conversation_id":"` + uuid.NewString() + `","content":"hi","message_type":"text","client_msg_id":"` + strings.Repeat("x", 65) + `"}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body := `{"messages":[` + strings.Join(items, ",") + `]}`
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages/batch", strings.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func repeatItem(item string, n int) []string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = item
+	}
+	return items
+}
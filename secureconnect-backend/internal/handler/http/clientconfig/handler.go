@@ -0,0 +1,141 @@
+package clientconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/pkg/config"
+	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/response"
+)
+
+// cacheMaxAge is how long clients may reuse the document before revalidating
+const cacheMaxAge = 300
+
+// Document is the public runtime configuration served to clients. Every field
+// is copied explicitly from config so new settings are never exposed by accident.
+type Document struct {
+	MinClientVersion string          `json:"min_client_version"`
+	Features         map[string]bool `json:"features"`
+	Uploads          UploadLimits    `json:"uploads"`
+	Messages         MessageLimits   `json:"messages"`
+	ICEServers       []ICEServer     `json:"ice_servers"`
+	Maintenance      Maintenance     `json:"maintenance"`
+}
+
+// UploadLimits describes what the storage service accepts
+type UploadLimits struct {
+	MaxFileSize      int64    `json:"max_file_size"`
+	AllowedFileTypes []string `json:"allowed_file_types"`
+}
+
+// MessageLimits describes what the chat service accepts
+type MessageLimits struct {
+	MaxLength int `json:"max_length"`
+}
+
+// ICEServer is a STUN/TURN server without credentials
+type ICEServer struct {
+	URLs []string `json:"urls"`
+}
+
+// Maintenance tells clients whether the service is down for maintenance
+type Maintenance struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+// BuildDocument assembles the public document from the application config
+func BuildDocument(cfg *config.Config) Document {
+	features := make(map[string]bool, len(cfg.Client.FeatureFlags))
+	for _, flag := range cfg.Client.FeatureFlags {
+		if flag = strings.TrimSpace(flag); flag != "" {
+			features[flag] = true
+		}
+	}
+
+	fileTypes := make([]string, 0, len(constants.AllowedMIMETypes))
+	for mimeType, allowed := range constants.AllowedMIMETypes {
+		if allowed {
+			fileTypes = append(fileTypes, mimeType)
+		}
+	}
+	sort.Strings(fileTypes)
+
+	iceServers := make([]ICEServer, 0, len(cfg.Client.ICEServers))
+	for _, u := range cfg.Client.ICEServers {
+		if u = strings.TrimSpace(u); u != "" {
+			iceServers = append(iceServers, ICEServer{URLs: []string{u}})
+		}
+	}
+
+	return Document{
+		MinClientVersion: cfg.Client.MinClientVersion,
+		Features:         features,
+		Uploads: UploadLimits{
+			MaxFileSize:      constants.MaxAttachmentSize,
+			AllowedFileTypes: fileTypes,
+		},
+		Messages:   MessageLimits{MaxLength: constants.MaxMessageLength},
+		ICEServers: iceServers,
+		Maintenance: Maintenance{
+			Enabled: cfg.Client.MaintenanceMode,
+			Message: cfg.Client.MaintenanceMessage,
+		},
+	}
+}
+
+// Handler serves the client configuration document
+type Handler struct {
+	document Document
+	etag     string
+}
+
+// NewHandler creates a handler for doc. The document and its ETag are computed
+// once since the config does not change while the process runs.
+func NewHandler(doc Document) (*Handler, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal client config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	return &Handler{
+		document: doc,
+		// Weak because the response envelope carries a per-request timestamp
+		etag: fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:8])),
+	}, nil
+}
+
+// GetConfig returns the client configuration
+// GET /v1/config
+func (h *Handler) GetConfig(c *gin.Context) {
+	c.Header("ETag", h.etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", cacheMaxAge))
+
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, h.etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	response.Success(c, http.StatusOK, h.document)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag
+func etagMatches(header, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
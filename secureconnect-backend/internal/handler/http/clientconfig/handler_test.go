@@ -0,0 +1,81 @@
+package clientconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/config"
+)
+
+func TestGetConfig_PublicFieldsOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secrets := []string{"jwt-secret-0123456789abcdef0123456789", "smtp-password", "db-password", "minio-secret", "audit-hmac-key"}
+	cfg := &config.Config{
+		JWT:      config.JWTConfig{Secret: secrets[0]},
+		SMTP:     config.SMTPConfig{Password: secrets[1]},
+		Database: config.DatabaseConfig{Password: secrets[2]},
+		MinIO:    config.MinIOConfig{SecretKey: secrets[3]},
+		Audit:    config.AuditConfig{HMACKey: secrets[4]},
+		Client: config.ClientConfig{
+			MinClientVersion: "2.3.0",
+			FeatureFlags:     []string{"polls", " reactions"},
+			ICEServers:       []string{"stun:stun.example.com:3478"},
+			MaintenanceMode:  true,
+		},
+	}
+
+	h, err := NewHandler(BuildDocument(cfg))
+	require.NoError(t, err)
+	router := gin.New()
+	router.GET("/v1/config", h.GetConfig)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, secret := range secrets {
+		assert.NotContains(t, w.Body.String(), secret)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.ElementsMatch(t, []string{"min_client_version", "features", "uploads", "messages", "ice_servers", "maintenance"}, keys(body.Data))
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &struct {
+		Data *Document `json:"data"`
+	}{&doc}))
+	assert.Equal(t, "2.3.0", doc.MinClientVersion)
+	assert.Equal(t, map[string]bool{"polls": true, "reactions": true}, doc.Features)
+	assert.Contains(t, doc.Uploads.AllowedFileTypes, "image/png")
+	assert.Positive(t, doc.Uploads.MaxFileSize)
+	assert.Equal(t, []ICEServer{{URLs: []string{"stun:stun.example.com:3478"}}}, doc.ICEServers)
+	assert.True(t, doc.Maintenance.Enabled)
+
+	// A matching ETag is answered with 304
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	req := httptest.NewRequest(http.MethodGet, "/v1/config", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -1,14 +1,19 @@
 package conversation
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/conversation"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/response"
+	"secureconnect-backend/pkg/webhook"
 )
 
 // Handler handles conversation HTTP requests
@@ -29,13 +34,32 @@ type CreateConversationRequest struct {
 	Type           string   `json:"type" binding:"required,oneof=direct group"`
 	ParticipantIDs []string `json:"participant_ids" binding:"required,min=2"`
 	IsE2EEEnabled  *bool    `json:"is_e2ee_enabled"` // Optional, defaults to true
+	// InitialMessage is sent by the creator as soon as the conversation exists
+	InitialMessage *InitialMessageRequest `json:"initial_message,omitempty"`
+}
+
+// InitialMessageRequest is the first message of a new conversation
+type InitialMessageRequest struct {
+	Content     string                 `json:"content" binding:"required"`
+	IsEncrypted bool                   `json:"is_encrypted"`
+	MessageType string                 `json:"message_type" binding:"required,oneof=text image video file"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	ClientMsgID string                 `json:"client_msg_id,omitempty" binding:"omitempty,max=64"`
+}
+
+// CreateConversationResponse is the new conversation plus the result of
+// sending its initial message, when one was requested
+type CreateConversationResponse struct {
+	*domain.Conversation
+	InitialMessage      *domain.MessageResponse `json:"initial_message,omitempty"`
+	InitialMessageError string                  `json:"initial_message_error,omitempty"`
 }
 
 // CreateConversation creates a new conversation
 // POST /v1/conversations
 func (h *Handler) CreateConversation(c *gin.Context) {
 	var req CreateConversationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -64,21 +88,68 @@ func (h *Handler) CreateConversation(c *gin.Context) {
 		participantUUIDs[i] = id
 	}
 
-	// Create conversation
-	conv, err := h.conversationService.CreateConversation(c.Request.Context(), &conversation.CreateConversationInput{
+	input := &conversation.CreateConversationInput{
 		Title:         req.Title,
 		Type:          req.Type,
 		CreatedBy:     creatorID,
 		Participants:  participantUUIDs,
 		IsE2EEEnabled: req.IsE2EEEnabled,
-	})
+		Credentials:   c.GetHeader("Authorization"),
+	}
+	if req.InitialMessage != nil {
+		input.InitialMessage = &conversation.InitialMessage{
+			Content:     req.InitialMessage.Content,
+			IsEncrypted: req.InitialMessage.IsEncrypted,
+			MessageType: req.InitialMessage.MessageType,
+			Metadata:    req.InitialMessage.Metadata,
+			ClientMsgID: req.InitialMessage.ClientMsgID,
+		}
+	}
 
+	// Create conversation
+	output, err := h.conversationService.CreateConversation(c.Request.Context(), input)
+	if conversationLimitError(c, err) {
+		return
+	}
 	if err != nil {
 		response.InternalError(c, "Failed to create conversation: "+err.Error())
 		return
 	}
 
-	response.Success(c, http.StatusCreated, conv)
+	resp := CreateConversationResponse{
+		Conversation:   output.Conversation,
+		InitialMessage: output.InitialMessage,
+	}
+	if output.InitialMessageErr != nil {
+		resp.InitialMessageError = initialMessageError(output.InitialMessageErr)
+	}
+	response.Success(c, http.StatusCreated, resp)
+}
+
+// conversationLimitError responds with 409 and the users at the limit when
+// err is a conversation limit error, and reports whether it did
+func conversationLimitError(c *gin.Context, err error) bool {
+	var limit *domain.ConversationLimitError
+	if !errors.As(err, &limit) {
+		return false
+	}
+	response.ErrorWithData(c, http.StatusConflict, domain.ErrConversationLimitReached.Code, limit.Error(), gin.H{
+		"limit":    limit.Limit,
+		"user_ids": limit.UserIDs,
+	})
+	return true
+}
+
+// initialMessageError describes why the initial message was not sent without
+// exposing storage errors
+func initialMessageError(err error) string {
+	var rejected *conversation.MessageRejectedError
+	switch {
+	case errors.As(err, &rejected), errors.Is(err, conversation.ErrInitialMessageUnavailable):
+		return err.Error()
+	default:
+		return "Failed to send initial message; send it again in the conversation"
+	}
 }
 
 // GetConversations retrieves user's conversations
@@ -154,20 +225,44 @@ func (h *Handler) UpdateSettings(c *gin.Context) {
 		return
 	}
 
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
 	var req struct {
-		IsE2EEEnabled bool `json:"is_e2ee_enabled"`
+		IsE2EEEnabled  *bool `json:"is_e2ee_enabled"`
+		SearchIndexing *bool `json:"search_indexing"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
-
-	if err := h.conversationService.UpdateE2EESettings(c.Request.Context(), conversationID, req.IsE2EEEnabled); err != nil {
-		response.InternalError(c, "Failed to update settings")
+	if req.IsE2EEEnabled == nil && req.SearchIndexing == nil {
+		response.ValidationError(c, "No settings to update")
 		return
 	}
 
+	if req.IsE2EEEnabled != nil {
+		if err := h.conversationService.UpdateE2EESettings(c.Request.Context(), conversationID, userID, *req.IsE2EEEnabled); err != nil {
+			participantModerationError(c, err, "Failed to update settings")
+			return
+		}
+	}
+	if req.SearchIndexing != nil {
+		if err := h.conversationService.UpdateSearchIndexing(c.Request.Context(), conversationID, userID, *req.SearchIndexing); err != nil {
+			participantModerationError(c, err, "Failed to update settings")
+			return
+		}
+	}
+
 	response.Success(c, http.StatusOK, gin.H{
 		"message": "Settings updated successfully",
 	})
@@ -188,7 +283,7 @@ func (h *Handler) AddParticipants(c *gin.Context) {
 		UserIDs []string `json:"user_ids" binding:"required,min=1"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -204,7 +299,11 @@ func (h *Handler) AddParticipants(c *gin.Context) {
 		userUUIDs[i] = id
 	}
 
-	if err := h.conversationService.AddParticipants(c.Request.Context(), conversationID, userUUIDs); err != nil {
+	err = h.conversationService.AddParticipants(c.Request.Context(), conversationID, userUUIDs)
+	if conversationLimitError(c, err) {
+		return
+	}
+	if err != nil {
 		response.InternalError(c, "Failed to add participants")
 		return
 	}
@@ -268,7 +367,7 @@ func (h *Handler) RemoveParticipant(c *gin.Context) {
 	}
 
 	if err := h.conversationService.RemoveParticipant(c.Request.Context(), conversationID, userID, requestingUserID); err != nil {
-		response.InternalError(c, "Failed to remove participant: "+err.Error())
+		participantModerationError(c, err, "Failed to remove participant")
 		return
 	}
 
@@ -277,6 +376,252 @@ func (h *Handler) RemoveParticipant(c *gin.Context) {
 	})
 }
 
+// maxMuteDuration caps how long a single mute can last
+const maxMuteDuration = 30 * 24 * time.Hour
+
+// MuteParticipantRequest represents a mute request
+type MuteParticipantRequest struct {
+	DurationSeconds int64 `json:"duration_seconds" binding:"required,min=1"`
+}
+
+// MuteParticipant stops a participant from posting for a duration
+// POST /v1/conversations/:id/participants/:userId/mute
+func (h *Handler) MuteParticipant(c *gin.Context) {
+	conversationID, targetID, adminID, ok := participantActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req MuteParticipantRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration > maxMuteDuration {
+		response.ValidationError(c, fmt.Sprintf("duration_seconds must be at most %d", int64(maxMuteDuration.Seconds())))
+		return
+	}
+
+	until := time.Now().Add(duration).UTC()
+	if err := h.conversationService.MuteParticipant(c.Request.Context(), conversationID, targetID, until, adminID); err != nil {
+		participantModerationError(c, err, "Failed to mute participant")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"user_id":     targetID,
+		"muted_until": until,
+	})
+}
+
+// UnmuteParticipant lifts a participant's mute
+// DELETE /v1/conversations/:id/participants/:userId/mute
+func (h *Handler) UnmuteParticipant(c *gin.Context) {
+	conversationID, targetID, adminID, ok := participantActionIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.conversationService.UnmuteParticipant(c.Request.Context(), conversationID, targetID, adminID); err != nil {
+		participantModerationError(c, err, "Failed to unmute participant")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Participant unmuted successfully",
+	})
+}
+
+// CallMuteRequest represents a call mute change
+type CallMuteRequest struct {
+	Muted *bool `json:"muted" binding:"required"`
+}
+
+// SetCallMute sets whether group calls in the conversation ring the signed-in user
+// PUT /v1/conversations/:id/call-mute
+func (h *Handler) SetCallMute(c *gin.Context) {
+	conversationID, userID, ok := conversationActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req CallMuteRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := h.conversationService.SetCallsMuted(c.Request.Context(), conversationID, userID, *req.Muted); err != nil {
+		participantModerationError(c, err, "Failed to update call mute")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"calls_muted": *req.Muted,
+	})
+}
+
+// RegisterBotRequest represents a bot registration request
+type RegisterBotRequest struct {
+	WebhookURL string `json:"webhook_url" binding:"required,url,max=2048"`
+	Secret     string `json:"secret" binding:"required,min=16,max=256"`
+}
+
+// RegisterBot adds a webhook bot to a conversation. The bot token is only
+// returned in this response.
+// POST /v1/conversations/:id/bots
+func (h *Handler) RegisterBot(c *gin.Context) {
+	conversationID, adminID, ok := conversationActionIDs(c)
+	if !ok {
+		return
+	}
+
+	var req RegisterBotRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	output, err := h.conversationService.RegisterBot(c.Request.Context(), conversationID, adminID, req.WebhookURL, req.Secret)
+	if err != nil {
+		botError(c, err, "Failed to register bot")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, output)
+}
+
+// ListBots lists a conversation's bots
+// GET /v1/conversations/:id/bots
+func (h *Handler) ListBots(c *gin.Context) {
+	conversationID, adminID, ok := conversationActionIDs(c)
+	if !ok {
+		return
+	}
+
+	bots, err := h.conversationService.ListBots(c.Request.Context(), conversationID, adminID)
+	if err != nil {
+		botError(c, err, "Failed to list bots")
+		return
+	}
+	if bots == nil {
+		bots = []*domain.Bot{}
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"bots": bots,
+	})
+}
+
+// RemoveBot removes a bot from a conversation
+// DELETE /v1/conversations/:id/bots/:botId
+func (h *Handler) RemoveBot(c *gin.Context) {
+	conversationID, adminID, ok := conversationActionIDs(c)
+	if !ok {
+		return
+	}
+	botID, err := uuid.Parse(c.Param("botId"))
+	if err != nil {
+		response.ValidationError(c, "Invalid bot ID")
+		return
+	}
+
+	if err := h.conversationService.RemoveBot(c.Request.Context(), conversationID, botID, adminID); err != nil {
+		botError(c, err, "Failed to remove bot")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Bot removed successfully",
+	})
+}
+
+// botError maps bot registration errors to responses
+func botError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrBotLimitReached):
+		response.Error(c, http.StatusConflict, domain.ErrBotLimitReached.Code, domain.ErrBotLimitReached.Message)
+	case errors.Is(err, domain.ErrBotE2EEConversation):
+		response.Error(c, http.StatusConflict, domain.ErrBotE2EEConversation.Code, domain.ErrBotE2EEConversation.Message)
+	case errors.Is(err, domain.ErrBotNotFound):
+		response.NotFound(c, domain.ErrBotNotFound.Message)
+	case errors.Is(err, webhook.ErrInvalidURL), errors.Is(err, conversation.ErrInvalidBotSecret):
+		response.ValidationError(c, err.Error())
+	case errors.Is(err, conversation.ErrBotsUnavailable):
+		response.Error(c, http.StatusServiceUnavailable, "BOTS_UNAVAILABLE", "Bots are not available")
+	default:
+		participantModerationError(c, err, fallback)
+	}
+}
+
+// conversationActionIDs parses the conversation from the path and the acting
+// user from the auth context, writing an error response on failure
+func conversationActionIDs(c *gin.Context) (conversationID, actorID uuid.UUID, ok bool) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	actorIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	actorID, ok = actorIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+	}
+	return
+}
+
+// participantActionIDs parses the conversation and target user from the path
+// and the acting user from the auth context, writing an error response on failure
+func participantActionIDs(c *gin.Context) (conversationID, targetID, actorID uuid.UUID, ok bool) {
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid conversation ID")
+		return
+	}
+
+	targetID, err = uuid.Parse(c.Param("userId"))
+	if err != nil {
+		response.ValidationError(c, "Invalid user ID")
+		return
+	}
+
+	actorIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	actorID, ok = actorIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+	}
+	return
+}
+
+// participantModerationError maps moderation and E2EE policy errors to responses
+func participantModerationError(c *gin.Context, err error, fallback string) {
+	var domainErr *domain.Error
+	switch {
+	case errors.Is(err, domain.ErrNotConversationAdmin),
+		errors.Is(err, domain.ErrCannotMuteAdmin),
+		errors.Is(err, domain.ErrE2EEDowngradeBlocked),
+		errors.Is(err, domain.ErrNotParticipant):
+		errors.As(err, &domainErr)
+		response.Error(c, http.StatusForbidden, domainErr.Code, domainErr.Message)
+	case errors.Is(err, domain.ErrSearchIndexingE2EE):
+		response.Error(c, http.StatusConflict, domain.ErrSearchIndexingE2EE.Code, domain.ErrSearchIndexingE2EE.Message)
+	case errors.Is(err, domain.ErrInvalidMuteDuration):
+		response.ValidationError(c, domain.ErrInvalidMuteDuration.Message)
+	default:
+		response.InternalError(c, fallback)
+	}
+}
+
 // UpdateConversation updates conversation metadata
 // PATCH /v1/conversations/:id
 func (h *Handler) UpdateConversation(c *gin.Context) {
@@ -293,7 +638,7 @@ func (h *Handler) UpdateConversation(c *gin.Context) {
 		AvatarURL *string `json:"avatar_url"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
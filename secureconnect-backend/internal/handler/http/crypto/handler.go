@@ -8,6 +8,7 @@ import (
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/crypto"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -34,7 +35,7 @@ type UploadKeysRequest struct {
 // POST /v1/keys/upload
 func (h *Handler) UploadKeys(c *gin.Context) {
 	var req UploadKeysRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -100,7 +101,7 @@ func (h *Handler) RotateKeys(c *gin.Context) {
 		NewOneTimeKeys  []domain.OneTimeKeyUpload `json:"new_one_time_keys"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
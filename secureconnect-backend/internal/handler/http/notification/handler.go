@@ -9,6 +9,8 @@ import (
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/notification"
+	"secureconnect-backend/pkg/jsonbind"
+	"secureconnect-backend/pkg/pagination"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -56,6 +58,11 @@ func (h *Handler) GetNotifications(c *gin.Context) {
 		}
 	}
 
+	if err := pagination.ValidateOffset(offset); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
 	// Get notifications
 	result, err := h.notificationService.GetNotifications(c.Request.Context(), userID, limit, offset)
 	if err != nil {
@@ -225,7 +232,7 @@ func (h *Handler) GetPreferences(c *gin.Context) {
 func (h *Handler) UpdatePreferences(c *gin.Context) {
 	var req domain.NotificationPreferenceUpdate
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -1,6 +1,7 @@
 package poll
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/poll"
+	"secureconnect-backend/pkg/jsonbind"
+	"secureconnect-backend/pkg/pagination"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -51,13 +54,14 @@ type GetPollsQuery struct {
 	ConversationID string `form:"conversation_id" binding:"required,uuid"`
 	Page           int    `form:"page"`
 	PageSize       int    `form:"page_size"`
+	Cursor         string `form:"cursor"`
 }
 
 // CreatePoll handles creating a new poll
 // POST /v1/polls
 func (h *Handler) CreatePoll(c *gin.Context) {
 	var req CreatePollRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -94,19 +98,7 @@ func (h *Handler) CreatePoll(c *gin.Context) {
 	})
 
 	if err != nil {
-		// Handle specific errors
-		switch err.Error() {
-		case domain.ErrInvalidPollType.Error():
-			response.ValidationError(c, "Invalid poll type")
-		case domain.ErrInsufficientOptions.Error():
-			response.ValidationError(c, "At least 2 options are required")
-		case domain.ErrTooManyOptions.Error():
-			response.ValidationError(c, "Maximum 10 options allowed")
-		case "user is not a participant in this conversation":
-			response.Forbidden(c, "You are not a participant in this conversation")
-		default:
-			response.InternalError(c, "Failed to create poll")
-		}
+		respondError(c, err, "Failed to create poll")
 		return
 	}
 
@@ -143,11 +135,7 @@ func (h *Handler) GetPoll(c *gin.Context) {
 	})
 
 	if err != nil {
-		if err.Error() == "poll not found" {
-			response.NotFound(c, "Poll not found")
-			return
-		}
-		response.InternalError(c, "Failed to get poll")
+		respondError(c, err, "Failed to get poll")
 		return
 	}
 
@@ -156,6 +144,7 @@ func (h *Handler) GetPoll(c *gin.Context) {
 
 // GetPolls handles retrieving polls for a conversation
 // GET /v1/polls?conversation_id=uuid&page=1&page_size=20
+// GET /v1/polls?conversation_id=uuid&cursor=...&page_size=20
 func (h *Handler) GetPolls(c *gin.Context) {
 	var query GetPollsQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
@@ -199,20 +188,26 @@ func (h *Handler) GetPolls(c *gin.Context) {
 		ConversationID: conversationID,
 		Page:           query.Page,
 		PageSize:       query.PageSize,
+		Cursor:         query.Cursor,
 		UserID:         userID,
 	})
 
 	if err != nil {
+		if errors.Is(err, pagination.ErrOffsetTooLarge) || errors.Is(err, pagination.ErrNegativeOffset) || errors.Is(err, pagination.ErrInvalidCursor) {
+			response.ValidationError(c, err.Error())
+			return
+		}
 		response.InternalError(c, "Failed to get polls")
 		return
 	}
 
 	response.Success(c, http.StatusOK, gin.H{
-		"polls":     output.Polls,
-		"total":     output.Total,
-		"page":      output.Page,
-		"page_size": output.PageSize,
-		"has_more":  output.HasMore,
+		"polls":       output.Polls,
+		"total":       output.Total,
+		"page":        output.Page,
+		"page_size":   output.PageSize,
+		"has_more":    output.HasMore,
+		"next_cursor": output.NextCursor,
 	})
 }
 
@@ -220,7 +215,7 @@ func (h *Handler) GetPolls(c *gin.Context) {
 // POST /v1/polls/vote
 func (h *Handler) Vote(c *gin.Context) {
 	var req VoteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -264,25 +259,7 @@ func (h *Handler) Vote(c *gin.Context) {
 	})
 
 	if err != nil {
-		// Handle specific errors
-		switch err.Error() {
-		case domain.ErrPollNotFound.Error():
-			response.NotFound(c, "Poll not found")
-		case domain.ErrPollClosed.Error():
-			response.Conflict(c, "Poll is closed")
-		case domain.ErrPollExpired.Error():
-			response.Conflict(c, "Poll has expired")
-		case domain.ErrAlreadyVoted.Error():
-			response.Conflict(c, "You have already voted on this poll")
-		case domain.ErrMultipleOptionsNotAllowed.Error():
-			response.ValidationError(c, "Multiple options not allowed for single-choice polls")
-		case domain.ErrAtLeastOneOptionRequired.Error():
-			response.ValidationError(c, "At least one option must be selected")
-		case domain.ErrOptionNotFound.Error():
-			response.NotFound(c, "Poll option not found")
-		default:
-			response.InternalError(c, "Failed to cast vote")
-		}
+		respondError(c, err, "Failed to cast vote")
 		return
 	}
 
@@ -293,7 +270,7 @@ func (h *Handler) Vote(c *gin.Context) {
 // POST /v1/polls/close
 func (h *Handler) ClosePoll(c *gin.Context) {
 	var req ClosePollRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -326,15 +303,7 @@ func (h *Handler) ClosePoll(c *gin.Context) {
 	})
 
 	if err != nil {
-		// Handle specific errors
-		switch err.Error() {
-		case "poll not found":
-			response.NotFound(c, "Poll not found")
-		case domain.ErrNotPollCreator.Error():
-			response.Forbidden(c, "Only the poll creator can close this poll")
-		default:
-			response.InternalError(c, "Failed to close poll")
-		}
+		respondError(c, err, "Failed to close poll")
 		return
 	}
 
@@ -371,15 +340,7 @@ func (h *Handler) DeletePoll(c *gin.Context) {
 	})
 
 	if err != nil {
-		// Handle specific errors
-		switch err.Error() {
-		case "poll not found":
-			response.NotFound(c, "Poll not found")
-		case domain.ErrNotPollCreator.Error():
-			response.Forbidden(c, "Only the poll creator can delete this poll")
-		default:
-			response.InternalError(c, "Failed to delete poll")
-		}
+		respondError(c, err, "Failed to delete poll")
 		return
 	}
 
@@ -437,6 +398,10 @@ func (h *Handler) GetActivePolls(c *gin.Context) {
 	})
 
 	if err != nil {
+		if errors.Is(err, pagination.ErrOffsetTooLarge) || errors.Is(err, pagination.ErrNegativeOffset) {
+			response.ValidationError(c, err.Error())
+			return
+		}
 		response.InternalError(c, "Failed to get active polls")
 		return
 	}
@@ -449,3 +414,42 @@ func (h *Handler) GetActivePolls(c *gin.Context) {
 		"has_more":  output.HasMore,
 	})
 }
+
+// pollErrorStatus maps poll domain errors to HTTP status codes.
+// The second return value is false for errors that are not domain conditions.
+func pollErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, domain.ErrNotPollCreator),
+		errors.Is(err, domain.ErrNotParticipant):
+		return http.StatusForbidden, true
+	case errors.Is(err, domain.ErrPollNotFound),
+		errors.Is(err, domain.ErrOptionNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, domain.ErrAlreadyVoted),
+		errors.Is(err, domain.ErrVoteInProgress),
+		errors.Is(err, domain.ErrPollClosed),
+		errors.Is(err, domain.ErrPollExpired):
+		return http.StatusConflict, true
+	case errors.Is(err, domain.ErrInvalidPollType),
+		errors.Is(err, domain.ErrInsufficientOptions),
+		errors.Is(err, domain.ErrTooManyOptions),
+		errors.Is(err, domain.ErrMultipleOptionsNotAllowed),
+		errors.Is(err, domain.ErrAtLeastOneOptionRequired):
+		return http.StatusBadRequest, true
+	}
+	return 0, false
+}
+
+// respondError writes the error response for a poll service error,
+// falling back to a 500 with the given message for unexpected errors
+func respondError(c *gin.Context, err error, fallback string) {
+	status, ok := pollErrorStatus(err)
+	if !ok {
+		response.InternalError(c, fallback)
+		return
+	}
+
+	var domainErr *domain.Error
+	errors.As(err, &domainErr)
+	response.Error(c, status, domainErr.Code, domainErr.Message)
+}
@@ -0,0 +1,54 @@
+package poll
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/response"
+)
+
+func TestRespondError_MapsDomainErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"creator only", domain.ErrNotPollCreator, http.StatusForbidden, "NOT_POLL_CREATOR"},
+		{"not participant", domain.ErrNotParticipant, http.StatusForbidden, "NOT_PARTICIPANT"},
+		{"poll not found", domain.ErrPollNotFound, http.StatusNotFound, "POLL_NOT_FOUND"},
+		{"wrapped poll not found", fmt.Errorf("failed to get poll: %w", domain.ErrPollNotFound), http.StatusNotFound, "POLL_NOT_FOUND"},
+		{"option not found", domain.ErrOptionNotFound, http.StatusNotFound, "OPTION_NOT_FOUND"},
+		{"already voted", domain.ErrAlreadyVoted, http.StatusConflict, "ALREADY_VOTED"},
+		{"closed", domain.ErrPollClosed, http.StatusConflict, "POLL_CLOSED"},
+		{"expired", domain.ErrPollExpired, http.StatusConflict, "POLL_EXPIRED"},
+		{"invalid poll type", domain.ErrInvalidPollType, http.StatusBadRequest, "INVALID_POLL_TYPE"},
+		{"too many options", domain.ErrTooManyOptions, http.StatusBadRequest, "TOO_MANY_OPTIONS"},
+		{"unexpected", errors.New("connection reset"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			respondError(c, tt.err, "Failed")
+
+			assert.Equal(t, tt.status, w.Code)
+
+			var body response.Response
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Error.Code)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/push"
 )
@@ -59,7 +60,7 @@ func (h *Handler) RegisterToken(c *gin.Context) {
 
 	// Parse request
 	var req RegisterTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -135,7 +136,7 @@ func (h *Handler) UnregisterToken(c *gin.Context) {
 
 	// Parse request
 	var req UnregisterTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -285,7 +286,7 @@ func (h *Handler) TestNotification(c *gin.Context) {
 
 	// Parse request
 	var req TestNotificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -11,6 +11,7 @@ import (
 
 	"secureconnect-backend/internal/service/storage"
 	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/response"
 	"secureconnect-backend/pkg/sanitize"
 )
@@ -84,7 +85,7 @@ type GenerateUploadURLRequest struct {
 // POST /v1/storage/upload-url
 func (h *Handler) GenerateUploadURL(c *gin.Context) {
 	var req GenerateUploadURLRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -152,6 +153,55 @@ func (h *Handler) GenerateUploadURL(c *gin.Context) {
 	response.Success(c, http.StatusOK, output)
 }
 
+// GeneratePresignedPostRequest represents a browser upload policy request
+type GeneratePresignedPostRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+	MaxSize     int64  `json:"max_size" binding:"required,min=1"`
+}
+
+// GeneratePresignedPost creates a presigned POST policy for direct browser uploads
+// POST /v1/storage/upload-post
+func (h *Handler) GeneratePresignedPost(c *gin.Context) {
+	var req GeneratePresignedPostRequest
+	if err := jsonbind.Bind(c, &req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	if req.MaxSize > constants.MaxAttachmentSize {
+		storageUploadRejectedSizeExceeded.Inc()
+		response.ValidationError(c, fmt.Sprintf("File size exceeds maximum allowed size of %d MB", constants.MaxAttachmentSize/(1024*1024)))
+		return
+	}
+
+	if !constants.AllowedMIMETypes[req.ContentType] {
+		storageUploadRejectedInvalidMIME.Inc()
+		response.ValidationError(c, "Invalid content type: "+req.ContentType)
+		return
+	}
+	storageUploadByMIMEType.WithLabelValues(req.ContentType).Inc()
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	output, err := h.storageService.GeneratePresignedPost(c.Request.Context(), userID, req.ContentType, req.MaxSize)
+	if err != nil {
+		response.InternalError(c, "Failed to generate upload policy")
+		return
+	}
+
+	response.Success(c, http.StatusOK, output)
+}
+
 // containsPathTraversal checks if a filename contains path traversal patterns
 func containsPathTraversal(filename string) bool {
 	// Check for common path traversal patterns
@@ -252,7 +302,7 @@ func (h *Handler) CompleteUpload(c *gin.Context) {
 		FileID string `json:"file_id" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -302,3 +352,21 @@ func (h *Handler) GetQuota(c *gin.Context) {
 		"usage_percentage": float64(used) / float64(total) * 100,
 	})
 }
+
+// RecalculateUsage recomputes a user's storage usage from their stored objects
+// POST /v1/admin/storage/users/:user_id/recalculate-usage
+func (h *Handler) RecalculateUsage(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid user ID")
+		return
+	}
+
+	result, err := h.storageService.RecalculateUsage(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, "Failed to recalculate storage usage")
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
@@ -1,13 +1,17 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"secureconnect-backend/internal/service/auth"
 	"secureconnect-backend/internal/service/user"
+	"secureconnect-backend/pkg/jsonbind"
+	"secureconnect-backend/pkg/pagination"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -85,7 +89,7 @@ func (h *Handler) GetProfile(c *gin.Context) {
 // PATCH /v1/users/me
 func (h *Handler) UpdateProfile(c *gin.Context) {
 	var req UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -119,7 +123,7 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 // POST /v1/users/me/password
 func (h *Handler) ChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -144,6 +148,11 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 			response.Unauthorized(c, "Invalid old password")
 			return
 		}
+		var weak *auth.PasswordStrengthError
+		if errors.As(err, &weak) {
+			response.Error(c, http.StatusBadRequest, weak.Code(), weak.Message)
+			return
+		}
 		response.InternalError(c, "Failed to change password")
 		return
 	}
@@ -157,7 +166,7 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 // POST /v1/users/me/email
 func (h *Handler) ChangeEmail(c *gin.Context) {
 	var req ChangeEmailRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -194,7 +203,7 @@ func (h *Handler) VerifyEmail(c *gin.Context) {
 		Token string `json:"token" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -271,6 +280,11 @@ func (h *Handler) GetBlockedUsers(c *gin.Context) {
 		}
 	}
 
+	if err := pagination.ValidateOffset(offset); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
 	// Get user ID from context
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
@@ -323,13 +337,19 @@ func (h *Handler) BlockUser(c *gin.Context) {
 	}
 
 	var req BlockUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
 
 	// Block user
-	err = h.userService.BlockUser(c.Request.Context(), requestingUserID, targetUserID, req.Reason)
+	err = h.userService.BlockUser(c.Request.Context(), &user.BlockUserInput{
+		BlockerID: requestingUserID,
+		BlockedID: targetUserID,
+		Reason:    req.Reason,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
 	if err != nil {
 		response.InternalError(c, "Failed to block user")
 		return
@@ -395,6 +415,11 @@ func (h *Handler) GetFriends(c *gin.Context) {
 		}
 	}
 
+	if err := pagination.ValidateOffset(offset); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
 	// Get user ID from context
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
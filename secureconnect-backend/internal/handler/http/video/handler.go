@@ -1,12 +1,15 @@
 package video
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/service/video"
+	"secureconnect-backend/pkg/jsonbind"
 	"secureconnect-backend/pkg/response"
 )
 
@@ -33,7 +36,7 @@ type InitiateCallRequest struct {
 // POST /v1/calls/initiate
 func (h *Handler) InitiateCall(c *gin.Context) {
 	var req InitiateCallRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := jsonbind.Bind(c, &req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
@@ -78,6 +81,10 @@ func (h *Handler) InitiateCall(c *gin.Context) {
 	})
 
 	if err != nil {
+		if errors.Is(err, domain.ErrTooManyActiveCalls) {
+			response.Error(c, http.StatusTooManyRequests, domain.ErrTooManyActiveCalls.Code, domain.ErrTooManyActiveCalls.Message)
+			return
+		}
 		response.InternalError(c, "Failed to initiate call")
 		return
 	}
@@ -157,6 +164,41 @@ func (h *Handler) JoinCall(c *gin.Context) {
 	})
 }
 
+// RejectCall declines a ringing call
+// POST /v1/calls/:id/reject
+func (h *Handler) RejectCall(c *gin.Context) {
+	callID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid call ID")
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Not authenticated")
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		response.InternalError(c, "Invalid user ID")
+		return
+	}
+
+	if err := h.videoService.RejectCall(c.Request.Context(), callID, userID); err != nil {
+		if errors.Is(err, domain.ErrCallNotRinging) {
+			response.Error(c, http.StatusConflict, domain.ErrCallNotRinging.Code, domain.ErrCallNotRinging.Message)
+			return
+		}
+		response.InternalError(c, "Failed to reject call")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"message": "Call rejected",
+		"call_id": callID,
+	})
+}
+
 // GetCallStatus retrieves call information
 // GET /v1/calls/:id
 func (h *Handler) GetCallStatus(c *gin.Context) {
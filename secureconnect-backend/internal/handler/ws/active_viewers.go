@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"os"
+	"strconv"
+)
+
+// Viewer signals sent by clients when a conversation gains or loses focus.
+// They update the connection's state and are never broadcast.
+const (
+	MessageTypeFocus = "focus"
+	MessageTypeBlur  = "blur"
+)
+
+// scopeReadReceiptsFromEnv reads CHAT_SCOPE_READ_RECEIPTS; read receipts reach
+// every participant unless it is true
+func scopeReadReceiptsFromEnv() bool {
+	if val := os.Getenv("CHAT_SCOPE_READ_RECEIPTS"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return false
+}
+
+// SetScopeReadReceipts limits read receipts to active viewers, as typing
+// indicators always are. Receipts are also persisted, so clients that miss
+// the live event still see them when they open the conversation.
+func (h *ChatHub) SetScopeReadReceipts(scope bool) {
+	h.scopeReadReceipts.Store(scope)
+}
+
+// handleViewerSignal updates the client's viewing state and reports whether
+// msg was a focus or blur signal
+func (c *Client) handleViewerSignal(msg *Message) bool {
+	switch msg.Type {
+	case MessageTypeFocus:
+		c.hidden.Store(false)
+	case MessageTypeBlur:
+		c.hidden.Store(true)
+	default:
+		return false
+	}
+	return true
+}
+
+// viewersOnly reports whether message should only reach clients that are
+// currently viewing the conversation
+func (h *ChatHub) viewersOnly(message *Message) bool {
+	switch message.Type {
+	case MessageTypeTyping, MessageTypeTypingStop:
+		return true
+	case MessageTypeRead:
+		return h.scopeReadReceipts.Load()
+	}
+	return false
+}
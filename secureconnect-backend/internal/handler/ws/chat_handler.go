@@ -3,12 +3,13 @@ package ws
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,23 +18,72 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
-	"secureconnect-backend/internal/repository/cockroach"
 	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/env"
 	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
 )
 
+// MembershipChecker verifies conversation membership before a WebSocket upgrade
+type MembershipChecker interface {
+	IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error)
+}
+
 // ChatHub manages WebSocket connections for chat
 type ChatHub struct {
 	// Registered clients per conversation
 	conversations map[uuid.UUID]map[*Client]bool
 
+	// Registered clients per user, across conversations (one per connected device)
+	userClients map[uuid.UUID]map[*Client]bool
+
+	// The connection of each device to each conversation; a newer one replaces it
+	devices map[deviceKey]*Client
+
 	// Cancel functions for conversation subscriptions
 	subscriptionCancels map[uuid.UUID]context.CancelFunc
 
 	// Redis client for Pub/Sub
 	redisClient *redis.Client
 
+	// subscribe opens a pub/sub subscription; defaults to redisClient.Subscribe
+	subscribe func(ctx context.Context, channel string) pubSubConn
+
+	// Backoff bounds between resubscription attempts after a pub/sub failure
+	resubscribeMinBackoff time.Duration
+	resubscribeMaxBackoff time.Duration
+
+	// Optional message history used to replay events missed during a subscription gap
+	history MessageHistory
+
+	// Optional replayable event stream read instead of pub/sub, with the
+	// cursor of each followed conversation. streamWake starts the reader
+	// when the first conversation is followed.
+	events        EventStream
+	streamMu      sync.Mutex
+	streamCursors map[uuid.UUID]string
+	streamWake    chan struct{}
+
+	// Number of conversation subscriptions currently being retried
+	subscriptionsDown atomic.Int64
+
+	// user_left events are held back for presenceDebounce so a quick reconnect
+	// cancels both the leave and the following join
+	presenceDebounce time.Duration
+	pendingLeaves    map[presenceKey]*pendingLeave
+
+	// Users shown as typing, per conversation, until typingTTL passes
+	// without another typing event
+	typers      map[presenceKey]*typingEntry
+	typingTTL   time.Duration
+	typingStore TypingStore
+
+	// Whether read receipts, like typing indicators, only reach active viewers
+	scopeReadReceipts atomic.Bool
+
+	// relayGate checks chat messages clients send over the socket; nil relays them all
+	relayGate RelayGate
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 
@@ -46,8 +96,31 @@ type ChatHub struct {
 	maxConnections int
 	// Semaphore for limiting concurrent connections
 	semaphore chan struct{}
+
+	// maxMessageBytes caps a single client message; larger ones close the connection
+	maxMessageBytes int64
+
+	// ctx is cancelled by Stop, ending the goroutines that read from Redis;
+	// followers counts them so Stop can wait for them to return
+	ctx       context.Context
+	stop      context.CancelFunc
+	followers sync.WaitGroup
+}
+
+// DefaultChatMaxMessageBytes is the largest message a chat client may send.
+// Typing, read and draft events are small; a draft holds at most one message.
+const DefaultChatMaxMessageBytes = 64 * 1024
+
+// deviceKey identifies one device's connection to a conversation
+type deviceKey struct {
+	userID         uuid.UUID
+	deviceID       string
+	conversationID uuid.UUID
 }
 
+// maxDeviceIDLength bounds the client-chosen device_id query parameter
+const maxDeviceIDLength = 128
+
 // Client represents a WebSocket client
 type Client struct {
 	hub            *ChatHub
@@ -55,22 +128,104 @@ type Client struct {
 	send           chan []byte
 	userID         uuid.UUID
 	conversationID uuid.UUID
+	deviceID       string // Empty when the client did not identify its device
 	ctx            context.Context
 	cancel         context.CancelFunc
+
+	// hidden is set while the client has the conversation closed (blur until
+	// focus). A client starts out viewing, so one that never sends focus or
+	// blur gets every event.
+	hidden atomic.Bool
+
+	// replaced is set once a newer connection of the same device took over;
+	// the hub then only closes send when the client unregisters. Guarded by hub.mu.
+	replaced bool
+
+	// closeOnce makes sure only the first close reason is sent
+	closeOnce sync.Once
+}
+
+// closeWith ends the connection with reason; the read pump then unregisters
+// the client
+func (c *Client) closeWith(reason CloseReason) {
+	c.closeOnce.Do(func() {
+		closeConn(c.conn, reason)
+	})
 }
 
 // Message types
 const (
 	MessageTypeChat       = "chat"
 	MessageTypeTyping     = "typing"
+	MessageTypeTypingStop = "typing_stop"
 	MessageTypeRead       = "read"
 	MessageTypeUserJoined = "user_joined"
 	MessageTypeUserLeft   = "user_left"
+	MessageTypeDraft      = "draft"
+
+	// Poll events published by the poll service on the conversation channel
+	MessageTypePollCreated = "poll_created"
+	MessageTypePollVoted   = "poll_voted"
+	MessageTypePollClosed  = "poll_closed"
+
+	// Moderation events published by the conversation service on the conversation channel
+	MessageTypeParticipantMuted   = "participant_muted"
+	MessageTypeParticipantUnmuted = "participant_unmuted"
+
+	// Bot events published by the conversation service when an admin adds or removes a bot
+	MessageTypeBotAdded   = "bot_added"
+	MessageTypeBotRemoved = "bot_removed"
+
+	// MessageTypeMessageEdited is published by the chat service when a sender
+	// edits a message; clients replace the content of MessageID in place
+	MessageTypeMessageEdited = "message_edited"
+
+	// MessageTypeMessageDeleted is published by the chat service when a
+	// message is deleted for everyone; clients replace MessageID with a tombstone
+	MessageTypeMessageDeleted = "message_deleted"
+
+	// MessageTypeReactionAdded and MessageTypeReactionRemoved are published by
+	// the chat service when SenderID adds or removes an Emoji reaction to MessageID
+	MessageTypeReactionAdded   = "reaction_added"
+	MessageTypeReactionRemoved = "reaction_removed"
+
+	// MessageTypeMessagePinned and MessageTypeMessageUnpinned are published by
+	// the chat service when SenderID pins or unpins MessageID
+	MessageTypeMessagePinned   = "message_pinned"
+	MessageTypeMessageUnpinned = "message_unpinned"
+
+	// MessageTypePinsReordered is published by the chat service when SenderID
+	// reorders the pins; MessageIDs is the new order
+	MessageTypePinsReordered = "pins_reordered"
+
+	// MessageTypeE2EEDisabled warns participants that an admin turned off end-to-end encryption
+	MessageTypeE2EEDisabled = "e2ee_disabled"
+
+	// MessageTypeResync asks clients to refetch history after a real-time delivery gap
+	MessageTypeResync = "resync"
+
+	// MessageTypeUnreadUpdate carries a user's new unread total after they read
+	// on one device, so their other devices can update the badge
+	MessageTypeUnreadUpdate = "unread_update"
+
+	// MessageTypeMessageRejected tells a sender that a chat message they sent
+	// over the socket was not relayed; Content holds the reason
+	MessageTypeMessageRejected = "message_rejected"
+)
+
+// Event categories distinguish a user's own activity mirrored from another
+// device from events produced by other participants
+const (
+	EventCategorySelfSync = "self_sync"
+
+	// EventCategoryReplay marks messages re-sent from history after a pub/sub outage
+	EventCategoryReplay = "replay"
 )
 
 // Message represents a WebSocket message
 type Message struct {
 	Type           string                 `json:"type"`
+	Category       string                 `json:"category,omitempty"`
 	ConversationID uuid.UUID              `json:"conversation_id"`
 	SenderID       uuid.UUID              `json:"sender_id,omitempty"`
 	MessageID      uuid.UUID              `json:"message_id,omitempty"`
@@ -79,6 +234,82 @@ type Message struct {
 	MessageType    string                 `json:"message_type,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 	Timestamp      time.Time              `json:"timestamp"`
+
+	// SentAt is the server send time of messages published by the chat service
+	SentAt time.Time `json:"sent_at,omitzero"`
+
+	// DeletedBy is who deleted the message of a message_deleted event
+	DeletedBy uuid.UUID `json:"deleted_by,omitzero"`
+
+	// Emoji is the reaction of a reaction_added or reaction_removed event
+	Emoji string `json:"emoji,omitempty"`
+
+	// MessageIDs is the new pin order of a pins_reordered event
+	MessageIDs []uuid.UUID `json:"message_ids,omitempty"`
+
+	// Cursor is the event's stream ID, set when the hub reads from an event stream
+	Cursor string `json:"cursor,omitempty"`
+
+	// origin is the connection the message was read from, if any
+	origin *Client
+}
+
+// isSelfSyncEvent reports whether msg should be mirrored to the sender's other devices.
+// Messages published by the chat service carry no type but do carry a message ID.
+func isSelfSyncEvent(msg *Message) bool {
+	switch msg.Type {
+	case MessageTypeChat, MessageTypeRead, MessageTypeDraft, MessageTypeUnreadUpdate, MessageTypeMessageEdited, MessageTypeMessageDeleted,
+		MessageTypeReactionAdded, MessageTypeReactionRemoved, MessageTypeMessagePinned, MessageTypeMessageUnpinned,
+		MessageTypePinsReordered:
+		return true
+	case "":
+		return msg.MessageID != uuid.Nil
+	}
+	return false
+}
+
+// isServerOnlyEvent reports whether msg may only be published by the hub or a
+// service, so the same type sent by a client is dropped. Messages without a
+// type are chat messages published by the chat service.
+func isServerOnlyEvent(msg *Message) bool {
+	switch msg.Type {
+	case "", MessageTypeUserJoined, MessageTypeUserLeft, MessageTypeResync, MessageTypeUnreadUpdate,
+		MessageTypePollCreated, MessageTypePollVoted, MessageTypePollClosed,
+		MessageTypeParticipantMuted, MessageTypeParticipantUnmuted, MessageTypeBotAdded, MessageTypeBotRemoved,
+		MessageTypeE2EEDisabled, MessageTypeMessageEdited, MessageTypeMessageDeleted,
+		MessageTypeReactionAdded, MessageTypeReactionRemoved, MessageTypeMessagePinned, MessageTypeMessageUnpinned,
+		MessageTypePinsReordered, MessageTypeMessageRejected:
+		return true
+	}
+	return false
+}
+
+// isChatMessage reports whether msg carries a chat message rather than a signal
+func isChatMessage(msg *Message) bool {
+	return msg.Type == MessageTypeChat || (msg.Type == "" && msg.MessageID != uuid.Nil)
+}
+
+// recordDelivery observes how long msg took to reach client, measured from the
+// message's server timestamp. Only live chat messages handed to a recipient
+// count; the sender's own copies and history replays are skipped.
+func recordDelivery(client *Client, msg *Message) {
+	if !isChatMessage(msg) || msg.Category != "" || client.userID == msg.SenderID {
+		return
+	}
+	sentAt := msg.SentAt
+	if sentAt.IsZero() {
+		sentAt = msg.Timestamp
+	}
+	if sentAt.IsZero() {
+		return
+	}
+	metrics.MessageDeliveryLatency.WithLabelValues("socket").Observe(time.Since(sentAt).Seconds())
+}
+
+// isPrivateEvent reports whether msg only concerns its sender and is delivered
+// to their own devices rather than the whole conversation
+func isPrivateEvent(msg *Message) bool {
+	return msg.Type == MessageTypeDraft || msg.Type == MessageTypeUnreadUpdate
 }
 
 // GetAllowedOrigins returns allowed WebSocket origins from environment or defaults
@@ -124,6 +355,13 @@ var upgrader = websocket.Upgrader{
 
 // NewChatHub creates a new chat hub
 func NewChatHub(redisClient *redis.Client) *ChatHub {
+	hub := newChatHub(redisClient)
+	go hub.run()
+	return hub
+}
+
+// newChatHub creates a chat hub without starting its run loop
+func newChatHub(redisClient *redis.Client) *ChatHub {
 	// Default max connections: 1000 (configurable via environment if needed)
 	maxConns := 1000
 	if val := os.Getenv("WS_MAX_CHAT_CONNECTIONS"); val != "" {
@@ -132,22 +370,46 @@ func NewChatHub(redisClient *redis.Client) *ChatHub {
 		}
 	}
 
+	ctx, stop := context.WithCancel(context.Background())
 	hub := &ChatHub{
 		conversations:       make(map[uuid.UUID]map[*Client]bool),
+		userClients:         make(map[uuid.UUID]map[*Client]bool),
+		devices:             make(map[deviceKey]*Client),
 		subscriptionCancels: make(map[uuid.UUID]context.CancelFunc),
 		redisClient:         redisClient,
-		register:            make(chan *Client),
-		unregister:          make(chan *Client),
-		broadcast:           make(chan *Message, 1000), // MEDIUM FIX #2: Increased from 256 to 1000
-		maxConnections:      maxConns,
-		semaphore:           make(chan struct{}, maxConns),
+		subscribe: func(ctx context.Context, channel string) pubSubConn {
+			return redisClient.Subscribe(ctx, channel)
+		},
+		resubscribeMinBackoff: defaultResubscribeMinBackoff,
+		resubscribeMaxBackoff: defaultResubscribeMaxBackoff,
+		register:              make(chan *Client),
+		unregister:            make(chan *Client),
+		broadcast:             make(chan *Message, 1000), // MEDIUM FIX #2: Increased from 256 to 1000
+		maxConnections:        maxConns,
+		semaphore:             make(chan struct{}, maxConns),
+		presenceDebounce:      presenceDebounceFromEnv(),
+		pendingLeaves:         make(map[presenceKey]*pendingLeave),
+		typers:                make(map[presenceKey]*typingEntry),
+		typingTTL:             DefaultTypingTTL,
+		maxMessageBytes:       DefaultChatMaxMessageBytes,
+		ctx:                   ctx,
+		stop:                  stop,
 	}
-
-	go hub.run()
+	hub.scopeReadReceipts.Store(scopeReadReceiptsFromEnv())
+	hub.SetMaxMessageBytes(int64(env.GetInt("WS_CHAT_MAX_MESSAGE_BYTES", DefaultChatMaxMessageBytes)))
+	hub.SetTypingTTL(env.GetDuration("CHAT_TYPING_TTL", DefaultTypingTTL))
 
 	return hub
 }
 
+// SetMaxMessageBytes sets the largest message a client may send. A
+// non-positive value keeps the current limit.
+func (h *ChatHub) SetMaxMessageBytes(maxMessageBytes int64) {
+	if maxMessageBytes > 0 {
+		h.maxMessageBytes = maxMessageBytes
+	}
+}
+
 // run handles hub operations
 func (h *ChatHub) run() {
 	for {
@@ -157,14 +419,25 @@ func (h *ChatHub) run() {
 			if h.conversations[client.conversationID] == nil {
 				h.conversations[client.conversationID] = make(map[*Client]bool)
 
-				// Create cancelable context for subscription
-				ctx, cancel := context.WithCancel(context.Background())
-				h.subscriptionCancels[client.conversationID] = cancel
-
-				// Subscribe to Redis channel for this conversation
-				go h.subscribeToConversation(ctx, client.conversationID)
+				// Follow the conversation's event stream, or its Redis channel
+				if h.events != nil {
+					conversationID := client.conversationID
+					h.followConversation(conversationID)
+					h.subscriptionCancels[conversationID] = func() { h.unfollowConversation(conversationID) }
+				} else {
+					// Create cancelable context for subscription
+					ctx, cancel := context.WithCancel(h.ctx)
+					h.subscriptionCancels[client.conversationID] = cancel
+					h.goFollow(func() { h.subscribeToConversation(ctx, client.conversationID) })
+				}
 			}
 			h.conversations[client.conversationID][client] = true
+			if h.userClients[client.userID] == nil {
+				h.userClients[client.userID] = make(map[*Client]bool)
+			}
+			h.userClients[client.userID][client] = true
+			replaced := h.replaceDeviceLocked(client)
+			rejoined := h.cancelPendingLeaveLocked(presenceKey{client.conversationID, client.userID})
 			h.mu.Unlock()
 
 			// Increment WebSocket connections gauge
@@ -174,128 +447,277 @@ func (h *ChatHub) run() {
 			metrics.ChatConversationParticipantsTotal.WithLabelValues(client.conversationID.String()).Set(
 				float64(len(h.conversations[client.conversationID])))
 
-			// Notify others that user joined
-			h.broadcast <- &Message{
-				Type:           MessageTypeUserJoined,
+			// Notify others that user joined, unless this reconnect cancelled a
+			// pending leave or took over from the device's previous connection
+			if !rejoined && replaced == nil {
+				h.broadcast <- &Message{
+					Type:           MessageTypeUserJoined,
+					ConversationID: client.conversationID,
+					SenderID:       client.userID,
+					Timestamp:      time.Now(),
+				}
+			}
+
+		case client := <-h.unregister:
+			h.unregisterClient(client)
+
+		case message := <-h.broadcast:
+			h.broadcastToConversation(message)
+			h.syncSenderDevices(message)
+		}
+	}
+}
+
+// unregisterClient takes client out of the hub and announces that its user left
+func (h *ChatHub) unregisterClient(client *Client) {
+	var leaving []*Message
+	h.mu.Lock()
+	if client.replaced {
+		// Already taken out of the hub when its device reconnected
+		client.replaced = false
+		close(client.send)
+	} else if clients, ok := h.conversations[client.conversationID]; ok {
+		if _, exists := clients[client]; exists {
+			delete(clients, client)
+			h.removeUserClient(client)
+			h.removeDeviceLocked(client)
+			close(client.send)
+			client.cancel() // Cancel client context
+
+			// Clear an indicator the client left behind mid-typing
+			if msg := h.endTypingOnLeaveLocked(client); msg != nil {
+				leaving = append(leaving, msg)
+			}
+
+			// Notify others that user left (after the debounce window)
+			if msg := h.announceLeaveLocked(&Message{
+				Type:           MessageTypeUserLeft,
 				ConversationID: client.conversationID,
 				SenderID:       client.userID,
 				Timestamp:      time.Now(),
+			}); msg != nil {
+				leaving = append(leaving, msg)
 			}
 
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if clients, ok := h.conversations[client.conversationID]; ok {
-				if _, exists := clients[client]; exists {
-					delete(clients, client)
-					close(client.send)
-					client.cancel() // Cancel client context
-
-					// Notify others that user left
-					h.broadcast <- &Message{
-						Type:           MessageTypeUserLeft,
-						ConversationID: client.conversationID,
-						SenderID:       client.userID,
-						Timestamp:      time.Now(),
-					}
-
-					// Clean up empty conversations
-					if len(clients) == 0 {
-						// Cancel Redis subscription
-						if cancel, ok := h.subscriptionCancels[client.conversationID]; ok {
-							cancel()
-							delete(h.subscriptionCancels, client.conversationID)
-						}
-						delete(h.conversations, client.conversationID)
-						// Set conversation participants to 0
-						metrics.ChatConversationParticipantsTotal.WithLabelValues(client.conversationID.String()).Set(0)
-					} else {
-						// Update conversation participants gauge
-						metrics.ChatConversationParticipantsTotal.WithLabelValues(client.conversationID.String()).Set(
-							float64(len(clients)))
-					}
+			// Clean up empty conversations
+			if len(clients) == 0 {
+				// Cancel Redis subscription
+				if cancel, ok := h.subscriptionCancels[client.conversationID]; ok {
+					cancel()
+					delete(h.subscriptionCancels, client.conversationID)
 				}
+				delete(h.conversations, client.conversationID)
+				// Set conversation participants to 0
+				metrics.ChatConversationParticipantsTotal.WithLabelValues(client.conversationID.String()).Set(0)
+			} else {
+				// Update conversation participants gauge
+				metrics.ChatConversationParticipantsTotal.WithLabelValues(client.conversationID.String()).Set(
+					float64(len(clients)))
 			}
-			h.mu.Unlock()
+		}
+	}
+	h.mu.Unlock()
 
-			// Decrement WebSocket connections gauge
-			metrics.ChatWebSocketConnections.Dec()
+	if len(leaving) > 0 {
+		go h.enqueueBroadcast(leaving)
+	}
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			var clientsToRemove []*Client
-			if clients, ok := h.conversations[message.ConversationID]; ok {
-				messageJSON, _ := json.Marshal(message)
-				for client := range clients {
-					select {
-					case client.send <- messageJSON:
-						// Increment messages sent (outbound)
-						metrics.ChatWebSocketMessagesTotal.WithLabelValues("out").Inc()
-					default:
-						// Mark for removal instead of deleting now
-						clientsToRemove = append(clientsToRemove, client)
-					}
-				}
+	// Decrement WebSocket connections gauge
+	metrics.ChatWebSocketConnections.Dec()
+}
+
+// enqueueBroadcast queues msgs for the run loop in order. The run loop is the
+// only reader of h.broadcast, so it hands its own messages to this on a new
+// goroutine rather than waiting for room in the buffer.
+func (h *ChatHub) enqueueBroadcast(msgs []*Message) {
+	for _, msg := range msgs {
+		h.broadcast <- msg
+	}
+}
+
+// broadcastToConversation delivers message to every client connected to its conversation.
+// Drafts and unread updates are private to the user and are only mirrored to
+// their own devices. Typing indicators only reach clients currently viewing
+// the conversation.
+func (h *ChatHub) broadcastToConversation(message *Message) {
+	if isPrivateEvent(message) {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if clients, ok := h.conversations[message.ConversationID]; ok {
+		messageJSON, _ := json.Marshal(message)
+		viewersOnly := h.viewersOnly(message)
+		for client := range clients {
+			if viewersOnly && client.hidden.Load() {
+				continue
 			}
-			h.mu.RUnlock()
-
-			// Remove clients outside of read lock
-			if len(clientsToRemove) > 0 {
-				h.mu.Lock()
-				if clients, ok := h.conversations[message.ConversationID]; ok {
-					for _, client := range clientsToRemove {
-						delete(clients, client)
-					}
-				}
-				h.mu.Unlock()
+			select {
+			case client.send <- messageJSON:
+				// Increment messages sent (outbound)
+				metrics.ChatWebSocketMessagesTotal.WithLabelValues("out").Inc()
+				recordDelivery(client, message)
+			default:
+				// The client's buffer is full; it reloads history on reconnect
+				metrics.ChatWebSocketErrorsTotal.WithLabelValues("too_slow").Inc()
+				go client.closeWith(CloseTooSlow)
 			}
 		}
 	}
 }
 
-// subscribeToConversation subscribes to Redis Pub/Sub for a conversation
-func (h *ChatHub) subscribeToConversation(ctx context.Context, conversationID uuid.UUID) {
-	channel := fmt.Sprintf("chat:%s", conversationID)
-
-	pubsub := h.redisClient.Subscribe(ctx, channel)
-	defer pubsub.Close()
-
-	// Wait for confirmation that subscription is created before receiving messages
-	// This prevents a race condition (optional but good practice)
-	if _, err := pubsub.Receive(ctx); err != nil {
-		logger.Error("Failed to subscribe to Redis channel",
-			zap.String("conversation_id", conversationID.String()),
-			zap.Error(err))
+// syncSenderDevices mirrors the sender's own activity to their other connected devices.
+// Devices in the same conversation already received the message through the
+// conversation broadcast, so only the remaining ones get a self_sync copy.
+func (h *ChatHub) syncSenderDevices(message *Message) {
+	if message.SenderID == uuid.Nil || !isSelfSyncEvent(message) {
 		return
 	}
 
-	ch := pubsub.Channel()
+	syncMsg := *message
+	syncMsg.Category = EventCategorySelfSync
+	messageJSON, err := json.Marshal(&syncMsg)
+	if err != nil {
+		return
+	}
 
-	for {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.userClients[message.SenderID] {
+		if client == message.origin {
+			continue
+		}
+		if client.conversationID == message.ConversationID && !isPrivateEvent(message) {
+			continue
+		}
 		select {
-		case <-ctx.Done():
-			return
-		case msg := <-ch:
-			if msg == nil {
-				continue
-			}
-			// Parse message from Redis
-			var chatMsg Message
-			if err := json.Unmarshal([]byte(msg.Payload), &chatMsg); err != nil {
-				logger.Warn("Failed to unmarshal Redis message",
-					zap.String("conversation_id", conversationID.String()),
-					zap.Error(err))
-				metrics.ChatWebSocketErrorsTotal.WithLabelValues("unmarshal_error").Inc()
-				continue
-			}
+		case client.send <- messageJSON:
+			metrics.ChatWebSocketMessagesTotal.WithLabelValues("out").Inc()
+		default:
+			// Slow device; it will catch up from history on reconnect
+		}
+	}
+}
 
-			// Broadcast to WebSocket clients
-			h.broadcast <- &chatMsg
+// removeUserClient drops client from the per-user index. Callers must hold h.mu.
+func (h *ChatHub) removeUserClient(client *Client) {
+	if devices, ok := h.userClients[client.userID]; ok {
+		delete(devices, client)
+		if len(devices) == 0 {
+			delete(h.userClients, client.userID)
 		}
 	}
 }
 
+// replaceDeviceLocked records client as its device's connection to the
+// conversation. A previous connection of the same device is taken out of the
+// hub without a leave event and closed with CloseReplaced, so a reconnect
+// after a flaky network does not leave the stale connection receiving every
+// event twice. It returns the replaced client, or nil. h.mu must be held.
+func (h *ChatHub) replaceDeviceLocked(client *Client) *Client {
+	if client.deviceID == "" {
+		return nil
+	}
+	key := deviceKey{client.userID, client.deviceID, client.conversationID}
+	old := h.devices[key]
+	h.devices[key] = client
+	if old == nil {
+		return nil
+	}
+
+	// The conversation still has client, so it keeps its subscription
+	delete(h.conversations[old.conversationID], old)
+	h.removeUserClient(old)
+	old.replaced = true
+	old.cancel()
+	// send stays open: a cursor replay may still be queueing events on it.
+	// Closing the connection ends the read pump, whose unregister closes send
+	// once the replay is over, so the close frame also goes out first.
+	go old.closeWith(CloseReplaced)
+	return old
+}
+
+// removeDeviceLocked forgets client as its device's connection unless a newer
+// one already replaced it. h.mu must be held.
+func (h *ChatHub) removeDeviceLocked(client *Client) {
+	key := deviceKey{client.userID, client.deviceID, client.conversationID}
+	if client.deviceID != "" && h.devices[key] == client {
+		delete(h.devices, key)
+	}
+}
+
+// CloseAll closes every connected client with CloseServerShutdown. It is
+// registered as a server shutdown hook since hijacked WebSocket connections
+// are not drained by http.Server.Shutdown.
+func (h *ChatHub) CloseAll() {
+	h.mu.RLock()
+	var clients []*Client
+	for _, members := range h.conversations {
+		for client := range members {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.closeWith(CloseServerShutdown)
+	}
+}
+
+// Stop ends the hub's Redis subscriptions and event stream reader, as well as
+// FollowUserDisconnects, and waits for the subscriptions and the reader to
+// return. Clients should be closed first with CloseAll.
+func (h *ChatHub) Stop() {
+	h.mu.Lock()
+	h.stop()
+	h.mu.Unlock()
+	h.followers.Wait()
+}
+
+// goFollow runs follow on a goroutine Stop waits for, unless the hub is
+// already stopped. Callers hold h.mu or run before the hub is shared, so no
+// goroutine is added once Stop is waiting.
+func (h *ChatHub) goFollow(follow func()) {
+	if h.ctx.Err() != nil {
+		return
+	}
+	h.followers.Add(1)
+	go func() {
+		defer h.followers.Done()
+		follow()
+	}()
+}
+
+// DisconnectUser closes every connection of userID with reason and returns
+// how many were closed
+func (h *ChatHub) DisconnectUser(userID uuid.UUID, reason CloseReason) int {
+	h.mu.RLock()
+	var clients []*Client
+	for client := range h.userClients[userID] {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.closeWith(reason)
+	}
+	return len(clients)
+}
+
+// FollowUserDisconnects closes the connections of users published on
+// domain.UserDisconnectChannel, e.g. when they are banned, until ctx is done
+// or the hub is stopped
+func (h *ChatHub) FollowUserDisconnects(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(h.ctx, cancel)
+	defer stop()
+	followUserDisconnects(ctx, h.subscribe, h.resubscribeBackoff(), h.DisconnectUser)
+}
+
 // ServeWS handles WebSocket requests
-func (h *ChatHub) ServeWS(c *gin.Context, conversationRepo *cockroach.ConversationRepository) {
+func (h *ChatHub) ServeWS(c *gin.Context, membership MembershipChecker) {
 	// Acquire semaphore to limit concurrent connections
 	select {
 	case h.semaphore <- struct{}{}:
@@ -338,7 +760,7 @@ func (h *ChatHub) ServeWS(c *gin.Context, conversationRepo *cockroach.Conversati
 	}
 
 	// CRITICAL FIX #2: Validate user is a participant in the conversation
-	isParticipant, err := conversationRepo.IsParticipant(c.Request.Context(), conversationID, userID)
+	isParticipant, err := membership.IsMember(c.Request.Context(), userID, conversationID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to verify conversation membership"})
 		return
@@ -349,6 +771,14 @@ func (h *ChatHub) ServeWS(c *gin.Context, conversationRepo *cockroach.Conversati
 		return
 	}
 
+	// Clients that send a stable device_id get one connection per conversation
+	// on that device; a reconnect replaces the previous one
+	deviceID := c.Query("device_id")
+	if len(deviceID) > maxDeviceIDLength {
+		c.JSON(400, gin.H{"error": "invalid device_id"})
+		return
+	}
+
 	// Upgrade to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -363,6 +793,18 @@ func (h *ChatHub) ServeWS(c *gin.Context, conversationRepo *cockroach.Conversati
 	// Record successful connection
 	metrics.ChatWebSocketConnectionTotal.WithLabelValues("success").Inc()
 
+	h.attachFrom(conn, userID, conversationID, deviceID, c.Query("cursor"), tokenExpiry(c.Get("token_expires_at")))
+}
+
+// attach registers an upgraded connection with the hub and starts its pumps
+func (h *ChatHub) attach(conn *websocket.Conn, userID, conversationID uuid.UUID) {
+	h.attachFrom(conn, userID, conversationID, "", "", time.Time{})
+}
+
+// attachFrom is attach for a client on deviceID resuming from an event stream
+// cursor; either may be empty. The connection is closed with CloseAuthExpired
+// at expiresAt unless it is zero.
+func (h *ChatHub) attachFrom(conn *websocket.Conn, userID, conversationID uuid.UUID, deviceID, cursor string, expiresAt time.Time) {
 	// Create cancelable context for this client's subscription interest
 	ctx, cancel := context.WithCancel(context.Background())
 	client := &Client{
@@ -371,15 +813,22 @@ func (h *ChatHub) ServeWS(c *gin.Context, conversationRepo *cockroach.Conversati
 		send:           make(chan []byte, 1000), // MEDIUM FIX #4: Increased from 256 to 1000
 		userID:         userID,
 		conversationID: conversationID,
+		deviceID:       deviceID,
 		ctx:            ctx,
 		cancel:         cancel,
 	}
 
 	client.hub.register <- client
 
+	// Queue missed events before the write pump starts draining the buffer
+	if cursor != "" && h.events != nil {
+		h.replayFromCursor(client, cursor)
+	}
+
 	// Start goroutines for read/write
 	go client.writePump()
 	go client.readPump()
+	go closeAtExpiry(ctx, expiresAt, client.closeWith)
 }
 
 // readPump reads messages from WebSocket
@@ -389,6 +838,8 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
+	// Larger messages make ReadMessage fail and close the connection with 1009
+	c.conn.SetReadLimit(c.hub.maxMessageBytes)
 	c.conn.SetReadDeadline(time.Now().Add(constants.WebSocketPingInterval))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(constants.WebSocketPingInterval))
@@ -398,6 +849,14 @@ func (c *Client) readPump() {
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				metrics.ChatWebSocketErrorsTotal.WithLabelValues("frame_too_large").Inc()
+				logger.Warn("Chat message too large, closing connection",
+					zap.String("conversation_id", c.conversationID.String()),
+					zap.String("user_id", c.userID.String()),
+					zap.Int64("max_bytes", c.hub.maxMessageBytes))
+				break
+			}
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logger.Debug("WebSocket connection closed",
 					zap.String("conversation_id", c.conversationID.String()),
@@ -422,10 +881,42 @@ func (c *Client) readPump() {
 		// Increment messages received (inbound)
 		metrics.ChatWebSocketMessagesTotal.WithLabelValues("in").Inc()
 
+		if c.handleViewerSignal(&msg) {
+			continue
+		}
+		if isServerOnlyEvent(&msg) {
+			metrics.ChatWebSocketErrorsTotal.WithLabelValues("forbidden_type").Inc()
+			continue
+		}
+
 		// Set metadata
 		msg.SenderID = c.userID
 		msg.ConversationID = c.conversationID
 		msg.Timestamp = time.Now()
+		msg.Category = ""
+		msg.origin = c
+
+		if msg.Type == MessageTypeChat && !c.allowRelay(&msg) {
+			continue
+		}
+
+		switch msg.Type {
+		case MessageTypeTyping:
+			c.hub.startTyping(c.ctx, presenceKey{c.conversationID, c.userID})
+		case MessageTypeTypingStop:
+			c.hub.stopTyping(c.ctx, presenceKey{c.conversationID, c.userID})
+		}
+
+		// With an event stream, durable events reach every instance through it
+		if c.hub.events != nil && isStreamedEvent(&msg) {
+			err := c.appendEvent(&msg)
+			if err == nil {
+				continue
+			}
+			logger.Warn("Failed to append event to stream, broadcasting locally",
+				zap.String("conversation_id", c.conversationID.String()),
+				zap.Error(err))
+		}
 
 		// Broadcast to hub
 		c.hub.broadcast <- &msg
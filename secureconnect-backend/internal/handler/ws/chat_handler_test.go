@@ -0,0 +1,377 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+func TestMain(m *testing.M) {
+	// Initialised once: hubs log from goroutines, and replacing the global
+	// logger while they run is a data race
+	logger.InitDefault("test")
+	os.Exit(m.Run())
+}
+
+// newTestChatHub returns a running hub whose Redis is unreachable. It is
+// stopped when the test ends, so its subscriptions do not outlive it.
+func newTestChatHub(t *testing.T) *ChatHub {
+	t.Helper()
+	hub := NewChatHub(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}))
+	t.Cleanup(hub.Stop)
+	return hub
+}
+
+// dialHub connects a device for userID to conversationID on hub through a real WebSocket
+func dialHub(t *testing.T, hub *ChatHub, userID, conversationID uuid.UUID) *websocket.Conn {
+	t.Helper()
+	return dialHubFrom(t, hub, userID, conversationID, "")
+}
+
+// dialHubFrom is dialHub for a client resuming from an event stream cursor
+func dialHubFrom(t *testing.T, hub *ChatHub, userID, conversationID uuid.UUID, cursor string) *websocket.Conn {
+	t.Helper()
+	return dialHubWith(t, hub, userID, conversationID, "", cursor, time.Time{})
+}
+
+// dialHubWith is dialHubFrom for a client on deviceID whose access token
+// expires at expiresAt
+func dialHubWith(t *testing.T, hub *ChatHub, userID, conversationID uuid.UUID, deviceID, cursor string, expiresAt time.Time) *websocket.Conn {
+	t.Helper()
+	testUpgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := testUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.attachFrom(conn, userID, conversationID, deviceID, cursor, expiresAt)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil returns the first message of the given type, or nil if none arrives within timeout
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string, timeout time.Duration) *Message {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		var msg Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		if msg.Type == msgType {
+			return &msg
+		}
+	}
+}
+
+func TestChatHub_SelfSyncAcrossDevices(t *testing.T) {
+	// Redis is unreachable; the hub only logs failed subscriptions
+	hub := newTestChatHub(t)
+
+	userID := uuid.New()
+	peerID := uuid.New()
+	conversationA := uuid.New()
+	conversationB := uuid.New()
+
+	phone := dialHub(t, hub, userID, conversationA)
+	laptop := dialHub(t, hub, userID, conversationB)
+	peer := dialHub(t, hub, peerID, conversationA)
+
+	// Phone sends a message in conversation A; the laptop sits in conversation B
+	require.NoError(t, phone.WriteJSON(Message{Type: MessageTypeChat, Content: "hello"}))
+
+	synced := readUntil(t, laptop, MessageTypeChat, 2*time.Second)
+	require.NotNil(t, synced, "laptop should receive the phone's message")
+	assert.Equal(t, EventCategorySelfSync, synced.Category)
+	assert.Equal(t, conversationA, synced.ConversationID)
+	assert.Equal(t, userID, synced.SenderID)
+	assert.Equal(t, "hello", synced.Content)
+
+	delivered := readUntil(t, peer, MessageTypeChat, 2*time.Second)
+	require.NotNil(t, delivered, "peer should receive the message")
+	assert.Empty(t, delivered.Category)
+
+	// Drafts are mirrored to the author's devices only
+	require.NoError(t, phone.WriteJSON(Message{Type: MessageTypeDraft, Content: "typing a reply"}))
+
+	draft := readUntil(t, laptop, MessageTypeDraft, 2*time.Second)
+	require.NotNil(t, draft)
+	assert.Equal(t, EventCategorySelfSync, draft.Category)
+	assert.Nil(t, readUntil(t, peer, MessageTypeDraft, 200*time.Millisecond), "peer must not see drafts")
+}
+
+func TestChatHub_NewerConnectionFromSameDeviceReplacesOlder(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	userID := uuid.New()
+	conversationID := uuid.New()
+	peer := dialHub(t, hub, uuid.New(), conversationID)
+	require.NotNil(t, readUntil(t, peer, MessageTypeUserJoined, 2*time.Second), "the peer's own join")
+	stale := dialHubWith(t, hub, userID, conversationID, "phone", "", time.Time{})
+	require.NotNil(t, readUntil(t, peer, MessageTypeUserJoined, 2*time.Second))
+	laptop := dialHubWith(t, hub, userID, conversationID, "laptop", "", time.Time{})
+	require.NotNil(t, readUntil(t, peer, MessageTypeUserJoined, 2*time.Second))
+
+	// The phone reconnects after a flaky network while the old socket lingers
+	phone := dialHubWith(t, hub, userID, conversationID, "phone", "", time.Time{})
+	assert.Equal(t, CloseReplaced, readClose(t, stale))
+	chatConnected(t, hub, userID, 2)
+	assert.Nil(t, readUntil(t, peer, MessageTypeUserJoined, 200*time.Millisecond), "the user never left")
+
+	require.NoError(t, peer.WriteJSON(Message{Type: MessageTypeChat, Content: "hello"}))
+	require.NotNil(t, readUntil(t, phone, MessageTypeChat, 2*time.Second))
+	assert.Nil(t, readUntil(t, phone, MessageTypeChat, 200*time.Millisecond), "the device gets each event once")
+	require.NotNil(t, readUntil(t, laptop, MessageTypeChat, 2*time.Second), "other devices stay connected")
+}
+
+func TestChatHub_TypingOnlyReachesActiveViewers(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	conversationID := uuid.New()
+	typer := dialHub(t, hub, uuid.New(), conversationID)
+	viewer := dialHub(t, hub, uuid.New(), conversationID)
+	blurred := dialHub(t, hub, uuid.New(), conversationID)
+	idle := dialHub(t, hub, uuid.New(), conversationID)
+
+	require.NoError(t, viewer.WriteJSON(Message{Type: MessageTypeFocus}))
+	require.NoError(t, blurred.WriteJSON(Message{Type: MessageTypeFocus}))
+	require.NoError(t, blurred.WriteJSON(Message{Type: MessageTypeBlur}))
+
+	// A chat message sent after the signals arrives only once they were handled
+	require.NoError(t, viewer.WriteJSON(Message{Type: MessageTypeChat, Content: "viewer ready"}))
+	require.NoError(t, blurred.WriteJSON(Message{Type: MessageTypeChat, Content: "blurred ready"}))
+	require.NotNil(t, readUntil(t, typer, MessageTypeChat, 2*time.Second))
+	require.NotNil(t, readUntil(t, typer, MessageTypeChat, 2*time.Second))
+	assert.Nil(t, readUntil(t, typer, MessageTypeFocus, 100*time.Millisecond), "viewer signals are not broadcast")
+
+	require.NoError(t, typer.WriteJSON(Message{Type: MessageTypeTyping}))
+
+	typing := readUntil(t, viewer, MessageTypeTyping, 2*time.Second)
+	require.NotNil(t, typing, "viewing participant receives the typing indicator")
+	assert.Equal(t, conversationID, typing.ConversationID)
+	assert.Nil(t, readUntil(t, blurred, MessageTypeTyping, 200*time.Millisecond), "blurred participant is skipped")
+	assert.NotNil(t, readUntil(t, idle, MessageTypeTyping, 2*time.Second), "participant that never sent focus or blur counts as viewing")
+}
+
+func TestChatHub_OversizedMessageClosesWithMessageTooBig(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetMaxMessageBytes(1024)
+
+	conversationID := uuid.New()
+	sender := dialHub(t, hub, uuid.New(), conversationID)
+	peer := dialHub(t, hub, uuid.New(), conversationID)
+
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeChat, Content: strings.Repeat("a", 2048)}))
+
+	assert.Equal(t, websocket.CloseMessageTooBig, readCloseCode(t, sender))
+	assert.Nil(t, readUntil(t, peer, MessageTypeChat, 200*time.Millisecond), "oversized message is not broadcast")
+}
+
+// socketDeliveries returns the sample count and sum of socket delivery latency
+func socketDeliveries(t *testing.T) (uint64, float64) {
+	var m dto.Metric
+	observer := metrics.MessageDeliveryLatency.WithLabelValues("socket").(prometheus.Histogram)
+	require.NoError(t, observer.Write(&m))
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestChatHub_RecordsDeliveryLatencyForRecipients(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	conversationID := uuid.New()
+	senderID := uuid.New()
+	sender := &Client{send: make(chan []byte, 4), userID: senderID, conversationID: conversationID}
+	recipient := &Client{send: make(chan []byte, 4), userID: uuid.New(), conversationID: conversationID}
+	hub.conversations[conversationID] = map[*Client]bool{sender: true, recipient: true}
+
+	count, sum := socketDeliveries(t)
+
+	// Published by the chat service two seconds ago
+	hub.broadcastToConversation(&Message{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		MessageID:      uuid.New(),
+		SentAt:         time.Now().Add(-2 * time.Second),
+	})
+
+	newCount, newSum := socketDeliveries(t)
+	assert.Equal(t, count+1, newCount, "only the recipient's delivery is observed")
+	assert.InDelta(t, 2.0, newSum-sum, 0.5)
+
+	// Signals and replays are not deliveries
+	hub.broadcastToConversation(&Message{
+		Type:           MessageTypeUserJoined,
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Timestamp:      time.Now(),
+	})
+	hub.broadcastToConversation(&Message{
+		Category:       EventCategoryReplay,
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		MessageID:      uuid.New(),
+		Timestamp:      time.Now().Add(-time.Minute),
+	})
+	finalCount, _ := socketDeliveries(t)
+	assert.Equal(t, newCount, finalCount)
+}
+
+func TestChatHub_UnreadUpdateOnlyReachesOwnDevices(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	conversationID, otherConversationID := uuid.New(), uuid.New()
+	userID := uuid.New()
+	phone := &Client{send: make(chan []byte, 4), userID: userID, conversationID: conversationID}
+	laptop := &Client{send: make(chan []byte, 4), userID: userID, conversationID: otherConversationID}
+	participant := &Client{send: make(chan []byte, 4), userID: uuid.New(), conversationID: conversationID}
+	hub.conversations[conversationID] = map[*Client]bool{phone: true, participant: true}
+	hub.conversations[otherConversationID] = map[*Client]bool{laptop: true}
+	hub.userClients[userID] = map[*Client]bool{phone: true, laptop: true}
+
+	update := &Message{
+		Type:           MessageTypeUnreadUpdate,
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Metadata:       map[string]interface{}{"total_unread": 2},
+	}
+	hub.broadcastToConversation(update)
+	hub.syncSenderDevices(update)
+
+	assert.Empty(t, participant.send, "other participants never see the user's unread counts")
+	for _, device := range []*Client{phone, laptop} {
+		require.Len(t, device.send, 1)
+		var msg Message
+		require.NoError(t, json.Unmarshal(<-device.send, &msg))
+		assert.Equal(t, MessageTypeUnreadUpdate, msg.Type)
+		assert.Equal(t, EventCategorySelfSync, msg.Category)
+	}
+}
+
+func TestChatHub_MessageChangesOnlyFromServices(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	conversationID := uuid.New()
+	sender := dialHub(t, hub, uuid.New(), conversationID)
+	peer := dialHub(t, hub, uuid.New(), conversationID)
+
+	// A client cannot forge an edit, a deletion or a reaction
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeMessageEdited, MessageID: uuid.New(), Content: "forged"}))
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeMessageDeleted, MessageID: uuid.New()}))
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeReactionAdded, MessageID: uuid.New(), Emoji: "👍"}))
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeChat, Content: "after"}))
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg Message
+		require.NoError(t, peer.ReadJSON(&msg))
+		require.NotEqual(t, MessageTypeMessageEdited, msg.Type, "the forged edit was broadcast")
+		require.NotEqual(t, MessageTypeMessageDeleted, msg.Type, "the forged deletion was broadcast")
+		require.NotEqual(t, MessageTypeReactionAdded, msg.Type, "the forged reaction was broadcast")
+		if msg.Type == MessageTypeChat {
+			break
+		}
+	}
+
+	// Edits published by the chat service reach the conversation
+	messageID := uuid.New()
+	hub.broadcastToConversation(&Message{Type: MessageTypeMessageEdited, ConversationID: conversationID, MessageID: messageID, Content: "fixed"})
+	edited := readUntil(t, peer, MessageTypeMessageEdited, 2*time.Second)
+	require.NotNil(t, edited)
+	assert.Equal(t, messageID, edited.MessageID)
+	assert.Equal(t, "fixed", edited.Content)
+
+	// So do deletions, with who deleted the message
+	admin := uuid.New()
+	hub.broadcastToConversation(&Message{Type: MessageTypeMessageDeleted, ConversationID: conversationID, MessageID: messageID, DeletedBy: admin})
+	deleted := readUntil(t, peer, MessageTypeMessageDeleted, 2*time.Second)
+	require.NotNil(t, deleted)
+	assert.Equal(t, messageID, deleted.MessageID)
+	assert.Equal(t, admin, deleted.DeletedBy)
+
+	// And reactions, with the emoji
+	reactor := uuid.New()
+	hub.broadcastToConversation(&Message{Type: MessageTypeReactionAdded, ConversationID: conversationID, SenderID: reactor, MessageID: messageID, Emoji: "🙏"})
+	reacted := readUntil(t, peer, MessageTypeReactionAdded, 2*time.Second)
+	require.NotNil(t, reacted)
+	assert.Equal(t, reactor, reacted.SenderID)
+	assert.Equal(t, "🙏", reacted.Emoji)
+}
+
+func TestChatHub_ReplacedClientKeepsSendOpenUntilUnregistered(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	// newClient returns a registered client of the phone whose pumps have not
+	// started yet, as while attachFrom replays its cursor
+	userID, conversationID := uuid.New(), uuid.New()
+	newClient := func() *Client {
+		conns := make(chan *websocket.Conn, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+			require.NoError(t, err)
+			conns <- conn
+		}))
+		t.Cleanup(server.Close)
+		peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { peer.Close() })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		client := &Client{
+			hub:            hub,
+			conn:           <-conns,
+			send:           make(chan []byte, 10),
+			userID:         userID,
+			conversationID: conversationID,
+			deviceID:       "phone",
+			ctx:            ctx,
+			cancel:         cancel,
+		}
+		hub.register <- client
+		return client
+	}
+
+	replaying := newClient()
+	newClient()
+
+	// The replay keeps queueing on the replaced client without panicking
+	hub.sendTo(replaying, &Message{Type: MessageTypeChat, Content: "missed"})
+
+	hub.unregister <- replaying
+	var replayed bool
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case data, ok := <-replaying.send:
+			if !ok {
+				assert.True(t, replayed, "the replayed event was queued")
+				return
+			}
+			replayed = replayed || strings.Contains(string(data), "missed")
+		case <-timeout:
+			t.Fatal("send was not closed once the replaced client unregistered")
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/backoff"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+const (
+	// Backoff bounds between resubscription attempts after a Redis pub/sub failure
+	defaultResubscribeMinBackoff = 500 * time.Millisecond
+	defaultResubscribeMaxBackoff = 30 * time.Second
+
+	// replayHistoryLimit bounds how many persisted messages are replayed after a gap
+	replayHistoryLimit = 100
+)
+
+// pubSubConn is the subset of *redis.PubSub used by the hub
+type pubSubConn interface {
+	Receive(ctx context.Context) (interface{}, error)
+	ReceiveMessage(ctx context.Context) (*redis.Message, error)
+	Close() error
+}
+
+// MessageHistory loads persisted conversation messages, newest first
+type MessageHistory interface {
+	GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]*domain.Message, []byte, error)
+}
+
+// SetMessageHistory enables replaying messages missed while a conversation's
+// Redis subscription was down. Without it clients are sent a resync event instead.
+func (h *ChatHub) SetMessageHistory(history MessageHistory) {
+	h.history = history
+}
+
+// subscribeToConversation keeps a Redis subscription for the conversation alive
+// until ctx is cancelled. Subscription errors and dropped connections are retried
+// with exponential backoff, and once the subscription is back the messages
+// published during the gap are replayed to connected clients.
+func (h *ChatHub) subscribeToConversation(ctx context.Context, conversationID uuid.UUID) {
+	channel := fmt.Sprintf("chat:%s", conversationID)
+	retry := h.resubscribeBackoff()
+	failures := 0
+	var downSince time.Time
+
+	for {
+		pubsub := h.subscribe(ctx, channel)
+
+		// Wait for confirmation that subscription is created before receiving messages
+		_, err := pubsub.Receive(ctx)
+		if err == nil {
+			if !downSince.IsZero() {
+				h.markSubscriptionUp()
+				metrics.ChatPubSubResubscribeTotal.WithLabelValues("success").Inc()
+				logger.Info("Redis subscription restored",
+					zap.String("conversation_id", conversationID.String()),
+					zap.Duration("gap", time.Since(downSince)))
+				h.replayMissed(ctx, conversationID, downSince)
+				downSince = time.Time{}
+			}
+			failures = 0
+			err = h.receiveMessages(ctx, pubsub, conversationID)
+		} else if !downSince.IsZero() {
+			metrics.ChatPubSubResubscribeTotal.WithLabelValues("failure").Inc()
+		}
+		pubsub.Close()
+
+		if ctx.Err() != nil {
+			if !downSince.IsZero() {
+				h.markSubscriptionUp()
+			}
+			return
+		}
+
+		if downSince.IsZero() {
+			downSince = time.Now()
+			h.markSubscriptionDown()
+		}
+		failures++
+		delay := retry.Delay(failures)
+		logger.Warn("Redis subscription lost, resubscribing",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Duration("backoff", delay),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			h.markSubscriptionUp()
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// resubscribeBackoff is the delay between attempts to restore a lost
+// conversation subscription or event stream
+func (h *ChatHub) resubscribeBackoff() backoff.Config {
+	return backoff.Config{Base: h.resubscribeMinBackoff, Max: h.resubscribeMaxBackoff}
+}
+
+// markSubscriptionDown records a conversation subscription waiting to be re-established
+func (h *ChatHub) markSubscriptionDown() {
+	h.subscriptionsDown.Add(1)
+	metrics.ChatPubSubSubscriptionsDown.Inc()
+}
+
+// markSubscriptionUp records a subscription that recovered or is no longer needed
+func (h *ChatHub) markSubscriptionUp() {
+	h.subscriptionsDown.Add(-1)
+	metrics.ChatPubSubSubscriptionsDown.Dec()
+}
+
+// SubscriptionsDown returns the number of conversation subscriptions currently being retried
+func (h *ChatHub) SubscriptionsDown() int {
+	return int(h.subscriptionsDown.Load())
+}
+
+// receiveMessages forwards published messages to the hub until the subscription fails
+func (h *ChatHub) receiveMessages(ctx context.Context, pubsub pubSubConn, conversationID uuid.UUID) error {
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Parse message from Redis
+		var chatMsg Message
+		if err := json.Unmarshal([]byte(msg.Payload), &chatMsg); err != nil {
+			logger.Warn("Failed to unmarshal Redis message",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+			metrics.ChatWebSocketErrorsTotal.WithLabelValues("unmarshal_error").Inc()
+			continue
+		}
+
+		// Broadcast to WebSocket clients
+		h.broadcast <- &chatMsg
+	}
+}
+
+// replayMissed re-sends messages persisted since the subscription dropped. Messages
+// delivered both live and by replay share a message ID so clients can de-duplicate.
+// When history is unavailable or the gap is too large, clients are told to resync.
+func (h *ChatHub) replayMissed(ctx context.Context, conversationID uuid.UUID, since time.Time) {
+	if h.history != nil {
+		messages, _, err := h.history.GetRecentMessages(ctx, conversationID, replayHistoryLimit)
+		if err != nil {
+			logger.Warn("Failed to load history for replay",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+		} else {
+			var missed []*domain.Message
+			for _, m := range messages {
+				if m.SentAt.After(since) {
+					missed = append(missed, m)
+				}
+			}
+
+			if len(missed) < replayHistoryLimit {
+				// History is newest first; replay in send order
+				for i := len(missed) - 1; i >= 0; i-- {
+					m := missed[i]
+					h.broadcast <- &Message{
+						Category:       EventCategoryReplay,
+						ConversationID: m.ConversationID,
+						SenderID:       m.SenderID,
+						MessageID:      m.MessageID,
+						Content:        m.Content,
+						IsEncrypted:    m.IsEncrypted,
+						MessageType:    m.MessageType,
+						Metadata:       m.Metadata,
+						Timestamp:      m.SentAt,
+					}
+				}
+				metrics.ChatPubSubReplayedMessagesTotal.Add(float64(len(missed)))
+				return
+			}
+		}
+	}
+
+	h.broadcast <- &Message{
+		Type:           MessageTypeResync,
+		ConversationID: conversationID,
+		Metadata:       map[string]interface{}{"since": since.UTC().Format(time.RFC3339Nano)},
+		Timestamp:      time.Now(),
+	}
+}
@@ -0,0 +1,161 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// fakePubSub is a scripted subscription that either fails to subscribe or
+// delivers messages until it is dropped
+type fakePubSub struct {
+	subscribeErr error
+	messages     chan *redis.Message
+	dropped      chan struct{}
+}
+
+func newFakePubSub() *fakePubSub {
+	return &fakePubSub{messages: make(chan *redis.Message), dropped: make(chan struct{})}
+}
+
+func (p *fakePubSub) Receive(ctx context.Context) (interface{}, error) {
+	if p.subscribeErr != nil {
+		return nil, p.subscribeErr
+	}
+	return &redis.Subscription{Kind: "subscribe"}, nil
+}
+
+func (p *fakePubSub) ReceiveMessage(ctx context.Context) (*redis.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.dropped:
+		return nil, errors.New("connection reset by peer")
+	case msg := <-p.messages:
+		return msg, nil
+	}
+}
+
+func (p *fakePubSub) Close() error { return nil }
+
+func (p *fakePubSub) publish(t *testing.T, msg *domain.Message) {
+	t.Helper()
+	payload, err := json.Marshal(msg)
+	require.NoError(t, err)
+	p.messages <- &redis.Message{Payload: string(payload)}
+}
+
+// fakeHistory returns whatever messages the test has persisted, newest first
+type fakeHistory struct {
+	messages func() []*domain.Message
+}
+
+func (h *fakeHistory) GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]*domain.Message, []byte, error) {
+	return h.messages(), nil, nil
+}
+
+func TestChatHub_ResubscribesAndReplaysAfterPubSubDrop(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.resubscribeMinBackoff = 10 * time.Millisecond
+	hub.resubscribeMaxBackoff = 20 * time.Millisecond
+
+	sessions := make(chan *fakePubSub, 3)
+	hub.subscribe = func(ctx context.Context, channel string) pubSubConn {
+		select {
+		case p := <-sessions:
+			return p
+		case <-ctx.Done():
+			return &fakePubSub{subscribeErr: ctx.Err()}
+		}
+	}
+
+	userID := uuid.New()
+	conversationID := uuid.New()
+	message := func(content string, sentAt time.Time) *domain.Message {
+		return &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, SenderID: uuid.New(), Content: content, SentAt: sentAt}
+	}
+	beforeDrop := message("before", time.Now().Add(-time.Minute))
+	hub.SetMessageHistory(&fakeHistory{messages: func() []*domain.Message {
+		return []*domain.Message{message("during gap", time.Now()), beforeDrop}
+	}})
+
+	first := newFakePubSub()
+	sessions <- first
+	conn := dialHub(t, hub, userID, conversationID)
+
+	first.publish(t, beforeDrop)
+	live := readUntil(t, conn, "", 2*time.Second)
+	require.NotNil(t, live)
+	assert.Equal(t, "before", live.Content)
+
+	// Redis drops the connection and the first resubscribe attempt is refused
+	sessions <- &fakePubSub{subscribeErr: errors.New("connection refused")}
+	close(first.dropped)
+	assert.Eventually(t, func() bool { return hub.SubscriptionsDown() == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	// Redis comes back
+	second := newFakePubSub()
+	sessions <- second
+
+	replayed := readUntil(t, conn, "", 2*time.Second)
+	require.NotNil(t, replayed, "message sent during the gap should be replayed")
+	assert.Equal(t, "during gap", replayed.Content)
+	assert.Equal(t, EventCategoryReplay, replayed.Category)
+	assert.Eventually(t, func() bool { return hub.SubscriptionsDown() == 0 }, 2*time.Second, 5*time.Millisecond)
+
+	second.publish(t, message("after", time.Now()))
+	after := readUntil(t, conn, "", 2*time.Second)
+	require.NotNil(t, after, "live delivery should resume on the new subscription")
+	assert.Equal(t, "after", after.Content)
+	assert.Empty(t, after.Category)
+}
+
+// closeRecordingPubSub is a fakePubSub that records being closed
+type closeRecordingPubSub struct {
+	*fakePubSub
+	closed chan struct{}
+}
+
+func (p *closeRecordingPubSub) Close() error {
+	close(p.closed)
+	return nil
+}
+
+func TestChatHub_StopEndsSubscriptions(t *testing.T) {
+	hub := newTestChatHub(t)
+	pubsub := &closeRecordingPubSub{fakePubSub: newFakePubSub(), closed: make(chan struct{})}
+	subscribed := make(chan struct{})
+	hub.subscribe = func(ctx context.Context, channel string) pubSubConn {
+		close(subscribed)
+		return pubsub
+	}
+
+	dialHub(t, hub, uuid.New(), uuid.New())
+	<-subscribed
+
+	stopped := make(chan struct{})
+	go func() {
+		hub.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+	select {
+	case <-pubsub.closed:
+	default:
+		t.Fatal("the subscription was still open after Stop returned")
+	}
+	assert.Zero(t, hub.SubscriptionsDown())
+}
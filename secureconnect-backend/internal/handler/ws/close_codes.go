@@ -0,0 +1,133 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/backoff"
+	"secureconnect-backend/pkg/logger"
+)
+
+// CloseReason is an application close code sent in the close frame when the
+// server ends a connection, with the reason string clients match on. Codes
+// are in the 4000-4999 range RFC 6455 leaves to applications.
+type CloseReason struct {
+	Code int
+	Text string
+}
+
+// Close reasons sent by the chat and signaling hubs. The comment on each is
+// what a client should do; docs/WEBSOCKET_PROTOCOL.md lists them for client
+// developers.
+var (
+	// CloseServerShutdown: the instance is draining; reconnect with backoff
+	CloseServerShutdown = CloseReason{Code: 4000, Text: "server_shutdown"}
+	// CloseAuthExpired: the access token expired; refresh it, then reconnect
+	CloseAuthExpired = CloseReason{Code: 4001, Text: "auth_expired"}
+	// CloseBanned: the user was banned; do not reconnect
+	CloseBanned = CloseReason{Code: 4003, Text: "banned"}
+	// CloseRateLimited: the connection sent too much; reconnect after a delay
+	CloseRateLimited = CloseReason{Code: 4008, Text: "rate_limited"}
+	// CloseReplaced: a newer connection of the same user took over; do not reconnect
+	CloseReplaced = CloseReason{Code: 4009, Text: "replaced_by_newer_connection"}
+	// CloseTooSlow: the client did not keep up with its messages; reconnect
+	// and reload history
+	CloseTooSlow = CloseReason{Code: 4010, Text: "too_slow"}
+)
+
+// closeReasonsByText maps the reasons that can be requested over
+// domain.UserDisconnectChannel
+var closeReasonsByText = map[string]CloseReason{
+	CloseBanned.Text: CloseBanned,
+}
+
+// closeConn sends a close frame for reason and closes conn. WriteControl may
+// be called concurrently with the write pump.
+func closeConn(conn *websocket.Conn, reason CloseReason) {
+	closeMsg := websocket.FormatCloseMessage(reason.Code, reason.Text)
+	conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// closeAtExpiry calls closeFn when the access token a connection was opened
+// with expires, unless ctx ends first. A zero expiresAt never expires.
+func closeAtExpiry(ctx context.Context, expiresAt time.Time, closeFn func(CloseReason)) {
+	if expiresAt.IsZero() {
+		return
+	}
+	timer := time.NewTimer(time.Until(expiresAt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		closeFn(CloseAuthExpired)
+	case <-ctx.Done():
+	}
+}
+
+// tokenExpiry returns the expiry of the access token the auth middleware
+// validated, or the zero time if it is unknown
+func tokenExpiry(value interface{}, exists bool) time.Time {
+	if !exists {
+		return time.Time{}
+	}
+	expiresAt, _ := value.(time.Time)
+	return expiresAt
+}
+
+// followUserDisconnects closes the local connections of users published on
+// domain.UserDisconnectChannel until ctx is done, resubscribing with retry
+// after failures
+func followUserDisconnects(ctx context.Context, subscribe func(ctx context.Context, channel string) pubSubConn, retry backoff.Config, disconnect func(userID uuid.UUID, reason CloseReason) int) {
+	failures := 0
+	for {
+		if pubsub := subscribe(ctx, domain.UserDisconnectChannel); pubsub != nil {
+			if _, err := pubsub.Receive(ctx); err == nil {
+				failures = 0
+				receiveUserDisconnects(ctx, pubsub, disconnect)
+			}
+			pubsub.Close()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		failures++
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry.Delay(failures)):
+		}
+	}
+}
+
+// receiveUserDisconnects applies disconnect events until ctx is done or the
+// subscription fails
+func receiveUserDisconnects(ctx context.Context, pubsub pubSubConn, disconnect func(userID uuid.UUID, reason CloseReason) int) {
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return
+		}
+
+		var event domain.UserDisconnectEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			continue
+		}
+		reason, ok := closeReasonsByText[event.Reason]
+		if !ok {
+			continue
+		}
+		if n := disconnect(event.UserID, reason); n > 0 {
+			logger.Info("Closed connections of disconnected user",
+				zap.String("user_id", event.UserID.String()),
+				zap.String("reason", reason.Text),
+				zap.Int("connections", n))
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// readClose returns the close frame the server ends conn with
+func readClose(t *testing.T, conn *websocket.Conn) CloseReason {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			require.ErrorAs(t, err, &closeErr)
+			return CloseReason{Code: closeErr.Code, Text: closeErr.Text}
+		}
+	}
+}
+
+// chatConnected waits until the hub has registered n connections of userID
+func chatConnected(t *testing.T, hub *ChatHub, userID uuid.UUID, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return len(hub.userClients[userID]) == n
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestChatHub_ExpiredTokenClosesWithAuthExpired(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	conn := dialHubWith(t, hub, uuid.New(), uuid.New(), "", "", time.Now().Add(200*time.Millisecond))
+	assert.Equal(t, CloseAuthExpired, readClose(t, conn))
+}
+
+func TestChatHub_CloseAllSendsServerShutdown(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	userID := uuid.New()
+	phone := dialHub(t, hub, userID, uuid.New())
+	laptop := dialHub(t, hub, userID, uuid.New())
+	chatConnected(t, hub, userID, 2)
+
+	hub.CloseAll()
+	assert.Equal(t, CloseServerShutdown, readClose(t, phone))
+	assert.Equal(t, CloseServerShutdown, readClose(t, laptop))
+}
+
+func TestChatHub_BannedUserIsDisconnected(t *testing.T) {
+	hub := newTestChatHub(t)
+	pubsub := newFakePubSub()
+	hub.subscribe = func(ctx context.Context, channel string) pubSubConn {
+		if channel == domain.UserDisconnectChannel {
+			return pubsub
+		}
+		return newFakePubSub() // conversation channels stay quiet
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.FollowUserDisconnects(ctx)
+
+	banned, other := uuid.New(), uuid.New()
+	conversationID := uuid.New()
+	conn := dialHub(t, hub, banned, conversationID)
+	peer := dialHub(t, hub, other, conversationID)
+	chatConnected(t, hub, banned, 1)
+
+	for _, event := range []domain.UserDisconnectEvent{
+		{UserID: other, Reason: "unknown"}, // ignored
+		{UserID: banned, Reason: "banned"},
+	} {
+		payload, err := json.Marshal(event)
+		require.NoError(t, err)
+		pubsub.messages <- &redis.Message{Payload: string(payload)}
+	}
+
+	assert.Equal(t, CloseBanned, readClose(t, conn))
+	require.NotNil(t, readUntil(t, peer, MessageTypeUserJoined, 2*time.Second), "other users stay connected")
+}
+
+func TestSignalingHub_ExpiredTokenClosesWithAuthExpired(t *testing.T) {
+	hub := newTestSignalingHub(t, 1024, 1<<20)
+
+	conn := dialSignalingUntil(t, hub, uuid.New(), uuid.New(), time.Now().Add(200*time.Millisecond))
+	assert.Equal(t, CloseAuthExpired, readClose(t, conn))
+}
+
+func TestSignalingHub_CloseAllSendsServerShutdown(t *testing.T) {
+	hub := newTestSignalingHub(t, 1024, 1<<20)
+	callID := uuid.New()
+
+	caller := dialSignaling(t, hub, uuid.New(), callID)
+	callee := dialSignaling(t, hub, uuid.New(), callID)
+	require.NotNil(t, readSignal(t, caller, SignalTypeJoin, 2*time.Second), "both peers are in the call")
+
+	hub.CloseAll()
+	assert.Equal(t, CloseServerShutdown, readClose(t, caller))
+	assert.Equal(t, CloseServerShutdown, readClose(t, callee))
+}
+
+func TestSignalingHub_NewerConnectionReplacesOlder(t *testing.T) {
+	hub := newTestSignalingHub(t, 1024, 1<<20)
+	callID := uuid.New()
+	userID := uuid.New()
+
+	peer := dialSignaling(t, hub, uuid.New(), callID)
+	older := dialSignaling(t, hub, userID, callID)
+	require.NotNil(t, readSignal(t, peer, SignalTypeJoin, 2*time.Second))
+
+	newer := dialSignaling(t, hub, userID, callID)
+	assert.Equal(t, CloseReplaced, readClose(t, older))
+	require.NotNil(t, readSignal(t, peer, SignalTypeJoin, 2*time.Second), "the newer connection joins")
+
+	require.NoError(t, newer.WriteJSON(SignalingMessage{Type: SignalTypeOffer, SDP: "v=0"}))
+	offer := readSignal(t, peer, SignalTypeOffer, 2*time.Second)
+	require.NotNil(t, offer, "the newer connection relays")
+	assert.Equal(t, userID, offer.SenderID)
+	assert.Nil(t, readSignal(t, peer, SignalTypeLeave, 300*time.Millisecond), "replacing a connection does not announce a leave")
+}
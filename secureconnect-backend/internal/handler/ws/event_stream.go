@@ -0,0 +1,235 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+const (
+	// streamReadBlock is how long one XREAD waits before it is reissued. It
+	// also bounds how late a newly followed conversation joins the read.
+	streamReadBlock = time.Second
+
+	// streamReplayLimit bounds how many events are replayed to a reconnecting
+	// client; further behind than this it is told to resync instead
+	streamReplayLimit = 500
+)
+
+// EventStream is a capped, replayable log of conversation events
+type EventStream interface {
+	Append(ctx context.Context, conversationID uuid.UUID, payload []byte) (string, error)
+	// Read waits up to block for events after each conversation's cursor
+	Read(ctx context.Context, cursors map[uuid.UUID]string, block time.Duration) (map[uuid.UUID][]domain.ConversationEvent, error)
+	Range(ctx context.Context, conversationID uuid.UUID, afterID string, count int64) ([]domain.ConversationEvent, error)
+}
+
+// SetEventStream makes the hub read conversation events from a replayable
+// stream instead of pub/sub. Every delivered event carries its stream ID as
+// a cursor, and a client reconnecting with ?cursor= is sent what it missed.
+// Publishers must append to the same stream. All followed conversations are
+// read by one goroutine with one blocking read, so a hub holds a single
+// Redis connection for events however many conversations it serves.
+func (h *ChatHub) SetEventStream(events EventStream) {
+	h.events = events
+	h.streamCursors = make(map[uuid.UUID]string)
+	h.streamWake = make(chan struct{}, 1)
+	h.goFollow(h.readEventStreams)
+}
+
+// isStreamedEvent reports whether a client-sent event is appended to the
+// stream rather than broadcast only by this instance
+func isStreamedEvent(msg *Message) bool {
+	switch msg.Type {
+	case MessageTypeRead, MessageTypeTypingStop:
+		return true
+	}
+	return false
+}
+
+// appendEvent writes a client-sent event to the conversation's stream. The
+// hub delivers it when it reads the entry back, like any published event.
+func (c *Client) appendEvent(msg *Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = c.hub.events.Append(c.ctx, c.conversationID, payload)
+	return err
+}
+
+// followConversation adds the conversation to the streams read by
+// readEventStreams, starting with events appended from now
+func (h *ChatHub) followConversation(conversationID uuid.UUID) {
+	h.streamMu.Lock()
+	// Stream IDs start with a millisecond timestamp
+	h.streamCursors[conversationID] = fmt.Sprintf("%d-0", time.Now().UnixMilli())
+	h.streamMu.Unlock()
+
+	select {
+	case h.streamWake <- struct{}{}:
+	default:
+	}
+}
+
+// unfollowConversation stops reading the conversation's stream
+func (h *ChatHub) unfollowConversation(conversationID uuid.UUID) {
+	h.streamMu.Lock()
+	delete(h.streamCursors, conversationID)
+	h.streamMu.Unlock()
+}
+
+// readEventStreams reads the streams of every followed conversation and
+// broadcasts each event. Reads resume from the last event seen, so nothing
+// appended while Redis was unreachable is skipped. It returns when the hub is
+// stopped.
+func (h *ChatHub) readEventStreams() {
+	ctx := h.ctx
+	retry := h.resubscribeBackoff()
+	failures := 0
+	down := false
+
+	for {
+		h.streamMu.Lock()
+		cursors := maps.Clone(h.streamCursors)
+		h.streamMu.Unlock()
+		if len(cursors) == 0 {
+			select {
+			case <-h.streamWake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		events, err := h.events.Read(ctx, cursors, streamReadBlock)
+		if ctx.Err() != nil {
+			if down {
+				h.markSubscriptionUp()
+			}
+			return
+		}
+		if err != nil {
+			if !down {
+				down = true
+				h.markSubscriptionDown()
+			}
+			failures++
+			delay := retry.Delay(failures)
+			logger.Warn("Conversation event stream read failed, retrying",
+				zap.Int("conversations", len(cursors)),
+				zap.Duration("backoff", delay),
+				zap.Error(err))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				h.markSubscriptionUp()
+				return
+			}
+			continue
+		}
+
+		if down {
+			down = false
+			h.markSubscriptionUp()
+			logger.Info("Conversation event stream restored")
+		}
+		failures = 0
+
+		for conversationID, conversationEvents := range events {
+			if len(conversationEvents) == 0 {
+				continue
+			}
+			// Advance only conversations still followed; an unfollowed one
+			// starts again from the present if it is followed later
+			h.streamMu.Lock()
+			_, followed := h.streamCursors[conversationID]
+			if followed {
+				h.streamCursors[conversationID] = conversationEvents[len(conversationEvents)-1].ID
+			}
+			h.streamMu.Unlock()
+			if !followed {
+				continue
+			}
+
+			for _, event := range conversationEvents {
+				msg, err := decodeStreamEvent(conversationID, event)
+				if err != nil {
+					logger.Warn("Failed to unmarshal conversation event",
+						zap.String("conversation_id", conversationID.String()),
+						zap.String("cursor", event.ID),
+						zap.Error(err))
+					metrics.ChatWebSocketErrorsTotal.WithLabelValues("unmarshal_error").Inc()
+					continue
+				}
+				h.broadcast <- msg
+			}
+		}
+	}
+}
+
+// replayFromCursor sends a reconnecting client the events appended after
+// cursor. Events may also arrive live while the replay runs; clients drop
+// any cursor they have already seen.
+func (h *ChatHub) replayFromCursor(client *Client, cursor string) {
+	events, err := h.events.Range(client.ctx, client.conversationID, cursor, streamReplayLimit+1)
+	if err != nil || len(events) > streamReplayLimit {
+		if err != nil {
+			logger.Warn("Failed to replay conversation events",
+				zap.String("conversation_id", client.conversationID.String()),
+				zap.String("cursor", cursor),
+				zap.Error(err))
+		}
+		h.sendTo(client, &Message{
+			Type:           MessageTypeResync,
+			ConversationID: client.conversationID,
+			Metadata:       map[string]interface{}{"cursor": cursor},
+			Timestamp:      time.Now(),
+		})
+		return
+	}
+
+	for _, event := range events {
+		msg, err := decodeStreamEvent(client.conversationID, event)
+		if err != nil {
+			continue
+		}
+		msg.Category = EventCategoryReplay
+		h.sendTo(client, msg)
+	}
+	metrics.ChatPubSubReplayedMessagesTotal.Add(float64(len(events)))
+}
+
+// sendTo queues msg for a single client, dropping it if the client is backed up
+func (h *ChatHub) sendTo(client *Client, msg *Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case client.send <- data:
+		metrics.ChatWebSocketMessagesTotal.WithLabelValues("out").Inc()
+	default:
+	}
+}
+
+// decodeStreamEvent parses an entry of conversationID's stream. The stream
+// an event was read from decides its conversation, not its payload.
+func decodeStreamEvent(conversationID uuid.UUID, event domain.ConversationEvent) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(event.Payload, &msg); err != nil {
+		return nil, err
+	}
+	msg.ConversationID = conversationID
+	msg.Cursor = event.ID
+	return &msg, nil
+}
@@ -0,0 +1,213 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// fakeEventStream is an in-memory EventStream using Redis-style "<ms>-<seq>" IDs
+type fakeEventStream struct {
+	mu      sync.Mutex
+	seq     int
+	events  map[uuid.UUID][]domain.ConversationEvent
+	changed chan struct{}
+	reading chan struct{}
+	// active and maxActive count concurrent Read calls
+	active    int
+	maxActive int
+}
+
+func newFakeEventStream() *fakeEventStream {
+	return &fakeEventStream{
+		events:  map[uuid.UUID][]domain.ConversationEvent{},
+		changed: make(chan struct{}),
+		reading: make(chan struct{}, 1),
+	}
+}
+
+func (f *fakeEventStream) Append(ctx context.Context, conversationID uuid.UUID, payload []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	id := fmt.Sprintf("%d-%d", time.Now().UnixMilli(), f.seq)
+	f.events[conversationID] = append(f.events[conversationID], domain.ConversationEvent{ID: id, Payload: payload})
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return id, nil
+}
+
+func (f *fakeEventStream) Read(ctx context.Context, cursors map[uuid.UUID]string, block time.Duration) (map[uuid.UUID][]domain.ConversationEvent, error) {
+	select {
+	case f.reading <- struct{}{}:
+	default:
+	}
+	f.mu.Lock()
+	f.active++
+	f.maxActive = max(f.maxActive, f.active)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+	}()
+
+	timeout := time.After(block)
+	for {
+		f.mu.Lock()
+		events := make(map[uuid.UUID][]domain.ConversationEvent)
+		for conversationID, afterID := range cursors {
+			if after := f.after(conversationID, afterID, -1); len(after) > 0 {
+				events[conversationID] = after
+			}
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		if len(events) > 0 {
+			return events, nil
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (f *fakeEventStream) Range(ctx context.Context, conversationID uuid.UUID, afterID string, count int64) ([]domain.ConversationEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.after(conversationID, afterID, int(count)), nil
+}
+
+// after returns up to count events with IDs greater than afterID; a negative count means all
+func (f *fakeEventStream) after(conversationID uuid.UUID, afterID string, count int) []domain.ConversationEvent {
+	var out []domain.ConversationEvent
+	for _, event := range f.events[conversationID] {
+		if streamIDLess(afterID, event.ID) && (count < 0 || len(out) < count) {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+func streamIDLess(a, b string) bool {
+	parse := func(id string) (int64, int64) {
+		ms, seq, _ := strings.Cut(id, "-")
+		m, _ := strconv.ParseInt(ms, 10, 64)
+		s, _ := strconv.ParseInt(seq, 10, 64)
+		return m, s
+	}
+	am, as := parse(a)
+	bm, bs := parse(b)
+	return am < bm || (am == bm && as < bs)
+}
+
+func newStreamHub(t *testing.T, events EventStream) *ChatHub {
+	hub := newTestChatHub(t)
+	hub.SetEventStream(events)
+	return hub
+}
+
+func TestChatHub_EventStreamLiveDelivery(t *testing.T) {
+	stream := newFakeEventStream()
+	hub := newStreamHub(t, stream)
+
+	conversationID := uuid.New()
+	alice := dialHub(t, hub, uuid.New(), conversationID)
+	bob := dialHub(t, hub, uuid.New(), conversationID)
+	require.NotNil(t, readUntil(t, alice, MessageTypeUserJoined, 2*time.Second))
+	<-stream.reading
+
+	// A service publishing through the stream reaches every client with a cursor
+	id, err := stream.Append(context.Background(), conversationID, []byte(`{"type":"chat","content":"hello"}`))
+	require.NoError(t, err)
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		msg := readUntil(t, conn, MessageTypeChat, 2*time.Second)
+		require.NotNil(t, msg)
+		assert.Equal(t, "hello", msg.Content)
+		assert.Equal(t, id, msg.Cursor)
+	}
+
+	// Read receipts from clients are appended, then delivered from the stream
+	require.NoError(t, alice.WriteJSON(Message{Type: MessageTypeRead, MessageID: uuid.New()}))
+	read := readUntil(t, bob, MessageTypeRead, 2*time.Second)
+	require.NotNil(t, read)
+	assert.NotEmpty(t, read.Cursor)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	require.Len(t, stream.events[conversationID], 2)
+	assert.Equal(t, read.Cursor, stream.events[conversationID][1].ID)
+}
+
+func TestChatHub_EventStreamReadsConversationsTogether(t *testing.T) {
+	stream := newFakeEventStream()
+	hub := newStreamHub(t, stream)
+
+	conversations := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	conns := make([]*websocket.Conn, len(conversations))
+	for i, conversationID := range conversations {
+		conns[i] = dialHub(t, hub, uuid.New(), conversationID)
+		require.NotNil(t, readUntil(t, conns[i], MessageTypeUserJoined, 2*time.Second))
+	}
+
+	for i, conversationID := range conversations {
+		_, err := stream.Append(context.Background(), conversationID, []byte(fmt.Sprintf(`{"type":"chat","content":"m%d"}`, i)))
+		require.NoError(t, err)
+	}
+	for i, conn := range conns {
+		msg := readUntil(t, conn, MessageTypeChat, 3*time.Second)
+		require.NotNil(t, msg)
+		assert.Equal(t, fmt.Sprintf("m%d", i), msg.Content, "each event reaches its own conversation")
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	assert.Equal(t, 1, stream.maxActive, "one read serves every followed conversation")
+}
+
+func TestChatHub_EventStreamReplaysFromCursor(t *testing.T) {
+	stream := newFakeEventStream()
+	hub := newStreamHub(t, stream)
+
+	ctx := context.Background()
+	conversationID := uuid.New()
+	var ids []string
+	for i := 1; i <= 3; i++ {
+		id, err := stream.Append(ctx, conversationID, []byte(fmt.Sprintf(`{"type":"chat","content":"m%d"}`, i)))
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	conn := dialHubFrom(t, hub, uuid.New(), conversationID, ids[0])
+	for _, want := range []string{"m2", "m3"} {
+		msg := readUntil(t, conn, MessageTypeChat, 2*time.Second)
+		require.NotNil(t, msg)
+		assert.Equal(t, want, msg.Content)
+		assert.Equal(t, EventCategoryReplay, msg.Category)
+	}
+
+	// A client further behind than the replay limit is told to resync
+	for i := 0; i < streamReplayLimit; i++ {
+		_, err := stream.Append(ctx, conversationID, []byte(`{"type":"chat"}`))
+		require.NoError(t, err)
+	}
+	behind := dialHubFrom(t, hub, uuid.New(), conversationID, ids[0])
+	resync := readUntil(t, behind, MessageTypeResync, 2*time.Second)
+	require.NotNil(t, resync)
+	assert.Equal(t, ids[0], resync.Metadata["cursor"])
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
-	"secureconnect-backend/internal/repository/cockroach"
 	"secureconnect-backend/pkg/constants"
 	"secureconnect-backend/pkg/logger"
 )
@@ -251,7 +250,7 @@ func (h *PollHub) subscribeToConversation(ctx context.Context, conversationID uu
 }
 
 // ServePollWS handles WebSocket requests for polls
-func (h *PollHub) ServePollWS(c *gin.Context, conversationRepo *cockroach.ConversationRepository) {
+func (h *PollHub) ServePollWS(c *gin.Context, membership MembershipChecker) {
 	// Acquire semaphore to limit concurrent connections
 	select {
 	case h.semaphore <- struct{}{}:
@@ -294,7 +293,7 @@ func (h *PollHub) ServePollWS(c *gin.Context, conversationRepo *cockroach.Conver
 	}
 
 	// Validate user is a participant in conversation
-	isParticipant, err := conversationRepo.IsParticipant(c.Request.Context(), conversationID, userID)
+	isParticipant, err := membership.IsMember(c.Request.Context(), userID, conversationID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to verify conversation membership"})
 		return
@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/pkg/metrics"
+)
+
+// defaultPresenceDebounce is how long a user_left is held back waiting for a reconnect
+const defaultPresenceDebounce = 3 * time.Second
+
+// presenceKey identifies a user's presence in one conversation
+type presenceKey struct {
+	conversationID uuid.UUID
+	userID         uuid.UUID
+}
+
+// pendingLeave is a user_left event waiting out the debounce window
+type pendingLeave struct {
+	timer *time.Timer
+}
+
+// presenceDebounceFromEnv reads CHAT_PRESENCE_DEBOUNCE; 0 disables debouncing
+func presenceDebounceFromEnv() time.Duration {
+	if val := os.Getenv("CHAT_PRESENCE_DEBOUNCE"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultPresenceDebounce
+}
+
+// SetPresenceDebounce sets how long user_left events are delayed so that a
+// disconnect followed quickly by a reconnect produces no presence events.
+// Zero publishes every join and leave immediately.
+func (h *ChatHub) SetPresenceDebounce(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.presenceDebounce = d
+}
+
+// announceLeaveLocked broadcasts msg once the debounce window passes without
+// the user reconnecting. With debouncing off it returns msg for the caller to
+// broadcast after releasing h.mu; otherwise it returns nil. Called with h.mu held.
+func (h *ChatHub) announceLeaveLocked(msg *Message) *Message {
+	if h.presenceDebounce <= 0 {
+		return msg
+	}
+
+	key := presenceKey{msg.ConversationID, msg.SenderID}
+	if existing, ok := h.pendingLeaves[key]; ok {
+		// Another device of the same user left; restart the window
+		existing.timer.Stop()
+	}
+
+	entry := &pendingLeave{}
+	entry.timer = time.AfterFunc(h.presenceDebounce, func() {
+		h.mu.Lock()
+		if h.pendingLeaves[key] != entry {
+			h.mu.Unlock()
+			return
+		}
+		delete(h.pendingLeaves, key)
+		h.mu.Unlock()
+
+		h.broadcast <- msg
+	})
+	h.pendingLeaves[key] = entry
+	return nil
+}
+
+// cancelPendingLeaveLocked drops a pending user_left for key and reports whether
+// there was one, in which case the matching user_joined is suppressed too.
+// Called with h.mu held.
+func (h *ChatHub) cancelPendingLeaveLocked(key presenceKey) bool {
+	entry, ok := h.pendingLeaves[key]
+	if !ok {
+		return false
+	}
+	entry.timer.Stop()
+	delete(h.pendingLeaves, key)
+	metrics.ChatPresenceFlapsSuppressedTotal.Inc()
+	return true
+}
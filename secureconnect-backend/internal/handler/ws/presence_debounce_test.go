@@ -0,0 +1,163 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchPresence reads conn in the background and forwards join/leave events about userID.
+// A timed-out read breaks a websocket connection, so reads never use a deadline.
+func watchPresence(t *testing.T, conn *websocket.Conn, userID uuid.UUID) <-chan string {
+	t.Helper()
+	events := make(chan string, 16)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg Message
+			if json.Unmarshal(data, &msg) == nil && msg.SenderID == userID &&
+				(msg.Type == MessageTypeUserJoined || msg.Type == MessageTypeUserLeft) {
+				events <- msg.Type
+			}
+		}
+	}()
+	return events
+}
+
+// collect returns the events received within d
+func collect(events <-chan string, d time.Duration) []string {
+	var got []string
+	deadline := time.After(d)
+	for {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-deadline:
+			return got
+		}
+	}
+}
+
+// joinStopped adds a client to a hub whose run loop is not started
+func joinStopped(hub *ChatHub, userID, conversationID uuid.UUID) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{
+		hub:            hub,
+		send:           make(chan []byte, 1),
+		userID:         userID,
+		conversationID: conversationID,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.conversations[conversationID] == nil {
+		hub.conversations[conversationID] = make(map[*Client]bool)
+	}
+	hub.conversations[conversationID][client] = true
+	if hub.userClients[userID] == nil {
+		hub.userClients[userID] = make(map[*Client]bool)
+	}
+	hub.userClients[userID][client] = true
+	return client
+}
+
+// fillBroadcast fills the hub's broadcast buffer with chat messages
+func fillBroadcast(hub *ChatHub) {
+	for len(hub.broadcast) < cap(hub.broadcast) {
+		hub.broadcast <- &Message{Type: MessageTypeChat}
+	}
+}
+
+// unregisterWithin unregisters client and fails the test if that blocks
+func unregisterWithin(t *testing.T, hub *ChatHub, client *Client, d time.Duration) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		hub.unregisterClient(client)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(d):
+		t.Fatal("unregistering blocked on the full broadcast buffer")
+	}
+	require.True(t, hub.mu.TryLock(), "the hub lock is released")
+	hub.mu.Unlock()
+}
+
+// drainBroadcast reads the hub's broadcast buffer until it has seen n
+// messages other than the chat fillers, and returns their types
+func drainBroadcast(t *testing.T, hub *ChatHub, n int) []string {
+	t.Helper()
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < n {
+		select {
+		case msg := <-hub.broadcast:
+			if msg.Type != MessageTypeChat {
+				got = append(got, msg.Type)
+			}
+		case <-timeout:
+			t.Fatalf("got %v, want %d messages", got, n)
+		}
+	}
+	return got
+}
+
+func TestChatHub_PresenceFlapIsDebounced(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetPresenceDebounce(300 * time.Millisecond)
+
+	conversationID := uuid.New()
+	userID := uuid.New()
+	peer := watchPresence(t, dialHub(t, hub, uuid.New(), conversationID), userID)
+
+	phone := dialHub(t, hub, userID, conversationID)
+	assert.Equal(t, []string{MessageTypeUserJoined}, collect(peer, 200*time.Millisecond))
+
+	// A flaky connection drops and comes straight back
+	phone.Close()
+	time.Sleep(50 * time.Millisecond)
+	dialHub(t, hub, userID, conversationID)
+
+	assert.Empty(t, collect(peer, 600*time.Millisecond), "flap must not produce presence events")
+}
+
+func TestChatHub_PresenceLeaveAfterDebounce(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetPresenceDebounce(100 * time.Millisecond)
+
+	conversationID := uuid.New()
+	userID := uuid.New()
+	peer := watchPresence(t, dialHub(t, hub, uuid.New(), conversationID), userID)
+
+	phone := dialHub(t, hub, userID, conversationID)
+	assert.Equal(t, []string{MessageTypeUserJoined}, collect(peer, 100*time.Millisecond))
+
+	phone.Close()
+	assert.Equal(t, []string{MessageTypeUserLeft}, collect(peer, 500*time.Millisecond))
+}
+
+func TestChatHub_ImmediateLeaveDoesNotBlockOnFullBroadcast(t *testing.T) {
+	hub := newChatHub(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}))
+	t.Cleanup(hub.Stop)
+	hub.SetPresenceDebounce(0)
+	client := joinStopped(hub, uuid.New(), uuid.New())
+
+	fillBroadcast(hub)
+	unregisterWithin(t, hub, client, time.Second)
+
+	assert.Equal(t, []string{MessageTypeUserLeft}, drainBroadcast(t, hub, 1),
+		"user_left is queued once the buffer has room")
+}
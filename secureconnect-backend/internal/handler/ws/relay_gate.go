@@ -0,0 +1,78 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// relayCheckTimeout bounds the check of one relayed chat message
+const relayCheckTimeout = 5 * time.Second
+
+// RelayGate decides whether a chat message a client sends over the socket may
+// be relayed to the conversation. Such messages are not stored, so they get
+// the same checks here that the chat service applies to stored messages.
+type RelayGate interface {
+	// CheckRelayedMessage returns domain.ErrNotParticipant, a
+	// *domain.MutedError for a muted sender, or a *domain.BlockedMessageError
+	// for a message moderation rejects
+	CheckRelayedMessage(ctx context.Context, conversationID, senderID uuid.UUID, content string) error
+}
+
+// SetRelayGate checks every chat message clients send over the socket with gate
+func (h *ChatHub) SetRelayGate(gate RelayGate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.relayGate = gate
+}
+
+// allowRelay reports whether msg may be relayed. A rejected message is
+// answered with message_rejected to the sender only.
+func (c *Client) allowRelay(msg *Message) bool {
+	c.hub.mu.RLock()
+	gate := c.hub.relayGate
+	c.hub.mu.RUnlock()
+	if gate == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, relayCheckTimeout)
+	defer cancel()
+	err := gate.CheckRelayedMessage(ctx, c.conversationID, c.userID, msg.Content)
+	if err == nil {
+		return true
+	}
+
+	reason := "message could not be sent"
+	var blocked *domain.BlockedMessageError
+	var muted *domain.MutedError
+	switch {
+	case errors.As(err, &blocked):
+		reason = blocked.Reason
+	case errors.As(err, &muted):
+		reason = muted.Error()
+	case errors.Is(err, domain.ErrNotParticipant):
+		reason = "not a participant in this conversation"
+	default:
+		logger.Warn("Failed to check relayed chat message",
+			zap.String("conversation_id", c.conversationID.String()),
+			zap.String("user_id", c.userID.String()),
+			zap.Error(err))
+	}
+	metrics.ChatWebSocketErrorsTotal.WithLabelValues("relay_rejected").Inc()
+	c.hub.sendTo(c, &Message{
+		Type:           MessageTypeMessageRejected,
+		ConversationID: c.conversationID,
+		MessageID:      msg.MessageID,
+		Content:        reason,
+		Timestamp:      time.Now(),
+	})
+	return false
+}
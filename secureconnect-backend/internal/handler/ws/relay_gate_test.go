@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// fakeRelayGate blocks messages containing "spam"
+type fakeRelayGate struct{}
+
+func (fakeRelayGate) CheckRelayedMessage(ctx context.Context, conversationID, senderID uuid.UUID, content string) error {
+	if strings.Contains(content, "spam") {
+		return &domain.BlockedMessageError{Reason: "links to known spam domains"}
+	}
+	return nil
+}
+
+func TestChatHub_RelayGateRejectsBlockedMessages(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetRelayGate(fakeRelayGate{})
+
+	conversationID := uuid.New()
+	sender := dialHub(t, hub, uuid.New(), conversationID)
+	peer := dialHub(t, hub, uuid.New(), conversationID)
+	require.NotNil(t, readUntil(t, sender, MessageTypeUserJoined, 2*time.Second))
+
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeChat, Content: "buy spam"}))
+	rejected := readUntil(t, sender, MessageTypeMessageRejected, 2*time.Second)
+	require.NotNil(t, rejected)
+	assert.Equal(t, "links to known spam domains", rejected.Content)
+
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeChat, Content: "hello"}))
+	delivered := readUntil(t, peer, MessageTypeChat, 2*time.Second)
+	require.NotNil(t, delivered)
+	assert.Equal(t, "hello", delivered.Content, "the blocked message never reached the peer")
+}
+
+func TestChatHub_DropsForgedServerEvents(t *testing.T) {
+	hub := newTestChatHub(t)
+
+	conversationID := uuid.New()
+	sender := dialHub(t, hub, uuid.New(), conversationID)
+	peer := dialHub(t, hub, uuid.New(), conversationID)
+	require.NotNil(t, readUntil(t, sender, MessageTypeUserJoined, 2*time.Second))
+
+	forged := []string{
+		MessageTypeParticipantMuted, MessageTypeParticipantUnmuted, MessageTypeE2EEDisabled,
+		MessageTypePollCreated, MessageTypePollVoted, MessageTypePollClosed, MessageTypeBotAdded,
+		MessageTypeUserLeft, MessageTypeResync, MessageTypeUnreadUpdate, "",
+	}
+	for _, msgType := range forged {
+		require.NoError(t, sender.WriteJSON(Message{Type: msgType, MessageID: uuid.New(), Content: "forged"}))
+	}
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeChat, Content: "real"}))
+
+	// Events arrive in order, so the real message comes first if all forged ones were dropped
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg Message
+		require.NoError(t, peer.ReadJSON(&msg))
+		if msg.Type == MessageTypeUserJoined {
+			continue
+		}
+		assert.Equal(t, MessageTypeChat, msg.Type)
+		assert.Equal(t, "real", msg.Content)
+		return
+	}
+}
+
+func TestChatHub_MutedSenderIsRejected(t *testing.T) {
+	hub := newTestChatHub(t)
+	mutedID := uuid.New()
+	hub.SetRelayGate(mutedGate{mutedID})
+
+	conversationID := uuid.New()
+	sender := dialHub(t, hub, mutedID, conversationID)
+	require.NotNil(t, readUntil(t, sender, MessageTypeUserJoined, 2*time.Second))
+
+	require.NoError(t, sender.WriteJSON(Message{Type: MessageTypeChat, Content: "hello"}))
+	rejected := readUntil(t, sender, MessageTypeMessageRejected, 2*time.Second)
+	require.NotNil(t, rejected)
+	assert.Contains(t, rejected.Content, "muted")
+}
+
+// mutedGate rejects every message of userID as muted
+type mutedGate struct {
+	userID uuid.UUID
+}
+
+func (g mutedGate) CheckRelayedMessage(ctx context.Context, conversationID, senderID uuid.UUID, content string) error {
+	if senderID == g.userID {
+		return &domain.MutedError{Until: time.Now().Add(time.Hour)}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -16,8 +17,11 @@ import (
 	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/database"
+	"secureconnect-backend/pkg/backoff"
 	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/env"
 	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
 )
 
 // SignalingHub manages WebRTC signaling connections
@@ -43,8 +47,23 @@ type SignalingHub struct {
 	maxConnections int
 	// Semaphore for limiting concurrent connections
 	semaphore chan struct{}
+
+	// maxMessageBytes caps a single signaling message; larger ones close the connection
+	maxMessageBytes int64
+	// relayBudgetBytes caps the total a connection may relay so peers cannot use
+	// the server as a data channel
+	relayBudgetBytes int64
+	// callRelayedBytes accumulates relayed bytes per call for metrics
+	callRelayedBytes map[uuid.UUID]int64
 }
 
+// Default signaling relay limits. An SDP offer with many codecs and candidates
+// stays well under 64 KiB.
+const (
+	DefaultSignalingMaxMessageBytes  = 64 * 1024
+	DefaultSignalingRelayBudgetBytes = 8 * 1024 * 1024
+)
+
 // SignalingClient represents a WebSocket client for signaling
 type SignalingClient struct {
 	hub    *SignalingHub
@@ -54,6 +73,21 @@ type SignalingClient struct {
 	callID uuid.UUID
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// relayed is the number of bytes this connection has sent for relay.
+	// Only readPump writes it; run reads it after readPump has returned.
+	relayed int64
+
+	// closeOnce makes sure only the first close reason is sent
+	closeOnce sync.Once
+}
+
+// closeWith ends the connection with reason; the read pump then unregisters
+// the client
+func (c *SignalingClient) closeWith(reason CloseReason) {
+	c.closeOnce.Do(func() {
+		closeConn(c.conn, reason)
+	})
 }
 
 // SignalingMessage types
@@ -119,13 +153,31 @@ func NewSignalingHub(redisClient *database.RedisClient) *SignalingHub {
 		broadcast:           make(chan *SignalingMessage, 256),
 		maxConnections:      maxConns,
 		semaphore:           make(chan struct{}, maxConns),
+		maxMessageBytes:     DefaultSignalingMaxMessageBytes,
+		relayBudgetBytes:    DefaultSignalingRelayBudgetBytes,
+		callRelayedBytes:    make(map[uuid.UUID]int64),
 	}
+	hub.SetRelayLimits(
+		int64(env.GetInt("WS_SIGNALING_MAX_MESSAGE_BYTES", DefaultSignalingMaxMessageBytes)),
+		int64(env.GetInt("WS_SIGNALING_RELAY_BUDGET_BYTES", DefaultSignalingRelayBudgetBytes)),
+	)
 
 	go hub.run()
 
 	return hub
 }
 
+// SetRelayLimits sets the per-message size cap and the per-connection relay
+// budget in bytes. Non-positive values keep the current limit.
+func (h *SignalingHub) SetRelayLimits(maxMessageBytes, relayBudgetBytes int64) {
+	if maxMessageBytes > 0 {
+		h.maxMessageBytes = maxMessageBytes
+	}
+	if relayBudgetBytes > 0 {
+		h.relayBudgetBytes = relayBudgetBytes
+	}
+}
+
 // run handles hub operations
 func (h *SignalingHub) run() {
 	for {
@@ -149,6 +201,14 @@ func (h *SignalingHub) run() {
 					go h.subscribeToCall(ctx, client.callID)
 				}
 			}
+			// A user has one signaling connection per call; a reconnect
+			// replaces the old one without announcing that they left
+			for old := range h.calls[client.callID] {
+				if old.userID == client.userID {
+					delete(h.calls[client.callID], old)
+					go old.closeWith(CloseReplaced)
+				}
+			}
 			h.calls[client.callID][client] = true
 			h.mu.Unlock()
 
@@ -161,12 +221,12 @@ func (h *SignalingHub) run() {
 			}
 
 		case client := <-h.unregister:
+			client.cancel() // Cancel client context, also of replaced clients
 			h.mu.Lock()
 			if clients, ok := h.calls[client.callID]; ok {
 				if _, exists := clients[client]; exists {
 					delete(clients, client)
 					close(client.send)
-					client.cancel() // Cancel client context
 
 					// Notify others that user left
 					h.broadcast <- &SignalingMessage{
@@ -176,8 +236,12 @@ func (h *SignalingHub) run() {
 						Timestamp: time.Now(),
 					}
 
+					h.callRelayedBytes[client.callID] += client.relayed
+
 					// Clean up empty calls
 					if len(clients) == 0 {
+						metrics.SignalingCallRelayedBytes.Observe(float64(h.callRelayedBytes[client.callID]))
+						delete(h.callRelayedBytes, client.callID)
 						// Cancel Redis subscription
 						if cancel, ok := h.subscriptionCancels[client.callID]; ok {
 							cancel()
@@ -201,8 +265,7 @@ func (h *SignalingHub) run() {
 							select {
 							case client.send <- messageJSON:
 							default:
-								close(client.send)
-								delete(clients, client)
+								go client.closeWith(CloseTooSlow)
 							}
 							break
 						}
@@ -214,8 +277,7 @@ func (h *SignalingHub) run() {
 							select {
 							case client.send <- messageJSON:
 							default:
-								close(client.send)
-								delete(clients, client)
+								go client.closeWith(CloseTooSlow)
 							}
 						}
 					}
@@ -226,6 +288,56 @@ func (h *SignalingHub) run() {
 	}
 }
 
+// CloseAll closes every connected client with CloseServerShutdown. It is
+// registered as a server shutdown hook since hijacked WebSocket connections
+// are not drained by http.Server.Shutdown.
+func (h *SignalingHub) CloseAll() {
+	h.mu.RLock()
+	var clients []*SignalingClient
+	for _, members := range h.calls {
+		for client := range members {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.closeWith(CloseServerShutdown)
+	}
+}
+
+// DisconnectUser closes every signaling connection of userID with reason and
+// returns how many were closed
+func (h *SignalingHub) DisconnectUser(userID uuid.UUID, reason CloseReason) int {
+	h.mu.RLock()
+	var clients []*SignalingClient
+	for _, members := range h.calls {
+		for client := range members {
+			if client.userID == userID {
+				clients = append(clients, client)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.closeWith(reason)
+	}
+	return len(clients)
+}
+
+// FollowUserDisconnects closes the connections of users published on
+// domain.UserDisconnectChannel, e.g. when they are banned, until ctx is done
+func (h *SignalingHub) FollowUserDisconnects(ctx context.Context) {
+	subscribe := func(ctx context.Context, channel string) pubSubConn {
+		if pubsub := h.redisClient.SafeSubscribe(ctx, channel); pubsub != nil {
+			return pubsub
+		}
+		return nil
+	}
+	followUserDisconnects(ctx, subscribe, backoff.Config{Base: time.Second, Max: 30 * time.Second}, h.DisconnectUser)
+}
+
 // subscribeToCall subscribes to Redis Pub/Sub for a call
 func (h *SignalingHub) subscribeToCall(ctx context.Context, callID uuid.UUID) {
 	channel := fmt.Sprintf("call:%s", callID)
@@ -324,6 +436,12 @@ func (h *SignalingHub) ServeWS(c *gin.Context) {
 		return
 	}
 
+	h.attach(conn, userID, callID, tokenExpiry(c.Get("token_expires_at")))
+}
+
+// attach registers an upgraded connection with the hub and starts its pumps.
+// The connection is closed with CloseAuthExpired at expiresAt unless it is zero.
+func (h *SignalingHub) attach(conn *websocket.Conn, userID, callID uuid.UUID, expiresAt time.Time) {
 	// Create cancelable context for this client
 	ctx, cancel := context.WithCancel(context.Background())
 	client := &SignalingClient{
@@ -341,6 +459,7 @@ func (h *SignalingHub) ServeWS(c *gin.Context) {
 	// Start goroutines for read/write
 	go client.writePump()
 	go client.readPump()
+	go closeAtExpiry(ctx, expiresAt, client.closeWith)
 }
 
 // readPump reads messages from WebSocket
@@ -350,6 +469,8 @@ func (c *SignalingClient) readPump() {
 		c.conn.Close()
 	}()
 
+	// Larger messages make ReadMessage fail and close the connection with 1009
+	c.conn.SetReadLimit(c.hub.maxMessageBytes)
 	c.conn.SetReadDeadline(time.Now().Add(constants.WebSocketPingInterval))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(constants.WebSocketPingInterval))
@@ -359,6 +480,14 @@ func (c *SignalingClient) readPump() {
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				metrics.SignalingConnectionsDroppedTotal.WithLabelValues("message_too_large").Inc()
+				logger.Warn("Signaling message too large, closing connection",
+					zap.String("call_id", c.callID.String()),
+					zap.String("user_id", c.userID.String()),
+					zap.Int64("max_bytes", c.hub.maxMessageBytes))
+				break
+			}
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logger.Debug("WebSocket connection closed",
 					zap.String("call_id", c.callID.String()),
@@ -368,6 +497,18 @@ func (c *SignalingClient) readPump() {
 			break
 		}
 
+		c.relayed += int64(len(message))
+		if c.relayed > c.hub.relayBudgetBytes {
+			metrics.SignalingConnectionsDroppedTotal.WithLabelValues("budget_exceeded").Inc()
+			logger.Warn("Signaling relay budget exceeded, closing connection",
+				zap.String("call_id", c.callID.String()),
+				zap.String("user_id", c.userID.String()),
+				zap.Int64("relayed_bytes", c.relayed),
+				zap.Int64("budget_bytes", c.hub.relayBudgetBytes))
+			c.closeWith(CloseRateLimited)
+			break
+		}
+
 		// Parse message
 		var msg SignalingMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
@@ -383,6 +524,9 @@ func (c *SignalingClient) readPump() {
 		msg.CallID = c.callID
 		msg.Timestamp = time.Now()
 
+		metrics.SignalingRelayedMessagesTotal.Inc()
+		metrics.SignalingRelayedBytesTotal.Add(float64(len(message)))
+
 		// Broadcast to hub
 		c.hub.broadcast <- &msg
 	}
@@ -0,0 +1,120 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+)
+
+// newTestSignalingHub returns a hub whose Redis is unreachable, so signaling stays local
+func newTestSignalingHub(t *testing.T, maxMessageBytes, relayBudgetBytes int64) *SignalingHub {
+	t.Helper()
+	redisDB, err := database.NewRedisDB(&database.RedisConfig{Host: "127.0.0.1", Port: 1, Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	t.Cleanup(redisDB.Close)
+	require.Error(t, redisDB.HealthCheck(context.Background()))
+
+	hub := NewSignalingHub(redisDB)
+	hub.SetRelayLimits(maxMessageBytes, relayBudgetBytes)
+	return hub
+}
+
+// dialSignaling connects userID to callID on hub through a real WebSocket
+func dialSignaling(t *testing.T, hub *SignalingHub, userID, callID uuid.UUID) *websocket.Conn {
+	t.Helper()
+	return dialSignalingUntil(t, hub, userID, callID, time.Time{})
+}
+
+// dialSignalingUntil is dialSignaling for a client whose access token expires at expiresAt
+func dialSignalingUntil(t *testing.T, hub *SignalingHub, userID, callID uuid.UUID, expiresAt time.Time) *websocket.Conn {
+	t.Helper()
+	testUpgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := testUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.attach(conn, userID, callID, expiresAt)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readSignal returns the first signaling message of the given type, or nil if none arrives within timeout
+func readSignal(t *testing.T, conn *websocket.Conn, signalType string, timeout time.Duration) *SignalingMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		var msg SignalingMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		if msg.Type == signalType {
+			return &msg
+		}
+	}
+}
+
+// readCloseCode reads until the server closes the connection and returns the close code
+func readCloseCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			require.ErrorAs(t, err, &closeErr)
+			return closeErr.Code
+		}
+	}
+}
+
+func TestSignalingHub_RejectsOversizedMessage(t *testing.T) {
+	hub := newTestSignalingHub(t, 1024, 1<<20)
+	callID := uuid.New()
+
+	sender := dialSignaling(t, hub, uuid.New(), callID)
+	peer := dialSignaling(t, hub, uuid.New(), callID)
+	require.NotNil(t, readSignal(t, sender, SignalTypeJoin, 2*time.Second), "both peers are in the call")
+
+	require.NoError(t, sender.WriteJSON(SignalingMessage{Type: SignalTypeOffer, SDP: strings.Repeat("a", 2048)}))
+
+	assert.Equal(t, websocket.CloseMessageTooBig, readCloseCode(t, sender))
+	assert.Nil(t, readSignal(t, peer, SignalTypeOffer, 300*time.Millisecond), "oversized offer is not relayed")
+}
+
+func TestSignalingHub_EnforcesRelayBudget(t *testing.T) {
+	hub := newTestSignalingHub(t, 1024, 1500)
+	callID := uuid.New()
+
+	sender := dialSignaling(t, hub, uuid.New(), callID)
+	peer := dialSignaling(t, hub, uuid.New(), callID)
+	require.NotNil(t, readSignal(t, sender, SignalTypeJoin, 2*time.Second), "both peers are in the call")
+
+	offer := SignalingMessage{Type: SignalTypeOffer, SDP: strings.Repeat("a", 600)}
+	require.NoError(t, sender.WriteJSON(offer))
+	require.NotNil(t, readSignal(t, peer, SignalTypeOffer, 2*time.Second), "messages within the budget are relayed")
+
+	require.NoError(t, sender.WriteJSON(offer))
+	require.NoError(t, sender.WriteJSON(offer))
+
+	assert.Equal(t, CloseRateLimited.Code, readCloseCode(t, sender))
+	assert.Nil(t, readSignal(t, peer, SignalTypeOffer, 300*time.Millisecond), "the message that exceeds the budget is not relayed")
+}
@@ -0,0 +1,179 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// DefaultTypingTTL is how long a typing indicator lasts without another typing event
+const DefaultTypingTTL = 8 * time.Second
+
+// typingStoreTimeout bounds the store lookup made when an indicator expires
+const typingStoreTimeout = 2 * time.Second
+
+// TypingStore records who is typing with a TTL, so the state is shared by
+// every instance and expires on its own
+type TypingStore interface {
+	RefreshTyping(ctx context.Context, conversationID, userID uuid.UUID, ttl time.Duration) error
+	ClearTyping(ctx context.Context, conversationID, userID uuid.UUID) error
+	// TypingTTL returns how long userID remains typing, or 0 when they are not
+	TypingTTL(ctx context.Context, conversationID, userID uuid.UUID) (time.Duration, error)
+}
+
+// typingEntry is a user shown as typing until its timer fires
+type typingEntry struct {
+	timer *time.Timer
+}
+
+// SetTypingStore records typing state in store, so a user typing on a
+// device connected to another instance keeps their indicator alive here
+func (h *ChatHub) SetTypingStore(store TypingStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.typingStore = store
+}
+
+// SetTypingTTL sets how long a typing indicator lasts without another typing
+// event before the hub sends typing_stop. A non-positive value keeps the current TTL.
+func (h *ChatHub) SetTypingTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.typingTTL = ttl
+}
+
+// startTyping shows key's user as typing for the TTL, restarting it if they
+// already were. Each conversation a user types in is tracked separately.
+func (h *ChatHub) startTyping(ctx context.Context, key presenceKey) {
+	h.mu.Lock()
+	entry, ok := h.typers[key]
+	if ok {
+		entry.timer.Stop()
+	} else {
+		metrics.ChatTypingIndicatorsActive.Inc()
+	}
+	entry = &typingEntry{}
+	entry.timer = time.AfterFunc(h.typingTTL, func() { h.expireTyping(key, entry) })
+	h.typers[key] = entry
+	store, ttl := h.typingStore, h.typingTTL
+	h.mu.Unlock()
+
+	if store != nil {
+		if err := store.RefreshTyping(ctx, key.conversationID, key.userID, ttl); err != nil {
+			logger.Debug("Failed to refresh typing state",
+				zap.String("conversation_id", key.conversationID.String()),
+				zap.Error(err))
+		}
+	}
+}
+
+// stopTyping clears key's typing state after an explicit typing_stop
+func (h *ChatHub) stopTyping(ctx context.Context, key presenceKey) {
+	h.mu.Lock()
+	h.clearTypingLocked(key)
+	store := h.typingStore
+	h.mu.Unlock()
+
+	if store != nil {
+		if err := store.ClearTyping(ctx, key.conversationID, key.userID); err != nil {
+			logger.Debug("Failed to clear typing state",
+				zap.String("conversation_id", key.conversationID.String()),
+				zap.Error(err))
+		}
+	}
+}
+
+// clearTypingLocked forgets key's typing state and reports whether the user
+// was typing. h.mu must be held.
+func (h *ChatHub) clearTypingLocked(key presenceKey) bool {
+	entry, ok := h.typers[key]
+	if !ok {
+		return false
+	}
+	entry.timer.Stop()
+	delete(h.typers, key)
+	metrics.ChatTypingIndicatorsActive.Dec()
+	return true
+}
+
+// expireTyping sends typing_stop for an indicator whose TTL passed without a
+// refresh. If the store shows the user still typing, refreshed through
+// another instance, the indicator is kept for the remaining time instead.
+func (h *ChatHub) expireTyping(key presenceKey, entry *typingEntry) {
+	h.mu.RLock()
+	current, store := h.typers[key], h.typingStore
+	h.mu.RUnlock()
+	if current != entry {
+		return
+	}
+
+	if store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), typingStoreTimeout)
+		remaining, err := store.TypingTTL(ctx, key.conversationID, key.userID)
+		cancel()
+		if err == nil && remaining > 0 {
+			h.mu.Lock()
+			if h.typers[key] == entry {
+				entry.timer.Reset(remaining)
+			}
+			h.mu.Unlock()
+			return
+		}
+	}
+
+	h.mu.Lock()
+	if h.typers[key] != entry {
+		h.mu.Unlock()
+		return
+	}
+	h.clearTypingLocked(key)
+	h.mu.Unlock()
+
+	h.broadcast <- typingStopMessage(key)
+}
+
+// endTypingOnLeaveLocked clears the typing state when client was its user's
+// last connection to a conversation they were typing in, and returns the
+// typing_stop for the caller to broadcast after releasing h.mu, or nil. h.mu
+// must be held and client already removed from the hub.
+func (h *ChatHub) endTypingOnLeaveLocked(client *Client) *Message {
+	for other := range h.userClients[client.userID] {
+		if other.conversationID == client.conversationID {
+			return nil
+		}
+	}
+	key := presenceKey{client.conversationID, client.userID}
+	if !h.clearTypingLocked(key) {
+		return nil
+	}
+	if store := h.typingStore; store != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), typingStoreTimeout)
+			defer cancel()
+			if err := store.ClearTyping(ctx, key.conversationID, key.userID); err != nil {
+				logger.Debug("Failed to clear typing state",
+					zap.String("conversation_id", key.conversationID.String()),
+					zap.Error(err))
+			}
+		}()
+	}
+	return typingStopMessage(key)
+}
+
+// typingStopMessage is the typing_stop the hub sends on a user's behalf
+func typingStopMessage(key presenceKey) *Message {
+	return &Message{
+		Type:           MessageTypeTypingStop,
+		ConversationID: key.conversationID,
+		SenderID:       key.userID,
+		Timestamp:      time.Now(),
+	}
+}
@@ -0,0 +1,161 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTypingStore is an in-memory TypingStore
+type fakeTypingStore struct {
+	mu      sync.Mutex
+	expires map[presenceKey]time.Time
+}
+
+func newFakeTypingStore() *fakeTypingStore {
+	return &fakeTypingStore{expires: make(map[presenceKey]time.Time)}
+}
+
+func (s *fakeTypingStore) RefreshTyping(ctx context.Context, conversationID, userID uuid.UUID, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expires[presenceKey{conversationID, userID}] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *fakeTypingStore) ClearTyping(ctx context.Context, conversationID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, presenceKey{conversationID, userID})
+	return nil
+}
+
+func (s *fakeTypingStore) TypingTTL(ctx context.Context, conversationID, userID uuid.UUID) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if remaining := time.Until(s.expires[presenceKey{conversationID, userID}]); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// focusViewer makes conn an active viewer and waits until the hub handled it
+func focusViewer(t *testing.T, viewer, other *websocket.Conn) {
+	t.Helper()
+	require.NoError(t, viewer.WriteJSON(Message{Type: MessageTypeFocus}))
+	require.NoError(t, viewer.WriteJSON(Message{Type: MessageTypeChat, Content: "viewer ready"}))
+	require.NotNil(t, readUntil(t, other, MessageTypeChat, 2*time.Second))
+}
+
+func TestChatHub_TypingIndicatorExpires(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetTypingTTL(200 * time.Millisecond)
+	store := newFakeTypingStore()
+	hub.SetTypingStore(store)
+
+	conversationID, typerID := uuid.New(), uuid.New()
+	typer := dialHub(t, hub, typerID, conversationID)
+	viewer := dialHub(t, hub, uuid.New(), conversationID)
+	focusViewer(t, viewer, typer)
+
+	require.NoError(t, typer.WriteJSON(Message{Type: MessageTypeTyping}))
+	require.NotNil(t, readUntil(t, viewer, MessageTypeTyping, 2*time.Second))
+
+	stop := readUntil(t, viewer, MessageTypeTypingStop, 2*time.Second)
+	require.NotNil(t, stop, "the hub stops an indicator that was not refreshed")
+	assert.Equal(t, typerID, stop.SenderID)
+	assert.Equal(t, conversationID, stop.ConversationID)
+}
+
+func TestChatHub_TypingStoreKeepsIndicatorRefreshedElsewhere(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetTypingTTL(100 * time.Millisecond)
+	store := newFakeTypingStore()
+	hub.SetTypingStore(store)
+
+	key := presenceKey{uuid.New(), uuid.New()}
+	hub.startTyping(context.Background(), key)
+	// The user's device on another instance keeps typing
+	require.NoError(t, store.RefreshTyping(context.Background(), key.conversationID, key.userID, time.Hour))
+
+	time.Sleep(300 * time.Millisecond)
+	hub.mu.RLock()
+	_, typing := hub.typers[key]
+	hub.mu.RUnlock()
+	assert.True(t, typing, "the indicator lasts while the store still has it")
+}
+
+func TestChatHub_DisconnectMidTypingSendsTypingStop(t *testing.T) {
+	hub := newTestChatHub(t)
+	hub.SetPresenceDebounce(0)
+	store := newFakeTypingStore()
+	hub.SetTypingStore(store)
+
+	conversationID, typerID := uuid.New(), uuid.New()
+	typer := dialHub(t, hub, typerID, conversationID)
+	viewer := dialHub(t, hub, uuid.New(), conversationID)
+	focusViewer(t, viewer, typer)
+
+	require.NoError(t, typer.WriteJSON(Message{Type: MessageTypeTyping}))
+	require.NotNil(t, readUntil(t, viewer, MessageTypeTyping, 2*time.Second))
+	typer.Close()
+
+	stop := readUntil(t, viewer, MessageTypeTypingStop, time.Second)
+	require.NotNil(t, stop, "typing_stop is sent without waiting for the TTL")
+	assert.Equal(t, typerID, stop.SenderID)
+	require.Eventually(t, func() bool {
+		remaining, _ := store.TypingTTL(context.Background(), conversationID, typerID)
+		return remaining == 0
+	}, time.Second, 10*time.Millisecond, "the stored state is cleared")
+}
+
+func TestChatHub_DisconnectMidTypingDoesNotBlockOnFullBroadcast(t *testing.T) {
+	hub := newChatHub(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}))
+	t.Cleanup(hub.Stop)
+	hub.SetPresenceDebounce(0)
+
+	conversationID, typerID := uuid.New(), uuid.New()
+	typer := joinStopped(hub, typerID, conversationID)
+	hub.startTyping(context.Background(), presenceKey{conversationID, typerID})
+
+	fillBroadcast(hub)
+	unregisterWithin(t, hub, typer, time.Second)
+
+	assert.Equal(t, []string{MessageTypeTypingStop, MessageTypeUserLeft}, drainBroadcast(t, hub, 2),
+		"typing_stop and user_left are queued in order once the buffer has room")
+}
+
+func TestChatHub_TypingTrackedPerConversation(t *testing.T) {
+	hub := newTestChatHub(t)
+	store := newFakeTypingStore()
+	hub.SetTypingStore(store)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	first, second := presenceKey{uuid.New(), userID}, presenceKey{uuid.New(), userID}
+	hub.startTyping(ctx, first)
+	hub.startTyping(ctx, second)
+	hub.stopTyping(ctx, first)
+
+	hub.mu.RLock()
+	_, typingFirst := hub.typers[first]
+	_, typingSecond := hub.typers[second]
+	hub.mu.RUnlock()
+	assert.False(t, typingFirst)
+	assert.True(t, typingSecond, "stopping in one conversation leaves the other")
+
+	remaining, err := store.TypingTTL(ctx, second.conversationID, userID)
+	require.NoError(t, err)
+	assert.Positive(t, remaining)
+	remaining, err = store.TypingTTL(ctx, first.conversationID, userID)
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+	hub.stopTyping(ctx, second)
+}
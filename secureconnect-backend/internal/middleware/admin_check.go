@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/response"
+)
+
+// DefaultAdminCheckTimeout bounds the call RequireAdminFrom makes
+const DefaultAdminCheckTimeout = 2 * time.Second
+
+// AdminChecker reports whether the role stored for a user is admin
+type AdminChecker interface {
+	CheckAdminRole(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// RequireAdmin rejects requests from users who are not admins, for services
+// that can read users' roles themselves. It runs after AuthMiddleware.
+func RequireAdmin(checker AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			response.Unauthorized(c, "Not authenticated")
+			c.Abort()
+			return
+		}
+		id, ok := userID.(uuid.UUID)
+		if !ok {
+			response.InternalError(c, "Invalid user ID")
+			c.Abort()
+			return
+		}
+
+		isAdmin, err := checker.CheckAdminRole(c.Request.Context(), id)
+		if err != nil {
+			logger.Warn("Admin check failed", zap.String("user_id", id.String()), zap.Error(err))
+			response.Error(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Failed to check admin privileges")
+			c.Abort()
+			return
+		}
+		if !isAdmin {
+			response.Forbidden(c, "Admin privileges required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdminFrom rejects requests from users who are not admins. Access
+// tokens do not carry the admin role, so it asks the auth service, which
+// checks the role stored for the user: checkURL is called with the request's
+// Authorization header and a 2xx answer allows the request.
+func RequireAdminFrom(checkURL string, timeout time.Duration) gin.HandlerFunc {
+	if timeout <= 0 {
+		timeout = DefaultAdminCheckTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	return func(c *gin.Context) {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, checkURL, nil)
+		if err != nil {
+			response.InternalError(c, "Failed to check admin privileges")
+			c.Abort()
+			return
+		}
+		req.Header.Set("Authorization", c.GetHeader("Authorization"))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.Warn("Admin check failed", zap.String("url", checkURL), zap.Error(err))
+			response.Error(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Failed to check admin privileges")
+			c.Abort()
+			return
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			c.Next()
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			response.Forbidden(c, "Admin privileges required")
+			c.Abort()
+		default:
+			logger.Warn("Admin check returned an unexpected status",
+				zap.String("url", checkURL),
+				zap.Int("status", resp.StatusCode))
+			response.Error(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Failed to check admin privileges")
+			c.Abort()
+		}
+	}
+}
@@ -18,7 +18,7 @@ type RevocationChecker interface {
 
 // AuthMiddleware creates a Gin middleware that validates JWT tokens
 // It checks for the Authorization header, validates the token, and checks revocation status
-// If valid, it sets user_id, username, and role in the Gin context
+// If valid, it sets user_id, username, role and token_expires_at in the Gin context
 // Parameters:
 //   - jwtManager: JWT manager for token validation
 //   - revocationChecker: Optional checker for token revocation (can be nil)
@@ -64,6 +64,9 @@ func AuthMiddleware(jwtManager *jwt.JWTManager, revocationChecker RevocationChec
 				c.Set("user_id", claims.UserID)
 				c.Set("username", claims.Username)
 				c.Set("role", claims.Role)
+				if claims.ExpiresAt != nil {
+					c.Set("token_expires_at", claims.ExpiresAt.Time)
+				}
 				c.Next()
 				return
 			}
@@ -77,6 +80,10 @@ func AuthMiddleware(jwtManager *jwt.JWTManager, revocationChecker RevocationChec
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		if claims.ExpiresAt != nil {
+			// WebSocket hubs close connections when the token expires
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/pkg/response"
+)
+
+// ClientVersionHeader carries the app version, such as 2.4.1
+const ClientVersionHeader = "X-Client-Version"
+
+// DefaultClientVersionExemptRoutes stay reachable for outdated apps so they
+// can still sign in, load the config and be told to upgrade. Bots are not
+// app clients and send no version.
+var DefaultClientVersionExemptRoutes = []string{
+	"/health*",
+	"/metrics",
+	"/.well-known/*",
+	"/v1/auth/*",
+	"/v1/config",
+	"/v1/bots/*",
+}
+
+// ClientVersionConfig holds minimum client version settings
+type ClientVersionConfig struct {
+	// MinVersion is the oldest accepted version; empty disables enforcement
+	MinVersion string
+	// UpgradeURL is returned to outdated clients so they can upgrade
+	UpgradeURL string
+	// AllowMissing lets requests without the version header through
+	AllowMissing bool
+	// ExemptRoutes are never checked; entries match as in TimeoutConfig
+	ExemptRoutes []string
+}
+
+// ClientVersionGate rejects requests from apps older than the configured
+// minimum. The config can be replaced while the server runs.
+type ClientVersionGate struct {
+	settings atomic.Pointer[clientVersionSettings]
+}
+
+type clientVersionSettings struct {
+	config ClientVersionConfig
+	min    []int // nil when enforcement is disabled
+}
+
+// NewClientVersionGate creates a gate from config
+func NewClientVersionGate(config ClientVersionConfig) (*ClientVersionGate, error) {
+	g := &ClientVersionGate{}
+	if err := g.Update(config); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// MinClientVersion returns middleware rejecting clients below min, letting
+// requests without the header through and exempting the default routes. It
+// panics if min is not a valid version.
+func MinClientVersion(min string) gin.HandlerFunc {
+	g, err := NewClientVersionGate(ClientVersionConfig{
+		MinVersion:   min,
+		AllowMissing: true,
+		ExemptRoutes: DefaultClientVersionExemptRoutes,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return g.Middleware()
+}
+
+// Update replaces the config. The previous config stays in effect if the new
+// minimum is invalid.
+func (g *ClientVersionGate) Update(config ClientVersionConfig) error {
+	settings := &clientVersionSettings{config: config}
+	if config.MinVersion != "" {
+		min, ok := parseClientVersion(config.MinVersion)
+		if !ok {
+			return fmt.Errorf("invalid minimum client version %q", config.MinVersion)
+		}
+		settings.min = min
+	}
+	g.settings.Store(settings)
+	return nil
+}
+
+// MinVersion returns the minimum version currently enforced
+func (g *ClientVersionGate) MinVersion() string {
+	return g.settings.Load().config.MinVersion
+}
+
+// Middleware returns a Gin middleware enforcing the minimum client version
+func (g *ClientVersionGate) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := g.settings.Load()
+		if s.min == nil || s.exempt(c) {
+			c.Next()
+			return
+		}
+
+		header := strings.TrimSpace(c.GetHeader(ClientVersionHeader))
+		if header == "" {
+			if s.config.AllowMissing {
+				c.Next()
+				return
+			}
+			s.reject(c, "Client version is required, please upgrade the app")
+			return
+		}
+
+		version, ok := parseClientVersion(header)
+		if !ok {
+			response.ValidationError(c, "Invalid "+ClientVersionHeader+" header")
+			c.Abort()
+			return
+		}
+		if compareClientVersions(version, s.min) < 0 {
+			s.reject(c, "This version of the app is no longer supported, please upgrade")
+			return
+		}
+		c.Next()
+	}
+}
+
+// exempt reports whether the request matches an exempt route
+func (s *clientVersionSettings) exempt(c *gin.Context) bool {
+	for _, route := range s.config.ExemptRoutes {
+		if matchesRoute(route, c.FullPath()) || matchesRoute(route, c.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// reject aborts with 426 Upgrade Required
+func (s *clientVersionSettings) reject(c *gin.Context, message string) {
+	response.ErrorWithData(c, http.StatusUpgradeRequired, "UPGRADE_REQUIRED", message, gin.H{
+		"min_version": s.config.MinVersion,
+		"upgrade_url": s.config.UpgradeURL,
+	})
+	c.Abort()
+}
+
+// parseClientVersion parses a dotted numeric version such as 2.4 or v2.4.1.
+// Pre-release and build suffixes (2.4.1-beta+7) are ignored.
+func parseClientVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	version := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+// compareClientVersions returns -1, 0 or 1; missing components count as zero
+func compareClientVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClientVersionRouter(t *testing.T, allowMissing bool) (*gin.Engine, *ClientVersionGate) {
+	gin.SetMode(gin.TestMode)
+	gate, err := NewClientVersionGate(ClientVersionConfig{
+		MinVersion:   "2.1.0",
+		UpgradeURL:   "https://secureconnect.com/download",
+		AllowMissing: allowMissing,
+		ExemptRoutes: DefaultClientVersionExemptRoutes,
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(gate.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/health", ok)
+	router.POST("/v1/auth/login", ok)
+	router.GET("/v1/messages", ok)
+	return router, gate
+}
+
+func serveWithVersion(router *gin.Engine, method, path, version string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if version != "" {
+		req.Header.Set(ClientVersionHeader, version)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestClientVersionGate_Versions(t *testing.T) {
+	router, _ := newClientVersionRouter(t, true)
+
+	tests := []struct {
+		version    string
+		wantStatus int
+	}{
+		{"2.0.9", http.StatusUpgradeRequired},
+		{"1.99", http.StatusUpgradeRequired},
+		{"2.0.9-rc.1", http.StatusUpgradeRequired},
+		{"2.1.0-beta", http.StatusOK}, // suffixes are ignored
+		{"2.1.0", http.StatusOK},
+		{"2.1", http.StatusOK},
+		{"v2.10.0", http.StatusOK},
+		{"3.0.0+build.7", http.StatusOK},
+		{"latest", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			w := serveWithVersion(router, http.MethodGet, "/v1/messages", tt.version)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestClientVersionGate_UpgradeRequiredBody(t *testing.T) {
+	router, _ := newClientVersionRouter(t, true)
+
+	w := serveWithVersion(router, http.MethodGet, "/v1/messages", "1.0.0")
+	require.Equal(t, http.StatusUpgradeRequired, w.Code)
+
+	var body struct {
+		Data struct {
+			MinVersion string `json:"min_version"`
+			UpgradeURL string `json:"upgrade_url"`
+		} `json:"data"`
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "UPGRADE_REQUIRED", body.Error.Code)
+	assert.Equal(t, "2.1.0", body.Data.MinVersion)
+	assert.Equal(t, "https://secureconnect.com/download", body.Data.UpgradeURL)
+}
+
+func TestClientVersionGate_MissingHeader(t *testing.T) {
+	allowRouter, _ := newClientVersionRouter(t, true)
+	assert.Equal(t, http.StatusOK, serveWithVersion(allowRouter, http.MethodGet, "/v1/messages", "").Code)
+
+	denyRouter, _ := newClientVersionRouter(t, false)
+	assert.Equal(t, http.StatusUpgradeRequired, serveWithVersion(denyRouter, http.MethodGet, "/v1/messages", "").Code)
+}
+
+func TestClientVersionGate_ExemptRoutes(t *testing.T) {
+	router, _ := newClientVersionRouter(t, false)
+
+	assert.Equal(t, http.StatusOK, serveWithVersion(router, http.MethodGet, "/health", "1.0.0").Code)
+	assert.Equal(t, http.StatusOK, serveWithVersion(router, http.MethodPost, "/v1/auth/login", "").Code)
+}
+
+func TestClientVersionGate_Update(t *testing.T) {
+	router, gate := newClientVersionRouter(t, true)
+	assert.Equal(t, http.StatusOK, serveWithVersion(router, http.MethodGet, "/v1/messages", "2.5.0").Code)
+
+	require.NoError(t, gate.Update(ClientVersionConfig{MinVersion: "3.0.0"}))
+	assert.Equal(t, http.StatusUpgradeRequired, serveWithVersion(router, http.MethodGet, "/v1/messages", "2.5.0").Code)
+
+	// An invalid minimum keeps the previous one
+	assert.Error(t, gate.Update(ClientVersionConfig{MinVersion: "three"}))
+	assert.Equal(t, "3.0.0", gate.MinVersion())
+
+	// An empty minimum disables the check
+	require.NoError(t, gate.Update(ClientVersionConfig{}))
+	assert.Equal(t, http.StatusOK, serveWithVersion(router, http.MethodGet, "/v1/messages", "0.1").Code)
+}
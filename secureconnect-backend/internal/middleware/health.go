@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultReadinessTimeout bounds each dependency check of a readiness probe
+const DefaultReadinessTimeout = 2 * time.Second
+
+// DependencyCheck reports whether a dependency can serve requests
+type DependencyCheck func(ctx context.Context) error
+
+// HealthChecker serves a service's liveness and readiness probes.
+// Liveness only says the process is up; readiness checks every registered
+// dependency, so orchestrators stop routing traffic while one is down.
+type HealthChecker struct {
+	service string
+	timeout time.Duration
+	names   []string
+	checks  map[string]DependencyCheck
+	reports map[string]func() interface{}
+}
+
+// NewHealthChecker creates a health checker for service. A timeout <= 0
+// uses DefaultReadinessTimeout.
+func NewHealthChecker(service string, timeout time.Duration) *HealthChecker {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	return &HealthChecker{
+		service: service,
+		timeout: timeout,
+		checks:  make(map[string]DependencyCheck),
+		reports: make(map[string]func() interface{}),
+	}
+}
+
+// AddCheck registers a dependency checked by the readiness probe
+func (h *HealthChecker) AddCheck(name string, check DependencyCheck) {
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// AddReport adds a status shown by the readiness probe that does not affect
+// readiness, such as an optional integration
+func (h *HealthChecker) AddReport(name string, report func() interface{}) {
+	h.reports[name] = report
+}
+
+// Ready runs every dependency check concurrently, each bounded by the
+// readiness timeout. It returns each dependency's status and whether all of
+// them passed.
+func (h *HealthChecker) Ready(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[string]string, len(h.names))
+	ready := true
+	for _, name := range h.names {
+		wg.Add(1)
+		go func(name string, check DependencyCheck) {
+			defer wg.Done()
+			err := check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				statuses[name] = "unhealthy"
+				ready = false
+				return
+			}
+			statuses[name] = "healthy"
+		}(name, h.checks[name])
+	}
+	wg.Wait()
+	return statuses, ready
+}
+
+// Register serves GET /health/live and GET /health/ready on router
+func (h *HealthChecker) Register(router gin.IRoutes) {
+	router.GET("/health/live", h.LiveHandler())
+	router.GET("/health/ready", h.ReadyHandler())
+}
+
+// LiveHandler reports that the process is up and serving requests
+func (h *HealthChecker) LiveHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "alive",
+			"service": h.service,
+			"time":    time.Now().UTC(),
+		})
+	}
+}
+
+// ReadyHandler reports each dependency as "healthy" or "unhealthy", with
+// 503 when any of them is unhealthy
+func (h *HealthChecker) ReadyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses, ready := h.Ready(c.Request.Context())
+
+		body := gin.H{"status": "ready", "service": h.service}
+		for name, report := range h.reports {
+			body[name] = report()
+		}
+		for name, status := range statuses {
+			body[name] = status
+		}
+		code := http.StatusOK
+		if !ready {
+			body["status"] = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, body)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveHealth(t *testing.T, health *HealthChecker, path string) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	health.Register(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHealthChecker_ReadyWhenEveryDependencyPasses(t *testing.T) {
+	health := NewHealthChecker("chat-service", time.Second)
+	health.AddCheck("redis", func(ctx context.Context) error { return nil })
+	health.AddCheck("cassandra", func(ctx context.Context) error { return nil })
+	health.AddReport("push", func() interface{} { return map[string]interface{}{"healthy": false} })
+
+	code, body := serveHealth(t, health, "/health/ready")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", body["status"])
+	assert.Equal(t, "healthy", body["redis"])
+	assert.Equal(t, "healthy", body["cassandra"])
+	assert.NotNil(t, body["push"], "reports are shown without failing readiness")
+	assert.Equal(t, map[string]bool{"redis": true, "cassandra": true, "push": false}, readinessDependencies(body),
+		"the gateway system status reads the same body")
+}
+
+func TestHealthChecker_NotReadyWhenADependencyFailsOrHangs(t *testing.T) {
+	health := NewHealthChecker("auth-service", 50*time.Millisecond)
+	health.AddCheck("redis", func(ctx context.Context) error { return nil })
+	health.AddCheck("cockroach", func(ctx context.Context) error { return errors.New("connection refused") })
+	health.AddCheck("cassandra", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	code, body := serveHealth(t, health, "/health/ready")
+	assert.Less(t, time.Since(start), time.Second, "checks are bounded by the readiness timeout")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", body["status"])
+	assert.Equal(t, "healthy", body["redis"])
+	assert.Equal(t, "unhealthy", body["cockroach"])
+	assert.Equal(t, "unhealthy", body["cassandra"])
+
+	code, body = serveHealth(t, health, "/health/live")
+	assert.Equal(t, http.StatusOK, code, "liveness ignores dependencies")
+	assert.Equal(t, "alive", body["status"])
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+const (
+	// DefaultLoadShedMaxInFlight is the in-flight request count above which
+	// requests start being shed
+	DefaultLoadShedMaxInFlight = 1024
+
+	// DefaultLoadShedPriorityHeadroom is how far past the thresholds load must
+	// go before priority requests are shed too
+	DefaultLoadShedPriorityHeadroom = 0.5
+
+	// loadShedLatencyDecay is the weight of each new latency sample
+	loadShedLatencyDecay = 0.1
+
+	// loadShedLatencyStale is how long the latency average counts without new
+	// samples, so shedding every request cannot keep it high forever
+	loadShedLatencyStale = 10 * time.Second
+)
+
+// DefaultLoadShedExemptRoutes are never shed or counted. Health checks must
+// keep answering under load, and long-lived connections would otherwise
+// count as in flight for their whole lifetime.
+var DefaultLoadShedExemptRoutes = []string{
+	"/health*",
+	"/metrics",
+	"/v1/ws/*",
+	"/v1/storage/stream*",
+}
+
+// DefaultLoadShedCriticalRoutes let users sign in and keep their session
+// while the gateway is shedding load
+var DefaultLoadShedCriticalRoutes = []string{
+	"/v1/auth/login",
+	"/v1/auth/login/totp",
+	"/v1/auth/refresh",
+}
+
+// LoadShedConfig holds load shedding configuration
+type LoadShedConfig struct {
+	// MaxInFlight is the concurrent request count treated as full load; 0 disables it
+	MaxInFlight int
+	// LatencyThreshold is the average request latency treated as full load; 0 disables it
+	LatencyThreshold time.Duration
+	// PriorityHeadroom is the extra load, as a fraction of the thresholds,
+	// tolerated for priority requests before they are shed too
+	PriorityHeadroom float64
+	// RetryAfter is sent with 503 responses
+	RetryAfter time.Duration
+	// ExemptRoutes are never shed or counted; entries match as in TimeoutConfig
+	ExemptRoutes []string
+	// CriticalRoutes are always treated as priority requests
+	CriticalRoutes []string
+	// UserIdentity finds authenticated users, whose requests are priority; nil
+	// only uses user_id from the context
+	UserIdentity UserIdentityFunc
+}
+
+// LoadShedder rejects a share of incoming requests while the service is
+// overloaded, so it keeps serving most requests instead of slowing down for
+// all of them. Once in-flight requests or average latency pass their
+// threshold, anonymous requests are shed with a probability that rises with
+// the excess load, reaching all of them at twice the threshold. Priority
+// requests get PriorityHeadroom more before the same applies to them.
+type LoadShedder struct {
+	config   LoadShedConfig
+	inFlight atomic.Int64
+
+	mu            sync.Mutex
+	latency       float64 // average latency in seconds
+	latencyUpdate time.Time
+
+	random func() float64
+}
+
+// NewLoadShedder creates a load shedder from config
+func NewLoadShedder(config LoadShedConfig) *LoadShedder {
+	if config.PriorityHeadroom < 0 {
+		config.PriorityHeadroom = 0
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	return &LoadShedder{config: config, random: rand.Float64}
+}
+
+// Middleware returns a Gin middleware that sheds requests under overload
+func (ls *LoadShedder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ls.matches(c, ls.config.ExemptRoutes) {
+			c.Next()
+			return
+		}
+
+		// Priority is only worked out under overload since it may validate a token
+		if excess := ls.overload() - 1; excess > 0 {
+			priority := ls.isPriority(c)
+			if priority {
+				excess -= ls.config.PriorityHeadroom
+			}
+			if excess > 0 && ls.random() < excess {
+				ls.shed(c, priority)
+				return
+			}
+		}
+
+		ls.inFlight.Add(1)
+		start := time.Now()
+		defer func() {
+			ls.inFlight.Add(-1)
+			ls.observeLatency(time.Since(start))
+		}()
+		c.Next()
+	}
+}
+
+// overload returns the current load as a multiple of the tightest threshold
+func (ls *LoadShedder) overload() float64 {
+	var load float64
+	if ls.config.MaxInFlight > 0 {
+		load = float64(ls.inFlight.Load()) / float64(ls.config.MaxInFlight)
+	}
+	if ls.config.LatencyThreshold > 0 {
+		ls.mu.Lock()
+		latency, updated := ls.latency, ls.latencyUpdate
+		ls.mu.Unlock()
+		if time.Since(updated) < loadShedLatencyStale {
+			load = max(load, latency/ls.config.LatencyThreshold.Seconds())
+		}
+	}
+	return load
+}
+
+// observeLatency adds a completed request to the latency average
+func (ls *LoadShedder) observeLatency(d time.Duration) {
+	if ls.config.LatencyThreshold <= 0 {
+		return
+	}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if time.Since(ls.latencyUpdate) >= loadShedLatencyStale {
+		ls.latency = d.Seconds()
+	} else {
+		ls.latency += loadShedLatencyDecay * (d.Seconds() - ls.latency)
+	}
+	ls.latencyUpdate = time.Now()
+}
+
+// isPriority reports whether the request is from an authenticated user or
+// for a critical route
+func (ls *LoadShedder) isPriority(c *gin.Context) bool {
+	if ls.matches(c, ls.config.CriticalRoutes) {
+		return true
+	}
+	_, perUser := rateLimitIdentifier(c, ls.config.UserIdentity)
+	return perUser
+}
+
+// matches reports whether the request matches one of routes
+func (ls *LoadShedder) matches(c *gin.Context, routes []string) bool {
+	for _, route := range routes {
+		if matchesRoute(route, c.FullPath()) || matchesRoute(route, c.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// shed rejects the request with 503 and a Retry-After header
+func (ls *LoadShedder) shed(c *gin.Context, priority bool) {
+	label := "low"
+	if priority {
+		label = "priority"
+	}
+	metrics.GatewayLoadShedTotal.WithLabelValues(label).Inc()
+	logger.Debug("Request shed under load",
+		zap.String("path", c.Request.URL.Path),
+		zap.Bool("priority", priority),
+		zap.Int64("in_flight", ls.inFlight.Load()))
+
+	retryAfter := int64(ls.config.RetryAfter.Seconds())
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error": "Service overloaded, please retry",
+	})
+}
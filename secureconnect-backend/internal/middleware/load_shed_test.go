@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func newLoadShedRouter(ls *LoadShedder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ls.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/health", ok)
+	router.GET("/v1/messages", ok)
+	router.POST("/v1/auth/login", ok)
+	return router
+}
+
+func serveLoadShed(router *gin.Engine, method, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoadShedder_ShedsLowPriorityUnderLoad(t *testing.T) {
+	logger.InitDefault("test")
+
+	ls := NewLoadShedder(LoadShedConfig{
+		MaxInFlight:      10,
+		PriorityHeadroom: 0.5,
+		RetryAfter:       3 * time.Second,
+		ExemptRoutes:     DefaultLoadShedExemptRoutes,
+		CriticalRoutes:   DefaultLoadShedCriticalRoutes,
+		UserIdentity: func(c *gin.Context) (string, bool) {
+			user := c.GetHeader("X-Test-User")
+			return user, user != ""
+		},
+	})
+	router := newLoadShedRouter(ls)
+
+	// Below the threshold nothing is shed
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code)
+
+	// At 140% of the threshold: anonymous requests are shed with probability
+	// 0.4, priority requests are still within their headroom
+	ls.inFlight.Add(14)
+	ls.random = func() float64 { return 0.3 }
+	w := serveLoadShed(router, http.MethodGet, "/v1/messages", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "alice").Code)
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodPost, "/v1/auth/login", "").Code)
+	ls.random = func() float64 { return 0.5 }
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code, "only a fraction is shed")
+
+	// At twice the threshold every anonymous request is shed
+	ls.inFlight.Add(6)
+	ls.random = func() float64 { return 0.99 }
+	assert.Equal(t, http.StatusServiceUnavailable, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code)
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "alice").Code)
+
+	// Health checks are never shed
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/health", "").Code)
+	assert.Equal(t, int64(20), ls.inFlight.Load(), "finished requests are no longer in flight")
+}
+
+func TestLoadShedder_LatencyThreshold(t *testing.T) {
+	logger.InitDefault("test")
+
+	ls := NewLoadShedder(LoadShedConfig{LatencyThreshold: 100 * time.Millisecond})
+	ls.random = func() float64 { return 0 }
+	router := newLoadShedRouter(ls)
+
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code)
+
+	// Slow requests raise the average gradually
+	ls.observeLatency(150 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code)
+
+	// A stale average is replaced by the next sample
+	ls.latencyUpdate = time.Now().Add(-loadShedLatencyStale)
+	ls.observeLatency(150 * time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code)
+
+	// Until then it is ignored
+	ls.latencyUpdate = time.Now().Add(-loadShedLatencyStale)
+	assert.Equal(t, http.StatusOK, serveLoadShed(router, http.MethodGet, "/v1/messages", "").Code)
+}
@@ -6,54 +6,76 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"secureconnect-backend/pkg/logger"
 )
 
-// RequestLogger logs HTTP requests with structured logging
-func RequestLogger() gin.HandlerFunc {
+// RequestLogger writes one structured access log entry per request. Fields
+// match the HTTP metrics labels (method, endpoint, status) so log lines and
+// series can be correlated. Successful requests are logged at level (debug,
+// info, warn or error; anything else uses info), client errors at warn and
+// server errors at error. Query strings, bodies and headers other than the
+// user agent are never logged. The request ID set by RequestID is logged and
+// echoed; without that middleware one is generated for the request.
+func RequestLogger(level string) gin.HandlerFunc {
+	successLevel, err := zapcore.ParseLevel(level)
+	if err != nil {
+		successLevel = zapcore.InfoLevel
+	}
+
 	return func(c *gin.Context) {
-		// Generate request ID
-		requestID := uuid.New().String()
-		c.Set("request_id", requestID)
-		c.Writer.Header().Set("X-Request-ID", requestID)
+		requestID := c.GetString("request_id")
+		if requestID == "" {
+			requestID = uuid.New().String()
+			c.Set("request_id", requestID)
+			c.Writer.Header().Set(RequestIDHeader, requestID)
+		}
 
-		// Start timer
 		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
-		// Process request
 		c.Next()
 
-		// Calculate latency
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		clientIP := c.ClientIP()
-		method := c.Request.Method
-
-		// Build full path
-		if query != "" {
-			path = path + "?" + query
+		status := c.Writer.Status()
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0 // nothing was written
 		}
-
-		// Log with appropriate level
 		fields := []zap.Field{
 			zap.String("request_id", requestID),
-			zap.Int("status", statusCode),
-			zap.Duration("latency", latency),
-			zap.String("client_ip", clientIP),
-			zap.String("method", method),
-			zap.String("path", path),
+			zap.String("method", c.Request.Method),
+			// Route template such as /v1/users/:id, as in the metrics; empty when unmatched
+			zap.String("endpoint", c.FullPath()),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("response_size", size),
+			zap.String("client_ip", c.ClientIP()),
 			zap.String("user_agent", c.Request.UserAgent()),
 		}
+		if userID, exists := c.Get("user_id"); exists {
+			fields = append(fields, zap.Any("user_id", userID))
+		}
 
-		if statusCode >= 500 {
+		switch {
+		case status >= 500:
 			logger.Error("Server error", fields...)
-		} else if statusCode >= 400 {
+		case status >= 400:
 			logger.Warn("Client error", fields...)
-		} else {
-			logger.Info("Request completed", fields...)
+		default:
+			logAtLevel(successLevel, "Request completed", fields...)
 		}
 	}
 }
+
+// logAtLevel logs msg through the logger function for level
+func logAtLevel(level zapcore.Level, msg string, fields ...zap.Field) {
+	switch level {
+	case zapcore.DebugLevel:
+		logger.Debug(msg, fields...)
+	case zapcore.WarnLevel:
+		logger.Warn(msg, fields...)
+	case zapcore.ErrorLevel:
+		logger.Error(msg, fields...)
+	default:
+		logger.Info(msg, fields...)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestRequestLogger_LogsRouteTemplateAndFields(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = previous })
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestLogger("debug"))
+	router.GET("/v1/users/:id", func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		c.String(http.StatusOK, "hello")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/42?token=secret", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("User-Agent", "test-agent")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, zap.DebugLevel, entry.Level)
+
+	fields := entry.ContextMap()
+	assert.Equal(t, "GET", fields["method"])
+	assert.Equal(t, "/v1/users/:id", fields["endpoint"])
+	assert.EqualValues(t, http.StatusOK, fields["status"])
+	assert.EqualValues(t, 5, fields["response_size"])
+	assert.Equal(t, "203.0.113.7", fields["client_ip"])
+	assert.Equal(t, "user-1", fields["user_id"])
+	assert.Equal(t, "test-agent", fields["user_agent"])
+	assert.Equal(t, w.Header().Get("X-Request-ID"), fields["request_id"])
+	assert.Contains(t, fields, "latency")
+
+	for key, value := range fields {
+		text := fmt.Sprint(value)
+		assert.NotContains(t, text, "secret", "field %s", key)
+		assert.NotContains(t, text, "/v1/users/42", "field %s", key)
+	}
+}
@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
@@ -59,6 +63,41 @@ func MetricsHandler(m *metrics.Metrics) gin.HandlerFunc {
 	}
 }
 
+// MetricsAuth protects operator endpoints with a static bearer token.
+// When no token is configured the endpoints are disabled.
+func MetricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			response.Forbidden(c, "Metrics endpoint is not enabled")
+			c.Abort()
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			response.Unauthorized(c, "Invalid metrics token")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SLOHandler returns a handler serving the aggregated SLO summary
+// GET /admin/slo
+func SLOHandler(aggregator *metrics.SLOAggregator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		summary, err := aggregator.Summary()
+		if err != nil {
+			response.InternalError(c, "Failed to compute SLO summary")
+			return
+		}
+
+		response.Success(c, http.StatusOK, summary)
+	}
+}
+
 // GetMetricsPath returns the path for the metrics endpoint
 func GetMetricsPath() string {
 	return "/metrics"
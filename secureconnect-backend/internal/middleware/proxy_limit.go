@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// DefaultProxyMaxInFlight is the per-service cap on concurrent proxied requests
+const DefaultProxyMaxInFlight = 256
+
+// ProxyConcurrencyLimiter caps in-flight proxied requests per downstream
+// service, so a slow service cannot hold every gateway connection and starve
+// routing to the healthy ones
+type ProxyConcurrencyLimiter struct {
+	defaultLimit int
+	overrides    map[string]int
+	retryAfter   time.Duration
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewProxyConcurrencyLimiter creates a limiter allowing defaultLimit requests
+// per service, or the service's entry in overrides. A limit <= 0 disables it.
+func NewProxyConcurrencyLimiter(defaultLimit int, overrides map[string]int) *ProxyConcurrencyLimiter {
+	return &ProxyConcurrencyLimiter{
+		defaultLimit: defaultLimit,
+		overrides:    overrides,
+		retryAfter:   time.Second,
+		slots:        make(map[string]chan struct{}),
+	}
+}
+
+// ParseProxyLimits parses "service=limit" pairs separated by commas, as in
+// GATEWAY_PROXY_SERVICE_LIMITS. Malformed entries are skipped.
+func ParseProxyLimits(value string) map[string]int {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		service, limit, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			continue
+		}
+		limits[strings.TrimSpace(service)] = n
+	}
+	return limits
+}
+
+// SetRetryAfter sets the Retry-After sent with 503 responses
+func (l *ProxyConcurrencyLimiter) SetRetryAfter(d time.Duration) {
+	l.retryAfter = d
+}
+
+// serviceSlots returns the semaphore for service, or nil if it is unlimited
+func (l *ProxyConcurrencyLimiter) serviceSlots(service string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slots, ok := l.slots[service]; ok {
+		return slots
+	}
+	limit := l.defaultLimit
+	if n, ok := l.overrides[service]; ok {
+		limit = n
+	}
+	var slots chan struct{}
+	if limit > 0 {
+		slots = make(chan struct{}, limit)
+	}
+	l.slots[service] = slots
+	return slots
+}
+
+// Wrap runs next only while service has a free slot. Otherwise the request is
+// rejected with 503 and a Retry-After header without reaching the service.
+// WebSocket upgrades are not limited: a socket would hold its slot for as long
+// as it stays open, so open sockets would lock out the service's REST calls.
+func (l *ProxyConcurrencyLimiter) Wrap(service string, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			next(c)
+			return
+		}
+
+		slots := l.serviceSlots(service)
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				metrics.GatewayProxyRejectedTotal.WithLabelValues(service).Inc()
+				logger.Warn("Proxy concurrency limit reached",
+					zap.String("service", service),
+					zap.Int("limit", cap(slots)))
+				retryAfter := int64(l.retryAfter.Seconds())
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "Service busy, please retry",
+					"service": service,
+				})
+				return
+			}
+		}
+
+		metrics.GatewayProxyInFlight.WithLabelValues(service).Inc()
+		defer metrics.GatewayProxyInFlight.WithLabelValues(service).Dec()
+		next(c)
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestProxyConcurrencyLimiter_IsolatesServices(t *testing.T) {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewProxyConcurrencyLimiter(5, map[string]int{"video-service": 1})
+	limiter.SetRetryAfter(3 * time.Second)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/video", limiter.Wrap("video-service", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	}))
+	router.GET("/chat", limiter.Wrap("chat-service", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// Hold video-service's only slot with a slow request
+	done := make(chan int)
+	go func() { done <- serve("/video").Code }()
+	<-started
+
+	w := serve("/video")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, serve("/chat").Code, "other services stay reachable")
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-done)
+
+	go func() { <-started }()
+	assert.Equal(t, http.StatusOK, serve("/video").Code, "the slot is freed when the request finishes")
+}
+
+func TestProxyConcurrencyLimiter_WebSocketsDoNotHoldSlots(t *testing.T) {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewProxyConcurrencyLimiter(1, nil)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/ws/chat", limiter.Wrap("chat-service", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	}))
+	router.GET("/messages", limiter.Wrap("chat-service", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}))
+
+	// A long-lived socket stays open while REST calls keep flowing
+	done := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "/ws/chat", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	close(release)
+	<-done
+}
+
+func TestParseProxyLimits(t *testing.T) {
+	limits := ParseProxyLimits("video-service=50, chat-service = 300,bogus,storage-service=x")
+	assert.Equal(t, map[string]int{"video-service": 50, "chat-service": 300}, limits)
+}
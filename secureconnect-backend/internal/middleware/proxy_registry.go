@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// Default connection pool settings for proxied requests
+const (
+	DefaultProxyMaxIdleConns        = 512
+	DefaultProxyMaxIdleConnsPerHost = 64
+	DefaultProxyIdleConnTimeout     = 90 * time.Second
+)
+
+// ProxyTransportConfig tunes the connection pool shared by all proxied services
+type ProxyTransportConfig struct {
+	// MaxIdleConns caps idle connections across all services
+	MaxIdleConns int
+	// MaxIdleConnsPerHost caps idle connections kept to each service
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes connections idle for longer
+	IdleConnTimeout time.Duration
+}
+
+// NewProxyTransport creates the transport shared by all proxied services
+func NewProxyTransport(config ProxyTransportConfig) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// ProxyRegistry holds one long-lived reverse proxy per downstream service, so
+// requests reuse pooled connections instead of building a proxy each time
+type ProxyRegistry struct {
+	transport http.RoundTripper
+	retrier   *ProxyRetrier
+
+	mu      sync.Mutex
+	proxies map[string]*httputil.ReverseProxy
+}
+
+// NewProxyRegistry creates a registry whose proxies share transport. When
+// retrier is set, requests it prepares are retried on transient failures.
+func NewProxyRegistry(transport http.RoundTripper, retrier *ProxyRetrier) *ProxyRegistry {
+	return &ProxyRegistry{
+		transport: transport,
+		retrier:   retrier,
+		proxies:   make(map[string]*httputil.ReverseProxy),
+	}
+}
+
+// Register returns the proxy for service, creating it for baseURL on first
+// use. Later calls for the same service return the existing proxy.
+func (r *ProxyRegistry) Register(service, baseURL string) (*httputil.ReverseProxy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if proxy, ok := r.proxies[service]; ok {
+		return proxy, nil
+	}
+
+	remote, err := url.Parse(baseURL)
+	if err != nil || remote.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q for %s", baseURL, service)
+	}
+
+	transport := r.transport
+	if r.retrier != nil {
+		transport = r.retrier.Transport(service, transport)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		// Path, query and headers are already those of the incoming request;
+		// only the target changes and the request ID is forwarded
+		Director: func(req *http.Request) {
+			req.Host = remote.Host
+			req.URL.Scheme = remote.Scheme
+			req.URL.Host = remote.Host
+			if requestID := logger.RequestIDFromContext(req.Context()); requestID != "" {
+				req.Header.Set(RequestIDHeader, requestID)
+			}
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			logger.FromContext(req.Context()).Error("Proxy error",
+				zap.String("service", service),
+				zap.Error(err),
+			)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"Service unavailable","service":"` + service + `"}`))
+		},
+	}
+	r.proxies[service] = proxy
+	return proxy, nil
+}
+
+// Proxy returns the registered proxy for service, or nil
+func (r *ProxyRegistry) Proxy(service string) *httputil.ReverseProxy {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.proxies[service]
+}
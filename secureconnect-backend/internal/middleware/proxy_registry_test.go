@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestProxyRegistry_SharesOneProxyPerService(t *testing.T) {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":       r.URL.Path,
+			"query":      r.URL.RawQuery,
+			"request_id": r.Header.Get(RequestIDHeader),
+			"auth":       r.Header.Get("Authorization"),
+		})
+	}))
+	defer upstream.Close()
+
+	registry := NewProxyRegistry(NewProxyTransport(ProxyTransportConfig{MaxIdleConnsPerHost: 4}), nil)
+	proxy, err := registry.Register("chat-service", upstream.URL)
+	require.NoError(t, err)
+	again, err := registry.Register("chat-service", "http://ignored:1")
+	require.NoError(t, err)
+	assert.Same(t, proxy, again, "routes of one service share its proxy")
+	assert.Same(t, proxy, registry.Proxy("chat-service"))
+
+	_, err = registry.Register("bad-service", "::not a url")
+	assert.Error(t, err)
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/v1/messages/:id", func(c *gin.Context) {
+		registry.Proxy("chat-service").ServeHTTP(c.Writer, c.Request)
+	})
+
+	w := closeNotifyRecorder{httptest.NewRecorder()}
+	req := httptest.NewRequest(http.MethodGet, "/v1/messages/42?limit=5", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var got map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, map[string]string{
+		"path":       "/v1/messages/42",
+		"query":      "limit=5",
+		"request_id": "req-1",
+		"auth":       "Bearer token",
+	}, got)
+}
+
+func TestProxyRegistry_UnreachableServiceReturnsBadGateway(t *testing.T) {
+	logger.InitDefault("test")
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	registry := NewProxyRegistry(NewProxyTransport(ProxyTransportConfig{}), nil)
+	proxy, err := registry.Register("video-service", down.URL)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/calls/1", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.JSONEq(t, `{"error":"Service unavailable","service":"video-service"}`, w.Body.String())
+}
+
+// benchmarkProxy proxies b.N requests through the proxy returned by next and
+// reports new upstream connections per request next to allocations
+func benchmarkProxy(b *testing.B, next func(target *url.URL) http.Handler) {
+	logger.InitDefault("bench")
+	var conns atomic.Int64
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	upstream.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	upstream.Start()
+	defer upstream.Close()
+
+	target, err := url.Parse(upstream.URL)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		next(target).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/messages", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("status %d", w.Code)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+}
+
+// BenchmarkProxy_PerRequest builds a proxy and transport for every request,
+// as proxyToService used to
+func BenchmarkProxy_PerRequest(b *testing.B) {
+	benchmarkProxy(b, func(target *url.URL) http.Handler {
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		transport := NewProxyTransport(ProxyTransportConfig{})
+		proxy.Transport = transport
+		b.Cleanup(transport.CloseIdleConnections)
+		return proxy
+	})
+}
+
+// BenchmarkProxy_Registry reuses the service's registered proxy
+func BenchmarkProxy_Registry(b *testing.B) {
+	registry := NewProxyRegistry(NewProxyTransport(ProxyTransportConfig{
+		MaxIdleConns:        DefaultProxyMaxIdleConns,
+		MaxIdleConnsPerHost: DefaultProxyMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultProxyIdleConnTimeout,
+	}), nil)
+	benchmarkProxy(b, func(target *url.URL) http.Handler {
+		proxy, err := registry.Register("chat-service", target.String())
+		require.NoError(b, err)
+		return proxy
+	})
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/backoff"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+const (
+	// DefaultProxyRetryMaxAttempts is the number of upstream calls made for a
+	// retryable request, including the first one
+	DefaultProxyRetryMaxAttempts = 3
+
+	// DefaultProxyRetryMaxBodyBytes is the largest request body buffered so
+	// it can be replayed on retry
+	DefaultProxyRetryMaxBodyBytes = 64 << 10
+)
+
+// ProxyRetryConfig holds retry settings for proxied requests
+type ProxyRetryConfig struct {
+	// MaxAttempts is the number of upstream calls including the first; <= 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the backoff before the second attempt, doubled for each later one
+	BaseDelay time.Duration
+	// MaxDelay caps a single backoff; 0 means no cap
+	MaxDelay time.Duration
+	// MaxBodyBytes is the largest request body buffered for replay. Requests
+	// with larger or unknown-length bodies are proxied once.
+	MaxBodyBytes int64
+	// DeadlineMargin stops retrying once the client's deadline is closer than
+	// the next backoff plus this margin
+	DeadlineMargin time.Duration
+	// IdempotentRoutes are retried for every method; entries match as in
+	// TimeoutConfig. GET and HEAD requests are always retried.
+	IdempotentRoutes []string
+}
+
+// ProxyRetrier retries proxied requests that are safe to replay when the
+// upstream call fails to connect or the service answers 502, 503 or 504
+type ProxyRetrier struct {
+	config ProxyRetryConfig
+	policy backoff.Config
+}
+
+// NewProxyRetrier creates a retrier from config
+func NewProxyRetrier(config ProxyRetryConfig) *ProxyRetrier {
+	if config.MaxBodyBytes < 0 {
+		config.MaxBodyBytes = 0
+	}
+	return &ProxyRetrier{
+		config: config,
+		policy: backoff.Config{Base: config.BaseDelay, Max: config.MaxDelay, Jitter: true},
+	}
+}
+
+// ParseRouteList splits a comma-separated route list, as in
+// GATEWAY_PROXY_RETRY_ROUTES. Empty entries are skipped.
+func ParseRouteList(value string) []string {
+	var routes []string
+	for _, route := range strings.Split(value, ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// proxyRetryKey marks a request context as safe to retry
+type proxyRetryKey struct{}
+
+// Prepare returns the request to proxy for c. Requests that are safe to
+// replay are marked for retries, with their body buffered so it can be sent
+// again; anything else is returned unchanged and proxied once.
+func (r *ProxyRetrier) Prepare(c *gin.Context) *http.Request {
+	req := c.Request
+	if r.config.MaxAttempts <= 1 || !r.idempotent(c) {
+		return req
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		if req.ContentLength < 0 || req.ContentLength > r.config.MaxBodyBytes {
+			return req
+		}
+		body, err := io.ReadAll(io.LimitReader(req.Body, req.ContentLength))
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			// Forward what was read; the upstream sees the short body
+			return req
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), proxyRetryKey{}, true))
+}
+
+// idempotent reports whether c's request can be sent more than once
+func (r *ProxyRetrier) idempotent(c *gin.Context) bool {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return true
+	}
+	for _, route := range r.config.IdempotentRoutes {
+		if matchesRoute(route, c.FullPath()) || matchesRoute(route, c.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transport wraps base so requests marked by Prepare are retried. service
+// labels the retry metric.
+func (r *ProxyRetrier) Transport(service string, base http.RoundTripper) http.RoundTripper {
+	return &retryTransport{retrier: r, service: service, base: base}
+}
+
+// backoff returns a random delay between zero and the exponential backoff
+// before the given attempt (attempt >= 2)
+func (r *ProxyRetrier) backoff(attempt int) time.Duration {
+	return r.policy.Next(attempt - 1)
+}
+
+// retryTransport is the http.RoundTripper returned by ProxyRetrier.Transport
+type retryTransport struct {
+	retrier *ProxyRetrier
+	service string
+	base    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if retry, _ := ctx.Value(proxyRetryKey{}).(bool); !retry {
+		return t.base.RoundTrip(req)
+	}
+	// A body that cannot be rewound is only sent once
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	replayable := !hasBody || req.GetBody != nil
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		reason := retryReason(resp, err)
+		if reason == "" || ctx.Err() != nil || !replayable || attempt >= t.retrier.config.MaxAttempts {
+			return resp, err
+		}
+
+		delay := t.retrier.backoff(attempt + 1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay+t.retrier.config.DeadlineMargin {
+			return resp, err
+		}
+
+		var body io.ReadCloser
+		if hasBody {
+			var bodyErr error
+			if body, bodyErr = req.GetBody(); bodyErr != nil {
+				return resp, err
+			}
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+
+		metrics.GatewayProxyRetriesTotal.WithLabelValues(t.service, reason).Inc()
+		logger.FromContext(ctx).Warn("Retrying proxied request",
+			zap.String("service", t.service),
+			zap.String("path", req.URL.Path),
+			zap.String("reason", reason),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if body != nil {
+				body.Close()
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if body != nil {
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// retryReason returns why an upstream result should be retried, or "" if it
+// should be returned to the client as is
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return "error"
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return strconv.Itoa(resp.StatusCode)
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// flakyUpstream answers 503 to the first failures requests and 200 after that,
+// recording the bodies it received
+type flakyUpstream struct {
+	mu       sync.Mutex
+	failures int
+	bodies   []string
+}
+
+func (u *flakyUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.bodies = append(u.bodies, string(body))
+	if len(u.bodies) <= u.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func newRetryRouter(t *testing.T, retrier *ProxyRetrier, upstream http.Handler) *gin.Engine {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+	remote, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	proxy := func(c *gin.Context) {
+		proxy := httputil.NewSingleHostReverseProxy(remote)
+		proxy.Transport = retrier.Transport("test-service", http.DefaultTransport)
+		proxy.ServeHTTP(c.Writer, retrier.Prepare(c))
+	}
+	router := gin.New()
+	router.GET("/items", proxy)
+	router.POST("/items", proxy)
+	router.PUT("/items/:id", proxy)
+	return router
+}
+
+// serveProxied serves req through router. ReverseProxy needs a CloseNotifier,
+// which httptest.ResponseRecorder is not.
+func serveProxied(router *gin.Engine, req *http.Request) int {
+	w := closeNotifyRecorder{httptest.NewRecorder()}
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (closeNotifyRecorder) CloseNotify() <-chan bool { return make(chan bool) }
+
+func TestProxyRetrier_RetriesSafeMethods(t *testing.T) {
+	retrier := NewProxyRetrier(ProxyRetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	upstream := &flakyUpstream{failures: 2}
+	router := newRetryRouter(t, retrier, upstream)
+
+	assert.Equal(t, http.StatusOK, serveProxied(router, httptest.NewRequest(http.MethodGet, "/items", nil)))
+	assert.Len(t, upstream.bodies, 3)
+
+	// Once attempts run out the last failure is passed on
+	upstream = &flakyUpstream{failures: 5}
+	router = newRetryRouter(t, retrier, upstream)
+	assert.Equal(t, http.StatusServiceUnavailable, serveProxied(router, httptest.NewRequest(http.MethodGet, "/items", nil)))
+	assert.Len(t, upstream.bodies, 3)
+}
+
+func TestProxyRetrier_ReplaysBodiesOfIdempotentRoutes(t *testing.T) {
+	retrier := NewProxyRetrier(ProxyRetryConfig{
+		MaxAttempts:      3,
+		BaseDelay:        time.Millisecond,
+		MaxBodyBytes:     16,
+		IdempotentRoutes: []string{"/items/:id"},
+	})
+	upstream := &flakyUpstream{failures: 1}
+	router := newRetryRouter(t, retrier, upstream)
+
+	assert.Equal(t, http.StatusOK, serveProxied(router, httptest.NewRequest(http.MethodPut, "/items/1", strings.NewReader(`{"a":1}`))))
+	assert.Equal(t, []string{`{"a":1}`, `{"a":1}`}, upstream.bodies, "the buffered body is sent again")
+
+	// Bodies too large to buffer are sent once
+	upstream = &flakyUpstream{failures: 1}
+	router = newRetryRouter(t, retrier, upstream)
+	assert.Equal(t, http.StatusServiceUnavailable, serveProxied(router, httptest.NewRequest(http.MethodPut, "/items/1", strings.NewReader(`{"name":"too long to buffer"}`))))
+	assert.Len(t, upstream.bodies, 1)
+
+	// Other routes are only retried for safe methods
+	upstream = &flakyUpstream{failures: 1}
+	router = newRetryRouter(t, retrier, upstream)
+	assert.Equal(t, http.StatusServiceUnavailable, serveProxied(router, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{}`))))
+	assert.Len(t, upstream.bodies, 1)
+}
+
+func TestProxyRetrier_StopsNearDeadline(t *testing.T) {
+	retrier := NewProxyRetrier(ProxyRetryConfig{
+		MaxAttempts:    3,
+		BaseDelay:      time.Millisecond,
+		DeadlineMargin: time.Second,
+	})
+	upstream := &flakyUpstream{failures: 1}
+	router := newRetryRouter(t, retrier, upstream)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, http.StatusServiceUnavailable, serveProxied(router, httptest.NewRequest(http.MethodGet, "/items", nil).WithContext(ctx)))
+	assert.Len(t, upstream.bodies, 1, "no retry fits before the client's deadline")
+}
+
+func TestProxyRetrier_RetriesConnectionErrors(t *testing.T) {
+	logger.InitDefault("test")
+	retrier := NewProxyRetrier(ProxyRetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	calls := 0
+	transport := retrier.Transport("test-service", roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/items", nil)
+	resp, err := transport.RoundTrip(retrier.Prepare(c))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestParseRouteList(t *testing.T) {
+	assert.Equal(t, []string{"/v1/a", "/v1/b/*"}, ParseRouteList(" /v1/a, ,/v1/b/* "))
+	assert.Empty(t, ParseRouteList(""))
+}
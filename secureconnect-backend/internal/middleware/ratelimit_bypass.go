@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/pkg/metrics"
+)
+
+// InternalAPIKeyHeader carries the shared key internal callers use to skip
+// rate limiting
+const InternalAPIKeyHeader = "X-Internal-API-Key"
+
+// RateLimitBypassConfig lists the callers that are never rate limited
+type RateLimitBypassConfig struct {
+	// CIDRs are matched against the client IP gin resolves, the same address
+	// requests are rate limited by. Forwarded headers are honored only from
+	// the router's trusted proxies, so a load balancer inside an allowlisted
+	// network does not exempt the clients it forwards.
+	CIDRs []string
+	// APIKey is compared with the X-Internal-API-Key header; empty disables it
+	APIKey string
+}
+
+// RateLimitBypass decides whether a request comes from a trusted internal
+// caller that should not count against rate limits
+type RateLimitBypass struct {
+	networks []*net.IPNet
+	apiKey   []byte
+}
+
+// NewRateLimitBypass creates a bypass from config. Entries without a prefix
+// length are treated as single addresses.
+func NewRateLimitBypass(config RateLimitBypassConfig) (*RateLimitBypass, error) {
+	b := &RateLimitBypass{}
+	for _, entry := range config.CIDRs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit bypass CIDR %q: %w", entry, err)
+		}
+		b.networks = append(b.networks, network)
+	}
+	if config.APIKey != "" {
+		b.apiKey = []byte(config.APIKey)
+	}
+	return b, nil
+}
+
+// Allows reports whether the request may skip rate limiting. The internal
+// key header is removed either way so it is never forwarded downstream.
+func (b *RateLimitBypass) Allows(c *gin.Context) bool {
+	key := c.GetHeader(InternalAPIKeyHeader)
+	c.Request.Header.Del(InternalAPIKeyHeader)
+
+	if key != "" && len(b.apiKey) > 0 &&
+		subtle.ConstantTimeCompare([]byte(key), b.apiKey) == 1 {
+		metrics.GatewayRateLimitBypassedTotal.WithLabelValues("api_key").Inc()
+		return true
+	}
+
+	if len(b.networks) == 0 {
+		return false
+	}
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil {
+		return false
+	}
+	for _, network := range b.networks {
+		if network.Contains(ip) {
+			metrics.GatewayRateLimitBypassedTotal.WithLabelValues("cidr").Inc()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trustedProxy is the only peer whose forwarded headers the bypass router honors
+const trustedProxy = "10.0.0.2"
+
+func newBypassRouter(t *testing.T, config RateLimitBypassConfig) *gin.Engine {
+	t.Helper()
+	limiter := newFallbackLimiter(t, 1, time.Minute)
+	bypass, err := NewRateLimitBypass(config)
+	require.NoError(t, err)
+	limiter.SetBypass(bypass)
+	router := newLimiterRouter(limiter)
+	require.NoError(t, router.SetTrustedProxies([]string{trustedProxy}))
+	return router
+}
+
+func doRequestFrom(router *gin.Engine, remoteAddr string, headers map[string]string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimitBypass_AllowlistedCallersAreNotLimited(t *testing.T) {
+	router := newBypassRouter(t, RateLimitBypassConfig{CIDRs: []string{"10.0.0.0/8"}, APIKey: "internal-secret"})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequestFrom(router, "10.1.2.3:4000", nil), "allowlisted network")
+		assert.Equal(t, http.StatusOK, doRequestFrom(router, "203.0.113.7:5555",
+			map[string]string{InternalAPIKeyHeader: "internal-secret"}), "valid internal key")
+	}
+}
+
+func TestRateLimitBypass_SpoofedHeadersAreLimited(t *testing.T) {
+	router := newBypassRouter(t, RateLimitBypassConfig{CIDRs: []string{"10.0.0.0/8"}, APIKey: "internal-secret"})
+	spoofed := map[string]string{
+		"X-Forwarded-For":    "10.1.2.3",
+		"X-Real-IP":          "10.1.2.3",
+		InternalAPIKeyHeader: "guessed-secret",
+	}
+
+	assert.Equal(t, http.StatusOK, doRequestFrom(router, "203.0.113.7:5555", spoofed))
+	assert.Equal(t, http.StatusTooManyRequests, doRequestFrom(router, "203.0.113.7:5555", spoofed))
+}
+
+func TestRateLimitBypass_MatchesClientBehindTrustedProxy(t *testing.T) {
+	router := newBypassRouter(t, RateLimitBypassConfig{CIDRs: []string{"10.0.0.0/8"}})
+	internal := map[string]string{"X-Forwarded-For": "10.1.2.3"}
+	public := map[string]string{"X-Forwarded-For": "203.0.113.7"}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequestFrom(router, trustedProxy+":4000", internal), "internal client")
+	}
+	assert.Equal(t, http.StatusOK, doRequestFrom(router, trustedProxy+":4000", public))
+	assert.Equal(t, http.StatusTooManyRequests, doRequestFrom(router, trustedProxy+":4000", public),
+		"an allowlisted proxy does not exempt the clients it forwards")
+}
+
+func TestRateLimitBypass_EmptyKeyNeverMatches(t *testing.T) {
+	router := newBypassRouter(t, RateLimitBypassConfig{})
+	empty := map[string]string{InternalAPIKeyHeader: ""}
+
+	assert.Equal(t, http.StatusOK, doRequestFrom(router, "203.0.113.7:5555", empty))
+	assert.Equal(t, http.StatusTooManyRequests, doRequestFrom(router, "203.0.113.7:5555", empty))
+}
+
+func TestNewRateLimitBypass_RejectsInvalidCIDR(t *testing.T) {
+	_, err := NewRateLimitBypass(RateLimitBypassConfig{CIDRs: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+}
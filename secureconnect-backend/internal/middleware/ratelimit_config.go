@@ -199,6 +199,15 @@ func (m *RateLimitConfigManager) GetConfigForPath(path string) RateLimitConfig {
 	}
 }
 
+// EndpointOverrides returns the per-endpoint limits as RateLimiterConfig overrides
+func (m *RateLimitConfigManager) EndpointOverrides() map[string]EndpointLimit {
+	overrides := make(map[string]EndpointLimit, len(m.configs))
+	for pattern, config := range m.configs {
+		overrides[pattern] = EndpointLimit{Requests: config.Requests, Window: config.Window}
+	}
+	return overrides
+}
+
 // isPathMatch checks if a path matches a pattern (e.g., /v1/users/:id matches /v1/users/123)
 func isPathMatch(path, pattern string) bool {
 	// Simple pattern matching - in production, you might want to use a more sophisticated approach
@@ -288,21 +297,10 @@ func (rl *AdvancedRateLimiter) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set rate limit headers
-		c.Header("X-RateLimit-Limit", strconv.Itoa(config.Requests))
-		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
-		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime, 10))
+		setRateLimitHeaders(c, config.Requests, remaining, config.Window, resetTime)
 		c.Header("X-RateLimit-Window", config.Window.String())
-
 		if !allowed {
-			c.JSON(429, gin.H{
-				"error":       "Rate limit exceeded",
-				"limit":       config.Requests,
-				"remaining":   remaining,
-				"reset_at":    resetTime,
-				"retry_after": config.Window.Seconds(),
-			})
-			c.Abort()
+			abortRateLimited(c, config.Requests, resetTime)
 			return
 		}
 
@@ -347,9 +345,10 @@ func (rl *AdvancedRateLimiter) checkRateLimit(c *gin.Context, identifier string,
 
 	// Check if we need to reset window
 	lastWindowStart, _ := strconv.ParseInt(lastWindowStartBytes, 10, 64)
-	if lastWindowStart < windowStart || err != nil {
-		// New window, reset count
-		if err := rl.redisClient.Set(ctx, windowKey, windowStart, window).Err(); err != nil {
+	if lastWindowStart <= windowStart || err != nil {
+		// New window starting now, reset count
+		lastWindowStart = now
+		if err := rl.redisClient.Set(ctx, windowKey, now, window).Err(); err != nil {
 			return false, 0, 0, fmt.Errorf("failed to set window start: %w", err)
 		}
 		if err := rl.redisClient.Set(ctx, key, 1, window).Err(); err != nil {
@@ -1,10 +1,7 @@
 package middleware
 
 import (
-	"context"
-	"fmt"
 	"net/http"
-	"strconv"
 	"sync"
 	"time"
 
@@ -18,8 +15,19 @@ import (
 
 // RateLimiterConfig holds configuration for rate limiting with degraded mode support
 type RateLimiterConfig struct {
-	RedisClient            interface{} // Allow both *redis.Client and *database.RedisClient
-	RequestsPerMin         int
+	RedisClient interface{} // Allow both *redis.Client and *database.RedisClient
+	// RequestsPerMin limits each client IP per window
+	RequestsPerMin int
+	// UserRequestsPerMin limits each authenticated user per window across all
+	// of their IPs; 0 uses RequestsPerMin
+	UserRequestsPerMin int
+	// UserIdentity finds the authenticated user when the limiter runs before
+	// AuthMiddleware; nil only uses user_id from the context
+	UserIdentity UserIdentityFunc
+	// EndpointOverrides give route patterns their own limit, counted
+	// separately from the default budget. Keys are paths such as
+	// /v1/users/:id, or prefixes ending in *; the most specific match wins.
+	EndpointOverrides      map[string]EndpointLimit
 	Window                 time.Duration
 	EnableInMemoryFallback bool
 }
@@ -42,33 +50,24 @@ func NewInMemoryRateLimiter() *InMemoryRateLimiter {
 	}
 }
 
-// Check checks if a request is within rate limits using in-memory tracking
+// Check checks if a request is within rate limits using in-memory tracking.
+// It uses a fixed window that starts with the first request and returns
+// whether the request is allowed, the remaining budget and the Unix time the
+// window resets.
 func (im *InMemoryRateLimiter) Check(identifier string, requests int, window time.Duration) (bool, int, int64, error) {
-	im.mu.RLock()
-	defer im.mu.RUnlock()
+	im.mu.Lock()
+	defer im.mu.Unlock()
 
 	now := time.Now().Unix()
-	windowStart := now - int64(window.Seconds())
+	windowSeconds := int64(window.Seconds())
 
 	limiter, exists := im.limits[identifier]
-	if !exists {
-		// First request for this identifier
-		im.limits[identifier] = &userRateLimit{
-			count:       1,
-			windowStart: windowStart,
-		}
-		return true, requests - 1, 0, nil
-	}
-
-	// Check if window has expired
-	if limiter.windowStart < windowStart {
-		// Reset for new window
-		limiter.count = 1
-		limiter.windowStart = windowStart
-	} else {
-		// Increment count within window
-		limiter.count++
+	if !exists || now >= limiter.windowStart+windowSeconds {
+		// First request for this identifier or the window has expired
+		limiter = &userRateLimit{windowStart: now}
+		im.limits[identifier] = limiter
 	}
+	limiter.count++
 
 	remaining := requests - limiter.count
 	if remaining < 0 {
@@ -76,7 +75,7 @@ func (im *InMemoryRateLimiter) Check(identifier string, requests int, window tim
 	}
 
 	allowed := limiter.count <= requests
-	return allowed, remaining, limiter.windowStart + int64(window.Seconds()), nil
+	return allowed, remaining, limiter.windowStart + windowSeconds, nil
 }
 
 // RateLimiterWithFallback wraps the original Redis-based rate limiter with in-memory fallback
@@ -84,6 +83,13 @@ type RateLimiterWithFallback struct {
 	redisLimiter    *RateLimiter
 	inMemoryLimiter *InMemoryRateLimiter
 	config          RateLimiterConfig
+	bypass          *RateLimitBypass
+	metrics         RateLimitRecorder // nil until SetMetrics
+}
+
+// RateLimitRecorder counts requests blocked by rate limiting, such as *metrics.Metrics
+type RateLimitRecorder interface {
+	RecordRateLimitBlocked(endpoint string)
 }
 
 // NewRateLimiterWithFallback creates a new rate limiter with degraded mode support
@@ -95,16 +101,35 @@ func NewRateLimiterWithFallback(config RateLimiterConfig) *RateLimiterWithFallba
 		redisClient = rc
 	}
 
+	redisLimiter := NewRateLimiter(redisClient, config.RequestsPerMin, config.Window)
+	redisLimiter.SetUserLimit(config.UserRequestsPerMin, config.UserIdentity)
+
 	return &RateLimiterWithFallback{
-		redisLimiter:    NewRateLimiter(redisClient, config.RequestsPerMin, config.Window),
+		redisLimiter:    redisLimiter,
 		inMemoryLimiter: NewInMemoryRateLimiter(),
 		config:          config,
 	}
 }
 
+// SetBypass exempts trusted internal callers matched by bypass from rate limiting
+func (rl *RateLimiterWithFallback) SetBypass(bypass *RateLimitBypass) {
+	rl.bypass = bypass
+}
+
+// SetMetrics records blocked requests in m, labelled with the matched
+// endpoint override or "default"
+func (rl *RateLimiterWithFallback) SetMetrics(m RateLimitRecorder) {
+	rl.metrics = m
+}
+
 // Middleware returns a Gin middleware for rate limiting with degraded mode support
 func (rl *RateLimiterWithFallback) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if rl.bypass != nil && rl.bypass.Allows(c) {
+			c.Next()
+			return
+		}
+
 		// Get client IP
 		clientIP := c.ClientIP()
 		if clientIP == "" {
@@ -113,13 +138,19 @@ func (rl *RateLimiterWithFallback) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		// Get user ID if authenticated (for per-user rate limiting)
-		userID, exists := c.Get("user_id")
-		var identifier string
-		if exists {
-			identifier = fmt.Sprintf("user:%v", userID)
-		} else {
-			identifier = fmt.Sprintf("ip:%s", clientIP)
+		// Authenticated users are limited per user, everyone else per IP
+		identifier, perUser := rateLimitIdentifier(c, rl.config.UserIdentity)
+		limit, window := rl.redisLimiter.limitFor(perUser), rl.config.Window
+
+		// Routes with an override are counted in their own bucket
+		endpoint := defaultEndpointLabel
+		if pattern, override, ok := matchEndpointLimit(rl.config.EndpointOverrides, c.Request.URL.Path); ok {
+			endpoint = pattern
+			identifier += ":" + pattern
+			limit = override.Requests
+			if override.Window > 0 {
+				window = override.Window
+			}
 		}
 
 		// Check if Redis is in degraded mode
@@ -142,8 +173,8 @@ func (rl *RateLimiterWithFallback) Middleware() gin.HandlerFunc {
 
 			allowed, remaining, resetTime, err = rl.inMemoryLimiter.Check(
 				identifier,
-				rl.config.RequestsPerMin,
-				rl.config.Window,
+				limit,
+				window,
 			)
 
 			if err != nil {
@@ -153,10 +184,14 @@ func (rl *RateLimiterWithFallback) Middleware() gin.HandlerFunc {
 			}
 		} else {
 			// NORMAL MODE: Use Redis-based rate limiting
-			allowed, remaining, resetTime, err = rl.redisLimiter.checkRateLimit(
+			var reset time.Time
+			allowed, remaining, reset, err = rl.redisLimiter.Allow(
 				c.Request.Context(),
 				identifier,
+				limit,
+				window,
 			)
+			resetTime = resetUnix(reset)
 
 			if err != nil {
 				// Log error but don't fail-open if Redis is degraded
@@ -166,90 +201,29 @@ func (rl *RateLimiterWithFallback) Middleware() gin.HandlerFunc {
 						zap.String("identifier", identifier))
 					// Fail-open: Allow request to prevent service disruption
 					allowed = true
-					remaining = rl.config.RequestsPerMin
-					resetTime = time.Now().Unix() + int64(rl.config.Window.Seconds())
+					remaining = limit
+					resetTime = time.Now().Unix() + int64(window.Seconds())
 					err = nil
 				} else {
 					// Redis is healthy but operation failed - this is a real error
 					logger.Error("Redis rate limit check failed",
 						zap.Error(err),
 						zap.String("identifier", identifier))
+					// The request is rejected; tell the client to retry after the window
+					resetTime = time.Now().Unix() + int64(window.Seconds())
 				}
 			}
 		}
 
-		// Set rate limit headers
-		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.config.RequestsPerMin))
-		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
-		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime, 10))
-
+		setRateLimitHeaders(c, limit, remaining, window, resetTime)
 		if !allowed {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":     "Rate limit exceeded",
-				"limit":     rl.config.RequestsPerMin,
-				"remaining": remaining,
-				"reset_at":  resetTime,
-			})
-			c.Abort()
+			if rl.metrics != nil {
+				rl.metrics.RecordRateLimitBlocked(endpoint)
+			}
+			abortRateLimited(c, limit, resetTime)
 			return
 		}
 
 		c.Next()
 	}
 }
-
-// checkRateLimit checks if a request is within rate limits using Redis
-// This is a wrapper around the original RateLimiter's checkRateLimit method
-func (rl *RateLimiterWithFallback) checkRateLimit(ctx context.Context, identifier string) (bool, int, int64, error) {
-	if redisClient, ok := rl.config.RedisClient.(*database.RedisClient); ok {
-		// Use the new SafeGet method from RedisClient
-		countCmd := redisClient.SafeGet(ctx, fmt.Sprintf("ratelimit:%s", identifier))
-		count, err := countCmd.Int()
-		if err != nil {
-			return false, 0, 0, fmt.Errorf("failed to get rate limit count: %w", err)
-		}
-
-		now := time.Now().Unix()
-		windowStart := now - int64(rl.config.Window.Seconds())
-
-		// Get last reset time
-		lastResetCmd := redisClient.SafeGet(ctx, fmt.Sprintf("ratelimit:%s:reset", identifier))
-		lastReset, err := lastResetCmd.Int64()
-		if err != nil && err != redis.Nil {
-			return false, 0, 0, fmt.Errorf("failed to get last reset time: %w", err)
-		}
-
-		// If no last reset or window has expired, reset count
-		if err == redis.Nil || lastReset < windowStart {
-			// New window, reset count
-			pipe := redisClient.Client.Pipeline()
-			pipe.Set(ctx, fmt.Sprintf("ratelimit:%s", identifier), 1, rl.config.Window)
-			pipe.Set(ctx, fmt.Sprintf("ratelimit:%s:reset", identifier), now, rl.config.Window)
-			_, err := pipe.Exec(ctx)
-			if err != nil {
-				return false, 0, 0, fmt.Errorf("failed to reset rate limit: %w", err)
-			}
-			count = 1
-			lastReset = now
-		} else {
-			// Increment count within window
-			pipe := redisClient.Client.Pipeline()
-			pipe.Incr(ctx, fmt.Sprintf("ratelimit:%s", identifier))
-			pipe.Expire(ctx, fmt.Sprintf("ratelimit:%s", identifier), rl.config.Window)
-			_, err := pipe.Exec(ctx)
-			if err != nil {
-				return false, 0, 0, fmt.Errorf("failed to increment rate limit: %w", err)
-			}
-			count++
-		}
-
-		remaining := rl.config.RequestsPerMin - count
-		if remaining < 0 {
-			remaining = 0
-		}
-
-		allowed := count <= rl.config.RequestsPerMin
-		return allowed, remaining, lastReset + int64(rl.config.Window.Seconds()), nil
-	}
-	return false, 0, 0, fmt.Errorf("redis client type assertion failed")
-}
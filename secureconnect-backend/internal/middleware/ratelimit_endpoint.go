@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+)
+
+// defaultEndpointLabel is the metrics label for requests without an override
+const defaultEndpointLabel = "default"
+
+// EndpointLimit is the rate limit for one route pattern
+type EndpointLimit struct {
+	Requests int
+	Window   time.Duration // the limiter's window when 0
+}
+
+// endpointMatch ranks how specifically a pattern matches a path
+type endpointMatch struct {
+	literals int  // path segments matched literally
+	exact    bool // the pattern covers the whole path rather than a prefix
+	length   int
+}
+
+// beats reports whether m is more specific than other
+func (m endpointMatch) beats(other endpointMatch) bool {
+	if m.literals != other.literals {
+		return m.literals > other.literals
+	}
+	if m.exact != other.exact {
+		return m.exact
+	}
+	return m.length > other.length
+}
+
+// matchEndpointLimit returns the most specific pattern in overrides matching
+// path. Patterns match whole segments, with :name matching any one segment,
+// or every path under a prefix when they end in *.
+func matchEndpointLimit(overrides map[string]EndpointLimit, path string) (string, EndpointLimit, bool) {
+	var (
+		bestPattern string
+		bestLimit   EndpointLimit
+		best        endpointMatch
+		found       bool
+	)
+	for pattern, limit := range overrides {
+		match, ok := matchEndpointPattern(pattern, path)
+		if !ok {
+			continue
+		}
+		// Ties are broken by pattern so the choice does not depend on map order
+		if !found || match.beats(best) || (!best.beats(match) && pattern < bestPattern) {
+			bestPattern, bestLimit, best, found = pattern, limit, match, true
+		}
+	}
+	return bestPattern, bestLimit, found
+}
+
+// matchEndpointPattern reports whether pattern matches path and how specifically
+func matchEndpointPattern(pattern, path string) (endpointMatch, bool) {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		if !strings.HasPrefix(path, prefix) {
+			return endpointMatch{}, false
+		}
+		// Only segments completed by the prefix count as literal matches
+		return endpointMatch{literals: strings.Count(prefix, "/") - 1, length: len(pattern)}, true
+	}
+
+	patternParts := splitPath(pattern)
+	pathParts := splitPath(path)
+	if len(patternParts) != len(pathParts) {
+		return endpointMatch{}, false
+	}
+	match := endpointMatch{exact: true, length: len(pattern)}
+	for i, part := range patternParts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+		case part == pathParts[i]:
+			match.literals++
+		default:
+			return endpointMatch{}, false
+		}
+	}
+	return match, true
+}
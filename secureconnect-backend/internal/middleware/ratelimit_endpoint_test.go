@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestMatchEndpointLimit_MostSpecificPattern(t *testing.T) {
+	overrides := map[string]EndpointLimit{
+		"/v1/auth/*":                         {Requests: 50},
+		"/v1/auth/login":                     {Requests: 10},
+		"/v1/calls/:id":                      {Requests: 30},
+		"/v1/calls/initiate":                 {Requests: 10},
+		"/v1/conversations/:id":              {Requests: 100},
+		"/v1/conversations/:id/participants": {Requests: 30},
+	}
+
+	tests := []struct {
+		path        string
+		wantPattern string
+	}{
+		{"/v1/auth/login", "/v1/auth/login"},
+		{"/v1/auth/register", "/v1/auth/*"},
+		{"/v1/calls/initiate", "/v1/calls/initiate"},
+		{"/v1/calls/42", "/v1/calls/:id"},
+		{"/v1/conversations/42/participants", "/v1/conversations/:id/participants"},
+		{"/v1/conversations/42/messages", ""},
+		{"/v1/messages", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			pattern, _, ok := matchEndpointLimit(overrides, tt.path)
+			assert.Equal(t, tt.wantPattern != "", ok)
+			assert.Equal(t, tt.wantPattern, pattern)
+		})
+	}
+}
+
+// blockedRecorder counts blocked requests per endpoint
+type blockedRecorder map[string]int
+
+func (r blockedRecorder) RecordRateLimitBlocked(endpoint string) { r[endpoint]++ }
+
+func TestRateLimiter_EndpointOverrides(t *testing.T) {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	limiter := NewRateLimiterWithFallback(RateLimiterConfig{
+		RedisClient:    goredis.NewClient(&goredis.Options{Addr: mr.Addr()}),
+		RequestsPerMin: 3,
+		Window:         time.Minute,
+		EndpointOverrides: map[string]EndpointLimit{
+			"/v1/auth/login": {Requests: 1, Window: 10 * time.Minute},
+		},
+	})
+	blocked := blockedRecorder{}
+	limiter.SetMetrics(blocked)
+
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	router.POST("/v1/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/v1/messages", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.10:1234"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/v1/auth/login")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1;w=600", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/v1/auth/login").Code)
+
+	// Other routes keep their own, default budget
+	for i := 0; i < 3; i++ {
+		w := serve(http.MethodGet, "/v1/messages")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/v1/messages").Code)
+
+	assert.Equal(t, blockedRecorder{"/v1/auth/login": 1, "default": 1}, blocked)
+}
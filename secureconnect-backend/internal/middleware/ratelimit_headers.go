@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setRateLimitHeaders reports the caller's budget on every response. The
+// X-RateLimit-* headers carry the reset as a Unix timestamp; the IETF draft
+// RateLimit-* headers carry it as seconds from now.
+func setRateLimitHeaders(c *gin.Context, limit, remaining int, window time.Duration, resetAt int64) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(resetAt, 10))
+
+	c.Header("RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("RateLimit-Reset", strconv.FormatInt(secondsUntil(resetAt), 10))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit, int64(window.Seconds())))
+}
+
+// abortRateLimited rejects the request with 429 and tells the client when to retry
+func abortRateLimited(c *gin.Context, limit int, resetAt int64) {
+	retryAfter := secondsUntil(resetAt)
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Rate limit exceeded",
+		"limit":       limit,
+		"remaining":   0,
+		"reset_at":    resetAt,
+		"retry_after": retryAfter,
+	})
+}
+
+// resetUnix returns reset as a Unix timestamp, rounded up so clients that
+// wait until then find the window over
+func resetUnix(reset time.Time) int64 {
+	if reset.Nanosecond() > 0 {
+		return reset.Unix() + 1
+	}
+	return reset.Unix()
+}
+
+// secondsUntil returns the whole seconds until the Unix timestamp resetAt, never negative
+func secondsUntil(resetAt int64) int64 {
+	delta := resetAt - time.Now().Unix()
+	if delta < 0 {
+		return 0
+	}
+	return delta
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/pkg/logger"
+)
+
+// newDegradedLimiter returns a limiter whose Redis is unreachable, so every
+// check goes through the in-memory fallback
+func newDegradedLimiter(t *testing.T, limit int, window time.Duration) *gin.Engine {
+	t.Helper()
+	return newLimiterRouter(newFallbackLimiter(t, limit, window))
+}
+
+func newFallbackLimiter(t *testing.T, limit int, window time.Duration) *RateLimiterWithFallback {
+	t.Helper()
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	redisDB, err := database.NewRedisDB(&database.RedisConfig{Host: "127.0.0.1", Port: 1, Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	t.Cleanup(redisDB.Close)
+	require.Error(t, redisDB.HealthCheck(context.Background()))
+	require.True(t, redisDB.IsDegraded())
+
+	return NewRateLimiterWithFallback(RateLimiterConfig{
+		RedisClient:            redisDB,
+		RequestsPerMin:         limit,
+		Window:                 window,
+		EnableInMemoryFallback: true,
+	})
+}
+
+func newLimiterRouter(limiter *RateLimiterWithFallback) *gin.Engine {
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func doRequest(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_HeadersTrackRemainingBudget(t *testing.T) {
+	router := newDegradedLimiter(t, 3, time.Minute)
+	start := time.Now().Unix()
+
+	for want := 2; want >= 0; want-- {
+		w := doRequest(router)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(want), w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, strconv.Itoa(want), w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "3;w=60", w.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, start+60, reset, 1, "reset is the end of the current window")
+
+		delta, err := strconv.Atoi(w.Header().Get("RateLimit-Reset"))
+		require.NoError(t, err)
+		assert.InDelta(t, 60, delta, 1)
+	}
+}
+
+func TestRateLimiter_BlockedRequestGets429WithRetryAfter(t *testing.T) {
+	router := newDegradedLimiter(t, 1, 30*time.Second)
+
+	require.Equal(t, http.StatusOK, doRequest(router).Code)
+	w := doRequest(router)
+
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 30, retryAfter, 1)
+	assert.Equal(t, w.Header().Get("RateLimit-Reset"), w.Header().Get("Retry-After"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Rate limit exceeded", body["error"])
+	assert.EqualValues(t, retryAfter, body["retry_after"])
+}
+
+func TestRateLimiter_RedisHeadersOnAllowedAndBlocked(t *testing.T) {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	router := newLimiterRouter(NewRateLimiterWithFallback(RateLimiterConfig{
+		RedisClient:    goredis.NewClient(&goredis.Options{Addr: mr.Addr()}),
+		RequestsPerMin: 2,
+		Window:         time.Minute,
+	}))
+	start := time.Now().Unix()
+
+	for want := 1; want >= 0; want-- {
+		w := doRequest(router)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(want), w.Header().Get("X-RateLimit-Remaining"))
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, start+60, reset, 1)
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	}
+
+	// The reset follows the window's remaining time in Redis
+	mr.FastForward(45 * time.Second)
+	w := doRequest(router)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, start+15, reset, 1)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 15, retryAfter, 1)
+
+	// A new window starts once the old one expires
+	mr.FastForward(15 * time.Second)
+	w = doRequest(router)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	mr := miniredis.RunT(t)
+	limiter := NewRateLimiter(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), 1, time.Minute)
+	ctx := context.Background()
+
+	allowed, remaining, reset, err := limiter.Allow(ctx, "ip:192.0.2.1", 1, 30*time.Second)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 0, remaining)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), reset, time.Second)
+
+	mr.FastForward(10 * time.Second)
+	allowed, remaining, reset, err = limiter.Allow(ctx, "ip:192.0.2.1", 1, 30*time.Second)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 0, remaining)
+	assert.WithinDuration(t, time.Now().Add(20*time.Second), reset, time.Second, "blocked requests do not extend the window")
+
+	mr.Close()
+	_, _, _, err = limiter.Allow(ctx, "ip:192.0.2.1", 1, 30*time.Second)
+	assert.Error(t, err)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,9 +12,11 @@ import (
 
 // RateLimiter implements Redis-based rate limiting
 type RateLimiter struct {
-	redisClient *redis.Client
-	requests    int
-	window      time.Duration
+	redisClient  *redis.Client
+	requests     int
+	window       time.Duration
+	userRequests int              // limit for authenticated users; requests when 0
+	userIdentity UserIdentityFunc // nil until SetUserLimit
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -29,6 +30,22 @@ func NewRateLimiter(redisClient *redis.Client, requests int, window time.Duratio
 	}
 }
 
+// SetUserLimit limits authenticated users to requests per window across all
+// of their IPs, instead of sharing their IP's budget. identity finds the user
+// when AuthMiddleware has not run yet; nil only uses user_id from the context.
+func (rl *RateLimiter) SetUserLimit(requests int, identity UserIdentityFunc) {
+	rl.userRequests = requests
+	rl.userIdentity = identity
+}
+
+// limitFor returns the request limit for a per-user or per-IP identifier
+func (rl *RateLimiter) limitFor(perUser bool) int {
+	if perUser && rl.userRequests > 0 {
+		return rl.userRequests
+	}
+	return rl.requests
+}
+
 // Middleware returns a Gin middleware for rate limiting
 func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -40,17 +57,12 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		// Get user ID if authenticated (for per-user rate limiting)
-		userID, exists := c.Get("user_id")
-		var identifier string
-		if exists {
-			identifier = fmt.Sprintf("user:%v", userID)
-		} else {
-			identifier = fmt.Sprintf("ip:%s", clientIP)
-		}
+		// Authenticated users are limited per user, everyone else per IP
+		identifier, perUser := rateLimitIdentifier(c, rl.userIdentity)
+		limit := rl.limitFor(perUser)
 
 		// Check rate limit
-		allowed, remaining, resetTime, err := rl.checkRateLimit(c.Request.Context(), identifier)
+		allowed, remaining, reset, err := rl.Allow(c.Request.Context(), identifier, limit, rl.window)
 		if err != nil {
 			// Fail-open: Allow request if Redis is unavailable to prevent service disruption
 			// Log the error but continue processing
@@ -58,19 +70,10 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set rate limit headers
-		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.requests))
-		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
-		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime, 10))
-
+		resetAt := resetUnix(reset)
+		setRateLimitHeaders(c, limit, remaining, rl.window, resetAt)
 		if !allowed {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":     "Rate limit exceeded",
-				"limit":     rl.requests,
-				"remaining": remaining,
-				"reset_at":  resetTime,
-			})
-			c.Abort()
+			abortRateLimited(c, limit, resetAt)
 			return
 		}
 
@@ -78,64 +81,38 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	}
 }
 
-// checkRateLimit checks if the request is within rate limits
-func (rl *RateLimiter) checkRateLimit(ctx context.Context, identifier string) (bool, int, int64, error) {
-	// Redis key for rate limiting
+// rateLimitScript counts a request in a fixed window and returns the count
+// and the window's remaining time in milliseconds. The window starts with
+// its first request; running as one script keeps concurrent requests from
+// racing on the count or losing the expiry.
+var rateLimitScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// Allow counts a request for identifier against requests per window and
+// returns whether it is allowed, how many requests are left and when the
+// current window resets
+func (rl *RateLimiter) Allow(ctx context.Context, identifier string, requests int, window time.Duration) (allowed bool, remaining int, reset time.Time, err error) {
 	key := fmt.Sprintf("ratelimit:%s", identifier)
 
-	// Use Redis INCR to count requests
-	now := time.Now().Unix()
-	windowStart := now - int64(rl.window.Seconds())
-
-	// Get current count
-	countCmd := rl.redisClient.Get(ctx, key)
-	count, err := countCmd.Int()
-	if err != nil && err != redis.Nil {
-		return false, 0, 0, fmt.Errorf("failed to get rate limit count: %w", err)
-	}
-
-	// If no count exists, start fresh
-	if err == redis.Nil {
-		count = 0
-	}
-
-	// Check if we're in the same window
-	lastResetCmd := rl.redisClient.Get(ctx, key+":reset")
-	lastReset, err := lastResetCmd.Int64()
-	if err != nil && err != redis.Nil {
-		return false, 0, 0, fmt.Errorf("failed to get last reset time: %w", err)
+	result, err := rateLimitScript.Run(ctx, rl.redisClient, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, time.Time{}, fmt.Errorf("failed to check rate limit: %w", err)
 	}
-
-	if err == redis.Nil || lastReset < windowStart {
-		// New window, reset count
-		pipe := rl.redisClient.Pipeline()
-		pipe.Set(ctx, key, 1, rl.window)
-		pipe.Set(ctx, key+":reset", now, rl.window)
-		_, err := pipe.Exec(ctx)
-		if err != nil {
-			return false, 0, 0, fmt.Errorf("failed to reset rate limit: %w", err)
-		}
-		count = 1
-		lastReset = now
-	} else {
-		// Increment count
-		pipe := rl.redisClient.Pipeline()
-		pipe.Incr(ctx, key)
-		pipe.Expire(ctx, key, rl.window)
-		_, err := pipe.Exec(ctx)
-		if err != nil {
-			return false, 0, 0, fmt.Errorf("failed to increment rate limit: %w", err)
-		}
-		count++
+	if len(result) != 2 {
+		return false, 0, time.Time{}, fmt.Errorf("unexpected rate limit script result %v", result)
 	}
+	count, ttl := int(result[0]), time.Duration(result[1])*time.Millisecond
 
-	remaining := rl.requests - count
+	remaining = requests - count
 	if remaining < 0 {
 		remaining = 0
 	}
-
-	allowed := count <= rl.requests
-	resetTime := lastReset + int64(rl.window.Seconds())
-
-	return allowed, remaining, resetTime, nil
+	return count <= requests, remaining, time.Now().Add(ttl), nil
 }
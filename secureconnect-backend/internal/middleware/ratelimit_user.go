@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/pkg/jwt"
+)
+
+// UserIdentityFunc returns the authenticated user making the request, if any
+type UserIdentityFunc func(c *gin.Context) (string, bool)
+
+// BearerTokenUser identifies the user from a valid bearer token, so limiters
+// running before AuthMiddleware can still key on the user. Tokens that fail
+// validation are ignored and the request is limited by IP; AuthMiddleware
+// rejects them later.
+func BearerTokenUser(jwtManager *jwt.JWTManager) UserIdentityFunc {
+	return func(c *gin.Context) (string, bool) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return "", false
+		}
+		claims, err := jwtManager.ValidateToken(token)
+		if err != nil || claims.Audience != "secureconnect-api" {
+			return "", false
+		}
+		return claims.UserID.String(), true
+	}
+}
+
+// rateLimitIdentifier returns the key a request is counted under: user:<id>
+// for an authenticated user, set by AuthMiddleware or found by identity, and
+// ip:<address> otherwise. perUser reports which one was used.
+func rateLimitIdentifier(c *gin.Context, identity UserIdentityFunc) (identifier string, perUser bool) {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID), true
+	}
+	if identity != nil {
+		if userID, ok := identity(c); ok {
+			return "user:" + userID, true
+		}
+	}
+	return "ip:" + c.ClientIP(), false
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
+)
+
+// newUserLimiterRouter limits IPs to 2 and users to 3 requests per minute,
+// identifying users by a valid bearer token
+func newUserLimiterRouter(t *testing.T, jwtManager *jwt.JWTManager) *gin.Engine {
+	t.Helper()
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	return newLimiterRouter(NewRateLimiterWithFallback(RateLimiterConfig{
+		RedisClient:        goredis.NewClient(&goredis.Options{Addr: mr.Addr()}),
+		RequestsPerMin:     2,
+		UserRequestsPerMin: 3,
+		UserIdentity:       BearerTokenUser(jwtManager),
+		Window:             time.Minute,
+	}))
+}
+
+func doRequestAs(router *gin.Engine, remoteAddr, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = remoteAddr
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_PerUserLimitAcrossIPs(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret-key-at-least-32-characters", time.Minute, time.Hour)
+	router := newUserLimiterRouter(t, jwtManager)
+
+	token, err := jwtManager.GenerateAccessToken(uuid.New(), "a@example.com", "alice", "user")
+	require.NoError(t, err)
+
+	// One user is counted once across every address they use
+	for i, addr := range []string{"198.51.100.1:1000", "198.51.100.2:1000", "198.51.100.3:1000"} {
+		w := doRequestAs(router, addr, token)
+		require.Equal(t, http.StatusOK, w.Code, "request %d", i+1)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequestAs(router, "198.51.100.4:1000", token).Code)
+}
+
+func TestRateLimiter_UsersBehindSharedIP(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret-key-at-least-32-characters", time.Minute, time.Hour)
+	router := newUserLimiterRouter(t, jwtManager)
+	const nat = "203.0.113.50:4000"
+
+	// Anonymous requests from the NAT use up the IP budget
+	assert.Equal(t, http.StatusOK, doRequestAs(router, nat, "").Code)
+	assert.Equal(t, http.StatusOK, doRequestAs(router, nat, "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequestAs(router, nat, "").Code)
+
+	// Signed-in users behind the same NAT each have their own budget
+	for _, name := range []string{"alice", "bob"} {
+		token, err := jwtManager.GenerateAccessToken(uuid.New(), name+"@example.com", name, "user")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, doRequestAs(router, nat, token).Code, name)
+	}
+
+	// A token that does not validate is limited by IP
+	assert.Equal(t, http.StatusTooManyRequests, doRequestAs(router, nat, "not-a-token").Code)
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestRequestID_PropagatesIncomingID(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = previous })
+	gin.SetMode(gin.TestMode)
+
+	var forwarded string
+	router := gin.New()
+	router.Use(RequestID())
+	router.Use(RequestLogger("info"))
+	router.GET("/v1/messages", func(c *gin.Context) {
+		forwarded = c.Request.Header.Get(RequestIDHeader)
+		logger.FromContext(c.Request.Context()).Info("Handling request")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/messages", nil)
+	req.Header.Set(RequestIDHeader, "gw-1234")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "gw-1234", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "gw-1234", forwarded, "proxied requests forward the request headers")
+	require.Equal(t, 2, logs.Len())
+	for _, entry := range logs.All() {
+		assert.Equal(t, "gw-1234", entry.ContextMap()["request_id"], entry.Message)
+	}
+}
+
+func TestRequestID_GeneratesMissingOrMalformedIDs(t *testing.T) {
+	logger.InitDefault("test")
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("request_id"))
+	})
+
+	for name, incoming := range map[string]string{
+		"missing":   "",
+		"too long":  strings.Repeat("a", maxRequestIDLength+1),
+		"injection": "abc\" forged=1",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			req.Header.Set(RequestIDHeader, incoming)
+		}
+		router.ServeHTTP(w, req)
+
+		_, err := uuid.Parse(w.Header().Get(RequestIDHeader))
+		assert.NoError(t, err, name)
+		assert.Equal(t, w.Header().Get(RequestIDHeader), w.Body.String(), name)
+	}
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"secureconnect-backend/pkg/response"
+)
+
+// DefaultSystemStatusTimeout bounds each service's readiness probe
+const DefaultSystemStatusTimeout = 2 * time.Second
+
+// System status values
+const (
+	SystemStatusHealthy  = "healthy"
+	SystemStatusDegraded = "degraded"
+	SystemStatusDown     = "down"
+)
+
+// StatusTarget is a downstream service probed for the system status
+type StatusTarget struct {
+	Service string
+	// URL is the service's readiness endpoint
+	URL string
+}
+
+// ServiceStatus is one service's entry in the system status
+type ServiceStatus struct {
+	Service   string `json:"service"`
+	Reachable bool   `json:"reachable"`
+	Ready     bool   `json:"ready"`
+	// Degraded is set for a reachable service that is not ready or reports an
+	// unhealthy dependency
+	Degraded     bool            `json:"degraded"`
+	StatusCode   int             `json:"status_code,omitempty"`
+	Dependencies map[string]bool `json:"dependencies,omitempty"`
+	LatencyMS    int64           `json:"latency_ms"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// SystemStatus is the consolidated health of every downstream service
+type SystemStatus struct {
+	// Status is healthy when every service is ready, down when none can be
+	// reached, and degraded otherwise
+	Status    string           `json:"status"`
+	Services  []*ServiceStatus `json:"services"`
+	CheckedAt time.Time        `json:"checked_at"`
+}
+
+// SystemStatusChecker fans out to each service's readiness endpoint and
+// consolidates the answers. An unreachable service is reported as down
+// instead of failing the whole check.
+type SystemStatusChecker struct {
+	targets []StatusTarget
+	client  *http.Client
+}
+
+// NewSystemStatusChecker creates a checker probing targets, waiting at most
+// timeout for each one
+func NewSystemStatusChecker(targets []StatusTarget, timeout time.Duration) *SystemStatusChecker {
+	if timeout <= 0 {
+		timeout = DefaultSystemStatusTimeout
+	}
+	return &SystemStatusChecker{
+		targets: targets,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Check probes every target concurrently
+func (sc *SystemStatusChecker) Check(ctx context.Context) *SystemStatus {
+	services := make([]*ServiceStatus, len(sc.targets))
+	var wg sync.WaitGroup
+	for i, target := range sc.targets {
+		wg.Add(1)
+		go func(i int, target StatusTarget) {
+			defer wg.Done()
+			services[i] = sc.probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Service < services[j].Service })
+
+	status := &SystemStatus{
+		Status:    SystemStatusHealthy,
+		Services:  services,
+		CheckedAt: time.Now().UTC(),
+	}
+	reachable := 0
+	for _, service := range services {
+		if service.Reachable {
+			reachable++
+		}
+		if !service.Reachable || service.Degraded {
+			status.Status = SystemStatusDegraded
+		}
+	}
+	if reachable == 0 && len(services) > 0 {
+		status.Status = SystemStatusDown
+	}
+	return status
+}
+
+// probe calls one service's readiness endpoint
+func (sc *SystemStatusChecker) probe(ctx context.Context, target StatusTarget) *ServiceStatus {
+	status := &ServiceStatus{Service: target.Service}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	start := time.Now()
+	resp, err := sc.client.Do(req)
+	status.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+
+	status.Reachable = true
+	status.StatusCode = resp.StatusCode
+	status.Ready = resp.StatusCode == http.StatusOK
+	if !status.Ready {
+		status.Error = fmt.Sprintf("readiness check returned %d", resp.StatusCode)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err == nil {
+		status.Dependencies = readinessDependencies(body)
+	}
+
+	status.Degraded = !status.Ready
+	for _, healthy := range status.Dependencies {
+		if !healthy {
+			status.Degraded = true
+		}
+	}
+	return status
+}
+
+// readinessDependencies extracts dependency health from a readiness body.
+// Dependencies appear as "healthy"/"unhealthy" strings or as objects with a
+// healthy flag, e.g. the video service's push provider status.
+func readinessDependencies(body map[string]interface{}) map[string]bool {
+	dependencies := make(map[string]bool)
+	for name, value := range body {
+		if name == "status" || name == "service" {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if v == "healthy" || v == "unhealthy" {
+				dependencies[name] = v == "healthy"
+			}
+		case map[string]interface{}:
+			if healthy, ok := v["healthy"].(bool); ok {
+				dependencies[name] = healthy
+			}
+		}
+	}
+	if len(dependencies) == 0 {
+		return nil
+	}
+	return dependencies
+}
+
+// Handler serves the consolidated system status
+func (sc *SystemStatusChecker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, http.StatusOK, sc.Check(c.Request.Context()))
+	}
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readinessServer(t *testing.T, code int, body gin.H) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSystemStatusChecker_MarksUnreachableServiceDown(t *testing.T) {
+	chat := readinessServer(t, http.StatusOK, gin.H{"status": "ready", "service": "chat-service", "cassandra": "healthy"})
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	checker := NewSystemStatusChecker([]StatusTarget{
+		{Service: "video-service", URL: downURL + "/ready"},
+		{Service: "chat-service", URL: chat.URL + "/ready"},
+	}, time.Second)
+
+	status := checker.Check(context.Background())
+	assert.Equal(t, SystemStatusDegraded, status.Status)
+	require.Len(t, status.Services, 2)
+
+	chatStatus, videoStatus := status.Services[0], status.Services[1]
+	assert.Equal(t, "chat-service", chatStatus.Service)
+	assert.True(t, chatStatus.Reachable)
+	assert.True(t, chatStatus.Ready)
+	assert.False(t, chatStatus.Degraded)
+	assert.Equal(t, map[string]bool{"cassandra": true}, chatStatus.Dependencies)
+
+	assert.Equal(t, "video-service", videoStatus.Service)
+	assert.False(t, videoStatus.Reachable)
+	assert.False(t, videoStatus.Ready)
+	assert.NotEmpty(t, videoStatus.Error)
+}
+
+func TestSystemStatusChecker_ReportsDegradedDependencies(t *testing.T) {
+	chat := readinessServer(t, http.StatusServiceUnavailable, gin.H{"status": "not_ready", "cassandra": "unhealthy"})
+	video := readinessServer(t, http.StatusOK, gin.H{"status": "ready", "push": gin.H{"provider": "fcm", "healthy": false}})
+	auth := readinessServer(t, http.StatusOK, gin.H{"status": "healthy", "service": "auth-service"})
+
+	checker := NewSystemStatusChecker([]StatusTarget{
+		{Service: "chat-service", URL: chat.URL},
+		{Service: "video-service", URL: video.URL},
+		{Service: "auth-service", URL: auth.URL},
+	}, time.Second)
+
+	status := checker.Check(context.Background())
+	assert.Equal(t, SystemStatusDegraded, status.Status)
+
+	byService := make(map[string]*ServiceStatus)
+	for _, service := range status.Services {
+		byService[service.Service] = service
+	}
+	assert.True(t, byService["chat-service"].Degraded, "not ready")
+	assert.Equal(t, http.StatusServiceUnavailable, byService["chat-service"].StatusCode)
+	assert.True(t, byService["video-service"].Degraded, "push provider unhealthy")
+	assert.Equal(t, map[string]bool{"push": false}, byService["video-service"].Dependencies)
+	assert.False(t, byService["auth-service"].Degraded)
+	assert.Nil(t, byService["auth-service"].Dependencies)
+}
+
+func TestRequireAdminFrom(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		checkCode int
+		want      int
+	}{
+		{"admin", http.StatusNoContent, http.StatusOK},
+		{"not an admin", http.StatusForbidden, http.StatusForbidden},
+		{"token rejected", http.StatusUnauthorized, http.StatusForbidden},
+		{"auth service failing", http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuth string
+			auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+				w.WriteHeader(tt.checkCode)
+			}))
+			defer auth.Close()
+
+			router := gin.New()
+			router.GET("/admin", RequireAdminFrom(auth.URL, time.Second), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			assert.Equal(t, "Bearer token", gotAuth, "the caller's token is forwarded")
+		})
+	}
+}
+
+type fakeAdminChecker map[uuid.UUID]bool
+
+func (f fakeAdminChecker) CheckAdminRole(ctx context.Context, userID uuid.UUID) (bool, error) {
+	isAdmin, ok := f[userID]
+	if !ok {
+		return false, errors.New("user not found")
+	}
+	return isAdmin, nil
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	admin, member := uuid.New(), uuid.New()
+	checker := fakeAdminChecker{admin: true, member: false}
+
+	tests := []struct {
+		name   string
+		userID interface{}
+		want   int
+	}{
+		{"admin", admin, http.StatusOK},
+		{"not an admin", member, http.StatusForbidden},
+		{"role lookup failing", uuid.New(), http.StatusServiceUnavailable},
+		{"not authenticated", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/admin", func(c *gin.Context) {
+				if tt.userID != nil {
+					c.Set("user_id", tt.userID)
+				}
+			}, RequireAdmin(checker), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin", nil))
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,15 +14,27 @@ import (
 	"secureconnect-backend/pkg/metrics"
 )
 
+// DefaultTimeoutExemptRoutes are WebSocket endpoints and streaming downloads,
+// which legitimately hold the connection open for as long as the client needs
+var DefaultTimeoutExemptRoutes = []string{
+	"/v1/ws/*",
+	"/v1/storage/stream*",
+}
+
 // TimeoutConfig holds timeout configuration
 type TimeoutConfig struct {
 	DefaultTimeout time.Duration
+	// ExemptRoutes are never timed out. An entry matches a route template
+	// (such as /v1/files/:id) or request path exactly, or every route under
+	// a prefix when it ends in *. WebSocket upgrades are always exempt.
+	ExemptRoutes []string
 }
 
 // DefaultTimeoutConfig returns default timeout configuration
 func DefaultTimeoutConfig() *TimeoutConfig {
 	return &TimeoutConfig{
 		DefaultTimeout: 30 * time.Second,
+		ExemptRoutes:   append([]string(nil), DefaultTimeoutExemptRoutes...),
 	}
 }
 
@@ -46,6 +59,11 @@ func (tm *TimeoutMiddleware) SetConfig(config *TimeoutConfig) {
 // Middleware returns a Gin middleware for timeout protection
 func (tm *TimeoutMiddleware) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if tm.isExempt(c) {
+			c.Next()
+			return
+		}
+
 		// Check for per-route timeout override
 		timeout := tm.config.DefaultTimeout
 		if timeoutOverride, exists := c.Get("timeout_override"); exists {
@@ -105,6 +123,30 @@ func (tm *TimeoutMiddleware) Middleware() gin.HandlerFunc {
 	}
 }
 
+// isExempt reports whether the request is a WebSocket upgrade or matches an exempt route
+func (tm *TimeoutMiddleware) isExempt(c *gin.Context) bool {
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return true
+	}
+	for _, route := range tm.config.ExemptRoutes {
+		if matchesRoute(route, c.FullPath()) || matchesRoute(route, c.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRoute reports whether path is route, or is under route when route ends in *
+func matchesRoute(route, path string) bool {
+	if path == "" {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(route, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return path == route
+}
+
 // WithTimeout creates a context with timeout for use in handlers
 // This allows handlers to create their own timeout contexts
 func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// slowHandler responds after 50ms unless the request context ends first
+func slowHandler(c *gin.Context) {
+	select {
+	case <-time.After(50 * time.Millisecond):
+		c.String(http.StatusOK, "done")
+	case <-c.Request.Context().Done():
+	}
+}
+
+func newTimeoutRouter(exempt ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(NewTimeoutMiddleware(&TimeoutConfig{
+		DefaultTimeout: 10 * time.Millisecond,
+		ExemptRoutes:   append(append([]string(nil), DefaultTimeoutExemptRoutes...), exempt...),
+	}).Middleware())
+	router.GET("/v1/messages", slowHandler)
+	router.GET("/v1/exports/:id", slowHandler)
+	router.GET("/v1/storage/stream/:file_id", slowHandler)
+	router.GET("/v1/ws/chat", slowHandler)
+	return router
+}
+
+func TestTimeoutMiddleware_ExemptRoutes(t *testing.T) {
+	logger.InitDefault("test")
+	router := newTimeoutRouter("/v1/exports/:id")
+
+	tests := []struct {
+		name       string
+		path       string
+		websocket  bool
+		wantStatus int
+	}{
+		{"normal route times out", "/v1/messages", false, http.StatusGatewayTimeout},
+		{"configured route template", "/v1/exports/123", false, http.StatusOK},
+		{"streaming download exempt by default", "/v1/storage/stream/abc", false, http.StatusOK},
+		{"websocket route exempt by default", "/v1/ws/chat", false, http.StatusOK},
+		{"websocket upgrade on any route", "/v1/messages", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.websocket {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
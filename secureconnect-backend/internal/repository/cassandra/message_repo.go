@@ -2,7 +2,9 @@ package cassandra
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
@@ -11,6 +13,7 @@ import (
 
 	"secureconnect-backend/internal/database"
 	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/backoff"
 	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
 )
@@ -50,47 +53,15 @@ func (r *MessageRepository) Save(ctx context.Context, message *domain.Message) e
 		message.MessageID = uuid.New()
 	}
 
-	// Handle metadata - convert to map[string]string for Cassandra MAP<TEXT, TEXT>
-	metadataMap := make(map[string]string)
-	if message.Metadata != nil {
-		const MaxMetadataKeyLen = 100
-		const MaxMetadataValueLen = 1000
-
-		for k, v := range message.Metadata {
-			// Defense in depth: validate lengths
-			if len(k) > MaxMetadataKeyLen {
-				return fmt.Errorf("metadata key too long: %s", k)
-			}
-
-			var strVal string
-			// Convert value to string
-			switch val := v.(type) {
-			case string:
-				strVal = val
-			case int, int8, int16, int32, int64:
-				strVal = fmt.Sprintf("%d", val)
-			case float32, float64:
-				strVal = fmt.Sprintf("%f", val)
-			case bool:
-				strVal = fmt.Sprintf("%t", val)
-			default:
-				strVal = fmt.Sprintf("%v", val)
-			}
-
-			if len(strVal) > MaxMetadataValueLen {
-				return fmt.Errorf("metadata value too long for key %s", k)
-			}
-			metadataMap[k] = strVal
-		}
-	} else {
-		// Use empty map if metadata is nil
-		metadataMap = map[string]string{}
+	metadataMap, err := toMetadataMap(message.Metadata)
+	if err != nil {
+		return err
 	}
 
-	query := `INSERT INTO messages (conversation_id, message_id, sender_id, content, is_encrypted, message_type, metadata, sent_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO messages (conversation_id, message_id, sender_id, content, is_encrypted, message_type, metadata, sent_at, seq) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	// Execute with retry logic that respects context cancellation
-	err := r.executeWithRetry(ctx, operation, table, func() error {
+	err = r.executeWithRetry(ctx, operation, table, func() error {
 		return r.db.ExecWithContext(ctx, query,
 			toGocqlUUID(message.ConversationID),
 			toGocqlUUID(message.MessageID),
@@ -100,6 +71,7 @@ func (r *MessageRepository) Save(ctx context.Context, message *domain.Message) e
 			message.MessageType,
 			metadataMap,
 			message.SentAt,
+			message.Seq,
 		)
 	})
 
@@ -123,6 +95,104 @@ func (r *MessageRepository) Save(ctx context.Context, message *domain.Message) e
 	return nil
 }
 
+// SaveBatch inserts several messages of the same conversation with a single
+// unlogged batch. All messages must share the conversation_id partition key.
+func (r *MessageRepository) SaveBatch(ctx context.Context, messages []*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	startTime := time.Now()
+	operation := "save_batch"
+	table := "messages"
+	conversationID := messages[0].ConversationID
+
+	rows := make([][]interface{}, 0, len(messages))
+	for _, message := range messages {
+		if message.ConversationID != conversationID {
+			return fmt.Errorf("batch spans multiple conversations")
+		}
+		if message.MessageID == uuid.Nil {
+			message.MessageID = uuid.New()
+		}
+
+		metadataMap, err := toMetadataMap(message.Metadata)
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, []interface{}{
+			toGocqlUUID(message.ConversationID),
+			toGocqlUUID(message.MessageID),
+			toGocqlUUID(message.SenderID),
+			message.Content,
+			message.IsEncrypted,
+			message.MessageType,
+			metadataMap,
+			message.SentAt,
+			message.Seq,
+		})
+	}
+
+	query := `INSERT INTO messages (conversation_id, message_id, sender_id, content, is_encrypted, message_type, metadata, sent_at, seq) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	// Single-partition unlogged batch: applied atomically without the batch log overhead
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		return r.db.ExecBatchWithContext(ctx, gocql.UnloggedBatch, query, rows)
+	})
+
+	// Record metrics
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraWriteError(table, classifyError(err))
+		logger.Error("Failed to save message batch",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Int("count", len(messages)),
+			zap.Error(err))
+		return fmt.Errorf("failed to save message batch: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return nil
+}
+
+// toMetadataMap converts message metadata to map[string]string for Cassandra MAP<TEXT, TEXT>
+func toMetadataMap(metadata map[string]interface{}) (map[string]string, error) {
+	const MaxMetadataKeyLen = 100
+	const MaxMetadataValueLen = 1000
+
+	metadataMap := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		// Defense in depth: validate lengths
+		if len(k) > MaxMetadataKeyLen {
+			return nil, fmt.Errorf("metadata key too long: %s", k)
+		}
+
+		var strVal string
+		// Convert value to string
+		switch val := v.(type) {
+		case string:
+			strVal = val
+		case int, int8, int16, int32, int64:
+			strVal = fmt.Sprintf("%d", val)
+		case float32, float64:
+			strVal = fmt.Sprintf("%f", val)
+		case bool:
+			strVal = fmt.Sprintf("%t", val)
+		default:
+			strVal = fmt.Sprintf("%v", val)
+		}
+
+		if len(strVal) > MaxMetadataValueLen {
+			return nil, fmt.Errorf("metadata value too long for key %s", k)
+		}
+		metadataMap[k] = strVal
+	}
+	return metadataMap, nil
+}
+
 // GetByConversation retrieves messages for a conversation with pagination and timeout
 func (r *MessageRepository) GetByConversation(
 	ctx context.Context,
@@ -136,22 +206,25 @@ func (r *MessageRepository) GetByConversation(
 
 	query := `
 		SELECT conversation_id, message_id, sender_id, content,
-		       is_encrypted, message_type, metadata, sent_at
+		       is_encrypted, message_type, metadata, sent_at, seq, edited_at,
+		       deleted_at, deleted_by
 		FROM messages
 		WHERE conversation_id = ?
-		ORDER BY sent_at DESC
-		LIMIT ?
+		ORDER BY sent_at DESC, message_id DESC
 	`
 
 	var messages []*domain.Message
 	var nextPageState []byte
 
-	// Execute with retry logic that respects context cancellation
+	// Execute with retry logic that respects context cancellation.
+	// The page size (not a LIMIT) bounds each call so the page state can resume the query.
 	err := r.executeWithRetry(ctx, operation, table, func() error {
-		iter := r.db.QueryWithContext(ctx, query, toGocqlUUID(conversationID), limit).PageState(pageState).Iter()
+		iter := r.db.QueryWithContext(ctx, query, toGocqlUUID(conversationID)).PageSize(limit).PageState(pageState).Iter()
 		defer iter.Close()
 
-		for {
+		// Stop at the page boundary; scanning further would make gocql fetch the next page
+		messages = nil
+		for len(messages) < limit {
 			message := &domain.Message{}
 			if !iter.Scan(
 				&message.ConversationID,
@@ -162,6 +235,10 @@ func (r *MessageRepository) GetByConversation(
 				&message.MessageType,
 				&message.Metadata,
 				&message.SentAt,
+				&message.Seq,
+				&message.EditedAt,
+				&message.DeletedAt,
+				&message.DeletedBy,
 			) {
 				break
 			}
@@ -226,15 +303,17 @@ func (r *MessageRepository) GetRecentMessages(ctx context.Context, conversationI
 	return r.GetByConversation(ctx, conversationID, limit, nil)
 }
 
-// GetByID retrieves a specific message with timeout
-func (r *MessageRepository) GetByID(ctx context.Context, conversationID uuid.UUID, bucket int, messageID uuid.UUID) (*domain.Message, error) {
+// GetByID retrieves a specific message with timeout. It returns
+// domain.ErrMessageNotFound when the conversation has no such message.
+func (r *MessageRepository) GetByID(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.Message, error) {
 	startTime := time.Now()
 	operation := "get_by_id"
 	table := "messages"
 
 	query := `
 		SELECT conversation_id, message_id, sender_id, content,
-		       is_encrypted, message_type, metadata, sent_at
+		       is_encrypted, message_type, metadata, sent_at, seq, edited_at,
+		       deleted_at, deleted_by
 		FROM messages
 		WHERE conversation_id = ? AND message_id = ?
 		LIMIT 1
@@ -253,6 +332,10 @@ func (r *MessageRepository) GetByID(ctx context.Context, conversationID uuid.UUI
 			&message.MessageType,
 			&message.Metadata,
 			&message.SentAt,
+			&message.Seq,
+			&message.EditedAt,
+			&message.DeletedAt,
+			&message.DeletedBy,
 		)
 	})
 
@@ -262,7 +345,7 @@ func (r *MessageRepository) GetByID(ctx context.Context, conversationID uuid.UUI
 	if err != nil {
 		if err == gocql.ErrNotFound {
 			metrics.RecordCassandraQuery(operation, table, "not_found")
-			return nil, fmt.Errorf("message not found")
+			return nil, domain.ErrMessageNotFound
 		}
 		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
 		metrics.RecordCassandraReadError(table, classifyError(err))
@@ -277,6 +360,278 @@ func (r *MessageRepository) GetByID(ctx context.Context, conversationID uuid.UUI
 	return message, nil
 }
 
+// maxKeysPerQuery bounds the IN list of GetByKeys below Cassandra's default
+// max_clustering_key_restrictions_per_query
+const maxKeysPerQuery = 100
+
+// GetByKeys reads the messages of a conversation at keys. Messages that do
+// not exist are left out; the order of the result is unspecified.
+func (r *MessageRepository) GetByKeys(ctx context.Context, conversationID uuid.UUID, keys []domain.MessageKey) ([]*domain.Message, error) {
+	messages := make([]*domain.Message, 0, len(keys))
+	for start := 0; start < len(keys); start += maxKeysPerQuery {
+		batch, err := r.getByKeys(ctx, conversationID, keys[start:min(start+maxKeysPerQuery, len(keys))])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, batch...)
+	}
+	return messages, nil
+}
+
+func (r *MessageRepository) getByKeys(ctx context.Context, conversationID uuid.UUID, keys []domain.MessageKey) ([]*domain.Message, error) {
+	startTime := time.Now()
+	operation := "get_by_keys"
+	table := "messages"
+
+	placeholders := make([]string, len(keys))
+	args := make([]interface{}, 0, 1+2*len(keys))
+	args = append(args, toGocqlUUID(conversationID))
+	for i, key := range keys {
+		placeholders[i] = "(?, ?)"
+		args = append(args, key.SentAt, toGocqlUUID(key.MessageID))
+	}
+	query := `
+		SELECT conversation_id, message_id, sender_id, content,
+		       is_encrypted, message_type, metadata, sent_at, seq, edited_at,
+		       deleted_at, deleted_by
+		FROM messages
+		WHERE conversation_id = ? AND (sent_at, message_id) IN (` + strings.Join(placeholders, ", ") + `)`
+
+	var messages []*domain.Message
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		iter := r.db.QueryWithContext(ctx, query, args...).Iter()
+		messages = nil
+		for {
+			message := &domain.Message{}
+			if !iter.Scan(
+				&message.ConversationID,
+				&message.MessageID,
+				&message.SenderID,
+				&message.Content,
+				&message.IsEncrypted,
+				&message.MessageType,
+				&message.Metadata,
+				&message.SentAt,
+				&message.Seq,
+				&message.EditedAt,
+				&message.DeletedAt,
+				&message.DeletedBy,
+			) {
+				break
+			}
+			messages = append(messages, message)
+		}
+		return iter.Close()
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraReadError(table, classifyError(err))
+		logger.Error("Failed to get messages",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Int("count", len(keys)),
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return messages, nil
+}
+
+// Update rewrites a message's content and edited_at. The message is located
+// by conversation, sent_at and message ID, and the write only applies if
+// message.SenderID sent it and it is not deleted: domain.ErrNotMessageSender
+// or domain.ErrMessageDeleted is returned otherwise, and
+// domain.ErrMessageNotFound when there is no such message.
+func (r *MessageRepository) Update(ctx context.Context, message *domain.Message) error {
+	startTime := time.Now()
+	operation := "update"
+	table := "messages"
+
+	// Lightweight transaction, so the ownership check and the write are atomic
+	query := `UPDATE messages SET content = ?, edited_at = ? WHERE conversation_id = ? AND sent_at = ? AND message_id = ? IF sender_id = ? AND deleted_at = null`
+
+	var applied bool
+	var current map[string]interface{}
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		var casErr error
+		current = map[string]interface{}{}
+		applied, casErr = r.db.QueryWithContext(ctx, query,
+			message.Content,
+			message.EditedAt,
+			toGocqlUUID(message.ConversationID),
+			message.SentAt,
+			toGocqlUUID(message.MessageID),
+			toGocqlUUID(message.SenderID),
+		).MapScanCAS(current)
+		return casErr
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraWriteError(table, classifyError(err))
+		logger.Error("Failed to update message",
+			zap.String("conversation_id", message.ConversationID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+	if !applied {
+		// A missing row reports no current sender
+		if sender, _ := current["sender_id"].(gocql.UUID); sender == (gocql.UUID{}) {
+			metrics.RecordCassandraQuery(operation, table, "not_found")
+			return domain.ErrMessageNotFound
+		}
+		metrics.RecordCassandraQuery(operation, table, "not_applied")
+		if deletedAt, _ := current["deleted_at"].(time.Time); !deletedAt.IsZero() {
+			return domain.ErrMessageDeleted
+		}
+		return domain.ErrNotMessageSender
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return nil
+}
+
+// SoftDelete turns a message into a tombstone: its content and metadata are
+// cleared and deleted_at and deleted_by are set, while the row stays so
+// clients can show that a message was deleted. Authorization is up to the
+// caller. It returns domain.ErrMessageNotFound when there is no such message
+// and domain.ErrMessageDeleted when it was already deleted.
+func (r *MessageRepository) SoftDelete(ctx context.Context, conversationID, messageID, byUserID uuid.UUID) error {
+	// The row is keyed by sent_at, which the caller does not know
+	message, err := r.GetByID(ctx, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+	if message.IsDeleted() {
+		return domain.ErrMessageDeleted
+	}
+
+	startTime := time.Now()
+	operation := "soft_delete"
+	table := "messages"
+
+	// Conditional on the row read above, so a concurrent edit cannot
+	// resurrect the content and a missing row is not created
+	query := `UPDATE messages SET content = '', metadata = null, deleted_at = ?, deleted_by = ? WHERE conversation_id = ? AND sent_at = ? AND message_id = ? IF sender_id = ? AND deleted_at = null`
+
+	var applied bool
+	var current map[string]interface{}
+	err = r.executeWithRetry(ctx, operation, table, func() error {
+		var casErr error
+		current = map[string]interface{}{}
+		applied, casErr = r.db.QueryWithContext(ctx, query,
+			time.Now(),
+			toGocqlUUID(byUserID),
+			toGocqlUUID(conversationID),
+			message.SentAt,
+			toGocqlUUID(messageID),
+			toGocqlUUID(message.SenderID),
+		).MapScanCAS(current)
+		return casErr
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraWriteError(table, classifyError(err))
+		logger.Error("Failed to soft delete message",
+			zap.String("conversation_id", conversationID.String()),
+			zap.String("message_id", messageID.String()),
+			zap.Error(err))
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	if !applied {
+		metrics.RecordCassandraQuery(operation, table, "not_applied")
+		if sender, _ := current["sender_id"].(gocql.UUID); sender == (gocql.UUID{}) {
+			return domain.ErrMessageNotFound
+		}
+		return domain.ErrMessageDeleted
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return nil
+}
+
+// HideForUser hides a message from one user's view of the conversation
+// without changing it for anyone else
+func (r *MessageRepository) HideForUser(ctx context.Context, conversationID, messageID, userID uuid.UUID) error {
+	startTime := time.Now()
+	operation := "hide_for_user"
+	table := "hidden_messages"
+
+	query := `INSERT INTO hidden_messages (conversation_id, user_id, message_id, hidden_at) VALUES (?, ?, ?, ?)`
+
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		return r.db.ExecWithContext(ctx, query,
+			toGocqlUUID(conversationID),
+			toGocqlUUID(userID),
+			toGocqlUUID(messageID),
+			time.Now(),
+		)
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraWriteError(table, classifyError(err))
+		logger.Error("Failed to hide message",
+			zap.String("conversation_id", conversationID.String()),
+			zap.String("message_id", messageID.String()),
+			zap.Error(err))
+		return fmt.Errorf("failed to hide message: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return nil
+}
+
+// GetHiddenMessageIDs reports which of messageIDs the user has hidden
+func (r *MessageRepository) GetHiddenMessageIDs(ctx context.Context, conversationID, userID uuid.UUID, messageIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	hidden := make(map[uuid.UUID]bool)
+	if len(messageIDs) == 0 {
+		return hidden, nil
+	}
+
+	startTime := time.Now()
+	operation := "get_hidden"
+	table := "hidden_messages"
+
+	ids := make([]gocql.UUID, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = toGocqlUUID(id)
+	}
+	query := `SELECT message_id FROM hidden_messages WHERE conversation_id = ? AND user_id = ? AND message_id IN ?`
+
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		clear(hidden)
+		iter := r.db.QueryWithContext(ctx, query, toGocqlUUID(conversationID), toGocqlUUID(userID), ids).Iter()
+		var messageID uuid.UUID
+		for iter.Scan(&messageID) {
+			hidden[messageID] = true
+		}
+		return iter.Close()
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraReadError(table, classifyError(err))
+		return nil, fmt.Errorf("failed to get hidden messages: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return hidden, nil
+}
+
 // Delete removes a message (if needed for GDPR compliance)
 func (r *MessageRepository) Delete(ctx context.Context, conversationID uuid.UUID, bucket int, messageID uuid.UUID) error {
 	startTime := time.Now()
@@ -338,78 +693,118 @@ func (r *MessageRepository) CountMessages(ctx context.Context, conversationID uu
 	return count, nil
 }
 
+// CountOlderThan counts a conversation's messages sent before cutoff
+func (r *MessageRepository) CountOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) (int, error) {
+	startTime := time.Now()
+	operation := "count_older_than"
+	table := "messages"
+
+	query := `SELECT COUNT(*) FROM messages WHERE conversation_id = ? AND sent_at < ?`
+
+	var count int
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		return r.db.QueryWithContext(ctx, query, toGocqlUUID(conversationID), cutoff).Scan(&count)
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraReadError(table, classifyError(err))
+		return 0, fmt.Errorf("failed to count old messages: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return count, nil
+}
+
+// DeleteOlderThan removes a conversation's messages sent before cutoff. It is a
+// single range delete, so it writes one range tombstone rather than one per row.
+func (r *MessageRepository) DeleteOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) error {
+	startTime := time.Now()
+	operation := "delete_older_than"
+	table := "messages"
+
+	query := `DELETE FROM messages WHERE conversation_id = ? AND sent_at < ?`
+
+	err := r.executeWithRetry(ctx, operation, table, func() error {
+		return r.db.ExecWithContext(ctx, query, toGocqlUUID(conversationID), cutoff)
+	})
+
+	duration := time.Since(startTime).Seconds()
+	metrics.RecordCassandraQueryDuration(operation, table, duration)
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraWriteError(table, classifyError(err))
+		logger.Error("Failed to purge old messages",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Time("cutoff", cutoff),
+			zap.Error(err))
+		return fmt.Errorf("failed to delete old messages: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return nil
+}
+
+// retryPolicy is the backoff between attempts of one query
+var retryPolicy = backoff.Config{
+	Base:        RetryDelay,
+	Factor:      RetryBackoff,
+	MaxAttempts: MaxRetries + 1,
+}
+
 // executeWithRetry executes a function with retry logic that respects context cancellation
 // It will retry on transient errors but abort immediately on context cancellation or timeout
 func (r *MessageRepository) executeWithRetry(ctx context.Context, operation, table string, fn func() error) error {
-	var lastErr error
-	delay := RetryDelay
-
-	for attempt := 0; attempt <= MaxRetries; attempt++ {
-		// Check if context is cancelled before attempting
-		select {
-		case <-ctx.Done():
-			// Context cancelled, return immediately
-			metrics.RecordCassandraQueryTimeout(operation, table)
-			logger.Warn("Cassandra query cancelled by context",
-				zap.String("operation", operation),
-				zap.String("table", table),
-				zap.Int("attempt", attempt))
-			return domain.ErrCassandraTimeout
-		default:
-			// Context not cancelled, proceed with attempt
-		}
-
-		// Execute the function
+	err := backoff.RetryNotify(ctx, retryPolicy, func(ctx context.Context) error {
 		err := fn()
 		if err == nil {
-			// Success, no retry needed
 			return nil
 		}
-
-		lastErr = err
-
-		// Check if error is retryable
 		if !isRetryableError(err) {
-			// Non-retryable error, return immediately
-			return err
+			return backoff.Permanent(err)
 		}
 
-		// Check if this was the last attempt
-		if attempt == MaxRetries {
-			metrics.RecordCassandraQueryRetryExhausted(operation, table)
-			logger.Error("Cassandra query retries exhausted",
-				zap.String("operation", operation),
-				zap.String("table", table),
-				zap.Int("attempts", attempt),
-				zap.Error(err))
-			return fmt.Errorf("max retries (%d) exhausted: %w", MaxRetries, err)
+		// The session lost all its hosts; rebuild it so the next attempt has a chance to succeed
+		if database.IsNoHostsError(err) {
+			if rerr := r.db.Reconnect(); rerr != nil && !errors.Is(rerr, database.ErrCassandraReconnectThrottled) {
+				logger.Warn("Cassandra session rebuild failed",
+					zap.String("operation", operation),
+					zap.String("table", table),
+					zap.Error(rerr))
+			}
 		}
-
-		// Record retry metric
+		return err
+	}, func(err error, retry int, delay time.Duration) {
 		metrics.RecordCassandraQueryRetry(operation, table, classifyError(err))
-
-		// Wait before retrying with exponential backoff
 		logger.Debug("Retrying Cassandra query",
 			zap.String("operation", operation),
 			zap.String("table", table),
-			zap.Int("attempt", attempt),
+			zap.Int("attempt", retry),
 			zap.Duration("delay", delay),
 			zap.Error(err))
+	})
 
-		select {
-		case <-ctx.Done():
-			// Context cancelled during backoff
-			metrics.RecordCassandraQueryTimeout(operation, table)
-			return domain.ErrCassandraTimeout
-		case <-time.After(delay):
-			// Backoff completed, proceed with next attempt
-		}
-
-		// Exponential backoff
-		delay *= time.Duration(RetryBackoff)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		metrics.RecordCassandraQueryTimeout(operation, table)
+		logger.Warn("Cassandra query cancelled by context",
+			zap.String("operation", operation),
+			zap.String("table", table),
+			zap.Error(err))
+		return domain.ErrCassandraTimeout
+	case errors.Is(err, backoff.ErrExhausted):
+		metrics.RecordCassandraQueryRetryExhausted(operation, table)
+		logger.Error("Cassandra query retries exhausted",
+			zap.String("operation", operation),
+			zap.String("table", table),
+			zap.Int("attempts", retryPolicy.MaxAttempts),
+			zap.Error(err))
 	}
-
-	return lastErr
+	return err
 }
 
 // isRetryableError checks if an error is retryable
@@ -434,7 +829,7 @@ func isRetryableError(err error) bool {
 	}
 
 	// Host unavailable errors may be retryable
-	if errStr == "no hosts available" || errStr == "unavailable" {
+	if database.IsNoHostsError(err) || errStr == "unavailable" {
 		return true
 	}
 
@@ -461,6 +856,10 @@ func classifyError(err error) string {
 		return domainErr.Code
 	}
 
+	if database.IsNoHostsError(err) {
+		return "unavailable"
+	}
+
 	// Check for gocql errors by string matching
 	errStr := err.Error()
 
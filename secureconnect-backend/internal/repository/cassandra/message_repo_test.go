@@ -0,0 +1,62 @@
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/pkg/logger"
+)
+
+func newTestRepository(t *testing.T, factoryCalls *int) *MessageRepository {
+	t.Helper()
+	logger.InitDefault("test")
+
+	db, err := database.NewCassandraDBWithFactory(func() (*gocql.Session, error) {
+		*factoryCalls++
+		return nil, nil
+	})
+	require.NoError(t, err)
+	return NewMessageRepository(db)
+}
+
+func TestExecuteWithRetry_ReconnectsOnNoHostsAvailable(t *testing.T) {
+	factoryCalls := 0
+	repo := newTestRepository(t, &factoryCalls)
+
+	attempts := 0
+	err := repo.executeWithRetry(context.Background(), "save", "messages", func() error {
+		attempts++
+		if attempts == 1 {
+			return gocql.ErrNoConnections
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2, factoryCalls, "session should be rebuilt once after the initial connect")
+	assert.True(t, repo.db.IsHealthy())
+}
+
+func TestExecuteWithRetry_NoReconnectOnOtherErrors(t *testing.T) {
+	factoryCalls := 0
+	repo := newTestRepository(t, &factoryCalls)
+
+	attempts := 0
+	err := repo.executeWithRetry(context.Background(), "save", "messages", func() error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("write failure")
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, factoryCalls)
+}
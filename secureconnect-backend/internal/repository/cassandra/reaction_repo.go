@@ -0,0 +1,119 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// ReactionRepository stores emoji reactions in Cassandra, one partition per
+// message
+type ReactionRepository struct {
+	db       *database.CassandraDB
+	messages *MessageRepository // for its retry handling
+}
+
+// NewReactionRepository creates a new ReactionRepository
+func NewReactionRepository(db *database.CassandraDB) *ReactionRepository {
+	return &ReactionRepository{db: db, messages: NewMessageRepository(db)}
+}
+
+// AddReaction records a user reacting to a message with an emoji. Adding
+// the same reaction twice keeps one.
+func (r *ReactionRepository) AddReaction(ctx context.Context, reaction *domain.Reaction) error {
+	query := `INSERT INTO message_reactions_by_message (conversation_id, message_id, emoji, user_id, reacted_at) VALUES (?, ?, ?, ?, ?)`
+	return r.exec(ctx, "add_reaction", reaction, query,
+		toGocqlUUID(reaction.ConversationID),
+		toGocqlUUID(reaction.MessageID),
+		reaction.Emoji,
+		toGocqlUUID(reaction.UserID),
+		time.Now(),
+	)
+}
+
+// RemoveReaction deletes one of a user's reactions to a message. Removing a
+// reaction that does not exist succeeds.
+func (r *ReactionRepository) RemoveReaction(ctx context.Context, reaction *domain.Reaction) error {
+	query := `DELETE FROM message_reactions_by_message WHERE conversation_id = ? AND message_id = ? AND emoji = ? AND user_id = ?`
+	return r.exec(ctx, "remove_reaction", reaction, query,
+		toGocqlUUID(reaction.ConversationID),
+		toGocqlUUID(reaction.MessageID),
+		reaction.Emoji,
+		toGocqlUUID(reaction.UserID),
+	)
+}
+
+// exec runs a reaction write with the message repository's retries
+func (r *ReactionRepository) exec(ctx context.Context, operation string, reaction *domain.Reaction, query string, args ...interface{}) error {
+	startTime := time.Now()
+	table := "message_reactions_by_message"
+
+	err := r.messages.executeWithRetry(ctx, operation, table, func() error {
+		return r.db.ExecWithContext(ctx, query, args...)
+	})
+
+	metrics.RecordCassandraQueryDuration(operation, table, time.Since(startTime).Seconds())
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraWriteError(table, classifyError(err))
+		logger.Error("Failed to write reaction",
+			zap.String("operation", operation),
+			zap.String("conversation_id", reaction.ConversationID.String()),
+			zap.String("message_id", reaction.MessageID.String()),
+			zap.Error(err))
+		return fmt.Errorf("failed to write reaction: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return nil
+}
+
+// GetReactions returns the reactions to the given messages of a conversation
+func (r *ReactionRepository) GetReactions(ctx context.Context, conversationID uuid.UUID, messageIDs []uuid.UUID) ([]*domain.Reaction, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	startTime := time.Now()
+	operation := "get_reactions"
+	table := "message_reactions_by_message"
+
+	ids := make([]gocql.UUID, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = toGocqlUUID(id)
+	}
+	query := `SELECT message_id, emoji, user_id FROM message_reactions_by_message WHERE conversation_id = ? AND message_id IN ?`
+
+	var reactions []*domain.Reaction
+	err := r.messages.executeWithRetry(ctx, operation, table, func() error {
+		reactions = nil
+		iter := r.db.QueryWithContext(ctx, query, toGocqlUUID(conversationID), ids).Iter()
+		for {
+			reaction := &domain.Reaction{ConversationID: conversationID}
+			if !iter.Scan(&reaction.MessageID, &reaction.Emoji, &reaction.UserID) {
+				break
+			}
+			reactions = append(reactions, reaction)
+		}
+		return iter.Close()
+	})
+
+	metrics.RecordCassandraQueryDuration(operation, table, time.Since(startTime).Seconds())
+	if err != nil {
+		metrics.RecordCassandraQueryError(operation, table, classifyError(err))
+		metrics.RecordCassandraReadError(table, classifyError(err))
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	metrics.RecordCassandraQuery(operation, table, "success")
+	return reactions, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/pagination"
 )
 
 // AdminRepository handles administrative data operations
@@ -100,6 +101,19 @@ func (r *AdminRepository) GetUsers(ctx context.Context, req *domain.UserListRequ
 		argCount++
 	}
 
+	// Keyset pagination: only rows older than the cursor, newest first
+	var cursor *pagination.Cursor
+	if req.Cursor != "" {
+		decoded, err := pagination.DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = decoded
+		query += fmt.Sprintf(" AND (u.created_at, u.user_id) < ($%d, $%d)", argCount, argCount+1)
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		argCount += 2
+	}
+
 	// Add sorting with whitelist validation
 	validSortColumns := map[string]bool{
 		"created_at":    true,
@@ -122,11 +136,19 @@ func (r *AdminRepository) GetUsers(ctx context.Context, req *domain.UserListRequ
 	if req.SortOrder == "ASC" {
 		sortOrder = "ASC"
 	}
-	query += fmt.Sprintf(" ORDER BY u.%s %s", sortBy, sortOrder)
+	if cursor != nil {
+		sortBy, sortOrder = "created_at", "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY u.%s %s, u.user_id %s", sortBy, sortOrder, sortOrder)
 
-	// Add pagination
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, req.Limit, req.Offset)
+	// Add pagination (one extra row in cursor mode to detect a following page)
+	if cursor != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, req.Limit+1)
+	} else {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
+		args = append(args, req.Limit, req.Offset)
+	}
 
 	// Execute query
 	rows, err := r.db.Query(ctx, query, args...)
@@ -187,13 +209,27 @@ func (r *AdminRepository) GetUsers(ctx context.Context, req *domain.UserListRequ
 		return nil, fmt.Errorf("failed to count users: %w", err)
 	}
 
-	hasMore := (req.Offset + len(users)) < totalCount
+	var hasMore bool
+	if cursor != nil {
+		hasMore = len(users) > req.Limit
+		if hasMore {
+			users = users[:req.Limit]
+		}
+	} else {
+		hasMore = (req.Offset + len(users)) < totalCount
+	}
 
-	return &domain.UserListResponse{
+	result := &domain.UserListResponse{
 		Users:      users,
 		TotalCount: totalCount,
 		HasMore:    hasMore,
-	}, nil
+	}
+	// Cursors are only meaningful in the default newest-first order
+	if hasMore && len(users) > 0 && sortBy == "created_at" && sortOrder == "DESC" {
+		last := users[len(users)-1]
+		result.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.UserID)
+	}
+	return result, nil
 }
 
 // BanUser bans a user
@@ -282,6 +318,18 @@ func (r *AdminRepository) UnbanUser(ctx context.Context, req *domain.UnbanUserRe
 	return tx.Commit(ctx)
 }
 
+// LogAction records an admin action in the audit log
+func (r *AdminRepository) LogAction(ctx context.Context, adminID uuid.UUID, action, targetType string, targetID uuid.UUID, ip, userAgent, details string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO audit_logs (admin_id, action, target_type, target_id, ip_address, user_agent, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+	`, adminID, action, targetType, targetID, ip, userAgent, details)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
 // GetAuditLogs retrieves audit logs
 func (r *AdminRepository) GetAuditLogs(ctx context.Context, req *domain.AuditLogRequest) ([]domain.AuditLog, int, error) {
 	// Build query
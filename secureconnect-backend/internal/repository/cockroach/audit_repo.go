@@ -0,0 +1,238 @@
+package cockroach
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"secureconnect-backend/pkg/audit"
+	"secureconnect-backend/pkg/pagination"
+)
+
+// AuditRepository stores audit events in CockroachDB. It implements
+// audit.Store and audit.Searcher, with filtering and paging done in SQL.
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+const auditEventColumns = `event_id, user_id, event_type, resource, action, ip_address, user_agent,
+	success, error_code, details, geo, timestamp, sequence, prev_hash, hash, signature`
+
+// Save inserts an audit event, or updates its hash chain fields when it was
+// saved before
+func (r *AuditRepository) Save(ctx context.Context, event *audit.AuditEvent) error {
+	var geo []byte
+	if event.Geo != nil {
+		var err error
+		if geo, err = json.Marshal(event.Geo); err != nil {
+			return fmt.Errorf("failed to marshal audit geo: %w", err)
+		}
+	}
+
+	query := `INSERT INTO audit_events (` + auditEventColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (event_id) DO UPDATE SET
+			sequence = excluded.sequence,
+			prev_hash = excluded.prev_hash,
+			hash = excluded.hash,
+			signature = excluded.signature`
+
+	_, err := r.pool.Exec(ctx, query,
+		event.EventID,
+		event.UserID,
+		string(event.EventType),
+		event.Resource,
+		event.Action,
+		event.IPAddress,
+		event.UserAgent,
+		event.Success,
+		event.ErrorCode,
+		event.Details,
+		geo,
+		event.Timestamp,
+		event.Sequence,
+		event.PrevHash,
+		event.Hash,
+		event.Signature,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store audit event: %w", err)
+	}
+	return nil
+}
+
+// auditEventFilter builds the WHERE clause for query's filters, numbering
+// placeholders from 1
+func auditEventFilter(query audit.EventQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if query.UserID != uuid.Nil {
+		where("user_id = $%d", query.UserID)
+	}
+	if query.EventType != "" {
+		where("event_type = $%d", string(query.EventType))
+	}
+	if query.Success != nil {
+		where("success = $%d", *query.Success)
+	}
+	if query.IPAddress != "" {
+		where("ip_address = $%d", query.IPAddress)
+	}
+	if !query.From.IsZero() {
+		where("timestamp >= $%d", query.From)
+	}
+	if !query.To.IsZero() {
+		where("timestamp <= $%d", query.To)
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Find returns a page of events matching query, newest first, along with
+// the number of matches
+func (r *AuditRepository) Find(ctx context.Context, query audit.EventQuery) (*audit.EventPage, error) {
+	filter, args := auditEventFilter(query)
+
+	page := &audit.EventPage{Events: []*audit.AuditEvent{}}
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_events`+filter, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	// A NULL limit leaves the page open
+	var limit *int
+	if query.Limit > 0 {
+		limit = &query.Limit
+	}
+	args = append(args, limit, query.Offset)
+	sql := fmt.Sprintf(`SELECT %s FROM audit_events%s ORDER BY timestamp DESC LIMIT $%d OFFSET $%d`,
+		auditEventColumns, filter, len(args)-1, len(args))
+
+	err := r.queryEvents(ctx, sql, args, func(event *audit.AuditEvent) error {
+		page.Events = append(page.Events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Search returns up to page.Limit events matching filter that are older
+// than page.Cursor, newest first. Keyset pagination over (timestamp,
+// event_id) keeps pages stable while events are added, and each page reads
+// only its own rows through the timestamp indexes.
+func (r *AuditRepository) Search(ctx context.Context, filter audit.EventQuery, page audit.PageRequest) (*audit.SearchPage, error) {
+	where, args := auditEventFilter(filter)
+	if page.Cursor != "" {
+		cursor, err := pagination.DecodeCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		keyset := fmt.Sprintf("(timestamp, event_id) < ($%d, $%d)", len(args)-1, len(args))
+		if where == "" {
+			where = " WHERE " + keyset
+		} else {
+			where += " AND " + keyset
+		}
+	}
+
+	// One extra row tells whether another page follows
+	var limit *int
+	if page.Limit > 0 {
+		extra := page.Limit + 1
+		limit = &extra
+	}
+	args = append(args, limit)
+	sql := fmt.Sprintf(`SELECT %s FROM audit_events%s ORDER BY timestamp DESC, event_id DESC LIMIT $%d`,
+		auditEventColumns, where, len(args))
+
+	result := &audit.SearchPage{Events: []*audit.AuditEvent{}}
+	err := r.queryEvents(ctx, sql, args, func(event *audit.AuditEvent) error {
+		result.Events = append(result.Events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Limit > 0 && len(result.Events) > page.Limit {
+		result.Events = result.Events[:page.Limit]
+		result.NextCursor = audit.EncodeEventCursor(result.Events[page.Limit-1])
+	}
+	return result, nil
+}
+
+// Export calls fn for every event matching filter, newest first, as rows
+// are read, so large result sets are never held in memory
+func (r *AuditRepository) Export(ctx context.Context, filter audit.EventQuery, fn func(*audit.AuditEvent) error) error {
+	where, args := auditEventFilter(filter)
+	sql := fmt.Sprintf(`SELECT %s FROM audit_events%s ORDER BY timestamp DESC, event_id DESC`,
+		auditEventColumns, where)
+	return r.queryEvents(ctx, sql, args, fn)
+}
+
+// queryEvents runs sql and passes each audit event row to fn, stopping at
+// the first error
+func (r *AuditRepository) queryEvents(ctx context.Context, sql string, args []interface{}, fn func(*audit.AuditEvent) error) error {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event := &audit.AuditEvent{}
+		var eventType string
+		var geo []byte
+		err := rows.Scan(
+			&event.EventID,
+			&event.UserID,
+			&eventType,
+			&event.Resource,
+			&event.Action,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.Success,
+			&event.ErrorCode,
+			&event.Details,
+			&geo,
+			&event.Timestamp,
+			&event.Sequence,
+			&event.PrevHash,
+			&event.Hash,
+			&event.Signature,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		event.EventType = audit.AuditEventType(eventType)
+		if len(geo) > 0 {
+			if err := json.Unmarshal(geo, &event.Geo); err != nil {
+				return fmt.Errorf("failed to decode audit geo: %w", err)
+			}
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit events: %w", err)
+	}
+	return nil
+}
@@ -35,8 +35,7 @@ func (r *BlockedUserRepository) BlockUser(ctx context.Context, blockerID, blocke
 		INSERT INTO blocked_users (blocker_id, blocked_id, reason, created_at)
 		VALUES ($1, $2, $3, NOW())
 		ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET
-			reason = EXCLUDED.reason,
-			created_at = NOW()
+			reason = EXCLUDED.reason
 	`
 
 	_, err := r.pool.Exec(ctx, query, blockerID, blockedID, reason)
@@ -47,6 +46,42 @@ func (r *BlockedUserRepository) BlockUser(ctx context.Context, blockerID, blocke
 	return nil
 }
 
+// BlockUserAndEndFriendship blocks blockedID and, in the same transaction,
+// deletes any friendship or friend request between the two users. It returns
+// the status of the deleted friendship, or "" when there was none.
+func (r *BlockedUserRepository) BlockUserAndEndFriendship(ctx context.Context, blockerID, blockedID uuid.UUID, reason *string) (string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var endedStatus string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM friendships
+		WHERE ((user_id_1 = $1 AND user_id_2 = $2) OR (user_id_1 = $2 AND user_id_2 = $1))
+		RETURNING status
+	`, blockerID, blockedID).Scan(&endedStatus)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", fmt.Errorf("failed to delete friendship: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO blocked_users (blocker_id, blocked_id, reason, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET
+			reason = EXCLUDED.reason
+	`, blockerID, blockedID, reason)
+	if err != nil {
+		return "", fmt.Errorf("failed to block user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return endedStatus, nil
+}
+
 // UnblockUser unblocks a user
 func (r *BlockedUserRepository) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
 	query := `
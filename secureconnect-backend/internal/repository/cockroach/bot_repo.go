@@ -0,0 +1,130 @@
+package cockroach
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// BotRepository handles conversation bot data operations in CockroachDB
+type BotRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewBotRepository creates a new BotRepository
+func NewBotRepository(pool *pgxpool.Pool) *BotRepository {
+	return &BotRepository{pool: pool}
+}
+
+// CreateBot stores a bot unless its conversation already has maxBots, in
+// which case domain.ErrBotLimitReached is returned
+func (r *BotRepository) CreateBot(ctx context.Context, bot *domain.Bot, maxBots int) error {
+	// The count and insert run as one statement, so concurrent registrations
+	// cannot exceed the cap under serializable isolation
+	query := `
+		INSERT INTO conversation_bots (bot_id, conversation_id, webhook_url, secret, token_hash, created_by, created_at)
+		SELECT $1, $2, $3, $4, $5, $6, NOW()
+		WHERE (SELECT count(*) FROM conversation_bots WHERE conversation_id = $2) < $7
+		RETURNING created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		bot.BotID,
+		bot.ConversationID,
+		bot.WebhookURL,
+		bot.Secret,
+		bot.TokenHash,
+		bot.CreatedBy,
+		maxBots,
+	).Scan(&bot.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.ErrBotLimitReached
+		}
+		return fmt.Errorf("failed to create bot: %w", err)
+	}
+	return nil
+}
+
+// GetBotsByConversation lists a conversation's bots, oldest first
+func (r *BotRepository) GetBotsByConversation(ctx context.Context, conversationID uuid.UUID) ([]*domain.Bot, error) {
+	query := `
+		SELECT bot_id, conversation_id, webhook_url, secret, token_hash, created_by, created_at
+		FROM conversation_bots
+		WHERE conversation_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bots: %w", err)
+	}
+	defer rows.Close()
+
+	var bots []*domain.Bot
+	for rows.Next() {
+		bot, err := scanBot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bot: %w", err)
+		}
+		bots = append(bots, bot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate bots: %w", err)
+	}
+	return bots, nil
+}
+
+// GetBotByTokenHash finds the bot owning a token
+func (r *BotRepository) GetBotByTokenHash(ctx context.Context, tokenHash string) (*domain.Bot, error) {
+	query := `
+		SELECT bot_id, conversation_id, webhook_url, secret, token_hash, created_by, created_at
+		FROM conversation_bots
+		WHERE token_hash = $1
+	`
+
+	bot, err := scanBot(r.pool.QueryRow(ctx, query, tokenHash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrBotNotFound
+		}
+		return nil, fmt.Errorf("failed to get bot: %w", err)
+	}
+	return bot, nil
+}
+
+// DeleteBot removes a bot from a conversation
+func (r *BotRepository) DeleteBot(ctx context.Context, conversationID, botID uuid.UUID) error {
+	query := `DELETE FROM conversation_bots WHERE conversation_id = $1 AND bot_id = $2`
+
+	cmdTag, err := r.pool.Exec(ctx, query, conversationID, botID)
+	if err != nil {
+		return fmt.Errorf("failed to delete bot: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return domain.ErrBotNotFound
+	}
+	return nil
+}
+
+func scanBot(row pgx.Row) (*domain.Bot, error) {
+	bot := &domain.Bot{}
+	err := row.Scan(
+		&bot.BotID,
+		&bot.ConversationID,
+		&bot.WebhookURL,
+		&bot.Secret,
+		&bot.TokenHash,
+		&bot.CreatedBy,
+		&bot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
@@ -38,12 +38,28 @@ func (t *Transaction) Rollback(ctx context.Context) error {
 
 // ConversationRepository handles conversation operations
 type ConversationRepository struct {
-	pool *pgxpool.Pool
+	pool        *pgxpool.Pool
+	defaultE2EE bool
+	maxPerUser  int // 0 means unlimited
 }
 
 // NewConversationRepository creates a new conversation repository
 func NewConversationRepository(pool *pgxpool.Pool) *ConversationRepository {
-	return &ConversationRepository{pool: pool}
+	return &ConversationRepository{pool: pool, defaultE2EE: true}
+}
+
+// SetDefaultE2EE sets whether a conversation without a settings row is
+// reported as end-to-end encrypted. It should match the E2EE policy's
+// default, which is on unless configured otherwise.
+func (r *ConversationRepository) SetDefaultE2EE(enabled bool) {
+	r.defaultE2EE = enabled
+}
+
+// SetConversationLimit caps how many conversations a user can belong to.
+// Participants are only inserted while under the cap, in the same statement
+// that counts them. A limit of 0 or less disables the cap.
+func (r *ConversationRepository) SetConversationLimit(maxPerUser int) {
+	r.maxPerUser = maxPerUser
 }
 
 // Create creates a new conversation
@@ -96,6 +112,48 @@ func (r *ConversationRepository) CreateTx(ctx context.Context, tx *Transaction,
 	return nil
 }
 
+// CreateWithParticipants stores a new conversation with its participants and
+// settings in one transaction, so a failure leaves nothing behind. It returns
+// a *domain.ConversationLimitError when participants are at the conversation
+// limit.
+func (r *ConversationRepository) CreateWithParticipants(ctx context.Context, conversation *domain.Conversation, participants []*domain.ConversationParticipant, settings *domain.ConversationSettings) (err error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		}
+	}()
+
+	if err = r.CreateTx(ctx, tx, conversation); err != nil {
+		return err
+	}
+	var full []uuid.UUID
+	for _, participant := range participants {
+		var added bool
+		if added, err = r.addParticipantUnderLimitTx(ctx, tx, conversation.ConversationID, participant.UserID, participant.Role); err != nil {
+			return err
+		}
+		if !added {
+			full = append(full, participant.UserID)
+		}
+	}
+	if len(full) > 0 {
+		err = &domain.ConversationLimitError{Limit: r.maxPerUser, UserIDs: full}
+		return err
+	}
+	if err = r.UpdateSettingsTx(ctx, tx, conversation.ConversationID, settings); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 // AddParticipant adds a user to conversation
 func (r *ConversationRepository) AddParticipant(ctx context.Context, conversationID, userID uuid.UUID, role string) error {
 	query := `
@@ -128,6 +186,65 @@ func (r *ConversationRepository) AddParticipantTx(ctx context.Context, tx *Trans
 	return nil
 }
 
+// AddParticipants adds users to a conversation in one transaction. Nobody is
+// added if any of them is at the conversation limit; the returned
+// *domain.ConversationLimitError names them.
+func (r *ConversationRepository) AddParticipants(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, role string) (err error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		}
+	}()
+
+	var full []uuid.UUID
+	for _, userID := range userIDs {
+		var added bool
+		if added, err = r.addParticipantUnderLimitTx(ctx, tx, conversationID, userID, role); err != nil {
+			return err
+		}
+		if !added {
+			full = append(full, userID)
+		}
+	}
+	if len(full) > 0 {
+		err = &domain.ConversationLimitError{Limit: r.maxPerUser, UserIDs: full}
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// addParticipantUnderLimitTx adds a user to a conversation unless they are
+// already in maxPerUser conversations, reporting whether they were added.
+// Transactions are serializable, so two concurrent inserts for the same user
+// cannot both see a count under the limit; one of them is retried or fails.
+func (r *ConversationRepository) addParticipantUnderLimitTx(ctx context.Context, tx *Transaction, conversationID, userID uuid.UUID, role string) (bool, error) {
+	if r.maxPerUser <= 0 {
+		return true, r.AddParticipantTx(ctx, tx, conversationID, userID, role)
+	}
+
+	query := `
+		INSERT INTO conversation_participants (
+			conversation_id, user_id, role, joined_at
+		)
+		SELECT $1, $2, $3, $4
+		WHERE (SELECT count(*) FROM conversation_participants WHERE user_id = $2) < $5
+	`
+
+	tag, err := tx.tx.Exec(ctx, query, conversationID, userID, role, time.Now(), r.maxPerUser)
+	if err != nil {
+		return false, fmt.Errorf("failed to add participant: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
 // GetByID retrieves a conversation by ID
 func (r *ConversationRepository) GetByID(ctx context.Context, conversationID uuid.UUID) (*domain.Conversation, error) {
 	query := `
@@ -231,17 +348,17 @@ func (r *ConversationRepository) UpdateSettings(ctx context.Context, conversatio
 		// Update
 		query := `
 			UPDATE conversation_settings
-			SET is_e2ee_enabled = $2, updated_at = $3
+			SET is_e2ee_enabled = $2, updated_at = $3, e2ee_disabled_by = $4, e2ee_disabled_at = $5, search_indexing = $6
 			WHERE conversation_id = $1
 		`
-		_, err = r.pool.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now())
+		_, err = r.pool.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now(), settings.E2EEDisabledBy, settings.E2EEDisabledAt, settings.SearchIndexing)
 	} else {
 		// Insert
 		query := `
-			INSERT INTO conversation_settings (conversation_id, is_e2ee_enabled, updated_at)
-			VALUES ($1, $2, $3)
+			INSERT INTO conversation_settings (conversation_id, is_e2ee_enabled, updated_at, e2ee_disabled_by, e2ee_disabled_at, search_indexing)
+			VALUES ($1, $2, $3, $4, $5, $6)
 		`
-		_, err = r.pool.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now())
+		_, err = r.pool.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now(), settings.E2EEDisabledBy, settings.E2EEDisabledAt, settings.SearchIndexing)
 	}
 
 	if err != nil {
@@ -265,17 +382,17 @@ func (r *ConversationRepository) UpdateSettingsTx(ctx context.Context, tx *Trans
 		// Update
 		query := `
 			UPDATE conversation_settings
-			SET is_e2ee_enabled = $2, updated_at = $3
+			SET is_e2ee_enabled = $2, updated_at = $3, e2ee_disabled_by = $4, e2ee_disabled_at = $5, search_indexing = $6
 			WHERE conversation_id = $1
 		`
-		_, err = tx.tx.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now())
+		_, err = tx.tx.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now(), settings.E2EEDisabledBy, settings.E2EEDisabledAt, settings.SearchIndexing)
 	} else {
 		// Insert
 		query := `
-			INSERT INTO conversation_settings (conversation_id, is_e2ee_enabled, updated_at)
-			VALUES ($1, $2, $3)
+			INSERT INTO conversation_settings (conversation_id, is_e2ee_enabled, updated_at, e2ee_disabled_by, e2ee_disabled_at, search_indexing)
+			VALUES ($1, $2, $3, $4, $5, $6)
 		`
-		_, err = tx.tx.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now())
+		_, err = tx.tx.Exec(ctx, query, conversationID, settings.IsE2EEEnabled, time.Now(), settings.E2EEDisabledBy, settings.E2EEDisabledAt, settings.SearchIndexing)
 	}
 
 	if err != nil {
@@ -288,7 +405,7 @@ func (r *ConversationRepository) UpdateSettingsTx(ctx context.Context, tx *Trans
 // GetSettings retrieves conversation settings
 func (r *ConversationRepository) GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error) {
 	query := `
-		SELECT conversation_id, is_e2ee_enabled
+		SELECT conversation_id, is_e2ee_enabled, e2ee_disabled_by, e2ee_disabled_at, search_indexing
 		FROM conversation_settings
 		WHERE conversation_id = $1
 	`
@@ -297,6 +414,9 @@ func (r *ConversationRepository) GetSettings(ctx context.Context, conversationID
 	err := r.pool.QueryRow(ctx, query, conversationID).Scan(
 		&settings.ConversationID,
 		&settings.IsE2EEEnabled,
+		&settings.E2EEDisabledBy,
+		&settings.E2EEDisabledAt,
+		&settings.SearchIndexing,
 	)
 
 	if err != nil {
@@ -304,7 +424,7 @@ func (r *ConversationRepository) GetSettings(ctx context.Context, conversationID
 			// Default settings if not found
 			return &domain.ConversationSettings{
 				ConversationID: conversationID,
-				IsE2EEEnabled:  true, // Default to E2EE enabled
+				IsE2EEEnabled:  r.defaultE2EE,
 			}, nil
 		}
 		return nil, fmt.Errorf("failed to get settings: %w", err)
@@ -338,11 +458,99 @@ func (r *ConversationRepository) IsParticipant(ctx context.Context, conversation
 	return exists, nil
 }
 
+// GetParticipant retrieves a single participant's membership, including role
+// and mute. It returns domain.ErrNotParticipant when the user is not a member.
+func (r *ConversationRepository) GetParticipant(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ConversationParticipant, error) {
+	query := `
+		SELECT conversation_id, user_id, role, joined_at, muted_until, calls_muted
+		FROM conversation_participants
+		WHERE conversation_id = $1 AND user_id = $2
+	`
+
+	participant := &domain.ConversationParticipant{}
+	err := r.pool.QueryRow(ctx, query, conversationID, userID).Scan(
+		&participant.ConversationID,
+		&participant.UserID,
+		&participant.Role,
+		&participant.JoinedAt,
+		&participant.MutedUntil,
+		&participant.CallsMuted,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrNotParticipant
+		}
+		return nil, fmt.Errorf("failed to get participant: %w", err)
+	}
+
+	return participant, nil
+}
+
+// SetParticipantMute mutes a participant until the given time, or unmutes them when until is nil
+func (r *ConversationRepository) SetParticipantMute(ctx context.Context, conversationID, userID uuid.UUID, until *time.Time) error {
+	query := `UPDATE conversation_participants SET muted_until = $3 WHERE conversation_id = $1 AND user_id = $2`
+
+	cmdTag, err := r.pool.Exec(ctx, query, conversationID, userID, until)
+	if err != nil {
+		return fmt.Errorf("failed to update participant mute: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return domain.ErrNotParticipant
+	}
+
+	return nil
+}
+
+// SetCallsMuted sets whether group calls in the conversation ring the participant
+func (r *ConversationRepository) SetCallsMuted(ctx context.Context, conversationID, userID uuid.UUID, muted bool) error {
+	query := `UPDATE conversation_participants SET calls_muted = $3 WHERE conversation_id = $1 AND user_id = $2`
+
+	cmdTag, err := r.pool.Exec(ctx, query, conversationID, userID, muted)
+	if err != nil {
+		return fmt.Errorf("failed to update participant call mute: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return domain.ErrNotParticipant
+	}
+
+	return nil
+}
+
+// GetCallMutedUserIDs returns the participants who muted calls in a group
+// conversation. Direct conversations always ring, so none are returned for them.
+func (r *ConversationRepository) GetCallMutedUserIDs(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT cp.user_id
+		FROM conversation_participants cp
+		INNER JOIN conversations c ON c.conversation_id = cp.conversation_id
+		WHERE cp.conversation_id = $1 AND cp.calls_muted AND c.type = 'group'
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get call-muted participants: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan participant: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
 // GetParticipantsWithDetails retrieves all participants in a conversation with user details
 func (r *ConversationRepository) GetParticipantsWithDetails(ctx context.Context, conversationID uuid.UUID) ([]*domain.ConversationParticipantDetail, error) {
 	query := `
 		SELECT
-			cp.conversation_id, cp.user_id, cp.role, cp.joined_at,
+			cp.conversation_id, cp.user_id, cp.role, cp.joined_at, cp.muted_until,
 			u.email, u.username, u.display_name, u.avatar_url, u.status
 		FROM conversation_participants cp
 		INNER JOIN users u ON cp.user_id = u.user_id
@@ -364,6 +572,7 @@ func (r *ConversationRepository) GetParticipantsWithDetails(ctx context.Context,
 			&participant.UserID,
 			&participant.Role,
 			&participant.JoinedAt,
+			&participant.MutedUntil,
 			&participant.Email,
 			&participant.Username,
 			&participant.DisplayName,
@@ -421,3 +630,33 @@ func (r *ConversationRepository) UpdateConversation(ctx context.Context, convers
 func (r *ConversationRepository) IsUserInConversation(ctx context.Context, conversationID, userID uuid.UUID) (bool, error) {
 	return r.IsParticipant(ctx, conversationID, userID)
 }
+
+// ListMessageRetention pages through conversations ordered by ID, starting after
+// the given ID, with each one's message retention setting
+func (r *ConversationRepository) ListMessageRetention(ctx context.Context, after uuid.UUID, limit int) ([]domain.ConversationRetention, error) {
+	query := `
+		SELECT c.conversation_id, COALESCE(s.message_retention_days, 0)
+		FROM conversations c
+		LEFT JOIN conversation_settings s ON s.conversation_id = c.conversation_id
+		WHERE c.conversation_id > $1
+		ORDER BY c.conversation_id
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list message retention: %w", err)
+	}
+	defer rows.Close()
+
+	var retention []domain.ConversationRetention
+	for rows.Next() {
+		var item domain.ConversationRetention
+		if err := rows.Scan(&item.ConversationID, &item.RetentionDays); err != nil {
+			return nil, fmt.Errorf("failed to scan message retention: %w", err)
+		}
+		retention = append(retention, item)
+	}
+
+	return retention, rows.Err()
+}
@@ -99,30 +99,30 @@ func (r *EmailVerificationRepository) MarkTokenUsed(ctx context.Context, token s
 	return nil
 }
 
-// DeleteExpiredTokens deletes expired tokens
-func (r *EmailVerificationRepository) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+// deleteExpiredBatchSize bounds how many tokens one DELETE statement removes
+// so cleanup never holds a long-running transaction
+const deleteExpiredBatchSize = 1000
+
+// DeleteExpired deletes tokens that expired or were used before olderThan and
+// returns how many were removed
+func (r *EmailVerificationRepository) DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error) {
 	query := `
 		DELETE FROM email_verification_tokens
-		WHERE expires_at < NOW()
-		RETURNING token_id
+		WHERE expires_at < $1 OR used_at < $1
+		LIMIT $2
 	`
 
-	rows, err := r.pool.Query(ctx, query)
-	if err != nil {
-		return 0, fmt.Errorf("failed to delete expired tokens: %w", err)
-	}
-	defer rows.Close()
-
-	count := int64(0)
-	for rows.Next() {
-		count++
-	}
-
-	if err := rows.Err(); err != nil {
-		return 0, fmt.Errorf("error iterating deleted tokens: %w", err)
+	var total int64
+	for {
+		tag, err := r.pool.Exec(ctx, query, olderThan, deleteExpiredBatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to delete expired tokens: %w", err)
+		}
+		total += tag.RowsAffected()
+		if tag.RowsAffected() < deleteExpiredBatchSize {
+			return total, nil
+		}
 	}
-
-	return count, nil
 }
 
 // DeleteUserTokens deletes all tokens for a user
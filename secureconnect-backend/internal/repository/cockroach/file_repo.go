@@ -225,3 +225,87 @@ func (r *FileRepository) GetExpiredUploads(ctx context.Context, expiryDuration t
 
 	return files, nil
 }
+
+// GetCompletedFiles retrieves every completed file of a user, i.e. the files counted against the quota
+func (r *FileRepository) GetCompletedFiles(ctx context.Context, userID uuid.UUID) ([]*domain.File, error) {
+	query := `
+		SELECT file_id, user_id, file_name, file_size, content_type,
+		       minio_object_key, is_encrypted, status, created_at
+		FROM files
+		WHERE user_id = $1 AND status = 'completed'
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get completed files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*domain.File
+	for rows.Next() {
+		file := &domain.File{}
+		err := rows.Scan(
+			&file.FileID,
+			&file.UserID,
+			&file.FileName,
+			&file.FileSize,
+			&file.ContentType,
+			&file.MinIOObjectKey,
+			&file.IsEncrypted,
+			&file.Status,
+			&file.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// UpdateFileSize corrects the recorded size of a file
+func (r *FileRepository) UpdateFileSize(ctx context.Context, fileID uuid.UUID, size int64) error {
+	query := `
+		UPDATE files
+		SET file_size = $2, updated_at = $3
+		WHERE file_id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, fileID, size, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update file size: %w", err)
+	}
+
+	return nil
+}
+
+// ListUsersWithFiles returns up to limit users with completed files, ordered
+// by user ID and starting after the given ID, for paging through all users
+func (r *FileRepository) ListUsersWithFiles(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT user_id
+		FROM files
+		WHERE status = 'completed' AND user_id > $1
+		ORDER BY user_id
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users with files: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, nil
+}
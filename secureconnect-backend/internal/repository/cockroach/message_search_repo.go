@@ -0,0 +1,147 @@
+package cockroach
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// MessageSearchRepository is the server-side message search index. It holds
+// a copy of plaintext messages with a full-text index over their content;
+// Cassandra remains the message store. Content is tokenized with the
+// 'simple' text search configuration, which lowercases words without
+// language-specific stemming, so it works the same for every language.
+type MessageSearchRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewMessageSearchRepository creates a new MessageSearchRepository
+func NewMessageSearchRepository(pool *pgxpool.Pool) *MessageSearchRepository {
+	return &MessageSearchRepository{pool: pool}
+}
+
+// IndexMessages adds messages to the index, replacing the entries of ones
+// already indexed so edits are searchable by their new content
+func (r *MessageSearchRepository) IndexMessages(ctx context.Context, messages []*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	query := `
+		UPSERT INTO message_search (conversation_id, message_id, sender_id, content, message_type, sent_at, edited_at, seq)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	batch := &pgx.Batch{}
+	for _, msg := range messages {
+		batch.Queue(query,
+			msg.ConversationID,
+			msg.MessageID,
+			msg.SenderID,
+			msg.Content,
+			msg.MessageType,
+			msg.SentAt,
+			msg.EditedAt,
+			msg.Seq,
+		)
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to index messages: %w", err)
+	}
+	return nil
+}
+
+// RemoveMessage removes one message from the index
+func (r *MessageSearchRepository) RemoveMessage(ctx context.Context, conversationID, messageID uuid.UUID) error {
+	query := `DELETE FROM message_search WHERE conversation_id = $1 AND message_id = $2`
+	if _, err := r.pool.Exec(ctx, query, conversationID, messageID); err != nil {
+		return fmt.Errorf("failed to remove message from search index: %w", err)
+	}
+	return nil
+}
+
+// RemoveConversation removes all of a conversation's messages from the index
+func (r *MessageSearchRepository) RemoveConversation(ctx context.Context, conversationID uuid.UUID) error {
+	query := `DELETE FROM message_search WHERE conversation_id = $1`
+	if _, err := r.pool.Exec(ctx, query, conversationID); err != nil {
+		return fmt.Errorf("failed to remove conversation from search index: %w", err)
+	}
+	return nil
+}
+
+// DeleteOlderThan removes a conversation's messages sent before cutoff, so
+// the index follows the retention purge
+func (r *MessageSearchRepository) DeleteOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) error {
+	query := `DELETE FROM message_search WHERE conversation_id = $1 AND sent_at < $2`
+	if _, err := r.pool.Exec(ctx, query, conversationID, cutoff); err != nil {
+		return fmt.Errorf("failed to purge search index: %w", err)
+	}
+	return nil
+}
+
+// SearchMessages returns up to limit indexed messages matching every word of
+// query, most relevant first, from the conversations userID participates in,
+// or only from conversationID when it is set. Conversations that are E2EE or
+// opted out of indexing are skipped even if their index entries have not
+// been removed yet. Results continue after the after cursor when it is set.
+func (r *MessageSearchRepository) SearchMessages(ctx context.Context, userID uuid.UUID, query string, conversationID *uuid.UUID, after *domain.MessageSearchCursor, limit int) ([]*domain.MessageSearchHit, error) {
+	sql := `
+		SELECT conversation_id, message_id, sender_id, content, message_type, sent_at, edited_at, seq, rank
+		FROM (
+			SELECT s.conversation_id, s.message_id, s.sender_id, s.content, s.message_type, s.sent_at, s.edited_at, s.seq,
+			       ts_rank(s.content_tsv, plainto_tsquery('simple', $2))::FLOAT8 AS rank
+			FROM message_search s
+			INNER JOIN conversation_participants cp ON cp.conversation_id = s.conversation_id AND cp.user_id = $1
+			INNER JOIN conversation_settings cs ON cs.conversation_id = s.conversation_id
+			WHERE s.content_tsv @@ plainto_tsquery('simple', $2)
+			  AND NOT COALESCE(cs.is_e2ee_enabled, true)
+			  AND COALESCE(cs.search_indexing, true)
+	`
+	args := []interface{}{userID, query}
+	if conversationID != nil {
+		args = append(args, *conversationID)
+		sql += fmt.Sprintf(` AND s.conversation_id = $%d`, len(args))
+	}
+	sql += `) AS hits`
+	if after != nil {
+		args = append(args, after.Rank, after.SentAt, after.MessageID)
+		sql += fmt.Sprintf(` WHERE (rank, sent_at, message_id) < ($%d, $%d, $%d)`, len(args)-2, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	sql += fmt.Sprintf(` ORDER BY rank DESC, sent_at DESC, message_id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]*domain.MessageSearchHit, 0)
+	for rows.Next() {
+		msg := &domain.Message{}
+		hit := &domain.MessageSearchHit{Message: msg}
+		if err := rows.Scan(
+			&msg.ConversationID,
+			&msg.MessageID,
+			&msg.SenderID,
+			&msg.Content,
+			&msg.MessageType,
+			&msg.SentAt,
+			&msg.EditedAt,
+			&msg.Seq,
+			&hit.Rank,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return hits, nil
+}
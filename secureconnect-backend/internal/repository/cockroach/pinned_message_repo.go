@@ -0,0 +1,179 @@
+package cockroach
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// PinnedMessageRepository handles pinned message data operations in CockroachDB
+type PinnedMessageRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPinnedMessageRepository creates a new PinnedMessageRepository
+func NewPinnedMessageRepository(pool *pgxpool.Pool) *PinnedMessageRepository {
+	return &PinnedMessageRepository{pool: pool}
+}
+
+// PinMessage stores pin and reports whether it is new. A new pin is placed
+// before the conversation's other pins. A message that is already pinned
+// keeps its original pin. When the conversation already has maxPins,
+// domain.ErrPinLimitReached is returned.
+func (r *PinnedMessageRepository) PinMessage(ctx context.Context, pin *domain.PinnedMessage, maxPins int) (bool, error) {
+	// The count and insert run as one statement, so concurrent pins cannot
+	// exceed the cap under serializable isolation
+	query := `
+		INSERT INTO pinned_messages (conversation_id, message_id, pinned_by, pinned_at, display_order, message_sent_at)
+		SELECT $1, $2, $3, NOW(),
+			COALESCE((SELECT min(display_order) FROM pinned_messages WHERE conversation_id = $1), 0) - 1, $5
+		WHERE (SELECT count(*) FROM pinned_messages WHERE conversation_id = $1) < $4
+		ON CONFLICT (conversation_id, message_id) DO NOTHING
+		RETURNING pinned_at, display_order
+	`
+
+	err := r.pool.QueryRow(ctx, query, pin.ConversationID, pin.MessageID, pin.PinnedBy, maxPins, pin.MessageSentAt).Scan(&pin.PinnedAt, &pin.DisplayOrder, &pin.MessageSentAt)
+	if err == nil {
+		return true, nil
+	}
+	if err != pgx.ErrNoRows {
+		return false, fmt.Errorf("failed to pin message: %w", err)
+	}
+
+	// Nothing was inserted: either the message is already pinned or the cap is reached
+	existing, err := r.GetPin(ctx, pin.ConversationID, pin.MessageID)
+	if err == domain.ErrMessageNotPinned {
+		return false, domain.ErrPinLimitReached
+	}
+	if err != nil {
+		return false, err
+	}
+	*pin = *existing
+	return false, nil
+}
+
+// GetPin returns the pin of a message, or domain.ErrMessageNotPinned
+func (r *PinnedMessageRepository) GetPin(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.PinnedMessage, error) {
+	query := `
+		SELECT conversation_id, message_id, pinned_by, pinned_at, display_order, message_sent_at
+		FROM pinned_messages
+		WHERE conversation_id = $1 AND message_id = $2
+	`
+
+	pin := &domain.PinnedMessage{}
+	err := r.pool.QueryRow(ctx, query, conversationID, messageID).Scan(
+		&pin.ConversationID,
+		&pin.MessageID,
+		&pin.PinnedBy,
+		&pin.PinnedAt,
+		&pin.DisplayOrder,
+		&pin.MessageSentAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrMessageNotPinned
+		}
+		return nil, fmt.Errorf("failed to get pin: %w", err)
+	}
+	return pin, nil
+}
+
+// UnpinMessage removes the pin of a message, or returns domain.ErrMessageNotPinned
+func (r *PinnedMessageRepository) UnpinMessage(ctx context.Context, conversationID, messageID uuid.UUID) error {
+	query := `DELETE FROM pinned_messages WHERE conversation_id = $1 AND message_id = $2`
+
+	tag, err := r.pool.Exec(ctx, query, conversationID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to unpin message: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMessageNotPinned
+	}
+	return nil
+}
+
+// GetPinnedMessages lists a conversation's pins in display order
+func (r *PinnedMessageRepository) GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]*domain.PinnedMessage, error) {
+	query := `
+		SELECT conversation_id, message_id, pinned_by, pinned_at, display_order, message_sent_at
+		FROM pinned_messages
+		WHERE conversation_id = $1
+		ORDER BY display_order ASC, pinned_at DESC, message_id DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %w", err)
+	}
+	defer rows.Close()
+
+	pins := make([]*domain.PinnedMessage, 0)
+	for rows.Next() {
+		pin := &domain.PinnedMessage{}
+		if err := rows.Scan(&pin.ConversationID, &pin.MessageID, &pin.PinnedBy, &pin.PinnedAt, &pin.DisplayOrder, &pin.MessageSentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pinned message: %w", err)
+		}
+		pins = append(pins, pin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pinned messages: %w", err)
+	}
+	return pins, nil
+}
+
+// ReorderPins sets the display order of a conversation's pins to the order of
+// messageIDs, which must list every pin exactly once; otherwise
+// domain.ErrInvalidPinOrder is returned and nothing changes.
+func (r *PinnedMessageRepository) ReorderPins(ctx context.Context, conversationID uuid.UUID, messageIDs []uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the pins so a concurrent pin or unpin cannot change the set being ordered
+	rows, err := tx.Query(ctx, `SELECT message_id FROM pinned_messages WHERE conversation_id = $1 FOR UPDATE`, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get pins: %w", err)
+	}
+	pinned := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var messageID uuid.UUID
+		if err := rows.Scan(&messageID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pin: %w", err)
+		}
+		pinned[messageID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate pins: %w", err)
+	}
+
+	if len(messageIDs) != len(pinned) {
+		return domain.ErrInvalidPinOrder
+	}
+	for _, messageID := range messageIDs {
+		if !pinned[messageID] {
+			return domain.ErrInvalidPinOrder
+		}
+		delete(pinned, messageID) // A repeated ID fails on its second occurrence
+	}
+
+	query := `UPDATE pinned_messages SET display_order = $3 WHERE conversation_id = $1 AND message_id = $2`
+	for i, messageID := range messageIDs {
+		if _, err := tx.Exec(ctx, query, conversationID, messageID, i); err != nil {
+			return fmt.Errorf("failed to reorder pins: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/pagination"
 )
 
 // PollRepository handles poll data operations in CockroachDB
@@ -102,7 +103,7 @@ func (r *PollRepository) GetPollByID(ctx context.Context, pollID uuid.UUID) (*do
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("poll not found")
+			return nil, domain.ErrPollNotFound
 		}
 		return nil, fmt.Errorf("failed to get poll: %w", err)
 	}
@@ -143,7 +144,7 @@ func (r *PollRepository) GetPollByIDWithVotes(ctx context.Context, pollID uuid.U
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("poll not found")
+			return nil, domain.ErrPollNotFound
 		}
 		return nil, fmt.Errorf("failed to get poll with votes: %w", err)
 	}
@@ -186,7 +187,7 @@ func (r *PollRepository) GetPollByIDWithUserVote(ctx context.Context, pollID, us
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("poll not found")
+			return nil, domain.ErrPollNotFound
 		}
 		return nil, fmt.Errorf("failed to get poll with user vote: %w", err)
 	}
@@ -245,7 +246,7 @@ func (r *PollRepository) GetPollsByConversation(ctx context.Context, conversatio
 		       expires_at, is_closed, closed_at, created_at, updated_at
 		FROM polls
 		WHERE conversation_id = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, poll_id DESC
 		LIMIT $2 OFFSET $3
 	`
 
@@ -284,6 +285,58 @@ func (r *PollRepository) GetPollsByConversation(ctx context.Context, conversatio
 	return polls, total, nil
 }
 
+// GetPollsByConversationAfter retrieves up to limit polls older than the cursor using
+// keyset pagination on (created_at, poll_id). A nil cursor returns the newest polls.
+func (r *PollRepository) GetPollsByConversationAfter(ctx context.Context, conversationID uuid.UUID, cursor *pagination.Cursor, limit int) ([]*domain.Poll, error) {
+	query := `
+		SELECT poll_id, conversation_id, creator_id, question, poll_type, allow_vote_change,
+		       expires_at, is_closed, closed_at, created_at, updated_at
+		FROM polls
+		WHERE conversation_id = $1
+	`
+	args := []interface{}{conversationID}
+	if cursor != nil {
+		query += ` AND (created_at, poll_id) < ($2, $3)`
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC, poll_id DESC LIMIT $%d`, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get polls: %w", err)
+	}
+	defer rows.Close()
+
+	polls := make([]*domain.Poll, 0)
+	for rows.Next() {
+		poll := &domain.Poll{}
+		err := rows.Scan(
+			&poll.PollID,
+			&poll.ConversationID,
+			&poll.CreatorID,
+			&poll.Question,
+			&poll.PollType,
+			&poll.AllowVoteChange,
+			&poll.ExpiresAt,
+			&poll.IsClosed,
+			&poll.ClosedAt,
+			&poll.CreatedAt,
+			&poll.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan poll: %w", err)
+		}
+		polls = append(polls, poll)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating polls: %w", err)
+	}
+
+	return polls, nil
+}
+
 // GetPollOptions retrieves options for a poll
 func (r *PollRepository) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]*domain.PollOption, error) {
 	query := `
@@ -370,8 +423,11 @@ func (r *PollRepository) GetPollOptionsWithVotes(ctx context.Context, pollID uui
 	return options, nil
 }
 
-// CastVote casts a vote in a poll (transactional)
-func (r *PollRepository) CastVote(ctx context.Context, vote *domain.PollVote) error {
+// CastVotes records a user's first vote in a poll, one row per option, in a
+// transaction. The user's row in poll_voters is the backstop against
+// concurrent first votes: if it already exists nothing is written and
+// domain.ErrAlreadyVoted is returned.
+func (r *PollRepository) CastVotes(ctx context.Context, pollID, userID uuid.UUID, optionIDs []uuid.UUID) error {
 	// Begin transaction
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
@@ -379,28 +435,34 @@ func (r *PollRepository) CastVote(ctx context.Context, vote *domain.PollVote) er
 	}
 	defer tx.Rollback(ctx)
 
-	// Insert vote
-	query := `
+	votedAt := time.Now()
+	voterQuery := `
+		INSERT INTO poll_voters (poll_id, user_id, voted_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (poll_id, user_id) DO NOTHING
+		RETURNING poll_id
+	`
+
+	var inserted uuid.UUID
+	err = tx.QueryRow(ctx, voterQuery, pollID, userID, votedAt).Scan(&inserted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.ErrAlreadyVoted
+		}
+		return fmt.Errorf("failed to record voter: %w", err)
+	}
+
+	// Insert votes
+	voteQuery := `
 		INSERT INTO poll_votes (vote_id, poll_id, option_id, user_id, voted_at)
 		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (poll_id, user_id, option_id) DO NOTHING
-		RETURNING voted_at
 	`
 
-	err = tx.QueryRow(ctx, query,
-		vote.VoteID,
-		vote.PollID,
-		vote.OptionID,
-		vote.UserID,
-		vote.VotedAt,
-	).Scan(&vote.VotedAt)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			// Vote already exists (duplicate), but that's okay
-			return nil
+	for _, optionID := range optionIDs {
+		if _, err := tx.Exec(ctx, voteQuery, uuid.New(), pollID, optionID, userID, votedAt); err != nil {
+			return fmt.Errorf("failed to cast vote: %w", err)
 		}
-		return fmt.Errorf("failed to cast vote: %w", err)
 	}
 
 	// Commit transaction
@@ -505,7 +567,7 @@ func (r *PollRepository) ClosePoll(ctx context.Context, pollID uuid.UUID) error
 	err := r.pool.QueryRow(ctx, query, pollID).Scan(&returnedID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("poll not found")
+			return domain.ErrPollNotFound
 		}
 		return fmt.Errorf("failed to close poll: %w", err)
 	}
@@ -521,7 +583,7 @@ func (r *PollRepository) DeletePoll(ctx context.Context, pollID uuid.UUID) error
 	err := r.pool.QueryRow(ctx, query, pollID).Scan(&returnedID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("poll not found")
+			return domain.ErrPollNotFound
 		}
 		return fmt.Errorf("failed to delete poll: %w", err)
 	}
@@ -589,6 +651,56 @@ func (r *PollRepository) GetActivePolls(ctx context.Context, conversationID uuid
 	return polls, total, nil
 }
 
+// GetActivePollSummaries counts the active polls of each conversation and
+// returns the latest of them, in one query. Conversations without active
+// polls are left out.
+func (r *PollRepository) GetActivePollSummaries(ctx context.Context, conversationIDs []uuid.UUID) (map[uuid.UUID]*domain.ActivePolls, error) {
+	summaries := make(map[uuid.UUID]*domain.ActivePolls)
+	if len(conversationIDs) == 0 {
+		return summaries, nil
+	}
+
+	query := `
+		SELECT conversation_id, poll_id, question, poll_type, expires_at, created_at, active_count
+		FROM (
+			SELECT conversation_id, poll_id, question, poll_type, expires_at, created_at,
+			       count(*) OVER (PARTITION BY conversation_id) AS active_count,
+			       row_number() OVER (PARTITION BY conversation_id ORDER BY created_at DESC, poll_id DESC) AS rn
+			FROM polls
+			WHERE conversation_id = ANY($1) AND is_closed = FALSE AND (expires_at IS NULL OR expires_at > NOW())
+		) active
+		WHERE rn = 1
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active poll summaries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var conversationID uuid.UUID
+		latest := &domain.ActivePollSummary{}
+		summary := &domain.ActivePolls{Latest: latest}
+		if err := rows.Scan(
+			&conversationID,
+			&latest.PollID,
+			&latest.Question,
+			&latest.PollType,
+			&latest.ExpiresAt,
+			&latest.CreatedAt,
+			&summary.Count,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan active poll summary: %w", err)
+		}
+		summaries[conversationID] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating active poll summaries: %w", err)
+	}
+	return summaries, nil
+}
+
 // IsPollCreator checks if a user is the creator of a poll
 func (r *PollRepository) IsPollCreator(ctx context.Context, pollID, userID uuid.UUID) (bool, error) {
 	query := `
@@ -0,0 +1,109 @@
+package cockroach
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// TOTPRepository handles two-factor authentication enrollments in CockroachDB
+type TOTPRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewTOTPRepository creates a new TOTPRepository
+func NewTOTPRepository(pool *pgxpool.Pool) *TOTPRepository {
+	return &TOTPRepository{pool: pool}
+}
+
+// Save stores a new, unconfirmed enrollment, replacing any previous one
+func (r *TOTPRepository) Save(ctx context.Context, totp *domain.UserTOTP) error {
+	query := `
+		UPSERT INTO user_totp (user_id, secret, recovery_code_hashes, last_used_step, created_at, confirmed_at)
+		VALUES ($1, $2, $3, 0, $4, NULL)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, totp.UserID, totp.Secret, totp.RecoveryCodeHashes, totp.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save totp enrollment: %w", err)
+	}
+	return nil
+}
+
+// GetByUserID returns the user's enrollment, or nil if they have none
+func (r *TOTPRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*domain.UserTOTP, error) {
+	query := `
+		SELECT user_id, secret, recovery_code_hashes, last_used_step, created_at, confirmed_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	totp := &domain.UserTOTP{}
+	err := r.pool.QueryRow(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.RecoveryCodeHashes,
+		&totp.LastUsedStep,
+		&totp.CreatedAt,
+		&totp.ConfirmedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get totp enrollment: %w", err)
+	}
+	return totp, nil
+}
+
+// Confirm enables the enrollment, recording step as the last code used
+func (r *TOTPRepository) Confirm(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `UPDATE user_totp SET confirmed_at = now(), last_used_step = $2 WHERE user_id = $1`
+
+	if _, err := r.pool.Exec(ctx, query, userID, step); err != nil {
+		return fmt.Errorf("failed to confirm totp enrollment: %w", err)
+	}
+	return nil
+}
+
+// UseStep records a code's time step as used. It reports false when that
+// step or a later one was already used, so each code works only once.
+func (r *TOTPRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	tag, err := r.pool.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode removes a recovery code hash, reporting false if the user
+// has no such unused code
+func (r *TOTPRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_totp
+		SET recovery_code_hashes = array_remove(recovery_code_hashes, $2)
+		WHERE user_id = $1 AND $2 = ANY(recovery_code_hashes)
+	`
+
+	tag, err := r.pool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Delete removes the user's enrollment
+func (r *TOTPRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM user_totp WHERE user_id = $1`
+
+	if _, err := r.pool.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete totp enrollment: %w", err)
+	}
+	return nil
+}
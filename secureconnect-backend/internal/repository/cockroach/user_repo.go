@@ -77,12 +77,12 @@ func (r *UserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*domain
 	return user, nil
 }
 
-// GetByEmail retrieves a user by email
+// GetByEmail retrieves a user by email (case-insensitive)
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
 		SELECT user_id, email, username, password_hash, display_name, avatar_url, status, created_at, updated_at
 		FROM users
-		WHERE email = $1
+		WHERE lower(email) = lower($1)
 	`
 
 	user := &domain.User{}
@@ -181,6 +181,26 @@ func (r *UserRepository) UpdateStatus(ctx context.Context, userID uuid.UUID, sta
 	return nil
 }
 
+// UpdatePasswordHash replaces a user's password hash. Update does not touch the
+// password, so password changes and rehashes go through here.
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	cmdTag, err := r.pool.Exec(ctx, query, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
 // Delete deletes a user (soft delete could be implemented)
 func (r *UserRepository) Delete(ctx context.Context, userID uuid.UUID) error {
 	query := `DELETE FROM users WHERE user_id = $1`
@@ -197,9 +217,9 @@ func (r *UserRepository) Delete(ctx context.Context, userID uuid.UUID) error {
 	return nil
 }
 
-// EmailExists checks if email already exists
+// EmailExists checks if email already exists (case-insensitive)
 func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE lower(email) = lower($1))`
 
 	var exists bool
 	err := r.pool.QueryRow(ctx, query, email).Scan(&exists)
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+)
+
+// acquireActiveCallScript drops expired calls from the user's set, then adds
+// the call if the user is under the limit. Calls are scored by the time they
+// expire, so calls that are never ended stop counting after the TTL. It
+// returns 1 when the call was added and 0 when the limit was reached.
+var acquireActiveCallScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+if redis.call("ZSCORE", KEYS[1], ARGV[3]) == false and redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[4]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[3])
+redis.call("PEXPIREAT", KEYS[1], ARGV[2])
+return 1`)
+
+// ActiveCallRepository counts each user's ringing or active outgoing calls
+// across video-service instances
+type ActiveCallRepository struct {
+	client *database.RedisClient
+}
+
+// NewActiveCallRepository creates a new ActiveCallRepository
+func NewActiveCallRepository(client *database.RedisClient) *ActiveCallRepository {
+	return &ActiveCallRepository{client: client}
+}
+
+func activeCallsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("active_calls:%s", userID)
+}
+
+// Acquire counts callID against the user's limit of concurrent calls for at
+// most ttl. It reports false without counting the call when the user already
+// has limit calls.
+func (r *ActiveCallRepository) Acquire(ctx context.Context, userID, callID uuid.UUID, limit int, ttl time.Duration) (bool, error) {
+	if r.client.IsDegraded() {
+		return false, fmt.Errorf("redis is in degraded mode, call limit skipped")
+	}
+
+	now := time.Now()
+	added, err := acquireActiveCallScript.Run(ctx, r.client.Client, []string{activeCallsKey(userID)},
+		now.UnixMilli(), now.Add(ttl).UnixMilli(), callID.String(), limit).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to count active calls: %w", err)
+	}
+	return added == 1, nil
+}
+
+// Release stops counting callID against the user's limit
+func (r *ActiveCallRepository) Release(ctx context.Context, userID, callID uuid.UUID) error {
+	if err := r.client.Client.ZRem(ctx, activeCallsKey(userID), callID.String()).Err(); err != nil {
+		return fmt.Errorf("failed to release active call: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+)
+
+func TestActiveCallRepository_LimitsConcurrentCalls(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewActiveCallRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+
+	userID := uuid.New()
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+
+	for _, callID := range []uuid.UUID{first, second} {
+		acquired, err := repo.Acquire(ctx, userID, callID, 2, time.Hour)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	}
+
+	acquired, err := repo.Acquire(ctx, userID, third, 2, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, acquired, "a third call is over the limit")
+
+	acquired, err = repo.Acquire(ctx, userID, first, 2, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "a call already counted is not counted twice")
+
+	acquired, err = repo.Acquire(ctx, uuid.New(), third, 2, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "other users have their own limit")
+
+	require.NoError(t, repo.Release(ctx, userID, first))
+	acquired, err = repo.Acquire(ctx, userID, third, 2, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "ending a call frees its slot")
+}
+
+func TestActiveCallRepository_ExpiresCallsNeverEnded(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewActiveCallRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	acquired, err := repo.Acquire(ctx, userID, uuid.New(), 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	acquired, err = repo.Acquire(ctx, userID, uuid.New(), 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	time.Sleep(60 * time.Millisecond)
+	acquired, err = repo.Acquire(ctx, userID, uuid.New(), 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, acquired, "the abandoned call stopped counting after its TTL")
+}
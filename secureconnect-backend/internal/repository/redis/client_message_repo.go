@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+)
+
+// clientMessagePending marks a client_msg_id whose send has not finished
+const clientMessagePending = "pending"
+
+// ClientMessageRepository maps a sender's client_msg_id in a conversation to
+// the message it produced, so retried sends are not stored twice
+type ClientMessageRepository struct {
+	client *database.RedisClient
+}
+
+// NewClientMessageRepository creates a new ClientMessageRepository
+func NewClientMessageRepository(client *database.RedisClient) *ClientMessageRepository {
+	return &ClientMessageRepository{client: client}
+}
+
+func clientMessageKey(senderID, conversationID uuid.UUID, clientMsgID string) string {
+	return fmt.Sprintf("chat:client_msg:%s:%s:%s", senderID, conversationID, clientMsgID)
+}
+
+// ClaimClientMessage marks clientMsgID as being sent for ttl. If it was
+// already claimed it returns the message recorded for it, or nil while that
+// send is still pending.
+func (r *ClientMessageRepository) ClaimClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string, ttl time.Duration) (bool, *domain.MessageResponse, error) {
+	if r.client.IsDegraded() {
+		return false, nil, fmt.Errorf("redis is in degraded mode, client_msg_id not claimed")
+	}
+
+	key := clientMessageKey(senderID, conversationID, clientMsgID)
+	claimed, err := r.client.Client.SetNX(ctx, key, clientMessagePending, ttl).Result()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to claim client_msg_id: %w", err)
+	}
+	if claimed {
+		return true, nil, nil
+	}
+
+	value, err := r.client.Client.Get(ctx, key).Result()
+	if err == redis.Nil || value == clientMessagePending {
+		// Expired between the two calls, or the first send is still running
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get client_msg_id: %w", err)
+	}
+	var message domain.MessageResponse
+	if err := json.Unmarshal([]byte(value), &message); err != nil {
+		return false, nil, fmt.Errorf("invalid client_msg_id entry: %w", err)
+	}
+	return false, &message, nil
+}
+
+// CompleteClientMessage records the message clientMsgID produced for ttl
+func (r *ClientMessageRepository) CompleteClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string, message *domain.MessageResponse, ttl time.Duration) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := r.client.SafeSet(ctx, clientMessageKey(senderID, conversationID, clientMsgID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to record client_msg_id: %w", err)
+	}
+	return nil
+}
+
+// ReleaseClientMessage forgets clientMsgID so it can be sent again
+func (r *ClientMessageRepository) ReleaseClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string) error {
+	if err := r.client.SafeDel(ctx, clientMessageKey(senderID, conversationID, clientMsgID)).Err(); err != nil {
+		return fmt.Errorf("failed to release client_msg_id: %w", err)
+	}
+	return nil
+}
@@ -6,13 +6,16 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/pkg/sanitize"
 )
 
 // DirectoryRepository handles user directory (email->UserID mapping) in Redis
 // This is the Global Directory for fast user lookups across sharded CockroachDB
 // Per spec: docs/04-database-sharding-strategy.md
 type DirectoryRepository struct {
-	client *redis.Client
+	client            *redis.Client
+	stripGmailAliases bool
 }
 
 // NewDirectoryRepository creates a new DirectoryRepository
@@ -20,9 +23,20 @@ func NewDirectoryRepository(client *redis.Client) *DirectoryRepository {
 	return &DirectoryRepository{client: client}
 }
 
+// SetEmailNormalization keys Gmail dot and plus-tag aliases as one address.
+// It must match the auth service's setting.
+func (r *DirectoryRepository) SetEmailNormalization(stripGmailAliases bool) {
+	r.stripGmailAliases = stripGmailAliases
+}
+
+// emailKey is the directory key for an email; emails are keyed in normalized form
+func (r *DirectoryRepository) emailKey(email string) string {
+	return fmt.Sprintf("directory:email:%s", sanitize.NormalizeEmail(email, r.stripGmailAliases))
+}
+
 // SetEmailToUserID maps email to user_id for fast lookup
 func (r *DirectoryRepository) SetEmailToUserID(ctx context.Context, email string, userID uuid.UUID) error {
-	key := fmt.Sprintf("directory:email:%s", email)
+	key := r.emailKey(email)
 	err := r.client.Set(ctx, key, userID.String(), 0).Err() // No expiration
 	if err != nil {
 		return fmt.Errorf("failed to set email mapping: %w", err)
@@ -32,7 +46,7 @@ func (r *DirectoryRepository) SetEmailToUserID(ctx context.Context, email string
 
 // GetUserIDByEmail retrieves user_id from email
 func (r *DirectoryRepository) GetUserIDByEmail(ctx context.Context, email string) (uuid.UUID, error) {
-	key := fmt.Sprintf("directory:email:%s", email)
+	key := r.emailKey(email)
 
 	userIDStr, err := r.client.Get(ctx, key).Result()
 	if err != nil {
@@ -82,7 +96,7 @@ func (r *DirectoryRepository) GetUserIDByUsername(ctx context.Context, username
 
 // DeleteEmailMapping removes email->UserID mapping
 func (r *DirectoryRepository) DeleteEmailMapping(ctx context.Context, email string) error {
-	key := fmt.Sprintf("directory:email:%s", email)
+	key := r.emailKey(email)
 	return r.client.Del(ctx, key).Err()
 }
 
@@ -94,7 +108,7 @@ func (r *DirectoryRepository) DeleteUsernameMapping(ctx context.Context, usernam
 
 // EmailExists checks if email exists in directory
 func (r *DirectoryRepository) EmailExists(ctx context.Context, email string) (bool, error) {
-	key := fmt.Sprintf("directory:email:%s", email)
+	key := r.emailKey(email)
 	count, err := r.client.Exists(ctx, key).Result()
 	if err != nil {
 		return false, fmt.Errorf("failed to check email existence: %w", err)
@@ -0,0 +1,173 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+)
+
+// Defaults for ConversationEventStream
+const (
+	DefaultEventStreamMaxLen = 10000
+	DefaultEventStreamTTL    = 7 * 24 * time.Hour
+
+	// eventStreamReadCount bounds the entries returned by one XREAD
+	eventStreamReadCount = 100
+)
+
+// ConversationEventStream keeps a capped Redis Stream of events per
+// conversation so clients can replay what they missed from a cursor
+type ConversationEventStream struct {
+	client *database.RedisClient
+	maxLen int64
+	ttl    time.Duration
+}
+
+// NewConversationEventStream creates a stream holding about maxLen events per
+// conversation. A stream with no new events expires after ttl.
+func NewConversationEventStream(client *database.RedisClient, maxLen int64, ttl time.Duration) *ConversationEventStream {
+	if maxLen <= 0 {
+		maxLen = DefaultEventStreamMaxLen
+	}
+	if ttl <= 0 {
+		ttl = DefaultEventStreamTTL
+	}
+	return &ConversationEventStream{client: client, maxLen: maxLen, ttl: ttl}
+}
+
+func conversationEventsKey(conversationID uuid.UUID) string {
+	return fmt.Sprintf("conv:%s:events", conversationID)
+}
+
+// Append adds an event to the conversation's stream and returns its ID.
+// Trimming is approximate, so the stream may briefly exceed maxLen.
+func (s *ConversationEventStream) Append(ctx context.Context, conversationID uuid.UUID, payload []byte) (string, error) {
+	if s.client.IsDegraded() {
+		return "", fmt.Errorf("redis is in degraded mode, event not appended")
+	}
+
+	key := conversationEventsKey(conversationID)
+	pipe := s.client.Client.TxPipeline()
+	add := pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": payload},
+	})
+	pipe.Expire(ctx, key, s.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to append conversation event: %w", err)
+	}
+	return add.Val(), nil
+}
+
+// Read waits up to block for events after the cursor of each conversation in
+// cursors, with one XREAD across all their streams, and returns the events
+// per conversation. An empty result means the wait timed out.
+func (s *ConversationEventStream) Read(ctx context.Context, cursors map[uuid.UUID]string, block time.Duration) (map[uuid.UUID][]domain.ConversationEvent, error) {
+	if s.client.IsDegraded() {
+		return nil, fmt.Errorf("redis is in degraded mode, events unavailable")
+	}
+	if len(cursors) == 0 {
+		return nil, nil
+	}
+
+	// XREAD takes every stream key followed by every ID
+	args := make([]string, 0, 2*len(cursors))
+	ids := make([]string, 0, len(cursors))
+	conversations := make(map[string]uuid.UUID, len(cursors))
+	for conversationID, afterID := range cursors {
+		key := conversationEventsKey(conversationID)
+		conversations[key] = conversationID
+		args = append(args, key)
+		ids = append(ids, afterID)
+	}
+
+	streams, err := s.client.Client.XRead(ctx, &redis.XReadArgs{
+		Streams: append(args, ids...),
+		Count:   eventStreamReadCount,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation events: %w", err)
+	}
+
+	events := make(map[uuid.UUID][]domain.ConversationEvent, len(streams))
+	for _, stream := range streams {
+		if conversationID, ok := conversations[stream.Stream]; ok {
+			events[conversationID] = toConversationEvents(stream.Messages)
+		}
+	}
+	return events, nil
+}
+
+// Range returns up to count events after afterID, oldest first
+func (s *ConversationEventStream) Range(ctx context.Context, conversationID uuid.UUID, afterID string, count int64) ([]domain.ConversationEvent, error) {
+	if s.client.IsDegraded() {
+		return nil, fmt.Errorf("redis is in degraded mode, events unavailable")
+	}
+
+	messages, err := s.client.Client.XRangeN(ctx, conversationEventsKey(conversationID), "("+afterID, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to range conversation events: %w", err)
+	}
+	return toConversationEvents(messages), nil
+}
+
+// Publish implements the services' Publisher interface. Messages for a
+// conversation channel ("chat:<id>") are appended to its stream and then
+// published as before, so pub/sub subscribers keep working.
+func (s *ConversationEventStream) Publish(ctx context.Context, channel string, message interface{}) error {
+	if id, ok := strings.CutPrefix(channel, "chat:"); ok {
+		conversationID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid conversation channel %q: %w", channel, err)
+		}
+		payload, err := eventPayload(message)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Append(ctx, conversationID, payload); err != nil {
+			return err
+		}
+	}
+	return s.client.SafePublish(ctx, channel, message).Err()
+}
+
+func eventPayload(message interface{}) ([]byte, error) {
+	switch m := message.(type) {
+	case []byte:
+		return m, nil
+	case string:
+		return []byte(m), nil
+	default:
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal conversation event: %w", err)
+		}
+		return data, nil
+	}
+}
+
+func toConversationEvents(messages []redis.XMessage) []domain.ConversationEvent {
+	events := make([]domain.ConversationEvent, 0, len(messages))
+	for _, msg := range messages {
+		payload, ok := msg.Values["event"].(string)
+		if !ok {
+			continue
+		}
+		events = append(events, domain.ConversationEvent{ID: msg.ID, Payload: []byte(payload)})
+	}
+	return events
+}
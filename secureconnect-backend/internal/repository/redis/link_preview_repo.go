@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+)
+
+// LinkPreviewCache caches unfurled link previews, including failures, so a URL
+// is fetched at most once per TTL across all chat-service instances
+type LinkPreviewCache struct {
+	client *database.RedisClient
+}
+
+// NewLinkPreviewCache creates a new LinkPreviewCache
+func NewLinkPreviewCache(client *database.RedisClient) *LinkPreviewCache {
+	return &LinkPreviewCache{client: client}
+}
+
+// linkPreviewKey hashes the URL so arbitrary user input never becomes part of a key
+func linkPreviewKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return "linkpreview:" + hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached preview for rawURL and whether there was an entry
+func (r *LinkPreviewCache) Get(ctx context.Context, rawURL string) (*domain.LinkPreview, bool, error) {
+	if r.client.IsDegraded() {
+		return nil, false, fmt.Errorf("redis is in degraded mode, link preview cache skipped")
+	}
+
+	data, err := r.client.Client.Get(ctx, linkPreviewKey(rawURL)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get link preview: %w", err)
+	}
+
+	var preview domain.LinkPreview
+	if err := json.Unmarshal(data, &preview); err != nil {
+		return nil, false, fmt.Errorf("failed to decode link preview: %w", err)
+	}
+	return &preview, true, nil
+}
+
+// Set caches preview for ttl
+func (r *LinkPreviewCache) Set(ctx context.Context, rawURL string, preview *domain.LinkPreview, ttl time.Duration) error {
+	if r.client.IsDegraded() {
+		return nil
+	}
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return fmt.Errorf("failed to encode link preview: %w", err)
+	}
+	if err := r.client.Client.Set(ctx, linkPreviewKey(rawURL), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set link preview: %w", err)
+	}
+	return nil
+}
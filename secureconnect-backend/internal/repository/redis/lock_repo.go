@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+)
+
+// unlockScript deletes the lock only if it is still held by the caller's token
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// extendScript resets the lock's TTL only if it is still held by the caller's token
+var extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// LockRepository provides best-effort distributed locks for background jobs
+type LockRepository struct {
+	client *database.RedisClient
+}
+
+// NewLockRepository creates a new LockRepository
+func NewLockRepository(client *database.RedisClient) *LockRepository {
+	return &LockRepository{client: client}
+}
+
+// TryLock acquires key for ttl without waiting. It returns the token needed to
+// release the lock, or ok=false when another holder has it.
+func (r *LockRepository) TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	if r.client.IsDegraded() {
+		return "", false, fmt.Errorf("redis is in degraded mode, lock skipped")
+	}
+
+	token := uuid.New().String()
+	ok, err := r.client.Client.SetNX(ctx, "lock:"+key, token, ttl).Result()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return token, ok, nil
+}
+
+// Unlock releases key if it is still held with token
+func (r *LockRepository) Unlock(ctx context.Context, key, token string) error {
+	if err := unlockScript.Run(ctx, r.client.Client, []string{"lock:" + key}, token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// Extend resets the TTL of key to ttl if it is still held with token, and
+// reports whether it was. A false result means the lock expired or was taken
+// by another holder.
+func (r *LockRepository) Extend(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	extended, err := extendScript.Run(ctx, r.client.Client, []string{"lock:" + key}, token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to extend lock: %w", err)
+	}
+	return extended == 1, nil
+}
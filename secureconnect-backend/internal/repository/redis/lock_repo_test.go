@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+)
+
+func TestLockRepository_Extend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewLockRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+
+	token, ok, err := repo.TryLock(ctx, "retention", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	mr.FastForward(50 * time.Second)
+	extended, err := repo.Extend(ctx, "retention", token, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, extended)
+	mr.FastForward(50 * time.Second)
+	assert.True(t, mr.Exists("lock:retention"), "an extension restarts the TTL")
+
+	extended, err = repo.Extend(ctx, "retention", "someone-else", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, extended, "only the holder can extend the lock")
+
+	mr.FastForward(time.Minute)
+	extended, err = repo.Extend(ctx, "retention", token, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, extended, "an expired lock cannot be extended")
+}
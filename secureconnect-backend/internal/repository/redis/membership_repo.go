@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+)
+
+// MembershipCache caches conversation membership checks. It lives in Redis so
+// an invalidation in one service is seen by every other service.
+type MembershipCache struct {
+	client *database.RedisClient
+}
+
+// NewMembershipCache creates a new MembershipCache
+func NewMembershipCache(client *database.RedisClient) *MembershipCache {
+	return &MembershipCache{client: client}
+}
+
+func membershipKey(conversationID, userID uuid.UUID) string {
+	return fmt.Sprintf("membership:%s:%s", conversationID, userID)
+}
+
+// Get returns the cached membership of userID and whether there was an entry
+func (r *MembershipCache) Get(ctx context.Context, conversationID, userID uuid.UUID) (member bool, found bool, err error) {
+	if r.client.IsDegraded() {
+		return false, false, fmt.Errorf("redis is in degraded mode, membership cache skipped")
+	}
+
+	val, err := r.client.Client.Get(ctx, membershipKey(conversationID, userID)).Result()
+	if err == redis.Nil {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get membership: %w", err)
+	}
+	return val == "1", true, nil
+}
+
+// Set caches whether userID is a member for ttl
+func (r *MembershipCache) Set(ctx context.Context, conversationID, userID uuid.UUID, member bool, ttl time.Duration) error {
+	if r.client.IsDegraded() {
+		return nil
+	}
+
+	val := "0"
+	if member {
+		val = "1"
+	}
+	if err := r.client.Client.Set(ctx, membershipKey(conversationID, userID), val, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set membership: %w", err)
+	}
+	return nil
+}
+
+// Invalidate drops the cached membership of each user
+func (r *MembershipCache) Invalidate(ctx context.Context, conversationID uuid.UUID, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = membershipKey(conversationID, userID)
+	}
+	if err := r.client.Client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate membership: %w", err)
+	}
+	return nil
+}
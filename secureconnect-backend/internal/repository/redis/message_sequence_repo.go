@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/database"
+)
+
+// MessageSequenceRepository hands out per-conversation message sequence
+// numbers from a Redis counter
+type MessageSequenceRepository struct {
+	client *database.RedisClient
+}
+
+// NewMessageSequenceRepository creates a new MessageSequenceRepository
+func NewMessageSequenceRepository(client *database.RedisClient) *MessageSequenceRepository {
+	return &MessageSequenceRepository{client: client}
+}
+
+func messageSequenceKey(conversationID uuid.UUID) string {
+	return fmt.Sprintf("chat:seq:%s", conversationID)
+}
+
+// ReserveSequences reserves n consecutive sequence numbers for the
+// conversation and returns the last one. The first is last-n+1.
+func (r *MessageSequenceRepository) ReserveSequences(ctx context.Context, conversationID uuid.UUID, n int64) (int64, error) {
+	if r.client.IsDegraded() {
+		return 0, fmt.Errorf("redis is in degraded mode, sequence not reserved")
+	}
+
+	last, err := r.client.Client.IncrBy(ctx, messageSequenceKey(conversationID), n).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve message sequence: %w", err)
+	}
+	return last, nil
+}
@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+)
+
+// MFAChallengeRepository stores the short-lived tokens that link a password
+// login to its second factor
+type MFAChallengeRepository struct {
+	client *database.RedisClient
+}
+
+// NewMFAChallengeRepository creates a new MFAChallengeRepository
+func NewMFAChallengeRepository(client *database.RedisClient) *MFAChallengeRepository {
+	return &MFAChallengeRepository{client: client}
+}
+
+func mfaChallengeKey(token string) string {
+	return fmt.Sprintf("mfa:%s", token)
+}
+
+func mfaFailuresKey(token string) string {
+	return fmt.Sprintf("mfa:%s:failures", token)
+}
+
+func userMFAFailuresKey(userID uuid.UUID) string {
+	return fmt.Sprintf("mfa:user:%s:failures", userID)
+}
+
+// CreateMFAChallenge stores a challenge for userID that expires after ttl
+func (r *MFAChallengeRepository) CreateMFAChallenge(ctx context.Context, token string, userID uuid.UUID, ttl time.Duration) error {
+	if err := r.client.SafeSet(ctx, mfaChallengeKey(token), userID.String(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to create mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// GetMFAChallenge returns the user a challenge belongs to, or uuid.Nil if it
+// does not exist or has expired
+func (r *MFAChallengeRepository) GetMFAChallenge(ctx context.Context, token string) (uuid.UUID, error) {
+	value, err := r.client.SafeGet(ctx, mfaChallengeKey(token)).Result()
+	if err == redis.Nil {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get mfa challenge: %w", err)
+	}
+	userID, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid mfa challenge: %w", err)
+	}
+	return userID, nil
+}
+
+// RecordMFAFailure counts a wrong code against a challenge and returns the
+// number of failures so far
+func (r *MFAChallengeRepository) RecordMFAFailure(ctx context.Context, token string, ttl time.Duration) (int, error) {
+	if r.client.IsDegraded() {
+		return 0, fmt.Errorf("redis is in degraded mode, mfa failure not recorded")
+	}
+
+	key := mfaFailuresKey(token)
+	pipe := r.client.Client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record mfa failure: %w", err)
+	}
+	return int(incr.Val()), nil
+}
+
+// DeleteMFAChallenge removes a challenge so it cannot be used again
+func (r *MFAChallengeRepository) DeleteMFAChallenge(ctx context.Context, token string) error {
+	if err := r.client.SafeDel(ctx, mfaChallengeKey(token), mfaFailuresKey(token)).Err(); err != nil {
+		return fmt.Errorf("failed to delete mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// GetUserMFAFailures returns the wrong codes counted against userID across
+// all their challenges
+func (r *MFAChallengeRepository) GetUserMFAFailures(ctx context.Context, userID uuid.UUID) (int, error) {
+	failures, err := r.client.SafeGet(ctx, userMFAFailuresKey(userID)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mfa failures: %w", err)
+	}
+	return failures, nil
+}
+
+// RecordUserMFAFailure counts a wrong code against userID and returns the
+// number of failures so far. Each failure restarts the ttl.
+func (r *MFAChallengeRepository) RecordUserMFAFailure(ctx context.Context, userID uuid.UUID, ttl time.Duration) (int, error) {
+	if r.client.IsDegraded() {
+		return 0, fmt.Errorf("redis is in degraded mode, mfa failure not recorded")
+	}
+
+	key := userMFAFailuresKey(userID)
+	pipe := r.client.Client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record mfa failure: %w", err)
+	}
+	return int(incr.Val()), nil
+}
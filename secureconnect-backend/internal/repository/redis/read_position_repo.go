@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+)
+
+// advanceReadScript stores "<sent_at_micros>:<message_id>" for a user only if
+// it is further than the stored position, so a late or reordered write never
+// moves a read position backwards
+var advanceReadScript = redis.NewScript(`
+local current = redis.call("HGET", KEYS[1], ARGV[1])
+if current then
+	local sentAt = tonumber(string.match(current, "^(%d+):"))
+	if sentAt and sentAt >= tonumber(ARGV[2]) then
+		return 0
+	end
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2] .. ":" .. ARGV[3])
+return 1`)
+
+// ReadPositionRepository stores each participant's furthest-read message per conversation
+type ReadPositionRepository struct {
+	client *database.RedisClient
+}
+
+// NewReadPositionRepository creates a new ReadPositionRepository
+func NewReadPositionRepository(client *database.RedisClient) *ReadPositionRepository {
+	return &ReadPositionRepository{client: client}
+}
+
+func readPositionsKey(conversationID uuid.UUID) string {
+	return fmt.Sprintf("read:%s", conversationID)
+}
+
+// AdvanceReadPosition records position unless the user has already read past it.
+// It reports whether the stored position moved.
+func (r *ReadPositionRepository) AdvanceReadPosition(ctx context.Context, position *domain.ReadPosition) (bool, error) {
+	if r.client.IsDegraded() {
+		return false, fmt.Errorf("redis is in degraded mode, read position not stored")
+	}
+
+	moved, err := advanceReadScript.Run(ctx, r.client.Client,
+		[]string{readPositionsKey(position.ConversationID)},
+		position.UserID.String(), position.SentAt.UnixMicro(), position.MessageID.String(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to store read position: %w", err)
+	}
+	return moved == 1, nil
+}
+
+// GetReadPosition returns the user's read position, or nil if they have read nothing
+func (r *ReadPositionRepository) GetReadPosition(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ReadPosition, error) {
+	value, err := r.client.SafeHGet(ctx, readPositionsKey(conversationID), userID.String()).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read position: %w", err)
+	}
+
+	sentAt, messageID, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid read position %q", value)
+	}
+	micros, err := strconv.ParseInt(sentAt, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read position time: %w", err)
+	}
+	id, err := uuid.Parse(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read position message: %w", err)
+	}
+	return &domain.ReadPosition{
+		ConversationID: conversationID,
+		UserID:         userID,
+		MessageID:      id,
+		SentAt:         time.UnixMicro(micros).UTC(),
+	}, nil
+}
@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+)
+
+// RecentMessageRepository keeps the latest messages of each conversation so
+// history can still be read while Cassandra is unavailable
+type RecentMessageRepository struct {
+	client *database.RedisClient
+}
+
+// NewRecentMessageRepository creates a new RecentMessageRepository
+func NewRecentMessageRepository(client *database.RedisClient) *RecentMessageRepository {
+	return &RecentMessageRepository{client: client}
+}
+
+// recentMessagesKey is a sorted set of message JSON scored by send time
+func recentMessagesKey(conversationID uuid.UUID) string {
+	return fmt.Sprintf("chat:recent:%s", conversationID)
+}
+
+// AddRecentMessages adds messages to the conversation's cache, keeping the
+// newest keep messages sent within ttl
+func (r *RecentMessageRepository) AddRecentMessages(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message, keep int, ttl time.Duration) error {
+	if r.client.IsDegraded() {
+		return fmt.Errorf("redis is in degraded mode, recent messages not cached")
+	}
+
+	members := make([]redis.Z, 0, len(messages))
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		members = append(members, redis.Z{Score: float64(message.SentAt.UnixMilli()), Member: data})
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	key := recentMessagesKey(conversationID)
+	cutoff := strconv.FormatInt(time.Now().Add(-ttl).UnixMilli(), 10)
+	_, err := r.client.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+cutoff)
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-keep-1))
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cache recent messages: %w", err)
+	}
+	return nil
+}
+
+// GetRecentMessages returns up to limit cached messages, newest first
+func (r *RecentMessageRepository) GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]*domain.Message, error) {
+	if r.client.IsDegraded() {
+		return nil, fmt.Errorf("redis is in degraded mode, recent messages unavailable")
+	}
+
+	values, err := r.client.Client.ZRevRange(ctx, recentMessagesKey(conversationID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent messages: %w", err)
+	}
+	messages := make([]*domain.Message, 0, len(values))
+	for _, value := range values {
+		var message domain.Message
+		if err := json.Unmarshal([]byte(value), &message); err != nil {
+			return nil, fmt.Errorf("invalid cached message: %w", err)
+		}
+		messages = append(messages, &message)
+	}
+	return messages, nil
+}
+
+// ReplaceRecentMessage swaps the cached copy of an edited or deleted message.
+// Messages that are not cached, for example because they expired, are left
+// alone.
+func (r *RecentMessageRepository) ReplaceRecentMessage(ctx context.Context, message *domain.Message) error {
+	if r.client.IsDegraded() {
+		return fmt.Errorf("redis is in degraded mode, recent messages not updated")
+	}
+
+	key := recentMessagesKey(message.ConversationID)
+	score := strconv.FormatInt(message.SentAt.UnixMilli(), 10)
+	// Members are scored by send time, so only messages from the same
+	// millisecond need to be compared
+	values, err := r.client.Client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: score, Max: score}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get recent messages: %w", err)
+	}
+	for _, value := range values {
+		var cached domain.Message
+		if err := json.Unmarshal([]byte(value), &cached); err != nil || cached.MessageID != message.MessageID {
+			continue
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		_, err = r.client.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRem(ctx, key, value)
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(message.SentAt.UnixMilli()), Member: data})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to replace recent message: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+	"secureconnect-backend/internal/domain"
+)
+
+func TestRecentMessageRepository_KeepsNewestWithinTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewRecentMessageRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+
+	conversationID := uuid.New()
+	now := time.Now()
+	message := func(content string, age time.Duration) *domain.Message {
+		return &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, Content: content, SentAt: now.Add(-age)}
+	}
+
+	require.NoError(t, repo.AddRecentMessages(ctx, conversationID, []*domain.Message{
+		message("expired", 2*time.Hour),
+		message("oldest", 3*time.Minute),
+	}, 3, time.Hour))
+	require.NoError(t, repo.AddRecentMessages(ctx, conversationID, []*domain.Message{
+		message("older", 2*time.Minute),
+		message("newer", time.Minute),
+		message("newest", 0),
+	}, 3, time.Hour))
+
+	messages, err := repo.GetRecentMessages(ctx, conversationID, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "newest", messages[0].Content)
+	assert.Equal(t, "newer", messages[1].Content)
+	assert.Equal(t, "older", messages[2].Content)
+	assert.True(t, mr.TTL(recentMessagesKey(conversationID)) > 0)
+
+	messages, err = repo.GetRecentMessages(ctx, conversationID, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "newest", messages[0].Content)
+}
+
+func TestRecentMessageRepository_ReplaceRecentMessage(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewRecentMessageRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+
+	conversationID := uuid.New()
+	sentAt := time.Now()
+	// Two messages sent in the same millisecond share a score
+	target := &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, Content: "secret", SentAt: sentAt}
+	sibling := &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, Content: "sibling", SentAt: sentAt}
+	require.NoError(t, repo.AddRecentMessages(ctx, conversationID, []*domain.Message{target, sibling}, 10, time.Hour))
+
+	deletedAt := time.Now()
+	tombstone := *target
+	tombstone.Content = ""
+	tombstone.DeletedAt = &deletedAt
+	require.NoError(t, repo.ReplaceRecentMessage(ctx, &tombstone))
+
+	messages, err := repo.GetRecentMessages(ctx, conversationID, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	byID := map[uuid.UUID]*domain.Message{}
+	for _, m := range messages {
+		byID[m.MessageID] = m
+	}
+	assert.True(t, byID[target.MessageID].IsDeleted())
+	assert.Empty(t, byID[target.MessageID].Content)
+	assert.Equal(t, "sibling", byID[sibling.MessageID].Content)
+
+	// Messages that are not cached are left alone
+	require.NoError(t, repo.ReplaceRecentMessage(ctx, &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, SentAt: sentAt}))
+	messages, err = repo.GetRecentMessages(ctx, conversationID, 10)
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+}
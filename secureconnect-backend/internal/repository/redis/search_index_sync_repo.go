@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/database"
+)
+
+// searchIndexSyncKey is the set of conversations whose search index entries
+// must be brought in line with their settings. A set collapses repeated
+// requests for one conversation into a single sync.
+const searchIndexSyncKey = "search:index-sync"
+
+// SearchIndexSyncQueue hands conversations whose indexing setting changed
+// from the conversation service to the chat service
+type SearchIndexSyncQueue struct {
+	client *database.RedisClient
+}
+
+// NewSearchIndexSyncQueue creates a new SearchIndexSyncQueue
+func NewSearchIndexSyncQueue(client *database.RedisClient) *SearchIndexSyncQueue {
+	return &SearchIndexSyncQueue{client: client}
+}
+
+// RequestSearchIndexSync queues a sync for the conversation
+func (q *SearchIndexSyncQueue) RequestSearchIndexSync(ctx context.Context, conversationID uuid.UUID) error {
+	if err := q.client.SafeSAdd(ctx, searchIndexSyncKey, conversationID.String()).Err(); err != nil {
+		return fmt.Errorf("failed to queue search index sync: %w", err)
+	}
+	return nil
+}
+
+// PopSearchIndexSyncs removes and returns up to count queued conversations
+func (q *SearchIndexSyncQueue) PopSearchIndexSyncs(ctx context.Context, count int64) ([]uuid.UUID, error) {
+	if q.client.IsDegraded() {
+		return nil, fmt.Errorf("redis is in degraded mode, search index syncs not read")
+	}
+
+	members, err := q.client.Client.SPopN(ctx, searchIndexSyncKey, count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop search index syncs: %w", err)
+	}
+
+	conversationIDs := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		id, err := uuid.Parse(member)
+		if err != nil {
+			continue
+		}
+		conversationIDs = append(conversationIDs, id)
+	}
+	return conversationIDs, nil
+}
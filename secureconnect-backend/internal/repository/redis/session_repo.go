@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"secureconnect-backend/internal/database"
 	"secureconnect-backend/pkg/constants"
@@ -12,6 +13,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrSessionNotFound is returned by GetSession for a missing or expired session
+var ErrSessionNotFound = errors.New("session not found")
+
 // SessionRepository handles user session management in Redis
 type SessionRepository struct {
 	client *database.RedisClient
@@ -30,6 +34,9 @@ type Session struct {
 	RefreshToken string    `json:"refresh_token"`
 	CreatedAt    time.Time `json:"created_at"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	IP           string    `json:"ip,omitempty"`          // Client IP at login
+	UserAgent    string    `json:"user_agent,omitempty"`  // Client User-Agent at login
+	RememberMe   bool      `json:"remember_me,omitempty"` // Login chose the long sliding window
 }
 
 // CreateSession stores a new session
@@ -63,7 +70,7 @@ func (r *SessionRepository) GetSession(ctx context.Context, sessionID string) (*
 	data, err := r.client.SafeGet(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("session not found")
+			return nil, ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -114,6 +121,31 @@ func (r *SessionRepository) DeleteAllUserSessions(ctx context.Context, userID uu
 	return nil
 }
 
+// GetUserSessions returns the user's live sessions. Expired sessions still in
+// the user index are removed from it.
+func (r *SessionRepository) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	userSessionKey := fmt.Sprintf("user:sessions:%s", userID)
+
+	sessionIDs, err := r.client.SafeSMembers(ctx, userSessionKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sessions: %w", err)
+	}
+
+	sessions := make([]*Session, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session, err := r.GetSession(ctx, sessionID)
+		if err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				r.client.SafeSRem(ctx, userSessionKey, sessionID)
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
 // RefreshSessionTTL extends session expiration
 func (r *SessionRepository) RefreshSessionTTL(ctx context.Context, sessionID string, ttl time.Duration) error {
 	key := fmt.Sprintf("session:%s", sessionID)
@@ -136,6 +168,163 @@ func (r *SessionRepository) IsTokenBlacklisted(ctx context.Context, jti string)
 	return exists > 0, nil
 }
 
+// ActiveToken is an issued access token that has not yet expired
+type ActiveToken struct {
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// userTokensKey is a sorted set of a user's access-token JTIs scored by expiry
+func userTokensKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user:tokens:%s", userID)
+}
+
+// TrackAccessToken records an issued access token so it can be revoked along
+// with the user's other tokens. Expired entries are pruned on every call, and
+// the set itself expires with its newest token.
+func (r *SessionRepository) TrackAccessToken(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	if r.client.IsDegraded() {
+		return fmt.Errorf("redis is in degraded mode, token not tracked")
+	}
+
+	key := userTokensKey(userID)
+	pipe := r.client.Client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("%d", time.Now().Unix()))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(expiresAt.Unix()), Member: jti})
+	pipe.Expire(ctx, key, time.Until(expiresAt))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to track access token: %w", err)
+	}
+	return nil
+}
+
+// UntrackAccessTokens removes revoked tokens from the user's set. Only the
+// given JTIs are removed so a token issued concurrently stays tracked.
+func (r *SessionRepository) UntrackAccessTokens(ctx context.Context, userID uuid.UUID, jtis ...string) error {
+	if len(jtis) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(jtis))
+	for i, jti := range jtis {
+		members[i] = jti
+	}
+	if err := r.client.SafeZRem(ctx, userTokensKey(userID), members...).Err(); err != nil {
+		return fmt.Errorf("failed to untrack access token: %w", err)
+	}
+	return nil
+}
+
+// GetActiveAccessTokens returns the user's unexpired access tokens
+func (r *SessionRepository) GetActiveAccessTokens(ctx context.Context, userID uuid.UUID) ([]ActiveToken, error) {
+	if r.client.IsDegraded() {
+		return nil, fmt.Errorf("redis is in degraded mode, tokens unavailable")
+	}
+
+	entries, err := r.client.Client.ZRangeByScoreWithScores(ctx, userTokensKey(userID), &redis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", time.Now().Unix()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active access tokens: %w", err)
+	}
+
+	tokens := make([]ActiveToken, 0, len(entries))
+	for _, entry := range entries {
+		jti, ok := entry.Member.(string)
+		if !ok {
+			continue
+		}
+		tokens = append(tokens, ActiveToken{JTI: jti, ExpiresAt: time.Unix(int64(entry.Score), 0)})
+	}
+	return tokens, nil
+}
+
+// RefreshTokenState is the rotation state of an issued refresh token
+type RefreshTokenState string
+
+const (
+	// RefreshTokenActive has been issued and not yet exchanged
+	RefreshTokenActive RefreshTokenState = "active"
+	// RefreshTokenUsed has already been exchanged for a new pair
+	RefreshTokenUsed RefreshTokenState = "used"
+	// RefreshTokenUnknown was never recorded, has expired, or was revoked
+	RefreshTokenUnknown RefreshTokenState = ""
+)
+
+// storeRefreshScript records a refresh JTI as "active:<expiry>", pruning
+// entries past their expiry so the hash stays bounded by token lifetime. The
+// hash expires with its longest-lived token.
+var storeRefreshScript = redis.NewScript(`
+local now = tonumber(ARGV[3])
+local entries = redis.call("HGETALL", KEYS[1])
+for i = 1, #entries, 2 do
+	local expiry = tonumber(string.match(entries[i + 1], ":(%d+)$"))
+	if expiry and expiry <= now then
+		redis.call("HDEL", KEYS[1], entries[i])
+	end
+end
+redis.call("HSET", KEYS[1], ARGV[1], "active:" .. ARGV[2])
+local ttl = redis.call("TTL", KEYS[1])
+if ttl < 0 or now + ttl < tonumber(ARGV[2]) then
+	redis.call("EXPIREAT", KEYS[1], ARGV[2])
+end
+return 1`)
+
+// markRefreshUsedScript flips an active JTI to used and returns its previous
+// state, so of two concurrent refreshes with one token only one sees "active"
+var markRefreshUsedScript = redis.NewScript(`
+local value = redis.call("HGET", KEYS[1], ARGV[1])
+if not value then
+	return ""
+end
+local state, expiry = string.match(value, "^(%a+):(%d+)$")
+if state == "active" then
+	redis.call("HSET", KEYS[1], ARGV[1], "used:" .. expiry)
+end
+return state or ""`)
+
+// refreshTokensKey is a hash of a user's refresh-token JTIs to "<state>:<expiry>"
+func refreshTokensKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user:refresh:%s", userID)
+}
+
+// StoreRefreshJTI records an issued refresh token as active until expiresAt
+func (r *SessionRepository) StoreRefreshJTI(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	if r.client.IsDegraded() {
+		return fmt.Errorf("redis is in degraded mode, refresh token not recorded")
+	}
+
+	err := storeRefreshScript.Run(ctx, r.client.Client, []string{refreshTokensKey(userID)},
+		jti, expiresAt.Unix(), time.Now().Unix()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// MarkRefreshUsed marks an active refresh token as used and returns the state
+// it had before. RefreshTokenUsed means the token is being replayed.
+func (r *SessionRepository) MarkRefreshUsed(ctx context.Context, userID uuid.UUID, jti string) (RefreshTokenState, error) {
+	if r.client.IsDegraded() {
+		return RefreshTokenUnknown, fmt.Errorf("redis is in degraded mode, refresh token not checked")
+	}
+
+	state, err := markRefreshUsedScript.Run(ctx, r.client.Client, []string{refreshTokensKey(userID)}, jti).Text()
+	if err != nil {
+		return RefreshTokenUnknown, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	return RefreshTokenState(state), nil
+}
+
+// RevokeUserTokens forgets every refresh token of the user, so none of them
+// can be exchanged again
+func (r *SessionRepository) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	if err := r.client.SafeDel(ctx, refreshTokensKey(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
 // AccountLock represents a locked account
 type AccountLock struct {
 	LockedUntil time.Time `json:"locked_until"`
@@ -153,6 +342,9 @@ type FailedLoginAttempt struct {
 // GetAccountLock retrieves account lock status
 func (r *SessionRepository) GetAccountLock(ctx context.Context, key string) (*AccountLock, error) {
 	data, err := r.client.SafeGet(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account lock: %w", err)
 	}
@@ -170,19 +362,51 @@ func (r *SessionRepository) GetAccountLock(ctx context.Context, key string) (*Ac
 	return &AccountLock{LockedUntil: lockedUntil}, nil
 }
 
-// LockAccount locks an account
+// LockAccount locks an account until lockedUntil
 func (r *SessionRepository) LockAccount(ctx context.Context, key string, lockedUntil time.Time) error {
-	data := fmt.Sprintf("%d", lockedUntil.Unix())
-	err := r.client.SafeSet(ctx, key, data, constants.AccountLockDuration).Err()
+	// Stored as JSON so GetAccountLock can decode it
+	data, err := json.Marshal(lockedUntil)
 	if err != nil {
+		return fmt.Errorf("failed to marshal account lock: %w", err)
+	}
+
+	ttl := time.Until(lockedUntil)
+	if ttl <= 0 {
+		ttl = constants.AccountLockDuration
+	}
+	if err := r.client.SafeSet(ctx, key, data, ttl).Err(); err != nil {
 		return fmt.Errorf("failed to lock account: %w", err)
 	}
 	return nil
 }
 
+// MarkLockoutNotified records that the owner of key was told about a lock and
+// reports whether this is the first notice within ttl
+func (r *SessionRepository) MarkLockoutNotified(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if r.client.IsDegraded() {
+		return false, fmt.Errorf("redis is in degraded mode, lockout notice not recorded")
+	}
+	first, err := r.client.Client.SetNX(ctx, "lockout_notified:"+key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to record lockout notice: %w", err)
+	}
+	return first, nil
+}
+
+// UnlockAccount removes an account lock
+func (r *SessionRepository) UnlockAccount(ctx context.Context, key string) error {
+	if err := r.client.SafeDel(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
 // GetFailedLoginAttempts retrieves failed login attempts
 func (r *SessionRepository) GetFailedLoginAttempts(ctx context.Context, key string) (int, error) {
 	data, err := r.client.SafeGet(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
 	if err != nil {
 		return 0, fmt.Errorf("failed to get failed login attempts: %w", err)
 	}
@@ -201,6 +425,9 @@ func (r *SessionRepository) GetFailedLoginAttempts(ctx context.Context, key stri
 // GetFailedLoginAttempt retrieves full failed login attempt information
 func (r *SessionRepository) GetFailedLoginAttempt(ctx context.Context, key string) (*FailedLoginAttempt, error) {
 	data, err := r.client.SafeGet(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get failed login attempt: %w", err)
 	}
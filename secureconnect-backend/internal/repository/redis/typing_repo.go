@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/database"
+)
+
+// TypingRepository records who is typing in each conversation. Entries
+// expire on their own, so an indicator whose client disconnected without a
+// typing_stop does not stick.
+type TypingRepository struct {
+	client *database.RedisClient
+}
+
+// NewTypingRepository creates a new TypingRepository
+func NewTypingRepository(client *database.RedisClient) *TypingRepository {
+	return &TypingRepository{client: client}
+}
+
+func typingKey(conversationID, userID uuid.UUID) string {
+	return fmt.Sprintf("typing:%s:%s", conversationID, userID)
+}
+
+// RefreshTyping marks userID as typing in the conversation for ttl
+func (r *TypingRepository) RefreshTyping(ctx context.Context, conversationID, userID uuid.UUID, ttl time.Duration) error {
+	if err := r.client.Client.Set(ctx, typingKey(conversationID, userID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to refresh typing state: %w", err)
+	}
+	return nil
+}
+
+// ClearTyping marks userID as no longer typing in the conversation
+func (r *TypingRepository) ClearTyping(ctx context.Context, conversationID, userID uuid.UUID) error {
+	if err := r.client.Client.Del(ctx, typingKey(conversationID, userID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear typing state: %w", err)
+	}
+	return nil
+}
+
+// TypingTTL returns how long userID remains typing in the conversation, or
+// 0 when they are not
+func (r *TypingRepository) TypingTTL(ctx context.Context, conversationID, userID uuid.UUID) (time.Duration, error) {
+	ttl, err := r.client.Client.PTTL(ctx, typingKey(conversationID, userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get typing state: %w", err)
+	}
+	// PTTL is negative for a missing key or one without expiry
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+)
+
+func TestTypingRepository_Expires(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewTypingRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+
+	userID, conversationID, otherConversationID := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, repo.RefreshTyping(ctx, conversationID, userID, 8*time.Second))
+	require.NoError(t, repo.RefreshTyping(ctx, otherConversationID, userID, 8*time.Second))
+	assert.True(t, mr.Exists("typing:"+conversationID.String()+":"+userID.String()))
+
+	ttl, err := repo.TypingTTL(ctx, conversationID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 8*time.Second, ttl)
+
+	mr.FastForward(5 * time.Second)
+	require.NoError(t, repo.RefreshTyping(ctx, conversationID, userID, 8*time.Second))
+	mr.FastForward(5 * time.Second)
+	ttl, err = repo.TypingTTL(ctx, conversationID, userID)
+	require.NoError(t, err)
+	assert.Positive(t, ttl, "a refresh restarts the TTL")
+	ttl, err = repo.TypingTTL(ctx, otherConversationID, userID)
+	require.NoError(t, err)
+	assert.Zero(t, ttl, "each conversation expires on its own")
+
+	require.NoError(t, repo.ClearTyping(ctx, conversationID, userID))
+	ttl, err = repo.TypingTTL(ctx, conversationID, userID)
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+}
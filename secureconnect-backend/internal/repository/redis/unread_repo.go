@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/internal/database"
+)
+
+// incrementUnreadScript adds to one conversation's unread count and the
+// user's total in one step
+var incrementUnreadScript = redis.NewScript(`
+redis.call("HINCRBY", KEYS[1], ARGV[1], ARGV[2])
+return redis.call("INCRBY", KEYS[2], ARGV[2])`)
+
+// resetUnreadScript clears one conversation's unread count and recomputes the
+// total from the remaining conversations, repairing any drift. It returns the
+// cleared count and the new total.
+var resetUnreadScript = redis.NewScript(`
+local cleared = tonumber(redis.call("HGET", KEYS[1], ARGV[1]) or "0")
+redis.call("HDEL", KEYS[1], ARGV[1])
+local total = 0
+for _, count in ipairs(redis.call("HVALS", KEYS[1])) do
+	total = total + tonumber(count)
+end
+redis.call("SET", KEYS[2], total)
+return {cleared, total}`)
+
+// resetAllUnreadScript clears every conversation's unread count and returns
+// the counts that were cleared as conversation/count pairs
+var resetAllUnreadScript = redis.NewScript(`
+local counts = redis.call("HGETALL", KEYS[1])
+redis.call("DEL", KEYS[1])
+redis.call("SET", KEYS[2], 0)
+return counts`)
+
+// UnreadRepository keeps each user's unread message count per conversation
+// and the total across conversations used for badges
+type UnreadRepository struct {
+	client *database.RedisClient
+}
+
+// NewUnreadRepository creates a new UnreadRepository
+func NewUnreadRepository(client *database.RedisClient) *UnreadRepository {
+	return &UnreadRepository{client: client}
+}
+
+func unreadKey(userID uuid.UUID) string {
+	return fmt.Sprintf("unread:%s", userID)
+}
+
+func unreadTotalKey(userID uuid.UUID) string {
+	return fmt.Sprintf("unread:%s:total", userID)
+}
+
+// IncrementUnread adds n unread messages in the conversation for each user
+func (r *UnreadRepository) IncrementUnread(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, n int) error {
+	if r.client.IsDegraded() {
+		return fmt.Errorf("redis is in degraded mode, unread count not updated")
+	}
+
+	// EVAL rather than EVALSHA: a pipeline cannot fall back when the script is not cached
+	pipe := r.client.Client.Pipeline()
+	for _, userID := range userIDs {
+		incrementUnreadScript.Eval(ctx, pipe, []string{unreadKey(userID), unreadTotalKey(userID)}, conversationID.String(), n)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to increment unread count: %w", err)
+	}
+	return nil
+}
+
+// ResetUnread clears the user's unread count for the conversation. It returns
+// the count that was cleared and the user's new total.
+func (r *UnreadRepository) ResetUnread(ctx context.Context, userID, conversationID uuid.UUID) (int, int, error) {
+	if r.client.IsDegraded() {
+		return 0, 0, fmt.Errorf("redis is in degraded mode, unread count not reset")
+	}
+
+	result, err := resetUnreadScript.Run(ctx, r.client.Client,
+		[]string{unreadKey(userID), unreadTotalKey(userID)}, conversationID.String(),
+	).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to reset unread count: %w", err)
+	}
+	return int(result[0]), int(result[1]), nil
+}
+
+// ResetAllUnread clears all of the user's unread counts and returns the
+// conversations that had unread messages with their cleared counts
+func (r *UnreadRepository) ResetAllUnread(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error) {
+	if r.client.IsDegraded() {
+		return nil, fmt.Errorf("redis is in degraded mode, unread counts not reset")
+	}
+
+	pairs, err := resetAllUnreadScript.Run(ctx, r.client.Client,
+		[]string{unreadKey(userID), unreadTotalKey(userID)},
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset unread counts: %w", err)
+	}
+
+	cleared := make(map[uuid.UUID]int, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		conversationID, err := uuid.Parse(pairs[i])
+		if err != nil {
+			continue
+		}
+		count, err := strconv.Atoi(pairs[i+1])
+		if err != nil || count <= 0 {
+			continue
+		}
+		cleared[conversationID] = count
+	}
+	return cleared, nil
+}
+
+// GetTotalUnread returns the user's unread messages across all conversations
+func (r *UnreadRepository) GetTotalUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	total, err := r.client.SafeGet(ctx, unreadTotalKey(userID)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unread total: %w", err)
+	}
+	return total, nil
+}
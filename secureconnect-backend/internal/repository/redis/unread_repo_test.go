@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/database"
+)
+
+func TestUnreadRepository_ResetDecrementsTotal(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := NewUnreadRepository(&database.RedisClient{Client: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})})
+	ctx := context.Background()
+
+	userID, otherID := uuid.New(), uuid.New()
+	busy, quiet := uuid.New(), uuid.New()
+	require.NoError(t, repo.IncrementUnread(ctx, busy, []uuid.UUID{userID, otherID}, 3))
+	require.NoError(t, repo.IncrementUnread(ctx, busy, []uuid.UUID{userID}, 2))
+	require.NoError(t, repo.IncrementUnread(ctx, quiet, []uuid.UUID{userID}, 1))
+
+	total, err := repo.GetTotalUnread(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 6, total)
+
+	cleared, total, err := repo.ResetUnread(ctx, userID, busy)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cleared)
+	assert.Equal(t, 1, total, "total drops by the conversation's prior unread count")
+
+	total, err = repo.GetTotalUnread(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	total, err = repo.GetTotalUnread(ctx, otherID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total, "other participants are unaffected")
+
+	all, err := repo.ResetAllUnread(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]int{quiet: 1}, all)
+	total, err = repo.GetTotalUnread(ctx, userID)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
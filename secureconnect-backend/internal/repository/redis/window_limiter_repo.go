@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"secureconnect-backend/internal/database"
+)
+
+// WindowLimiter is a fixed-window counter shared by all instances
+type WindowLimiter struct {
+	client *database.RedisClient
+}
+
+// NewWindowLimiter creates a new WindowLimiter
+func NewWindowLimiter(client *database.RedisClient) *WindowLimiter {
+	return &WindowLimiter{client: client}
+}
+
+// Allow counts one request against key and reports whether it is within limit
+// for the current window
+func (r *WindowLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	if r.client.IsDegraded() {
+		return false, fmt.Errorf("redis is in degraded mode, rate limit skipped")
+	}
+
+	redisKey := "ratelimit:" + key
+	pipe := r.client.Client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.ExpireNX(ctx, redisKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	return incr.Val() <= int64(limit), nil
+}
@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/audit"
+)
+
+// fakeAuditEventSearcher records the last search it was given
+type fakeAuditEventSearcher struct {
+	filter audit.EventQuery
+	page   audit.PageRequest
+	events []*audit.AuditEvent
+}
+
+func (f *fakeAuditEventSearcher) SearchEvents(ctx context.Context, filter audit.EventQuery, page audit.PageRequest) (*audit.SearchPage, error) {
+	f.filter, f.page = filter, page
+	return &audit.SearchPage{Events: f.events, NextCursor: "next"}, nil
+}
+
+func (f *fakeAuditEventSearcher) ExportEvents(ctx context.Context, filter audit.EventQuery, fn func(*audit.AuditEvent) error) error {
+	f.filter = filter
+	for _, event := range f.events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fakeAuditRecorder keeps the events it records
+type fakeAuditRecorder struct {
+	events []*audit.AuditEvent
+	err    error
+}
+
+func (f *fakeAuditRecorder) Log(ctx context.Context, event *audit.AuditEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, event)
+	return nil
+}
+
+func TestSearchAuditEvents_NotConfigured(t *testing.T) {
+	_, err := NewService(nil).SearchAuditEvents(context.Background(), AuditSearchRequest{}, 0, "")
+	assert.ErrorIs(t, err, ErrAuditEventsNotConfigured)
+}
+
+func TestSearchAuditEvents_BoundsAndRecordsQuery(t *testing.T) {
+	searcher, recorder := &fakeAuditEventSearcher{}, &fakeAuditRecorder{}
+	service := NewService(nil)
+	service.SetAuditEventSearcher(searcher, recorder)
+	ctx := context.Background()
+
+	adminID := uuid.New()
+	failed := false
+	req := AuditSearchRequest{
+		AdminID:   adminID,
+		IPAddress: "192.0.2.1",
+		Filter:    audit.EventQuery{IPAddress: "10.0.0.1", Success: &failed},
+	}
+	page, err := service.SearchAuditEvents(ctx, req, 0, "cursor")
+	require.NoError(t, err)
+	assert.Equal(t, "next", page.NextCursor)
+	assert.Equal(t, audit.PageRequest{Limit: DefaultAuditEventLimit, Cursor: "cursor"}, searcher.page)
+	assert.Equal(t, "10.0.0.1", searcher.filter.IPAddress)
+
+	require.Len(t, recorder.events, 1, "the search itself is audited")
+	recorded := recorder.events[0]
+	assert.Equal(t, adminID, *recorded.UserID)
+	assert.Equal(t, audit.EventAdminAction, recorded.EventType)
+	assert.Equal(t, "audit_search", recorded.Action)
+	assert.Equal(t, "192.0.2.1", recorded.IPAddress)
+	assert.Equal(t, "success=false ip_address=10.0.0.1", recorded.Details)
+
+	_, err = service.SearchAuditEvents(ctx, req, 10000, "")
+	require.NoError(t, err)
+	assert.Equal(t, MaxAuditEventLimit, searcher.page.Limit)
+
+	now := time.Now()
+	_, err = service.SearchAuditEvents(ctx, AuditSearchRequest{Filter: audit.EventQuery{From: now, To: now.Add(-time.Hour)}}, 0, "")
+	assert.ErrorIs(t, err, ErrInvalidAuditRange)
+}
+
+func TestSearchAuditEvents_RefusedWhenNotRecorded(t *testing.T) {
+	searcher := &fakeAuditEventSearcher{}
+	service := NewService(nil)
+	service.SetAuditEventSearcher(searcher, &fakeAuditRecorder{err: errors.New("store down")})
+
+	_, err := service.SearchAuditEvents(context.Background(), AuditSearchRequest{AdminID: uuid.New()}, 10, "")
+	assert.Error(t, err)
+	assert.Zero(t, searcher.page, "nothing is searched without an audit record")
+}
+
+func TestExportAuditEvents_WritesCSV(t *testing.T) {
+	userID := uuid.New()
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	searcher := &fakeAuditEventSearcher{events: []*audit.AuditEvent{
+		{EventID: uuid.New(), UserID: &userID, EventType: audit.EventLoginFailed, IPAddress: "10.0.0.1", UserAgent: "=HYPERLINK(\"x\")", Timestamp: at},
+		{EventID: uuid.New(), EventType: audit.EventLogout, Success: true, Timestamp: at.Add(-time.Minute)},
+	}}
+	recorder := &fakeAuditRecorder{}
+	service := NewService(nil)
+	service.SetAuditEventSearcher(searcher, recorder)
+
+	var out bytes.Buffer
+	require.NoError(t, service.ExportAuditEvents(context.Background(), AuditSearchRequest{AdminID: uuid.New()}, &out))
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, auditCSVHeader, rows[0])
+	assert.Equal(t, []string{
+		searcher.events[0].EventID.String(), "2026-03-01T12:00:00Z", userID.String(), "login_failed",
+		"", "", "false", "", "10.0.0.1", "'=HYPERLINK(\"x\")", "",
+	}, rows[1])
+	assert.Equal(t, "", rows[2][2], "events without a user leave the column empty")
+
+	require.Len(t, recorder.events, 1)
+	assert.Equal(t, "audit_export", recorder.events[0].Action)
+}
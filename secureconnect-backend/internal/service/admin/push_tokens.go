@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/push"
+)
+
+// ErrPushTokensNotConfigured is returned when no push token store is set
+var ErrPushTokensNotConfigured = errors.New("push token store is not configured")
+
+// SetPushTokenRepository enables inspecting and purging users' push tokens
+func (s *Service) SetPushTokenRepository(repo push.TokenRepository) {
+	s.pushTokens = repo
+}
+
+// ListPushTokens returns a user's push tokens with the token values masked
+func (s *Service) ListPushTokens(ctx context.Context, userID uuid.UUID) ([]*domain.PushTokenInfo, error) {
+	if s.pushTokens == nil {
+		return nil, ErrPushTokensNotConfigured
+	}
+
+	tokens, err := s.pushTokens.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get push tokens: %w", err)
+	}
+
+	infos := make([]*domain.PushTokenInfo, 0, len(tokens))
+	for _, token := range tokens {
+		infos = append(infos, &domain.PushTokenInfo{
+			ID:          token.ID,
+			Type:        string(token.Type),
+			Platform:    token.Platform,
+			DeviceID:    token.DeviceID,
+			MaskedToken: push.MaskToken(token.Token),
+			Active:      token.Active,
+			CreatedAt:   time.Unix(token.CreatedAt, 0).UTC(),
+			LastSeenAt:  time.Unix(token.UpdatedAt, 0).UTC(),
+		})
+	}
+	return infos, nil
+}
+
+// PurgePushTokens deletes all of a user's push tokens so their devices
+// register again, and returns how many were removed
+func (s *Service) PurgePushTokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	if s.pushTokens == nil {
+		return 0, ErrPushTokensNotConfigured
+	}
+
+	tokens, err := s.pushTokens.GetByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get push tokens: %w", err)
+	}
+	if err := s.pushTokens.DeleteByUserID(ctx, userID); err != nil {
+		return 0, fmt.Errorf("failed to purge push tokens: %w", err)
+	}
+	return len(tokens), nil
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/push"
+)
+
+// fakeTokenRepository keeps push tokens in memory
+type fakeTokenRepository struct {
+	push.TokenRepository
+	tokens map[uuid.UUID][]*push.Token
+}
+
+func (f *fakeTokenRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*push.Token, error) {
+	return f.tokens[userID], nil
+}
+
+func (f *fakeTokenRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	delete(f.tokens, userID)
+	return nil
+}
+
+func TestListPushTokens_MasksTokenValues(t *testing.T) {
+	userID := uuid.New()
+	seen := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeTokenRepository{tokens: map[uuid.UUID][]*push.Token{
+		userID: {
+			{ID: uuid.New(), UserID: userID, Token: "fcm-token-abcdefghijklmnop-123456", Type: push.TokenTypeFCM, Platform: "android", Active: true, UpdatedAt: seen.Unix()},
+			{ID: uuid.New(), UserID: userID, Token: "short", Type: push.TokenTypeAPNs, Platform: "ios", Active: false},
+		},
+	}}
+	service := NewService(nil)
+	service.SetPushTokenRepository(repo)
+
+	tokens, err := service.ListPushTokens(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+
+	assert.Equal(t, "********123456", tokens[0].MaskedToken)
+	assert.True(t, tokens[0].Active)
+	assert.Equal(t, seen, tokens[0].LastSeenAt)
+	assert.Equal(t, "********", tokens[1].MaskedToken)
+	assert.False(t, tokens[1].Active)
+}
+
+func TestPurgePushTokens_ClearsUserTokens(t *testing.T) {
+	userID := uuid.New()
+	otherID := uuid.New()
+	repo := &fakeTokenRepository{tokens: map[uuid.UUID][]*push.Token{
+		userID:  {{Token: "a"}, {Token: "b"}},
+		otherID: {{Token: "c"}},
+	}}
+	service := NewService(nil)
+	service.SetPushTokenRepository(repo)
+
+	purged, err := service.PurgePushTokens(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	remaining, err := service.ListPushTokens(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+	assert.Len(t, repo.tokens[otherID], 1, "other users keep their tokens")
+}
+
+func TestPushTokens_NotConfigured(t *testing.T) {
+	_, err := NewService(nil).ListPushTokens(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrPushTokensNotConfigured)
+}
@@ -2,18 +2,38 @@ package admin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/repository/cockroach"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/pagination"
+	"secureconnect-backend/pkg/push"
 )
 
 // Service handles administrative business logic
 type Service struct {
-	adminRepo *cockroach.AdminRepository
+	adminRepo     *cockroach.AdminRepository
+	pushTokens    push.TokenRepository // nil until SetPushTokenRepository
+	auditSearch   AuditEventSearcher   // nil until SetAuditEventSearcher
+	auditRecorder AuditRecorder        // nil until SetAuditEventSearcher
+	publisher     Publisher            // nil until SetPublisher
+}
+
+// Publisher publishes pub/sub events
+type Publisher interface {
+	Publish(ctx context.Context, channel string, message interface{}) error
+}
+
+// SetPublisher lets BanUser ask the WebSocket hubs to disconnect the banned
+// user through domain.UserDisconnectChannel
+func (s *Service) SetPublisher(publisher Publisher) {
+	s.publisher = publisher
 }
 
 // NewService creates a new admin service
@@ -47,6 +67,11 @@ func (s *Service) GetUsers(ctx context.Context, req *domain.UserListRequest) (*d
 	if req.SortOrder == "" {
 		req.SortOrder = "DESC"
 	}
+	if req.Cursor == "" {
+		if err := pagination.ValidateOffset(req.Offset); err != nil {
+			return nil, err
+		}
+	}
 
 	users, err := s.adminRepo.GetUsers(ctx, req)
 	if err != nil {
@@ -66,9 +91,28 @@ func (s *Service) BanUser(ctx context.Context, adminID uuid.UUID, req *domain.Ba
 	if err != nil {
 		return fmt.Errorf("failed to ban user: %w", err)
 	}
+	s.disconnectUser(ctx, req.UserID, "banned")
 	return nil
 }
 
+// disconnectUser asks the WebSocket hubs to close the user's connections.
+// Failures are logged; the ban itself is already stored.
+func (s *Service) disconnectUser(ctx context.Context, userID uuid.UUID, reason string) {
+	if s.publisher == nil {
+		return
+	}
+	event, err := json.Marshal(&domain.UserDisconnectEvent{UserID: userID, Reason: reason})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, domain.UserDisconnectChannel, event); err != nil {
+		logger.Warn("Failed to disconnect user",
+			zap.String("user_id", userID.String()),
+			zap.String("reason", reason),
+			zap.Error(err))
+	}
+}
+
 // UnbanUser unbans a user
 func (s *Service) UnbanUser(ctx context.Context, adminID uuid.UUID, req *domain.UnbanUserRequest, ipAddress string) error {
 	err := s.adminRepo.UnbanUser(ctx, req, adminID, ipAddress)
@@ -78,6 +122,14 @@ func (s *Service) UnbanUser(ctx context.Context, adminID uuid.UUID, req *domain.
 	return nil
 }
 
+// LogAction records an admin action that is not audited by the repository itself
+func (s *Service) LogAction(ctx context.Context, adminID uuid.UUID, action, targetType string, targetID uuid.UUID, ipAddress, userAgent, details string) error {
+	if err := s.adminRepo.LogAction(ctx, adminID, action, targetType, targetID, ipAddress, userAgent, details); err != nil {
+		return fmt.Errorf("failed to log admin action: %w", err)
+	}
+	return nil
+}
+
 // GetAuditLogs retrieves audit logs
 func (s *Service) GetAuditLogs(ctx context.Context, req *domain.AuditLogRequest) ([]domain.AuditLog, int, error) {
 	// Set defaults
@@ -87,6 +139,9 @@ func (s *Service) GetAuditLogs(ctx context.Context, req *domain.AuditLogRequest)
 	if req.Limit > 200 {
 		req.Limit = 200
 	}
+	if err := pagination.ValidateOffset(req.Offset); err != nil {
+		return nil, 0, err
+	}
 
 	logs, totalCount, err := s.adminRepo.GetAuditLogs(ctx, req)
 	if err != nil {
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/audit"
+	"secureconnect-backend/pkg/logger"
+)
+
+// Error codes recorded on failed audit events
+const (
+	auditCodeInvalidCredentials = "INVALID_CREDENTIALS"
+	auditCodeAccountLocked      = "ACCOUNT_LOCKED"
+	auditCodeWeakPassword       = "WEAK_PASSWORD"
+	auditCodeUpdateFailed       = "UPDATE_FAILED"
+)
+
+// SetAuditLogger records logins, logouts, registrations and password resets
+// in the audit log
+func (s *Service) SetAuditLogger(auditLogger *audit.AuditLogger) {
+	s.auditLogger = auditLogger
+}
+
+// recordAudit writes an audit event through write. Audit failures are logged
+// and never fail the request being audited.
+func (s *Service) recordAudit(eventType audit.AuditEventType, write func(al *audit.AuditLogger) error) {
+	if s.auditLogger == nil {
+		return
+	}
+	if err := write(s.auditLogger); err != nil {
+		logger.Warn("Failed to write audit event",
+			zap.String("event_type", string(eventType)),
+			zap.Error(err))
+	}
+}
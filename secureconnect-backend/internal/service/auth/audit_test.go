@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/audit"
+)
+
+// storedAuditEvents returns today's audit events, oldest first
+func storedAuditEvents(t *testing.T, mr *miniredis.Miniredis) []*audit.AuditEvent {
+	t.Helper()
+	key := "audit:events:" + time.Now().UTC().Format("2006-01-02")
+	if !mr.Exists(key) {
+		return nil
+	}
+	members, err := mr.List(key)
+	require.NoError(t, err)
+
+	events := make([]*audit.AuditEvent, 0, len(members))
+	for i := len(members) - 1; i >= 0; i-- { // LPUSH stores newest first
+		_, eventJSON, ok := strings.Cut(members[i], ":")
+		require.True(t, ok)
+		var event audit.AuditEvent
+		require.NoError(t, json.Unmarshal([]byte(eventJSON), &event))
+		events = append(events, &event)
+	}
+	return events
+}
+
+func TestAuditEvents_LoginAndLogout(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	service.SetAuditLogger(audit.NewAuditLogger(goredis.NewClient(&goredis.Options{Addr: mr.Addr()})))
+	store.On("GetFailedLoginAttempt", ctx, mock.Anything).Return(nil, nil)
+	store.On("SetFailedLoginAttempt", ctx, mock.Anything, mock.Anything).Return(nil)
+
+	const ip, userAgent = "203.0.113.5", "SecureConnect/2.0 (iOS)"
+	_, err := service.Login(ctx, &LoginInput{Email: "alice@example.com", Password: "wrong-password", IP: ip, UserAgent: userAgent})
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	login, err := service.Login(ctx, &LoginInput{Email: "alice@example.com", Password: "password123", IP: ip, UserAgent: userAgent})
+	require.NoError(t, err)
+
+	sessionID := store.sessionIDs[len(store.sessionIDs)-1]
+	require.NoError(t, service.Logout(ctx, &LogoutInput{SessionID: sessionID, UserID: user.UserID, Token: login.AccessToken, IP: ip, UserAgent: userAgent}))
+
+	events := storedAuditEvents(t, mr)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, audit.EventLoginFailed, events[0].EventType)
+	assert.False(t, events[0].Success)
+	assert.Equal(t, "INVALID_CREDENTIALS", events[0].ErrorCode)
+	assert.Equal(t, "alice@example.com", events[0].Resource)
+
+	assert.Equal(t, audit.EventLoginSuccess, events[1].EventType)
+	assert.True(t, events[1].Success)
+	require.NotNil(t, events[1].UserID)
+	assert.Equal(t, user.UserID, *events[1].UserID)
+
+	assert.Equal(t, audit.EventLogout, events[2].EventType)
+	assert.True(t, events[2].Success)
+
+	for _, event := range events {
+		assert.Equal(t, ip, event.IPAddress)
+		assert.Equal(t, userAgent, event.UserAgent)
+	}
+}
+
+func TestAuditEvents_Register(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	service.SetAuditLogger(audit.NewAuditLogger(goredis.NewClient(&goredis.Options{Addr: mr.Addr()})))
+
+	userRepo := service.userRepo.(*MockUserRepository)
+	dirRepo := service.directoryRepo.(*MockDirectoryRepository)
+	userRepo.On("EmailExists", ctx, "bob@example.com").Return(false, nil)
+	userRepo.On("UsernameExists", ctx, "bob").Return(false, nil)
+	userRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Return(nil)
+	dirRepo.On("SetEmailToUserID", ctx, "bob@example.com", mock.Anything).Return(nil)
+	dirRepo.On("SetUsernameToUserID", ctx, "bob", mock.Anything).Return(nil)
+
+	output, err := service.Register(ctx, &RegisterInput{
+		Email:       "bob@example.com",
+		Username:    "bob",
+		Password:    "password123",
+		DisplayName: "Bob",
+		IP:          "198.51.100.20",
+		UserAgent:   "Mozilla/5.0",
+	})
+	require.NoError(t, err)
+	require.Len(t, store.sessionIDs, 1)
+
+	events := storedAuditEvents(t, mr)
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.EventRegister, events[0].EventType)
+	assert.True(t, events[0].Success)
+	require.NotNil(t, events[0].UserID)
+	assert.Equal(t, output.User.UserID, *events[0].UserID)
+	assert.Equal(t, "198.51.100.20", events[0].IPAddress)
+	assert.Equal(t, "Mozilla/5.0", events[0].UserAgent)
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"secureconnect-backend/pkg/sanitize"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomainsList string
+
+// disposableDomains is the embedded set of throwaway email providers
+var disposableDomains = parseList(disposableDomainsList)
+
+// parseList parses a newline separated list of domains or passwords into a
+// lowercase set, skipping blanks and comments
+func parseList(list string) map[string]struct{} {
+	domains := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = struct{}{}
+	}
+	return domains
+}
+
+// normalizeEmail returns the canonical form of an email for storage and lookups
+func (s *Service) normalizeEmail(email string) string {
+	return sanitize.NormalizeEmail(email, s.stripGmailAliases)
+}
+
+// SetEmailNormalization enables folding Gmail dot and plus-tag aliases into a
+// single canonical address
+func (s *Service) SetEmailNormalization(stripGmailAliases bool) {
+	s.stripGmailAliases = stripGmailAliases
+}
+
+// emailDomain returns the part of a normalized email after the last '@'
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return email[at+1:]
+}
+
+// SetEmailDomainPolicy restricts registration to the allowed domains (all domains
+// when empty) and optionally rejects known disposable email providers
+func (s *Service) SetEmailDomainPolicy(allowedDomains []string, blockDisposable bool) {
+	s.allowedEmailDomains = nil
+	if len(allowedDomains) > 0 {
+		s.allowedEmailDomains = parseList(strings.Join(allowedDomains, "\n"))
+	}
+	s.blockDisposableEmails = blockDisposable
+}
+
+// checkEmailDomain enforces the email domain policy on a normalized email
+func (s *Service) checkEmailDomain(email string) error {
+	domain := emailDomain(email)
+	if domain == "" {
+		return fmt.Errorf("invalid email address")
+	}
+
+	if len(s.allowedEmailDomains) > 0 {
+		if _, ok := s.allowedEmailDomains[domain]; !ok {
+			return fmt.Errorf("email domain %q is not allowed for registration", domain)
+		}
+	}
+
+	if s.blockDisposableEmails {
+		if _, ok := disposableDomains[domain]; ok {
+			return fmt.Errorf("disposable email addresses are not allowed")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/email"
+	"secureconnect-backend/pkg/logger"
+)
+
+// LockoutNotifier emails users whose account was locked by failed logins
+type LockoutNotifier interface {
+	SendAccountLockedEmail(ctx context.Context, to string, data *email.AccountLockedEmailData) error
+}
+
+// LockoutNoticeRepository remembers which locks the owner was already told
+// about, so repeated locks do not flood their inbox
+type LockoutNoticeRepository interface {
+	MarkLockoutNotified(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// SetLockoutNotification emails the account owner when failed logins lock
+// their account, at most once per lock period. Without it no email is sent.
+func (s *Service) SetLockoutNotification(notifier LockoutNotifier, notices LockoutNoticeRepository) {
+	s.lockoutNotifier = notifier
+	s.lockoutNotices = notices
+}
+
+// notifyAccountLocked tells the owner of a just-locked account about it.
+// Failures are logged; the lock itself is already in place.
+func (s *Service) notifyAccountLocked(ctx context.Context, userID uuid.UUID, ip string, lockedUntil time.Time) {
+	if s.lockoutNotifier == nil || userID == uuid.Nil {
+		return
+	}
+
+	first, err := s.lockoutNotices.MarkLockoutNotified(ctx, userID.String(), constants.AccountLockDuration)
+	if err != nil {
+		logger.Warn("Failed to record lockout notice, email not sent",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return
+	}
+	if !first {
+		return
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		logger.Warn("Failed to get user for lockout notice",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return
+	}
+	if err := s.lockoutNotifier.SendAccountLockedEmail(ctx, user.Email, &email.AccountLockedEmailData{
+		Username:    user.Username,
+		IP:          ip,
+		AttemptedAt: time.Now(),
+		LockedUntil: lockedUntil,
+	}); err != nil {
+		logger.Warn("Failed to send account locked email",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+}
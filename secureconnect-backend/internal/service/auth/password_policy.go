@@ -0,0 +1,164 @@
+package auth
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"secureconnect-backend/pkg/constants"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+// Password rules reported by PasswordStrengthError
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleCommon           = "common_password"
+	PasswordRuleCharClasses      = "character_classes"
+	PasswordRuleContainsUsername = "contains_username"
+	PasswordRuleContainsEmail    = "contains_email"
+)
+
+// ErrWeakPassword is matched by every *PasswordStrengthError
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// PasswordStrengthError names the first password rule a password failed
+type PasswordStrengthError struct {
+	Rule    string
+	Message string
+}
+
+// Error implements the error interface
+func (e *PasswordStrengthError) Error() string {
+	return e.Message
+}
+
+// Code returns the API error code for the failed rule, for example
+// WEAK_PASSWORD_COMMON_PASSWORD
+func (e *PasswordStrengthError) Code() string {
+	return "WEAK_PASSWORD_" + strings.ToUpper(e.Rule)
+}
+
+// Is reports whether target is ErrWeakPassword
+func (e *PasswordStrengthError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// PasswordPolicy decides which new passwords are accepted
+type PasswordPolicy struct {
+	minLength      int
+	minCharClasses int
+	denylist       map[string]struct{}
+}
+
+// NewPasswordPolicy creates a policy requiring at least minCharClasses of
+// lowercase, uppercase, digits and symbols (clamped to 0-4). denylistPath
+// names a file of common passwords, one per line; empty uses the built-in list.
+func NewPasswordPolicy(minCharClasses int, denylistPath string) (*PasswordPolicy, error) {
+	list := commonPasswordsList
+	if denylistPath != "" {
+		data, err := os.ReadFile(denylistPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password denylist: %w", err)
+		}
+		list = string(data)
+	}
+	if minCharClasses < 0 {
+		minCharClasses = 0
+	}
+	if minCharClasses > 4 {
+		minCharClasses = 4
+	}
+	return &PasswordPolicy{
+		minLength:      constants.MinPasswordLength,
+		minCharClasses: minCharClasses,
+		denylist:       parseList(list),
+	}, nil
+}
+
+// ValidatePasswordStrength returns a *PasswordStrengthError naming the first
+// rule password fails. username and email are the account's, which the
+// password may not contain.
+func (p *PasswordPolicy) ValidatePasswordStrength(password, username, email string) error {
+	if len(password) < p.minLength {
+		return &PasswordStrengthError{
+			Rule:    PasswordRuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters", p.minLength),
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := p.denylist[lower]; ok {
+		return &PasswordStrengthError{
+			Rule:    PasswordRuleCommon,
+			Message: "password is too common, choose a less predictable one",
+		}
+	}
+
+	if classes := charClasses(password); classes < p.minCharClasses {
+		return &PasswordStrengthError{
+			Rule:    PasswordRuleCharClasses,
+			Message: fmt.Sprintf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.minCharClasses),
+		}
+	}
+
+	if username = strings.ToLower(username); len(username) >= constants.MinUsernameLength && strings.Contains(lower, username) {
+		return &PasswordStrengthError{
+			Rule:    PasswordRuleContainsUsername,
+			Message: "password must not contain your username",
+		}
+	}
+
+	localPart, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(localPart) >= constants.MinUsernameLength && strings.Contains(lower, localPart) {
+		return &PasswordStrengthError{
+			Rule:    PasswordRuleContainsEmail,
+			Message: "password must not contain your email address",
+		}
+	}
+
+	return nil
+}
+
+// charClasses counts which of lowercase, uppercase, digits and symbols appear
+func charClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// SetPasswordPolicy checks new passwords at registration and reset against
+// policy. Without one only the minimum length is enforced.
+func (s *Service) SetPasswordPolicy(policy *PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
+// validateNewPassword applies the password policy, or the minimum length
+// when none is set
+func (s *Service) validateNewPassword(password, username, email string) error {
+	if s.passwordPolicy != nil {
+		return s.passwordPolicy.ValidatePasswordStrength(password, username, email)
+	}
+	if len(password) < constants.MinPasswordLength {
+		return &PasswordStrengthError{
+			Rule:    PasswordRuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters", constants.MinPasswordLength),
+		}
+	}
+	return nil
+}
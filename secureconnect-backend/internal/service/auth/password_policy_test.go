@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/jwt"
+)
+
+func TestValidatePasswordStrength(t *testing.T) {
+	policy, err := NewPasswordPolicy(3, "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		wantRule string
+	}{
+		{"strong", "Tangerine-Lamp-42", ""},
+		{"too short", "Ab1!", PasswordRuleMinLength},
+		{"common", "Password123", PasswordRuleCommon},
+		{"too few classes", "tangerinelamp", PasswordRuleCharClasses},
+		{"contains username", "xAliceSmith-9", PasswordRuleContainsUsername},
+		{"contains email local part", "Asmith.work#1", PasswordRuleContainsEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.ValidatePasswordStrength(tt.password, "alicesmith", "asmith.work@example.com")
+			if tt.wantRule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var weak *PasswordStrengthError
+			require.True(t, errors.As(err, &weak))
+			assert.Equal(t, tt.wantRule, weak.Rule)
+			assert.ErrorIs(t, err, ErrWeakPassword)
+		})
+	}
+}
+
+func TestNewPasswordPolicy_DenylistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# site specific\nSecureConnect2024\n"), 0o600))
+
+	policy, err := NewPasswordPolicy(0, path)
+	require.NoError(t, err)
+
+	var weak *PasswordStrengthError
+	require.True(t, errors.As(policy.ValidatePasswordStrength("secureconnect2024", "bob", "bob@example.com"), &weak))
+	assert.Equal(t, PasswordRuleCommon, weak.Rule)
+	// The configured file replaces the built-in list
+	assert.NoError(t, policy.ValidatePasswordStrength("password123", "bob", "bob@example.com"))
+
+	_, err = NewPasswordPolicy(0, filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestRegister_RejectsWeakPassword(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), new(MockSessionRepository), new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	policy, err := NewPasswordPolicy(2, "")
+	require.NoError(t, err)
+	service.SetPasswordPolicy(policy)
+
+	output, err := service.Register(context.Background(), &RegisterInput{
+		Email:       "carol@example.com",
+		Username:    "carol",
+		Password:    "qwerty123",
+		DisplayName: "Carol",
+	})
+
+	assert.Nil(t, output)
+	var weak *PasswordStrengthError
+	require.True(t, errors.As(err, &weak))
+	assert.Equal(t, PasswordRuleCommon, weak.Rule)
+	assert.Equal(t, "WEAK_PASSWORD_COMMON_PASSWORD", weak.Code())
+	mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -3,23 +3,25 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/repository/cockroach"
 	"secureconnect-backend/internal/repository/redis"
+	"secureconnect-backend/pkg/audit"
 	"secureconnect-backend/pkg/constants"
 	"secureconnect-backend/pkg/email"
-	"secureconnect-backend/pkg/env"
 	"secureconnect-backend/pkg/jwt"
 	"secureconnect-backend/pkg/logger"
 	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/password"
 )
 
 // UserRepository interface
@@ -29,6 +31,7 @@ type UserRepository interface {
 	GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
 	UpdateStatus(ctx context.Context, userID uuid.UUID, status string) error
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
 	EmailExists(ctx context.Context, email string) (bool, error)
 	UsernameExists(ctx context.Context, username string) (bool, error)
 }
@@ -48,8 +51,17 @@ type SessionRepository interface {
 	DeleteSession(ctx context.Context, sessionID string, userID uuid.UUID) error
 	BlacklistToken(ctx context.Context, jti string, expiresAt time.Duration) error
 	IsTokenBlacklisted(ctx context.Context, jti string) (bool, error)
+	TrackAccessToken(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error
+	UntrackAccessTokens(ctx context.Context, userID uuid.UUID, jtis ...string) error
+	GetActiveAccessTokens(ctx context.Context, userID uuid.UUID) ([]redis.ActiveToken, error)
+	DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error
+	GetUserSessions(ctx context.Context, userID uuid.UUID) ([]*redis.Session, error)
+	StoreRefreshJTI(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error
+	MarkRefreshUsed(ctx context.Context, userID uuid.UUID, jti string) (redis.RefreshTokenState, error)
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
 	GetAccountLock(ctx context.Context, key string) (*redis.AccountLock, error)
 	LockAccount(ctx context.Context, key string, lockedUntil time.Time) error
+	UnlockAccount(ctx context.Context, key string) error
 	GetFailedLoginAttempts(ctx context.Context, key string) (int, error)
 	SetFailedLoginAttempts(ctx context.Context, key string, attempts int) error
 	GetFailedLoginAttempt(ctx context.Context, key string) (*redis.FailedLoginAttempt, error)
@@ -58,6 +70,22 @@ type SessionRepository interface {
 	IsDegraded() bool
 }
 
+// Login errors. ErrAccountLocked is returned before the password is checked,
+// so a locked account gives no hint whether a guess was right.
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account temporarily locked due to too many failed attempts")
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// exchanged is presented again. All of the user's tokens are revoked, since
+// either the client or an attacker holds a stolen copy.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// ErrSessionExpired is returned when a refresh would extend a session past
+// its absolute lifetime; the user must log in again
+var ErrSessionExpired = errors.New("session reached its maximum lifetime")
+
 // PresenceRepository interface
 type PresenceRepository interface {
 	SetUserOnline(ctx context.Context, userID uuid.UUID) error
@@ -85,6 +113,31 @@ type Service struct {
 	emailVerificationRepo EmailVerificationRepository
 	emailService          EmailService
 	jwtManager            *jwt.JWTManager
+	hasher                password.Hasher
+
+	// Registration email domain policy (see SetEmailDomainPolicy)
+	allowedEmailDomains   map[string]struct{}
+	blockDisposableEmails bool
+	stripGmailAliases     bool
+
+	// Two-factor authentication (see SetTOTP); nil disables it
+	totpRepo      TOTPRepository
+	mfaChallenges MFAChallengeRepository
+	totpIssuer    string
+
+	// New password rules (see SetPasswordPolicy); nil checks length only
+	passwordPolicy *PasswordPolicy
+
+	// Security audit log (see SetAuditLogger); nil disables it
+	auditLogger *audit.AuditLogger
+
+	// Account lock emails (see SetLockoutNotification); nil disables them
+	lockoutNotifier LockoutNotifier
+	lockoutNotices  LockoutNoticeRepository
+
+	// Refresh token lifetime extended on each refresh (see SetSlidingSessions);
+	// a zero Window keeps the fixed refresh token lifetime
+	sliding SlidingSessionConfig
 }
 
 // NewService creates a new auth service
@@ -105,15 +158,24 @@ func NewService(
 		emailVerificationRepo: emailVerificationRepo,
 		emailService:          emailService,
 		jwtManager:            jwtManager,
+		hasher:                password.DefaultHasher(),
 	}
 }
 
+// SetPasswordHasher sets the password hasher. Stored hashes it reports as
+// needing a rehash are upgraded on the user's next successful login.
+func (s *Service) SetPasswordHasher(hasher password.Hasher) {
+	s.hasher = hasher
+}
+
 // RegisterInput contains user registration data
 type RegisterInput struct {
 	Email       string
 	Username    string
 	Password    string
 	DisplayName string
+	IP          string // Client IP address for the audit log
+	UserAgent   string
 }
 
 // RegisterOutput contains registration result
@@ -125,7 +187,8 @@ type RegisterOutput struct {
 
 // Register creates a new user account
 func (s *Service) Register(ctx context.Context, input *RegisterInput) (*RegisterOutput, error) {
-	// 1. Validate input
+	// 1. Normalize and validate input
+	input.Email = s.normalizeEmail(input.Email)
 	if err := s.validateRegisterInput(input); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
@@ -149,7 +212,7 @@ func (s *Service) Register(ctx context.Context, input *RegisterInput) (*Register
 	}
 
 	// 4. Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	passwordHash, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -159,7 +222,7 @@ func (s *Service) Register(ctx context.Context, input *RegisterInput) (*Register
 		UserID:       uuid.New(),
 		Email:        input.Email,
 		Username:     input.Username,
-		PasswordHash: string(passwordHash),
+		PasswordHash: passwordHash,
 		DisplayName:  input.DisplayName,
 		Status:       "offline",
 		CreatedAt:    time.Now(),
@@ -181,30 +244,37 @@ func (s *Service) Register(ctx context.Context, input *RegisterInput) (*Register
 	}
 
 	// 8. Generate tokens
-	accessToken, err := s.jwtManager.GenerateAccessToken(user.UserID, user.Email, user.Username, "user")
+	accessToken, err := s.issueAccessToken(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.UserID)
+	now := time.Now()
+	refreshOpts := s.refreshTokenOptions(now, false, now)
+	refreshToken, err := s.issueRefreshToken(ctx, user.UserID, refreshOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
 	// 9. Store session in Redis
+	ttl := sessionTTL(refreshOpts)
 	session := &redis.Session{
 		SessionID:    uuid.New().String(),
 		UserID:       user.UserID,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		CreatedAt:    time.Now(),
-		ExpiresAt:    time.Now().Add(constants.SessionExpiry),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
 	}
 
-	if err := s.sessionRepo.CreateSession(ctx, session, constants.SessionExpiry); err != nil {
+	if err := s.sessionRepo.CreateSession(ctx, session, ttl); err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
+	s.recordAudit(audit.EventRegister, func(al *audit.AuditLogger) error {
+		return al.LogRegister(ctx, user.UserID, input.IP, input.UserAgent)
+	})
+
 	return &RegisterOutput{
 		User:         user.ToResponse(),
 		AccessToken:  accessToken,
@@ -214,20 +284,29 @@ func (s *Service) Register(ctx context.Context, input *RegisterInput) (*Register
 
 // LoginInput contains login credentials
 type LoginInput struct {
-	Email    string
-	Password string
-	IP       string // Client IP address for security tracking
+	Email     string
+	Password  string
+	IP        string // Client IP address for security tracking
+	UserAgent string // Shown in the user's session list
+	// RememberMe keeps an active session signed in for the longer
+	// "remember me" window when sliding sessions are enabled
+	RememberMe bool
 }
 
-// LoginOutput contains login result
+// LoginOutput contains login result. When MFARequired is set no tokens are
+// issued; the client exchanges MFAToken and a code with VerifyTOTP.
 type LoginOutput struct {
 	User         *domain.UserResponse
 	AccessToken  string
 	RefreshToken string
+	MFARequired  bool
+	MFAToken     string
 }
 
 // Login authenticates a user
 func (s *Service) Login(ctx context.Context, input *LoginInput) (*LoginOutput, error) {
+	input.Email = s.normalizeEmail(input.Email)
+
 	// 0. Check if account is locked (CRITICAL FIX #1)
 	locked, err := s.checkAccountLocked(ctx, input.Email)
 	if err != nil {
@@ -235,7 +314,8 @@ func (s *Service) Login(ctx context.Context, input *LoginInput) (*LoginOutput, e
 	}
 	if locked {
 		metrics.AuthAccountLockedTotal.Inc()
-		return nil, fmt.Errorf("account temporarily locked due to too many failed attempts")
+		s.auditLoginFailed(ctx, input, auditCodeAccountLocked)
+		return nil, ErrAccountLocked
 	}
 
 	// 1. Get user by email
@@ -245,19 +325,23 @@ func (s *Service) Login(ctx context.Context, input *LoginInput) (*LoginOutput, e
 		_ = s.recordFailedLogin(ctx, input.Email, input.IP, uuid.Nil)
 		metrics.AuthLoginFailedTotal.Inc()
 		metrics.AuthLoginFailedByIP.WithLabelValues(input.IP).Inc()
-		return nil, fmt.Errorf("invalid credentials")
+		s.auditLoginFailed(ctx, input, auditCodeInvalidCredentials)
+		return nil, ErrInvalidCredentials
 	}
 
 	// 2. Compare password
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
-	if err != nil {
+	if ok, err := s.hasher.Verify(user.PasswordHash, input.Password); err != nil || !ok {
 		// Record failed login attempt (CRITICAL FIX #1)
 		_ = s.recordFailedLogin(ctx, input.Email, input.IP, user.UserID)
 		metrics.AuthLoginFailedTotal.Inc()
 		metrics.AuthLoginFailedByIP.WithLabelValues(input.IP).Inc()
-		return nil, fmt.Errorf("invalid credentials")
+		s.auditLoginFailed(ctx, input, auditCodeInvalidCredentials)
+		return nil, ErrInvalidCredentials
 	}
 
+	// Upgrade the stored hash now that the plaintext is known to be correct
+	s.rehashPassword(ctx, user, input.Password)
+
 	// 3. Clear failed login attempts on success (CRITICAL FIX #1)
 	if err := s.clearFailedLoginAttempts(ctx, input.Email); err != nil {
 		// Log but don't fail - login succeeded
@@ -266,18 +350,43 @@ func (s *Service) Login(ctx context.Context, input *LoginInput) (*LoginOutput, e
 			zap.Error(err))
 	}
 
-	// 4. Generate tokens
-	accessToken, err := s.jwtManager.GenerateAccessToken(user.UserID, user.Email, user.Username, "user")
+	// 4. Users with two-factor authentication get a challenge instead of tokens
+	mfaRequired, err := s.requiresTOTP(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaRequired {
+		return s.startMFAChallenge(ctx, user.UserID)
+	}
+
+	return s.completeLogin(ctx, user, input.IP, input.UserAgent, input.RememberMe)
+}
+
+// auditLoginFailed records a failed login for the attempted email
+func (s *Service) auditLoginFailed(ctx context.Context, input *LoginInput, errorCode string) {
+	s.recordAudit(audit.EventLoginFailed, func(al *audit.AuditLogger) error {
+		return al.LogLoginFailed(ctx, input.Email, input.IP, input.UserAgent, errorCode, "")
+	})
+}
+
+// completeLogin issues tokens and a session for an authenticated user. ip and
+// userAgent describe the client in the user's session list; rememberMe picks
+// the session's sliding window.
+func (s *Service) completeLogin(ctx context.Context, user *domain.User, ip, userAgent string, rememberMe bool) (*LoginOutput, error) {
+	// Generate tokens
+	accessToken, err := s.issueAccessToken(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.UserID)
+	now := time.Now()
+	refreshOpts := s.refreshTokenOptions(now, rememberMe, now)
+	refreshToken, err := s.issueRefreshToken(ctx, user.UserID, refreshOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	// 5. Store session (with degraded mode support)
+	// Store session (with degraded mode support)
 	// DEGRADED MODE: When Redis is degraded, skip session storage but still allow login
 	if s.sessionRepo.IsDegraded() {
 		logger.Warn("Session storage skipped (Redis degraded)",
@@ -289,16 +398,23 @@ func (s *Service) Login(ctx context.Context, input *LoginInput) (*LoginOutput, e
 			UserID:       user.UserID,
 			AccessToken:  accessToken,
 			RefreshToken: refreshToken,
-			CreatedAt:    time.Now(),
-			ExpiresAt:    time.Now().Add(constants.SessionExpiry),
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(sessionTTL(refreshOpts)),
+			IP:           ip,
+			UserAgent:    userAgent,
+			RememberMe:   rememberMe,
 		}
 
-		if err := s.sessionRepo.CreateSession(ctx, session, constants.SessionExpiry); err != nil {
+		if err := s.sessionRepo.CreateSession(ctx, session, sessionTTL(refreshOpts)); err != nil {
 			return nil, fmt.Errorf("failed to create session: %w", err)
 		}
 	}
 
-	// 6. Update user status to online
+	s.recordAudit(audit.EventLoginSuccess, func(al *audit.AuditLogger) error {
+		return al.LogLoginSuccess(ctx, user.UserID, ip, userAgent)
+	})
+
+	// Update user status to online
 	if err := s.userRepo.UpdateStatus(ctx, user.UserID, "online"); err != nil {
 		// Non-critical, log but don't fail
 		return &LoginOutput{
@@ -337,38 +453,54 @@ func (s *Service) RefreshToken(ctx context.Context, input *RefreshTokenInput) (*
 		return nil, fmt.Errorf("invalid refresh token")
 	}
 
-	// 2. Get user to ensure they still exist
+	// 2. Reject tokens revoked by logout
+	blacklisted, err := s.sessionRepo.IsTokenBlacklisted(ctx, tokenID(claims, input.RefreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check refresh token: %w", err)
+	}
+	if blacklisted {
+		metrics.AuthRefreshTokenInvalidTotal.Inc()
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	// 3. Get user to ensure they still exist
 	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
 		metrics.AuthRefreshTokenInvalidTotal.Inc()
 		return nil, fmt.Errorf("user not found")
 	}
 
-	// 3. Blacklist old refresh token (HIGH FIX #1)
-	if claims.ID != "" {
-		expiresIn := time.Until(claims.ExpiresAt.Time)
-		if expiresIn > 0 {
-			if err := s.sessionRepo.BlacklistToken(ctx, claims.ID, expiresIn); err != nil {
-				logger.Warn("Failed to blacklist old refresh token",
-					zap.String("jti", claims.ID),
-					zap.Error(err))
-			} else {
-				metrics.AuthRefreshTokenBlacklistedTotal.Inc()
-			}
-		}
+	// 4. Rotate: each refresh token can be exchanged once
+	if err := s.exchangeRefreshToken(ctx, user.UserID, claims, input.RefreshToken); err != nil {
+		return nil, err
 	}
 
-	// 4. Generate new tokens
-	accessToken, err := s.jwtManager.GenerateAccessToken(user.UserID, user.Email, user.Username, "user")
+	// 5. Slide the session forward from now, but never past its absolute
+	// limit; tokens from before auth_time was tracked count from issuance
+	now := time.Now()
+	authTime := claims.IssuedAt.Time
+	if claims.AuthTime != nil {
+		authTime = claims.AuthTime.Time
+	}
+	refreshOpts := s.refreshTokenOptions(authTime, claims.RememberMe, now)
+	if !refreshOpts.ExpiresAt.IsZero() && !refreshOpts.ExpiresAt.After(now) {
+		metrics.AuthRefreshTokenInvalidTotal.Inc()
+		return nil, ErrSessionExpired
+	}
+
+	// 6. Generate new tokens
+	accessToken, err := s.issueAccessToken(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	newRefreshToken, err := s.jwtManager.GenerateRefreshToken(user.UserID)
+	newRefreshToken, err := s.issueRefreshToken(ctx, user.UserID, refreshOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
+	s.rotateSession(ctx, user.UserID, input.RefreshToken, accessToken, newRefreshToken, refreshOpts.ExpiresAt)
+
 	metrics.AuthRefreshTokenSuccessTotal.Inc()
 
 	return &RefreshTokenOutput{
@@ -377,53 +509,95 @@ func (s *Service) RefreshToken(ctx context.Context, input *RefreshTokenInput) (*
 	}, nil
 }
 
+// exchangeRefreshToken marks a refresh token as exchanged. Tokens issued
+// before refresh tokens carried a JTI have no record to mark; they stay valid
+// until they expire and are blacklisted here so they too are exchanged once.
+func (s *Service) exchangeRefreshToken(ctx context.Context, userID uuid.UUID, claims *jwt.Claims, token string) error {
+	if claims.ID == "" {
+		if err := s.sessionRepo.BlacklistToken(ctx, tokenID(claims, token), time.Until(claims.ExpiresAt.Time)); err != nil {
+			return fmt.Errorf("failed to check refresh token: %w", err)
+		}
+		return nil
+	}
+
+	state, err := s.sessionRepo.MarkRefreshUsed(ctx, userID, claims.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check refresh token: %w", err)
+	}
+	switch state {
+	case redis.RefreshTokenActive:
+		return nil
+	case redis.RefreshTokenUsed:
+		metrics.AuthRefreshTokenReuseTotal.Inc()
+		logger.Warn("Refresh token reuse detected, revoking all user tokens",
+			zap.String("user_id", userID.String()),
+			zap.String("jti", claims.ID))
+		if _, err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+			logger.Error("Failed to revoke tokens after refresh token reuse",
+				zap.String("user_id", userID.String()),
+				zap.Error(err))
+		}
+		return ErrRefreshTokenReused
+	default:
+		metrics.AuthRefreshTokenInvalidTotal.Inc()
+		return fmt.Errorf("invalid refresh token")
+	}
+}
+
+// tokenID returns the ID a token is blacklisted under: its JTI, or a hash of
+// the token for refresh tokens issued before they carried one
+func tokenID(claims *jwt.Claims, token string) string {
+	if claims.ID != "" {
+		return claims.ID
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "legacy:" + hex.EncodeToString(sum[:])
+}
+
+// LogoutInput identifies the session to end
+type LogoutInput struct {
+	SessionID string
+	UserID    uuid.UUID
+	Token     string // Access token presented with the request, blacklisted on logout
+	IP        string // Client IP address for the audit log
+	UserAgent string
+}
+
 // Logout invalidates user session and blacklists token
-func (s *Service) Logout(ctx context.Context, sessionID string, userID uuid.UUID, tokenString string) error {
+func (s *Service) Logout(ctx context.Context, input *LogoutInput) error {
 	// 1. Validate session belongs to user
-	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	session, err := s.sessionRepo.GetSession(ctx, input.SessionID)
 	if err != nil {
 		return fmt.Errorf("session not found: %w", err)
 	}
-	if session.UserID != userID {
+	if session.UserID != input.UserID {
 		return fmt.Errorf("unauthorized: session does not belong to user")
 	}
 
-	// 2. Blacklist refresh token (MEDIUM FIX #1)
-	if session.RefreshToken != "" {
-		refreshClaims, err := s.jwtManager.ValidateToken(session.RefreshToken)
-		if err == nil && refreshClaims.ID != "" {
-			expiresIn := time.Until(refreshClaims.ExpiresAt.Time)
-			if expiresIn > 0 {
-				if err := s.sessionRepo.BlacklistToken(ctx, refreshClaims.ID, expiresIn); err != nil {
-					logger.Warn("Failed to blacklist refresh token during logout",
-						zap.String("user_id", userID.String()),
-						zap.String("jti", refreshClaims.ID),
-						zap.Error(err))
-				} else {
-					metrics.AuthRefreshTokenBlacklistedTotal.Inc()
-				}
-			}
-		}
+	// 2. Blacklist refresh token (MEDIUM FIX #1), including one issued
+	// without a JTI before refresh tokens were tracked
+	if jti := s.revokeJWT(ctx, session.RefreshToken); jti != "" {
+		metrics.AuthRefreshTokenBlacklistedTotal.Inc()
 	}
 
 	// 3. Delete session
-	if err := s.sessionRepo.DeleteSession(ctx, sessionID, userID); err != nil {
+	if err := s.sessionRepo.DeleteSession(ctx, input.SessionID, input.UserID); err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
 	// 4. Update user status to offline in CockroachDB
-	if err := s.userRepo.UpdateStatus(ctx, userID, "offline"); err != nil {
+	if err := s.userRepo.UpdateStatus(ctx, input.UserID, "offline"); err != nil {
 		// Log but don't fail - session is already deleted
 		logger.Warn("Failed to update user status during logout",
-			zap.String("user_id", userID.String()),
+			zap.String("user_id", input.UserID.String()),
 			zap.Error(err))
 	}
 
 	// 5. Remove from presence in Redis
-	if err := s.presenceRepo.SetUserOffline(ctx, userID); err != nil {
+	if err := s.presenceRepo.SetUserOffline(ctx, input.UserID); err != nil {
 		// Log but don't fail - session is already deleted
 		logger.Warn("Failed to update user presence during logout",
-			zap.String("user_id", userID.String()),
+			zap.String("user_id", input.UserID.String()),
 			zap.Error(err))
 	}
 
@@ -431,7 +605,7 @@ func (s *Service) Logout(ctx context.Context, sessionID string, userID uuid.UUID
 	// We parse unverified because we trust the source (AuthMiddleware already validated signature)
 	// or even if we don't, we just want to block THIS string.
 	// However, extracting claims is safer.
-	claims, err := s.jwtManager.ValidateToken(tokenString)
+	claims, err := s.jwtManager.ValidateToken(input.Token)
 	if err == nil && claims.ID != "" {
 		// Calculate remaining time
 		expiresIn := time.Until(claims.ExpiresAt.Time)
@@ -439,20 +613,144 @@ func (s *Service) Logout(ctx context.Context, sessionID string, userID uuid.UUID
 			if err := s.sessionRepo.BlacklistToken(ctx, claims.ID, expiresIn); err != nil {
 				// Log but don't fail, session is already deleted
 				logger.Warn("Failed to blacklist token during logout",
-					zap.String("user_id", userID.String()),
+					zap.String("user_id", input.UserID.String()),
 					zap.String("jti", claims.ID),
 					zap.Error(err))
 			} else {
 				metrics.AuthTokenBlacklistedTotal.Inc()
 			}
 		}
+		// Only this token leaves the user's set; other sessions keep theirs
+		if err := s.sessionRepo.UntrackAccessTokens(ctx, input.UserID, claims.ID); err != nil {
+			logger.Warn("Failed to untrack token during logout",
+				zap.String("user_id", input.UserID.String()),
+				zap.Error(err))
+		}
 	}
 
 	metrics.AuthLogoutTotal.Inc()
+	s.recordAudit(audit.EventLogout, func(al *audit.AuditLogger) error {
+		return al.LogLogout(ctx, input.UserID, input.IP, input.UserAgent)
+	})
 
 	return nil
 }
 
+// rehashPassword replaces user's password hash if the hasher asks for it. A
+// failure only means the upgrade is retried on the next login.
+func (s *Service) rehashPassword(ctx context.Context, user *domain.User, plaintext string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	passwordHash, err := s.hasher.Hash(plaintext)
+	if err == nil {
+		err = s.userRepo.UpdatePasswordHash(ctx, user.UserID, passwordHash)
+	}
+	if err != nil {
+		logger.Warn("Failed to rehash password",
+			zap.String("user_id", user.UserID.String()),
+			zap.Error(err))
+		return
+	}
+	user.PasswordHash = passwordHash
+	metrics.AuthPasswordRehashedTotal.Inc()
+}
+
+// issueAccessToken generates an access token for user and records its JTI in
+// the user's token set so RevokeAllUserTokens can reach it. Failing to record
+// it does not fail the login; the token then only ends by expiring or by its
+// own logout.
+func (s *Service) issueAccessToken(ctx context.Context, user *domain.User) (string, error) {
+	accessToken, err := s.jwtManager.GenerateAccessToken(user.UserID, user.Email, user.Username, "user")
+	if err != nil {
+		return "", err
+	}
+
+	if s.sessionRepo.IsDegraded() {
+		return accessToken, nil
+	}
+	claims, err := s.jwtManager.ValidateToken(accessToken)
+	if err != nil {
+		return "", err
+	}
+	if err := s.sessionRepo.TrackAccessToken(ctx, user.UserID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		logger.Warn("Failed to track access token",
+			zap.String("user_id", user.UserID.String()),
+			zap.Error(err))
+	}
+	return accessToken, nil
+}
+
+// issueRefreshToken generates a refresh token and records its JTI as active so
+// RefreshToken can exchange it once. Failing to record it does not fail the
+// login; the token then cannot be refreshed and the user logs in again.
+func (s *Service) issueRefreshToken(ctx context.Context, userID uuid.UUID, opts jwt.RefreshTokenOptions) (string, error) {
+	refreshToken, err := s.jwtManager.GenerateRefreshTokenWithOptions(userID, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if s.sessionRepo.IsDegraded() {
+		return refreshToken, nil
+	}
+	claims, err := s.jwtManager.ValidateToken(refreshToken)
+	if err != nil {
+		return "", err
+	}
+	if err := s.sessionRepo.StoreRefreshJTI(ctx, userID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		logger.Warn("Failed to record refresh token",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+	return refreshToken, nil
+}
+
+// RevokeAllUserTokens logs a user out everywhere: every tracked access token is
+// blacklisted for the rest of its lifetime, every refresh token is revoked and
+// every session is deleted. It returns the number of access tokens revoked.
+func (s *Service) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	tokens, err := s.sessionRepo.GetActiveAccessTokens(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get active tokens: %w", err)
+	}
+
+	revoked := make([]string, 0, len(tokens))
+	var blacklistErr error
+	for _, token := range tokens {
+		expiresIn := time.Until(token.ExpiresAt)
+		if expiresIn <= 0 {
+			continue
+		}
+		if err := s.sessionRepo.BlacklistToken(ctx, token.JTI, expiresIn); err != nil {
+			// Keep going so one failure does not leave the remaining tokens valid
+			blacklistErr = err
+			continue
+		}
+		revoked = append(revoked, token.JTI)
+		metrics.AuthTokenBlacklistedTotal.Inc()
+	}
+
+	if err := s.sessionRepo.UntrackAccessTokens(ctx, userID, revoked...); err != nil {
+		logger.Warn("Failed to untrack revoked tokens",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+	if err := s.sessionRepo.RevokeUserTokens(ctx, userID); err != nil {
+		return len(revoked), fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.sessionRepo.DeleteAllUserSessions(ctx, userID); err != nil {
+		return len(revoked), fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	if blacklistErr != nil {
+		return len(revoked), fmt.Errorf("failed to blacklist token: %w", blacklistErr)
+	}
+
+	logger.Info("Revoked all user tokens",
+		zap.String("user_id", userID.String()),
+		zap.Int("tokens", len(revoked)))
+	return len(revoked), nil
+}
+
 // IsTokenRevoked checks if a token has been blacklisted
 func (s *Service) IsTokenRevoked(ctx context.Context, tokenString string) (bool, error) {
 	// Extract JTI
@@ -477,11 +775,14 @@ func (s *Service) validateRegisterInput(input *RegisterInput) error {
 	if input.Email == "" {
 		return fmt.Errorf("email is required")
 	}
+	if err := s.checkEmailDomain(input.Email); err != nil {
+		return err
+	}
 	if input.Username == "" || len(input.Username) < constants.MinUsernameLength {
 		return fmt.Errorf("username must be at least %d characters", constants.MinUsernameLength)
 	}
-	if input.Password == "" || len(input.Password) < constants.MinPasswordLength {
-		return fmt.Errorf("password must be at least %d characters", constants.MinPasswordLength)
+	if err := s.validateNewPassword(input.Password, input.Username, input.Email); err != nil {
+		return err
 	}
 	if input.DisplayName == "" {
 		return fmt.Errorf("display name is required")
@@ -498,12 +799,20 @@ type FailedLoginAttempt struct {
 	LockedUntil *time.Time
 }
 
+// failedLoginKey is the Redis key holding the failed login record for an email
+func failedLoginKey(email string) string {
+	return fmt.Sprintf("failed_login:%s", email)
+}
+
+// accountLockKey is the Redis key holding the lock for an email
+func accountLockKey(email string) string {
+	return fmt.Sprintf("account_lock:%s", email)
+}
+
 // checkAccountLocked checks if an account is locked
 func (s *Service) checkAccountLocked(ctx context.Context, email string) (bool, error) {
-	key := fmt.Sprintf("failed_login:%s", email)
-
 	// Check if account is locked
-	locked, err := s.sessionRepo.GetAccountLock(ctx, key)
+	locked, err := s.sessionRepo.GetAccountLock(ctx, accountLockKey(email))
 	if err != nil {
 		return false, fmt.Errorf("failed to check account lock: %w", err)
 	}
@@ -517,15 +826,18 @@ func (s *Service) checkAccountLocked(ctx context.Context, email string) (bool, e
 
 // recordFailedLogin records a failed login attempt
 func (s *Service) recordFailedLogin(ctx context.Context, email, ip string, userID uuid.UUID) error {
-	key := fmt.Sprintf("failed_login:%s", email)
+	key := failedLoginKey(email)
 
 	// Get current attempts
-	attempts, err := s.sessionRepo.GetFailedLoginAttempts(ctx, key)
+	previous, err := s.sessionRepo.GetFailedLoginAttempt(ctx, key)
 	if err != nil {
 		return fmt.Errorf("failed to get login attempts: %w", err)
 	}
 
-	attempts++
+	attempts := 1
+	if previous != nil {
+		attempts = previous.Attempts + 1
+	}
 
 	// Check if should lock account
 	if attempts >= constants.MaxFailedLoginAttempts {
@@ -541,9 +853,10 @@ func (s *Service) recordFailedLogin(ctx context.Context, email, ip string, userI
 		if err := s.sessionRepo.SetFailedLoginAttempt(ctx, key, attempt); err != nil {
 			return fmt.Errorf("failed to set failed login attempt: %w", err)
 		}
-		if err := s.sessionRepo.LockAccount(ctx, key, lockedUntil); err != nil {
+		if err := s.sessionRepo.LockAccount(ctx, accountLockKey(email), lockedUntil); err != nil {
 			return fmt.Errorf("failed to lock account: %w", err)
 		}
+		s.notifyAccountLocked(ctx, userID, ip, lockedUntil)
 	} else {
 		// Update attempts with IP information
 		attempt := &redis.FailedLoginAttempt{
@@ -560,6 +873,59 @@ func (s *Service) recordFailedLogin(ctx context.Context, email, ip string, userI
 	return nil
 }
 
+// LockStatus describes the failed login state of an account
+type LockStatus struct {
+	Email        string     `json:"email"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"` // nil when no account was seen for the email
+	Locked       bool       `json:"locked"`
+	Attempts     int        `json:"attempts"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	LastFailedIP string     `json:"last_failed_ip,omitempty"`
+}
+
+// GetLockStatus returns the failed login attempts and lock state for an email
+func (s *Service) GetLockStatus(ctx context.Context, email string) (*LockStatus, error) {
+	status := &LockStatus{Email: email}
+
+	attempt, err := s.sessionRepo.GetFailedLoginAttempt(ctx, failedLoginKey(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if attempt != nil {
+		if attempt.UserID != uuid.Nil {
+			userID := attempt.UserID
+			status.UserID = &userID
+		}
+		status.Attempts = attempt.Attempts
+		status.LastFailedIP = attempt.IP
+	}
+
+	lock, err := s.sessionRepo.GetAccountLock(ctx, accountLockKey(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check account lock: %w", err)
+	}
+	if lock != nil && time.Now().Before(lock.LockedUntil) {
+		lockedUntil := lock.LockedUntil
+		status.Locked = true
+		status.LockedUntil = &lockedUntil
+	}
+
+	return status, nil
+}
+
+// ClearLock removes the lock and resets failed login attempts for an email
+func (s *Service) ClearLock(ctx context.Context, email string) error {
+	if err := s.sessionRepo.UnlockAccount(ctx, accountLockKey(email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if err := s.sessionRepo.DeleteFailedLoginAttempts(ctx, failedLoginKey(email)); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+
+	logger.Info("Account lock cleared", zap.String("email", email))
+	return nil
+}
+
 // RequestPasswordResetInput contains data for password reset request
 type RequestPasswordResetInput struct {
 	Email string
@@ -567,6 +933,8 @@ type RequestPasswordResetInput struct {
 
 // RequestPasswordReset initiates password reset flow
 func (s *Service) RequestPasswordReset(ctx context.Context, input *RequestPasswordResetInput) error {
+	input.Email = s.normalizeEmail(input.Email)
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, input.Email)
 	if err != nil {
@@ -599,7 +967,6 @@ func (s *Service) RequestPasswordReset(ctx context.Context, input *RequestPasswo
 	err = s.emailService.SendPasswordResetEmail(ctx, user.Email, &email.PasswordResetEmailData{
 		Username: user.Username,
 		Token:    token,
-		AppURL:   env.GetString("APP_URL", "http://localhost:9090"),
 	})
 	if err != nil {
 		logger.Error("Failed to send password reset email",
@@ -621,6 +988,8 @@ func (s *Service) RequestPasswordReset(ctx context.Context, input *RequestPasswo
 type ResetPasswordInput struct {
 	Token       string
 	NewPassword string
+	IP          string // Client IP address for the audit log
+	UserAgent   string
 }
 
 // ResetPassword completes password reset flow
@@ -658,8 +1027,13 @@ func (s *Service) ResetPassword(ctx context.Context, input *ResetPasswordInput)
 		return fmt.Errorf("user not found")
 	}
 
+	if err := s.validateNewPassword(input.NewPassword, user.Username, user.Email); err != nil {
+		s.auditPasswordReset(ctx, user.UserID, input, auditCodeWeakPassword)
+		return err
+	}
+
 	// Hash new password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	passwordHash, err := s.hasher.Hash(input.NewPassword)
 	if err != nil {
 		logger.Error("Failed to hash new password",
 			zap.String("user_id", user.UserID.String()),
@@ -668,13 +1042,12 @@ func (s *Service) ResetPassword(ctx context.Context, input *ResetPasswordInput)
 	}
 
 	// Update user password
-	user.PasswordHash = string(passwordHash)
-	user.UpdatedAt = time.Now()
-	err = s.userRepo.Update(ctx, user)
+	err = s.userRepo.UpdatePasswordHash(ctx, user.UserID, passwordHash)
 	if err != nil {
 		logger.Error("Failed to update user password",
 			zap.String("user_id", user.UserID.String()),
 			zap.Error(err))
+		s.auditPasswordReset(ctx, user.UserID, input, auditCodeUpdateFailed)
 		return fmt.Errorf("failed to update password")
 	}
 
@@ -690,10 +1063,19 @@ func (s *Service) ResetPassword(ctx context.Context, input *ResetPasswordInput)
 
 	logger.Info("Password reset completed",
 		zap.String("user_id", user.UserID.String()))
+	s.auditPasswordReset(ctx, user.UserID, input, "")
 
 	return nil
 }
 
+// auditPasswordReset records a password reset as a password change; an empty
+// errorCode means it succeeded
+func (s *Service) auditPasswordReset(ctx context.Context, userID uuid.UUID, input *ResetPasswordInput, errorCode string) {
+	s.recordAudit(audit.EventPasswordChange, func(al *audit.AuditLogger) error {
+		return al.LogPasswordChange(ctx, userID, input.IP, input.UserAgent, errorCode == "", errorCode)
+	})
+}
+
 // generateToken generates a random token
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -714,9 +1096,7 @@ func maskToken(token string) string {
 
 // clearFailedLoginAttempts clears failed login attempts on successful login
 func (s *Service) clearFailedLoginAttempts(ctx context.Context, email string) error {
-	key := fmt.Sprintf("failed_login:%s", email)
-
-	if err := s.sessionRepo.DeleteFailedLoginAttempts(ctx, key); err != nil {
+	if err := s.sessionRepo.DeleteFailedLoginAttempts(ctx, failedLoginKey(email)); err != nil {
 		// Log but don't fail
 		logger.Warn("Failed to clear failed login attempts",
 			zap.String("email", email),
@@ -2,18 +2,25 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	gojwt "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/repository/cockroach"
 	"secureconnect-backend/internal/repository/redis"
+	"secureconnect-backend/pkg/constants"
 	"secureconnect-backend/pkg/email"
 	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/password"
 )
 
 // Mocks
@@ -52,6 +59,11 @@ func (m *MockUserRepository) UpdateStatus(ctx context.Context, userID uuid.UUID,
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
 	args := m.Called(ctx, email)
 	return args.Bool(0), args.Error(1)
@@ -118,6 +130,52 @@ func (m *MockSessionRepository) IsTokenBlacklisted(ctx context.Context, jti stri
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockSessionRepository) TrackAccessToken(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) UntrackAccessTokens(ctx context.Context, userID uuid.UUID, jtis ...string) error {
+	args := m.Called(ctx, userID, jtis)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetActiveAccessTokens(ctx context.Context, userID uuid.UUID) ([]redis.ActiveToken, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]redis.ActiveToken), args.Error(1)
+}
+
+func (m *MockSessionRepository) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]*redis.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*redis.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) StoreRefreshJTI(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) MarkRefreshUsed(ctx context.Context, userID uuid.UUID, jti string) (redis.RefreshTokenState, error) {
+	args := m.Called(ctx, userID, jti)
+	return args.Get(0).(redis.RefreshTokenState), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func (m *MockSessionRepository) GetAccountLock(ctx context.Context, key string) (*redis.AccountLock, error) {
 	args := m.Called(ctx, key)
 	if args.Get(0) == nil {
@@ -159,6 +217,15 @@ func (m *MockSessionRepository) DeleteFailedLoginAttempts(ctx context.Context, k
 	return args.Error(0)
 }
 
+func (m *MockSessionRepository) UnlockAccount(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) IsDegraded() bool {
+	return false
+}
+
 type MockPresenceRepository struct {
 	mock.Mock
 }
@@ -225,12 +292,14 @@ func TestRegister(t *testing.T) {
 	ctx := context.Background()
 
 	// Expectations
-	mockDirRepo.On("EmailExists", ctx, input.Email).Return(false, nil)
-	mockDirRepo.On("UsernameExists", ctx, input.Username).Return(false, nil)
+	mockUserRepo.On("EmailExists", ctx, input.Email).Return(false, nil)
+	mockUserRepo.On("UsernameExists", ctx, input.Username).Return(false, nil)
 	mockUserRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Return(nil)
 	mockDirRepo.On("SetEmailToUserID", ctx, input.Email, mock.AnythingOfType("uuid.UUID")).Return(nil)
 	mockDirRepo.On("SetUsernameToUserID", ctx, input.Username, mock.AnythingOfType("uuid.UUID")).Return(nil)
 	mockSessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*redis.Session"), mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
 
 	// Execute
 	output, err := service.Register(ctx, input)
@@ -267,7 +336,7 @@ func TestRegister_EmailExists(t *testing.T) {
 	ctx := context.Background()
 
 	// Expectations
-	mockDirRepo.On("EmailExists", ctx, input.Email).Return(true, nil)
+	mockUserRepo.On("EmailExists", ctx, input.Email).Return(true, nil)
 
 	// Execute
 	output, err := service.Register(ctx, input)
@@ -277,5 +346,905 @@ func TestRegister_EmailExists(t *testing.T) {
 	assert.Nil(t, output)
 	assert.Contains(t, err.Error(), "email already registered")
 
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestGetLockStatus_LockedAccount(t *testing.T) {
+	mockSessionRepo := new(MockSessionRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(new(MockUserRepository), new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	ctx := context.Background()
+	email := "locked@example.com"
+	userID := uuid.New()
+	lockedUntil := time.Now().Add(10 * time.Minute)
+
+	// Expectations
+	mockSessionRepo.On("GetFailedLoginAttempt", ctx, "failed_login:"+email).Return(&redis.FailedLoginAttempt{
+		UserID:      userID,
+		Email:       email,
+		IP:          "203.0.113.7",
+		Attempts:    5,
+		LockedUntil: &lockedUntil,
+	}, nil)
+	mockSessionRepo.On("GetAccountLock", ctx, "account_lock:"+email).Return(&redis.AccountLock{LockedUntil: lockedUntil}, nil)
+
+	// Execute
+	status, err := service.GetLockStatus(ctx, email)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.Equal(t, &userID, status.UserID)
+	assert.Equal(t, 5, status.Attempts)
+	assert.Equal(t, "203.0.113.7", status.LastFailedIP)
+	if assert.NotNil(t, status.LockedUntil) {
+		assert.True(t, status.LockedUntil.Equal(lockedUntil))
+	}
+
+	mockSessionRepo.AssertExpectations(t)
+}
+
+func TestGetLockStatus_UnknownEmail(t *testing.T) {
+	mockSessionRepo := new(MockSessionRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+	service := NewService(new(MockUserRepository), new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	ctx := context.Background()
+	email := "nobody@example.com"
+	mockSessionRepo.On("GetFailedLoginAttempt", ctx, "failed_login:"+email).Return(&redis.FailedLoginAttempt{Email: email, Attempts: 2}, nil)
+	mockSessionRepo.On("GetAccountLock", ctx, "account_lock:"+email).Return(nil, nil)
+
+	status, err := service.GetLockStatus(ctx, email)
+	require.NoError(t, err)
+	assert.Nil(t, status.UserID, "failed logins for unknown emails have no user")
+	assert.Equal(t, 2, status.Attempts)
+}
+
+func TestClearLock_RestoresLogin(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockUserRepo := new(MockUserRepository)
+	mockSessionRepo := new(MockSessionRepository)
+	mockPresenceRepo := new(MockPresenceRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), mockSessionRepo, mockPresenceRepo, new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	ctx := context.Background()
+	email := "locked@example.com"
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &domain.User{UserID: uuid.New(), Email: email, Username: "locked", PasswordHash: string(hash)}
+	input := &LoginInput{Email: email, Password: "password123", IP: "203.0.113.7"}
+
+	// Locked until cleared, then no lock
+	mockSessionRepo.On("GetAccountLock", ctx, "account_lock:"+email).
+		Return(&redis.AccountLock{LockedUntil: time.Now().Add(10 * time.Minute)}, nil).Once()
+	mockSessionRepo.On("GetAccountLock", ctx, "account_lock:"+email).Return(nil, nil)
+
+	output, err := service.Login(ctx, input)
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "account temporarily locked")
+
+	// Clear the lock
+	mockSessionRepo.On("UnlockAccount", ctx, "account_lock:"+email).Return(nil)
+	mockSessionRepo.On("DeleteFailedLoginAttempts", ctx, "failed_login:"+email).Return(nil)
+
+	assert.NoError(t, service.ClearLock(ctx, email))
+
+	// Login succeeds again
+	mockUserRepo.On("GetByEmail", ctx, email).Return(user, nil)
+	mockUserRepo.On("UpdatePasswordHash", ctx, user.UserID, mock.AnythingOfType("string")).Return(nil) // MinCost hash is upgraded to the default cost
+	mockUserRepo.On("UpdateStatus", ctx, user.UserID, "online").Return(nil)
+	mockSessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*redis.Session"), mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockPresenceRepo.On("SetUserOnline", ctx, user.UserID).Return(nil)
+
+	output, err = service.Login(ctx, input)
+	assert.NoError(t, err)
+	if assert.NotNil(t, output) {
+		assert.NotEmpty(t, output.AccessToken)
+	}
+
+	mockSessionRepo.AssertCalled(t, "UnlockAccount", ctx, "account_lock:"+email)
+	mockSessionRepo.AssertCalled(t, "DeleteFailedLoginAttempts", ctx, "failed_login:"+email)
+}
+
+func TestRegister_EmailDomainPolicy(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr string
+	}{
+		{"allowed domain", "alice@corp.example.com", ""},
+		{"allowed domain with different case", "Alice@CORP.example.com", ""},
+		{"disallowed domain", "alice@gmail.com", "is not allowed for registration"},
+		{"disposable domain", "alice@mailinator.com", "disposable email addresses are not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockDirRepo := new(MockDirectoryRepository)
+			mockSessionRepo := new(MockSessionRepository)
+
+			service := NewService(mockUserRepo, mockDirRepo, mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+			service.SetEmailDomainPolicy([]string{"corp.example.com", "mailinator.com"}, true)
+
+			ctx := context.Background()
+			input := &RegisterInput{
+				Email:       tt.email,
+				Username:    "alice",
+				Password:    "password123",
+				DisplayName: "Alice",
+			}
+
+			if tt.wantErr == "" {
+				mockUserRepo.On("EmailExists", ctx, "alice@corp.example.com").Return(false, nil)
+				mockUserRepo.On("UsernameExists", ctx, input.Username).Return(false, nil)
+				mockUserRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Return(nil)
+				mockDirRepo.On("SetEmailToUserID", ctx, "alice@corp.example.com", mock.AnythingOfType("uuid.UUID")).Return(nil)
+				mockDirRepo.On("SetUsernameToUserID", ctx, input.Username, mock.AnythingOfType("uuid.UUID")).Return(nil)
+				mockSessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*redis.Session"), mock.Anything).Return(nil)
+				mockSessionRepo.On("TrackAccessToken", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+				mockSessionRepo.On("StoreRefreshJTI", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+			}
+
+			output, err := service.Register(ctx, input)
+
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Nil(t, output)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRegister_NormalizesEmail(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockDirRepo := new(MockDirectoryRepository)
+	mockSessionRepo := new(MockSessionRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(mockUserRepo, mockDirRepo, mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	ctx := context.Background()
+	input := &RegisterInput{
+		Email:       "  Test.User@Example.COM ",
+		Username:    "testuser",
+		Password:    "password123",
+		DisplayName: "Test User",
+	}
+
+	// Expectations: every lookup and write uses the normalized email
+	mockUserRepo.On("EmailExists", ctx, "test.user@example.com").Return(false, nil)
+	mockUserRepo.On("UsernameExists", ctx, input.Username).Return(false, nil)
+	mockUserRepo.On("Create", ctx, mock.MatchedBy(func(u *domain.User) bool {
+		return u.Email == "test.user@example.com"
+	})).Return(nil)
+	mockDirRepo.On("SetEmailToUserID", ctx, "test.user@example.com", mock.AnythingOfType("uuid.UUID")).Return(nil)
+	mockDirRepo.On("SetUsernameToUserID", ctx, input.Username, mock.AnythingOfType("uuid.UUID")).Return(nil)
+	mockSessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*redis.Session"), mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+
+	output, err := service.Register(ctx, input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test.user@example.com", output.User.Email)
+	mockUserRepo.AssertExpectations(t)
 	mockDirRepo.AssertExpectations(t)
 }
+
+func TestRegister_CaseVariantEmailCollides(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), new(MockSessionRepository), new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	ctx := context.Background()
+
+	// user@example.com is already registered; the repository is only ever asked about the normalized form
+	mockUserRepo.On("EmailExists", ctx, "user@example.com").Return(true, nil)
+
+	for _, variant := range []string{"User@Example.com", "USER@EXAMPLE.COM", " user@example.com "} {
+		output, err := service.Register(ctx, &RegisterInput{
+			Email:       variant,
+			Username:    "newuser",
+			Password:    "password123",
+			DisplayName: "New User",
+		})
+		assert.Error(t, err, variant)
+		assert.Nil(t, output)
+		assert.Contains(t, err.Error(), "email already registered")
+	}
+
+	mockUserRepo.AssertNumberOfCalls(t, "EmailExists", 3)
+	mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestLogin_NormalizesEmail(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockSessionRepo := new(MockSessionRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+	service.SetEmailNormalization(true)
+
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &domain.User{UserID: uuid.New(), Email: "johndoe@gmail.com", Username: "johndoe", PasswordHash: string(hash)}
+
+	// A Gmail alias with different case resolves to the canonical account
+	mockSessionRepo.On("GetAccountLock", ctx, "account_lock:johndoe@gmail.com").Return(nil, nil)
+	mockSessionRepo.On("DeleteFailedLoginAttempts", ctx, "failed_login:johndoe@gmail.com").Return(nil)
+	mockSessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*redis.Session"), mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", ctx, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockUserRepo.On("GetByEmail", ctx, "johndoe@gmail.com").Return(user, nil)
+	mockUserRepo.On("UpdatePasswordHash", ctx, user.UserID, mock.AnythingOfType("string")).Return(nil) // MinCost hash is upgraded to the default cost
+	mockUserRepo.On("UpdateStatus", ctx, user.UserID, "online").Return(nil)
+
+	output, err := service.Login(ctx, &LoginInput{Email: "John.Doe+news@GoogleMail.com", Password: "password123"})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, output) {
+		assert.Equal(t, user.UserID, output.User.UserID)
+	}
+	mockUserRepo.AssertExpectations(t)
+}
+
+// fakeSessionStore keeps sessions, tracked tokens, refresh JTIs and the
+// blacklist in memory. Lock and failed-login calls fall through to the
+// embedded mock.
+type fakeSessionStore struct {
+	*MockSessionRepository
+	sessions    map[string]*redis.Session
+	sessionIDs  []string
+	tokens      map[uuid.UUID]map[string]time.Time
+	refresh     map[uuid.UUID]map[string]redis.RefreshTokenState
+	blacklisted map[string]bool
+}
+
+func newFakeSessionStore() *fakeSessionStore {
+	return &fakeSessionStore{
+		MockSessionRepository: new(MockSessionRepository),
+		sessions:              map[string]*redis.Session{},
+		tokens:                map[uuid.UUID]map[string]time.Time{},
+		refresh:               map[uuid.UUID]map[string]redis.RefreshTokenState{},
+		blacklisted:           map[string]bool{},
+	}
+}
+
+func (f *fakeSessionStore) StoreRefreshJTI(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	if f.refresh[userID] == nil {
+		f.refresh[userID] = map[string]redis.RefreshTokenState{}
+	}
+	f.refresh[userID][jti] = redis.RefreshTokenActive
+	return nil
+}
+
+func (f *fakeSessionStore) MarkRefreshUsed(ctx context.Context, userID uuid.UUID, jti string) (redis.RefreshTokenState, error) {
+	state := f.refresh[userID][jti]
+	if state == redis.RefreshTokenActive {
+		f.refresh[userID][jti] = redis.RefreshTokenUsed
+	}
+	return state, nil
+}
+
+func (f *fakeSessionStore) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	delete(f.refresh, userID)
+	return nil
+}
+
+func (f *fakeSessionStore) CreateSession(ctx context.Context, session *redis.Session, ttl time.Duration) error {
+	f.sessions[session.SessionID] = session
+	f.sessionIDs = append(f.sessionIDs, session.SessionID)
+	return nil
+}
+
+func (f *fakeSessionStore) GetSession(ctx context.Context, sessionID string) (*redis.Session, error) {
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, redis.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (f *fakeSessionStore) DeleteSession(ctx context.Context, sessionID string, userID uuid.UUID) error {
+	delete(f.sessions, sessionID)
+	return nil
+}
+
+func (f *fakeSessionStore) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error {
+	for id, session := range f.sessions {
+		if session.UserID == userID {
+			delete(f.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (f *fakeSessionStore) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]*redis.Session, error) {
+	var sessions []*redis.Session
+	for _, session := range f.sessions {
+		if session.UserID == userID {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeSessionStore) BlacklistToken(ctx context.Context, jti string, expiresAt time.Duration) error {
+	f.blacklisted[jti] = true
+	return nil
+}
+
+func (f *fakeSessionStore) IsTokenBlacklisted(ctx context.Context, jti string) (bool, error) {
+	return f.blacklisted[jti], nil
+}
+
+func (f *fakeSessionStore) TrackAccessToken(ctx context.Context, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	if f.tokens[userID] == nil {
+		f.tokens[userID] = map[string]time.Time{}
+	}
+	f.tokens[userID][jti] = expiresAt
+	return nil
+}
+
+func (f *fakeSessionStore) UntrackAccessTokens(ctx context.Context, userID uuid.UUID, jtis ...string) error {
+	for _, jti := range jtis {
+		delete(f.tokens[userID], jti)
+	}
+	return nil
+}
+
+func (f *fakeSessionStore) GetActiveAccessTokens(ctx context.Context, userID uuid.UUID) ([]redis.ActiveToken, error) {
+	var tokens []redis.ActiveToken
+	for jti, expiresAt := range f.tokens[userID] {
+		tokens = append(tokens, redis.ActiveToken{JTI: jti, ExpiresAt: expiresAt})
+	}
+	return tokens, nil
+}
+
+// newTestUser returns a user whose password is "password123"
+func newTestUser(t *testing.T) *domain.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return &domain.User{UserID: uuid.New(), Email: "alice@example.com", Username: "alice", PasswordHash: string(hash)}
+}
+
+// newSessionTestService returns a service where user can log in and whose
+// sessions are kept in store
+func newSessionTestService(t *testing.T, store *fakeSessionStore, user *domain.User) *Service {
+	t.Helper()
+	logger.InitDefault("test")
+	mockUserRepo := new(MockUserRepository)
+	mockPresenceRepo := new(MockPresenceRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), store, mockPresenceRepo, new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	store.On("GetAccountLock", mock.Anything, mock.Anything).Return(nil, nil)
+	store.On("DeleteFailedLoginAttempts", mock.Anything, mock.Anything).Return(nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("GetByID", mock.Anything, user.UserID).Return(user, nil).Maybe()
+	mockUserRepo.On("UpdatePasswordHash", mock.Anything, user.UserID, mock.AnythingOfType("string")).Return(nil).Maybe() // MinCost hash is upgraded to the default cost
+	mockUserRepo.On("UpdateStatus", mock.Anything, user.UserID, mock.Anything).Return(nil).Maybe()
+	mockPresenceRepo.On("SetUserOffline", mock.Anything, user.UserID).Return(nil).Maybe()
+	return service
+}
+
+// loginOnDevices logs user in n times, as if from n devices
+func loginOnDevices(t *testing.T, service *Service, user *domain.User, n int) []*LoginOutput {
+	t.Helper()
+	var logins []*LoginOutput
+	for i := 0; i < n; i++ {
+		output, err := service.Login(context.Background(), &LoginInput{Email: user.Email, Password: "password123"})
+		require.NoError(t, err)
+		logins = append(logins, output)
+	}
+	return logins
+}
+
+func TestLogout_DoesNotAffectOtherSessions(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	logins := loginOnDevices(t, service, user, 2)
+	ctx := context.Background()
+
+	require.NoError(t, service.Logout(ctx, &LogoutInput{SessionID: store.sessionIDs[0], UserID: user.UserID, Token: logins[0].AccessToken}))
+
+	revoked, err := service.IsTokenRevoked(ctx, logins[0].AccessToken)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = service.IsTokenRevoked(ctx, logins[1].AccessToken)
+	require.NoError(t, err)
+	assert.False(t, revoked, "the other device stays logged in")
+	assert.Contains(t, store.sessions, store.sessionIDs[1])
+	assert.Len(t, store.tokens[user.UserID], 1, "only the logged-out token leaves the set")
+}
+
+func TestRevokeEverySession(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(s *Service, ctx context.Context, userID uuid.UUID) (int, error)
+	}{
+		{name: "RevokeAllUserTokens", revoke: (*Service).RevokeAllUserTokens},
+		{name: "LogoutAll", revoke: (*Service).LogoutAll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeSessionStore()
+			user := newTestUser(t)
+			service := newSessionTestService(t, store, user)
+			logins := loginOnDevices(t, service, user, 2)
+			ctx := context.Background()
+
+			count, err := tt.revoke(service, ctx, user.UserID)
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+
+			for _, login := range logins {
+				revoked, err := service.IsTokenRevoked(ctx, login.AccessToken)
+				require.NoError(t, err)
+				assert.True(t, revoked)
+				_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: login.RefreshToken})
+				assert.Error(t, err)
+			}
+			assert.Empty(t, store.sessions)
+			assert.Empty(t, store.tokens[user.UserID])
+
+			count, err = tt.revoke(service, ctx, user.UserID)
+			require.NoError(t, err)
+			assert.Zero(t, count, "a second call has nothing left to revoke")
+		})
+	}
+}
+
+func TestRevokeSession_SignsOutOneDevice(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	logins := loginOnDevices(t, service, user, 2)
+	ctx := context.Background()
+
+	sessions, err := service.GetSessions(ctx, user.UserID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	assert.ErrorIs(t, service.RevokeSession(ctx, uuid.New(), store.sessionIDs[0]), ErrSessionNotFound, "another user's session")
+	require.NoError(t, service.RevokeSession(ctx, user.UserID, store.sessionIDs[0]))
+
+	revoked, err := service.IsTokenRevoked(ctx, logins[0].AccessToken)
+	require.NoError(t, err)
+	assert.True(t, revoked, "the session's access token is blacklisted")
+	_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: logins[0].RefreshToken})
+	assert.Error(t, err, "the session's refresh token is blacklisted")
+
+	revoked, err = service.IsTokenRevoked(ctx, logins[1].AccessToken)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	sessions, err = service.GetSessions(ctx, user.UserID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, store.sessionIDs[1], sessions[0].SessionID)
+}
+
+func TestRevokeSession_LookupErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		lookupErr    error
+		wantNotFound bool
+	}{
+		{name: "missing session", lookupErr: redis.ErrSessionNotFound, wantNotFound: true},
+		{name: "redis failure", lookupErr: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessionRepo := new(MockSessionRepository)
+			jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+			service := NewService(new(MockUserRepository), new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+			mockSessionRepo.On("GetSession", mock.Anything, "session-1").Return(nil, tt.lookupErr)
+
+			err := service.RevokeSession(context.Background(), uuid.New(), "session-1")
+			require.Error(t, err)
+			assert.Equal(t, tt.wantNotFound, errors.Is(err, ErrSessionNotFound))
+		})
+	}
+}
+
+func TestRevokeSession_ReachesRefreshedTokens(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	logins := loginOnDevices(t, service, user, 2)
+	ctx := context.Background()
+
+	refreshed, err := service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: logins[1].RefreshToken})
+	require.NoError(t, err)
+	require.NoError(t, service.RevokeSession(ctx, user.UserID, store.sessionIDs[1]))
+
+	revoked, err := service.IsTokenRevoked(ctx, refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: refreshed.RefreshToken})
+	assert.Error(t, err)
+}
+
+func TestRefreshToken_RotatesRefreshToken(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	logins := loginOnDevices(t, service, user, 2)
+	ctx := context.Background()
+
+	first, err := service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: logins[0].RefreshToken})
+	require.NoError(t, err)
+	assert.NotEqual(t, logins[0].RefreshToken, first.RefreshToken)
+
+	second, err := service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: first.RefreshToken})
+	require.NoError(t, err, "the rotated token is exchangeable once")
+	assert.NotEmpty(t, second.AccessToken)
+	assert.Len(t, store.sessions, 2, "sessions survive normal rotation")
+	assert.NotNil(t, store.refresh[user.UserID])
+}
+
+func TestRefreshToken_ReuseRevokesTokenFamily(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	logins := loginOnDevices(t, service, user, 2)
+	ctx := context.Background()
+
+	rotated, err := service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: logins[0].RefreshToken})
+	require.NoError(t, err)
+
+	// An attacker replays the stolen, already rotated token
+	_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: logins[0].RefreshToken})
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	assert.Empty(t, store.sessions, "every session is deleted")
+	for _, token := range []string{logins[0].AccessToken, logins[1].AccessToken, rotated.AccessToken} {
+		revoked, err := service.IsTokenRevoked(ctx, token)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	}
+	for _, refreshToken := range []string{rotated.RefreshToken, logins[1].RefreshToken} {
+		_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: refreshToken})
+		assert.Error(t, err, "no refresh token of the user survives")
+		assert.NotErrorIs(t, err, ErrRefreshTokenReused)
+	}
+	assert.Empty(t, store.refresh[user.UserID])
+}
+
+// legacyRefreshToken signs a refresh token without a JTI, as issued before
+// refresh tokens were tracked
+func legacyRefreshToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	now := time.Now()
+	token, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, &jwt.Claims{
+		UserID: userID,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ExpiresAt: gojwt.NewNumericDate(now.Add(24 * time.Hour)),
+			IssuedAt:  gojwt.NewNumericDate(now),
+			Subject:   userID.String(),
+		},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return token
+}
+
+func TestRefreshToken_LegacyTokenWithoutJTI(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("exchanged once until it expires", func(t *testing.T) {
+		store := newFakeSessionStore()
+		user := newTestUser(t)
+		service := newSessionTestService(t, store, user)
+		legacy := legacyRefreshToken(t, user.UserID)
+
+		refreshed, err := service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: legacy})
+		require.NoError(t, err, "tokens issued before rotation keep working")
+		claims, err := service.jwtManager.ValidateToken(refreshed.RefreshToken)
+		require.NoError(t, err)
+		assert.NotEmpty(t, claims.ID, "the replacement is tracked")
+
+		_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: legacy})
+		assert.Error(t, err, "the legacy token cannot be replayed")
+		_, err = service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: refreshed.RefreshToken})
+		assert.NoError(t, err)
+	})
+
+	t.Run("revoked by logout", func(t *testing.T) {
+		store := newFakeSessionStore()
+		user := newTestUser(t)
+		service := newSessionTestService(t, store, user)
+		legacy := legacyRefreshToken(t, user.UserID)
+		sessionID := uuid.New().String()
+		require.NoError(t, store.CreateSession(ctx, &redis.Session{
+			SessionID:    sessionID,
+			UserID:       user.UserID,
+			RefreshToken: legacy,
+			CreatedAt:    time.Now(),
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+		}, 24*time.Hour))
+
+		require.NoError(t, service.Logout(ctx, &LogoutInput{SessionID: sessionID, UserID: user.UserID}))
+		_, err := service.RefreshToken(ctx, &RefreshTokenInput{RefreshToken: legacy})
+		assert.Error(t, err, "logging out revokes a refresh token without a JTI")
+	})
+}
+
+// slidingSession stores a session whose refresh token continues a login made
+// at authTime and expires at expiresAt
+func slidingSession(t *testing.T, service *Service, store *fakeSessionStore, userID uuid.UUID, authTime, expiresAt time.Time) string {
+	t.Helper()
+	refreshToken, err := service.issueRefreshToken(context.Background(), userID, jwt.RefreshTokenOptions{AuthTime: authTime, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	require.NoError(t, store.CreateSession(context.Background(), &redis.Session{
+		SessionID:    uuid.New().String(),
+		UserID:       userID,
+		RefreshToken: refreshToken,
+		CreatedAt:    authTime,
+		ExpiresAt:    expiresAt,
+	}, time.Until(expiresAt)))
+	return refreshToken
+}
+
+func TestLogin_RememberMeGetsLongWindow(t *testing.T) {
+	store := newFakeSessionStore()
+	user := newTestUser(t)
+	service := newSessionTestService(t, store, user)
+	service.SetSlidingSessions(SlidingSessionConfig{Window: time.Hour, RememberMeWindow: 24 * time.Hour, AbsoluteMax: 7 * 24 * time.Hour})
+
+	remembered, err := service.Login(context.Background(), &LoginInput{Email: user.Email, Password: "password123", RememberMe: true})
+	require.NoError(t, err)
+
+	claims, err := service.jwtManager.ValidateToken(remembered.RefreshToken)
+	require.NoError(t, err)
+	assert.True(t, claims.RememberMe)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), claims.ExpiresAt.Time, time.Minute)
+	require.Len(t, store.sessionIDs, 1)
+	session := store.sessions[store.sessionIDs[0]]
+	assert.True(t, session.RememberMe)
+	assert.WithinDuration(t, claims.ExpiresAt.Time, session.ExpiresAt, time.Second)
+}
+
+func TestRefreshToken_SlidingExpiry(t *testing.T) {
+	const absoluteMax = 7 * 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		loggedIn   time.Duration // how long before now the session began
+		wantExpiry func(authTime time.Time) time.Time
+		wantErr    error
+	}{
+		{
+			name:       "activity extends the session by the window",
+			loggedIn:   50 * time.Minute,
+			wantExpiry: func(time.Time) time.Time { return time.Now().Add(time.Hour) },
+		},
+		{
+			name:       "the window is cut short at the absolute limit",
+			loggedIn:   absoluteMax - 30*time.Minute,
+			wantExpiry: func(authTime time.Time) time.Time { return authTime.Add(absoluteMax) },
+		},
+		{
+			name:     "past the absolute limit the session is not extended",
+			loggedIn: absoluteMax + time.Minute,
+			wantErr:  ErrSessionExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeSessionStore()
+			user := newTestUser(t)
+			service := newSessionTestService(t, store, user)
+			service.SetSlidingSessions(SlidingSessionConfig{Window: time.Hour, RememberMeWindow: 24 * time.Hour, AbsoluteMax: absoluteMax})
+
+			// The refresh token has 10 minutes left
+			authTime := time.Now().Add(-tt.loggedIn)
+			refreshToken := slidingSession(t, service, store, user.UserID, authTime, time.Now().Add(10*time.Minute))
+			sessionID := store.sessionIDs[0]
+
+			refreshed, err := service.RefreshToken(context.Background(), &RefreshTokenInput{RefreshToken: refreshToken})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			claims, err := service.jwtManager.ValidateToken(refreshed.RefreshToken)
+			require.NoError(t, err)
+			assert.WithinDuration(t, tt.wantExpiry(authTime), claims.ExpiresAt.Time, time.Minute)
+			assert.WithinDuration(t, authTime, claims.AuthTime.Time, time.Second, "the login time is carried forward")
+			assert.False(t, claims.RememberMe)
+			assert.WithinDuration(t, claims.ExpiresAt.Time, store.sessions[sessionID].ExpiresAt, time.Second, "the session slides with its refresh token")
+		})
+	}
+}
+
+func TestLogin_RehashesBcryptToArgon2id(t *testing.T) {
+	logger.InitDefault("test")
+	mockUserRepo := new(MockUserRepository)
+	mockSessionRepo := new(MockSessionRepository)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	argon2id := password.NewArgon2idHasher(password.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	hasher, err := password.NewMigratingHasher(password.AlgorithmArgon2id, password.NewBcryptHasher(bcrypt.MinCost), argon2id)
+	require.NoError(t, err)
+	service.SetPasswordHasher(hasher)
+
+	ctx := context.Background()
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &domain.User{UserID: uuid.New(), Email: "alice@example.com", Username: "alice", PasswordHash: string(bcryptHash)}
+
+	var stored string
+	mockSessionRepo.On("GetAccountLock", ctx, mock.Anything).Return(nil, nil)
+	mockSessionRepo.On("DeleteFailedLoginAttempts", ctx, mock.Anything).Return(nil)
+	mockSessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*redis.Session"), mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", ctx, user.UserID, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", ctx, user.UserID, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockUserRepo.On("GetByEmail", ctx, user.Email).Return(user, nil)
+	mockUserRepo.On("UpdateStatus", ctx, user.UserID, "online").Return(nil)
+	mockUserRepo.On("UpdatePasswordHash", ctx, user.UserID, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { stored = args.String(2) }).
+		Return(nil).Once()
+
+	_, err = service.Login(ctx, &LoginInput{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	algorithm, err := password.DetectAlgorithm(stored)
+	require.NoError(t, err)
+	assert.Equal(t, password.AlgorithmArgon2id, algorithm)
+	ok, err := hasher.Verify(stored, "password123")
+	require.NoError(t, err)
+	assert.True(t, ok, "the new hash verifies the same password")
+
+	// The upgraded hash logs in without being rewritten again
+	user.PasswordHash = stored
+	_, err = service.Login(ctx, &LoginInput{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	mockUserRepo.AssertNumberOfCalls(t, "UpdatePasswordHash", 1)
+}
+
+// lockoutSessionRepo keeps failed attempts and locks in memory like Redis
+type lockoutSessionRepo struct {
+	*MockSessionRepository
+	attempts map[string]*redis.FailedLoginAttempt
+	locks    map[string]time.Time
+}
+
+func (r *lockoutSessionRepo) GetAccountLock(ctx context.Context, key string) (*redis.AccountLock, error) {
+	until, ok := r.locks[key]
+	if !ok {
+		return nil, nil
+	}
+	return &redis.AccountLock{LockedUntil: until}, nil
+}
+
+func (r *lockoutSessionRepo) LockAccount(ctx context.Context, key string, lockedUntil time.Time) error {
+	r.locks[key] = lockedUntil
+	return nil
+}
+
+func (r *lockoutSessionRepo) GetFailedLoginAttempt(ctx context.Context, key string) (*redis.FailedLoginAttempt, error) {
+	return r.attempts[key], nil
+}
+
+func (r *lockoutSessionRepo) SetFailedLoginAttempt(ctx context.Context, key string, attempt *redis.FailedLoginAttempt) error {
+	r.attempts[key] = attempt
+	return nil
+}
+
+func TestLogin_LocksAccountAfterRepeatedFailures(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockUserRepo := new(MockUserRepository)
+	sessions := &lockoutSessionRepo{
+		MockSessionRepository: new(MockSessionRepository),
+		attempts:              map[string]*redis.FailedLoginAttempt{},
+		locks:                 map[string]time.Time{},
+	}
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), sessions, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+
+	ctx := context.Background()
+	email := "victim@example.com"
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	mockUserRepo.On("GetByEmail", ctx, email).Return(&domain.User{UserID: uuid.New(), Email: email, PasswordHash: string(hash)}, nil)
+
+	guess := &LoginInput{Email: email, Password: "wrong-password", IP: "203.0.113.9"}
+	for i := 0; i < constants.MaxFailedLoginAttempts; i++ {
+		_, err := service.Login(ctx, guess)
+		require.ErrorIs(t, err, ErrInvalidCredentials, "attempt %d", i+1)
+	}
+
+	_, err = service.Login(ctx, guess)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+
+	// The right password is refused too while the lock holds
+	_, err = service.Login(ctx, &LoginInput{Email: email, Password: "correct-password", IP: "203.0.113.9"})
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.WithinDuration(t, time.Now().Add(constants.AccountLockDuration), sessions.locks["account_lock:"+email], time.Minute)
+}
+
+// fakeLockoutNotices records lock emails and deduplicates them like Redis SETNX
+type fakeLockoutNotices struct {
+	notified map[string]bool
+	sent     []*email.AccountLockedEmailData
+}
+
+func (f *fakeLockoutNotices) MarkLockoutNotified(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if f.notified[key] {
+		return false, nil
+	}
+	f.notified[key] = true
+	return true, nil
+}
+
+func (f *fakeLockoutNotices) SendAccountLockedEmail(ctx context.Context, to string, data *email.AccountLockedEmailData) error {
+	f.sent = append(f.sent, data)
+	return nil
+}
+
+func TestLogin_LockSendsOneNotification(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockUserRepo := new(MockUserRepository)
+	sessions := &lockoutSessionRepo{
+		MockSessionRepository: new(MockSessionRepository),
+		attempts:              map[string]*redis.FailedLoginAttempt{},
+		locks:                 map[string]time.Time{},
+	}
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), sessions, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+	notices := &fakeLockoutNotices{notified: map[string]bool{}}
+	service.SetLockoutNotification(notices, notices)
+
+	ctx := context.Background()
+	user := &domain.User{UserID: uuid.New(), Email: "victim@example.com", Username: "victim", PasswordHash: "not-a-hash"}
+	mockUserRepo.On("GetByEmail", ctx, user.Email).Return(user, nil)
+	mockUserRepo.On("GetByID", ctx, user.UserID).Return(user, nil)
+
+	guess := &LoginInput{Email: user.Email, Password: "wrong-password", IP: "203.0.113.9"}
+	for i := 0; i < constants.MaxFailedLoginAttempts-1; i++ {
+		_, _ = service.Login(ctx, guess)
+	}
+	assert.Empty(t, notices.sent, "no email below the threshold")
+
+	_, _ = service.Login(ctx, guess)
+	require.Len(t, notices.sent, 1)
+	assert.Equal(t, "203.0.113.9", notices.sent[0].IP)
+	assert.Equal(t, sessions.locks["account_lock:"+user.Email], notices.sent[0].LockedUntil)
+
+	// Attempts while locked are refused before counting, and a relock in the
+	// same period does not email again
+	_, _ = service.Login(ctx, guess)
+	delete(sessions.locks, "account_lock:"+user.Email)
+	_, _ = service.Login(ctx, guess)
+	assert.Len(t, notices.sent, 1)
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/repository/redis"
+	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// ErrSessionNotFound is returned when a session does not exist or belongs to
+// another user
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionInfo describes one of a user's logged-in sessions
+type SessionInfo struct {
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// GetSessions lists the user's active sessions, newest first
+func (s *Service) GetSessions(ctx context.Context, userID uuid.UUID) ([]SessionInfo, error) {
+	sessions, err := s.sessionRepo.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, SessionInfo{
+			SessionID: session.SessionID,
+			CreatedAt: session.CreatedAt,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.After(infos[j].CreatedAt)
+	})
+	return infos, nil
+}
+
+// RevokeSession logs one of the user's sessions out. Its current access and
+// refresh tokens are blacklisted so the device is signed out immediately.
+func (s *Service) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if errors.Is(err, redis.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	if jti := s.revokeJWT(ctx, session.AccessToken); jti != "" {
+		if err := s.sessionRepo.UntrackAccessTokens(ctx, userID, jti); err != nil {
+			logger.Warn("Failed to untrack revoked token",
+				zap.String("user_id", userID.String()),
+				zap.Error(err))
+		}
+	}
+	s.revokeJWT(ctx, session.RefreshToken)
+
+	if err := s.sessionRepo.DeleteSession(ctx, sessionID, userID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	logger.Info("Session revoked",
+		zap.String("user_id", userID.String()),
+		zap.String("session_id", sessionID))
+	return nil
+}
+
+// LogoutAll signs the user out of every device: each session's access and
+// refresh tokens are blacklisted, all other tokens are revoked through
+// RevokeAllUserTokens, and the user is marked offline. It returns the number
+// of sessions revoked; calling it again returns 0.
+func (s *Service) LogoutAll(ctx context.Context, userID uuid.UUID) (int, error) {
+	sessions, err := s.sessionRepo.GetUserSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	for _, session := range sessions {
+		s.revokeJWT(ctx, session.AccessToken)
+		s.revokeJWT(ctx, session.RefreshToken)
+	}
+
+	// Also deletes the sessions and reaches access tokens issued outside them
+	if _, err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	if err := s.userRepo.UpdateStatus(ctx, userID, "offline"); err != nil {
+		logger.Warn("Failed to update user status during logout",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+	if err := s.presenceRepo.SetUserOffline(ctx, userID); err != nil {
+		logger.Warn("Failed to update user presence during logout",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+	}
+
+	metrics.AuthLogoutTotal.Inc()
+	logger.Info("Logged out of all sessions",
+		zap.String("user_id", userID.String()),
+		zap.Int("sessions", len(sessions)))
+	return len(sessions), nil
+}
+
+// revokeJWT blacklists a token for the rest of its lifetime and returns the
+// ID it was blacklisted under. Invalid or expired tokens and failures are
+// skipped and return "".
+func (s *Service) revokeJWT(ctx context.Context, token string) string {
+	if token == "" {
+		return ""
+	}
+	claims, err := s.jwtManager.ValidateToken(token)
+	if err != nil {
+		return ""
+	}
+	expiresIn := time.Until(claims.ExpiresAt.Time)
+	if expiresIn <= 0 {
+		return ""
+	}
+	jti := tokenID(claims, token)
+	if err := s.sessionRepo.BlacklistToken(ctx, jti, expiresIn); err != nil {
+		logger.Warn("Failed to blacklist token",
+			zap.String("jti", jti),
+			zap.Error(err))
+		return ""
+	}
+	metrics.AuthTokenBlacklistedTotal.Inc()
+	return jti
+}
+
+// rotateSession points the session created with oldRefreshToken at the new
+// token pair, so revoking it reaches the tokens currently in use. A non-zero
+// expiresAt slides the session's expiry along with the new refresh token.
+// Sessions are only a view of the tokens; failures are logged.
+func (s *Service) rotateSession(ctx context.Context, userID uuid.UUID, oldRefreshToken, accessToken, refreshToken string, expiresAt time.Time) {
+	if s.sessionRepo.IsDegraded() {
+		return
+	}
+	sessions, err := s.sessionRepo.GetUserSessions(ctx, userID)
+	if err != nil {
+		logger.Warn("Failed to get sessions for token rotation",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return
+	}
+	for _, session := range sessions {
+		if session.RefreshToken != oldRefreshToken {
+			continue
+		}
+		if !expiresAt.IsZero() {
+			session.ExpiresAt = expiresAt
+		}
+		ttl := time.Until(session.ExpiresAt)
+		if ttl <= 0 {
+			return
+		}
+		session.AccessToken = accessToken
+		session.RefreshToken = refreshToken
+		if err := s.sessionRepo.CreateSession(ctx, session, ttl); err != nil {
+			logger.Warn("Failed to update session tokens",
+				zap.String("session_id", session.SessionID),
+				zap.Error(err))
+		}
+		return
+	}
+}
+
+// SlidingSessionConfig extends a session each time its refresh token is used.
+// A refresh token issued at now expires at
+// min(now+window, login time+AbsoluteMax), so an active user stays signed in
+// until AbsoluteMax while an idle one is signed out after the window.
+type SlidingSessionConfig struct {
+	Window           time.Duration // window of normal sessions; 0 disables sliding
+	RememberMeWindow time.Duration // window of sessions that chose "remember me"
+	AbsoluteMax      time.Duration // longest a session lives after login; 0 is unbounded
+}
+
+// SetSlidingSessions extends refresh tokens and sessions on each refresh
+// instead of expiring them a fixed time after login
+func (s *Service) SetSlidingSessions(config SlidingSessionConfig) {
+	s.sliding = config
+}
+
+// refreshTokenOptions returns the options for a refresh token issued at now
+// for the login at authTime. ExpiresAt is zero, the manager's fixed lifetime,
+// when sliding sessions are disabled.
+func (s *Service) refreshTokenOptions(authTime time.Time, rememberMe bool, now time.Time) jwt.RefreshTokenOptions {
+	opts := jwt.RefreshTokenOptions{AuthTime: authTime, RememberMe: rememberMe}
+	if s.sliding.Window <= 0 {
+		return opts
+	}
+
+	window := s.sliding.Window
+	if rememberMe && s.sliding.RememberMeWindow > 0 {
+		window = s.sliding.RememberMeWindow
+	}
+	opts.ExpiresAt = now.Add(window)
+	if s.sliding.AbsoluteMax > 0 {
+		if limit := authTime.Add(s.sliding.AbsoluteMax); limit.Before(opts.ExpiresAt) {
+			opts.ExpiresAt = limit
+		}
+	}
+	return opts
+}
+
+// sessionTTL is how long to keep the session holding a refresh token issued
+// with opts
+func sessionTTL(opts jwt.RefreshTokenOptions) time.Duration {
+	if opts.ExpiresAt.IsZero() {
+		return constants.SessionExpiry
+	}
+	return time.Until(opts.ExpiresAt)
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// tokenCleanupLockKey guards the cleanup so only one auth-service instance runs it
+const tokenCleanupLockKey = "auth:token-cleanup"
+
+// ExpiredTokenRepository deletes stale email verification and reset tokens
+type ExpiredTokenRepository interface {
+	DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+// Locker provides a distributed lock
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error)
+	Unlock(ctx context.Context, key, token string) error
+}
+
+// TokenCleanupConfig controls the token cleanup job
+type TokenCleanupConfig struct {
+	// Interval between cleanup runs
+	Interval time.Duration
+	// Retention is how long used and expired tokens are kept before deletion
+	Retention time.Duration
+	// LockTTL bounds how long a crashed run can hold the lock
+	LockTTL time.Duration
+}
+
+// TokenCleanup periodically deletes used and long-expired tokens
+type TokenCleanup struct {
+	repo   ExpiredTokenRepository
+	locker Locker
+	config TokenCleanupConfig
+
+	now func() time.Time
+}
+
+// NewTokenCleanup creates a new token cleanup job
+func NewTokenCleanup(repo ExpiredTokenRepository, locker Locker, config TokenCleanupConfig) *TokenCleanup {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	if config.Retention < 0 {
+		config.Retention = 0
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 10 * time.Minute
+	}
+	return &TokenCleanup{
+		repo:   repo,
+		locker: locker,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Start runs the cleanup every interval until ctx is cancelled
+func (j *TokenCleanup) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := j.Run(ctx); err != nil {
+					logger.Error("Email token cleanup failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Run deletes stale tokens once if the distributed lock can be taken and
+// returns how many were deleted
+func (j *TokenCleanup) Run(ctx context.Context) (int64, error) {
+	token, ok, err := j.locker.TryLock(ctx, tokenCleanupLockKey, j.config.LockTTL)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		logger.Debug("Email token cleanup skipped, another instance holds the lock")
+		return 0, nil
+	}
+	defer func() {
+		if err := j.locker.Unlock(context.Background(), tokenCleanupLockKey, token); err != nil {
+			logger.Warn("Failed to release token cleanup lock", zap.Error(err))
+		}
+	}()
+
+	deleted, err := j.repo.DeleteExpired(ctx, j.now().Add(-j.config.Retention))
+	metrics.AuthEmailTokensPrunedTotal.Add(float64(deleted))
+	if err != nil {
+		return deleted, err
+	}
+
+	logger.Info("Email token cleanup finished", zap.Int64("deleted", deleted))
+	return deleted, nil
+}
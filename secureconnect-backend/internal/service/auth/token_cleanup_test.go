@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeTokenStore applies the same predicate as the CockroachDB DELETE
+type fakeTokenStore struct {
+	tokens map[string]fakeToken
+}
+
+type fakeToken struct {
+	expiresAt time.Time
+	usedAt    *time.Time
+}
+
+func (f *fakeTokenStore) DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deleted int64
+	for name, tok := range f.tokens {
+		if tok.expiresAt.Before(olderThan) || (tok.usedAt != nil && tok.usedAt.Before(olderThan)) {
+			delete(f.tokens, name)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type fakeLocker struct {
+	held bool
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	if l.held {
+		return "", false, nil
+	}
+	l.held = true
+	return "token", true, nil
+}
+
+func (l *fakeLocker) Unlock(ctx context.Context, key, token string) error {
+	l.held = false
+	return nil
+}
+
+func TestTokenCleanup_RemovesExpiredKeepsValid(t *testing.T) {
+	logger.InitDefault("test")
+	now := time.Now()
+	usedLongAgo := now.Add(-48 * time.Hour)
+	usedRecently := now.Add(-time.Hour)
+
+	store := &fakeTokenStore{tokens: map[string]fakeToken{
+		"expired":       {expiresAt: now.Add(-48 * time.Hour)},
+		"used-long-ago": {expiresAt: now.Add(time.Hour), usedAt: &usedLongAgo},
+		"just-expired":  {expiresAt: now.Add(-time.Hour)},
+		"used-recently": {expiresAt: now.Add(time.Hour), usedAt: &usedRecently},
+		"valid":         {expiresAt: now.Add(time.Hour)},
+	}}
+	locker := &fakeLocker{}
+
+	job := NewTokenCleanup(store, locker, TokenCleanupConfig{Retention: 24 * time.Hour})
+	job.now = func() time.Time { return now }
+
+	deleted, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, deleted)
+	assert.NotContains(t, store.tokens, "expired")
+	assert.NotContains(t, store.tokens, "used-long-ago")
+	assert.Contains(t, store.tokens, "valid")
+	assert.Contains(t, store.tokens, "just-expired", "tokens inside the retention window are kept")
+	assert.Contains(t, store.tokens, "used-recently")
+	assert.False(t, locker.held, "lock is released after the run")
+}
+
+func TestTokenCleanup_SkipsWhenLockHeld(t *testing.T) {
+	logger.InitDefault("test")
+	store := &fakeTokenStore{tokens: map[string]fakeToken{
+		"expired": {expiresAt: time.Now().Add(-48 * time.Hour)},
+	}}
+
+	deleted, err := NewTokenCleanup(store, &fakeLocker{held: true}, TokenCleanupConfig{}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	assert.Len(t, store.tokens, 1)
+}
@@ -0,0 +1,361 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/totp"
+)
+
+const (
+	// MFAChallengeTTL is how long the token from a password login can be
+	// exchanged for real tokens with a second factor
+	MFAChallengeTTL = 5 * time.Minute
+
+	// maxMFAFailures is how many wrong codes a challenge accepts before it is
+	// dropped and the user must enter their password again
+	maxMFAFailures = 5
+
+	// maxUserMFAFailures is how many wrong codes a user may enter across all
+	// their challenges before VerifyTOTP refuses them for
+	// constants.AccountLockDuration, so new challenges do not reset the count
+	maxUserMFAFailures = 10
+
+	// recoveryCodeCount is how many recovery codes are issued at enrollment
+	recoveryCodeCount = 10
+
+	// defaultTOTPIssuer labels the account in authenticator apps
+	defaultTOTPIssuer = "SecureConnect"
+)
+
+// Two-factor authentication errors
+var (
+	ErrTOTPNotConfigured  = errors.New("two-factor authentication is not configured")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTOTPCode    = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken    = errors.New("invalid or expired mfa token")
+)
+
+// TOTPRepository stores two-factor enrollments
+type TOTPRepository interface {
+	Save(ctx context.Context, totp *domain.UserTOTP) error
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*domain.UserTOTP, error)
+	Confirm(ctx context.Context, userID uuid.UUID, step int64) error
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+// MFAChallengeRepository stores pending second-factor logins
+type MFAChallengeRepository interface {
+	CreateMFAChallenge(ctx context.Context, token string, userID uuid.UUID, ttl time.Duration) error
+	GetMFAChallenge(ctx context.Context, token string) (uuid.UUID, error)
+	RecordMFAFailure(ctx context.Context, token string, ttl time.Duration) (int, error)
+	DeleteMFAChallenge(ctx context.Context, token string) error
+	GetUserMFAFailures(ctx context.Context, userID uuid.UUID) (int, error)
+	RecordUserMFAFailure(ctx context.Context, userID uuid.UUID, ttl time.Duration) (int, error)
+}
+
+// SetTOTP enables two-factor authentication. Users with a confirmed
+// enrollment must pass VerifyTOTP after their password. issuer labels the
+// account in authenticator apps; empty uses "SecureConnect".
+func (s *Service) SetTOTP(repo TOTPRepository, challenges MFAChallengeRepository, issuer string) {
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	s.totpRepo = repo
+	s.mfaChallenges = challenges
+	s.totpIssuer = issuer
+}
+
+// EnableTOTPOutput is a new enrollment. The secret and recovery codes are
+// shown to the user once and cannot be retrieved again.
+type EnableTOTPOutput struct {
+	Secret        string
+	QRURL         string // otpauth:// URI to render as a QR code
+	RecoveryCodes []string
+}
+
+// EnableTOTP starts two-factor enrollment. It is enforced only after
+// ConfirmTOTP proves the user's authenticator produces valid codes; calling
+// it again before then replaces the pending secret.
+func (s *Service) EnableTOTP(ctx context.Context, userID uuid.UUID) (*EnableTOTPOutput, error) {
+	if s.totpRepo == nil {
+		return nil, ErrTOTPNotConfigured
+	}
+	current, err := s.totpRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Enabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.totpRepo.Save(ctx, &domain.UserTOTP{
+		UserID:             userID,
+		Secret:             secret,
+		RecoveryCodeHashes: hashes,
+		CreatedAt:          time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &EnableTOTPOutput{
+		Secret:        secret,
+		QRURL:         totp.KeyURI(s.totpIssuer, user.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTOTP turns on a pending enrollment once the user submits a valid code
+func (s *Service) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	if s.totpRepo == nil {
+		return ErrTOTPNotConfigured
+	}
+	current, err := s.totpRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return ErrTOTPNotEnabled
+	}
+	if current.Enabled() {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(current.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	if err := s.totpRepo.Confirm(ctx, userID, step); err != nil {
+		return err
+	}
+
+	logger.Info("Two-factor authentication enabled", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// DisableTOTP removes two-factor authentication. A current code or an unused
+// recovery code is required, so a stolen session alone cannot turn it off.
+func (s *Service) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	if s.totpRepo == nil {
+		return ErrTOTPNotConfigured
+	}
+	current, err := s.totpRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !current.Enabled() {
+		return ErrTOTPNotEnabled
+	}
+	if err := s.checkUserMFALock(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.checkSecondFactor(ctx, current, code); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			s.recordUserMFAFailure(ctx, userID)
+		}
+		return err
+	}
+	if err := s.totpRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	logger.Info("Two-factor authentication disabled", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// VerifyTOTPInput completes a login that returned MFARequired
+type VerifyTOTPInput struct {
+	MFAToken  string
+	Code      string // Current authenticator code or unused recovery code
+	IP        string
+	UserAgent string
+	// RememberMe is the choice the client made at login
+	RememberMe bool
+}
+
+// VerifyTOTP completes a login that returned MFARequired
+func (s *Service) VerifyTOTP(ctx context.Context, input *VerifyTOTPInput) (*LoginOutput, error) {
+	mfaToken, code := input.MFAToken, input.Code
+	if s.totpRepo == nil {
+		return nil, ErrTOTPNotConfigured
+	}
+	userID, err := s.mfaChallenges.GetMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if userID == uuid.Nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	current, err := s.totpRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.Enabled() {
+		// Disabled since the password step; the challenge no longer applies
+		_ = s.mfaChallenges.DeleteMFAChallenge(ctx, mfaToken)
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.checkUserMFALock(ctx, userID); err != nil {
+		_ = s.mfaChallenges.DeleteMFAChallenge(ctx, mfaToken)
+		return nil, err
+	}
+
+	if err := s.checkSecondFactor(ctx, current, code); err != nil {
+		if !errors.Is(err, ErrInvalidTOTPCode) {
+			return nil, err
+		}
+		s.recordUserMFAFailure(ctx, userID)
+		failures, recordErr := s.mfaChallenges.RecordMFAFailure(ctx, mfaToken, MFAChallengeTTL)
+		if recordErr != nil || failures >= maxMFAFailures {
+			_ = s.mfaChallenges.DeleteMFAChallenge(ctx, mfaToken)
+		}
+		return nil, err
+	}
+	if err := s.mfaChallenges.DeleteMFAChallenge(ctx, mfaToken); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.completeLogin(ctx, user, input.IP, input.UserAgent, input.RememberMe)
+}
+
+// requiresTOTP reports whether the user must pass a second factor to log in
+func (s *Service) requiresTOTP(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if s.totpRepo == nil {
+		return false, nil
+	}
+	current, err := s.totpRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check two-factor status: %w", err)
+	}
+	return current.Enabled(), nil
+}
+
+// startMFAChallenge issues the token a client exchanges with VerifyTOTP
+func (s *Service) startMFAChallenge(ctx context.Context, userID uuid.UUID) (*LoginOutput, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	if err := s.mfaChallenges.CreateMFAChallenge(ctx, token, userID, MFAChallengeTTL); err != nil {
+		return nil, err
+	}
+	return &LoginOutput{MFARequired: true, MFAToken: token}, nil
+}
+
+// checkUserMFALock returns ErrAccountLocked once the user has entered
+// maxUserMFAFailures wrong codes within constants.AccountLockDuration
+func (s *Service) checkUserMFALock(ctx context.Context, userID uuid.UUID) error {
+	failures, err := s.mfaChallenges.GetUserMFAFailures(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check two-factor failures: %w", err)
+	}
+	if failures >= maxUserMFAFailures {
+		metrics.AuthAccountLockedTotal.Inc()
+		return ErrAccountLocked
+	}
+	return nil
+}
+
+// recordUserMFAFailure counts a wrong code against the user. Each failure
+// extends the window, so the count only clears after a quiet
+// constants.AccountLockDuration.
+func (s *Service) recordUserMFAFailure(ctx context.Context, userID uuid.UUID) {
+	failures, err := s.mfaChallenges.RecordUserMFAFailure(ctx, userID, constants.AccountLockDuration)
+	if err != nil {
+		logger.Warn("Failed to record two-factor failure",
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return
+	}
+	if failures == maxUserMFAFailures {
+		logger.Warn("Two-factor locked after too many wrong codes",
+			zap.String("user_id", userID.String()))
+	}
+}
+
+// checkSecondFactor accepts a TOTP code not used before, or consumes a
+// recovery code
+func (s *Service) checkSecondFactor(ctx context.Context, current *domain.UserTOTP, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(current.Secret, code, time.Now()); ok {
+		fresh, err := s.totpRepo.UseStep(ctx, current.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTOTPCode
+		}
+		return nil
+	}
+
+	used, err := s.totpRepo.UseRecoveryCode(ctx, current.UserID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTOTPCode
+	}
+	logger.Info("Recovery code used", zap.String("user_id", current.UserID.String()))
+	return nil
+}
+
+// generateRecoveryCodes returns codes formatted "xxxxx-xxxxx" and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage. The codes carry 50
+// random bits, so a fast hash is enough; case and dashes are ignored.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
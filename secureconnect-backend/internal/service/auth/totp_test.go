@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/jwt"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/totp"
+)
+
+type fakeTOTPRepo struct {
+	enrollments map[uuid.UUID]*domain.UserTOTP
+}
+
+func (f *fakeTOTPRepo) Save(ctx context.Context, t *domain.UserTOTP) error {
+	copied := *t
+	f.enrollments[t.UserID] = &copied
+	return nil
+}
+
+func (f *fakeTOTPRepo) GetByUserID(ctx context.Context, userID uuid.UUID) (*domain.UserTOTP, error) {
+	t, ok := f.enrollments[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (f *fakeTOTPRepo) Confirm(ctx context.Context, userID uuid.UUID, step int64) error {
+	now := time.Now()
+	f.enrollments[userID].ConfirmedAt = &now
+	f.enrollments[userID].LastUsedStep = step
+	return nil
+}
+
+func (f *fakeTOTPRepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	t := f.enrollments[userID]
+	if t.LastUsedStep >= step {
+		return false, nil
+	}
+	t.LastUsedStep = step
+	return true, nil
+}
+
+func (f *fakeTOTPRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	t := f.enrollments[userID]
+	for i, hash := range t.RecoveryCodeHashes {
+		if hash == codeHash {
+			t.RecoveryCodeHashes = append(t.RecoveryCodeHashes[:i], t.RecoveryCodeHashes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeTOTPRepo) Delete(ctx context.Context, userID uuid.UUID) error {
+	delete(f.enrollments, userID)
+	return nil
+}
+
+type fakeMFAChallenges struct {
+	users        map[string]uuid.UUID
+	failures     map[string]int
+	userFailures map[uuid.UUID]int
+}
+
+func (f *fakeMFAChallenges) CreateMFAChallenge(ctx context.Context, token string, userID uuid.UUID, ttl time.Duration) error {
+	f.users[token] = userID
+	return nil
+}
+
+func (f *fakeMFAChallenges) GetMFAChallenge(ctx context.Context, token string) (uuid.UUID, error) {
+	return f.users[token], nil
+}
+
+func (f *fakeMFAChallenges) RecordMFAFailure(ctx context.Context, token string, ttl time.Duration) (int, error) {
+	f.failures[token]++
+	return f.failures[token], nil
+}
+
+func (f *fakeMFAChallenges) DeleteMFAChallenge(ctx context.Context, token string) error {
+	delete(f.users, token)
+	delete(f.failures, token)
+	return nil
+}
+
+func (f *fakeMFAChallenges) GetUserMFAFailures(ctx context.Context, userID uuid.UUID) (int, error) {
+	return f.userFailures[userID], nil
+}
+
+func (f *fakeMFAChallenges) RecordUserMFAFailure(ctx context.Context, userID uuid.UUID, ttl time.Duration) (int, error) {
+	f.userFailures[userID]++
+	return f.userFailures[userID], nil
+}
+
+func TestTOTP_LoginRequiresSecondFactorOnceConfirmed(t *testing.T) {
+	logger.InitDefault("test")
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	user := &domain.User{UserID: uuid.New(), Email: "mfa@example.com", Username: "mfa", PasswordHash: string(hash)}
+
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("GetByID", mock.Anything, user.UserID).Return(user, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("UpdateStatus", mock.Anything, user.UserID, "online").Return(nil)
+	mockSessionRepo := new(MockSessionRepository)
+	mockSessionRepo.On("GetAccountLock", mock.Anything, mock.Anything).Return(nil, nil)
+	mockSessionRepo.On("DeleteFailedLoginAttempts", mock.Anything, mock.Anything).Return(nil)
+	mockSessionRepo.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+	totpRepo := &fakeTOTPRepo{enrollments: map[uuid.UUID]*domain.UserTOTP{}}
+	service.SetTOTP(totpRepo, &fakeMFAChallenges{users: map[string]uuid.UUID{}, failures: map[string]int{}, userFailures: map[uuid.UUID]int{}}, "")
+	ctx := context.Background()
+	login := &LoginInput{Email: user.Email, Password: "password123", IP: "203.0.113.1"}
+
+	enrollment, err := service.EnableTOTP(ctx, user.UserID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.QRURL, "otpauth://totp/SecureConnect:")
+	assert.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+
+	// Pending enrollments are not enforced
+	output, err := service.Login(ctx, login)
+	require.NoError(t, err)
+	assert.False(t, output.MFARequired)
+	assert.NotEmpty(t, output.AccessToken)
+
+	now := time.Now()
+	code, err := totp.Code(enrollment.Secret, now)
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.ConfirmTOTP(ctx, user.UserID, "000000"), ErrInvalidTOTPCode)
+	require.NoError(t, service.ConfirmTOTP(ctx, user.UserID, code))
+
+	output, err = service.Login(ctx, login)
+	require.NoError(t, err)
+	require.True(t, output.MFARequired)
+	assert.Empty(t, output.AccessToken, "no tokens before the second factor")
+
+	_, err = service.VerifyTOTP(ctx, &VerifyTOTPInput{MFAToken: output.MFAToken, Code: code})
+	assert.ErrorIs(t, err, ErrInvalidTOTPCode, "a code cannot be replayed")
+
+	next, err := totp.Code(enrollment.Secret, now.Add(totp.Period))
+	require.NoError(t, err)
+	verified, err := service.VerifyTOTP(ctx, &VerifyTOTPInput{MFAToken: output.MFAToken, Code: next})
+	require.NoError(t, err)
+	assert.NotEmpty(t, verified.AccessToken)
+	assert.NotEmpty(t, verified.RefreshToken)
+
+	_, err = service.VerifyTOTP(ctx, &VerifyTOTPInput{MFAToken: output.MFAToken, Code: next})
+	assert.ErrorIs(t, err, ErrInvalidMFAToken, "the mfa token is single use")
+}
+
+func TestTOTP_RecoveryCodesAreSingleUse(t *testing.T) {
+	logger.InitDefault("test")
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	user := &domain.User{UserID: uuid.New(), Email: "mfa@example.com", Username: "mfa", PasswordHash: string(hash)}
+
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("GetByID", mock.Anything, user.UserID).Return(user, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockUserRepo.On("UpdateStatus", mock.Anything, user.UserID, "online").Return(nil)
+	mockSessionRepo := new(MockSessionRepository)
+	mockSessionRepo.On("GetAccountLock", mock.Anything, mock.Anything).Return(nil, nil)
+	mockSessionRepo.On("DeleteFailedLoginAttempts", mock.Anything, mock.Anything).Return(nil)
+	mockSessionRepo.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionRepo.On("TrackAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionRepo.On("StoreRefreshJTI", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+	service := NewService(mockUserRepo, new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+	repo := &fakeTOTPRepo{enrollments: map[uuid.UUID]*domain.UserTOTP{}}
+	service.SetTOTP(repo, &fakeMFAChallenges{users: map[string]uuid.UUID{}, failures: map[string]int{}, userFailures: map[uuid.UUID]int{}}, "")
+	ctx := context.Background()
+
+	enrollment, err := service.EnableTOTP(ctx, user.UserID)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, service.ConfirmTOTP(ctx, user.UserID, code))
+	assert.NotContains(t, repo.enrollments[user.UserID].RecoveryCodeHashes, enrollment.RecoveryCodes[0], "codes are stored hashed")
+
+	output, err := service.Login(ctx, &LoginInput{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	_, err = service.VerifyTOTP(ctx, &VerifyTOTPInput{MFAToken: output.MFAToken, Code: enrollment.RecoveryCodes[0]})
+	require.NoError(t, err)
+
+	output, err = service.Login(ctx, &LoginInput{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	_, err = service.VerifyTOTP(ctx, &VerifyTOTPInput{MFAToken: output.MFAToken, Code: enrollment.RecoveryCodes[0]})
+	assert.ErrorIs(t, err, ErrInvalidTOTPCode)
+
+	require.NoError(t, service.DisableTOTP(ctx, user.UserID, enrollment.RecoveryCodes[1]))
+	output, err = service.Login(ctx, &LoginInput{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	assert.False(t, output.MFARequired)
+}
+
+func TestVerifyTOTP_CountsFailuresPerUser(t *testing.T) {
+	logger.InitDefault("test")
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		challengeFailures int
+		userFailures      int
+		validCode         bool
+		wantErr           error
+		wantChallenge     bool
+		wantUserFailures  int
+	}{
+		{name: "wrong code counts against the challenge and the user", wantErr: ErrInvalidTOTPCode, wantChallenge: true, wantUserFailures: 1},
+		{name: "last wrong code drops the challenge", challengeFailures: maxMFAFailures - 1, wantErr: ErrInvalidTOTPCode, wantUserFailures: 1},
+		{name: "a fresh challenge does not reset the user's failures", userFailures: maxUserMFAFailures, validCode: true, wantErr: ErrAccountLocked, wantUserFailures: maxUserMFAFailures},
+		{name: "right code below the limit logs in", userFailures: maxUserMFAFailures - 1, validCode: true, wantUserFailures: maxUserMFAFailures - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			user := &domain.User{UserID: uuid.New(), Email: "mfa@example.com", Username: "mfa"}
+
+			mockUserRepo := new(MockUserRepository)
+			mockUserRepo.On("GetByID", mock.Anything, user.UserID).Return(user, nil)
+			mockUserRepo.On("UpdateStatus", mock.Anything, user.UserID, "online").Return(nil)
+			mockSessionRepo := new(MockSessionRepository)
+			mockSessionRepo.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockSessionRepo.On("TrackAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockSessionRepo.On("StoreRefreshJTI", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, 24*time.Hour)
+
+			confirmedAt := time.Now().Add(-time.Hour)
+			totpRepo := &fakeTOTPRepo{enrollments: map[uuid.UUID]*domain.UserTOTP{
+				user.UserID: {UserID: user.UserID, Secret: secret, ConfirmedAt: &confirmedAt},
+			}}
+			challenges := &fakeMFAChallenges{
+				users:        map[string]uuid.UUID{"token": user.UserID},
+				failures:     map[string]int{"token": tt.challengeFailures},
+				userFailures: map[uuid.UUID]int{user.UserID: tt.userFailures},
+			}
+			service := NewService(mockUserRepo, new(MockDirectoryRepository), mockSessionRepo, new(MockPresenceRepository), new(MockEmailVerificationRepository), new(MockEmailService), jwtManager)
+			service.SetTOTP(totpRepo, challenges, "")
+
+			code, err := totp.Code(secret, time.Now())
+			require.NoError(t, err)
+			if !tt.validCode {
+				code = "x" + code[1:]
+			}
+
+			output, err := service.VerifyTOTP(ctx, &VerifyTOTPInput{MFAToken: "token", Code: code})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, output)
+				_, exists := challenges.users["token"]
+				assert.Equal(t, tt.wantChallenge, exists)
+			} else {
+				require.NoError(t, err)
+				assert.NotEmpty(t, output.AccessToken)
+			}
+			assert.Equal(t, tt.wantUserFailures, challenges.userFailures[user.UserID])
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	pkgContext "secureconnect-backend/pkg/context"
+	"secureconnect-backend/pkg/logger"
+)
+
+const (
+	// BotMessageEvent is the webhook event for a new conversation message
+	BotMessageEvent = "message.created"
+
+	// botDeliveryTimeout bounds delivering one send's messages to all bots
+	botDeliveryTimeout = 30 * time.Second
+)
+
+// BotDirectory finds the bots registered to conversations
+type BotDirectory interface {
+	GetBotsByConversation(ctx context.Context, conversationID uuid.UUID) ([]*domain.Bot, error)
+	GetBotByTokenHash(ctx context.Context, tokenHash string) (*domain.Bot, error)
+}
+
+// WebhookSender posts a signed event to a bot's webhook
+type WebhookSender interface {
+	Send(ctx context.Context, endpoint string, secret []byte, event string, payload interface{}) error
+}
+
+// BotEvent is the body delivered to bot webhooks
+type BotEvent struct {
+	Event          string                  `json:"event"`
+	ConversationID uuid.UUID               `json:"conversation_id"`
+	BotID          uuid.UUID               `json:"bot_id"`
+	Message        *domain.MessageResponse `json:"message"`
+}
+
+// BotMessageInput is a message posted by a bot
+type BotMessageInput struct {
+	Token       string
+	Content     string
+	MessageType string
+	Metadata    map[string]interface{}
+}
+
+// SetBots delivers new plaintext messages to the conversation's bots and lets
+// bots post with their token. Conversations whose settings turn on E2EE are
+// skipped in both directions.
+func (s *Service) SetBots(directory BotDirectory, settings SearchSettingsRepository, sender WebhookSender) {
+	s.bots = directory
+	s.botSettings = settings
+	s.botSender = sender
+	s.botSem = make(chan struct{}, 100)
+}
+
+// PostBotMessage stores a message from the bot owning token and fans it out
+// to the conversation's participants like any other message, but not to its
+// bots. The sender is the bot's ID and the metadata is marked with "bot": true.
+// Bots are not participants, so no membership or mute checks apply, but
+// moderation does.
+func (s *Service) PostBotMessage(ctx context.Context, input *BotMessageInput) (*SendMessageOutput, error) {
+	if s.bots == nil || input.Token == "" {
+		return nil, domain.ErrInvalidBotToken
+	}
+	bot, err := s.bots.GetBotByTokenHash(ctx, domain.HashBotToken(input.Token))
+	if err != nil {
+		if errors.Is(err, domain.ErrBotNotFound) {
+			return nil, domain.ErrInvalidBotToken
+		}
+		return nil, fmt.Errorf("failed to get bot: %w", err)
+	}
+	if !s.botsAllowed(ctx, bot.ConversationID) {
+		return nil, domain.ErrBotE2EEConversation
+	}
+
+	metadata := make(map[string]interface{}, len(input.Metadata)+1)
+	for k, v := range input.Metadata {
+		metadata[k] = v
+	}
+	metadata["bot"] = true
+
+	messageType := input.MessageType
+	if messageType == "" {
+		messageType = "text"
+	}
+	message := &domain.Message{
+		MessageID:      uuid.New(),
+		ConversationID: bot.ConversationID,
+		SenderID:       bot.BotID,
+		Content:        input.Content,
+		MessageType:    messageType,
+		Metadata:       metadata,
+		SentAt:         time.Now(),
+	}
+	if err := s.moderate(ctx, message); err != nil {
+		return nil, err
+	}
+	batch := []*domain.Message{message}
+	s.assignSequences(ctx, bot.ConversationID, batch)
+
+	if err := s.messageRepo.Save(ctx, message); err != nil {
+		return nil, fmt.Errorf("failed to save message: %w", err)
+	}
+	s.indexMessages(ctx, bot.ConversationID, batch)
+	s.cacheRecentMessages(ctx, bot.ConversationID, batch)
+	s.publishBatch(ctx, bot.ConversationID, batch)
+	s.notifyRecipients(ctx, bot.BotID, bot.ConversationID, 1, message.SentAt, false)
+	// Bot messages are never delivered to bots, so two bots cannot reply to each other forever
+
+	return &SendMessageOutput{Message: toMessageResponse(message)}, nil
+}
+
+// deliverToBots sends newly saved plaintext messages to the conversation's
+// bots in the background. Messages posted by bots are never delivered, so bots
+// cannot trigger each other in a loop. Failures are logged; delivery is best
+// effort and never fails the send.
+func (s *Service) deliverToBots(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message) {
+	if s.bots == nil {
+		return
+	}
+	plaintext := make([]*domain.Message, 0, len(messages))
+	for _, message := range plaintextMessages(messages) {
+		if isBot, _ := message.Metadata["bot"].(bool); !isBot {
+			plaintext = append(plaintext, message)
+		}
+	}
+	if len(plaintext) == 0 {
+		return
+	}
+
+	select {
+	case s.botSem <- struct{}{}:
+	default:
+		logger.Warn("Bot delivery queue full, skipping webhook delivery",
+			zap.String("conversation_id", conversationID.String()))
+		return
+	}
+
+	deliverCtx, cancel := pkgContext.Detached(ctx, botDeliveryTimeout)
+	go func() {
+		defer cancel()
+		defer func() { <-s.botSem }()
+		s.sendToBots(deliverCtx, conversationID, plaintext)
+	}()
+}
+
+// sendToBots delivers messages to every bot of the conversation
+func (s *Service) sendToBots(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message) {
+	bots, err := s.bots.GetBotsByConversation(ctx, conversationID)
+	if err != nil {
+		logger.Warn("Failed to get conversation bots",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return
+	}
+	if len(bots) == 0 || !s.botsAllowed(ctx, conversationID) {
+		return
+	}
+
+	for _, bot := range bots {
+		for _, message := range messages {
+			event := &BotEvent{
+				Event:          BotMessageEvent,
+				ConversationID: conversationID,
+				BotID:          bot.BotID,
+				Message:        toMessageResponse(message),
+			}
+			if err := s.botSender.Send(ctx, bot.WebhookURL, []byte(bot.Secret), BotMessageEvent, event); err != nil {
+				logger.Warn("Failed to deliver message to bot",
+					zap.String("conversation_id", conversationID.String()),
+					zap.String("bot_id", bot.BotID.String()),
+					zap.Error(err))
+				// Skip the rest for an unreachable bot rather than waiting on each message
+				break
+			}
+		}
+	}
+}
+
+// botsAllowed reports whether the conversation is not end-to-end encrypted.
+// Settings that cannot be read count as encrypted.
+func (s *Service) botsAllowed(ctx context.Context, conversationID uuid.UUID) bool {
+	settings, err := s.botSettings.GetSettings(ctx, conversationID)
+	if err != nil {
+		logger.Warn("Failed to get settings for bot delivery",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return false
+	}
+	return !settings.IsE2EEEnabled
+}
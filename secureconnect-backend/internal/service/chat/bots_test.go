@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+type fakeBotDirectory struct {
+	bots []*domain.Bot
+}
+
+func (f *fakeBotDirectory) GetBotsByConversation(ctx context.Context, conversationID uuid.UUID) ([]*domain.Bot, error) {
+	var bots []*domain.Bot
+	for _, bot := range f.bots {
+		if bot.ConversationID == conversationID {
+			bots = append(bots, bot)
+		}
+	}
+	return bots, nil
+}
+
+func (f *fakeBotDirectory) GetBotByTokenHash(ctx context.Context, tokenHash string) (*domain.Bot, error) {
+	for _, bot := range f.bots {
+		if bot.TokenHash == tokenHash {
+			return bot, nil
+		}
+	}
+	return nil, domain.ErrBotNotFound
+}
+
+type botDelivery struct {
+	endpoint string
+	secret   string
+	event    *BotEvent
+}
+
+type fakeWebhookSender struct {
+	deliveries chan botDelivery
+}
+
+func (f *fakeWebhookSender) Send(ctx context.Context, endpoint string, secret []byte, event string, payload interface{}) error {
+	f.deliveries <- botDelivery{endpoint: endpoint, secret: string(secret), event: payload.(*BotEvent)}
+	return nil
+}
+
+func (f *fakeWebhookSender) next(t *testing.T) botDelivery {
+	t.Helper()
+	select {
+	case d := <-f.deliveries:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook delivery")
+		return botDelivery{}
+	}
+}
+
+func (f *fakeWebhookSender) assertNone(t *testing.T) {
+	t.Helper()
+	select {
+	case d := <-f.deliveries:
+		t.Fatalf("unexpected delivery to %s", d.endpoint)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func newBot(conversationID uuid.UUID, endpoint, token string) *domain.Bot {
+	return &domain.Bot{
+		BotID:          uuid.New(),
+		ConversationID: conversationID,
+		WebhookURL:     endpoint,
+		Secret:         "secret-for-" + endpoint,
+		TokenHash:      domain.HashBotToken(token),
+	}
+}
+
+func TestSendMessage_DeliversPlaintextToBots(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+	conversationID := uuid.New()
+	senderID := uuid.New()
+
+	messageRepo := new(MockMessageRepository)
+	messageRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	publisher := new(MockPublisher)
+	publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	conversationRepo := new(MockConversationRepository)
+	conversationRepo.On("GetParticipant", mock.Anything, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	conversationRepo.On("GetParticipants", mock.Anything, conversationID).Return([]uuid.UUID{senderID}, nil).Maybe()
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+	service := NewService(messageRepo, nil, publisher, nil, conversationRepo, userRepo)
+	bot := newBot(conversationID, "https://bots.example.com/hook", "token")
+	sender := &fakeWebhookSender{deliveries: make(chan botDelivery, 4)}
+	service.SetBots(&fakeBotDirectory{bots: []*domain.Bot{bot}}, &fakeSearchSettings{settings: domain.ConversationSettings{ConversationID: conversationID}}, sender)
+
+	output, err := service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hello bot", MessageType: "text"})
+	require.NoError(t, err)
+
+	delivery := sender.next(t)
+	assert.Equal(t, bot.WebhookURL, delivery.endpoint)
+	assert.Equal(t, bot.Secret, delivery.secret)
+	assert.Equal(t, BotMessageEvent, delivery.event.Event)
+	assert.Equal(t, bot.BotID, delivery.event.BotID)
+	assert.Equal(t, output.Message.MessageID, delivery.event.Message.MessageID)
+	assert.Equal(t, "hello bot", delivery.event.Message.Content)
+
+	// Encrypted messages are never delivered
+	_, err = service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "c2VjcmV0", IsEncrypted: true, MessageType: "text"})
+	require.NoError(t, err)
+	sender.assertNone(t)
+}
+
+func TestPostBotMessage_FansOutToParticipants(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+	conversationID := uuid.New()
+
+	messageRepo := new(MockMessageRepository)
+	messageRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	var published []byte
+	publisher := new(MockPublisher)
+	publisher.On("Publish", mock.Anything, "chat:"+conversationID.String(), mock.Anything).
+		Run(func(args mock.Arguments) { published = args.Get(2).([]byte) }).
+		Return(nil)
+
+	conversationRepo := new(MockConversationRepository)
+	conversationRepo.On("GetParticipants", mock.Anything, conversationID).Return([]uuid.UUID{}, nil).Maybe()
+
+	service := NewService(messageRepo, nil, publisher, nil, conversationRepo, nil)
+	poster := newBot(conversationID, "https://poster.example.com/hook", "poster-token")
+	listener := newBot(conversationID, "https://listener.example.com/hook", "listener-token")
+	sender := &fakeWebhookSender{deliveries: make(chan botDelivery, 4)}
+	settings := &fakeSearchSettings{settings: domain.ConversationSettings{ConversationID: conversationID}}
+	service.SetBots(&fakeBotDirectory{bots: []*domain.Bot{poster, listener}}, settings, sender)
+
+	_, err := service.PostBotMessage(ctx, &BotMessageInput{Token: "wrong", Content: "hi"})
+	assert.ErrorIs(t, err, domain.ErrInvalidBotToken)
+
+	output, err := service.PostBotMessage(ctx, &BotMessageInput{Token: "poster-token", Content: "build passed"})
+	require.NoError(t, err)
+	assert.Equal(t, poster.BotID, output.Message.SenderID)
+	assert.Equal(t, conversationID, output.Message.ConversationID)
+	assert.Equal(t, "text", output.Message.MessageType)
+	assert.Equal(t, true, output.Message.Metadata["bot"])
+
+	// Participants receive it on the conversation channel
+	var broadcast domain.Message
+	require.NoError(t, json.Unmarshal(published, &broadcast))
+	assert.Equal(t, output.Message.MessageID, broadcast.MessageID)
+	assert.Equal(t, poster.BotID, broadcast.SenderID)
+	assert.Equal(t, "build passed", broadcast.Content)
+
+	// Bots never see bot messages, so they cannot reply to each other in a loop
+	sender.assertNone(t)
+
+	// Once the conversation turns on E2EE the bot can no longer post
+	settings.settings.IsE2EEEnabled = true
+	_, err = service.PostBotMessage(ctx, &BotMessageInput{Token: "poster-token", Content: "hi"})
+	assert.ErrorIs(t, err, domain.ErrBotE2EEConversation)
+}
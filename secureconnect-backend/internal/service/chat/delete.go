@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// messageDeletedEvent is published on the conversation channel when a message
+// is deleted for everyone, so connected clients replace it with a tombstone
+type messageDeletedEvent struct {
+	Type           string    `json:"type"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+	DeletedBy      uuid.UUID `json:"deleted_by"`
+	SentAt         time.Time `json:"sent_at"`
+	Timestamp      time.Time `json:"timestamp"` // When the message was deleted
+}
+
+// DeleteForEveryone replaces a message with a tombstone for all participants
+// and publishes a message_deleted event. The sender or a conversation admin
+// may do so; anyone else gets domain.ErrMessageDeleteForbidden. Deleting an
+// already deleted message succeeds without a new event.
+func (s *Service) DeleteForEveryone(ctx context.Context, conversationID, messageID, userID uuid.UUID) error {
+	participant, err := s.getParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+
+	message, err := s.getMessage(ctx, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+	if message.IsDeleted() {
+		return nil
+	}
+	if message.SenderID != userID && participant.Role != "admin" {
+		return domain.ErrMessageDeleteForbidden
+	}
+
+	if err := s.messageRepo.SoftDelete(ctx, conversationID, messageID, userID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMessageDeleted):
+			return nil
+		case errors.Is(err, domain.ErrMessageNotFound):
+			return err
+		}
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	deletedAt := time.Now()
+	message.Content = ""
+	message.Metadata = nil
+	message.DeletedAt = &deletedAt
+	message.DeletedBy = &userID
+
+	s.unindexMessage(ctx, message)
+	s.replaceRecentMessage(ctx, message)
+	s.publishDelete(ctx, message)
+	s.unpinDeleted(ctx, message)
+	return nil
+}
+
+// DeleteForMe hides a message from the user's own history. Other
+// participants still see it, and no event is published.
+func (s *Service) DeleteForMe(ctx context.Context, conversationID, messageID, userID uuid.UUID) error {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	if _, err := s.getMessage(ctx, conversationID, messageID); err != nil {
+		return err
+	}
+
+	if err := s.messageRepo.HideForUser(ctx, conversationID, messageID, userID); err != nil {
+		return fmt.Errorf("failed to hide message: %w", err)
+	}
+	return nil
+}
+
+// getMessage reads a message, passing domain.ErrMessageNotFound through
+func (s *Service) getMessage(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.Message, error) {
+	message, err := s.messageRepo.GetByID(ctx, conversationID, messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return message, nil
+}
+
+// withoutHidden drops the messages userID deleted for themselves. If that
+// cannot be read the messages are returned unfiltered.
+func (s *Service) withoutHidden(ctx context.Context, conversationID, userID uuid.UUID, messages []*domain.Message) []*domain.Message {
+	if len(messages) == 0 {
+		return messages
+	}
+	ids := make([]uuid.UUID, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.MessageID
+	}
+	hidden, err := s.messageRepo.GetHiddenMessageIDs(ctx, conversationID, userID, ids)
+	if err != nil {
+		logger.Warn("Failed to read hidden messages, returning them all",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return messages
+	}
+	if len(hidden) == 0 {
+		return messages
+	}
+
+	visible := make([]*domain.Message, 0, len(messages))
+	for _, msg := range messages {
+		if !hidden[msg.MessageID] {
+			visible = append(visible, msg)
+		}
+	}
+	return visible
+}
+
+// publishDelete publishes a message_deleted event. Failures are logged; the
+// tombstone is already stored and clients see it when they reload history.
+func (s *Service) publishDelete(ctx context.Context, message *domain.Message) {
+	eventJSON, err := json.Marshal(&messageDeletedEvent{
+		Type:           "message_deleted",
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		MessageID:      message.MessageID,
+		DeletedBy:      *message.DeletedBy,
+		SentAt:         message.SentAt,
+		Timestamp:      *message.DeletedAt,
+	})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", message.ConversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish message deletion",
+			zap.String("conversation_id", message.ConversationID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,226 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// newTestService returns a service over messages, publisher and conversations
+func newTestService(messages MessageRepository, publisher Publisher, conversations ConversationRepository) *Service {
+	logger.InitDefault("test")
+	return NewService(messages, new(MockPresenceRepository), publisher, new(MockNotificationService), conversations, new(MockUserRepository))
+}
+
+// participant is a conversation participant with role
+func participant(role string) *domain.ConversationParticipant {
+	return &domain.ConversationParticipant{Role: role}
+}
+
+// participantsOf answers GetParticipant for conversationID from members;
+// anyone else is not a participant
+func participantsOf(conversationID uuid.UUID, members map[uuid.UUID]*domain.ConversationParticipant) *MockConversationRepository {
+	repo := new(MockConversationRepository)
+	for userID, member := range members {
+		repo.On("GetParticipant", mock.Anything, conversationID, userID).Return(member, nil)
+	}
+	repo.On("GetParticipant", mock.Anything, conversationID, mock.Anything).Return(nil, domain.ErrNotParticipant)
+	return repo
+}
+
+// savedMessages is a MockMessageRepository that answers GetByID and
+// GetByKeys with the messages passed to save
+type savedMessages struct {
+	*MockMessageRepository
+	saved map[uuid.UUID]*domain.Message
+}
+
+func newSavedMessages() *savedMessages {
+	return &savedMessages{MockMessageRepository: new(MockMessageRepository), saved: make(map[uuid.UUID]*domain.Message)}
+}
+
+// save stores an hour-old plaintext message from senderID
+func (m *savedMessages) save(conversationID, senderID uuid.UUID, content string) *domain.Message {
+	message := &domain.Message{
+		MessageID:      uuid.New(),
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Content:        content,
+		MessageType:    "text",
+		Metadata:       map[string]interface{}{"link": "https://example.com"},
+		SentAt:         time.Now().Add(-time.Hour),
+	}
+	m.On("GetByID", mock.Anything, conversationID, message.MessageID).Return(message, nil)
+	m.saved[message.MessageID] = message
+	return message
+}
+
+func (m *savedMessages) GetByKeys(ctx context.Context, conversationID uuid.UUID, keys []domain.MessageKey) ([]*domain.Message, error) {
+	var messages []*domain.Message
+	for _, key := range keys {
+		if message, ok := m.saved[key.MessageID]; ok && message.SentAt.Equal(key.SentAt) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func TestDeleteForEveryone_TombstonesAndPublishes(t *testing.T) {
+	conversationID, senderID, memberID := uuid.New(), uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	publisher := new(MockPublisher)
+	service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+		senderID: participant("member"),
+		memberID: participant("member"),
+	}))
+	ctx := context.Background()
+	index := &fakeSearchIndex{indexed: map[uuid.UUID]map[uuid.UUID]bool{}}
+	service.SetSearchIndex(index, &fakeSearchSettings{})
+	cache := newFakeRecentMessageCache()
+	service.SetRecentMessageCache(cache, RecentMessagesConfig{})
+
+	message := messages.save(conversationID, senderID, "secret plans")
+	index.indexed[conversationID] = map[uuid.UUID]bool{message.MessageID: true}
+	cached := *message
+	cache.messages[conversationID] = []*domain.Message{&cached}
+
+	messages.On("SoftDelete", ctx, conversationID, message.MessageID, senderID).Return(nil).Once()
+	var published []byte
+	publisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).
+		Run(func(args mock.Arguments) { published = args.Get(2).([]byte) }).Return(nil).Once()
+
+	require.NoError(t, service.DeleteForEveryone(ctx, conversationID, message.MessageID, senderID))
+
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(published, &event))
+	assert.Equal(t, "message_deleted", event["type"])
+	assert.Equal(t, message.MessageID.String(), event["message_id"])
+	assert.Equal(t, senderID.String(), event["deleted_by"])
+	assert.NotContains(t, string(published), "secret plans")
+
+	assert.Empty(t, index.indexed[conversationID], "deleted messages are removed from search")
+	require.Len(t, cache.messages[conversationID], 1)
+	assert.True(t, cache.messages[conversationID][0].IsDeleted(), "the cached copy is replaced with the tombstone")
+	assert.Empty(t, cache.messages[conversationID][0].Content)
+	messages.AssertExpectations(t)
+
+	t.Run("history returns the tombstone", func(t *testing.T) {
+		deletedAt := time.Now()
+		tombstone := &domain.Message{MessageID: message.MessageID, ConversationID: conversationID, SenderID: senderID, MessageType: "text", SentAt: message.SentAt, DeletedAt: &deletedAt, DeletedBy: &senderID}
+		messages.On("GetByConversation", ctx, conversationID, 20, []byte(nil)).Return([]*domain.Message{tombstone}, []byte(nil), nil).Once()
+		messages.On("GetHiddenMessageIDs", ctx, conversationID, memberID, []uuid.UUID{message.MessageID}).Return(map[uuid.UUID]bool{}, nil).Once()
+
+		output, err := service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: memberID, Limit: 20})
+		require.NoError(t, err)
+		require.Len(t, output.Messages, 1)
+		assert.True(t, output.Messages[0].Deleted)
+		assert.Empty(t, output.Messages[0].Content)
+		assert.Nil(t, output.Messages[0].Metadata)
+		assert.Equal(t, &deletedAt, output.Messages[0].DeletedAt)
+	})
+}
+
+func TestDeleteForEveryone_SenderOrAdminOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string // Role of the deleting user; empty for a non-participant
+		ownMessage bool
+		missing    bool
+		deleted    bool
+		wantErr    error
+		wantDelete bool
+	}{
+		{name: "the sender deletes their message", role: "member", ownMessage: true, wantDelete: true},
+		{name: "an admin deletes anyone's message", role: "admin", wantDelete: true},
+		{name: "a member cannot delete someone else's message", role: "member", wantErr: domain.ErrMessageDeleteForbidden},
+		{name: "non-participants cannot delete", wantErr: domain.ErrNotParticipant},
+		{name: "unknown messages are not found", role: "member", ownMessage: true, missing: true, wantErr: domain.ErrMessageNotFound},
+		{name: "deleting twice is a no-op", role: "member", ownMessage: true, deleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, userID := uuid.New(), uuid.New()
+			members := map[uuid.UUID]*domain.ConversationParticipant{}
+			if tt.role != "" {
+				members[userID] = participant(tt.role)
+			}
+			messages := newSavedMessages()
+			publisher := new(MockPublisher)
+			publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			service := newTestService(messages, publisher, participantsOf(conversationID, members))
+			ctx := context.Background()
+
+			senderID := uuid.New()
+			if tt.ownMessage {
+				senderID = userID
+			}
+			message := messages.save(conversationID, senderID, "hello")
+			messageID := message.MessageID
+			if tt.missing {
+				messageID = uuid.New()
+				messages.On("GetByID", ctx, conversationID, messageID).Return(nil, domain.ErrMessageNotFound)
+			}
+			if tt.deleted {
+				deletedAt := time.Now()
+				message.DeletedAt = &deletedAt
+			}
+			messages.On("SoftDelete", ctx, conversationID, messageID, userID).Return(nil).Maybe()
+
+			err := service.DeleteForEveryone(ctx, conversationID, messageID, userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.wantDelete {
+				messages.AssertCalled(t, "SoftDelete", ctx, conversationID, messageID, userID)
+			} else {
+				messages.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestDeleteForMe_HidesOnlyForUser(t *testing.T) {
+	conversationID, senderID, memberID := uuid.New(), uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	publisher := new(MockPublisher)
+	service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+		senderID: participant("member"),
+		memberID: participant("member"),
+	}))
+	ctx := context.Background()
+
+	hidden, kept := messages.save(conversationID, senderID, "hidden"), messages.save(conversationID, senderID, "kept")
+	messages.On("HideForUser", ctx, conversationID, hidden.MessageID, memberID).Return(nil).Once()
+
+	require.NoError(t, service.DeleteForMe(ctx, conversationID, hidden.MessageID, memberID))
+	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+	messages.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	page := []*domain.Message{kept, hidden}
+	messages.On("GetByConversation", ctx, conversationID, 20, []byte(nil)).Return(page, []byte(nil), nil)
+	messages.On("GetHiddenMessageIDs", ctx, conversationID, memberID, mock.Anything).
+		Return(map[uuid.UUID]bool{hidden.MessageID: true}, nil)
+	messages.On("GetHiddenMessageIDs", ctx, conversationID, senderID, mock.Anything).
+		Return(map[uuid.UUID]bool{}, nil)
+
+	output, err := service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: memberID, Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, output.Messages, 1)
+	assert.Equal(t, kept.MessageID, output.Messages[0].MessageID)
+
+	output, err = service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: senderID, Limit: 20})
+	require.NoError(t, err)
+	assert.Len(t, output.Messages, 2, "other participants still see the message")
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// DefaultMessageEditWindow is how long after sending a message its sender may edit it
+const DefaultMessageEditWindow = 15 * time.Minute
+
+// messageEditedEvent is published on the conversation channel when a sender
+// edits a message, so connected clients update it in place
+type messageEditedEvent struct {
+	Type           string    `json:"type"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+	Content        string    `json:"content"`
+	MessageType    string    `json:"message_type"`
+	SentAt         time.Time `json:"sent_at"`
+	Timestamp      time.Time `json:"timestamp"` // When the message was edited
+}
+
+// SetMessageEditWindow sets how long after sending a message its sender may
+// edit it; window <= 0 uses DefaultMessageEditWindow
+func (s *Service) SetMessageEditWindow(window time.Duration) {
+	if window <= 0 {
+		window = DefaultMessageEditWindow
+	}
+	s.editWindow = window
+}
+
+// EditMessage replaces the content of a message and publishes a
+// message_edited event. Only the sender may edit, while they can still post
+// to the conversation and within the edit window. Encrypted messages are
+// rejected with domain.ErrEncryptedMessageEdit since the server cannot
+// moderate or index their new content, and deleted ones with
+// domain.ErrMessageDeleted. Like new messages, the new content
+// is moderated.
+func (s *Service) EditMessage(ctx context.Context, conversationID, messageID, senderID uuid.UUID, newContent string) (*domain.MessageResponse, error) {
+	if err := s.checkCanPost(ctx, conversationID, senderID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.getMessage(ctx, conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsDeleted() {
+		return nil, domain.ErrMessageDeleted
+	}
+	if message.SenderID != senderID {
+		return nil, domain.ErrNotMessageSender
+	}
+	if message.IsEncrypted {
+		return nil, domain.ErrEncryptedMessageEdit
+	}
+	editedAt := time.Now()
+	if editedAt.Sub(message.SentAt) > s.editWindow {
+		return nil, domain.ErrEditWindowExpired
+	}
+
+	message.Content = newContent
+	message.EditedAt = &editedAt
+	if err := s.moderate(ctx, message); err != nil {
+		return nil, err
+	}
+
+	if err := s.messageRepo.Update(ctx, message); err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) || errors.Is(err, domain.ErrNotMessageSender) || errors.Is(err, domain.ErrMessageDeleted) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update message: %w", err)
+	}
+	s.indexMessages(ctx, conversationID, []*domain.Message{message})
+	s.replaceRecentMessage(ctx, message)
+	s.publishEdit(ctx, message)
+
+	return toMessageResponse(message), nil
+}
+
+// publishEdit publishes a message_edited event. Failures are logged; the
+// edit is already stored and clients see it when they reload history.
+func (s *Service) publishEdit(ctx context.Context, message *domain.Message) {
+	eventJSON, err := json.Marshal(&messageEditedEvent{
+		Type:           "message_edited",
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		MessageID:      message.MessageID,
+		Content:        message.Content,
+		MessageType:    message.MessageType,
+		SentAt:         message.SentAt,
+		Timestamp:      *message.EditedAt,
+	})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", message.ConversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish message edit",
+			zap.String("conversation_id", message.ConversationID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+	}
+}
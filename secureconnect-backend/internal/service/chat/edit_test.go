@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestEditMessage(t *testing.T) {
+	logger.InitDefault("test")
+	senderID := uuid.New()
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name    string
+		stored  *domain.Message // nil when the message does not exist
+		wantErr error
+	}{
+		{
+			name:   "recent plaintext message",
+			stored: &domain.Message{SenderID: senderID, Content: "helo", MessageType: "text", SentAt: time.Now().Add(-time.Minute)},
+		},
+		{
+			name:    "another user's message",
+			stored:  &domain.Message{SenderID: uuid.New(), SentAt: time.Now()},
+			wantErr: domain.ErrNotMessageSender,
+		},
+		{
+			name:    "encrypted message",
+			stored:  &domain.Message{SenderID: senderID, IsEncrypted: true, SentAt: time.Now()},
+			wantErr: domain.ErrEncryptedMessageEdit,
+		},
+		{
+			name:    "outside the edit window",
+			stored:  &domain.Message{SenderID: senderID, SentAt: time.Now().Add(-11 * time.Minute)},
+			wantErr: domain.ErrEditWindowExpired,
+		},
+		{
+			name:    "deleted message",
+			stored:  &domain.Message{SenderID: senderID, SentAt: time.Now(), DeletedAt: &deletedAt},
+			wantErr: domain.ErrMessageDeleted,
+		},
+		{
+			name:    "unknown message",
+			wantErr: domain.ErrMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMsgRepo := new(MockMessageRepository)
+			mockPublisher := new(MockPublisher)
+			mockConversationRepo := new(MockConversationRepository)
+			service := NewService(mockMsgRepo, new(MockPresenceRepository), mockPublisher, new(MockNotificationService), mockConversationRepo, new(MockUserRepository))
+			service.SetMessageEditWindow(10 * time.Minute)
+			ctx := context.Background()
+
+			conversationID, messageID := uuid.New(), uuid.New()
+			mockConversationRepo.On("GetParticipant", mock.Anything, conversationID, senderID).
+				Return(&domain.ConversationParticipant{Role: "member"}, nil)
+			if tt.stored != nil {
+				stored := *tt.stored
+				stored.MessageID, stored.ConversationID = messageID, conversationID
+				mockMsgRepo.On("GetByID", ctx, conversationID, messageID).Return(&stored, nil)
+			} else {
+				mockMsgRepo.On("GetByID", ctx, conversationID, messageID).Return(nil, domain.ErrMessageNotFound)
+			}
+
+			var published []byte
+			if tt.wantErr == nil {
+				mockMsgRepo.On("Update", ctx, mock.MatchedBy(func(m *domain.Message) bool {
+					return m.Content == "hello" && m.EditedAt != nil
+				})).Return(nil).Once()
+				mockPublisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).
+					Run(func(args mock.Arguments) { published = args.Get(2).([]byte) }).Return(nil).Once()
+			}
+
+			edited, err := service.EditMessage(ctx, conversationID, messageID, senderID, "hello")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockMsgRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "hello", edited.Content)
+			require.NotNil(t, edited.EditedAt)
+
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal(published, &event))
+			assert.Equal(t, "message_edited", event["type"])
+			assert.Equal(t, messageID.String(), event["message_id"])
+			assert.Equal(t, "hello", event["content"])
+			mockMsgRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// Conversation export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatText = "text"
+)
+
+// exportPageSize is how many messages are read from Cassandra per page while exporting
+const exportPageSize = 200
+
+// encryptedPlaceholder replaces the ciphertext of end-to-end encrypted messages
+const encryptedPlaceholder = "[encrypted message unavailable]"
+
+// deletedPlaceholder stands in for messages deleted for everyone in text exports
+const deletedPlaceholder = "[message deleted]"
+
+// ErrInvalidExportFormat is returned for formats other than json and text
+var ErrInvalidExportFormat = fmt.Errorf("export format must be %q or %q", ExportFormatJSON, ExportFormatText)
+
+// ExportedConversation is the metadata header of a JSON export
+type ExportedConversation struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExportedAt     time.Time `json:"exported_at"`
+	ExportedBy     uuid.UUID `json:"exported_by"`
+}
+
+// ExportedParticipant is a participant entry in an export
+type ExportedParticipant struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// ExportedMessage is a message entry in an export. Encrypted messages never
+// carry their ciphertext, and messages deleted for everyone carry no content.
+type ExportedMessage struct {
+	MessageID   uuid.UUID `json:"message_id"`
+	SenderID    uuid.UUID `json:"sender_id"`
+	MessageType string    `json:"message_type"`
+	Content     string    `json:"content,omitempty"`
+	IsEncrypted bool      `json:"is_encrypted"`
+	Deleted     bool      `json:"deleted"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// ExportConversation writes a participant's export of a conversation to w as
+// JSON or a plain-text transcript. Messages are read page by page and written
+// newest first, so memory use does not grow with the conversation. Messages
+// the participant deleted for themselves are left out. The caller
+// must not write to w before this returns nil for the participant check.
+func (s *Service) ExportConversation(ctx context.Context, conversationID, requesterID uuid.UUID, format string, w io.Writer) error {
+	if format != ExportFormatJSON && format != ExportFormatText {
+		return ErrInvalidExportFormat
+	}
+
+	participants, err := s.conversationRepo.GetParticipantsWithDetails(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get participants: %w", err)
+	}
+	isParticipant := false
+	for _, p := range participants {
+		if p.UserID == requesterID {
+			isParticipant = true
+			break
+		}
+	}
+	if !isParticipant {
+		return domain.ErrNotParticipant
+	}
+
+	conversation, err := s.conversationRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	header := ExportedConversation{
+		ConversationID: conversation.ConversationID,
+		Type:           conversation.Type,
+		Title:          conversation.Title,
+		CreatedAt:      conversation.CreatedAt,
+		ExportedAt:     time.Now().UTC(),
+		ExportedBy:     requesterID,
+	}
+	exported := make([]ExportedParticipant, len(participants))
+	for i, p := range participants {
+		exported[i] = ExportedParticipant{
+			UserID:      p.UserID,
+			Username:    p.Username,
+			DisplayName: p.DisplayName,
+			Role:        p.Role,
+			JoinedAt:    p.JoinedAt,
+		}
+	}
+
+	var writer exportWriter
+	if format == ExportFormatJSON {
+		writer = &jsonExportWriter{w: w}
+	} else {
+		writer = &textExportWriter{w: w, names: participantNames(participants)}
+	}
+
+	if err := writer.begin(header, exported); err != nil {
+		return err
+	}
+
+	var pageState []byte
+	first := true
+	for first || len(pageState) > 0 {
+		first = false
+		messages, next, err := s.messageRepo.GetByConversation(ctx, conversationID, exportPageSize, pageState)
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+		for _, msg := range s.withoutHidden(ctx, conversationID, requesterID, messages) {
+			if err := writer.message(toExportedMessage(msg)); err != nil {
+				return err
+			}
+		}
+		pageState = next
+	}
+
+	return writer.end()
+}
+
+// toExportedMessage converts a stored message, dropping ciphertext. A message
+// deleted for everyone becomes a tombstone without content.
+func toExportedMessage(msg *domain.Message) ExportedMessage {
+	exported := ExportedMessage{
+		MessageID:   msg.MessageID,
+		SenderID:    msg.SenderID,
+		MessageType: msg.MessageType,
+		IsEncrypted: msg.IsEncrypted,
+		SentAt:      msg.SentAt,
+	}
+	if msg.IsDeleted() {
+		exported.Deleted = true
+	} else if msg.IsEncrypted {
+		exported.Content = encryptedPlaceholder
+	} else {
+		exported.Content = msg.Content
+	}
+	return exported
+}
+
+func participantNames(participants []*domain.ConversationParticipantDetail) map[uuid.UUID]string {
+	names := make(map[uuid.UUID]string, len(participants))
+	for _, p := range participants {
+		name := p.DisplayName
+		if name == "" {
+			name = p.Username
+		}
+		names[p.UserID] = name
+	}
+	return names
+}
+
+// exportWriter renders an export incrementally
+type exportWriter interface {
+	begin(header ExportedConversation, participants []ExportedParticipant) error
+	message(msg ExportedMessage) error
+	end() error
+}
+
+// jsonExportWriter writes {"conversation":..., "participants":[...], "messages":[...]}
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonExportWriter) begin(header ExportedConversation, participants []ExportedParticipant) error {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	participantsJSON, err := json.Marshal(participants)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, `{"conversation":%s,"participants":%s,"messages":[`, headerJSON, participantsJSON)
+	return err
+}
+
+func (j *jsonExportWriter) message(msg ExportedMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.count++
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonExportWriter) end() error {
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
+
+// textExportWriter writes a human-readable transcript
+type textExportWriter struct {
+	w     io.Writer
+	names map[uuid.UUID]string
+}
+
+func (t *textExportWriter) begin(header ExportedConversation, participants []ExportedParticipant) error {
+	title := header.Title
+	if title == "" {
+		title = header.ConversationID.String()
+	}
+	names := make([]string, len(participants))
+	for i, p := range participants {
+		names[i] = t.names[p.UserID]
+	}
+
+	_, err := fmt.Fprintf(t.w, "Conversation: %s (%s)\nCreated: %s\nExported: %s\nParticipants: %s\nMessages are listed newest first.\n\n",
+		title, header.Type,
+		header.CreatedAt.UTC().Format(time.RFC3339),
+		header.ExportedAt.Format(time.RFC3339),
+		strings.Join(names, ", "))
+	return err
+}
+
+func (t *textExportWriter) message(msg ExportedMessage) error {
+	sender, ok := t.names[msg.SenderID]
+	if !ok {
+		sender = msg.SenderID.String()
+	}
+	content := msg.Content
+	if msg.Deleted {
+		content = deletedPlaceholder
+	} else if !msg.IsEncrypted && msg.MessageType != "" && msg.MessageType != "text" {
+		content = fmt.Sprintf("[%s] %s", msg.MessageType, content)
+	}
+	_, err := fmt.Fprintf(t.w, "[%s] %s: %s\n", msg.SentAt.UTC().Format(time.RFC3339), sender, content)
+	return err
+}
+
+func (t *textExportWriter) end() error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+func TestExportConversation(t *testing.T) {
+	const ciphertext = "c2VjcmV0LWNpcGhlcnRleHQ="
+
+	tests := []struct {
+		name     string
+		format   string
+		outsider bool
+		wantErr  error
+	}{
+		{name: "json", format: ExportFormatJSON},
+		{name: "text", format: ExportFormatText},
+		{name: "unknown format", format: "csv", wantErr: ErrInvalidExportFormat},
+		{name: "non-participant", format: ExportFormatJSON, outsider: true, wantErr: domain.ErrNotParticipant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMsgRepo := new(MockMessageRepository)
+			mockConversationRepo := new(MockConversationRepository)
+			service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), mockConversationRepo, new(MockUserRepository))
+			ctx := context.Background()
+
+			conversationID, memberID := uuid.New(), uuid.New()
+			mockConversationRepo.On("GetParticipantsWithDetails", ctx, conversationID).Return([]*domain.ConversationParticipantDetail{
+				{UserID: memberID, Username: "alice", Role: "admin"},
+			}, nil).Maybe()
+			mockConversationRepo.On("GetByID", ctx, conversationID).Return(&domain.Conversation{
+				ConversationID: conversationID, Type: "group", Title: "Team",
+			}, nil).Maybe()
+
+			// Messages are streamed page by page. The member deleted one for
+			// themselves, and one was deleted for everyone.
+			deletedAt := time.Now()
+			hidden := &domain.Message{MessageID: uuid.New(), SenderID: memberID, Content: "hidden from me", MessageType: "text", SentAt: time.Now()}
+			tombstone := &domain.Message{MessageID: uuid.New(), SenderID: memberID, MessageType: "text", SentAt: time.Now(), DeletedAt: &deletedAt, DeletedBy: &memberID}
+			page1 := []*domain.Message{{MessageID: uuid.New(), SenderID: memberID, Content: "hello", MessageType: "text", SentAt: time.Now()}, hidden, tombstone}
+			page2 := []*domain.Message{{MessageID: uuid.New(), SenderID: memberID, Content: ciphertext, IsEncrypted: true, MessageType: "text", SentAt: time.Now()}}
+			mockMsgRepo.On("GetByConversation", ctx, conversationID, exportPageSize, []byte(nil)).Return(page1, []byte("page-2"), nil).Maybe()
+			mockMsgRepo.On("GetByConversation", ctx, conversationID, exportPageSize, []byte("page-2")).Return(page2, []byte(nil), nil).Maybe()
+			mockMsgRepo.On("GetHiddenMessageIDs", ctx, conversationID, memberID, mock.Anything).Return(map[uuid.UUID]bool{hidden.MessageID: true}, nil).Maybe()
+
+			requesterID := memberID
+			if tt.outsider {
+				requesterID = uuid.New()
+			}
+
+			var buf bytes.Buffer
+			err := service.ExportConversation(ctx, conversationID, requesterID, tt.format, &buf)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, buf.Len(), "nothing is written on failure")
+				mockMsgRepo.AssertNotCalled(t, "GetByConversation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, buf.String(), ciphertext, "encrypted messages have no plaintext")
+			assert.Contains(t, buf.String(), "hello")
+			assert.Contains(t, buf.String(), encryptedPlaceholder)
+			assert.NotContains(t, buf.String(), "hidden from me", "messages deleted for the requester are left out")
+			mockMsgRepo.AssertExpectations(t)
+
+			if tt.format != ExportFormatJSON {
+				assert.Contains(t, buf.String(), "alice: "+deletedPlaceholder)
+				return
+			}
+			var export struct {
+				Conversation ExportedConversation  `json:"conversation"`
+				Participants []ExportedParticipant `json:"participants"`
+				Messages     []ExportedMessage     `json:"messages"`
+			}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+			assert.Equal(t, "Team", export.Conversation.Title)
+			require.Len(t, export.Participants, 1)
+			require.Len(t, export.Messages, 3)
+			assert.Equal(t, "hello", export.Messages[0].Content)
+			assert.True(t, export.Messages[1].Deleted)
+			assert.Empty(t, export.Messages[1].Content)
+			assert.True(t, export.Messages[2].IsEncrypted)
+			assert.Equal(t, encryptedPlaceholder, export.Messages[2].Content)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+const (
+	// ClientMessageIDTTL is how long a sent message is returned again for a
+	// retry with the same client_msg_id
+	ClientMessageIDTTL = time.Hour
+
+	// clientMessagePendingTTL bounds how long a send in progress blocks
+	// retries, so a crashed send does not block them for ClientMessageIDTTL
+	clientMessagePendingTTL = 30 * time.Second
+)
+
+// ErrSendInProgress is returned when a retry arrives while the first send
+// with the same client_msg_id has not finished yet
+var ErrSendInProgress = errors.New("a message with this client_msg_id is still being sent")
+
+// ClientMessageStore remembers which message a client_msg_id produced in a
+// conversation, so a client retrying an unacknowledged send gets the original
+// message back
+type ClientMessageStore interface {
+	// ClaimClientMessage marks the id as being sent. When the id was already
+	// claimed it returns claimed false and the message it produced, which is
+	// nil while that send is still in progress.
+	ClaimClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string, ttl time.Duration) (claimed bool, sent *domain.MessageResponse, err error)
+	CompleteClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string, message *domain.MessageResponse, ttl time.Duration) error
+	ReleaseClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string) error
+}
+
+// SetClientMessageStore makes SendMessage idempotent per sender, conversation
+// and client_msg_id. Without a store every send creates a new message.
+func (s *Service) SetClientMessageStore(store ClientMessageStore) {
+	s.clientMessages = store
+}
+
+// claimClientMessage reserves input's client_msg_id. It returns the original
+// message when this is a retry of a completed send. Store failures only
+// disable deduplication for this send.
+func (s *Service) claimClientMessage(ctx context.Context, input *SendMessageInput) (claimed bool, sent *domain.MessageResponse, err error) {
+	if s.clientMessages == nil || input.ClientMsgID == "" {
+		return false, nil, nil
+	}
+	claimed, sent, err = s.clientMessages.ClaimClientMessage(ctx, input.SenderID, input.ConversationID, input.ClientMsgID, clientMessagePendingTTL)
+	if err != nil {
+		logger.Warn("Failed to claim client_msg_id, sending without deduplication",
+			zap.String("sender_id", input.SenderID.String()),
+			zap.Error(err))
+		return false, nil, nil
+	}
+	if !claimed && sent == nil {
+		return false, nil, ErrSendInProgress
+	}
+	return claimed, sent, nil
+}
+
+// completeClientMessage records the message a claimed client_msg_id produced
+func (s *Service) completeClientMessage(ctx context.Context, input *SendMessageInput, message *domain.MessageResponse) {
+	if err := s.clientMessages.CompleteClientMessage(ctx, input.SenderID, input.ConversationID, input.ClientMsgID, message, ClientMessageIDTTL); err != nil {
+		logger.Warn("Failed to record client_msg_id",
+			zap.String("sender_id", input.SenderID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+	}
+}
+
+// releaseClientMessage frees a claimed client_msg_id after a failed send so
+// the client can retry it
+func (s *Service) releaseClientMessage(ctx context.Context, input *SendMessageInput) {
+	if err := s.clientMessages.ReleaseClientMessage(ctx, input.SenderID, input.ConversationID, input.ClientMsgID); err != nil {
+		logger.Warn("Failed to release client_msg_id",
+			zap.String("sender_id", input.SenderID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// memoryClientMessages mimics the Redis client_msg_id store
+type memoryClientMessages struct {
+	mu      sync.Mutex
+	entries map[string]*domain.MessageResponse // nil while pending
+}
+
+func (m *memoryClientMessages) ClaimClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string, ttl time.Duration) (bool, *domain.MessageResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := senderID.String() + conversationID.String() + clientMsgID
+	if sent, ok := m.entries[key]; ok {
+		return false, sent, nil
+	}
+	m.entries[key] = nil
+	return true, nil, nil
+}
+
+func (m *memoryClientMessages) CompleteClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string, message *domain.MessageResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[senderID.String()+conversationID.String()+clientMsgID] = message
+	return nil
+}
+
+func (m *memoryClientMessages) ReleaseClientMessage(ctx context.Context, senderID, conversationID uuid.UUID, clientMsgID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, senderID.String()+conversationID.String()+clientMsgID)
+	return nil
+}
+
+func TestSendMessage_RetryWithSameClientMsgIDIsIdempotent(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockMsgRepo := new(MockMessageRepository)
+	mockPublisher := new(MockPublisher)
+	mockConversationRepo := new(MockConversationRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), mockPublisher, new(MockNotificationService), mockConversationRepo, mockUserRepo)
+	store := &memoryClientMessages{entries: map[string]*domain.MessageResponse{}}
+	service.SetClientMessageStore(store)
+
+	conversationID := uuid.New()
+	senderID := uuid.New()
+	ctx := context.Background()
+
+	mockConversationRepo.On("GetParticipant", ctx, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	mockConversationRepo.On("GetParticipants", mock.Anything, mock.Anything).Return([]uuid.UUID{}, nil).Maybe()
+	mockMsgRepo.On("Save", ctx, mock.Anything).Return(nil).Once()
+	mockPublisher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+	mockUserRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+	input := &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text", ClientMsgID: "c-1"}
+	first, err := service.SendMessage(ctx, input)
+	require.NoError(t, err)
+	retry, err := service.SendMessage(ctx, input)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Message, retry.Message, "the retry gets the original ack")
+	assert.Equal(t, "c-1", retry.Message.ClientMsgID)
+	mockMsgRepo.AssertNumberOfCalls(t, "Save", 1)
+	mockPublisher.AssertNumberOfCalls(t, "Publish", 1)
+
+	// A send still in progress makes the retry wait instead of duplicating
+	_, _, err = store.ClaimClientMessage(ctx, senderID, conversationID, "c-2", time.Minute)
+	require.NoError(t, err)
+	_, err = service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text", ClientMsgID: "c-2"})
+	assert.ErrorIs(t, err, ErrSendInProgress)
+}
+
+func TestSendMessage_FailedSendReleasesClientMsgID(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockMsgRepo := new(MockMessageRepository)
+	mockConversationRepo := new(MockConversationRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), mockConversationRepo, new(MockUserRepository))
+	store := &memoryClientMessages{entries: map[string]*domain.MessageResponse{}}
+	service.SetClientMessageStore(store)
+
+	conversationID := uuid.New()
+	senderID := uuid.New()
+	ctx := context.Background()
+	mockConversationRepo.On("GetParticipant", ctx, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	mockMsgRepo.On("Save", ctx, mock.Anything).Return(errors.New("cassandra unavailable"))
+
+	_, err := service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text", ClientMsgID: "c-1"})
+	require.Error(t, err)
+	assert.Empty(t, store.entries, "the client can retry")
+}
+
+func TestSendMessage_SameClientMsgIDInAnotherConversationIsSent(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockMsgRepo := new(MockMessageRepository)
+	mockPublisher := new(MockPublisher)
+	mockConversationRepo := new(MockConversationRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), mockPublisher, new(MockNotificationService), mockConversationRepo, mockUserRepo)
+	service.SetClientMessageStore(&memoryClientMessages{entries: map[string]*domain.MessageResponse{}})
+
+	senderID := uuid.New()
+	ctx := context.Background()
+	mockConversationRepo.On("GetParticipant", ctx, mock.Anything, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	mockConversationRepo.On("GetParticipants", mock.Anything, mock.Anything).Return([]uuid.UUID{}, nil).Maybe()
+	mockMsgRepo.On("Save", ctx, mock.Anything).Return(nil)
+	mockPublisher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	mockUserRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+	var sent []*domain.MessageResponse
+	for _, conversationID := range []uuid.UUID{uuid.New(), uuid.New()} {
+		output, err := service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text", ClientMsgID: "c-1"})
+		require.NoError(t, err)
+		assert.Equal(t, conversationID, output.Message.ConversationID)
+		sent = append(sent, output.Message)
+	}
+
+	assert.NotEqual(t, sent[0].MessageID, sent[1].MessageID)
+	mockMsgRepo.AssertNumberOfCalls(t, "Save", 2)
+}
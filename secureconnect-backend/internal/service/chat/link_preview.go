@@ -0,0 +1,342 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// LinkPreviewCache stores unfurled previews, including failures
+type LinkPreviewCache interface {
+	Get(ctx context.Context, rawURL string) (*domain.LinkPreview, bool, error)
+	Set(ctx context.Context, rawURL string, preview *domain.LinkPreview, ttl time.Duration) error
+}
+
+// RateLimiter counts requests per key in a fixed window
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+// LinkPreviewConfig bounds how much outbound work unfurling can cause
+type LinkPreviewConfig struct {
+	// UserRequests is how many previews a user may request per UserWindow
+	UserRequests int
+	UserWindow   time.Duration
+	// MaxConcurrent caps outbound fetches per instance; requests beyond it are shed
+	MaxConcurrent int
+	// Timeout bounds a whole fetch, including redirects and reading the body
+	Timeout time.Duration
+	// MaxBodyBytes is how much of a page is read looking for metadata
+	MaxBodyBytes int64
+	MaxRedirects int
+	// CacheTTL applies to successful previews and NegativeCacheTTL to failures
+	CacheTTL         time.Duration
+	NegativeCacheTTL time.Duration
+}
+
+// errBlockedAddress is returned by the dialer for non-public addresses
+var errBlockedAddress = errors.New("address is not publicly routable")
+
+// blockedPrefixes are non-public ranges not covered by the netip predicates
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublicAddr reports whether addr may be fetched
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsMulticast() ||
+		addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// LinkPreviewer unfurls URLs shared in messages. It is hardened against being
+// used to amplify traffic or reach internal services: requests are rate limited
+// per user, outbound fetches are capped per instance, responses are bounded in
+// size and time, and every connection, including those made for redirects, is
+// refused unless it goes to a public address.
+type LinkPreviewer struct {
+	cache   LinkPreviewCache
+	limiter RateLimiter
+	config  LinkPreviewConfig
+	client  *http.Client
+	slots   chan struct{}
+
+	// allowAddr decides which resolved addresses may be dialed
+	allowAddr func(netip.Addr) bool
+}
+
+// NewLinkPreviewer creates a new link previewer
+func NewLinkPreviewer(cache LinkPreviewCache, limiter RateLimiter, config LinkPreviewConfig) *LinkPreviewer {
+	if config.UserRequests <= 0 {
+		config.UserRequests = 30
+	}
+	if config.UserWindow <= 0 {
+		config.UserWindow = time.Minute
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 20
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 512 * 1024
+	}
+	if config.MaxRedirects <= 0 {
+		config.MaxRedirects = 3
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 24 * time.Hour
+	}
+	if config.NegativeCacheTTL <= 0 {
+		config.NegativeCacheTTL = 10 * time.Minute
+	}
+
+	p := &LinkPreviewer{
+		cache:     cache,
+		limiter:   limiter,
+		config:    config,
+		slots:     make(chan struct{}, config.MaxConcurrent),
+		allowAddr: isPublicAddr,
+	}
+
+	// The check runs on the resolved address of every connection, so neither a
+	// redirect nor a DNS answer that changes between lookups can reach a
+	// private range. Proxies from the environment are ignored for the same reason.
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !p.allowAddr(addrPort.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	p.client = &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   config.Timeout,
+			ResponseHeaderTimeout: config.Timeout,
+			MaxIdleConns:          config.MaxConcurrent,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > p.config.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", p.config.MaxRedirects)
+			}
+			return validatePreviewURL(req.URL)
+		},
+	}
+	return p
+}
+
+// validatePreviewURL accepts absolute http(s) URLs on default ports without
+// credentials. Literal addresses are checked again when dialing.
+func validatePreviewURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	if u.Host == "" || u.User != nil {
+		return errors.New("url must have a host and no credentials")
+	}
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		return fmt.Errorf("port %s is not allowed", port)
+	}
+	return nil
+}
+
+// Preview returns the preview of rawURL requested by userID. It returns
+// ErrLinkPreviewRateLimited or ErrLinkPreviewBusy when the request is refused
+// and ErrLinkPreviewUnavailable when the page could not be previewed.
+func (p *LinkPreviewer) Preview(ctx context.Context, userID uuid.UUID, rawURL string) (*domain.LinkPreview, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || validatePreviewURL(u) != nil {
+		metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("blocked").Inc()
+		return nil, domain.ErrLinkPreviewBlocked
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(u.Hostname(), "[]")); err == nil && !p.allowAddr(addr) {
+		metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("blocked").Inc()
+		return nil, domain.ErrLinkPreviewBlocked
+	}
+	u.Fragment = ""
+	normalized := u.String()
+
+	// Fail open if Redis is unavailable, the concurrency cap still bounds fetches
+	allowed, err := p.limiter.Allow(ctx, "linkpreview:"+userID.String(), p.config.UserRequests, p.config.UserWindow)
+	if err != nil {
+		logger.Warn("Link preview rate limit check failed", zap.Error(err))
+	} else if !allowed {
+		metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("rate_limited").Inc()
+		return nil, domain.ErrLinkPreviewRateLimited
+	}
+
+	if cached, found, err := p.cache.Get(ctx, normalized); err != nil {
+		logger.Warn("Link preview cache read failed", zap.Error(err))
+	} else if found {
+		metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("cache_hit").Inc()
+		if cached.Unavailable {
+			return nil, domain.ErrLinkPreviewUnavailable
+		}
+		return cached, nil
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("shed").Inc()
+		return nil, domain.ErrLinkPreviewBusy
+	}
+	preview, fetchErr := p.fetch(ctx, normalized)
+	<-p.slots
+
+	if fetchErr != nil {
+		// A cancelled request says nothing about the URL, so it is not cached
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.Debug("Link preview fetch failed", zap.String("host", u.Hostname()), zap.Error(fetchErr))
+		metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("failed").Inc()
+		p.store(ctx, normalized, &domain.LinkPreview{URL: normalized, Unavailable: true}, p.config.NegativeCacheTTL)
+		return nil, domain.ErrLinkPreviewUnavailable
+	}
+
+	metrics.ChatLinkPreviewRequestsTotal.WithLabelValues("fetched").Inc()
+	p.store(ctx, normalized, preview, p.config.CacheTTL)
+	return preview, nil
+}
+
+func (p *LinkPreviewer) store(ctx context.Context, rawURL string, preview *domain.LinkPreview, ttl time.Duration) {
+	if err := p.cache.Set(ctx, rawURL, preview, ttl); err != nil {
+		logger.Warn("Link preview cache write failed", zap.Error(err))
+	}
+}
+
+// fetch downloads at most MaxBodyBytes of an HTML page and extracts its metadata
+func (p *LinkPreviewer) fetch(ctx context.Context, rawURL string) (*domain.LinkPreview, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "SecureConnect-LinkPreview/1.0")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return nil, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	preview := parsePreview(io.LimitReader(resp.Body, p.config.MaxBodyBytes))
+	preview.URL = rawURL
+	if preview.Title == "" && preview.Description == "" {
+		return nil, errors.New("page has no title or description")
+	}
+	return preview, nil
+}
+
+// parsePreview reads Open Graph tags, falling back to <title> and the
+// description meta tag. It stops at the end of <head>.
+func parsePreview(r io.Reader) *domain.LinkPreview {
+	preview := &domain.LinkPreview{}
+	var title string
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if preview.Title == "" {
+				preview.Title = title
+			}
+			return preview
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				if preview.Title == "" {
+					preview.Title = title
+				}
+				return preview
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "body":
+				if preview.Title == "" {
+					preview.Title = title
+				}
+				return preview
+			case "title":
+				if tokenizer.Next() == html.TextToken {
+					title = strings.TrimSpace(string(tokenizer.Text()))
+				}
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = tokenizer.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = strings.TrimSpace(string(v))
+					}
+				}
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "description":
+					if preview.Description == "" {
+						preview.Description = content
+					}
+				case "og:image":
+					preview.ImageURL = content
+				case "og:site_name":
+					preview.SiteName = content
+				}
+			}
+		}
+	}
+}
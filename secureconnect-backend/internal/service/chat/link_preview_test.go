@@ -0,0 +1,162 @@
+package chat
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakePreviewCache is an in-memory LinkPreviewCache
+type fakePreviewCache struct {
+	mu      sync.Mutex
+	entries map[string]*domain.LinkPreview
+}
+
+func (f *fakePreviewCache) Get(ctx context.Context, rawURL string) (*domain.LinkPreview, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	preview, ok := f.entries[rawURL]
+	return preview, ok, nil
+}
+
+func (f *fakePreviewCache) Set(ctx context.Context, rawURL string, preview *domain.LinkPreview, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries[rawURL] = preview
+	return nil
+}
+
+// fakeLimiter counts requests per key without expiring them
+type fakeLimiter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts[key]++
+	return f.counts[key] <= limit, nil
+}
+
+// newTestPreviewer allows loopback so it can reach httptest servers
+func newTestPreviewer(config LinkPreviewConfig) (*LinkPreviewer, *fakePreviewCache) {
+	cache := &fakePreviewCache{entries: map[string]*domain.LinkPreview{}}
+	p := NewLinkPreviewer(cache, &fakeLimiter{counts: map[string]int{}}, config)
+	p.allowAddr = func(addr netip.Addr) bool { return addr.IsLoopback() }
+	return p, cache
+}
+
+// routeToServer sends connections for example.com to server. Every other
+// address goes through the previewer's own dialer and its address check.
+func routeToServer(p *LinkPreviewer, server *httptest.Server) {
+	transport := p.client.Transport.(*http.Transport)
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, _ := net.SplitHostPort(addr); host == "example.com" {
+			addr = server.Listener.Addr().String()
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+func previewPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<html><head><title>Fallback</title><meta property="og:title" content="Hello"><meta name="description" content="A page"></head><body>ignored</body></html>`))
+}
+
+func TestLinkPreviewer_RateLimitsPerUser(t *testing.T) {
+	logger.InitDefault("test")
+	p, cache := newTestPreviewer(LinkPreviewConfig{UserRequests: 2})
+	cache.entries["https://example.com/a"] = &domain.LinkPreview{URL: "https://example.com/a", Title: "A"}
+
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	for i := 0; i < 2; i++ {
+		preview, err := p.Preview(ctx, alice, "https://example.com/a")
+		require.NoError(t, err)
+		assert.Equal(t, "A", preview.Title)
+	}
+
+	_, err := p.Preview(ctx, alice, "https://example.com/a")
+	assert.ErrorIs(t, err, domain.ErrLinkPreviewRateLimited)
+
+	_, err = p.Preview(ctx, bob, "https://example.com/a")
+	assert.NoError(t, err, "the limit is per user")
+}
+
+func TestLinkPreviewer_ShedsFetchesOverConcurrencyCap(t *testing.T) {
+	logger.InitDefault("test")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		previewPage(w, r)
+	}))
+	defer server.Close()
+
+	p, _ := newTestPreviewer(LinkPreviewConfig{MaxConcurrent: 1})
+	routeToServer(p, server)
+
+	ctx := context.Background()
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Preview(ctx, uuid.New(), "http://example.com/slow")
+		done <- err
+	}()
+	<-started
+
+	_, err := p.Preview(ctx, uuid.New(), "http://example.com/other")
+	assert.ErrorIs(t, err, domain.ErrLinkPreviewBusy)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	preview, err := p.Preview(ctx, uuid.New(), "http://example.com/other")
+	require.NoError(t, err, "a slot is free again once the first fetch finishes")
+	assert.Equal(t, "Hello", preview.Title)
+	assert.Equal(t, "A page", preview.Description)
+}
+
+func TestLinkPreviewer_BlocksPrivateAddressesAndCachesFailures(t *testing.T) {
+	logger.InitDefault("test")
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.Redirect(w, r, "http://10.0.0.1/admin", http.StatusFound)
+	}))
+	defer server.Close()
+
+	p, cache := newTestPreviewer(LinkPreviewConfig{Timeout: time.Second})
+	routeToServer(p, server)
+
+	ctx := context.Background()
+	_, err := p.Preview(ctx, uuid.New(), "http://10.0.0.1/")
+	assert.ErrorIs(t, err, domain.ErrLinkPreviewBlocked, "literal private address is refused before fetching")
+
+	_, err = p.Preview(ctx, uuid.New(), "http://example.com/redirect")
+	assert.ErrorIs(t, err, domain.ErrLinkPreviewUnavailable, "redirect to a private range is not followed")
+	assert.True(t, cache.entries["http://example.com/redirect"].Unavailable)
+
+	_, err = p.Preview(ctx, uuid.New(), "http://example.com/redirect")
+	assert.ErrorIs(t, err, domain.ErrLinkPreviewUnavailable)
+	assert.Equal(t, 1, hits, "negative result is served from cache")
+
+	_, err = p.Preview(ctx, uuid.New(), "http://example.com:8080/")
+	assert.ErrorIs(t, err, domain.ErrLinkPreviewBlocked, "non-default ports are refused")
+}
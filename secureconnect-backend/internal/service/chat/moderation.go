@@ -0,0 +1,134 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// DefaultModerationTimeout bounds one moderation check
+const DefaultModerationTimeout = 2 * time.Second
+
+// Moderator scans outbound plaintext messages before they are stored and
+// delivered. A blocked message carries a reason shown to the sender.
+type Moderator interface {
+	Check(ctx context.Context, msg *domain.Message) (allow bool, reason string, err error)
+}
+
+// QuarantineStore keeps blocked messages for review
+type QuarantineStore interface {
+	Quarantine(ctx context.Context, msg *domain.Message, reason string) error
+}
+
+// ModerationConfig controls how moderation checks are run
+type ModerationConfig struct {
+	// Timeout bounds each check so a slow moderation service cannot stall
+	// sending; <= 0 uses DefaultModerationTimeout
+	Timeout time.Duration
+	// FailOpen delivers messages whose check failed or timed out instead of
+	// rejecting them
+	FailOpen bool
+}
+
+// SetModerator checks every message of conversations that are not end-to-end
+// encrypted with moderator before it is stored or relayed. Whether a
+// conversation is encrypted is read from settings, never from the message: the
+// server cannot read E2EE messages, but a sender could mark any message
+// encrypted to skip the check.
+func (s *Service) SetModerator(moderator Moderator, settings SearchSettingsRepository, config ModerationConfig) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultModerationTimeout
+	}
+	s.moderator = moderator
+	s.moderationSettings = settings
+	s.moderation = config
+}
+
+// SetQuarantineStore keeps blocked messages for review instead of dropping them
+func (s *Service) SetQuarantineStore(store QuarantineStore) {
+	s.quarantine = store
+}
+
+// CheckRelayedMessage checks a chat message a WebSocket client sends straight
+// to the conversation, which is relayed without being stored. Like
+// SendMessage, it returns domain.ErrNotParticipant or a *domain.MutedError
+// when the sender may not post, and a *domain.BlockedMessageError when
+// moderation rejects the message.
+func (s *Service) CheckRelayedMessage(ctx context.Context, conversationID, senderID uuid.UUID, content string) error {
+	if err := s.checkCanPost(ctx, conversationID, senderID); err != nil {
+		return err
+	}
+	return s.moderate(ctx, &domain.Message{
+		MessageID:      uuid.New(),
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Content:        content,
+		MessageType:    "text",
+		SentAt:         time.Now(),
+	})
+}
+
+// moderate returns a *domain.BlockedMessageError when msg may not be sent
+func (s *Service) moderate(ctx context.Context, msg *domain.Message) error {
+	if s.moderator == nil || s.conversationEncrypted(ctx, msg.ConversationID) {
+		return nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, s.moderation.Timeout)
+	defer cancel()
+
+	allow, reason, err := s.moderator.Check(checkCtx, msg)
+	if err != nil {
+		result := "error"
+		if errors.Is(err, context.DeadlineExceeded) {
+			result = "timeout"
+		}
+		metrics.ChatModerationChecksTotal.WithLabelValues(result).Inc()
+		logger.Warn("Message moderation check failed",
+			zap.String("conversation_id", msg.ConversationID.String()),
+			zap.Bool("fail_open", s.moderation.FailOpen),
+			zap.Error(err))
+		if s.moderation.FailOpen {
+			return nil
+		}
+		return &domain.BlockedMessageError{Reason: "moderation is unavailable, please retry"}
+	}
+	if allow {
+		metrics.ChatModerationChecksTotal.WithLabelValues("allowed").Inc()
+		return nil
+	}
+
+	metrics.ChatModerationChecksTotal.WithLabelValues("blocked").Inc()
+	blocked := &domain.BlockedMessageError{Reason: reason}
+	if s.quarantine != nil {
+		if err := s.quarantine.Quarantine(ctx, msg, reason); err != nil {
+			logger.Warn("Failed to quarantine blocked message",
+				zap.String("message_id", msg.MessageID.String()),
+				zap.Error(err))
+		} else {
+			blocked.Quarantined = true
+		}
+	}
+	return blocked
+}
+
+// conversationEncrypted reports whether the conversation is end-to-end
+// encrypted. Settings that cannot be read count as not encrypted, so the
+// message is still checked.
+func (s *Service) conversationEncrypted(ctx context.Context, conversationID uuid.UUID) bool {
+	settings, err := s.moderationSettings.GetSettings(ctx, conversationID)
+	if err != nil {
+		logger.Warn("Failed to get settings for moderation, checking message",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return false
+	}
+	return settings.IsE2EEEnabled
+}
@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeModerator blocks messages containing "spam" and records what it saw
+type fakeModerator struct {
+	checked []*domain.Message
+	err     error
+}
+
+func (f *fakeModerator) Check(ctx context.Context, msg *domain.Message) (bool, string, error) {
+	f.checked = append(f.checked, msg)
+	if f.err != nil {
+		return false, "", f.err
+	}
+	if strings.Contains(msg.Content, "spam") {
+		return false, "links to known spam domains", nil
+	}
+	return true, "", nil
+}
+
+type fakeQuarantine struct {
+	reasons map[uuid.UUID]string
+}
+
+func (f *fakeQuarantine) Quarantine(ctx context.Context, msg *domain.Message, reason string) error {
+	f.reasons[msg.MessageID] = reason
+	return nil
+}
+
+func TestSendMessage_Moderation(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		e2ee        bool
+		content     string
+		isEncrypted bool
+		checkErr    error
+		failOpen    bool
+		wantBlocked string // Reason of the expected *domain.BlockedMessageError
+		wantChecked bool
+		quarantined bool
+	}{
+		{name: "allowed messages are stored and broadcast", content: "hello", wantChecked: true},
+		{name: "blocked messages are quarantined", content: "buy spam", wantChecked: true, wantBlocked: "links to known spam domains", quarantined: true},
+		{name: "E2EE conversations are not checked", e2ee: true, content: "c3BhbQ==spam", isEncrypted: true},
+		{name: "the encrypted flag alone does not skip the check", content: "buy spam", isEncrypted: true, wantChecked: true, wantBlocked: "links to known spam domains", quarantined: true},
+		{name: "failed checks reject", content: "hello", checkErr: context.DeadlineExceeded, wantChecked: true, wantBlocked: "moderation is unavailable, please retry"},
+		{name: "failed checks deliver when failing open", content: "hello", checkErr: context.DeadlineExceeded, failOpen: true, wantChecked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, senderID := uuid.New(), uuid.New()
+			messageRepo := new(MockMessageRepository)
+			publisher := new(MockPublisher)
+			conversationRepo := new(MockConversationRepository)
+			userRepo := new(MockUserRepository)
+			conversationRepo.On("GetParticipant", mock.Anything, conversationID, senderID).
+				Return(&domain.ConversationParticipant{Role: "member"}, nil)
+			conversationRepo.On("GetParticipants", mock.Anything, conversationID).Return([]uuid.UUID{senderID}, nil).Maybe()
+			userRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+			service := NewService(messageRepo, nil, publisher, nil, conversationRepo, userRepo)
+			moderator := &fakeModerator{err: tt.checkErr}
+			settings := &fakeSearchSettings{settings: domain.ConversationSettings{ConversationID: conversationID, IsE2EEEnabled: tt.e2ee}}
+			service.SetModerator(moderator, settings, ModerationConfig{FailOpen: tt.failOpen})
+			quarantine := &fakeQuarantine{reasons: map[uuid.UUID]string{}}
+			service.SetQuarantineStore(quarantine)
+			if tt.wantBlocked == "" {
+				messageRepo.On("Save", ctx, mock.Anything).Return(nil).Once()
+				publisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).Return(nil).Once()
+			}
+
+			_, err := service.SendMessage(ctx, &SendMessageInput{
+				ConversationID: conversationID,
+				SenderID:       senderID,
+				Content:        tt.content,
+				IsEncrypted:    tt.isEncrypted,
+				MessageType:    "text",
+			})
+
+			assert.Equal(t, tt.wantChecked, len(moderator.checked) == 1)
+			if tt.wantBlocked == "" {
+				require.NoError(t, err)
+				messageRepo.AssertExpectations(t)
+				publisher.AssertExpectations(t)
+				return
+			}
+			var blocked *domain.BlockedMessageError
+			require.ErrorAs(t, err, &blocked)
+			assert.ErrorIs(t, err, domain.ErrMessageBlocked)
+			assert.Equal(t, tt.wantBlocked, blocked.Reason)
+			assert.Equal(t, tt.quarantined, blocked.Quarantined)
+			if tt.quarantined {
+				assert.Len(t, quarantine.reasons, 1)
+			}
+			messageRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+			publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCheckRelayedMessage(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+	conversationID := uuid.New()
+	member, muted, outsider := uuid.New(), uuid.New(), uuid.New()
+	mutedUntil := time.Now().Add(time.Hour)
+
+	conversationRepo := new(MockConversationRepository)
+	conversationRepo.On("GetParticipant", ctx, conversationID, member).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	conversationRepo.On("GetParticipant", ctx, conversationID, muted).Return(&domain.ConversationParticipant{Role: "member", MutedUntil: &mutedUntil}, nil)
+	conversationRepo.On("GetParticipant", ctx, conversationID, outsider).Return(nil, domain.ErrNotParticipant)
+	service := NewService(new(MockMessageRepository), nil, new(MockPublisher), nil, conversationRepo, new(MockUserRepository))
+	service.SetModerator(&fakeModerator{}, &fakeSearchSettings{settings: domain.ConversationSettings{ConversationID: conversationID}}, ModerationConfig{})
+
+	var mutedErr *domain.MutedError
+	tests := []struct {
+		name    string
+		sender  uuid.UUID
+		content string
+		check   func(t *testing.T, err error)
+	}{
+		{"allowed", member, "hello", func(t *testing.T, err error) { assert.NoError(t, err) }},
+		{"blocked by moderation", member, "buy spam", func(t *testing.T, err error) { assert.ErrorIs(t, err, domain.ErrMessageBlocked) }},
+		{"muted sender", muted, "hello", func(t *testing.T, err error) { assert.ErrorAs(t, err, &mutedErr) }},
+		{"not a participant", outsider, "hello", func(t *testing.T, err error) { assert.ErrorIs(t, err, domain.ErrNotParticipant) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, service.CheckRelayedMessage(ctx, conversationID, tt.sender, tt.content))
+		})
+	}
+}
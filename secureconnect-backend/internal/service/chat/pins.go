@@ -0,0 +1,292 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// ErrPinsUnavailable is returned when pinning is not configured
+var ErrPinsUnavailable = errors.New("pinned messages are not enabled")
+
+// PinStore stores the pinned messages of conversations
+type PinStore interface {
+	// PinMessage stores pin and reports whether it is new; an existing pin is
+	// copied into pin. It returns domain.ErrPinLimitReached at maxPins.
+	PinMessage(ctx context.Context, pin *domain.PinnedMessage, maxPins int) (bool, error)
+	GetPin(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.PinnedMessage, error)
+	UnpinMessage(ctx context.Context, conversationID, messageID uuid.UUID) error
+	GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]*domain.PinnedMessage, error)
+	// ReorderPins orders the pins as messageIDs, which must list every pin
+	// exactly once (domain.ErrInvalidPinOrder)
+	ReorderPins(ctx context.Context, conversationID uuid.UUID, messageIDs []uuid.UUID) error
+}
+
+// pinEvent is published on the conversation channel when a message is pinned
+// or unpinned, so connected clients update the pinned list
+type pinEvent struct {
+	Type           string    `json:"type"` // message_pinned or message_unpinned
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"` // Who pinned or unpinned
+	MessageID      uuid.UUID `json:"message_id"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// pinsReorderedEvent is published on the conversation channel when an admin
+// reorders the pins; MessageIDs is the new order
+type pinsReorderedEvent struct {
+	Type           string      `json:"type"` // pins_reordered
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       uuid.UUID   `json:"sender_id"` // Who reordered
+	MessageIDs     []uuid.UUID `json:"message_ids"`
+	Timestamp      time.Time   `json:"timestamp"`
+}
+
+// SetPins enables pinned messages, stored in store. A conversation can pin at
+// most maxPerConversation messages; a non-positive value uses
+// domain.DefaultMaxPinnedMessages.
+func (s *Service) SetPins(store PinStore, maxPerConversation int) {
+	if maxPerConversation <= 0 {
+		maxPerConversation = domain.DefaultMaxPinnedMessages
+	}
+	s.pins = store
+	s.maxPins = maxPerConversation
+}
+
+// PinMessage pins a message of the conversation and publishes a
+// message_pinned event. Any participant may pin; the message must not be
+// deleted (domain.ErrMessageDeleted) and the conversation must have room
+// (domain.ErrPinLimitReached). Pinning a pinned message succeeds without a
+// new event and keeps the original pin.
+func (s *Service) PinMessage(ctx context.Context, conversationID, messageID, byUserID uuid.UUID) (*domain.PinnedMessage, error) {
+	if s.pins == nil {
+		return nil, ErrPinsUnavailable
+	}
+	if err := s.checkParticipant(ctx, conversationID, byUserID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.getMessage(ctx, conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsDeleted() {
+		return nil, domain.ErrMessageDeleted
+	}
+
+	pin := &domain.PinnedMessage{ConversationID: conversationID, MessageID: messageID, PinnedBy: byUserID, MessageSentAt: &message.SentAt}
+	created, err := s.pins.PinMessage(ctx, pin, s.maxPins)
+	if err != nil {
+		if errors.Is(err, domain.ErrPinLimitReached) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to pin message: %w", err)
+	}
+	if created {
+		s.publishPin(ctx, "message_pinned", conversationID, messageID, byUserID)
+	}
+	return pin, nil
+}
+
+// UnpinMessage unpins a message and publishes a message_unpinned event.
+// Participants may unpin their own pins; only a conversation admin may unpin
+// someone else's (domain.ErrUnpinForbidden).
+func (s *Service) UnpinMessage(ctx context.Context, conversationID, messageID, byUserID uuid.UUID) error {
+	if s.pins == nil {
+		return ErrPinsUnavailable
+	}
+	participant, err := s.getParticipant(ctx, conversationID, byUserID)
+	if err != nil {
+		return err
+	}
+
+	pin, err := s.pins.GetPin(ctx, conversationID, messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotPinned) {
+			return err
+		}
+		return fmt.Errorf("failed to get pin: %w", err)
+	}
+	if pin.PinnedBy != byUserID && participant.Role != "admin" {
+		return domain.ErrUnpinForbidden
+	}
+
+	if err := s.pins.UnpinMessage(ctx, conversationID, messageID); err != nil {
+		if errors.Is(err, domain.ErrMessageNotPinned) {
+			return err
+		}
+		return fmt.Errorf("failed to unpin message: %w", err)
+	}
+	s.publishPin(ctx, "message_unpinned", conversationID, messageID, byUserID)
+	return nil
+}
+
+// ReorderPins sets the order of the conversation's pins to messageIDs and
+// publishes a pins_reordered event. Only a conversation admin may reorder
+// (domain.ErrNotConversationAdmin), and messageIDs must list every pinned
+// message exactly once (domain.ErrInvalidPinOrder).
+func (s *Service) ReorderPins(ctx context.Context, conversationID, byUserID uuid.UUID, messageIDs []uuid.UUID) error {
+	if s.pins == nil {
+		return ErrPinsUnavailable
+	}
+	participant, err := s.getParticipant(ctx, conversationID, byUserID)
+	if err != nil {
+		return err
+	}
+	if participant.Role != "admin" {
+		return domain.ErrNotConversationAdmin
+	}
+
+	if err := s.pins.ReorderPins(ctx, conversationID, messageIDs); err != nil {
+		if errors.Is(err, domain.ErrInvalidPinOrder) {
+			return err
+		}
+		return fmt.Errorf("failed to reorder pins: %w", err)
+	}
+
+	eventJSON, err := json.Marshal(&pinsReorderedEvent{
+		Type:           "pins_reordered",
+		ConversationID: conversationID,
+		SenderID:       byUserID,
+		MessageIDs:     messageIDs,
+		Timestamp:      time.Now(),
+	})
+	if err != nil {
+		return nil
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", conversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish pins reordered event",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// GetPinnedMessages returns the conversation's pinned messages in display
+// order, most recently pinned first unless an admin reordered them. Messages that were deleted, purged, or that userID deleted
+// for themselves are left out.
+func (s *Service) GetPinnedMessages(ctx context.Context, conversationID, userID uuid.UUID) ([]*domain.PinnedMessageResponse, error) {
+	if s.pins == nil {
+		return nil, ErrPinsUnavailable
+	}
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	pins, err := s.pins.GetPinnedMessages(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %w", err)
+	}
+
+	found, err := s.getPinnedMessages(ctx, conversationID, pins)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*domain.Message, 0, len(pins))
+	pinByMessage := make(map[uuid.UUID]*domain.PinnedMessage, len(pins))
+	for _, pin := range pins {
+		message, ok := found[pin.MessageID]
+		if !ok || message.IsDeleted() {
+			continue
+		}
+		messages = append(messages, message)
+		pinByMessage[message.MessageID] = pin
+	}
+	messages = s.withoutHidden(ctx, conversationID, userID, messages)
+
+	responses := make([]*domain.PinnedMessageResponse, len(messages))
+	for i, message := range messages {
+		pin := pinByMessage[message.MessageID]
+		responses[i] = &domain.PinnedMessageResponse{
+			Message:      toMessageResponse(message),
+			PinnedBy:     pin.PinnedBy,
+			PinnedAt:     pin.PinnedAt,
+			DisplayOrder: pin.DisplayOrder,
+		}
+	}
+	return responses, nil
+}
+
+// getPinnedMessages reads the messages of pins in one query, by message ID.
+// Messages that no longer exist are left out.
+func (s *Service) getPinnedMessages(ctx context.Context, conversationID uuid.UUID, pins []*domain.PinnedMessage) (map[uuid.UUID]*domain.Message, error) {
+	found := make(map[uuid.UUID]*domain.Message, len(pins))
+	keys := make([]domain.MessageKey, 0, len(pins))
+	for _, pin := range pins {
+		if pin.MessageSentAt != nil {
+			keys = append(keys, domain.MessageKey{SentAt: *pin.MessageSentAt, MessageID: pin.MessageID})
+			continue
+		}
+		// Pins stored before message_sent_at was recorded are read one by one
+		message, err := s.getMessage(ctx, conversationID, pin.MessageID)
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[message.MessageID] = message
+	}
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	messages, err := s.messageRepo.GetByKeys(ctx, conversationID, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %w", err)
+	}
+	for _, message := range messages {
+		found[message.MessageID] = message
+	}
+	return found, nil
+}
+
+// unpinDeleted removes the pin of a message deleted for everyone, so it no
+// longer counts toward the cap. Failures are logged; the pinned list already
+// leaves out deleted messages.
+func (s *Service) unpinDeleted(ctx context.Context, message *domain.Message) {
+	if s.pins == nil {
+		return
+	}
+	err := s.pins.UnpinMessage(ctx, message.ConversationID, message.MessageID)
+	switch {
+	case err == nil:
+		s.publishPin(ctx, "message_unpinned", message.ConversationID, message.MessageID, *message.DeletedBy)
+	case !errors.Is(err, domain.ErrMessageNotPinned):
+		logger.Warn("Failed to unpin deleted message",
+			zap.String("conversation_id", message.ConversationID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+	}
+}
+
+// publishPin publishes a pin event. Failures are logged; the pin is already
+// stored and clients see it when they reload the pinned list.
+func (s *Service) publishPin(ctx context.Context, eventType string, conversationID, messageID, userID uuid.UUID) {
+	eventJSON, err := json.Marshal(&pinEvent{
+		Type:           eventType,
+		ConversationID: conversationID,
+		SenderID:       userID,
+		MessageID:      messageID,
+		Timestamp:      time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", conversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish pin event",
+			zap.String("conversation_id", conversationID.String()),
+			zap.String("message_id", messageID.String()),
+			zap.String("type", eventType),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,406 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// fakePinStore is an in-memory PinStore
+type fakePinStore struct {
+	mu   sync.Mutex
+	pins map[uuid.UUID][]*domain.PinnedMessage
+}
+
+func newFakePinStore() *fakePinStore {
+	return &fakePinStore{pins: make(map[uuid.UUID][]*domain.PinnedMessage)}
+}
+
+func (s *fakePinStore) PinMessage(ctx context.Context, pin *domain.PinnedMessage, maxPins int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.pins[pin.ConversationID] {
+		if existing.MessageID == pin.MessageID {
+			*pin = *existing
+			return false, nil
+		}
+	}
+	if len(s.pins[pin.ConversationID]) >= maxPins {
+		return false, domain.ErrPinLimitReached
+	}
+	pin.PinnedAt = time.Now()
+	pin.DisplayOrder = 0
+	for _, existing := range s.pins[pin.ConversationID] {
+		pin.DisplayOrder = min(pin.DisplayOrder, existing.DisplayOrder)
+	}
+	pin.DisplayOrder--
+	stored := *pin
+	s.pins[pin.ConversationID] = append([]*domain.PinnedMessage{&stored}, s.pins[pin.ConversationID]...)
+	return true, nil
+}
+
+func (s *fakePinStore) GetPin(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.PinnedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pin := range s.pins[conversationID] {
+		if pin.MessageID == messageID {
+			copied := *pin
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrMessageNotPinned
+}
+
+func (s *fakePinStore) UnpinMessage(ctx context.Context, conversationID, messageID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pins := s.pins[conversationID]
+	for i, pin := range pins {
+		if pin.MessageID == messageID {
+			s.pins[conversationID] = append(pins[:i:i], pins[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrMessageNotPinned
+}
+
+func (s *fakePinStore) GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]*domain.PinnedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pins := make([]*domain.PinnedMessage, 0, len(s.pins[conversationID]))
+	for _, pin := range s.pins[conversationID] {
+		copied := *pin
+		pins = append(pins, &copied)
+	}
+	slices.SortStableFunc(pins, func(a, b *domain.PinnedMessage) int { return a.DisplayOrder - b.DisplayOrder })
+	return pins, nil
+}
+
+func (s *fakePinStore) ReorderPins(ctx context.Context, conversationID uuid.UUID, messageIDs []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pins := s.pins[conversationID]
+	if len(messageIDs) != len(pins) {
+		return domain.ErrInvalidPinOrder
+	}
+	order := make(map[uuid.UUID]int, len(messageIDs))
+	for i, messageID := range messageIDs {
+		order[messageID] = i
+	}
+	for _, pin := range pins {
+		if _, ok := order[pin.MessageID]; !ok {
+			return domain.ErrInvalidPinOrder
+		}
+	}
+	for _, pin := range pins {
+		pin.DisplayOrder = order[pin.MessageID]
+	}
+	return nil
+}
+
+// pinMembers is a conversation with a sender, a member and an admin
+func pinMembers(senderID, memberID, adminID uuid.UUID) map[uuid.UUID]*domain.ConversationParticipant {
+	return map[uuid.UUID]*domain.ConversationParticipant{
+		senderID: participant("member"),
+		memberID: participant("member"),
+		adminID:  participant("admin"),
+	}
+}
+
+func TestPinMessage_PublishesAndLists(t *testing.T) {
+	conversationID, senderID, memberID, adminID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	publisher := new(MockPublisher)
+	service := newTestService(messages, publisher, participantsOf(conversationID, pinMembers(senderID, memberID, adminID)))
+	service.SetPins(newFakePinStore(), 0)
+	ctx := context.Background()
+
+	older, newer := messages.save(conversationID, senderID, "agenda"), messages.save(conversationID, senderID, "dial-in")
+	var events []map[string]interface{}
+	publisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).
+		Run(func(args mock.Arguments) {
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &event))
+			events = append(events, event)
+		}).Return(nil)
+	messages.On("GetHiddenMessageIDs", ctx, conversationID, senderID, mock.Anything).Return(map[uuid.UUID]bool{}, nil)
+
+	pin, err := service.PinMessage(ctx, conversationID, older.MessageID, memberID)
+	require.NoError(t, err)
+	assert.Equal(t, memberID, pin.PinnedBy)
+	_, err = service.PinMessage(ctx, conversationID, newer.MessageID, senderID)
+	require.NoError(t, err)
+
+	again, err := service.PinMessage(ctx, conversationID, older.MessageID, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, memberID, again.PinnedBy, "pinning again keeps the original pin")
+
+	require.Len(t, events, 2, "only new pins are published")
+	assert.Equal(t, "message_pinned", events[0]["type"])
+	assert.Equal(t, older.MessageID.String(), events[0]["message_id"])
+	assert.Equal(t, memberID.String(), events[0]["sender_id"])
+
+	pins, err := service.GetPinnedMessages(ctx, conversationID, senderID)
+	require.NoError(t, err)
+	require.Len(t, pins, 2)
+	assert.Equal(t, newer.MessageID, pins[0].Message.MessageID, "most recently pinned first")
+	assert.Equal(t, "agenda", pins[1].Message.Content)
+	assert.Equal(t, memberID, pins[1].PinnedBy)
+	messages.AssertNumberOfCalls(t, "GetByID", 3) // One per PinMessage; the list is read in one batch
+}
+
+func TestPinMessage_Rules(t *testing.T) {
+	tests := []struct {
+		name     string
+		outsider bool
+		full     bool // Another message already took the only pin
+		deleted  bool
+		wantErr  error
+	}{
+		{name: "participants pin"},
+		{name: "only participants may pin", outsider: true, wantErr: domain.ErrNotParticipant},
+		{name: "the pin limit is enforced", full: true, wantErr: domain.ErrPinLimitReached},
+		{name: "deleted messages cannot be pinned", deleted: true, wantErr: domain.ErrMessageDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, memberID := uuid.New(), uuid.New()
+			messages := newSavedMessages()
+			publisher := new(MockPublisher)
+			publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+				memberID: participant("member"),
+			}))
+			store := newFakePinStore()
+			service.SetPins(store, 1)
+			ctx := context.Background()
+
+			if tt.full {
+				_, err := service.PinMessage(ctx, conversationID, messages.save(conversationID, memberID, "first").MessageID, memberID)
+				require.NoError(t, err)
+			}
+			message := messages.save(conversationID, memberID, "hello")
+			if tt.deleted {
+				deletedAt := time.Now()
+				message.DeletedAt = &deletedAt
+			}
+			userID := memberID
+			if tt.outsider {
+				userID = uuid.New()
+			}
+
+			_, err := service.PinMessage(ctx, conversationID, message.MessageID, userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				_, err = store.GetPin(ctx, conversationID, message.MessageID)
+				assert.ErrorIs(t, err, domain.ErrMessageNotPinned)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGetPinnedMessages_RejectsNonParticipant(t *testing.T) {
+	conversationID := uuid.New()
+	service := newTestService(newSavedMessages(), new(MockPublisher), participantsOf(conversationID, nil))
+	service.SetPins(newFakePinStore(), 0)
+
+	_, err := service.GetPinnedMessages(context.Background(), conversationID, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrNotParticipant)
+}
+
+func TestUnpinMessage_OwnPinsOrAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		ownPin    bool
+		notPinned bool
+		wantErr   error
+	}{
+		{name: "participants unpin their own pins", role: "member", ownPin: true},
+		{name: "admins unpin anyone's pins", role: "admin"},
+		{name: "members cannot unpin others' pins", role: "member", wantErr: domain.ErrUnpinForbidden},
+		{name: "messages that are not pinned", role: "member", notPinned: true, wantErr: domain.ErrMessageNotPinned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, userID, pinnerID := uuid.New(), uuid.New(), uuid.New()
+			if tt.ownPin {
+				pinnerID = userID
+			}
+			messages := newSavedMessages()
+			publisher := new(MockPublisher)
+			publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+				userID:   participant(tt.role),
+				pinnerID: participant("member"),
+			}))
+			service.SetPins(newFakePinStore(), 0)
+			ctx := context.Background()
+
+			message := messages.save(conversationID, pinnerID, "pinned")
+			if !tt.notPinned {
+				_, err := service.PinMessage(ctx, conversationID, message.MessageID, pinnerID)
+				require.NoError(t, err)
+			}
+
+			err := service.UnpinMessage(ctx, conversationID, message.MessageID, userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			publisher.AssertCalled(t, "Publish", ctx, "chat:"+conversationID.String(), mock.MatchedBy(func(payload []byte) bool {
+				var event map[string]interface{}
+				return json.Unmarshal(payload, &event) == nil && event["type"] == "message_unpinned"
+			}))
+		})
+	}
+}
+
+func TestDeleteForEveryone_UnpinsMessage(t *testing.T) {
+	conversationID, senderID, memberID := uuid.New(), uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	publisher := new(MockPublisher)
+	publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+		senderID: participant("member"),
+		memberID: participant("member"),
+	}))
+	store := newFakePinStore()
+	service.SetPins(store, 0)
+	ctx := context.Background()
+
+	message := messages.save(conversationID, senderID, "pinned")
+	_, err := service.PinMessage(ctx, conversationID, message.MessageID, memberID)
+	require.NoError(t, err)
+
+	messages.On("SoftDelete", ctx, conversationID, message.MessageID, senderID).Return(nil).Once()
+	require.NoError(t, service.DeleteForEveryone(ctx, conversationID, message.MessageID, senderID))
+
+	_, err = store.GetPin(ctx, conversationID, message.MessageID)
+	assert.ErrorIs(t, err, domain.ErrMessageNotPinned, "a deleted message no longer counts toward the cap")
+}
+
+func TestReorderPins(t *testing.T) {
+	// Orders index the pins "first", "second", "third"; -1 is a message that
+	// is not pinned. The three are pinned in turn, so "third" is listed first.
+	unreordered := []int{2, 1, 0}
+	tests := []struct {
+		name      string
+		role      string // Role of the reordering user; empty for a non-participant
+		order     []int
+		wantErr   error
+		wantOrder []int
+	}{
+		{
+			name:      "admin reorders and the fetch follows the new order",
+			role:      "admin",
+			order:     []int{1, 0, 2},
+			wantOrder: []int{1, 0, 2},
+		},
+		{
+			name:      "members cannot reorder",
+			role:      "member",
+			order:     []int{0, 1, 2},
+			wantErr:   domain.ErrNotConversationAdmin,
+			wantOrder: unreordered,
+		},
+		{
+			name:      "non-participants cannot reorder",
+			order:     []int{0, 1, 2},
+			wantErr:   domain.ErrNotParticipant,
+			wantOrder: unreordered,
+		},
+		{
+			name:      "the order must list every pin",
+			role:      "admin",
+			order:     []int{0, 1},
+			wantErr:   domain.ErrInvalidPinOrder,
+			wantOrder: unreordered,
+		},
+		{
+			name:      "the order may only list pins",
+			role:      "admin",
+			order:     []int{0, 1, -1},
+			wantErr:   domain.ErrInvalidPinOrder,
+			wantOrder: unreordered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, memberID, userID := uuid.New(), uuid.New(), uuid.New()
+			members := map[uuid.UUID]*domain.ConversationParticipant{memberID: participant("member")}
+			if tt.role != "" {
+				members[userID] = participant(tt.role)
+			}
+			messages := newSavedMessages()
+			publisher := new(MockPublisher)
+			service := newTestService(messages, publisher, participantsOf(conversationID, members))
+			service.SetPins(newFakePinStore(), 0)
+			ctx := context.Background()
+
+			var events []map[string]interface{}
+			publisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).
+				Run(func(args mock.Arguments) {
+					var event map[string]interface{}
+					require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &event))
+					events = append(events, event)
+				}).Return(nil)
+			messages.On("GetHiddenMessageIDs", ctx, conversationID, memberID, mock.Anything).Return(map[uuid.UUID]bool{}, nil)
+
+			var pinned []uuid.UUID
+			for _, content := range []string{"first", "second", "third"} {
+				message := messages.save(conversationID, memberID, content)
+				_, err := service.PinMessage(ctx, conversationID, message.MessageID, memberID)
+				require.NoError(t, err)
+				pinned = append(pinned, message.MessageID)
+			}
+			ids := func(order []int) []uuid.UUID {
+				out := make([]uuid.UUID, len(order))
+				for i, index := range order {
+					out[i] = uuid.New()
+					if index >= 0 {
+						out[i] = pinned[index]
+					}
+				}
+				return out
+			}
+			events = nil
+
+			err := service.ReorderPins(ctx, conversationID, userID, ids(tt.order))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, events)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				assert.Equal(t, "pins_reordered", events[0]["type"])
+				assert.Equal(t, userID.String(), events[0]["sender_id"])
+				assert.Len(t, events[0]["message_ids"], len(tt.order))
+			}
+
+			pins, err := service.GetPinnedMessages(ctx, conversationID, memberID)
+			require.NoError(t, err)
+			got := make([]uuid.UUID, len(pins))
+			for i, pin := range pins {
+				got[i] = pin.Message.MessageID
+			}
+			assert.Equal(t, ids(tt.wantOrder), got)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// DefaultReactionEmojis is the reaction allowlist used when none is configured
+var DefaultReactionEmojis = []string{"👍", "❤️", "😂", "😮", "😢", "🙏"}
+
+// ReactionStore stores emoji reactions to messages
+type ReactionStore interface {
+	AddReaction(ctx context.Context, reaction *domain.Reaction) error
+	RemoveReaction(ctx context.Context, reaction *domain.Reaction) error
+	GetReactions(ctx context.Context, conversationID uuid.UUID, messageIDs []uuid.UUID) ([]*domain.Reaction, error)
+}
+
+// reactionEvent is published on the conversation channel when a participant
+// adds or removes a reaction, so connected clients update the counts
+type reactionEvent struct {
+	Type           string    `json:"type"` // reaction_added or reaction_removed
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"` // Who reacted
+	MessageID      uuid.UUID `json:"message_id"`
+	Emoji          string    `json:"emoji"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// SetReactions enables reactions, stored in store. Only the emojis in
+// allowlist may be used; blank entries are ignored and an empty allowlist
+// uses DefaultReactionEmojis.
+func (s *Service) SetReactions(store ReactionStore, allowlist []string) {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, emoji := range allowlist {
+		if emoji = strings.TrimSpace(emoji); emoji != "" {
+			allowed[emoji] = true
+		}
+	}
+	if len(allowed) == 0 {
+		for _, emoji := range DefaultReactionEmojis {
+			allowed[emoji] = true
+		}
+	}
+	s.reactions = store
+	s.reactionEmojis = allowed
+}
+
+// AddReaction reacts to a message with an emoji and publishes a
+// reaction_added event. The user must be able to post to the conversation,
+// the emoji must be allowlisted (domain.ErrReactionNotAllowed) and the
+// message must not be deleted (domain.ErrMessageDeleted). Reacting twice with
+// the same emoji is a no-op for the counts.
+func (s *Service) AddReaction(ctx context.Context, conversationID, messageID, userID uuid.UUID, emoji string) error {
+	if s.reactions == nil {
+		return fmt.Errorf("reactions are not enabled")
+	}
+	if err := s.checkCanPost(ctx, conversationID, userID); err != nil {
+		return err
+	}
+	if !s.reactionEmojis[emoji] {
+		return domain.ErrReactionNotAllowed
+	}
+
+	message, err := s.getMessage(ctx, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+	if message.IsDeleted() {
+		return domain.ErrMessageDeleted
+	}
+
+	reaction := &domain.Reaction{ConversationID: conversationID, MessageID: messageID, UserID: userID, Emoji: emoji}
+	if err := s.reactions.AddReaction(ctx, reaction); err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+	s.publishReaction(ctx, "reaction_added", reaction)
+	return nil
+}
+
+// RemoveReaction takes back the user's reaction to a message with an emoji
+// and publishes a reaction_removed event. Any participant may remove their
+// own reactions, even when muted or the emoji is no longer allowlisted.
+func (s *Service) RemoveReaction(ctx context.Context, conversationID, messageID, userID uuid.UUID, emoji string) error {
+	if s.reactions == nil {
+		return fmt.Errorf("reactions are not enabled")
+	}
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	reaction := &domain.Reaction{ConversationID: conversationID, MessageID: messageID, UserID: userID, Emoji: emoji}
+	if err := s.reactions.RemoveReaction(ctx, reaction); err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	s.publishReaction(ctx, "reaction_removed", reaction)
+	return nil
+}
+
+// attachReactions fills in the reaction counts of a page of messages, marking
+// the ones userID made. Tombstones get none. If reactions cannot be read the
+// messages are returned without them.
+func (s *Service) attachReactions(ctx context.Context, conversationID, userID uuid.UUID, responses []*domain.MessageResponse) {
+	if s.reactions == nil || len(responses) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(responses))
+	for _, response := range responses {
+		if !response.Deleted {
+			ids = append(ids, response.MessageID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	reactions, err := s.reactions.GetReactions(ctx, conversationID, ids)
+	if err != nil {
+		logger.Warn("Failed to read reactions, returning messages without them",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return
+	}
+
+	counts := make(map[uuid.UUID]map[string]*domain.ReactionCount)
+	for _, reaction := range reactions {
+		byEmoji := counts[reaction.MessageID]
+		if byEmoji == nil {
+			byEmoji = make(map[string]*domain.ReactionCount)
+			counts[reaction.MessageID] = byEmoji
+		}
+		count := byEmoji[reaction.Emoji]
+		if count == nil {
+			count = &domain.ReactionCount{Emoji: reaction.Emoji}
+			byEmoji[reaction.Emoji] = count
+		}
+		count.Count++
+		if userID != uuid.Nil && reaction.UserID == userID {
+			count.ReactedByMe = true
+		}
+	}
+
+	for _, response := range responses {
+		byEmoji := counts[response.MessageID]
+		if response.Deleted || len(byEmoji) == 0 {
+			continue
+		}
+		response.Reactions = make([]domain.ReactionCount, 0, len(byEmoji))
+		for _, count := range byEmoji {
+			response.Reactions = append(response.Reactions, *count)
+		}
+		sort.Slice(response.Reactions, func(i, j int) bool {
+			a, b := response.Reactions[i], response.Reactions[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Emoji < b.Emoji
+		})
+	}
+}
+
+// publishReaction publishes a reaction event. Failures are logged; the
+// reaction is already stored and clients see it when they reload history.
+func (s *Service) publishReaction(ctx context.Context, eventType string, reaction *domain.Reaction) {
+	eventJSON, err := json.Marshal(&reactionEvent{
+		Type:           eventType,
+		ConversationID: reaction.ConversationID,
+		SenderID:       reaction.UserID,
+		MessageID:      reaction.MessageID,
+		Emoji:          reaction.Emoji,
+		Timestamp:      time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", reaction.ConversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish reaction",
+			zap.String("conversation_id", reaction.ConversationID.String()),
+			zap.String("message_id", reaction.MessageID.String()),
+			zap.String("type", eventType),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,199 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// fakeReactionStore keeps reactions in memory
+type fakeReactionStore struct {
+	reactions map[domain.Reaction]bool
+}
+
+func newFakeReactionStore() *fakeReactionStore {
+	return &fakeReactionStore{reactions: map[domain.Reaction]bool{}}
+}
+
+func (f *fakeReactionStore) AddReaction(ctx context.Context, reaction *domain.Reaction) error {
+	f.reactions[*reaction] = true
+	return nil
+}
+
+func (f *fakeReactionStore) RemoveReaction(ctx context.Context, reaction *domain.Reaction) error {
+	delete(f.reactions, *reaction)
+	return nil
+}
+
+func (f *fakeReactionStore) GetReactions(ctx context.Context, conversationID uuid.UUID, messageIDs []uuid.UUID) ([]*domain.Reaction, error) {
+	var reactions []*domain.Reaction
+	for reaction := range f.reactions {
+		for _, id := range messageIDs {
+			if reaction.ConversationID == conversationID && reaction.MessageID == id {
+				reactions = append(reactions, &reaction)
+			}
+		}
+	}
+	return reactions, nil
+}
+
+func TestAddReaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string // Configured allowlist; nil keeps the default
+		emoji    string
+		outsider bool
+		deleted  bool
+		wantErr  error
+	}{
+		{name: "allowlisted emojis are stored", allowed: []string{" 👍", "🎉 ", ""}, emoji: "🎉"},
+		{name: "the configured allowlist replaces the default", allowed: []string{" 👍", "🎉 ", ""}, emoji: "❤️", wantErr: domain.ErrReactionNotAllowed},
+		{name: "default emojis are allowed", emoji: DefaultReactionEmojis[0]},
+		{name: "an empty allowlist keeps the default", allowed: []string{""}, emoji: "🎉", wantErr: domain.ErrReactionNotAllowed},
+		{name: "non-participants cannot react", emoji: "👍", outsider: true, wantErr: domain.ErrNotParticipant},
+		{name: "deleted messages cannot get reactions", emoji: "👍", deleted: true, wantErr: domain.ErrMessageDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, memberID := uuid.New(), uuid.New()
+			messages := newSavedMessages()
+			publisher := new(MockPublisher)
+			publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+				memberID: participant("member"),
+			}))
+			store := newFakeReactionStore()
+			service.SetReactions(store, tt.allowed)
+			ctx := context.Background()
+
+			message := messages.save(conversationID, uuid.New(), "hello")
+			if tt.deleted {
+				deletedAt := time.Now()
+				message.DeletedAt = &deletedAt
+			}
+			userID := memberID
+			if tt.outsider {
+				userID = uuid.New()
+			}
+
+			err := service.AddReaction(ctx, conversationID, message.MessageID, userID, tt.emoji)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, store.reactions)
+				publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, store.reactions, 1)
+		})
+	}
+}
+
+func TestSetReactions_DefaultAllowlist(t *testing.T) {
+	conversationID, memberID := uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	publisher := new(MockPublisher)
+	publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+		memberID: participant("member"),
+	}))
+	service.SetReactions(newFakeReactionStore(), []string{""})
+	ctx := context.Background()
+	message := messages.save(conversationID, uuid.New(), "hello")
+
+	for _, emoji := range DefaultReactionEmojis {
+		assert.NoError(t, service.AddReaction(ctx, conversationID, message.MessageID, memberID, emoji))
+	}
+}
+
+func TestRemoveReaction_RejectsNonParticipant(t *testing.T) {
+	conversationID := uuid.New()
+	messages := newSavedMessages()
+	service := newTestService(messages, new(MockPublisher), participantsOf(conversationID, nil))
+	service.SetReactions(newFakeReactionStore(), nil)
+	message := messages.save(conversationID, uuid.New(), "hello")
+
+	err := service.RemoveReaction(context.Background(), conversationID, message.MessageID, uuid.New(), "👍")
+	assert.ErrorIs(t, err, domain.ErrNotParticipant)
+}
+
+func TestReactions_PublishEvents(t *testing.T) {
+	conversationID, memberID := uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	publisher := new(MockPublisher)
+	service := newTestService(messages, publisher, participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+		memberID: participant("member"),
+	}))
+	service.SetReactions(newFakeReactionStore(), nil)
+	ctx := context.Background()
+	message := messages.save(conversationID, uuid.New(), "hello")
+
+	var published [][]byte
+	publisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).
+		Run(func(args mock.Arguments) { published = append(published, args.Get(2).([]byte)) }).Return(nil)
+
+	require.NoError(t, service.AddReaction(ctx, conversationID, message.MessageID, memberID, "👍"))
+	require.NoError(t, service.RemoveReaction(ctx, conversationID, message.MessageID, memberID, "👍"))
+	require.Len(t, published, 2)
+
+	for i, eventType := range []string{"reaction_added", "reaction_removed"} {
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(published[i], &event))
+		assert.Equal(t, eventType, event["type"])
+		assert.Equal(t, message.MessageID.String(), event["message_id"])
+		assert.Equal(t, memberID.String(), event["sender_id"])
+		assert.Equal(t, "👍", event["emoji"])
+	}
+}
+
+func TestGetMessages_AggregatesReactions(t *testing.T) {
+	conversationID, senderID, memberID := uuid.New(), uuid.New(), uuid.New()
+	messages := newSavedMessages()
+	service := newTestService(messages, new(MockPublisher), participantsOf(conversationID, map[uuid.UUID]*domain.ConversationParticipant{
+		memberID: participant("member"),
+	}))
+	ctx := context.Background()
+	store := newFakeReactionStore()
+	service.SetReactions(store, nil)
+
+	popular, quiet := messages.save(conversationID, senderID, "popular"), messages.save(conversationID, senderID, "quiet")
+	deletedAt := time.Now()
+	tombstone := &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, SenderID: senderID, SentAt: time.Now(), DeletedAt: &deletedAt}
+	for _, reaction := range []domain.Reaction{
+		{MessageID: popular.MessageID, UserID: senderID, Emoji: "😂"},
+		{MessageID: popular.MessageID, UserID: memberID, Emoji: "😂"},
+		{MessageID: popular.MessageID, UserID: uuid.New(), Emoji: "👍"},
+		{MessageID: popular.MessageID, UserID: senderID, Emoji: "🙏"},
+		{MessageID: tombstone.MessageID, UserID: memberID, Emoji: "👍"},
+	} {
+		reaction.ConversationID = conversationID
+		store.reactions[reaction] = true
+	}
+	messages.On("GetByConversation", ctx, conversationID, 20, []byte(nil)).
+		Return([]*domain.Message{popular, quiet, tombstone}, []byte(nil), nil)
+	messages.On("GetHiddenMessageIDs", ctx, conversationID, memberID, mock.Anything).Return(map[uuid.UUID]bool{}, nil)
+
+	output, err := service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: memberID, Limit: 20})
+	require.NoError(t, err)
+
+	byID := map[uuid.UUID]*domain.MessageResponse{}
+	for _, msg := range output.Messages {
+		byID[msg.MessageID] = msg
+	}
+	assert.Equal(t, []domain.ReactionCount{
+		{Emoji: "😂", Count: 2, ReactedByMe: true},
+		{Emoji: "👍", Count: 1},
+		{Emoji: "🙏", Count: 1},
+	}, byID[popular.MessageID].Reactions, "most used first, then by emoji")
+	assert.Empty(t, byID[quiet.MessageID].Reactions)
+	assert.Empty(t, byID[tombstone.MessageID].Reactions, "tombstones have no reactions")
+}
@@ -0,0 +1,185 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+const (
+	// DefaultReadReceiptWindow is how long MarkRead calls for one user and
+	// conversation are coalesced before the furthest position is written
+	DefaultReadReceiptWindow = 2 * time.Second
+
+	// readFlushTimeout bounds the store write and publish of one coalesced read
+	readFlushTimeout = 5 * time.Second
+
+	// MaxReadPositionSkew is how far ahead of the server clock a read
+	// position's sent_at may be, allowing for clock differences between servers
+	MaxReadPositionSkew = time.Minute
+)
+
+// ErrReadPositionInFuture is returned when a read position's sent_at is more
+// than MaxReadPositionSkew ahead. Stored positions never move back, so
+// accepting it would pin the user's position past every later message.
+var ErrReadPositionInFuture = errors.New("sent_at is in the future")
+
+// ReadPositionStore persists read positions. AdvanceReadPosition must never
+// move a stored position backwards.
+type ReadPositionStore interface {
+	AdvanceReadPosition(ctx context.Context, position *domain.ReadPosition) (bool, error)
+}
+
+// MarkReadInput is the furthest message a user has seen in a conversation
+type MarkReadInput struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	MessageID      uuid.UUID
+	SentAt         time.Time
+}
+
+// readKey identifies one user's reads in one conversation
+type readKey struct {
+	conversationID uuid.UUID
+	userID         uuid.UUID
+}
+
+// readBatcher coalesces read positions per readKey within a window
+type readBatcher struct {
+	store  ReadPositionStore
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[readKey]*domain.ReadPosition
+}
+
+// readReceiptEvent is published on the conversation channel when a read position advances
+type readReceiptEvent struct {
+	Type           string                 `json:"type"`
+	ConversationID uuid.UUID              `json:"conversation_id"`
+	SenderID       uuid.UUID              `json:"sender_id"`
+	MessageID      uuid.UUID              `json:"message_id"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	Timestamp      time.Time              `json:"timestamp"`
+}
+
+// SetReadPositionStore enables MarkRead. Reads for the same user and
+// conversation within window are coalesced into one write of the furthest
+// position and at most one read event; window <= 0 uses the default.
+func (s *Service) SetReadPositionStore(store ReadPositionStore, window time.Duration) {
+	if window <= 0 {
+		window = DefaultReadReceiptWindow
+	}
+	s.reads = &readBatcher{
+		store:   store,
+		window:  window,
+		pending: make(map[readKey]*domain.ReadPosition),
+	}
+}
+
+// MarkRead records that the user has read the conversation up to a message.
+// The position is written when the user's read window closes, so the call
+// returns before it is stored.
+func (s *Service) MarkRead(ctx context.Context, input *MarkReadInput) error {
+	if s.reads == nil {
+		return errors.New("read receipts are not configured")
+	}
+	if err := s.checkParticipant(ctx, input.ConversationID, input.UserID); err != nil {
+		return err
+	}
+	if input.SentAt.After(time.Now().Add(MaxReadPositionSkew)) {
+		return ErrReadPositionInFuture
+	}
+
+	key := readKey{conversationID: input.ConversationID, userID: input.UserID}
+	position := &domain.ReadPosition{
+		ConversationID: input.ConversationID,
+		UserID:         input.UserID,
+		MessageID:      input.MessageID,
+		SentAt:         input.SentAt,
+	}
+
+	s.reads.mu.Lock()
+	defer s.reads.mu.Unlock()
+	if current, ok := s.reads.pending[key]; ok {
+		if position.SentAt.After(current.SentAt) {
+			s.reads.pending[key] = position
+		}
+		return nil
+	}
+	s.reads.pending[key] = position
+	time.AfterFunc(s.reads.window, func() { s.flushRead(key) })
+	return nil
+}
+
+// FlushPendingReads writes every coalesced read immediately. It is called on
+// shutdown so reads inside an open window are not lost.
+func (s *Service) FlushPendingReads() {
+	if s.reads == nil {
+		return
+	}
+	s.reads.mu.Lock()
+	keys := make([]readKey, 0, len(s.reads.pending))
+	for key := range s.reads.pending {
+		keys = append(keys, key)
+	}
+	s.reads.mu.Unlock()
+
+	for _, key := range keys {
+		s.flushRead(key)
+	}
+}
+
+// flushRead stores the furthest pending position for key and, if it moved,
+// clears the user's unread count and publishes a read event to the conversation
+func (s *Service) flushRead(key readKey) {
+	s.reads.mu.Lock()
+	position, ok := s.reads.pending[key]
+	delete(s.reads.pending, key)
+	s.reads.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readFlushTimeout)
+	defer cancel()
+
+	moved, err := s.reads.store.AdvanceReadPosition(ctx, position)
+	if err != nil {
+		logger.Warn("Failed to store read position",
+			zap.String("conversation_id", key.conversationID.String()),
+			zap.String("user_id", key.userID.String()),
+			zap.Error(err))
+		return
+	}
+	if !moved {
+		return
+	}
+	s.clearUnread(ctx, key.userID, key.conversationID)
+
+	eventJSON, err := json.Marshal(&readReceiptEvent{
+		Type:           "read",
+		ConversationID: position.ConversationID,
+		SenderID:       position.UserID,
+		MessageID:      position.MessageID,
+		Metadata:       map[string]interface{}{"sent_at": position.SentAt.UTC().Format(time.RFC3339Nano)},
+		Timestamp:      time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", key.conversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish read receipt",
+			zap.String("conversation_id", key.conversationID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,181 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeReadStore records every write and, like Redis, keeps only the furthest position
+type fakeReadStore struct {
+	mu       sync.Mutex
+	writes   []*domain.ReadPosition
+	furthest map[readKey]*domain.ReadPosition
+}
+
+func (f *fakeReadStore) AdvanceReadPosition(ctx context.Context, position *domain.ReadPosition) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, position)
+	key := readKey{conversationID: position.ConversationID, userID: position.UserID}
+	if current, ok := f.furthest[key]; ok && !position.SentAt.After(current.SentAt) {
+		return false, nil
+	}
+	f.furthest[key] = position
+	return true, nil
+}
+
+func (f *fakeReadStore) writeCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.writes)
+}
+
+func TestMarkRead_CoalescesRapidReads(t *testing.T) {
+	logger.InitDefault("test")
+
+	conversationRepo := new(MockConversationRepository)
+	publisher := new(MockPublisher)
+	service := NewService(nil, nil, publisher, nil, conversationRepo, nil)
+	store := &fakeReadStore{furthest: map[readKey]*domain.ReadPosition{}}
+	service.SetReadPositionStore(store, 50*time.Millisecond)
+
+	conversationID, userID := uuid.New(), uuid.New()
+	conversationRepo.On("GetParticipant", mock.Anything, conversationID, userID).
+		Return(&domain.ConversationParticipant{}, nil)
+
+	var published []byte
+	publisher.On("Publish", mock.Anything, "chat:"+conversationID.String(), mock.Anything).
+		Run(func(args mock.Arguments) { published = args.Get(2).([]byte) }).
+		Return(nil).Once()
+
+	// Ten reads arriving out of order while the user scrolls
+	base := time.Now().Add(-time.Hour)
+	messageIDs := make([]uuid.UUID, 10)
+	for i := range messageIDs {
+		messageIDs[i] = uuid.New()
+	}
+	for _, i := range []int{2, 0, 5, 9, 1, 7, 3, 8, 4, 6} {
+		require.NoError(t, service.MarkRead(context.Background(), &MarkReadInput{
+			ConversationID: conversationID,
+			UserID:         userID,
+			MessageID:      messageIDs[i],
+			SentAt:         base.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	require.Eventually(t, func() bool { return store.writeCount() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, store.writeCount(), "one write per window")
+	assert.Equal(t, messageIDs[9], store.writes[0].MessageID, "the furthest position is stored")
+
+	publisher.AssertNumberOfCalls(t, "Publish", 1)
+	var event readReceiptEvent
+	require.NoError(t, json.Unmarshal(published, &event))
+	assert.Equal(t, "read", event.Type)
+	assert.Equal(t, userID, event.SenderID)
+	assert.Equal(t, messageIDs[9], event.MessageID)
+}
+
+func TestMarkRead_RejectsNonParticipant(t *testing.T) {
+	logger.InitDefault("test")
+
+	conversationRepo := new(MockConversationRepository)
+	service := NewService(nil, nil, new(MockPublisher), nil, conversationRepo, nil)
+	store := &fakeReadStore{furthest: map[readKey]*domain.ReadPosition{}}
+	service.SetReadPositionStore(store, time.Millisecond)
+
+	conversationRepo.On("GetParticipant", mock.Anything, mock.Anything, mock.Anything).Return(nil, domain.ErrNotParticipant)
+
+	err := service.MarkRead(context.Background(), &MarkReadInput{ConversationID: uuid.New(), UserID: uuid.New(), MessageID: uuid.New()})
+	assert.ErrorIs(t, err, domain.ErrNotParticipant)
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, store.writeCount())
+}
+
+func TestMarkRead_SentAtSkew(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name    string
+		sentAt  time.Time
+		wantErr error
+	}{
+		{name: "in the past", sentAt: time.Now().Add(-time.Hour)},
+		{name: "slightly ahead of the server clock", sentAt: time.Now().Add(MaxReadPositionSkew / 2)},
+		{name: "beyond the skew window", sentAt: time.Now().Add(MaxReadPositionSkew + time.Minute), wantErr: ErrReadPositionInFuture},
+		{name: "far in the future", sentAt: time.Now().AddDate(10, 0, 0), wantErr: ErrReadPositionInFuture},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationRepo := new(MockConversationRepository)
+			publisher := new(MockPublisher)
+			service := NewService(nil, nil, publisher, nil, conversationRepo, nil)
+			store := &fakeReadStore{furthest: map[readKey]*domain.ReadPosition{}}
+			service.SetReadPositionStore(store, time.Millisecond)
+
+			conversationID, userID := uuid.New(), uuid.New()
+			conversationRepo.On("GetParticipant", mock.Anything, conversationID, userID).Return(&domain.ConversationParticipant{}, nil)
+			publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			err := service.MarkRead(context.Background(), &MarkReadInput{ConversationID: conversationID, UserID: userID, MessageID: uuid.New(), SentAt: tt.sentAt})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				time.Sleep(20 * time.Millisecond)
+				assert.Zero(t, store.writeCount(), "nothing is stored")
+				return
+			}
+			require.NoError(t, err)
+			require.Eventually(t, func() bool { return store.writeCount() == 1 }, time.Second, 5*time.Millisecond)
+		})
+	}
+}
+
+// fakeMembership answers IsMember from a fixed set of members
+type fakeMembership map[uuid.UUID]bool
+
+func (f fakeMembership) IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error) {
+	return f[userID], nil
+}
+
+func TestMarkRead_ChecksMembershipThroughChecker(t *testing.T) {
+	logger.InitDefault("test")
+	member, outsider := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		wantErr error
+	}{
+		{name: "member", userID: member},
+		{name: "outsider", userID: outsider, wantErr: domain.ErrNotParticipant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No GetParticipant expectation: the participant row is never read
+			conversationRepo := new(MockConversationRepository)
+			service := NewService(nil, nil, new(MockPublisher), nil, conversationRepo, nil)
+			service.SetReadPositionStore(&fakeReadStore{furthest: map[readKey]*domain.ReadPosition{}}, time.Hour)
+			service.SetMembershipChecker(fakeMembership{member: true})
+
+			err := service.MarkRead(context.Background(), &MarkReadInput{ConversationID: uuid.New(), UserID: tt.userID, MessageID: uuid.New()})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			conversationRepo.AssertNotCalled(t, "GetParticipant", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
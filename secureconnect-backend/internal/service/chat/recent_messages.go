@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+const (
+	// DefaultRecentMessagesSize is how many messages per conversation are cached
+	DefaultRecentMessagesSize = 50
+	// DefaultRecentMessagesTTL is how long a cached message is kept
+	DefaultRecentMessagesTTL = 24 * time.Hour
+)
+
+// ErrMessagesUnavailable is returned when history cannot be read from
+// Cassandra or the recent message cache. Clients should retry later.
+var ErrMessagesUnavailable = errors.New("messages are temporarily unavailable")
+
+// RecentMessageCache keeps the latest messages of each conversation
+type RecentMessageCache interface {
+	AddRecentMessages(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message, keep int, ttl time.Duration) error
+	// GetRecentMessages returns up to limit messages, newest first
+	GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]*domain.Message, error)
+	// ReplaceRecentMessage swaps the cached copy of a changed message, if cached
+	ReplaceRecentMessage(ctx context.Context, message *domain.Message) error
+}
+
+// RecentMessagesConfig controls the recent message cache
+type RecentMessagesConfig struct {
+	// Size is how many messages per conversation are kept
+	Size int
+	// TTL is how long a message is kept after it was sent
+	TTL time.Duration
+}
+
+// SetRecentMessageCache caches sent messages so the first page of history can
+// still be served, flagged as degraded, while Cassandra reads fail
+func (s *Service) SetRecentMessageCache(cache RecentMessageCache, config RecentMessagesConfig) {
+	if config.Size <= 0 {
+		config.Size = DefaultRecentMessagesSize
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultRecentMessagesTTL
+	}
+	s.recentMessages = cache
+	s.recentConfig = config
+}
+
+// cacheRecentMessages adds saved messages to the recent message cache.
+// Failures are logged; a missed entry must not fail the send.
+func (s *Service) cacheRecentMessages(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message) {
+	if s.recentMessages == nil {
+		return
+	}
+	if err := s.recentMessages.AddRecentMessages(ctx, conversationID, messages, s.recentConfig.Size, s.recentConfig.TTL); err != nil {
+		logger.Warn("Failed to cache recent messages",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+}
+
+// replaceRecentMessage updates the cached copy of an edited or deleted
+// message so the degraded history does not show its old content. Failures
+// are logged.
+func (s *Service) replaceRecentMessage(ctx context.Context, message *domain.Message) {
+	if s.recentMessages == nil {
+		return
+	}
+	if err := s.recentMessages.ReplaceRecentMessage(ctx, message); err != nil {
+		logger.Warn("Failed to update cached message",
+			zap.String("conversation_id", message.ConversationID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+	}
+}
+
+// recentMessagesFallback serves the first page of history from the cache
+// after a Cassandra read failed. Later pages cannot be served from it.
+func (s *Service) recentMessagesFallback(ctx context.Context, input *GetMessagesInput, readErr error) ([]*domain.Message, error) {
+	if s.recentMessages == nil || len(input.PageState) > 0 {
+		return nil, ErrMessagesUnavailable
+	}
+	messages, err := s.recentMessages.GetRecentMessages(ctx, input.ConversationID, input.Limit)
+	if err != nil {
+		logger.Error("Message history unavailable",
+			zap.String("conversation_id", input.ConversationID.String()),
+			zap.NamedError("cassandra_error", readErr),
+			zap.NamedError("cache_error", err))
+		return nil, ErrMessagesUnavailable
+	}
+	logger.Warn("Serving recent messages from cache (Cassandra read failed)",
+		zap.String("conversation_id", input.ConversationID.String()),
+		zap.Int("messages", len(messages)),
+		zap.Error(readErr))
+	return messages, nil
+}
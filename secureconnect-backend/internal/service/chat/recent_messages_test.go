@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeRecentMessageCache keeps recent messages in memory, oldest first
+type fakeRecentMessageCache struct {
+	mu       sync.Mutex
+	messages map[uuid.UUID][]*domain.Message
+	err      error // returned by GetRecentMessages when set
+}
+
+func newFakeRecentMessageCache() *fakeRecentMessageCache {
+	return &fakeRecentMessageCache{messages: map[uuid.UUID][]*domain.Message{}}
+}
+
+func (f *fakeRecentMessageCache) AddRecentMessages(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message, keep int, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cached := append(f.messages[conversationID], messages...)
+	if len(cached) > keep {
+		cached = cached[len(cached)-keep:]
+	}
+	f.messages[conversationID] = cached
+	return nil
+}
+
+func (f *fakeRecentMessageCache) GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]*domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	cached := f.messages[conversationID]
+	var newest []*domain.Message
+	for i := len(cached) - 1; i >= 0 && len(newest) < limit; i-- {
+		newest = append(newest, cached[i])
+	}
+	return newest, nil
+}
+
+func (f *fakeRecentMessageCache) ReplaceRecentMessage(ctx context.Context, message *domain.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, cached := range f.messages[message.ConversationID] {
+		if cached.MessageID == message.MessageID {
+			copied := *message
+			f.messages[message.ConversationID][i] = &copied
+		}
+	}
+	return nil
+}
+
+func TestGetMessages_ServesCachedMessagesWhenCassandraFails(t *testing.T) {
+	logger.InitDefault("test")
+
+	msgRepo := new(MockMessageRepository)
+	publisher := new(MockPublisher)
+	conversationRepo := new(MockConversationRepository)
+	userRepo := new(MockUserRepository)
+	service := NewService(msgRepo, new(MockPresenceRepository), publisher, new(MockNotificationService), conversationRepo, userRepo)
+	cache := newFakeRecentMessageCache()
+	service.SetRecentMessageCache(cache, RecentMessagesConfig{Size: 2})
+
+	ctx := context.Background()
+	conversationID, senderID := uuid.New(), uuid.New()
+	conversationRepo.On("GetParticipant", ctx, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	conversationRepo.On("GetParticipants", mock.Anything, conversationID).Return([]uuid.UUID{senderID}, nil).Maybe()
+	msgRepo.On("Save", ctx, mock.AnythingOfType("*domain.Message")).Return(nil)
+	publisher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	userRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+	for _, content := range []string{"first", "second", "third"} {
+		_, err := service.SendMessage(ctx, &SendMessageInput{
+			ConversationID: conversationID,
+			SenderID:       senderID,
+			Content:        content,
+			MessageType:    "text",
+		})
+		require.NoError(t, err)
+	}
+
+	msgRepo.On("GetByConversation", ctx, conversationID, 20, []byte(nil)).
+		Return([]*domain.Message(nil), []byte(nil), errors.New("gocql: no hosts available in the pool"))
+	msgRepo.On("GetHiddenMessageIDs", ctx, conversationID, senderID, mock.Anything).Return(map[uuid.UUID]bool{}, nil)
+
+	output, err := service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: senderID, Limit: 20})
+	require.NoError(t, err)
+	assert.True(t, output.Degraded)
+	assert.False(t, output.HasMore)
+	require.Len(t, output.Messages, 2, "only the cached window is returned")
+	assert.Equal(t, "third", output.Messages[0].Content)
+	assert.Equal(t, "second", output.Messages[1].Content)
+}
+
+func TestGetMessages_UnavailableWithoutFallback(t *testing.T) {
+	logger.InitDefault("test")
+
+	msgRepo := new(MockMessageRepository)
+	service := NewService(msgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), new(MockConversationRepository), new(MockUserRepository))
+	cache := newFakeRecentMessageCache()
+	service.SetRecentMessageCache(cache, RecentMessagesConfig{})
+	userID := uuid.New()
+	service.SetMembershipChecker(fakeMembership{userID: true})
+
+	ctx := context.Background()
+	conversationID := uuid.New()
+	readErr := errors.New("gocql: no hosts available in the pool")
+	msgRepo.On("GetByConversation", ctx, conversationID, 20, mock.Anything).Return([]*domain.Message(nil), []byte(nil), readErr)
+
+	// Later pages cannot come from the cache
+	_, err := service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: userID, Limit: 20, PageState: []byte("page-2")})
+	assert.ErrorIs(t, err, ErrMessagesUnavailable)
+
+	// Neither source is available
+	cache.err = errors.New("redis is in degraded mode")
+	_, err = service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: userID, Limit: 20})
+	assert.ErrorIs(t, err, ErrMessagesUnavailable)
+}
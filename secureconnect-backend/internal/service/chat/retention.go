@@ -0,0 +1,277 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+const (
+	// retentionLockKey guards the purge so only one chat-service instance runs it
+	retentionLockKey = "chat:retention-purge"
+
+	// retentionPageSize is how many conversations are read from CockroachDB at a time
+	retentionPageSize = 500
+)
+
+// RetentionMessageRepository deletes old messages per conversation
+type RetentionMessageRepository interface {
+	CountOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) (int, error)
+	DeleteOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) error
+}
+
+// RetentionSearchIndex removes purged messages from the search index
+type RetentionSearchIndex interface {
+	DeleteOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) error
+}
+
+// RetentionConversationRepository lists conversations with their retention settings
+type RetentionConversationRepository interface {
+	ListMessageRetention(ctx context.Context, after uuid.UUID, limit int) ([]domain.ConversationRetention, error)
+}
+
+// Locker provides a distributed lock
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error)
+	Unlock(ctx context.Context, key, token string) error
+	// Extend resets the lock's TTL and reports whether token still holds it
+	Extend(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+}
+
+// ErrRetentionLockLost is returned when a purge run stops because its lock
+// expired or was taken by another instance
+var ErrRetentionLockLost = errors.New("retention purge lock lost")
+
+// RetentionConfig controls the message retention purge
+type RetentionConfig struct {
+	// DefaultDays applies to conversations without their own setting; 0 keeps them forever
+	DefaultDays int
+	// Interval between purge runs
+	Interval time.Duration
+	// DeletesPerSecond caps conversation purges per second to avoid tombstone storms
+	DeletesPerSecond int
+	// DryRun counts what would be purged without deleting anything
+	DryRun bool
+	// LockTTL bounds how long a crashed run can hold the lock. A running purge
+	// renews it every third of LockTTL and stops if it was lost.
+	LockTTL time.Duration
+}
+
+// RetentionResult summarizes one purge run
+type RetentionResult struct {
+	Conversations int  // conversations with messages past their retention
+	Messages      int  // messages purged, or that would be purged in dry-run mode
+	DryRun        bool // nothing was deleted
+	Skipped       bool // another instance holds the lock
+}
+
+// RetentionPurger deletes messages older than each conversation's retention window
+type RetentionPurger struct {
+	messageRepo      RetentionMessageRepository
+	conversationRepo RetentionConversationRepository
+	locker           Locker
+	config           RetentionConfig
+	searchIndex      RetentionSearchIndex // nil until SetSearchIndex
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetentionPurger creates a new retention purger
+func NewRetentionPurger(
+	messageRepo RetentionMessageRepository,
+	conversationRepo RetentionConversationRepository,
+	locker Locker,
+	config RetentionConfig,
+) *RetentionPurger {
+	if config.Interval <= 0 {
+		config.Interval = 24 * time.Hour
+	}
+	if config.DeletesPerSecond <= 0 {
+		config.DeletesPerSecond = 10
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = time.Hour
+	}
+	return &RetentionPurger{
+		messageRepo:      messageRepo,
+		conversationRepo: conversationRepo,
+		locker:           locker,
+		config:           config,
+		now:              time.Now,
+		sleep:            sleepContext,
+	}
+}
+
+// SetSearchIndex purges the search index along with the messages, so search
+// does not return messages past their retention
+func (p *RetentionPurger) SetSearchIndex(index RetentionSearchIndex) {
+	p.searchIndex = index
+}
+
+// Start runs the purge now and then every interval until ctx is cancelled
+func (p *RetentionPurger) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := p.Run(ctx); err != nil {
+				logger.Error("Message retention purge failed", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run performs one purge pass if the distributed lock can be taken
+func (p *RetentionPurger) Run(ctx context.Context) (*RetentionResult, error) {
+	token, ok, err := p.locker.TryLock(ctx, retentionLockKey, p.config.LockTTL)
+	if err != nil {
+		metrics.ChatRetentionPurgeRunsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	if !ok {
+		metrics.ChatRetentionPurgeRunsTotal.WithLabelValues("skipped").Inc()
+		return &RetentionResult{Skipped: true, DryRun: p.config.DryRun}, nil
+	}
+	defer func() {
+		if err := p.locker.Unlock(context.Background(), retentionLockKey, token); err != nil {
+			logger.Warn("Failed to release retention purge lock", zap.Error(err))
+		}
+	}()
+
+	// Keep the lock while purging; if it is lost another instance may start,
+	// so this run stops
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go p.holdLock(runCtx, token, cancel)
+
+	result, err := p.purge(runCtx)
+	if errors.Is(context.Cause(runCtx), ErrRetentionLockLost) {
+		err = ErrRetentionLockLost
+	}
+	if err != nil {
+		metrics.ChatRetentionPurgeRunsTotal.WithLabelValues("error").Inc()
+		return result, err
+	}
+
+	metrics.ChatRetentionPurgeRunsTotal.WithLabelValues("success").Inc()
+	logger.Info("Message retention purge finished",
+		zap.Bool("dry_run", result.DryRun),
+		zap.Int("conversations", result.Conversations),
+		zap.Int("messages", result.Messages))
+	return result, nil
+}
+
+// holdLock extends the purge lock every third of its TTL until ctx is done,
+// cancelling ctx with ErrRetentionLockLost when the lock is no longer held.
+// An extension that fails is retried on the next tick while the lock lasts.
+func (p *RetentionPurger) holdLock(ctx context.Context, token string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(p.config.LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := p.locker.Extend(ctx, retentionLockKey, token, p.config.LockTTL)
+		switch {
+		case err != nil:
+			logger.Warn("Failed to extend retention purge lock", zap.Error(err))
+		case !held:
+			logger.Warn("Retention purge lock lost, stopping run")
+			cancel(ErrRetentionLockLost)
+			return
+		}
+	}
+}
+
+func (p *RetentionPurger) purge(ctx context.Context) (*RetentionResult, error) {
+	result := &RetentionResult{DryRun: p.config.DryRun}
+	mode := "deleted"
+	if p.config.DryRun {
+		mode = "dry_run"
+	}
+	pause := time.Second / time.Duration(p.config.DeletesPerSecond)
+	now := p.now()
+
+	after := uuid.Nil
+	for {
+		page, err := p.conversationRepo.ListMessageRetention(ctx, after, retentionPageSize)
+		if err != nil {
+			return result, err
+		}
+
+		for _, conv := range page {
+			days := conv.RetentionDays
+			if days <= 0 {
+				days = p.config.DefaultDays
+			}
+			if days <= 0 {
+				continue
+			}
+			cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+
+			count, err := p.messageRepo.CountOlderThan(ctx, conv.ConversationID, cutoff)
+			if err != nil {
+				return result, err
+			}
+			if count == 0 {
+				continue
+			}
+
+			if !p.config.DryRun {
+				// The index goes first: if it fails the messages are still
+				// counted next run and both are retried
+				if p.searchIndex != nil {
+					if err := p.searchIndex.DeleteOlderThan(ctx, conv.ConversationID, cutoff); err != nil {
+						return result, err
+					}
+				}
+				if err := p.messageRepo.DeleteOlderThan(ctx, conv.ConversationID, cutoff); err != nil {
+					return result, err
+				}
+				if err := p.sleep(ctx, pause); err != nil {
+					return result, err
+				}
+			}
+
+			result.Conversations++
+			result.Messages += count
+			metrics.ChatRetentionPurgedMessagesTotal.WithLabelValues(mode).Add(float64(count))
+		}
+
+		if len(page) < retentionPageSize {
+			return result, nil
+		}
+		after = page[len(page)-1].ConversationID
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("retention purge interrupted: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,239 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeRetentionStore holds message timestamps per conversation
+type fakeRetentionStore struct {
+	messages  map[uuid.UUID][]time.Time
+	retention []domain.ConversationRetention
+	deletes   int
+}
+
+func (f *fakeRetentionStore) CountOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) (int, error) {
+	count := 0
+	for _, sentAt := range f.messages[conversationID] {
+		if sentAt.Before(cutoff) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeRetentionStore) DeleteOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) error {
+	f.deletes++
+	var kept []time.Time
+	for _, sentAt := range f.messages[conversationID] {
+		if !sentAt.Before(cutoff) {
+			kept = append(kept, sentAt)
+		}
+	}
+	f.messages[conversationID] = kept
+	return nil
+}
+
+func (f *fakeRetentionStore) ListMessageRetention(ctx context.Context, after uuid.UUID, limit int) ([]domain.ConversationRetention, error) {
+	if after != uuid.Nil {
+		return nil, nil
+	}
+	return f.retention, nil
+}
+
+// fakeLocker is a single in-process lock. lost makes Extend report that the
+// lock is no longer held.
+type fakeLocker struct {
+	mu      sync.Mutex
+	held    bool
+	lost    bool
+	locks   int
+	extends int
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held {
+		return "", false, nil
+	}
+	l.held = true
+	l.locks++
+	return "token", true, nil
+}
+
+func (l *fakeLocker) Unlock(ctx context.Context, key, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held = false
+	return nil
+}
+
+func (l *fakeLocker) Extend(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.extends++
+	return !l.lost, nil
+}
+
+func (l *fakeLocker) lockCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.locks
+}
+
+// blockingRetentionStore waits in CountOlderThan until the run is cancelled
+type blockingRetentionStore struct {
+	*fakeRetentionStore
+}
+
+func (b blockingRetentionStore) CountOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestRetentionPurger_Run(t *testing.T) {
+	now := time.Now()
+	days := func(n int) time.Time { return now.Add(-time.Duration(n) * 24 * time.Hour) }
+
+	tests := []struct {
+		name          string
+		dryRun        bool
+		lockHeld      bool
+		searchIndex   func(short, long []time.Time) RetentionSearchIndex
+		wantErr       bool
+		wantSkipped   bool
+		wantMessages  int
+		wantShortLeft int // The conversation with a 7-day setting
+		wantLongLeft  int // The conversation using the 30-day default
+	}{
+		{
+			name:          "deletes only messages past retention",
+			wantMessages:  4,
+			wantShortLeft: 2,
+			wantLongLeft:  3,
+		},
+		{
+			name:          "dry run deletes nothing",
+			dryRun:        true,
+			wantMessages:  4,
+			wantShortLeft: 4,
+			wantLongLeft:  5,
+		},
+		{
+			name:          "skips when another instance holds the lock",
+			lockHeld:      true,
+			wantSkipped:   true,
+			wantShortLeft: 4,
+			wantLongLeft:  5,
+		},
+		{
+			name: "messages are kept until the search index is purged",
+			searchIndex: func(short, long []time.Time) RetentionSearchIndex {
+				return failingSearchIndex{}
+			},
+			wantErr:       true,
+			wantShortLeft: 4,
+			wantLongLeft:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.InitDefault("test")
+			short, long := uuid.New(), uuid.New()
+			store := &fakeRetentionStore{
+				messages: map[uuid.UUID][]time.Time{
+					short: {days(1), days(6), days(8), days(40)},
+					long:  {days(1), days(8), days(29), days(31), days(90)},
+				},
+				retention: []domain.ConversationRetention{
+					{ConversationID: short, RetentionDays: 7},
+					{ConversationID: long, RetentionDays: 0},
+				},
+			}
+			purger := NewRetentionPurger(store, store, &fakeLocker{held: tt.lockHeld}, RetentionConfig{DefaultDays: 30, DryRun: tt.dryRun})
+			purger.now = func() time.Time { return now }
+			purger.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+			if tt.searchIndex != nil {
+				purger.SetSearchIndex(tt.searchIndex(store.messages[short], store.messages[long]))
+			}
+
+			result, err := purger.Run(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantSkipped, result.Skipped)
+				assert.Equal(t, tt.dryRun, result.DryRun)
+				assert.Equal(t, tt.wantMessages, result.Messages)
+			}
+			assert.Len(t, store.messages[short], tt.wantShortLeft)
+			assert.Len(t, store.messages[long], tt.wantLongLeft)
+		})
+	}
+}
+
+func TestRetentionPurger_PurgesSearchIndex(t *testing.T) {
+	logger.InitDefault("test")
+	now := time.Now()
+	conversationID := uuid.New()
+	sentAt := []time.Time{now.Add(-time.Hour), now.Add(-48 * time.Hour)}
+	store := &fakeRetentionStore{
+		messages:  map[uuid.UUID][]time.Time{conversationID: sentAt},
+		retention: []domain.ConversationRetention{{ConversationID: conversationID, RetentionDays: 1}},
+	}
+	index := &fakeRetentionStore{messages: map[uuid.UUID][]time.Time{conversationID: sentAt}}
+
+	purger := NewRetentionPurger(store, store, &fakeLocker{}, RetentionConfig{})
+	purger.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	purger.SetSearchIndex(index)
+	_, err := purger.Run(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, index.messages[conversationID], 1)
+}
+
+func TestRetentionPurger_StopsWhenLockLost(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID := uuid.New()
+	store := &fakeRetentionStore{
+		messages:  map[uuid.UUID][]time.Time{conversationID: {time.Now().Add(-48 * time.Hour)}},
+		retention: []domain.ConversationRetention{{ConversationID: conversationID, RetentionDays: 1}},
+	}
+	locker := &fakeLocker{lost: true}
+
+	purger := NewRetentionPurger(blockingRetentionStore{store}, store, locker, RetentionConfig{LockTTL: 30 * time.Millisecond})
+	_, err := purger.Run(context.Background())
+	assert.ErrorIs(t, err, ErrRetentionLockLost)
+	assert.Equal(t, 1, locker.extends)
+	assert.Len(t, store.messages[conversationID], 1, "nothing is deleted after the lock is lost")
+}
+
+func TestRetentionPurger_StartRunsImmediately(t *testing.T) {
+	logger.InitDefault("test")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	locker := &fakeLocker{}
+
+	purger := NewRetentionPurger(&fakeRetentionStore{}, &fakeRetentionStore{}, locker, RetentionConfig{Interval: time.Hour})
+	purger.Start(ctx)
+	assert.Eventually(t, func() bool { return locker.lockCount() == 1 }, time.Second, 5*time.Millisecond,
+		"the first purge does not wait for the interval")
+}
+
+// failingSearchIndex cannot be purged
+type failingSearchIndex struct{}
+
+func (failingSearchIndex) DeleteOlderThan(ctx context.Context, conversationID uuid.UUID, cutoff time.Time) error {
+	return errors.New("cockroach unavailable")
+}
@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/pagination"
+)
+
+// ErrSearchUnavailable is returned by SearchMessages when no search index is
+// configured
+var ErrSearchUnavailable = errors.New("message search is not available")
+
+// SearchMessagesOutput is one page of search results
+type SearchMessagesOutput struct {
+	Messages []*domain.MessageResponse
+	// NextCursor continues the search on the next page; empty on the last page
+	NextCursor string
+	HasMore    bool
+}
+
+// SearchMessages returns up to limit messages matching query, most relevant
+// first, from the conversations userID participates in, or only from
+// conversationID when it is set. Only plaintext messages of conversations
+// indexed for search are found: E2EE conversations never are. Messages the
+// user deleted for themselves are left out. Pass the previous page's
+// NextCursor as cursor to continue; a cursor that cannot be decoded returns
+// pagination.ErrInvalidCursor.
+func (s *Service) SearchMessages(ctx context.Context, userID uuid.UUID, query string, conversationID *uuid.UUID, limit int, cursor string) (*SearchMessagesOutput, error) {
+	if s.searchIndex == nil {
+		return nil, ErrSearchUnavailable
+	}
+
+	var after *domain.MessageSearchCursor
+	if cursor != "" {
+		decoded, err := decodeSearchCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = decoded
+	}
+
+	if conversationID != nil {
+		if err := s.checkParticipant(ctx, *conversationID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	output := &SearchMessagesOutput{Messages: []*domain.MessageResponse{}}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return output, nil
+	}
+
+	// Fetch one extra hit to know whether another page follows
+	hits, err := s.searchIndex.SearchMessages(ctx, userID, query, conversationID, after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		output.HasMore = true
+		output.NextCursor = encodeSearchCursor(&domain.MessageSearchCursor{
+			Rank:      last.Rank,
+			SentAt:    last.Message.SentAt,
+			MessageID: last.Message.MessageID,
+		})
+	}
+
+	for _, msg := range s.visibleSearchHits(ctx, userID, hits) {
+		output.Messages = append(output.Messages, toMessageResponse(msg))
+	}
+	return output, nil
+}
+
+// visibleSearchHits returns the messages of hits in order, without the ones
+// userID deleted for themselves
+func (s *Service) visibleSearchHits(ctx context.Context, userID uuid.UUID, hits []*domain.MessageSearchHit) []*domain.Message {
+	byConversation := make(map[uuid.UUID][]*domain.Message)
+	for _, hit := range hits {
+		byConversation[hit.Message.ConversationID] = append(byConversation[hit.Message.ConversationID], hit.Message)
+	}
+	visible := make(map[uuid.UUID]bool, len(hits))
+	for conversationID, messages := range byConversation {
+		for _, msg := range s.withoutHidden(ctx, conversationID, userID, messages) {
+			visible[msg.MessageID] = true
+		}
+	}
+
+	messages := make([]*domain.Message, 0, len(hits))
+	for _, hit := range hits {
+		if visible[hit.Message.MessageID] {
+			messages = append(messages, hit.Message)
+		}
+	}
+	return messages
+}
+
+// encodeSearchCursor returns an opaque cursor for a search result position
+func encodeSearchCursor(cursor *domain.MessageSearchCursor) string {
+	raw := strconv.FormatFloat(cursor.Rank, 'g', -1, 64) + ":" +
+		strconv.FormatInt(cursor.SentAt.UnixNano(), 10) + ":" +
+		cursor.MessageID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSearchCursor parses a cursor produced by encodeSearchCursor
+func decodeSearchCursor(cursor string) (*domain.MessageSearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 {
+		return nil, pagination.ErrInvalidCursor
+	}
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+	messageID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+	return &domain.MessageSearchCursor{Rank: rank, SentAt: time.Unix(0, nanos).UTC(), MessageID: messageID}, nil
+}
@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+const (
+	// searchBackfillPageSize is how many messages are read per page when a
+	// conversation is added back to the search index
+	searchBackfillPageSize = 200
+
+	// searchSyncBatchSize is how many queued conversations one sync pass takes
+	searchSyncBatchSize = 50
+)
+
+// SearchIndex is the server-side message search index
+type SearchIndex interface {
+	IndexMessages(ctx context.Context, messages []*domain.Message) error
+	RemoveConversation(ctx context.Context, conversationID uuid.UUID) error
+	RemoveMessage(ctx context.Context, conversationID, messageID uuid.UUID) error
+	SearchMessages(ctx context.Context, userID uuid.UUID, query string, conversationID *uuid.UUID, after *domain.MessageSearchCursor, limit int) ([]*domain.MessageSearchHit, error)
+}
+
+// SearchSettingsRepository reads the settings that decide whether a
+// conversation is indexed
+type SearchSettingsRepository interface {
+	GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error)
+}
+
+// SearchIndexSyncQueue holds conversations whose indexing setting changed
+type SearchIndexSyncQueue interface {
+	RequestSearchIndexSync(ctx context.Context, conversationID uuid.UUID) error
+	PopSearchIndexSyncs(ctx context.Context, count int64) ([]uuid.UUID, error)
+}
+
+// SetSearchIndex indexes saved messages of conversations whose settings
+// allow it and enables SearchMessages. Without an index nothing is indexed.
+func (s *Service) SetSearchIndex(index SearchIndex, settings SearchSettingsRepository) {
+	s.searchIndex = index
+	s.searchSettings = settings
+}
+
+// searchIndexable reports whether the conversation's messages may be indexed.
+// Settings that cannot be read count as not indexable.
+func (s *Service) searchIndexable(ctx context.Context, conversationID uuid.UUID) bool {
+	settings, err := s.searchSettings.GetSettings(ctx, conversationID)
+	if err != nil {
+		logger.Warn("Failed to get settings, message not indexed",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return false
+	}
+	return settings.IsSearchIndexable()
+}
+
+// indexMessages adds newly saved messages of one conversation to the search
+// index. Indexing failures are logged and never fail the send.
+func (s *Service) indexMessages(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message) {
+	if s.searchIndex == nil || !s.searchIndexable(ctx, conversationID) {
+		return
+	}
+	plaintext := plaintextMessages(messages)
+	if len(plaintext) == 0 {
+		return
+	}
+	if err := s.searchIndex.IndexMessages(ctx, plaintext); err != nil {
+		logger.Warn("Failed to index messages",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+}
+
+// unindexMessage removes a deleted message from the search index whatever
+// the conversation's current setting. Failures are logged.
+func (s *Service) unindexMessage(ctx context.Context, message *domain.Message) {
+	if s.searchIndex == nil || message.IsEncrypted {
+		return
+	}
+	if err := s.searchIndex.RemoveMessage(ctx, message.ConversationID, message.MessageID); err != nil {
+		logger.Warn("Failed to remove message from search index",
+			zap.String("conversation_id", message.ConversationID.String()),
+			zap.String("message_id", message.MessageID.String()),
+			zap.Error(err))
+	}
+}
+
+// SyncSearchIndex brings the conversation's index entries in line with its
+// settings: all of them are removed when it is not indexable, and its stored
+// messages are backfilled when it is
+func (s *Service) SyncSearchIndex(ctx context.Context, conversationID uuid.UUID) error {
+	if s.searchIndex == nil {
+		return nil
+	}
+	settings, err := s.searchSettings.GetSettings(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	if !settings.IsSearchIndexable() {
+		if err := s.searchIndex.RemoveConversation(ctx, conversationID); err != nil {
+			return fmt.Errorf("failed to remove conversation from search index: %w", err)
+		}
+		return nil
+	}
+
+	var pageState []byte
+	for first := true; first || len(pageState) > 0; first = false {
+		messages, next, err := s.messageRepo.GetByConversation(ctx, conversationID, searchBackfillPageSize, pageState)
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+		if err := s.searchIndex.IndexMessages(ctx, plaintextMessages(messages)); err != nil {
+			return fmt.Errorf("failed to backfill search index: %w", err)
+		}
+		pageState = next
+	}
+	return nil
+}
+
+// StartSearchIndexSync syncs conversations taken from queue every interval
+// until ctx is cancelled. A failed sync is queued again for the next pass.
+func (s *Service) StartSearchIndexSync(ctx context.Context, queue SearchIndexSyncQueue, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runSearchIndexSync(ctx, queue)
+			}
+		}
+	}()
+}
+
+// runSearchIndexSync performs one pass over the queued conversations
+func (s *Service) runSearchIndexSync(ctx context.Context, queue SearchIndexSyncQueue) {
+	conversationIDs, err := queue.PopSearchIndexSyncs(ctx, searchSyncBatchSize)
+	if err != nil {
+		logger.Warn("Failed to read search index syncs", zap.Error(err))
+		return
+	}
+	for _, conversationID := range conversationIDs {
+		if err := s.SyncSearchIndex(ctx, conversationID); err != nil {
+			logger.Warn("Search index sync failed",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+			if err := queue.RequestSearchIndexSync(ctx, conversationID); err != nil {
+				logger.Error("Failed to requeue search index sync",
+					zap.String("conversation_id", conversationID.String()),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// plaintextMessages drops encrypted messages, whose ciphertext is never
+// indexed, and tombstones of deleted messages
+func plaintextMessages(messages []*domain.Message) []*domain.Message {
+	plaintext := make([]*domain.Message, 0, len(messages))
+	for _, msg := range messages {
+		if !msg.IsEncrypted && !msg.IsDeleted() {
+			plaintext = append(plaintext, msg)
+		}
+	}
+	return plaintext
+}
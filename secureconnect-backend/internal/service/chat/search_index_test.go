@@ -0,0 +1,201 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeSearchIndex keeps indexed message IDs per conversation. Searches rank
+// messages by how many of the query's words they contain.
+type fakeSearchIndex struct {
+	indexed  map[uuid.UUID]map[uuid.UUID]bool
+	messages map[uuid.UUID]*domain.Message
+}
+
+func (f *fakeSearchIndex) IndexMessages(ctx context.Context, messages []*domain.Message) error {
+	for _, msg := range messages {
+		if f.indexed[msg.ConversationID] == nil {
+			f.indexed[msg.ConversationID] = map[uuid.UUID]bool{}
+		}
+		f.indexed[msg.ConversationID][msg.MessageID] = true
+		if f.messages == nil {
+			f.messages = map[uuid.UUID]*domain.Message{}
+		}
+		f.messages[msg.MessageID] = msg
+	}
+	return nil
+}
+
+func (f *fakeSearchIndex) SearchMessages(ctx context.Context, userID uuid.UUID, query string, conversationID *uuid.UUID, after *domain.MessageSearchCursor, limit int) ([]*domain.MessageSearchHit, error) {
+	var hits []*domain.MessageSearchHit
+	for id, msg := range f.messages {
+		if !f.indexed[msg.ConversationID][id] || (conversationID != nil && msg.ConversationID != *conversationID) {
+			continue
+		}
+		rank := 0.0
+		for _, word := range strings.Fields(strings.ToLower(query)) {
+			if strings.Contains(strings.ToLower(msg.Content), word) {
+				rank++
+			}
+		}
+		if rank > 0 {
+			hits = append(hits, &domain.MessageSearchHit{Message: msg, Rank: rank})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		return searchHitBefore(hits[i], hits[j].Rank, hits[j].Message.SentAt, hits[j].Message.MessageID)
+	})
+
+	page := make([]*domain.MessageSearchHit, 0, limit)
+	for _, hit := range hits {
+		if after != nil && !searchHitBefore(&domain.MessageSearchHit{Rank: after.Rank, Message: &domain.Message{SentAt: after.SentAt, MessageID: after.MessageID}}, hit.Rank, hit.Message.SentAt, hit.Message.MessageID) {
+			continue
+		}
+		if len(page) < limit {
+			page = append(page, hit)
+		}
+	}
+	return page, nil
+}
+
+// searchHitBefore reports whether hit comes before the given position in
+// rank, then newest first order
+func searchHitBefore(hit *domain.MessageSearchHit, rank float64, sentAt time.Time, messageID uuid.UUID) bool {
+	if hit.Rank != rank {
+		return hit.Rank > rank
+	}
+	if !hit.Message.SentAt.Equal(sentAt) {
+		return hit.Message.SentAt.After(sentAt)
+	}
+	return hit.Message.MessageID.String() > messageID.String()
+}
+
+func (f *fakeSearchIndex) RemoveConversation(ctx context.Context, conversationID uuid.UUID) error {
+	delete(f.indexed, conversationID)
+	return nil
+}
+
+func (f *fakeSearchIndex) RemoveMessage(ctx context.Context, conversationID, messageID uuid.UUID) error {
+	delete(f.indexed[conversationID], messageID)
+	return nil
+}
+
+type fakeSearchSettings struct {
+	settings domain.ConversationSettings
+}
+
+func (f *fakeSearchSettings) GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error) {
+	copied := f.settings
+	return &copied, nil
+}
+
+// settingsByConversation answers GetSettings per conversation and fails for
+// conversations it does not know
+type settingsByConversation map[uuid.UUID]*domain.ConversationSettings
+
+func (s settingsByConversation) GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error) {
+	settings, ok := s[conversationID]
+	if !ok {
+		return nil, errors.New("settings unavailable")
+	}
+	return settings, nil
+}
+
+// fakeSyncQueue is an in-memory SearchIndexSyncQueue
+type fakeSyncQueue struct {
+	queued []uuid.UUID
+}
+
+func (q *fakeSyncQueue) RequestSearchIndexSync(ctx context.Context, conversationID uuid.UUID) error {
+	q.queued = append(q.queued, conversationID)
+	return nil
+}
+
+func (q *fakeSyncQueue) PopSearchIndexSyncs(ctx context.Context, count int64) ([]uuid.UUID, error) {
+	n := min(int(count), len(q.queued))
+	popped := q.queued[:n:n]
+	q.queued = q.queued[n:]
+	return popped, nil
+}
+
+func TestSyncSearchIndex(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name           string
+		e2ee           bool
+		indexing       *bool
+		wantIndexed    int
+		wantIndexesNew bool
+	}{
+		{name: "plaintext conversations are backfilled without ciphertext", wantIndexed: 2, wantIndexesNew: true},
+		{name: "toggling indexing off removes the conversation", indexing: &off},
+		{name: "E2EE conversations are removed by default", e2ee: true},
+		{name: "E2EE conversations are never indexed even when opted in", e2ee: true, indexing: &on},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.InitDefault("test")
+			ctx := context.Background()
+			conversationID := uuid.New()
+			messages := []*domain.Message{
+				{MessageID: uuid.New(), ConversationID: conversationID, Content: "hello"},
+				{MessageID: uuid.New(), ConversationID: conversationID, Content: "world"},
+				{MessageID: uuid.New(), ConversationID: conversationID, Content: "c2VjcmV0", IsEncrypted: true},
+			}
+			messageRepo := new(MockMessageRepository)
+			messageRepo.On("GetByConversation", mock.Anything, conversationID, searchBackfillPageSize, []byte(nil)).
+				Return(messages, []byte(nil), nil)
+			service := NewService(messageRepo, nil, nil, nil, nil, nil)
+
+			// An entry indexed before the setting changed
+			index := &fakeSearchIndex{indexed: map[uuid.UUID]map[uuid.UUID]bool{conversationID: {messages[0].MessageID: true}}}
+			service.SetSearchIndex(index, settingsByConversation{conversationID: {
+				ConversationID: conversationID,
+				IsE2EEEnabled:  tt.e2ee,
+				SearchIndexing: tt.indexing,
+			}})
+
+			require.NoError(t, service.SyncSearchIndex(ctx, conversationID))
+			assert.Len(t, index.indexed[conversationID], tt.wantIndexed)
+			assert.False(t, index.indexed[conversationID][messages[2].MessageID], "ciphertext is never indexed")
+
+			sent := &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, Content: "new"}
+			service.indexMessages(ctx, conversationID, []*domain.Message{sent})
+			assert.Equal(t, tt.wantIndexesNew, index.indexed[conversationID][sent.MessageID], "new messages follow the setting")
+		})
+	}
+}
+
+func TestRunSearchIndexSync_RequeuesFailedSyncs(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+	optedOut, unreadable := uuid.New(), uuid.New()
+	off := false
+
+	service := NewService(new(MockMessageRepository), nil, nil, nil, nil, nil)
+	index := &fakeSearchIndex{indexed: map[uuid.UUID]map[uuid.UUID]bool{
+		optedOut:   {uuid.New(): true},
+		unreadable: {uuid.New(): true},
+	}}
+	service.SetSearchIndex(index, settingsByConversation{optedOut: {ConversationID: optedOut, SearchIndexing: &off}})
+	queue := &fakeSyncQueue{queued: []uuid.UUID{optedOut, unreadable}}
+
+	service.runSearchIndexSync(ctx, queue)
+
+	assert.Empty(t, index.indexed[optedOut], "queued conversations are synced")
+	assert.Len(t, index.indexed[unreadable], 1)
+	assert.Equal(t, []uuid.UUID{unreadable}, queue.queued, "a failed sync is queued for the next pass")
+}
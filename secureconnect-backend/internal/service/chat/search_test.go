@@ -0,0 +1,179 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/pagination"
+)
+
+// searchMessage is a plaintext message sent age ago
+type searchMessage struct {
+	content string
+	age     time.Duration
+	hidden  bool // deleted for the searching user
+}
+
+func TestSearchMessages(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name     string
+		messages []searchMessage
+		query    string
+		scoped   bool // search only the conversation
+		outsider bool // search as a non-participant
+		cursor   string
+		want     []string
+		wantErr  error
+	}{
+		{
+			name: "matches of more words first, newest first among equals",
+			messages: []searchMessage{
+				{content: "release notes for v2", age: 3 * time.Hour},
+				{content: "Release notes are out", age: time.Hour},
+				{content: "the release is tomorrow", age: 2 * time.Minute},
+				{content: "lunch?", age: time.Minute},
+			},
+			query:  "release notes",
+			scoped: true,
+			want:   []string{"Release notes are out", "release notes for v2", "the release is tomorrow"},
+		},
+		{
+			name: "messages deleted for me are left out",
+			messages: []searchMessage{
+				{content: "budget draft", age: time.Hour},
+				{content: "budget final", age: time.Minute, hidden: true},
+			},
+			query: "budget",
+			want:  []string{"budget draft"},
+		},
+		{
+			name:     "non-participants cannot search a conversation",
+			messages: []searchMessage{{content: "budget draft", age: time.Hour}},
+			query:    "budget",
+			scoped:   true,
+			outsider: true,
+			wantErr:  domain.ErrNotParticipant,
+		},
+		{
+			name:     "cursors must come from a previous page",
+			messages: []searchMessage{{content: "budget draft", age: time.Hour}},
+			query:    "budget",
+			cursor:   "not-a-cursor",
+			wantErr:  pagination.ErrInvalidCursor,
+		},
+		{
+			name:     "a blank query finds nothing",
+			messages: []searchMessage{{content: "budget draft", age: time.Hour}},
+			query:    "   ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMsgRepo := new(MockMessageRepository)
+			mockConversationRepo := new(MockConversationRepository)
+			service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), mockConversationRepo, new(MockUserRepository))
+			ctx := context.Background()
+
+			conversationID, member := uuid.New(), uuid.New()
+			index := &fakeSearchIndex{indexed: map[uuid.UUID]map[uuid.UUID]bool{}}
+			service.SetSearchIndex(index, &fakeSearchSettings{settings: domain.ConversationSettings{ConversationID: conversationID}})
+			mockConversationRepo.On("GetParticipant", mock.Anything, conversationID, member).
+				Return(&domain.ConversationParticipant{UserID: member, Role: "member"}, nil)
+			mockConversationRepo.On("GetParticipant", mock.Anything, conversationID, mock.Anything).
+				Return(nil, domain.ErrNotParticipant)
+
+			hidden := map[uuid.UUID]bool{}
+			for _, m := range tt.messages {
+				message := &domain.Message{MessageID: uuid.New(), ConversationID: conversationID, SenderID: uuid.New(), Content: m.content, MessageType: "text", SentAt: time.Now().Add(-m.age)}
+				require.NoError(t, index.IndexMessages(ctx, []*domain.Message{message}))
+				if m.hidden {
+					hidden[message.MessageID] = true
+				}
+			}
+			mockMsgRepo.On("GetHiddenMessageIDs", mock.Anything, conversationID, member, mock.Anything).Return(hidden, nil).Maybe()
+
+			userID := member
+			if tt.outsider {
+				userID = uuid.New()
+			}
+			var scope *uuid.UUID
+			if tt.scoped {
+				scope = &conversationID
+			}
+
+			// Page through two results at a time
+			var found []string
+			cursor := tt.cursor
+			for pages := 0; ; pages++ {
+				require.Less(t, pages, len(tt.messages)+1, "the cursor must advance")
+				output, err := service.SearchMessages(ctx, userID, tt.query, scope, 2, cursor)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				for _, msg := range output.Messages {
+					found = append(found, msg.Content)
+				}
+				if !output.HasMore {
+					assert.Empty(t, output.NextCursor)
+					break
+				}
+				cursor = output.NextCursor
+			}
+			assert.Equal(t, tt.want, found)
+		})
+	}
+}
+
+func TestSearchMessages_NeedsIndex(t *testing.T) {
+	service := NewService(nil, nil, nil, nil, nil, nil)
+	_, err := service.SearchMessages(context.Background(), uuid.New(), "budget", nil, 20, "")
+	assert.ErrorIs(t, err, ErrSearchUnavailable)
+}
+
+func TestSearchMessages_E2EEMessagesAreNotFound(t *testing.T) {
+	logger.InitDefault("test")
+	mockMsgRepo := new(MockMessageRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), new(MockConversationRepository), new(MockUserRepository))
+	ctx := context.Background()
+	member := uuid.New()
+	mockMsgRepo.On("GetHiddenMessageIDs", ctx, mock.Anything, member, mock.Anything).Return(map[uuid.UUID]bool{}, nil)
+
+	plaintext, e2ee := uuid.New(), uuid.New()
+	index := &fakeSearchIndex{indexed: map[uuid.UUID]map[uuid.UUID]bool{}}
+	service.SetSearchIndex(index, settingsByConversation{
+		plaintext: {ConversationID: plaintext},
+		e2ee:      {ConversationID: e2ee, IsE2EEEnabled: true},
+	})
+	service.indexMessages(ctx, plaintext, []*domain.Message{
+		{MessageID: uuid.New(), ConversationID: plaintext, Content: "meet at noon", SentAt: time.Now()},
+		{MessageID: uuid.New(), ConversationID: plaintext, Content: "meet", IsEncrypted: true, SentAt: time.Now()},
+	})
+	service.indexMessages(ctx, e2ee, []*domain.Message{
+		{MessageID: uuid.New(), ConversationID: e2ee, Content: "meet at the usual place", SentAt: time.Now()},
+	})
+
+	output, err := service.SearchMessages(ctx, member, "meet", nil, 20, "")
+	require.NoError(t, err)
+	require.Len(t, output.Messages, 1, "ciphertext and E2EE conversations are never indexed")
+	assert.Equal(t, "meet at noon", output.Messages[0].Content)
+}
+
+func TestSearchCursor_RoundTrips(t *testing.T) {
+	cursor := &domain.MessageSearchCursor{Rank: 0.0607927, SentAt: time.Unix(0, 1760000000123456789).UTC(), MessageID: uuid.New()}
+	decoded, err := decodeSearchCursor(encodeSearchCursor(cursor))
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+}
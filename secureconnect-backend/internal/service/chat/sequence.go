@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// SequenceAllocator hands out increasing per-conversation sequence numbers
+type SequenceAllocator interface {
+	// ReserveSequences reserves n consecutive numbers and returns the last one
+	ReserveSequences(ctx context.Context, conversationID uuid.UUID, n int64) (int64, error)
+}
+
+// SetSequenceAllocator numbers every sent message so messages with the same
+// sent_at still have a total order within their conversation
+func (s *Service) SetSequenceAllocator(allocator SequenceAllocator) {
+	s.sequences = allocator
+}
+
+// assignSequences numbers messages of one conversation in slice order. If no
+// numbers can be reserved the messages are sent unnumbered (seq 0) rather
+// than failing the send; clients then fall back to sent_at.
+func (s *Service) assignSequences(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message) {
+	if s.sequences == nil || len(messages) == 0 {
+		return
+	}
+	last, err := s.sequences.ReserveSequences(ctx, conversationID, int64(len(messages)))
+	if err != nil {
+		logger.Warn("Failed to reserve message sequence, sending unnumbered",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return
+	}
+	first := last - int64(len(messages)) + 1
+	for i, msg := range messages {
+		msg.Seq = first + int64(i)
+	}
+}
+
+// sortNewestFirst orders a page newest first. Cassandra clusters by sent_at
+// and message_id, so only messages sharing a sent_at are reordered: by
+// sequence, then by message_id for messages stored before sequencing.
+func sortNewestFirst(messages []*domain.Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		if !messages[i].SentAt.Equal(messages[j].SentAt) {
+			return messages[i].SentAt.After(messages[j].SentAt)
+		}
+		if messages[i].Seq != messages[j].Seq {
+			return messages[i].Seq > messages[j].Seq
+		}
+		return bytes.Compare(messages[i].MessageID[:], messages[j].MessageID[:]) > 0
+	})
+}
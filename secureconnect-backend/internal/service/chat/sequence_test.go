@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// counterAllocator mimics the Redis INCRBY allocator
+type counterAllocator struct {
+	mu       sync.Mutex
+	counters map[uuid.UUID]int64
+}
+
+func (a *counterAllocator) ReserveSequences(ctx context.Context, conversationID uuid.UUID, n int64) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.counters[conversationID] += n
+	return a.counters[conversationID], nil
+}
+
+func TestSendMessages_SameTimestampGetsIncreasingSequences(t *testing.T) {
+	logger.InitDefault("test")
+
+	mockMsgRepo := new(MockMessageRepository)
+	mockPublisher := new(MockPublisher)
+	mockConversationRepo := new(MockConversationRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), mockPublisher, new(MockNotificationService), mockConversationRepo, mockUserRepo)
+	service.SetSequenceAllocator(&counterAllocator{counters: map[uuid.UUID]int64{}})
+
+	conversationID := uuid.New()
+	senderID := uuid.New()
+	ctx := context.Background()
+
+	mockConversationRepo.On("GetParticipant", ctx, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	mockConversationRepo.On("GetParticipants", mock.Anything, mock.Anything).Return([]uuid.UUID{}, nil).Maybe()
+	mockMsgRepo.On("SaveBatch", ctx, mock.Anything).Return(nil)
+	mockMsgRepo.On("Save", ctx, mock.Anything).Return(nil)
+	mockPublisher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	mockUserRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+	output, err := service.SendMessages(ctx, []*SendMessageInput{
+		{ConversationID: conversationID, SenderID: senderID, Content: "first", MessageType: "text"},
+		{ConversationID: conversationID, SenderID: senderID, Content: "second", MessageType: "text"},
+	})
+	require.NoError(t, err)
+	first, second := output.Results[0].Message, output.Results[1].Message
+	require.True(t, first.SentAt.Equal(second.SentAt), "batch items share a timestamp")
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Equal(t, int64(2), second.Seq)
+
+	single, err := service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "third", MessageType: "text"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), single.Message.Seq)
+}
+
+func TestGetMessages_OrdersSameTimestampBySequence(t *testing.T) {
+	sentAt := time.Now()
+	lowID := uuid.MustParse("00000000-0000-4000-8000-000000000001")
+	highID := uuid.MustParse("ffffffff-ffff-4fff-bfff-ffffffffffff")
+
+	tests := []struct {
+		name string
+		page []*domain.Message
+		want []string
+	}{
+		{
+			name: "by sequence",
+			page: []*domain.Message{
+				{MessageID: uuid.New(), Content: "older", SentAt: sentAt.Add(-time.Second), Seq: 1},
+				{MessageID: highID, Content: "a", SentAt: sentAt, Seq: 2},
+				{MessageID: lowID, Content: "b", SentAt: sentAt, Seq: 3},
+			},
+			want: []string{"b", "a", "older"},
+		},
+		{
+			name: "unsequenced messages by message_id",
+			page: []*domain.Message{
+				{MessageID: lowID, Content: "low", SentAt: sentAt},
+				{MessageID: uuid.New(), Content: "older", SentAt: sentAt.Add(-time.Second)},
+				{MessageID: highID, Content: "high", SentAt: sentAt},
+			},
+			want: []string{"high", "low", "older"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMsgRepo := new(MockMessageRepository)
+			service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), new(MockConversationRepository), new(MockUserRepository))
+			conversationID, readerID := uuid.New(), uuid.New()
+			service.SetMembershipChecker(fakeMembership{readerID: true})
+			mockMsgRepo.On("GetByConversation", mock.Anything, conversationID, 20, []byte(nil)).Return(tt.page, []byte(nil), nil)
+			mockMsgRepo.On("GetHiddenMessageIDs", mock.Anything, conversationID, readerID, mock.Anything).Return(map[uuid.UUID]bool{}, nil)
+
+			output, err := service.GetMessages(context.Background(), &GetMessagesInput{ConversationID: conversationID, UserID: readerID, Limit: 20})
+			require.NoError(t, err)
+			var got []string
+			for _, message := range output.Messages {
+				got = append(got, message.Content)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,13 +13,23 @@ import (
 	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/domain"
+	pkgContext "secureconnect-backend/pkg/context"
 	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/workerpool"
 )
 
 // MessageRepository interface
 type MessageRepository interface {
 	Save(ctx context.Context, message *domain.Message) error
+	SaveBatch(ctx context.Context, messages []*domain.Message) error
 	GetByConversation(ctx context.Context, conversationID uuid.UUID, limit int, pageState []byte) ([]*domain.Message, []byte, error)
+	GetByID(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.Message, error)
+	GetByKeys(ctx context.Context, conversationID uuid.UUID, keys []domain.MessageKey) ([]*domain.Message, error)
+	Update(ctx context.Context, message *domain.Message) error
+	SoftDelete(ctx context.Context, conversationID, messageID, byUserID uuid.UUID) error
+	HideForUser(ctx context.Context, conversationID, messageID, userID uuid.UUID) error
+	GetHiddenMessageIDs(ctx context.Context, conversationID, userID uuid.UUID, messageIDs []uuid.UUID) (map[uuid.UUID]bool, error)
 }
 
 // PresenceRepository interface
@@ -43,6 +54,15 @@ type NotificationService interface {
 // ConversationRepository interface for getting participants
 type ConversationRepository interface {
 	GetParticipants(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error)
+	GetParticipantsWithDetails(ctx context.Context, conversationID uuid.UUID) ([]*domain.ConversationParticipantDetail, error)
+	GetByID(ctx context.Context, conversationID uuid.UUID) (*domain.Conversation, error)
+	GetParticipant(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ConversationParticipant, error)
+}
+
+// MembershipChecker answers whether a user belongs to a conversation, from a
+// cache where one is configured
+type MembershipChecker interface {
+	IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error)
 }
 
 // UserRepository interface for getting sender details
@@ -68,7 +88,29 @@ type Service struct {
 	notificationService NotificationService
 	conversationRepo    ConversationRepository
 	userRepo            UserRepository
-	notificationSem     chan struct{} // Semaphore for rate limiting notifications
+	membership          MembershipChecker // nil until SetMembershipChecker
+	notifyPool          *workerpool.Pool  // Runs unread counting and push notifications after a send
+	reads               *readBatcher      // Coalesces MarkRead calls; nil until SetReadPositionStore
+	searchIndex         SearchIndex       // nil until SetSearchIndex
+	searchSettings      SearchSettingsRepository
+	moderator           Moderator // nil until SetModerator; messages are then not checked
+	moderationSettings  SearchSettingsRepository
+	moderation          ModerationConfig
+	quarantine          QuarantineStore
+	sequences           SequenceAllocator  // nil until SetSequenceAllocator
+	clientMessages      ClientMessageStore // nil until SetClientMessageStore
+	unread              UnreadCounter      // nil until SetUnreadCounter
+	recentMessages      RecentMessageCache // nil until SetRecentMessageCache
+	recentConfig        RecentMessagesConfig
+	bots                BotDirectory // nil until SetBots
+	botSettings         SearchSettingsRepository
+	botSender           WebhookSender
+	botSem              chan struct{} // Limits concurrent webhook deliveries
+	editWindow          time.Duration // How long after sending a message may be edited
+	reactions           ReactionStore // nil until SetReactions
+	reactionEmojis      map[string]bool
+	pins                PinStore // nil until SetPins
+	maxPins             int
 }
 
 // NewService creates a new chat service
@@ -87,8 +129,54 @@ func NewService(
 		notificationService: notificationService,
 		conversationRepo:    conversationRepo,
 		userRepo:            userRepo,
-		notificationSem:     make(chan struct{}, 100), // Limit to 100 concurrent notification routines
+		notifyPool:          workerpool.New("chat_notify", DefaultNotifyWorkers, DefaultNotifyQueueSize),
+		moderation:          ModerationConfig{Timeout: DefaultModerationTimeout},
+		editWindow:          DefaultMessageEditWindow,
+	}
+}
+
+// SetNotificationPool replaces the pool that counts unread messages and sends
+// push notifications. The caller shuts it down, after the servers stop, so
+// queued notifications still go out.
+func (s *Service) SetNotificationPool(pool *workerpool.Pool) {
+	s.notifyPool = pool
+}
+
+// SetMembershipChecker answers membership checks that need no more than
+// whether the user belongs to the conversation. Without one they read the
+// participant row.
+func (s *Service) SetMembershipChecker(checker MembershipChecker) {
+	s.membership = checker
+}
+
+// checkParticipant returns domain.ErrNotParticipant unless userID is in the conversation
+func (s *Service) checkParticipant(ctx context.Context, conversationID, userID uuid.UUID) error {
+	if s.membership != nil {
+		member, err := s.membership.IsMember(ctx, userID, conversationID)
+		if err != nil {
+			return fmt.Errorf("failed to check participant: %w", err)
+		}
+		if !member {
+			return domain.ErrNotParticipant
+		}
+		return nil
+	}
+	_, err := s.getParticipant(ctx, conversationID, userID)
+	return err
+}
+
+// getParticipant reads userID's participant row, for checks that need the
+// role or mute state and so cannot use the cached membership of
+// checkParticipant. It returns domain.ErrNotParticipant for non-members.
+func (s *Service) getParticipant(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ConversationParticipant, error) {
+	participant, err := s.conversationRepo.GetParticipant(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotParticipant) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to check participant: %w", err)
 	}
+	return participant, nil
 }
 
 // SendMessageInput contains message data
@@ -99,6 +187,9 @@ type SendMessageInput struct {
 	IsEncrypted    bool
 	MessageType    string
 	Metadata       map[string]interface{}
+	// ClientMsgID is chosen by the client; a retry with the same ID returns
+	// the original message instead of storing another
+	ClientMsgID string
 }
 
 // SendMessageOutput contains sent message info
@@ -106,8 +197,33 @@ type SendMessageOutput struct {
 	Message *domain.MessageResponse
 }
 
-// SendMessage stores a message and publishes to real-time channel
-func (s *Service) SendMessage(ctx context.Context, input *SendMessageInput) (*SendMessageOutput, error) {
+// SendMessage stores a message and publishes to real-time channel. Senders
+// must be participants and not muted; a muted sender gets a *domain.MutedError.
+// A plaintext message rejected by moderation is neither stored nor broadcast
+// and gets a *domain.BlockedMessageError. With a client message store, a
+// retry carrying an earlier ClientMsgID returns the original message.
+func (s *Service) SendMessage(ctx context.Context, input *SendMessageInput) (output *SendMessageOutput, err error) {
+	if err := s.checkCanPost(ctx, input.ConversationID, input.SenderID); err != nil {
+		return nil, err
+	}
+
+	claimed, sent, err := s.claimClientMessage(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if sent != nil {
+		return &SendMessageOutput{Message: sent}, nil
+	}
+	if claimed {
+		defer func() {
+			if err != nil {
+				s.releaseClientMessage(ctx, input)
+			} else {
+				s.completeClientMessage(ctx, input, output.Message)
+			}
+		}()
+	}
+
 	// Create message entity
 	message := &domain.Message{
 		MessageID:      uuid.New(),
@@ -120,28 +236,21 @@ func (s *Service) SendMessage(ctx context.Context, input *SendMessageInput) (*Se
 		SentAt:         time.Now(),
 	}
 
+	if err := s.moderate(ctx, message); err != nil {
+		return nil, err
+	}
+	s.assignSequences(ctx, input.ConversationID, []*domain.Message{message})
+
 	// Save to Cassandra
 	if err := s.messageRepo.Save(ctx, message); err != nil {
 		return nil, fmt.Errorf("failed to save message: %w", err)
 	}
+	s.indexMessages(ctx, input.ConversationID, []*domain.Message{message})
+	s.cacheRecentMessages(ctx, input.ConversationID, []*domain.Message{message})
+	s.deliverToBots(ctx, input.ConversationID, []*domain.Message{message})
 
-	// Trigger push notifications for conversation participants (non-blocking)
-	// Create a new context with timeout for the goroutine
-	notifyCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	go func() {
-		defer cancel()
-
-		// Acquire semaphore
-		select {
-		case s.notificationSem <- struct{}{}:
-			defer func() { <-s.notificationSem }() // Release
-			s.notifyMessageRecipients(notifyCtx, input.SenderID, input.ConversationID, input.Content)
-		default:
-			// If semaphore full, log warning and skip notification to preserve system stability
-			logger.Warn("Notification queue full, skipping push notification",
-				zap.String("conversation_id", input.ConversationID.String()))
-		}
-	}()
+	// Count unread and trigger push notifications (non-blocking)
+	s.notifyRecipients(ctx, input.SenderID, input.ConversationID, 1, message.SentAt, true)
 
 	// Publish to Redis Pub/Sub for real-time delivery
 	channel := fmt.Sprintf("chat:%s", input.ConversationID)
@@ -172,16 +281,259 @@ func (s *Service) SendMessage(ctx context.Context, input *SendMessageInput) (*Se
 		MessageType:    message.MessageType,
 		Metadata:       message.Metadata,
 		SentAt:         message.SentAt,
+		Seq:            message.Seq,
+		ClientMsgID:    input.ClientMsgID,
 	}
 
 	return &SendMessageOutput{Message: response}, nil
 }
 
+// notifyTimeout bounds sending push notifications for one send. It runs
+// after the request has returned.
+const notifyTimeout = 10 * time.Second
+
+// Defaults for the pool that notifies recipients of new messages
+const (
+	DefaultNotifyWorkers   = 100
+	DefaultNotifyQueueSize = 1024
+)
+
+// MaxSendBatchSize is the maximum number of messages accepted by SendMessages
+const MaxSendBatchSize = 100
+
+// ErrBatchTooLarge is returned when a batch exceeds MaxSendBatchSize
+var ErrBatchTooLarge = fmt.Errorf("batch exceeds maximum size of %d messages", MaxSendBatchSize)
+
+// SendMessageResult is the outcome of a single item of a batch
+type SendMessageResult struct {
+	Index   int                     `json:"index"`
+	Message *domain.MessageResponse `json:"message,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// SendMessagesOutput contains per-item results in input order
+type SendMessagesOutput struct {
+	Results   []*SendMessageResult
+	Succeeded int
+	Failed    int
+}
+
+// batchKey groups the items of a batch sent by one sender to one conversation
+type batchKey struct {
+	conversationID uuid.UUID
+	senderID       uuid.UUID
+}
+
+// SendMessages stores a batch of messages, possibly spanning several conversations
+// and senders. Posting is checked once per sender and conversation, each sender's
+// messages to a conversation are written with a single Cassandra batch, and failures
+// are reported per item instead of failing the batch.
+func (s *Service) SendMessages(ctx context.Context, inputs []*SendMessageInput) (*SendMessagesOutput, error) {
+	if len(inputs) > MaxSendBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]*SendMessageResult, len(inputs))
+	allowed := make(map[batchKey]error) // result of the sender's post check
+	pending := make(map[batchKey][]int) // indexes of valid items
+	var order []batchKey
+	messages := make([]*domain.Message, len(inputs))
+	now := time.Now()
+
+	for i, input := range inputs {
+		results[i] = &SendMessageResult{Index: i}
+		key := batchKey{input.ConversationID, input.SenderID}
+
+		postErr, checked := allowed[key]
+		if !checked {
+			postErr = s.checkCanPost(ctx, input.ConversationID, input.SenderID)
+			allowed[key] = postErr
+		}
+		if postErr != nil {
+			results[i].Error = batchPostError(postErr)
+			continue
+		}
+
+		messages[i] = &domain.Message{
+			MessageID:      uuid.New(),
+			ConversationID: input.ConversationID,
+			SenderID:       input.SenderID,
+			Content:        input.Content,
+			IsEncrypted:    input.IsEncrypted,
+			MessageType:    input.MessageType,
+			Metadata:       input.Metadata,
+			SentAt:         now,
+		}
+		if err := s.moderate(ctx, messages[i]); err != nil {
+			messages[i] = nil
+			results[i].Error = err.Error()
+			continue
+		}
+		if _, ok := pending[key]; !ok {
+			order = append(order, key)
+		}
+		pending[key] = append(pending[key], i)
+	}
+
+	for _, key := range order {
+		indexes := pending[key]
+		batch := make([]*domain.Message, len(indexes))
+		for j, idx := range indexes {
+			batch[j] = messages[idx]
+		}
+		s.assignSequences(ctx, key.conversationID, batch)
+
+		if err := s.messageRepo.SaveBatch(ctx, batch); err != nil {
+			for _, idx := range indexes {
+				results[idx].Error = "failed to save message"
+			}
+			continue
+		}
+
+		for _, idx := range indexes {
+			results[idx].Message = toMessageResponse(messages[idx])
+		}
+		s.indexMessages(ctx, key.conversationID, batch)
+		s.cacheRecentMessages(ctx, key.conversationID, batch)
+		s.publishBatch(ctx, key.conversationID, batch)
+		s.notifyRecipients(ctx, key.senderID, key.conversationID, len(batch), batch[0].SentAt, true)
+		s.deliverToBots(ctx, key.conversationID, batch)
+	}
+
+	output := &SendMessagesOutput{Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			output.Failed++
+		} else {
+			output.Succeeded++
+		}
+	}
+	return output, nil
+}
+
+// checkCanPost returns domain.ErrNotParticipant or a *domain.MutedError when
+// senderID may not post in the conversation. It reads the participant row
+// rather than checkParticipant's cached answer because mutes must apply at once.
+func (s *Service) checkCanPost(ctx context.Context, conversationID, senderID uuid.UUID) error {
+	participant, err := s.getParticipant(ctx, conversationID, senderID)
+	if err != nil {
+		return err
+	}
+	if participant.IsMuted(time.Now()) {
+		return &domain.MutedError{Until: *participant.MutedUntil}
+	}
+	return nil
+}
+
+// batchPostError converts a checkCanPost error into a per-item reason
+func batchPostError(err error) string {
+	var muted *domain.MutedError
+	switch {
+	case errors.As(err, &muted):
+		return muted.Error()
+	case errors.Is(err, domain.ErrNotParticipant):
+		return "not a participant in this conversation"
+	}
+	logger.Warn("Failed to check batch sender", zap.Error(err))
+	return "failed to check participant"
+}
+
+// publishBatch publishes saved messages of a conversation to its real-time channel
+func (s *Service) publishBatch(ctx context.Context, conversationID uuid.UUID, messages []*domain.Message) {
+	channel := fmt.Sprintf("chat:%s", conversationID)
+	for _, message := range messages {
+		messageJSON, err := json.Marshal(message)
+		if err != nil {
+			logger.Warn("Failed to marshal message for pub/sub",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+			continue
+		}
+		if err := s.publisher.Publish(ctx, channel, messageJSON); err != nil {
+			// Redis is most likely unavailable; skip the rest of this conversation
+			logger.Warn("Failed to publish message batch to Redis",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+			return
+		}
+	}
+}
+
+// notifyRecipients counts n new messages as unread and, with push, sends push
+// notifications to every participant except the sender. Both need the
+// participant list, which is read once on the notification pool after the
+// request has returned, so its context is detached from it. When the pool is
+// full the notification is dropped, to preserve system stability.
+func (s *Service) notifyRecipients(ctx context.Context, senderID, conversationID uuid.UUID, n int, sentAt time.Time, push bool) {
+	queued := s.notifyPool.Submit(func() {
+		notifyCtx, cancel := pkgContext.Detached(ctx, notifyTimeout)
+		defer cancel()
+
+		participants, err := s.conversationRepo.GetParticipants(notifyCtx, conversationID)
+		if err != nil {
+			logger.Warn("Failed to get conversation participants for notification",
+				zap.String("conversation_id", conversationID.String()),
+				zap.Error(err))
+			return
+		}
+		recipients := make([]uuid.UUID, 0, len(participants))
+		for _, participantID := range participants {
+			if participantID != senderID {
+				recipients = append(recipients, participantID)
+			}
+		}
+		if len(recipients) == 0 {
+			return
+		}
+		s.countUnread(notifyCtx, conversationID, recipients, n)
+		if push {
+			s.notifyMessageRecipients(notifyCtx, senderID, conversationID, recipients, sentAt)
+		}
+	})
+	if !queued {
+		logger.Warn("Notification pool is full or stopped, skipping notification",
+			zap.String("conversation_id", conversationID.String()))
+	}
+}
+
+// toMessageResponse converts a message entity to its API representation.
+// A message deleted for everyone becomes a tombstone without content.
+func toMessageResponse(message *domain.Message) *domain.MessageResponse {
+	if message.IsDeleted() {
+		return &domain.MessageResponse{
+			MessageID:      message.MessageID,
+			ConversationID: message.ConversationID,
+			SenderID:       message.SenderID,
+			IsEncrypted:    message.IsEncrypted,
+			MessageType:    message.MessageType,
+			SentAt:         message.SentAt,
+			Seq:            message.Seq,
+			Deleted:        true,
+			DeletedAt:      message.DeletedAt,
+		}
+	}
+	return &domain.MessageResponse{
+		MessageID:      message.MessageID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Content:        message.Content,
+		IsEncrypted:    message.IsEncrypted,
+		MessageType:    message.MessageType,
+		Metadata:       message.Metadata,
+		SentAt:         message.SentAt,
+		Seq:            message.Seq,
+		EditedAt:       message.EditedAt,
+	}
+}
+
 // GetMessagesInput contains query parameters
 type GetMessagesInput struct {
 	ConversationID uuid.UUID
-	Limit          int
-	PageState      []byte
+	// UserID is the reader, who must be a participant. The messages they
+	// deleted for themselves are left out and their reactions are marked.
+	UserID    uuid.UUID
+	Limit     int
+	PageState []byte
 }
 
 // GetMessagesOutput contains message list
@@ -189,10 +541,20 @@ type GetMessagesOutput struct {
 	Messages      []*domain.MessageResponse
 	NextPageState []byte
 	HasMore       bool
+	// Degraded is set when Cassandra could not be read and only recently
+	// cached messages were returned; older history may be missing
+	Degraded bool
 }
 
-// GetMessages retrieves conversation messages with pagination
+// GetMessages retrieves conversation messages with pagination. Only participants
+// may read the history (domain.ErrNotParticipant). When Cassandra reads fail, the
+// first page comes from the recent message cache and is flagged as degraded;
+// ErrMessagesUnavailable is returned if that fails too.
 func (s *Service) GetMessages(ctx context.Context, input *GetMessagesInput) (*GetMessagesOutput, error) {
+	if err := s.checkParticipant(ctx, input.ConversationID, input.UserID); err != nil {
+		return nil, err
+	}
+
 	// Fetch messages from Cassandra
 	messages, nextPageState, err := s.messageRepo.GetByConversation(
 		ctx,
@@ -201,29 +563,33 @@ func (s *Service) GetMessages(ctx context.Context, input *GetMessagesInput) (*Ge
 		input.PageState,
 	)
 
+	degraded := false
 	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+		messages, err = s.recentMessagesFallback(ctx, input, err)
+		if err != nil {
+			return nil, err
+		}
+		nextPageState, degraded = nil, true
 	}
 
-	// Convert to response format
+	sortNewestFirst(messages)
+	messages = s.withoutHidden(ctx, input.ConversationID, input.UserID, messages)
+
+	// Convert to response format; deleted messages stay as tombstones
 	responses := make([]*domain.MessageResponse, len(messages))
 	for i, msg := range messages {
-		responses[i] = &domain.MessageResponse{
-			MessageID:      msg.MessageID,
-			ConversationID: msg.ConversationID,
-			SenderID:       msg.SenderID,
-			Content:        msg.Content,
-			IsEncrypted:    msg.IsEncrypted,
-			MessageType:    msg.MessageType,
-			Metadata:       msg.Metadata,
-			SentAt:         msg.SentAt,
-		}
+		responses[i] = toMessageResponse(msg)
 	}
+	s.attachReactions(ctx, input.ConversationID, input.UserID, responses)
 
 	return &GetMessagesOutput{
 		Messages:      responses,
 		NextPageState: nextPageState,
 		HasMore:       len(nextPageState) > 0,
+		Degraded:      degraded,
 	}, nil
 }
 
@@ -257,9 +623,10 @@ func (s *Service) RefreshPresence(ctx context.Context, userID uuid.UUID) error {
 	return s.presenceRepo.RefreshPresence(ctx, userID)
 }
 
-// notifyMessageRecipients sends push notifications to all conversation participants except sender
-// This runs in a goroutine to avoid blocking the message send operation
-func (s *Service) notifyMessageRecipients(ctx context.Context, senderID, conversationID uuid.UUID, _ string) {
+// notifyMessageRecipients sends push notifications to recipients
+// This runs in a goroutine to avoid blocking the message send operation.
+// sentAt is the message's server timestamp, used for delivery latency.
+func (s *Service) notifyMessageRecipients(ctx context.Context, senderID, conversationID uuid.UUID, recipients []uuid.UUID, sentAt time.Time) {
 	// Get sender details for notification
 	sender, err := s.userRepo.GetByID(ctx, senderID)
 	if err != nil {
@@ -274,21 +641,7 @@ func (s *Service) notifyMessageRecipients(ctx context.Context, senderID, convers
 		senderName = sender.Username
 	}
 
-	// Get conversation participants
-	participants, err := s.conversationRepo.GetParticipants(ctx, conversationID)
-	if err != nil {
-		logger.Warn("Failed to get conversation participants for notification",
-			zap.String("conversation_id", conversationID.String()),
-			zap.Error(err))
-		return
-	}
-
-	// Send notification to each participant except sender
-	for _, participantID := range participants {
-		if participantID == senderID {
-			continue // Don't notify the sender
-		}
-
+	for _, participantID := range recipients {
 		// Create notification for this participant
 		err := s.notificationService.CreateMessageNotification(ctx, participantID, senderName, conversationID)
 		if err != nil {
@@ -298,7 +651,9 @@ func (s *Service) notifyMessageRecipients(ctx context.Context, senderID, convers
 				zap.String("conversation_id", conversationID.String()),
 				zap.String("sender_id", senderID.String()),
 				zap.Error(err))
+			continue
 		}
+		metrics.MessageDeliveryLatency.WithLabelValues("push").Observe(time.Since(sentAt).Seconds())
 	}
 }
 
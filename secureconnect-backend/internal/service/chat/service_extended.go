@@ -45,29 +45,6 @@ func (s *ExtendedService) MarkMessagesAsRead(ctx context.Context, userID uuid.UU
 	return fmt.Errorf("mark messages as read not implemented yet - requires repository update")
 }
 
-// SearchMessagesInput contains data for searching messages
-type SearchMessagesInput struct {
-	ConversationID uuid.UUID
-	UserID         uuid.UUID
-	Query          string
-	Limit          int
-	PageState      []byte
-}
-
-// SearchMessagesOutput contains search results
-type SearchMessagesOutput struct {
-	Messages      []*domain.MessageResponse
-	NextPageState []byte
-	HasMore       bool
-}
-
-// SearchMessages searches for messages in a conversation
-func (s *ExtendedService) SearchMessages(ctx context.Context, input *SearchMessagesInput) (*SearchMessagesOutput, error) {
-	// This would need to be implemented in the message repository
-	// For now, return a placeholder error
-	return nil, fmt.Errorf("search messages not implemented yet - requires repository update")
-}
-
 // ForwardMessageInput contains data for forwarding a message
 type ForwardMessageInput struct {
 	MessageID      uuid.UUID
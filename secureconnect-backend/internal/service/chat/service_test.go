@@ -2,14 +2,17 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
 )
 
 // Mocks
@@ -17,16 +20,60 @@ type MockMessageRepository struct {
 	mock.Mock
 }
 
-func (m *MockMessageRepository) Save(message *domain.Message) error {
-	args := m.Called(message)
+func (m *MockMessageRepository) Save(ctx context.Context, message *domain.Message) error {
+	args := m.Called(ctx, message)
 	return args.Error(0)
 }
 
-func (m *MockMessageRepository) GetByConversation(conversationID uuid.UUID, limit int, pageState []byte) ([]*domain.Message, []byte, error) {
-	args := m.Called(conversationID, limit, pageState)
+func (m *MockMessageRepository) SaveBatch(ctx context.Context, messages []*domain.Message) error {
+	args := m.Called(ctx, messages)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) GetByConversation(ctx context.Context, conversationID uuid.UUID, limit int, pageState []byte) ([]*domain.Message, []byte, error) {
+	args := m.Called(ctx, conversationID, limit, pageState)
 	return args.Get(0).([]*domain.Message), args.Get(1).([]byte), args.Error(2)
 }
 
+func (m *MockMessageRepository) GetByID(ctx context.Context, conversationID, messageID uuid.UUID) (*domain.Message, error) {
+	args := m.Called(ctx, conversationID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) GetByKeys(ctx context.Context, conversationID uuid.UUID, keys []domain.MessageKey) ([]*domain.Message, error) {
+	args := m.Called(ctx, conversationID, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) Update(ctx context.Context, message *domain.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) SoftDelete(ctx context.Context, conversationID, messageID, byUserID uuid.UUID) error {
+	args := m.Called(ctx, conversationID, messageID, byUserID)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) HideForUser(ctx context.Context, conversationID, messageID, userID uuid.UUID) error {
+	args := m.Called(ctx, conversationID, messageID, userID)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) GetHiddenMessageIDs(ctx context.Context, conversationID, userID uuid.UUID, messageIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	args := m.Called(ctx, conversationID, userID, messageIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]bool), args.Error(1)
+}
+
 type MockPresenceRepository struct {
 	mock.Mock
 }
@@ -51,6 +98,11 @@ func (m *MockPresenceRepository) IsUserOnline(ctx context.Context, userID uuid.U
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockPresenceRepository) IsDegraded() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
 type MockPublisher struct {
 	mock.Mock
 }
@@ -78,6 +130,27 @@ func (m *MockConversationRepository) GetParticipants(ctx context.Context, conver
 	return args.Get(0).([]uuid.UUID), args.Error(1)
 }
 
+func (m *MockConversationRepository) GetParticipantsWithDetails(ctx context.Context, conversationID uuid.UUID) ([]*domain.ConversationParticipantDetail, error) {
+	args := m.Called(ctx, conversationID)
+	return args.Get(0).([]*domain.ConversationParticipantDetail), args.Error(1)
+}
+
+func (m *MockConversationRepository) GetByID(ctx context.Context, conversationID uuid.UUID) (*domain.Conversation, error) {
+	args := m.Called(ctx, conversationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Conversation), args.Error(1)
+}
+
+func (m *MockConversationRepository) GetParticipant(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ConversationParticipant, error) {
+	args := m.Called(ctx, conversationID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ConversationParticipant), args.Error(1)
+}
+
 type MockUserRepository struct {
 	mock.Mock
 }
@@ -91,6 +164,8 @@ func (m *MockUserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*do
 }
 
 func TestSendMessage(t *testing.T) {
+	logger.InitDefault("test")
+
 	mockMsgRepo := new(MockMessageRepository)
 	mockPresenceRepo := new(MockPresenceRepository)
 	mockPublisher := new(MockPublisher)
@@ -112,8 +187,11 @@ func TestSendMessage(t *testing.T) {
 	ctx := context.Background()
 
 	// Expectations
-	mockMsgRepo.On("Save", mock.AnythingOfType("*domain.Message")).Return(nil)
+	mockConversationRepo.On("GetParticipant", ctx, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	mockConversationRepo.On("GetParticipants", mock.Anything, conversationID).Return([]uuid.UUID{senderID}, nil).Maybe()
+	mockMsgRepo.On("Save", ctx, mock.AnythingOfType("*domain.Message")).Return(nil)
 	mockPublisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).Return(nil)
+	mockUserRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
 
 	// Execute
 	output, err := service.SendMessage(ctx, input)
@@ -129,6 +207,54 @@ func TestSendMessage(t *testing.T) {
 }
 
 func TestGetMessages(t *testing.T) {
+	logger.InitDefault("test")
+	member, outsider := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		wantErr error
+	}{
+		{name: "member", userID: member},
+		{name: "outsider", userID: outsider, wantErr: domain.ErrNotParticipant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMsgRepo := new(MockMessageRepository)
+			service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), new(MockConversationRepository), new(MockUserRepository))
+			service.SetMembershipChecker(fakeMembership{member: true})
+
+			conversationID := uuid.New()
+			messageID := uuid.New()
+			mockMessages := []*domain.Message{
+				{
+					MessageID:      messageID,
+					ConversationID: conversationID,
+					Content:        "Msg 1",
+					SentAt:         time.Now(),
+				},
+			}
+			ctx := context.Background()
+			mockMsgRepo.On("GetByConversation", ctx, conversationID, 20, []byte(nil)).Return(mockMessages, []byte(nil), nil).Maybe()
+			mockMsgRepo.On("GetHiddenMessageIDs", ctx, conversationID, tt.userID, []uuid.UUID{messageID}).Return(map[uuid.UUID]bool{}, nil).Maybe()
+
+			output, err := service.GetMessages(ctx, &GetMessagesInput{ConversationID: conversationID, UserID: tt.userID, Limit: 20})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockMsgRepo.AssertNotCalled(t, "GetByConversation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, output.Messages, 1)
+			assert.Equal(t, "Msg 1", output.Messages[0].Content)
+			mockMsgRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendMessages_TwoConversationsWithRejectedItem(t *testing.T) {
+	logger.InitDefault("test")
+
 	mockMsgRepo := new(MockMessageRepository)
 	mockPresenceRepo := new(MockPresenceRepository)
 	mockPublisher := new(MockPublisher)
@@ -138,34 +264,166 @@ func TestGetMessages(t *testing.T) {
 
 	service := NewService(mockMsgRepo, mockPresenceRepo, mockPublisher, mockNotificationSvc, mockConversationRepo, mockUserRepo)
 
-	conversationID := uuid.New()
-	input := &GetMessagesInput{
-		ConversationID: conversationID,
-		Limit:          20,
+	senderID := uuid.New()
+	convA := uuid.New()
+	convB := uuid.New()
+	convC := uuid.New()
+	ctx := context.Background()
+
+	inputs := []*SendMessageInput{
+		{ConversationID: convA, SenderID: senderID, Content: "a1", MessageType: "text"},
+		{ConversationID: convB, SenderID: senderID, Content: "b1", MessageType: "text"},
+		{ConversationID: convC, SenderID: senderID, Content: "c1", MessageType: "text"}, // not a participant
+		{ConversationID: convA, SenderID: senderID, Content: "a2", MessageType: "text"},
 	}
 
-	mockMessages := []*domain.Message{
+	// Expectations: membership is checked once per conversation, one batch write per conversation for a single sender
+	mockConversationRepo.On("GetParticipant", ctx, convA, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil).Once()
+	mockConversationRepo.On("GetParticipant", ctx, convB, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil).Once()
+	mockConversationRepo.On("GetParticipant", ctx, convC, senderID).Return(nil, domain.ErrNotParticipant).Once()
+	mockConversationRepo.On("GetParticipants", mock.Anything, mock.Anything).Return([]uuid.UUID{}, nil).Maybe()
+	mockMsgRepo.On("SaveBatch", ctx, mock.MatchedBy(func(msgs []*domain.Message) bool {
+		return len(msgs) == 2 && msgs[0].ConversationID == convA
+	})).Return(nil).Once()
+	mockMsgRepo.On("SaveBatch", ctx, mock.MatchedBy(func(msgs []*domain.Message) bool {
+		return len(msgs) == 1 && msgs[0].ConversationID == convB
+	})).Return(nil).Once()
+	mockPublisher.On("Publish", ctx, "chat:"+convA.String(), mock.Anything).Return(nil).Twice()
+	mockPublisher.On("Publish", ctx, "chat:"+convB.String(), mock.Anything).Return(nil).Once()
+	mockUserRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
+
+	// Execute
+	output, err := service.SendMessages(ctx, inputs)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, output.Results, 4)
+	assert.Equal(t, 3, output.Succeeded)
+	assert.Equal(t, 1, output.Failed)
+	assert.Equal(t, "not a participant in this conversation", output.Results[2].Error)
+	assert.Nil(t, output.Results[2].Message)
+	assert.Equal(t, "a1", output.Results[0].Message.Content)
+	assert.Equal(t, convB, output.Results[1].Message.ConversationID)
+	assert.Equal(t, "a2", output.Results[3].Message.Content)
+
+	mockMsgRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestSendMessages_PostCheckedPerSender(t *testing.T) {
+	logger.InitDefault("test")
+
+	mutedUntil := time.Now().Add(10 * time.Minute)
+	tests := []struct {
+		name      string
+		blocked   *domain.ConversationParticipant
+		blockErr  error
+		wantError string
+	}{
+		{
+			name:      "muted sender",
+			blocked:   &domain.ConversationParticipant{Role: "member", MutedUntil: &mutedUntil},
+			wantError: (&domain.MutedError{Until: mutedUntil}).Error(),
+		},
 		{
-			MessageID:      uuid.New(),
-			ConversationID: conversationID,
-			Content:        "Msg 1",
-			SentAt:         time.Now(),
+			name:      "non-member sender",
+			blockErr:  domain.ErrNotParticipant,
+			wantError: "not a participant in this conversation",
 		},
 	}
 
-	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMsgRepo := new(MockMessageRepository)
+			mockPublisher := new(MockPublisher)
+			mockConversationRepo := new(MockConversationRepository)
+			mockUserRepo := new(MockUserRepository)
+			service := NewService(mockMsgRepo, new(MockPresenceRepository), mockPublisher, new(MockNotificationService), mockConversationRepo, mockUserRepo)
+
+			conversationID := uuid.New()
+			allowedID, blockedID := uuid.New(), uuid.New()
+			ctx := context.Background()
+
+			mockConversationRepo.On("GetParticipant", ctx, conversationID, allowedID).Return(&domain.ConversationParticipant{Role: "member"}, nil).Once()
+			if tt.blockErr != nil {
+				mockConversationRepo.On("GetParticipant", ctx, conversationID, blockedID).Return(nil, tt.blockErr).Once()
+			} else {
+				mockConversationRepo.On("GetParticipant", ctx, conversationID, blockedID).Return(tt.blocked, nil).Once()
+			}
+			mockConversationRepo.On("GetParticipants", mock.Anything, mock.Anything).Return([]uuid.UUID{}, nil).Maybe()
+			mockMsgRepo.On("SaveBatch", ctx, mock.MatchedBy(func(msgs []*domain.Message) bool {
+				return len(msgs) == 2 && msgs[0].SenderID == allowedID && msgs[1].SenderID == allowedID
+			})).Return(nil).Once()
+			mockPublisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).Return(nil).Twice()
+			mockUserRepo.On("GetByID", mock.Anything, allowedID).Return(nil, errors.New("not found")).Maybe()
+
+			output, err := service.SendMessages(ctx, []*SendMessageInput{
+				{ConversationID: conversationID, SenderID: allowedID, Content: "a1", MessageType: "text"},
+				{ConversationID: conversationID, SenderID: blockedID, Content: "b1", MessageType: "text"},
+				{ConversationID: conversationID, SenderID: allowedID, Content: "a2", MessageType: "text"},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 2, output.Succeeded)
+			assert.Equal(t, 1, output.Failed)
+			assert.Equal(t, tt.wantError, output.Results[1].Error)
+			assert.Nil(t, output.Results[1].Message)
+
+			mockConversationRepo.AssertExpectations(t)
+			mockMsgRepo.AssertExpectations(t)
+			mockPublisher.AssertExpectations(t)
+		})
+	}
+}
 
-	// Expectations
-	mockMsgRepo.On("GetByConversation", conversationID, 20, []byte(nil)).Return(mockMessages, []byte(nil), nil)
+func TestSendMessage_MutedParticipantRejectedUntilExpiry(t *testing.T) {
+	logger.InitDefault("test")
 
-	// Execute
-	output, err := service.GetMessages(ctx, input)
+	mockMsgRepo := new(MockMessageRepository)
+	mockPublisher := new(MockPublisher)
+	mockConversationRepo := new(MockConversationRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), mockPublisher, new(MockNotificationService), mockConversationRepo, mockUserRepo)
 
-	// Assert
-	assert.NoError(t, err)
-	assert.NotNil(t, output)
-	assert.Len(t, output.Messages, 1)
-	assert.Equal(t, "Msg 1", output.Messages[0].Content)
+	conversationID := uuid.New()
+	senderID := uuid.New()
+	input := &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text"}
+	ctx := context.Background()
+
+	mutedUntil := time.Now().Add(10 * time.Minute)
+	mockConversationRepo.On("GetParticipant", ctx, conversationID, senderID).
+		Return(&domain.ConversationParticipant{Role: "member", MutedUntil: &mutedUntil}, nil).Once()
+
+	_, err := service.SendMessage(ctx, input)
+	require.ErrorIs(t, err, domain.ErrParticipantMuted)
+	var muted *domain.MutedError
+	require.ErrorAs(t, err, &muted)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), muted.Remaining(time.Now()).Seconds(), 5)
+	mockMsgRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+	// Once the mute has expired the same participant can post again
+	expired := time.Now().Add(-time.Second)
+	mockConversationRepo.On("GetParticipant", ctx, conversationID, senderID).
+		Return(&domain.ConversationParticipant{Role: "member", MutedUntil: &expired}, nil).Once()
+	mockMsgRepo.On("Save", ctx, mock.AnythingOfType("*domain.Message")).Return(nil).Once()
+	mockPublisher.On("Publish", ctx, "chat:"+conversationID.String(), mock.Anything).Return(nil)
+	mockConversationRepo.On("GetParticipants", mock.Anything, mock.Anything).Return([]uuid.UUID{}, nil).Maybe()
+	mockUserRepo.On("GetByID", mock.Anything, senderID).Return(nil, errors.New("not found")).Maybe()
 
+	output, err := service.SendMessage(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "hi", output.Message.Content)
 	mockMsgRepo.AssertExpectations(t)
 }
+
+func TestSendMessage_NonParticipantRejected(t *testing.T) {
+	mockMsgRepo := new(MockMessageRepository)
+	mockConversationRepo := new(MockConversationRepository)
+	service := NewService(mockMsgRepo, new(MockPresenceRepository), new(MockPublisher), new(MockNotificationService), mockConversationRepo, new(MockUserRepository))
+
+	conversationID, senderID := uuid.New(), uuid.New()
+	mockConversationRepo.On("GetParticipant", mock.Anything, conversationID, senderID).Return(nil, domain.ErrNotParticipant)
+
+	_, err := service.SendMessage(context.Background(), &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text"})
+	assert.ErrorIs(t, err, domain.ErrNotParticipant)
+	mockMsgRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// ErrUnreadNotConfigured is returned when no unread counter is set
+var ErrUnreadNotConfigured = errors.New("unread counts are not configured")
+
+// UnreadCounter keeps each user's unread message count per conversation and
+// the total across conversations shown as the badge
+type UnreadCounter interface {
+	IncrementUnread(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, n int) error
+	// ResetUnread returns the cleared count and the user's new total
+	ResetUnread(ctx context.Context, userID, conversationID uuid.UUID) (int, int, error)
+	// ResetAllUnread returns the cleared count of each conversation that had unread messages
+	ResetAllUnread(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error)
+	GetTotalUnread(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// unreadUpdateEvent tells a user's devices that their unread counts changed.
+// It is published on the conversation channel but only delivered to the user.
+type unreadUpdateEvent struct {
+	Type           string                 `json:"type"`
+	ConversationID uuid.UUID              `json:"conversation_id"`
+	SenderID       uuid.UUID              `json:"sender_id"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	Timestamp      time.Time              `json:"timestamp"`
+}
+
+// SetUnreadCounter enables unread counts. Sent messages count as unread for
+// every other participant until they mark the conversation read.
+func (s *Service) SetUnreadCounter(counter UnreadCounter) {
+	s.unread = counter
+}
+
+// GetTotalUnread returns the user's unread messages across all conversations
+func (s *Service) GetTotalUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	if s.unread == nil {
+		return 0, ErrUnreadNotConfigured
+	}
+	return s.unread.GetTotalUnread(ctx, userID)
+}
+
+// MarkAllRead clears the user's unread counts in every conversation and tells
+// their devices to reset the badge
+func (s *Service) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	if s.unread == nil {
+		return ErrUnreadNotConfigured
+	}
+	cleared, err := s.unread.ResetAllUnread(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset unread counts: %w", err)
+	}
+	for conversationID := range cleared {
+		s.publishUnreadUpdate(ctx, userID, conversationID, 0)
+	}
+	return nil
+}
+
+// countUnread adds n unread messages for each recipient. Failures are
+// logged; a missed count must not fail the send.
+func (s *Service) countUnread(ctx context.Context, conversationID uuid.UUID, recipients []uuid.UUID, n int) {
+	if s.unread == nil {
+		return
+	}
+	if err := s.unread.IncrementUnread(ctx, conversationID, recipients, n); err != nil {
+		logger.Warn("Failed to update unread counts",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+}
+
+// clearUnread resets the user's unread count for a conversation they have
+// read and, if it changed, tells their devices the new total
+func (s *Service) clearUnread(ctx context.Context, userID, conversationID uuid.UUID) {
+	if s.unread == nil {
+		return
+	}
+	cleared, total, err := s.unread.ResetUnread(ctx, userID, conversationID)
+	if err != nil {
+		logger.Warn("Failed to reset unread count",
+			zap.String("conversation_id", conversationID.String()),
+			zap.String("user_id", userID.String()),
+			zap.Error(err))
+		return
+	}
+	if cleared > 0 {
+		s.publishUnreadUpdate(ctx, userID, conversationID, total)
+	}
+}
+
+// publishUnreadUpdate publishes the user's new unread total. The hub delivers
+// it to the user's devices as a self_sync event.
+func (s *Service) publishUnreadUpdate(ctx context.Context, userID, conversationID uuid.UUID, total int) {
+	eventJSON, err := json.Marshal(&unreadUpdateEvent{
+		Type:           "unread_update",
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Metadata:       map[string]interface{}{"unread_count": 0, "total_unread": total},
+		Timestamp:      time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", conversationID), eventJSON); err != nil {
+		logger.Warn("Failed to publish unread update",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+}
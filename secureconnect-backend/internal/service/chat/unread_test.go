@@ -0,0 +1,193 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/workerpool"
+)
+
+// fakeUnreadCounter keeps unread counts in memory
+type fakeUnreadCounter struct {
+	mu     sync.Mutex
+	counts map[uuid.UUID]map[uuid.UUID]int // user -> conversation -> unread
+}
+
+func newFakeUnreadCounter() *fakeUnreadCounter {
+	return &fakeUnreadCounter{counts: map[uuid.UUID]map[uuid.UUID]int{}}
+}
+
+func (f *fakeUnreadCounter) IncrementUnread(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, n int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, userID := range userIDs {
+		if f.counts[userID] == nil {
+			f.counts[userID] = map[uuid.UUID]int{}
+		}
+		f.counts[userID][conversationID] += n
+	}
+	return nil
+}
+
+func (f *fakeUnreadCounter) ResetUnread(ctx context.Context, userID, conversationID uuid.UUID) (int, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cleared := f.counts[userID][conversationID]
+	delete(f.counts[userID], conversationID)
+	return cleared, f.totalLocked(userID), nil
+}
+
+func (f *fakeUnreadCounter) ResetAllUnread(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cleared := f.counts[userID]
+	delete(f.counts, userID)
+	return cleared, nil
+}
+
+func (f *fakeUnreadCounter) GetTotalUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.totalLocked(userID), nil
+}
+
+func (f *fakeUnreadCounter) totalLocked(userID uuid.UUID) int {
+	total := 0
+	for _, n := range f.counts[userID] {
+		total += n
+	}
+	return total
+}
+
+func TestMarkRead_ResetsUnreadAndSyncsDevices(t *testing.T) {
+	logger.InitDefault("test")
+
+	conversationRepo := new(MockConversationRepository)
+	publisher := new(MockPublisher)
+	service := NewService(nil, nil, publisher, nil, conversationRepo, nil)
+	service.SetReadPositionStore(&fakeReadStore{furthest: map[readKey]*domain.ReadPosition{}}, 10*time.Millisecond)
+	counter := newFakeUnreadCounter()
+	service.SetUnreadCounter(counter)
+	ctx := context.Background()
+
+	read, other := uuid.New(), uuid.New()
+	userID := uuid.New()
+	conversationRepo.On("GetParticipant", mock.Anything, read, userID).Return(&domain.ConversationParticipant{}, nil)
+
+	service.countUnread(ctx, read, []uuid.UUID{userID}, 4)
+	require.NoError(t, counter.IncrementUnread(ctx, other, []uuid.UUID{userID}, 2))
+	total, err := service.GetTotalUnread(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 6, total)
+
+	var events []map[string]interface{}
+	var mu sync.Mutex
+	publisher.On("Publish", mock.Anything, "chat:"+read.String(), mock.Anything).
+		Run(func(args mock.Arguments) {
+			var event map[string]interface{}
+			_ = json.Unmarshal(args.Get(2).([]byte), &event)
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}).Return(nil)
+
+	require.NoError(t, service.MarkRead(ctx, &MarkReadInput{ConversationID: read, UserID: userID, MessageID: uuid.New(), SentAt: time.Now()}))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	total, err = service.GetTotalUnread(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total, "the read conversation's 4 unread are removed from the total")
+
+	update := events[0]
+	assert.Equal(t, "unread_update", update["type"])
+	assert.Equal(t, userID.String(), update["sender_id"])
+	assert.Equal(t, float64(2), update["metadata"].(map[string]interface{})["total_unread"])
+	assert.Equal(t, "read", events[1]["type"])
+}
+
+func TestSendMessage_CountsUnreadWithOneParticipantLookup(t *testing.T) {
+	logger.InitDefault("test")
+
+	msgRepo := new(MockMessageRepository)
+	publisher := new(MockPublisher)
+	notifications := new(MockNotificationService)
+	conversationRepo := new(MockConversationRepository)
+	userRepo := new(MockUserRepository)
+	service := NewService(msgRepo, nil, publisher, notifications, conversationRepo, userRepo)
+	counter := newFakeUnreadCounter()
+	service.SetUnreadCounter(counter)
+	ctx := context.Background()
+
+	conversationID, senderID, recipientID := uuid.New(), uuid.New(), uuid.New()
+	conversationRepo.On("GetParticipant", mock.Anything, conversationID, senderID).Return(&domain.ConversationParticipant{Role: "member"}, nil)
+	conversationRepo.On("GetParticipants", mock.Anything, conversationID).Return([]uuid.UUID{senderID, recipientID}, nil).Once()
+	msgRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	userRepo.On("GetByID", mock.Anything, senderID).Return(&domain.User{Username: "sender"}, nil)
+	notified := make(chan struct{}, 1)
+	notifications.On("CreateMessageNotification", mock.Anything, recipientID, "sender", conversationID).
+		Run(func(mock.Arguments) { notified <- struct{}{} }).Return(nil)
+
+	_, err := service.SendMessage(ctx, &SendMessageInput{ConversationID: conversationID, SenderID: senderID, Content: "hi", MessageType: "text"})
+	require.NoError(t, err)
+
+	select {
+	case <-notified:
+	case <-time.After(time.Second):
+		t.Fatal("recipient was not notified")
+	}
+	total, err := service.GetTotalUnread(ctx, recipientID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	total, err = service.GetTotalUnread(ctx, senderID)
+	require.NoError(t, err)
+	assert.Zero(t, total, "the sender's own message is not unread")
+	conversationRepo.AssertNumberOfCalls(t, "GetParticipants", 1)
+}
+
+func TestNotifyRecipients_FullPoolSkipsParticipantLookup(t *testing.T) {
+	logger.InitDefault("test")
+
+	conversationRepo := new(MockConversationRepository)
+	service := NewService(nil, nil, nil, nil, conversationRepo, nil)
+	counter := newFakeUnreadCounter()
+	service.SetUnreadCounter(counter)
+	pool := workerpool.New("chat_notify_full_test", 1, 1)
+	service.SetNotificationPool(pool)
+
+	conversationID, senderID, recipientID := uuid.New(), uuid.New(), uuid.New()
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	conversationRepo.On("GetParticipants", mock.Anything, conversationID).
+		Run(func(mock.Arguments) {
+			started <- struct{}{}
+			<-release
+		}).Return([]uuid.UUID{senderID, recipientID}, nil)
+
+	// One lookup hangs on the worker and one waits in the queue; the rest are shed
+	service.notifyRecipients(context.Background(), senderID, conversationID, 1, time.Now(), false)
+	<-started
+	for i := 0; i < 3; i++ {
+		service.notifyRecipients(context.Background(), senderID, conversationID, 1, time.Now(), false)
+	}
+	close(release)
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	conversationRepo.AssertNumberOfCalls(t, "GetParticipants", 2)
+	total, err := service.GetTotalUnread(context.Background(), recipientID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total, "shed notifications are not counted as unread")
+}
@@ -0,0 +1,132 @@
+package conversation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/webhook"
+)
+
+// botTokenPrefix marks bot tokens so they are recognisable in logs and leaks
+const botTokenPrefix = "scb_"
+
+// MinBotSecretLength is the shortest accepted webhook signing secret
+const MinBotSecretLength = 16
+
+// ErrBotsUnavailable is returned when no bot repository is configured
+var ErrBotsUnavailable = errors.New("bots are not available")
+
+// ErrInvalidBotSecret is returned for signing secrets shorter than MinBotSecretLength
+var ErrInvalidBotSecret = fmt.Errorf("bot secret must be at least %d characters", MinBotSecretLength)
+
+// BotRepository stores conversation bots
+type BotRepository interface {
+	CreateBot(ctx context.Context, bot *domain.Bot, maxBots int) error
+	GetBotsByConversation(ctx context.Context, conversationID uuid.UUID) ([]*domain.Bot, error)
+	DeleteBot(ctx context.Context, conversationID, botID uuid.UUID) error
+}
+
+// SetBotRepository enables bot registration
+func (s *Service) SetBotRepository(repo BotRepository) {
+	s.bots = repo
+}
+
+// RegisterBotOutput is a new bot with its token. The token is not stored and
+// cannot be retrieved again.
+type RegisterBotOutput struct {
+	Bot   *domain.Bot `json:"bot"`
+	Token string      `json:"token"`
+}
+
+// RegisterBot adds a webhook bot to a conversation. Only admins may register
+// bots, end-to-end encrypted conversations cannot have any, and each
+// conversation has at most domain.MaxBotsPerConversation.
+func (s *Service) RegisterBot(ctx context.Context, conversationID, adminID uuid.UUID, webhookURL, secret string) (*RegisterBotOutput, error) {
+	if s.bots == nil {
+		return nil, ErrBotsUnavailable
+	}
+	if err := webhook.ValidateURL(webhookURL); err != nil {
+		return nil, err
+	}
+	if len(secret) < MinBotSecretLength {
+		return nil, ErrInvalidBotSecret
+	}
+	if err := s.requireAdmin(ctx, conversationID, adminID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.settings.GetSettings(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	if settings.IsE2EEEnabled {
+		return nil, domain.ErrBotE2EEConversation
+	}
+
+	token, err := newBotToken()
+	if err != nil {
+		return nil, err
+	}
+	bot := &domain.Bot{
+		BotID:          uuid.New(),
+		ConversationID: conversationID,
+		WebhookURL:     webhookURL,
+		Secret:         secret,
+		TokenHash:      domain.HashBotToken(token),
+		CreatedBy:      adminID,
+	}
+	if err := s.bots.CreateBot(ctx, bot, domain.MaxBotsPerConversation); err != nil {
+		return nil, err
+	}
+
+	s.publishConversationEvent(ctx, "bot_added", conversationID, map[string]interface{}{
+		"bot_id":   bot.BotID,
+		"added_by": adminID,
+	})
+	return &RegisterBotOutput{Bot: bot, Token: token}, nil
+}
+
+// ListBots returns a conversation's bots to one of its admins
+func (s *Service) ListBots(ctx context.Context, conversationID, adminID uuid.UUID) ([]*domain.Bot, error) {
+	if s.bots == nil {
+		return nil, ErrBotsUnavailable
+	}
+	if err := s.requireAdmin(ctx, conversationID, adminID); err != nil {
+		return nil, err
+	}
+	return s.bots.GetBotsByConversation(ctx, conversationID)
+}
+
+// RemoveBot deletes a bot; its token stops working immediately
+func (s *Service) RemoveBot(ctx context.Context, conversationID, botID, adminID uuid.UUID) error {
+	if s.bots == nil {
+		return ErrBotsUnavailable
+	}
+	if err := s.requireAdmin(ctx, conversationID, adminID); err != nil {
+		return err
+	}
+	if err := s.bots.DeleteBot(ctx, conversationID, botID); err != nil {
+		return err
+	}
+
+	s.publishConversationEvent(ctx, "bot_removed", conversationID, map[string]interface{}{
+		"bot_id":     botID,
+		"removed_by": adminID,
+	})
+	return nil
+}
+
+// newBotToken returns a random bot token
+func newBotToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate bot token: %w", err)
+	}
+	return botTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
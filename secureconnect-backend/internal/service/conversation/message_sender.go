@@ -0,0 +1,110 @@
+package conversation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/internal/domain"
+)
+
+// DefaultMessageSendTimeout bounds one initial message send to the chat service
+const DefaultMessageSendTimeout = 5 * time.Second
+
+// MessageRejectedError is a message the chat service refused, such as one
+// blocked by moderation. Message is the chat service's explanation.
+type MessageRejectedError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *MessageRejectedError) Error() string {
+	return e.Message
+}
+
+// ChatServiceSender sends messages through the chat service's
+// POST /v1/messages with the sender's own credentials, so they are checked,
+// stored, counted and broadcast exactly like messages sent by the client
+type ChatServiceSender struct {
+	url    string
+	client *http.Client
+}
+
+// NewChatServiceSender creates a sender for the chat service at baseURL.
+// timeout <= 0 uses DefaultMessageSendTimeout.
+func NewChatServiceSender(baseURL string, timeout time.Duration) *ChatServiceSender {
+	if timeout <= 0 {
+		timeout = DefaultMessageSendTimeout
+	}
+	return &ChatServiceSender{
+		url:    strings.TrimRight(baseURL, "/") + "/v1/messages",
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type chatSendRequest struct {
+	ConversationID string                 `json:"conversation_id"`
+	Content        string                 `json:"content"`
+	IsEncrypted    bool                   `json:"is_encrypted"`
+	MessageType    string                 `json:"message_type"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	ClientMsgID    string                 `json:"client_msg_id,omitempty"`
+}
+
+type chatSendResponse struct {
+	Data  *domain.MessageResponse `json:"data"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// SendMessage sends message to the conversation as the user credentials
+// (an Authorization header value) belong to. A 4xx answer is returned as a
+// *MessageRejectedError.
+func (s *ChatServiceSender) SendMessage(ctx context.Context, credentials string, conversationID uuid.UUID, message *InitialMessage) (*domain.MessageResponse, error) {
+	body, err := json.Marshal(chatSendRequest{
+		ConversationID: conversationID.String(),
+		Content:        message.Content,
+		IsEncrypted:    message.IsEncrypted,
+		MessageType:    message.MessageType,
+		Metadata:       message.Metadata,
+		ClientMsgID:    message.ClientMsgID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", credentials)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("chat service unavailable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded chatSendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid chat service response (status %d): %w", resp.StatusCode, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusCreated && decoded.Data != nil:
+		return decoded.Data, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && decoded.Error != nil:
+		return nil, &MessageRejectedError{Status: resp.StatusCode, Code: decoded.Error.Code, Message: decoded.Error.Message}
+	default:
+		return nil, fmt.Errorf("chat service returned status %d", resp.StatusCode)
+	}
+}
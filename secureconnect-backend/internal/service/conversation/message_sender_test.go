@@ -0,0 +1,78 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatServiceSender_SendMessage(t *testing.T) {
+	conversationID := uuid.New()
+
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantContent string
+		wantErr     *MessageRejectedError
+	}{
+		{
+			name:        "stored message is returned",
+			status:      http.StatusCreated,
+			body:        `{"success":true,"data":{"message_id":"` + uuid.NewString() + `","content":"hi bob"}}`,
+			wantContent: "hi bob",
+		},
+		{
+			name:    "a refused message carries the chat service's reason",
+			status:  http.StatusUnprocessableEntity,
+			body:    `{"success":false,"error":{"code":"MESSAGE_BLOCKED","message":"Message blocked: spam"}}`,
+			wantErr: &MessageRejectedError{Status: http.StatusUnprocessableEntity, Code: "MESSAGE_BLOCKED", Message: "Message blocked: spam"},
+		},
+		{
+			name:   "server errors are not shown to the user",
+			status: http.StatusInternalServerError,
+			body:   `{"success":false,"error":{"code":"INTERNAL_ERROR","message":"Failed to send message"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got chatSendRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/messages", r.URL.Path)
+				assert.Equal(t, "Bearer alice-token", r.Header.Get("Authorization"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			sender := NewChatServiceSender(server.URL+"/", 0)
+			message, err := sender.SendMessage(context.Background(), "Bearer alice-token", conversationID,
+				&InitialMessage{Content: "hi bob", MessageType: "text", ClientMsgID: "c-1"})
+
+			assert.Equal(t, conversationID.String(), got.ConversationID)
+			assert.Equal(t, "c-1", got.ClientMsgID)
+			switch {
+			case tt.wantContent != "":
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantContent, message.Content)
+			case tt.wantErr != nil:
+				var rejected *MessageRejectedError
+				require.True(t, errors.As(err, &rejected))
+				assert.Equal(t, tt.wantErr, rejected)
+			default:
+				require.Error(t, err)
+				var rejected *MessageRejectedError
+				assert.False(t, errors.As(err, &rejected))
+			}
+		})
+	}
+}
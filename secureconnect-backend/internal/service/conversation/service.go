@@ -2,29 +2,150 @@ package conversation
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/repository/cockroach"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
 )
 
+// PollSummaryProvider supplies active poll information for conversation views
+type PollSummaryProvider interface {
+	GetActivePollSummaries(ctx context.Context, conversationIDs []uuid.UUID) (map[uuid.UUID]*domain.ActivePolls, error)
+}
+
+// ParticipantRepository reads, changes and moderates individual memberships
+type ParticipantRepository interface {
+	IsParticipant(ctx context.Context, conversationID, userID uuid.UUID) (bool, error)
+	GetParticipant(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ConversationParticipant, error)
+	GetParticipants(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error)
+	AddParticipants(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, role string) error
+	RemoveParticipant(ctx context.Context, conversationID, userID uuid.UUID) error
+	SetParticipantMute(ctx context.Context, conversationID, userID uuid.UUID, until *time.Time) error
+	SetCallsMuted(ctx context.Context, conversationID, userID uuid.UUID, muted bool) error
+}
+
+// MembershipCache caches IsMember answers. Entries must be dropped whenever a
+// membership changes.
+type MembershipCache interface {
+	Get(ctx context.Context, conversationID, userID uuid.UUID) (member bool, found bool, err error)
+	Set(ctx context.Context, conversationID, userID uuid.UUID, member bool, ttl time.Duration) error
+	Invalidate(ctx context.Context, conversationID uuid.UUID, userIDs ...uuid.UUID) error
+}
+
+// SettingsRepository reads and writes per-conversation settings
+type SettingsRepository interface {
+	GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error)
+	UpdateSettings(ctx context.Context, conversationID uuid.UUID, settings *domain.ConversationSettings) error
+}
+
+// E2EEPolicy is the organization-wide end-to-end encryption policy
+type E2EEPolicy struct {
+	// DefaultEnabled applies to new conversations that do not choose for themselves
+	DefaultEnabled bool
+	// AllowDowngrade lets conversation admins turn E2EE off once it is on
+	AllowDowngrade bool
+}
+
+// Publisher publishes conversation events to the chat WebSocket channel
+type Publisher interface {
+	Publish(ctx context.Context, channel string, message interface{}) error
+}
+
+// SearchIndexSyncer asks the chat service to bring a conversation's messages
+// in the search index in line with its settings, removing or backfilling them
+type SearchIndexSyncer interface {
+	RequestSearchIndexSync(ctx context.Context, conversationID uuid.UUID) error
+}
+
+// ConversationCreator stores a new conversation with its participants and
+// settings atomically
+type ConversationCreator interface {
+	CreateWithParticipants(ctx context.Context, conversation *domain.Conversation, participants []*domain.ConversationParticipant, settings *domain.ConversationSettings) error
+}
+
+// UserExistenceChecker reports which of a set of users exist
+type UserExistenceChecker interface {
+	UsersExist(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+}
+
+// MessageSender sends a chat message as the user credentials belong to
+type MessageSender interface {
+	SendMessage(ctx context.Context, credentials string, conversationID uuid.UUID, message *InitialMessage) (*domain.MessageResponse, error)
+}
+
+// ErrInitialMessageUnavailable is reported when a conversation is created with
+// an initial message but no message sender is configured
+var ErrInitialMessageUnavailable = errors.New("initial messages are not available")
+
 // Service handles conversation business logic
 type Service struct {
 	conversationRepo *cockroach.ConversationRepository
+	creator          ConversationCreator
+	users            UserExistenceChecker
+	messageSender    MessageSender
+	participants     ParticipantRepository
+	settings         SettingsRepository
 	userRepo         *cockroach.UserRepository
+	pollSummary      PollSummaryProvider
+	publisher        Publisher
+	e2eePolicy       E2EEPolicy
+	membership       MembershipCache
+	membershipTTL    time.Duration
+	searchIndex      SearchIndexSyncer
+	bots             BotRepository // nil until SetBotRepository
 }
 
 // NewService creates a new conversation service
-func NewService(conversationRepo *cockroach.ConversationRepository, userRepo *cockroach.UserRepository) *Service {
+// pollSummary is optional; when nil, conversations are returned without poll information
+func NewService(conversationRepo *cockroach.ConversationRepository, userRepo *cockroach.UserRepository, pollSummary PollSummaryProvider) *Service {
 	return &Service{
 		conversationRepo: conversationRepo,
+		creator:          conversationRepo,
+		users:            userRepo,
+		participants:     conversationRepo,
+		settings:         conversationRepo,
 		userRepo:         userRepo,
+		pollSummary:      pollSummary,
+		e2eePolicy:       E2EEPolicy{DefaultEnabled: true, AllowDowngrade: true},
 	}
 }
 
+// SetPublisher enables real-time events for moderation actions such as mutes
+func (s *Service) SetPublisher(publisher Publisher) {
+	s.publisher = publisher
+}
+
+// SetE2EEPolicy replaces the default policy of E2EE on by default with admin downgrades allowed
+func (s *Service) SetE2EEPolicy(policy E2EEPolicy) {
+	s.e2eePolicy = policy
+}
+
+// SetMembershipCache caches IsMember results for ttl
+func (s *Service) SetMembershipCache(cache MembershipCache, ttl time.Duration) {
+	s.membership = cache
+	s.membershipTTL = ttl
+}
+
+// SetSearchIndexSyncer requests a search index sync whenever a settings change
+// makes a conversation searchable or unsearchable
+func (s *Service) SetSearchIndexSyncer(syncer SearchIndexSyncer) {
+	s.searchIndex = syncer
+}
+
+// SetMessageSender lets CreateConversation send an initial message
+func (s *Service) SetMessageSender(sender MessageSender) {
+	s.messageSender = sender
+}
+
 // CreateConversationInput contains conversation creation data
 type CreateConversationInput struct {
 	Title         string
@@ -32,10 +153,37 @@ type CreateConversationInput struct {
 	CreatedBy     uuid.UUID
 	Participants  []uuid.UUID
 	IsE2EEEnabled *bool
+	// InitialMessage is optionally sent by the creator once the conversation exists
+	InitialMessage *InitialMessage
+	// Credentials is the creator's Authorization header, used to send
+	// InitialMessage as them
+	Credentials string
+}
+
+// InitialMessage is the first message of a new conversation
+type InitialMessage struct {
+	Content     string
+	IsEncrypted bool
+	MessageType string
+	Metadata    map[string]interface{}
+	ClientMsgID string
 }
 
-// CreateConversation creates a new conversation
-func (s *Service) CreateConversation(ctx context.Context, input *CreateConversationInput) (*domain.Conversation, error) {
+// CreateConversationOutput contains the new conversation and, when one was
+// requested, the outcome of sending its first message
+type CreateConversationOutput struct {
+	Conversation *domain.Conversation
+	// InitialMessage is the stored first message; nil when none was sent
+	InitialMessage *domain.MessageResponse
+	// InitialMessageErr explains why a requested first message was not sent.
+	// The conversation is kept either way so the client can send it again.
+	InitialMessageErr error
+}
+
+// CreateConversation creates a new conversation. With an InitialMessage the
+// creator's first message is stored and broadcast right after the
+// conversation is committed.
+func (s *Service) CreateConversation(ctx context.Context, input *CreateConversationInput) (*CreateConversationOutput, error) {
 	// Validate
 	if input.Type != "direct" && input.Type != "group" {
 		return nil, fmt.Errorf("invalid conversation type")
@@ -45,8 +193,17 @@ func (s *Service) CreateConversation(ctx context.Context, input *CreateConversat
 		return nil, fmt.Errorf("direct conversation must have exactly 2 participants")
 	}
 
+	if input.InitialMessage != nil {
+		if input.InitialMessage.Content == "" {
+			return nil, fmt.Errorf("initial message content is required")
+		}
+		if !slices.Contains(input.Participants, input.CreatedBy) {
+			return nil, fmt.Errorf("creator must be a participant to send an initial message")
+		}
+	}
+
 	// Validate that all participants exist
-	userExistence, err := s.userRepo.UsersExist(ctx, input.Participants)
+	userExistence, err := s.users.UsersExist(ctx, input.Participants)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate participants: %w", err)
 	}
@@ -62,20 +219,6 @@ func (s *Service) CreateConversation(ctx context.Context, input *CreateConversat
 		return nil, fmt.Errorf("the following users do not exist: %v", nonExistingUsers)
 	}
 
-	// Start transaction for atomic conversation creation
-	tx, err := s.conversationRepo.BeginTx(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	// Ensure rollback on error
-	defer func() {
-		if err != nil {
-			tx.Rollback(ctx)
-		}
-	}()
-
-	// Create conversation
 	conversation := &domain.Conversation{
 		ConversationID: uuid.New(),
 		Title:          input.Title,
@@ -85,24 +228,21 @@ func (s *Service) CreateConversation(ctx context.Context, input *CreateConversat
 		UpdatedAt:      time.Now(),
 	}
 
-	if err := s.conversationRepo.CreateTx(ctx, tx, conversation); err != nil {
-		return nil, fmt.Errorf("failed to create conversation: %w", err)
-	}
-
-	// Add participants
+	participants := make([]*domain.ConversationParticipant, 0, len(input.Participants))
 	for _, userID := range input.Participants {
 		role := "member"
 		if userID == input.CreatedBy {
 			role = "admin"
 		}
-
-		if err := s.conversationRepo.AddParticipantTx(ctx, tx, conversation.ConversationID, userID, role); err != nil {
-			return nil, fmt.Errorf("failed to add participant: %w", err)
-		}
+		participants = append(participants, &domain.ConversationParticipant{
+			ConversationID: conversation.ConversationID,
+			UserID:         userID,
+			Role:           role,
+		})
 	}
 
-	// Set E2EE settings (Default to true if not specified)
-	isE2EE := true
+	// Set E2EE settings (organization default if not specified)
+	isE2EE := s.e2eePolicy.DefaultEnabled
 	if input.IsE2EEEnabled != nil {
 		isE2EE = *input.IsE2EEEnabled
 	}
@@ -112,21 +252,43 @@ func (s *Service) CreateConversation(ctx context.Context, input *CreateConversat
 		IsE2EEEnabled:  isE2EE,
 	}
 
-	if err := s.conversationRepo.UpdateSettingsTx(ctx, tx, conversation.ConversationID, settings); err != nil {
-		return nil, fmt.Errorf("failed to set settings: %w", err)
+	if err := s.creator.CreateWithParticipants(ctx, conversation, participants, settings); err != nil {
+		return nil, err
 	}
 
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	output := &CreateConversationOutput{Conversation: conversation}
+	if input.InitialMessage != nil {
+		output.InitialMessage, output.InitialMessageErr = s.sendInitialMessage(ctx, conversation.ConversationID, input.Credentials, input.InitialMessage)
 	}
+	return output, nil
+}
 
-	return conversation, nil
+// sendInitialMessage sends the creator's first message. Failures are logged
+// and returned for the caller to report; they never undo the conversation.
+func (s *Service) sendInitialMessage(ctx context.Context, conversationID uuid.UUID, credentials string, message *InitialMessage) (*domain.MessageResponse, error) {
+	if s.messageSender == nil {
+		return nil, ErrInitialMessageUnavailable
+	}
+
+	sent, err := s.messageSender.SendMessage(ctx, credentials, conversationID, message)
+	if err != nil {
+		logger.Warn("Failed to send initial message",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return nil, err
+	}
+	return sent, nil
 }
 
 // GetConversation retrieves a conversation by ID
 func (s *Service) GetConversation(ctx context.Context, conversationID uuid.UUID) (*domain.Conversation, error) {
-	return s.conversationRepo.GetByID(ctx, conversationID)
+	conversation, err := s.conversationRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachActivePolls(ctx, conversation)
+	return conversation, nil
 }
 
 // GetUserConversations retrieves all conversations for a user
@@ -138,30 +300,166 @@ func (s *Service) GetUserConversations(ctx context.Context, userID uuid.UUID, li
 		limit = 100
 	}
 
-	return s.conversationRepo.GetUserConversations(ctx, userID, limit, offset)
+	conversations, err := s.conversationRepo.GetUserConversations(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachActivePolls(ctx, conversations...)
+	return conversations, nil
+}
+
+// attachActivePolls fills the active-poll fields of conversations with one
+// lookup for all of them. Failures are logged and ignored so that poll issues
+// never break conversation views.
+func (s *Service) attachActivePolls(ctx context.Context, conversations ...*domain.Conversation) {
+	if s.pollSummary == nil {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(conversations))
+	for _, conversation := range conversations {
+		if conversation != nil {
+			ids = append(ids, conversation.ConversationID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	summaries, err := s.pollSummary.GetActivePollSummaries(ctx, ids)
+	if err != nil {
+		logger.Warn("Failed to get active poll summaries",
+			zap.Int("conversations", len(ids)),
+			zap.Error(err))
+		return
+	}
+
+	for _, conversation := range conversations {
+		if conversation == nil {
+			continue
+		}
+		if summary, ok := summaries[conversation.ConversationID]; ok {
+			conversation.ActivePollCount = summary.Count
+			conversation.ActivePoll = summary.Latest
+		}
+	}
 }
 
-// UpdateE2EESettings updates conversation E2EE settings
-func (s *Service) UpdateE2EESettings(ctx context.Context, conversationID uuid.UUID, enabled bool) error {
+// UpdateE2EESettings turns E2EE on or off for a conversation. Any participant
+// may turn it on. Turning it off is a downgrade: only conversation admins may
+// do it, only when the policy allows it, and every participant is warned with
+// an e2ee_disabled event so no client can switch to plaintext silently.
+func (s *Service) UpdateE2EESettings(ctx context.Context, conversationID, requestingUserID uuid.UUID, enabled bool) error {
+	if err := s.checkMember(ctx, conversationID, requestingUserID); err != nil {
+		return err
+	}
+
+	current, err := s.settings.GetSettings(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	if current.IsE2EEEnabled == enabled {
+		return nil
+	}
+
 	settings := &domain.ConversationSettings{
 		ConversationID: conversationID,
 		IsE2EEEnabled:  enabled,
+		E2EEDisabledBy: current.E2EEDisabledBy,
+		E2EEDisabledAt: current.E2EEDisabledAt,
+		SearchIndexing: current.SearchIndexing,
+	}
+
+	if enabled {
+		if err := s.settings.UpdateSettings(ctx, conversationID, settings); err != nil {
+			return err
+		}
+		s.syncSearchIndex(ctx, current, settings)
+		return nil
+	}
+
+	if !s.e2eePolicy.AllowDowngrade {
+		return domain.ErrE2EEDowngradeBlocked
+	}
+	if err := s.requireAdmin(ctx, conversationID, requestingUserID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	settings.E2EEDisabledBy = &requestingUserID
+	settings.E2EEDisabledAt = &now
+	if err := s.settings.UpdateSettings(ctx, conversationID, settings); err != nil {
+		return err
+	}
+	s.syncSearchIndex(ctx, current, settings)
+
+	logger.Warn("Conversation E2EE disabled",
+		zap.String("conversation_id", conversationID.String()),
+		zap.String("disabled_by", requestingUserID.String()))
+	s.publishConversationEvent(ctx, "e2ee_disabled", conversationID, map[string]interface{}{
+		"disabled_by": requestingUserID,
+		"disabled_at": now,
+	})
+	return nil
+}
+
+// UpdateSearchIndexing opts a conversation in or out of server-side message
+// search. Any participant may opt out; opting in requires a conversation admin
+// and is refused for E2EE conversations, which are never indexed.
+func (s *Service) UpdateSearchIndexing(ctx context.Context, conversationID, requestingUserID uuid.UUID, enabled bool) error {
+	if err := s.checkMember(ctx, conversationID, requestingUserID); err != nil {
+		return err
+	}
+
+	current, err := s.settings.GetSettings(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		if current.IsE2EEEnabled {
+			return domain.ErrSearchIndexingE2EE
+		}
+		if err := s.requireAdmin(ctx, conversationID, requestingUserID); err != nil {
+			return err
+		}
 	}
 
-	return s.conversationRepo.UpdateSettings(ctx, conversationID, settings)
+	settings := *current
+	settings.SearchIndexing = &enabled
+	if err := s.settings.UpdateSettings(ctx, conversationID, &settings); err != nil {
+		return err
+	}
+	s.syncSearchIndex(ctx, current, &settings)
+	return nil
+}
+
+// syncSearchIndex requests a search index sync when a settings change flips
+// whether the conversation is indexable. A failed request is logged; the
+// chat service still checks the settings before indexing new messages.
+func (s *Service) syncSearchIndex(ctx context.Context, before, after *domain.ConversationSettings) {
+	if s.searchIndex == nil || before.IsSearchIndexable() == after.IsSearchIndexable() {
+		return
+	}
+	if err := s.searchIndex.RequestSearchIndexSync(ctx, after.ConversationID); err != nil {
+		logger.Warn("Failed to request search index sync",
+			zap.String("conversation_id", after.ConversationID.String()),
+			zap.Error(err))
+	}
 }
 
 // GetSettings retrieves conversation settings
 func (s *Service) GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error) {
-	return s.conversationRepo.GetSettings(ctx, conversationID)
+	return s.settings.GetSettings(ctx, conversationID)
 }
 
-// AddParticipants adds users to a conversation
+// AddParticipants adds users to a conversation. Nobody is added if any of
+// them is already at the conversation limit.
 func (s *Service) AddParticipants(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID) error {
-	for _, userID := range userIDs {
-		if err := s.conversationRepo.AddParticipant(ctx, conversationID, userID, "member"); err != nil {
-			return fmt.Errorf("failed to add participant %s: %w", userID, err)
-		}
+	// A non-member may have been cached before being added
+	defer s.invalidateMembership(ctx, conversationID, userIDs...)
+
+	if err := s.participants.AddParticipants(ctx, conversationID, userIDs, "member"); err != nil {
+		return fmt.Errorf("failed to add participants: %w", err)
 	}
 	return nil
 }
@@ -171,38 +469,27 @@ func (s *Service) GetParticipants(ctx context.Context, conversationID uuid.UUID)
 	return s.conversationRepo.GetParticipantsWithDetails(ctx, conversationID)
 }
 
-// RemoveParticipant removes a user from a conversation
+// RemoveParticipant removes a user from a conversation. Users may remove
+// themselves; removing anyone else requires a conversation admin.
 func (s *Service) RemoveParticipant(ctx context.Context, conversationID, userID, requestingUserID uuid.UUID) error {
-	// Verify requesting user is admin or removing themselves
-	participants, err := s.conversationRepo.GetParticipants(ctx, conversationID)
-	if err != nil {
-		return fmt.Errorf("failed to get participants: %w", err)
-	}
-
-	// Check if requesting user is admin
-	isAdmin := false
-	for _, p := range participants {
-		if p == requestingUserID {
-			// Get role from database
-			// For simplicity, we'll assume we need to check this properly
-			// In production, we should have a method to get participant with role
-			break
+	if userID != requestingUserID {
+		if err := s.requireAdmin(ctx, conversationID, requestingUserID); err != nil {
+			return err
 		}
 	}
 
-	// Allow users to remove themselves, or admins to remove others
-	if userID != requestingUserID && !isAdmin {
-		return fmt.Errorf("unauthorized: only admins can remove other participants")
+	if err := s.participants.RemoveParticipant(ctx, conversationID, userID); err != nil {
+		return err
 	}
-
-	return s.conversationRepo.RemoveParticipant(ctx, conversationID, userID)
+	s.invalidateMembership(ctx, conversationID, userID)
+	return nil
 }
 
 // UpdateConversation updates conversation metadata
 func (s *Service) UpdateConversation(ctx context.Context, conversationID, requestingUserID uuid.UUID, title *string, avatarURL *string) error {
 	// Verify requesting user is admin
 	// For simplicity, we'll check if user is in conversation
-	isParticipant, err := s.conversationRepo.IsUserInConversation(ctx, conversationID, requestingUserID)
+	isParticipant, err := s.IsMember(ctx, requestingUserID, conversationID)
 	if err != nil {
 		return fmt.Errorf("failed to verify participation: %w", err)
 	}
@@ -225,5 +512,167 @@ func (s *Service) DeleteConversation(ctx context.Context, conversationID, reques
 		return fmt.Errorf("unauthorized: only the creator can delete this conversation")
 	}
 
-	return s.conversationRepo.Delete(ctx, conversationID)
+	members, err := s.participants.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get participants: %w", err)
+	}
+
+	if err := s.conversationRepo.Delete(ctx, conversationID); err != nil {
+		return err
+	}
+	s.invalidateMembership(ctx, conversationID, members...)
+	return nil
+}
+
+// IsMember reports whether userID belongs to the conversation. Answers are
+// cached for a short time when a membership cache is set; cache failures fall
+// back to the database.
+func (s *Service) IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error) {
+	if s.membership != nil {
+		member, found, err := s.membership.Get(ctx, conversationID, userID)
+		switch {
+		case err != nil:
+			metrics.ChatMembershipCacheTotal.WithLabelValues("error").Inc()
+			logger.Debug("Membership cache unavailable", zap.Error(err))
+		case found:
+			metrics.ChatMembershipCacheTotal.WithLabelValues("hit").Inc()
+			return member, nil
+		default:
+			metrics.ChatMembershipCacheTotal.WithLabelValues("miss").Inc()
+		}
+	}
+
+	member, err := s.participants.IsParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if s.membership != nil {
+		if err := s.membership.Set(ctx, conversationID, userID, member, s.membershipTTL); err != nil {
+			logger.Debug("Failed to cache membership", zap.Error(err))
+		}
+	}
+	return member, nil
+}
+
+// checkMember returns domain.ErrNotParticipant unless userID is in the
+// conversation, answering from the membership cache when there is one
+func (s *Service) checkMember(ctx context.Context, conversationID, userID uuid.UUID) error {
+	member, err := s.IsMember(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return domain.ErrNotParticipant
+	}
+	return nil
+}
+
+// invalidateMembership drops cached memberships after a change. A failure is
+// logged; the entry then expires on its own within the cache TTL.
+func (s *Service) invalidateMembership(ctx context.Context, conversationID uuid.UUID, userIDs ...uuid.UUID) {
+	if s.membership == nil || len(userIDs) == 0 {
+		return
+	}
+	if err := s.membership.Invalidate(ctx, conversationID, userIDs...); err != nil {
+		logger.Warn("Failed to invalidate membership cache",
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+}
+
+// MuteParticipant stops targetID from posting in a group until the given time.
+// Only conversation admins may mute, and admins cannot be muted.
+func (s *Service) MuteParticipant(ctx context.Context, conversationID, targetID uuid.UUID, until time.Time, byAdminID uuid.UUID) error {
+	if !until.After(time.Now()) {
+		return domain.ErrInvalidMuteDuration
+	}
+	if err := s.requireAdmin(ctx, conversationID, byAdminID); err != nil {
+		return err
+	}
+
+	target, err := s.participants.GetParticipant(ctx, conversationID, targetID)
+	if err != nil {
+		return err
+	}
+	if target.Role == "admin" {
+		return domain.ErrCannotMuteAdmin
+	}
+
+	if err := s.participants.SetParticipantMute(ctx, conversationID, targetID, &until); err != nil {
+		return err
+	}
+
+	s.publishConversationEvent(ctx, "participant_muted", conversationID, map[string]interface{}{
+		"user_id":     targetID,
+		"muted_until": until,
+		"muted_by":    byAdminID,
+	})
+	return nil
+}
+
+// UnmuteParticipant lifts a mute before it expires
+func (s *Service) UnmuteParticipant(ctx context.Context, conversationID, targetID, byAdminID uuid.UUID) error {
+	if err := s.requireAdmin(ctx, conversationID, byAdminID); err != nil {
+		return err
+	}
+
+	if err := s.participants.SetParticipantMute(ctx, conversationID, targetID, nil); err != nil {
+		return err
+	}
+
+	s.publishConversationEvent(ctx, "participant_unmuted", conversationID, map[string]interface{}{
+		"user_id":    targetID,
+		"unmuted_by": byAdminID,
+	})
+	return nil
+}
+
+// SetCallsMuted sets whether group calls in the conversation ring userID.
+// Message notifications and direct calls are not affected.
+func (s *Service) SetCallsMuted(ctx context.Context, conversationID, userID uuid.UUID, muted bool) error {
+	return s.participants.SetCallsMuted(ctx, conversationID, userID, muted)
+}
+
+// requireAdmin returns an error unless userID is an admin of the conversation
+func (s *Service) requireAdmin(ctx context.Context, conversationID, userID uuid.UUID) error {
+	participant, err := s.participants.GetParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if participant.Role != "admin" {
+		return domain.ErrNotConversationAdmin
+	}
+	return nil
+}
+
+// publishConversationEvent notifies the conversation's WebSocket subscribers.
+// The payload follows the chat WebSocket message format.
+func (s *Service) publishConversationEvent(ctx context.Context, eventType string, conversationID uuid.UUID, metadata map[string]interface{}) {
+	if s.publisher == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"type":            eventType,
+		"conversation_id": conversationID,
+		"metadata":        metadata,
+		"timestamp":       time.Now(),
+	}
+
+	messageJSON, err := json.Marshal(event)
+	if err != nil {
+		logger.Warn("Failed to marshal conversation event",
+			zap.String("type", eventType),
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return
+	}
+
+	if err := s.publisher.Publish(ctx, fmt.Sprintf("chat:%s", conversationID), messageJSON); err != nil {
+		logger.Warn("Failed to publish conversation event",
+			zap.String("type", eventType),
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
 }
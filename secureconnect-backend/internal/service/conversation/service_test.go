@@ -0,0 +1,719 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// fakeParticipants keeps memberships of a single conversation in memory
+type fakeParticipants struct {
+	members map[uuid.UUID]*domain.ConversationParticipant
+	lookups int
+	addErr  error
+}
+
+func (f *fakeParticipants) IsParticipant(ctx context.Context, conversationID, userID uuid.UUID) (bool, error) {
+	f.lookups++
+	_, ok := f.members[userID]
+	return ok, nil
+}
+
+func (f *fakeParticipants) GetParticipants(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(f.members))
+	for id := range f.members {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (f *fakeParticipants) AddParticipants(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, role string) error {
+	if f.addErr != nil {
+		return f.addErr
+	}
+	for _, userID := range userIDs {
+		f.members[userID] = &domain.ConversationParticipant{ConversationID: conversationID, UserID: userID, Role: role}
+	}
+	return nil
+}
+
+func (f *fakeParticipants) RemoveParticipant(ctx context.Context, conversationID, userID uuid.UUID) error {
+	delete(f.members, userID)
+	return nil
+}
+
+func (f *fakeParticipants) GetParticipant(ctx context.Context, conversationID, userID uuid.UUID) (*domain.ConversationParticipant, error) {
+	p, ok := f.members[userID]
+	if !ok {
+		return nil, domain.ErrNotParticipant
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (f *fakeParticipants) SetParticipantMute(ctx context.Context, conversationID, userID uuid.UUID, until *time.Time) error {
+	p, ok := f.members[userID]
+	if !ok {
+		return domain.ErrNotParticipant
+	}
+	p.MutedUntil = until
+	return nil
+}
+
+func (f *fakeParticipants) SetCallsMuted(ctx context.Context, conversationID, userID uuid.UUID, muted bool) error {
+	p, ok := f.members[userID]
+	if !ok {
+		return domain.ErrNotParticipant
+	}
+	p.CallsMuted = muted
+	return nil
+}
+
+type fakePublisher struct {
+	events []map[string]interface{}
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	var event map[string]interface{}
+	if err := json.Unmarshal(message.([]byte), &event); err != nil {
+		return err
+	}
+	event["channel"] = channel
+	f.events = append(f.events, event)
+	return nil
+}
+
+// groupParticipants returns a conversation with one admin and one member
+func groupParticipants(conversationID, admin, member uuid.UUID) *fakeParticipants {
+	return &fakeParticipants{members: map[uuid.UUID]*domain.ConversationParticipant{
+		admin:  {ConversationID: conversationID, UserID: admin, Role: "admin"},
+		member: {ConversationID: conversationID, UserID: member, Role: "member"},
+	}}
+}
+
+func TestMuteParticipant_AdminMutesMember(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	participants := groupParticipants(conversationID, admin, member)
+	publisher := &fakePublisher{}
+	service := &Service{participants: participants}
+	service.SetPublisher(publisher)
+	until := time.Now().Add(time.Hour)
+
+	require.NoError(t, service.MuteParticipant(context.Background(), conversationID, member, until, admin))
+
+	muted := participants.members[member]
+	require.NotNil(t, muted.MutedUntil)
+	assert.True(t, muted.IsMuted(time.Now()))
+	assert.False(t, muted.IsMuted(until.Add(time.Second)), "mute lapses on its own")
+
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, "participant_muted", publisher.events[0]["type"])
+	assert.Equal(t, "chat:"+conversationID.String(), publisher.events[0]["channel"])
+	assert.Equal(t, member.String(), publisher.events[0]["metadata"].(map[string]interface{})["user_id"])
+
+	require.NoError(t, service.UnmuteParticipant(context.Background(), conversationID, member, admin))
+	assert.Nil(t, participants.members[member].MutedUntil)
+}
+
+func TestMuteParticipant_AdminGate(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name    string
+		target  string // "admin" or "member"
+		by      string // "admin", "member" or "outsider"
+		until   time.Duration
+		wantErr error
+	}{
+		{name: "members cannot mute", target: "admin", by: "member", until: time.Hour, wantErr: domain.ErrNotConversationAdmin},
+		{name: "outsiders cannot mute", target: "member", by: "outsider", until: time.Hour, wantErr: domain.ErrNotParticipant},
+		{name: "admins cannot be muted", target: "admin", by: "admin", until: time.Hour, wantErr: domain.ErrCannotMuteAdmin},
+		{name: "mutes must end in the future", target: "member", by: "admin", until: -time.Minute, wantErr: domain.ErrInvalidMuteDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+			participants := groupParticipants(conversationID, admin, member)
+			publisher := &fakePublisher{}
+			service := &Service{participants: participants}
+			service.SetPublisher(publisher)
+			users := map[string]uuid.UUID{"admin": admin, "member": member, "outsider": uuid.New()}
+
+			err := service.MuteParticipant(context.Background(), conversationID, users[tt.target], time.Now().Add(tt.until), users[tt.by])
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, participants.members[users[tt.target]].MutedUntil)
+			assert.Empty(t, publisher.events)
+		})
+	}
+}
+
+func TestUnmuteParticipant_AdminOnly(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	service := &Service{participants: groupParticipants(conversationID, admin, member)}
+
+	assert.ErrorIs(t, service.UnmuteParticipant(context.Background(), conversationID, member, member), domain.ErrNotConversationAdmin)
+}
+
+// fakeSettings stores the settings of a single conversation
+type fakeSettings struct {
+	current domain.ConversationSettings
+	writes  int
+}
+
+func (f *fakeSettings) GetSettings(ctx context.Context, conversationID uuid.UUID) (*domain.ConversationSettings, error) {
+	copied := f.current
+	return &copied, nil
+}
+
+func (f *fakeSettings) UpdateSettings(ctx context.Context, conversationID uuid.UUID, settings *domain.ConversationSettings) error {
+	f.current = *settings
+	f.writes++
+	return nil
+}
+
+func TestUpdateE2EESettings(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name        string
+		policy      E2EEPolicy
+		enabled     bool   // E2EE state before the change
+		by          string // "admin", "member" or "outsider"
+		enable      bool
+		wantErr     error
+		wantEnabled bool
+		wantWarning bool // an e2ee_disabled event is published
+	}{
+		{
+			name:        "members cannot disable",
+			policy:      E2EEPolicy{AllowDowngrade: true},
+			enabled:     true,
+			by:          "member",
+			wantErr:     domain.ErrNotConversationAdmin,
+			wantEnabled: true,
+		},
+		{
+			name:        "outsiders cannot change settings",
+			policy:      E2EEPolicy{AllowDowngrade: true},
+			enabled:     true,
+			by:          "outsider",
+			wantErr:     domain.ErrNotParticipant,
+			wantEnabled: true,
+		},
+		{
+			name:        "policy blocks admins too",
+			enabled:     true,
+			by:          "admin",
+			wantErr:     domain.ErrE2EEDowngradeBlocked,
+			wantEnabled: true,
+		},
+		{
+			name:        "admins disable when the policy allows it",
+			policy:      E2EEPolicy{AllowDowngrade: true},
+			enabled:     true,
+			by:          "admin",
+			wantWarning: true,
+		},
+		{
+			name:        "members can enable",
+			by:          "member",
+			enable:      true,
+			wantEnabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+			settings := &fakeSettings{current: domain.ConversationSettings{ConversationID: conversationID, IsE2EEEnabled: tt.enabled}}
+			publisher := &fakePublisher{}
+			service := &Service{participants: groupParticipants(conversationID, admin, member), settings: settings}
+			service.SetPublisher(publisher)
+			service.SetE2EEPolicy(tt.policy)
+			by := map[string]uuid.UUID{"admin": admin, "member": member, "outsider": uuid.New()}[tt.by]
+
+			err := service.UpdateE2EESettings(context.Background(), conversationID, by, tt.enable)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, settings.writes)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantEnabled, settings.current.IsE2EEEnabled)
+			if tt.wantWarning {
+				require.Len(t, publisher.events, 1)
+				assert.Equal(t, "e2ee_disabled", publisher.events[0]["type"])
+			} else {
+				assert.Empty(t, publisher.events)
+			}
+		})
+	}
+}
+
+func TestUpdateE2EESettings_AdminDisableBroadcastsWarning(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	settings := &fakeSettings{current: domain.ConversationSettings{ConversationID: conversationID, IsE2EEEnabled: true}}
+	publisher := &fakePublisher{}
+	service := &Service{participants: groupParticipants(conversationID, admin, member), settings: settings}
+	service.SetPublisher(publisher)
+	service.SetE2EEPolicy(E2EEPolicy{AllowDowngrade: true})
+
+	require.NoError(t, service.UpdateE2EESettings(context.Background(), conversationID, admin, false))
+
+	assert.False(t, settings.current.IsE2EEEnabled)
+	require.NotNil(t, settings.current.E2EEDisabledBy)
+	assert.Equal(t, admin, *settings.current.E2EEDisabledBy)
+	require.NotNil(t, settings.current.E2EEDisabledAt)
+	assert.WithinDuration(t, time.Now(), *settings.current.E2EEDisabledAt, time.Minute)
+
+	require.Len(t, publisher.events, 1)
+	event := publisher.events[0]
+	assert.Equal(t, "e2ee_disabled", event["type"])
+	assert.Equal(t, "chat:"+conversationID.String(), event["channel"])
+	assert.Equal(t, admin.String(), event["metadata"].(map[string]interface{})["disabled_by"])
+
+	require.NoError(t, service.UpdateE2EESettings(context.Background(), conversationID, admin, false))
+	assert.Len(t, publisher.events, 1, "disabling an already disabled conversation does not warn again")
+
+	require.NoError(t, service.UpdateE2EESettings(context.Background(), conversationID, admin, true))
+	assert.True(t, settings.current.IsE2EEEnabled)
+	assert.Equal(t, admin, *settings.current.E2EEDisabledBy, "re-enabling keeps the record of who disabled it")
+}
+
+// fakeMembershipCache is an in-memory cache with a controllable clock
+type fakeMembershipCache struct {
+	now     time.Time
+	entries map[uuid.UUID]fakeMembershipEntry
+}
+
+type fakeMembershipEntry struct {
+	member    bool
+	expiresAt time.Time
+}
+
+func (f *fakeMembershipCache) Get(ctx context.Context, conversationID, userID uuid.UUID) (bool, bool, error) {
+	entry, ok := f.entries[userID]
+	if !ok || !f.now.Before(entry.expiresAt) {
+		return false, false, nil
+	}
+	return entry.member, true, nil
+}
+
+func (f *fakeMembershipCache) Set(ctx context.Context, conversationID, userID uuid.UUID, member bool, ttl time.Duration) error {
+	f.entries[userID] = fakeMembershipEntry{member: member, expiresAt: f.now.Add(ttl)}
+	return nil
+}
+
+func (f *fakeMembershipCache) Invalidate(ctx context.Context, conversationID uuid.UUID, userIDs ...uuid.UUID) error {
+	for _, userID := range userIDs {
+		delete(f.entries, userID)
+	}
+	return nil
+}
+
+func TestIsMember_CacheHit(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	participants := &fakeParticipants{members: map[uuid.UUID]*domain.ConversationParticipant{
+		admin:  {ConversationID: conversationID, UserID: admin, Role: "admin"},
+		member: {ConversationID: conversationID, UserID: member, Role: "member"},
+	}}
+	cache := &fakeMembershipCache{now: time.Now(), entries: map[uuid.UUID]fakeMembershipEntry{}}
+	service := &Service{participants: participants}
+	service.SetMembershipCache(cache, 30*time.Second)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		ok, err := service.IsMember(ctx, member, conversationID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 1, participants.lookups, "only the first check reaches the database")
+
+	outsider := uuid.New()
+	ok, err := service.IsMember(ctx, outsider, conversationID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, _ = service.IsMember(ctx, outsider, conversationID)
+	assert.False(t, ok)
+	assert.Equal(t, 2, participants.lookups, "non-members are cached too")
+}
+
+func TestIsMember_InvalidatedOnParticipantChanges(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	participants := &fakeParticipants{members: map[uuid.UUID]*domain.ConversationParticipant{
+		admin:  {ConversationID: conversationID, UserID: admin, Role: "admin"},
+		member: {ConversationID: conversationID, UserID: member, Role: "member"},
+	}}
+	cache := &fakeMembershipCache{now: time.Now(), entries: map[uuid.UUID]fakeMembershipEntry{}}
+	service := &Service{participants: participants}
+	service.SetMembershipCache(cache, 30*time.Second)
+	ctx := context.Background()
+
+	ok, _ := service.IsMember(ctx, member, conversationID)
+	require.True(t, ok)
+
+	require.NoError(t, service.RemoveParticipant(ctx, conversationID, member, admin))
+	ok, err := service.IsMember(ctx, member, conversationID)
+	require.NoError(t, err)
+	assert.False(t, ok, "removed participant is not served from cache")
+
+	require.NoError(t, service.AddParticipants(ctx, conversationID, []uuid.UUID{member}))
+	ok, err = service.IsMember(ctx, member, conversationID)
+	require.NoError(t, err)
+	assert.True(t, ok, "re-added participant is not denied from a cached miss")
+	assert.Equal(t, 3, participants.lookups)
+
+	err = service.RemoveParticipant(ctx, conversationID, admin, member)
+	assert.ErrorIs(t, err, domain.ErrNotConversationAdmin, "members cannot remove others")
+}
+
+func TestIsMember_FallsBackToDatabaseAfterTTL(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	participants := &fakeParticipants{members: map[uuid.UUID]*domain.ConversationParticipant{
+		admin:  {ConversationID: conversationID, UserID: admin, Role: "admin"},
+		member: {ConversationID: conversationID, UserID: member, Role: "member"},
+	}}
+	cache := &fakeMembershipCache{now: time.Now(), entries: map[uuid.UUID]fakeMembershipEntry{}}
+	service := &Service{participants: participants}
+	service.SetMembershipCache(cache, 30*time.Second)
+	ctx := context.Background()
+
+	ok, _ := service.IsMember(ctx, member, conversationID)
+	require.True(t, ok)
+
+	// A change made outside the service is picked up once the entry expires
+	delete(participants.members, member)
+	ok, _ = service.IsMember(ctx, member, conversationID)
+	assert.True(t, ok)
+
+	cache.now = cache.now.Add(31 * time.Second)
+	ok, err := service.IsMember(ctx, member, conversationID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, participants.lookups)
+}
+
+type fakeSearchIndexSyncer struct {
+	requests []uuid.UUID
+}
+
+func (f *fakeSearchIndexSyncer) RequestSearchIndexSync(ctx context.Context, conversationID uuid.UUID) error {
+	f.requests = append(f.requests, conversationID)
+	return nil
+}
+
+func TestUpdateSearchIndexing(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name       string
+		e2ee       bool
+		by         string // "admin" or "member"
+		index      bool
+		wantErr    error
+		wantSynced bool
+	}{
+		{name: "E2EE conversations cannot opt in", e2ee: true, by: "admin", index: true, wantErr: domain.ErrSearchIndexingE2EE},
+		{name: "members can opt out and the index is synced", by: "member", wantSynced: true},
+		{name: "opting in requires an admin", by: "member", index: true, wantErr: domain.ErrNotConversationAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+			settings := &fakeSettings{current: domain.ConversationSettings{ConversationID: conversationID, IsE2EEEnabled: tt.e2ee}}
+			syncer := &fakeSearchIndexSyncer{}
+			service := &Service{participants: groupParticipants(conversationID, admin, member), settings: settings}
+			service.SetSearchIndexSyncer(syncer)
+			by := map[string]uuid.UUID{"admin": admin, "member": member}[tt.by]
+
+			err := service.UpdateSearchIndexing(context.Background(), conversationID, by, tt.index)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, settings.writes)
+				assert.Empty(t, syncer.requests)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, settings.current.SearchIndexing)
+			assert.Equal(t, tt.index, *settings.current.SearchIndexing)
+			if tt.wantSynced {
+				assert.Equal(t, []uuid.UUID{conversationID}, syncer.requests)
+			}
+		})
+	}
+}
+
+func TestUpdateE2EESettings_RemovesConversationFromSearch(t *testing.T) {
+	logger.InitDefault("test")
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	syncer := &fakeSearchIndexSyncer{}
+	service := &Service{participants: groupParticipants(conversationID, admin, member), settings: &fakeSettings{current: domain.ConversationSettings{ConversationID: conversationID}}}
+	service.SetSearchIndexSyncer(syncer)
+
+	require.NoError(t, service.UpdateE2EESettings(context.Background(), conversationID, member, true))
+	assert.Equal(t, []uuid.UUID{conversationID}, syncer.requests)
+}
+
+// fakeCreator records created conversations and their participants
+type fakeCreator struct {
+	conversations map[uuid.UUID]*domain.Conversation
+	participants  map[uuid.UUID][]*domain.ConversationParticipant
+	err           error
+}
+
+func (f *fakeCreator) CreateWithParticipants(ctx context.Context, conversation *domain.Conversation, participants []*domain.ConversationParticipant, settings *domain.ConversationSettings) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.conversations[conversation.ConversationID] = conversation
+	f.participants[conversation.ConversationID] = participants
+	return nil
+}
+
+type allUsersExist struct{}
+
+func (allUsersExist) UsersExist(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	exists := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		exists[id] = true
+	}
+	return exists, nil
+}
+
+// fakeMessageSender only accepts messages for conversations that already exist
+type fakeMessageSender struct {
+	creator     *fakeCreator
+	credentials []string
+	err         error
+}
+
+func (f *fakeMessageSender) SendMessage(ctx context.Context, credentials string, conversationID uuid.UUID, message *InitialMessage) (*domain.MessageResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if _, ok := f.creator.conversations[conversationID]; !ok {
+		return nil, domain.ErrNotParticipant
+	}
+	f.credentials = append(f.credentials, credentials)
+	return &domain.MessageResponse{
+		MessageID:      uuid.New(),
+		ConversationID: conversationID,
+		Content:        message.Content,
+	}, nil
+}
+
+func TestCreateConversation_WithInitialMessage(t *testing.T) {
+	logger.InitDefault("test")
+	alice, bob := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name             string
+		participants     []uuid.UUID
+		sendErr          error
+		wantErr          bool
+		wantConversation bool
+		wantMessageErr   string
+	}{
+		{name: "creates the conversation and sends the message", participants: []uuid.UUID{alice, bob}, wantConversation: true},
+		{name: "a failed send keeps the conversation and reports the error", participants: []uuid.UUID{alice, bob}, sendErr: errors.New("chat service unavailable"), wantConversation: true, wantMessageErr: "chat service unavailable"},
+		{name: "the creator must be a participant", participants: []uuid.UUID{bob, uuid.New()}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creator := &fakeCreator{
+				conversations: map[uuid.UUID]*domain.Conversation{},
+				participants:  map[uuid.UUID][]*domain.ConversationParticipant{},
+			}
+			sender := &fakeMessageSender{creator: creator, err: tt.sendErr}
+			service := &Service{creator: creator, users: allUsersExist{}}
+			service.SetMessageSender(sender)
+
+			output, err := service.CreateConversation(context.Background(), &CreateConversationInput{
+				Type:           "direct",
+				CreatedBy:      alice,
+				Participants:   tt.participants,
+				InitialMessage: &InitialMessage{Content: "hi bob", MessageType: "text", ClientMsgID: "c-1"},
+				Credentials:    "Bearer alice-token",
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, creator.conversations)
+				return
+			}
+			require.NoError(t, err)
+			conversationID := output.Conversation.ConversationID
+			assert.Contains(t, creator.conversations, conversationID)
+			assert.Len(t, creator.participants[conversationID], 2)
+
+			if tt.wantMessageErr != "" {
+				assert.Nil(t, output.InitialMessage)
+				assert.EqualError(t, output.InitialMessageErr, tt.wantMessageErr)
+				return
+			}
+			require.NoError(t, output.InitialMessageErr)
+			require.NotNil(t, output.InitialMessage)
+			assert.Equal(t, conversationID, output.InitialMessage.ConversationID)
+			assert.Equal(t, []string{"Bearer alice-token"}, sender.credentials, "sent as the creator")
+		})
+	}
+}
+
+func TestConversationLimit_ErrorShape(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	limitErr := &domain.ConversationLimitError{Limit: 2, UserIDs: []uuid.UUID{alice}}
+
+	tests := []struct {
+		name string
+		call func(service *Service) error
+	}{
+		{
+			name: "creating a conversation",
+			call: func(service *Service) error {
+				_, err := service.CreateConversation(ctx, &CreateConversationInput{
+					Type:           "group",
+					CreatedBy:      alice,
+					Participants:   []uuid.UUID{alice, bob},
+					InitialMessage: &InitialMessage{Content: "hi"},
+				})
+				return err
+			},
+		},
+		{
+			name: "adding participants",
+			call: func(service *Service) error {
+				return service.AddParticipants(ctx, uuid.New(), []uuid.UUID{bob, alice})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creator := &fakeCreator{conversations: map[uuid.UUID]*domain.Conversation{}, err: limitErr}
+			participants := &fakeParticipants{members: map[uuid.UUID]*domain.ConversationParticipant{}, addErr: limitErr}
+			sender := &fakeMessageSender{}
+			service := &Service{creator: creator, users: allUsersExist{}, participants: participants, messageSender: sender}
+
+			err := tt.call(service)
+			require.ErrorIs(t, err, domain.ErrConversationLimitReached)
+			var got *domain.ConversationLimitError
+			require.ErrorAs(t, err, &got)
+			assert.Equal(t, 2, got.Limit)
+			assert.Equal(t, []uuid.UUID{alice}, got.UserIDs, "only users at the limit are named")
+			assert.Empty(t, creator.conversations)
+			assert.Empty(t, participants.members)
+			assert.Empty(t, sender.credentials, "no initial message is sent")
+		})
+	}
+}
+
+// fakeBots stores bots in memory
+type fakeBots struct {
+	bots []*domain.Bot
+}
+
+func (f *fakeBots) CreateBot(ctx context.Context, bot *domain.Bot, maxBots int) error {
+	bots, _ := f.GetBotsByConversation(ctx, bot.ConversationID)
+	if len(bots) >= maxBots {
+		return domain.ErrBotLimitReached
+	}
+	f.bots = append(f.bots, bot)
+	return nil
+}
+
+func (f *fakeBots) GetBotsByConversation(ctx context.Context, conversationID uuid.UUID) ([]*domain.Bot, error) {
+	var bots []*domain.Bot
+	for _, bot := range f.bots {
+		if bot.ConversationID == conversationID {
+			bots = append(bots, bot)
+		}
+	}
+	return bots, nil
+}
+
+func (f *fakeBots) DeleteBot(ctx context.Context, conversationID, botID uuid.UUID) error {
+	return nil
+}
+
+func TestRegisterBot(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+	conversationID, admin, member := uuid.New(), uuid.New(), uuid.New()
+	settings := &fakeSettings{current: domain.ConversationSettings{ConversationID: conversationID, IsE2EEEnabled: true}}
+	publisher := &fakePublisher{}
+	service := &Service{participants: groupParticipants(conversationID, admin, member), settings: settings}
+	service.SetPublisher(publisher)
+	bots := &fakeBots{}
+	service.SetBotRepository(bots)
+	const endpoint, secret = "https://bots.example.com/hook", "a-long-enough-secret"
+
+	_, err := service.RegisterBot(ctx, conversationID, admin, endpoint, secret)
+	assert.ErrorIs(t, err, domain.ErrBotE2EEConversation)
+
+	settings.current.IsE2EEEnabled = false
+	_, err = service.RegisterBot(ctx, conversationID, member, endpoint, secret)
+	assert.ErrorIs(t, err, domain.ErrNotConversationAdmin)
+	_, err = service.RegisterBot(ctx, conversationID, admin, endpoint, "short")
+	assert.ErrorIs(t, err, ErrInvalidBotSecret)
+
+	output, err := service.RegisterBot(ctx, conversationID, admin, endpoint, secret)
+	require.NoError(t, err)
+	assert.Equal(t, domain.HashBotToken(output.Token), output.Bot.TokenHash, "only the token hash is stored")
+	assert.Equal(t, "bot_added", publisher.events[0]["type"])
+
+	for i := 1; i < domain.MaxBotsPerConversation; i++ {
+		_, err = service.RegisterBot(ctx, conversationID, admin, endpoint, secret)
+		require.NoError(t, err)
+	}
+	_, err = service.RegisterBot(ctx, conversationID, admin, endpoint, secret)
+	assert.ErrorIs(t, err, domain.ErrBotLimitReached)
+}
+
+// fakePollSummaries records each lookup of active polls
+type fakePollSummaries struct {
+	summaries map[uuid.UUID]*domain.ActivePolls
+	lookups   [][]uuid.UUID
+}
+
+func (f *fakePollSummaries) GetActivePollSummaries(ctx context.Context, conversationIDs []uuid.UUID) (map[uuid.UUID]*domain.ActivePolls, error) {
+	f.lookups = append(f.lookups, conversationIDs)
+	return f.summaries, nil
+}
+
+func TestAttachActivePolls_OneLookupForAllConversations(t *testing.T) {
+	withPolls, withoutPolls := uuid.New(), uuid.New()
+	latest := &domain.ActivePollSummary{PollID: uuid.New(), Question: "Lunch?"}
+	polls := &fakePollSummaries{summaries: map[uuid.UUID]*domain.ActivePolls{
+		withPolls: {Count: 2, Latest: latest},
+	}}
+	service := &Service{pollSummary: polls}
+
+	conversations := []*domain.Conversation{{ConversationID: withPolls}, {ConversationID: withoutPolls}}
+	service.attachActivePolls(context.Background(), conversations...)
+
+	require.Len(t, polls.lookups, 1)
+	assert.ElementsMatch(t, []uuid.UUID{withPolls, withoutPolls}, polls.lookups[0])
+	assert.Equal(t, 2, conversations[0].ActivePollCount)
+	assert.Equal(t, latest, conversations[0].ActivePoll)
+	assert.Zero(t, conversations[1].ActivePollCount)
+	assert.Nil(t, conversations[1].ActivePoll)
+}
@@ -3,7 +3,9 @@ package poll
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,7 +13,10 @@ import (
 	"go.uber.org/zap"
 
 	"secureconnect-backend/internal/domain"
+	pkgContext "secureconnect-backend/pkg/context"
 	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/pagination"
+	"secureconnect-backend/pkg/workerpool"
 )
 
 // PollRepository interface for poll data operations
@@ -21,14 +26,16 @@ type PollRepository interface {
 	GetPollByIDWithVotes(ctx context.Context, pollID uuid.UUID) (*domain.Poll, error)
 	GetPollByIDWithUserVote(ctx context.Context, pollID, userID uuid.UUID) (*domain.Poll, error)
 	GetPollsByConversation(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*domain.Poll, int, error)
+	GetPollsByConversationAfter(ctx context.Context, conversationID uuid.UUID, cursor *pagination.Cursor, limit int) ([]*domain.Poll, error)
 	GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]*domain.PollOption, error)
 	GetPollOptionsWithVotes(ctx context.Context, pollID uuid.UUID) ([]*domain.PollOption, error)
-	CastVote(ctx context.Context, vote *domain.PollVote) error
+	CastVotes(ctx context.Context, pollID, userID uuid.UUID, optionIDs []uuid.UUID) error
 	ChangeVote(ctx context.Context, pollID, userID uuid.UUID, newOptionIDs []uuid.UUID) error
 	GetUserVotes(ctx context.Context, pollID, userID uuid.UUID) ([]*domain.PollVote, error)
 	ClosePoll(ctx context.Context, pollID uuid.UUID) error
 	DeletePoll(ctx context.Context, pollID uuid.UUID) error
 	GetActivePolls(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*domain.Poll, int, error)
+	GetActivePollSummaries(ctx context.Context, conversationIDs []uuid.UUID) (map[uuid.UUID]*domain.ActivePolls, error)
 	IsPollCreator(ctx context.Context, pollID, userID uuid.UUID) (bool, error)
 	GetPollsByCreator(ctx context.Context, creatorID uuid.UUID, limit, offset int) ([]*domain.Poll, int, error)
 }
@@ -38,6 +45,12 @@ type ConversationRepository interface {
 	GetParticipants(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error)
 }
 
+// MembershipChecker answers whether a user belongs to a conversation, from a
+// cache where one is configured
+type MembershipChecker interface {
+	IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error)
+}
+
 // UserRepository interface for getting user details
 type UserRepository interface {
 	GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error)
@@ -64,8 +77,26 @@ type Service struct {
 	conversationRepo ConversationRepository
 	userRepo         UserRepository
 	publisher        Publisher
+	voteLocker       Locker            // nil until SetVoteLocker
+	membership       MembershipChecker // nil until SetMembershipChecker
+
+	// publishTimeout bounds each event publish started after a request
+	publishTimeout time.Duration
+	// publishPool runs event publishes so a burst of votes cannot spawn
+	// unbounded goroutines
+	publishPool *workerpool.Pool
 }
 
+// DefaultPublishTimeout bounds publishing one poll event. Publishes run after
+// the request has returned, so they are not cancelled with it.
+const DefaultPublishTimeout = 5 * time.Second
+
+// Defaults for the pool that publishes poll events
+const (
+	DefaultPublishWorkers   = 8
+	DefaultPublishQueueSize = 1024
+)
+
 // NewService creates a new poll service
 func NewService(
 	pollRepo PollRepository,
@@ -78,6 +109,50 @@ func NewService(
 		conversationRepo: conversationRepo,
 		userRepo:         userRepo,
 		publisher:        publisher,
+		publishTimeout:   DefaultPublishTimeout,
+		publishPool:      workerpool.New("poll_publish", DefaultPublishWorkers, DefaultPublishQueueSize),
+	}
+}
+
+// SetMembershipChecker answers membership checks, usually from a cache.
+// Without one the conversation's participant list is read.
+func (s *Service) SetMembershipChecker(checker MembershipChecker) {
+	s.membership = checker
+}
+
+// isMember reports whether userID belongs to the conversation
+func (s *Service) isMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error) {
+	if s.membership != nil {
+		member, err := s.membership.IsMember(ctx, userID, conversationID)
+		if err != nil {
+			return false, fmt.Errorf("failed to check conversation membership: %w", err)
+		}
+		return member, nil
+	}
+	participants, err := s.conversationRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get conversation participants: %w", err)
+	}
+	return slices.Contains(participants, userID), nil
+}
+
+// SetPublishPool replaces the pool that publishes poll events. The caller
+// shuts it down, after the servers stop, so queued events still go out.
+func (s *Service) SetPublishPool(pool *workerpool.Pool) {
+	s.publishPool = pool
+}
+
+// publishAsync queues publish on the publish pool with a context detached
+// from ctx's cancellation and bounded by publishTimeout. When the queue is
+// full the event is dropped; clients see the change when they reload the poll.
+func (s *Service) publishAsync(ctx context.Context, publish func(ctx context.Context)) {
+	queued := s.publishPool.Submit(func() {
+		publishCtx, cancel := pkgContext.Detached(ctx, s.publishTimeout)
+		defer cancel()
+		publish(publishCtx)
+	})
+	if !queued {
+		logger.Warn("Poll event publish pool is full or stopped, dropping event")
 	}
 }
 
@@ -110,21 +185,12 @@ func (s *Service) CreatePoll(ctx context.Context, input *CreatePollInput) (*Crea
 	}
 
 	// Verify user is a participant in the conversation
-	participants, err := s.conversationRepo.GetParticipants(ctx, input.ConversationID)
+	isParticipant, err := s.isMember(ctx, input.CreatorID, input.ConversationID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get conversation participants: %w", err)
-	}
-
-	isParticipant := false
-	for _, participantID := range participants {
-		if participantID == input.CreatorID {
-			isParticipant = true
-			break
-		}
+		return nil, err
 	}
-
 	if !isParticipant {
-		return nil, fmt.Errorf("user is not a participant in this conversation")
+		return nil, domain.ErrNotParticipant
 	}
 
 	// Create poll entity
@@ -171,7 +237,9 @@ func (s *Service) CreatePoll(ctx context.Context, input *CreatePollInput) (*Crea
 	}
 
 	// Publish poll_created event (non-blocking)
-	go s.publishPollCreated(context.Background(), poll.ConversationID, response)
+	s.publishAsync(ctx, func(ctx context.Context) {
+		s.publishPollCreated(ctx, poll.ConversationID, response)
+	})
 
 	return &CreatePollOutput{Poll: response}, nil
 }
@@ -222,21 +290,24 @@ func (s *Service) GetPoll(ctx context.Context, input *GetPollInput) (*GetPollOut
 	return &GetPollOutput{Poll: response}, nil
 }
 
-// GetPollsInput contains query parameters for listing polls
+// GetPollsInput contains query parameters for listing polls.
+// When Cursor is set, keyset pagination is used and Page is ignored.
 type GetPollsInput struct {
 	ConversationID uuid.UUID
 	Page           int
 	PageSize       int
+	Cursor         string
 	UserID         uuid.UUID
 }
 
 // GetPollsOutput contains poll list
 type GetPollsOutput struct {
-	Polls    []*domain.PollResponse
-	Total    int
-	Page     int
-	PageSize int
-	HasMore  bool
+	Polls      []*domain.PollResponse
+	Total      int
+	Page       int
+	PageSize   int
+	HasMore    bool
+	NextCursor string
 }
 
 // GetPolls retrieves polls for a conversation with pagination
@@ -252,7 +323,14 @@ func (s *Service) GetPolls(ctx context.Context, input *GetPollsInput) (*GetPolls
 		input.PageSize = 100
 	}
 
+	if input.Cursor != "" {
+		return s.getPollsAfter(ctx, input)
+	}
+
 	offset := (input.Page - 1) * input.PageSize
+	if err := pagination.ValidateOffset(offset); err != nil {
+		return nil, err
+	}
 
 	// Get polls
 	polls, total, err := s.pollRepo.GetPollsByConversation(ctx, input.ConversationID, input.PageSize, offset)
@@ -260,6 +338,46 @@ func (s *Service) GetPolls(ctx context.Context, input *GetPollsInput) (*GetPolls
 		return nil, fmt.Errorf("failed to get polls: %w", err)
 	}
 
+	output := &GetPollsOutput{
+		Polls:    s.buildPollResponses(ctx, polls, input.UserID),
+		Total:    total,
+		Page:     input.Page,
+		PageSize: input.PageSize,
+		HasMore:  (input.Page * input.PageSize) < total,
+	}
+	if output.HasMore && len(polls) > 0 {
+		last := polls[len(polls)-1]
+		output.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.PollID)
+	}
+	return output, nil
+}
+
+// getPollsAfter lists polls older than input.Cursor using keyset pagination
+func (s *Service) getPollsAfter(ctx context.Context, input *GetPollsInput) (*GetPollsOutput, error) {
+	cursor, err := pagination.DecodeCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to know whether another page follows
+	polls, err := s.pollRepo.GetPollsByConversationAfter(ctx, input.ConversationID, cursor, input.PageSize+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get polls: %w", err)
+	}
+
+	output := &GetPollsOutput{PageSize: input.PageSize}
+	if len(polls) > input.PageSize {
+		polls = polls[:input.PageSize]
+		last := polls[len(polls)-1]
+		output.HasMore = true
+		output.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.PollID)
+	}
+	output.Polls = s.buildPollResponses(ctx, polls, input.UserID)
+	return output, nil
+}
+
+// buildPollResponses enriches polls with options, the caller's vote and creator names
+func (s *Service) buildPollResponses(ctx context.Context, polls []*domain.Poll, userID uuid.UUID) []*domain.PollResponse {
 	// Build responses
 	responses := make([]*domain.PollResponse, len(polls))
 	for i, poll := range polls {
@@ -273,11 +391,11 @@ func (s *Service) GetPolls(ctx context.Context, input *GetPollsInput) (*GetPolls
 		}
 
 		// Get user vote info
-		pollWithVote, err := s.pollRepo.GetPollByIDWithUserVote(ctx, poll.PollID, input.UserID)
+		pollWithVote, err := s.pollRepo.GetPollByIDWithUserVote(ctx, poll.PollID, userID)
 		if err != nil {
 			logger.Warn("Failed to get user vote info",
 				zap.String("poll_id", poll.PollID.String()),
-				zap.String("user_id", input.UserID.String()),
+				zap.String("user_id", userID.String()),
 				zap.Error(err))
 		} else {
 			poll.UserVoted = pollWithVote.UserVoted
@@ -297,13 +415,7 @@ func (s *Service) GetPolls(ctx context.Context, input *GetPollsInput) (*GetPolls
 		responses[i] = response
 	}
 
-	return &GetPollsOutput{
-		Polls:    responses,
-		Total:    total,
-		Page:     input.Page,
-		PageSize: input.PageSize,
-		HasMore:  (input.Page * input.PageSize) < total,
-	}, nil
+	return responses
 }
 
 // VoteInput contains data for casting a vote
@@ -318,8 +430,16 @@ type VoteOutput struct {
 	Poll *domain.PollResponse
 }
 
-// Vote casts a vote in a poll
+// Vote casts a vote in a poll. The check whether the user already voted and
+// the insert run under a per-user vote lock, so a double submit cannot cast
+// two first votes.
 func (s *Service) Vote(ctx context.Context, input *VoteInput) (*VoteOutput, error) {
+	unlock, err := s.lockVote(ctx, input.PollID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// Get poll with user vote info
 	poll, err := s.pollRepo.GetPollByIDWithUserVote(ctx, input.PollID, input.UserID)
 	if err != nil {
@@ -372,17 +492,11 @@ func (s *Service) Vote(ctx context.Context, input *VoteInput) (*VoteOutput, erro
 		}
 	} else {
 		// Cast new vote
-		for _, optionID := range input.OptionIDs {
-			vote := &domain.PollVote{
-				VoteID:   uuid.New(),
-				PollID:   input.PollID,
-				OptionID: optionID,
-				UserID:   input.UserID,
-				VotedAt:  time.Now(),
-			}
-			if err := s.pollRepo.CastVote(ctx, vote); err != nil {
-				return nil, fmt.Errorf("failed to cast vote: %w", err)
+		if err := s.pollRepo.CastVotes(ctx, input.PollID, input.UserID, input.OptionIDs); err != nil {
+			if errors.Is(err, domain.ErrAlreadyVoted) {
+				return nil, err
 			}
+			return nil, fmt.Errorf("failed to cast vote: %w", err)
 		}
 	}
 
@@ -410,7 +524,9 @@ func (s *Service) Vote(ctx context.Context, input *VoteInput) (*VoteOutput, erro
 	}
 
 	// Publish poll_voted event (non-blocking)
-	go s.publishPollVoted(context.Background(), updatedPoll.ConversationID, response)
+	s.publishAsync(ctx, func(ctx context.Context) {
+		s.publishPollVoted(ctx, updatedPoll.ConversationID, response)
+	})
 
 	return &VoteOutput{Poll: response}, nil
 }
@@ -469,7 +585,9 @@ func (s *Service) ClosePoll(ctx context.Context, input *ClosePollInput) (*CloseP
 	}
 
 	// Publish poll_closed event (non-blocking)
-	go s.publishPollClosed(context.Background(), poll.ConversationID, response)
+	s.publishAsync(ctx, func(ctx context.Context) {
+		s.publishPollClosed(ctx, poll.ConversationID, response)
+	})
 
 	return &ClosePollOutput{Poll: response}, nil
 }
@@ -521,6 +639,9 @@ func (s *Service) GetActivePolls(ctx context.Context, input *GetActivePollsInput
 	}
 
 	offset := (input.Page - 1) * input.PageSize
+	if err := pagination.ValidateOffset(offset); err != nil {
+		return nil, err
+	}
 
 	// Get active polls
 	polls, total, err := s.pollRepo.GetActivePolls(ctx, input.ConversationID, input.PageSize, offset)
@@ -528,42 +649,7 @@ func (s *Service) GetActivePolls(ctx context.Context, input *GetActivePollsInput
 		return nil, fmt.Errorf("failed to get active polls: %w", err)
 	}
 
-	// Build responses
-	responses := make([]*domain.PollResponse, len(polls))
-	for i, poll := range polls {
-		// Get options
-		options, err := s.pollRepo.GetPollOptions(ctx, poll.PollID)
-		if err != nil {
-			logger.Warn("Failed to get poll options",
-				zap.String("poll_id", poll.PollID.String()),
-				zap.Error(err))
-			options = []*domain.PollOption{}
-		}
-
-		// Get user vote info
-		pollWithVote, err := s.pollRepo.GetPollByIDWithUserVote(ctx, poll.PollID, input.UserID)
-		if err != nil {
-			logger.Warn("Failed to get user vote info",
-				zap.String("poll_id", poll.PollID.String()),
-				zap.String("user_id", input.UserID.String()),
-				zap.Error(err))
-		} else {
-			poll.UserVoted = pollWithVote.UserVoted
-			poll.UserVoteOptions = pollWithVote.UserVoteOptions
-		}
-
-		// Get creator details
-		creator, err := s.userRepo.GetByID(ctx, poll.CreatorID)
-		response := poll.ToResponse()
-		response.Options = options
-		if err == nil && creator != nil {
-			response.CreatorName = creator.DisplayName
-			if response.CreatorName == "" {
-				response.CreatorName = creator.Username
-			}
-		}
-		responses[i] = response
-	}
+	responses := s.buildPollResponses(ctx, polls, input.UserID)
 
 	return &GetPollsOutput{
 		Polls:    responses,
@@ -574,6 +660,65 @@ func (s *Service) GetActivePolls(ctx context.Context, input *GetActivePollsInput
 	}, nil
 }
 
+// GetActivePollSummary returns the number of active polls in a conversation
+// and a summary of the most recent one (nil when there is none)
+func (s *Service) GetActivePollSummary(ctx context.Context, conversationID uuid.UUID) (int, *domain.ActivePollSummary, error) {
+	summaries, err := s.GetActivePollSummaries(ctx, []uuid.UUID{conversationID})
+	if err != nil {
+		return 0, nil, err
+	}
+	if summary, ok := summaries[conversationID]; ok {
+		return summary.Count, summary.Latest, nil
+	}
+	return 0, nil, nil
+}
+
+// GetActivePollSummaries returns the active polls of several conversations
+// at once. Conversations without active polls are left out.
+func (s *Service) GetActivePollSummaries(ctx context.Context, conversationIDs []uuid.UUID) (map[uuid.UUID]*domain.ActivePolls, error) {
+	summaries, err := s.pollRepo.GetActivePollSummaries(ctx, conversationIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active polls: %w", err)
+	}
+	return summaries, nil
+}
+
+// publishConversationPollEvent publishes a poll event on the conversation's chat channel
+// so that clients can update the active-poll indicator without subscribing to poll channels.
+// The payload follows the chat WebSocket message format.
+func (s *Service) publishConversationPollEvent(ctx context.Context, eventType string, conversationID uuid.UUID, poll *domain.PollResponse) {
+	metadata := map[string]interface{}{
+		"poll": poll,
+	}
+	if count, _, err := s.GetActivePollSummary(ctx, conversationID); err == nil {
+		metadata["active_poll_count"] = count
+	}
+
+	event := map[string]interface{}{
+		"type":            eventType,
+		"conversation_id": conversationID,
+		"metadata":        metadata,
+		"timestamp":       time.Now(),
+	}
+
+	messageJSON, err := json.Marshal(event)
+	if err != nil {
+		logger.Warn("Failed to marshal conversation poll event",
+			zap.String("type", eventType),
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+		return
+	}
+
+	channel := fmt.Sprintf("chat:%s", conversationID)
+	if err := s.publisher.Publish(ctx, channel, messageJSON); err != nil {
+		logger.Warn("Failed to publish conversation poll event",
+			zap.String("type", eventType),
+			zap.String("conversation_id", conversationID.String()),
+			zap.Error(err))
+	}
+}
+
 // publishPollCreated publishes a poll_created event to Redis
 func (s *Service) publishPollCreated(ctx context.Context, conversationID uuid.UUID, poll *domain.PollResponse) {
 	channel := fmt.Sprintf("poll:%s", conversationID)
@@ -595,6 +740,9 @@ func (s *Service) publishPollCreated(ctx context.Context, conversationID uuid.UU
 			zap.String("conversation_id", conversationID.String()),
 			zap.Error(err))
 	}
+
+	// Also notify the conversation channel so the active-poll indicator updates live
+	s.publishConversationPollEvent(ctx, "poll_created", conversationID, poll)
 }
 
 // publishPollVoted publishes a poll_voted event to Redis
@@ -641,4 +789,7 @@ func (s *Service) publishPollClosed(ctx context.Context, conversationID uuid.UUI
 			zap.String("conversation_id", conversationID.String()),
 			zap.Error(err))
 	}
+
+	// Also notify the conversation channel so the active-poll indicator updates live
+	s.publishConversationPollEvent(ctx, "poll_closed", conversationID, poll)
 }
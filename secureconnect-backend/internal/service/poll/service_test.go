@@ -0,0 +1,465 @@
+package poll
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/pagination"
+	"secureconnect-backend/pkg/workerpool"
+)
+
+// fakePollRepository is an in-memory PollRepository covering the calls made by CreatePoll
+type fakePollRepository struct {
+	mu    sync.Mutex
+	polls map[uuid.UUID]*domain.Poll
+}
+
+func newFakePollRepository() *fakePollRepository {
+	return &fakePollRepository{polls: make(map[uuid.UUID]*domain.Poll)}
+}
+
+func (r *fakePollRepository) CreatePoll(ctx context.Context, poll *domain.Poll, options []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	poll.CreatedAt = time.Now()
+	r.polls[poll.PollID] = poll
+	return nil
+}
+
+func (r *fakePollRepository) GetActivePolls(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*domain.Poll, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []*domain.Poll
+	for _, p := range r.polls {
+		if p.ConversationID == conversationID && !p.IsClosed && !p.IsExpired() {
+			active = append(active, p)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt.After(active[j].CreatedAt) })
+	total := len(active)
+	if offset >= total {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return active[offset:end], total, nil
+}
+
+func (r *fakePollRepository) GetActivePollSummaries(ctx context.Context, conversationIDs []uuid.UUID) (map[uuid.UUID]*domain.ActivePolls, error) {
+	summaries := make(map[uuid.UUID]*domain.ActivePolls)
+	for _, conversationID := range conversationIDs {
+		active, total, _ := r.GetActivePolls(ctx, conversationID, 1, 0)
+		if total == 0 {
+			continue
+		}
+		latest := active[0]
+		summaries[conversationID] = &domain.ActivePolls{Count: total, Latest: &domain.ActivePollSummary{
+			PollID:    latest.PollID,
+			Question:  latest.Question,
+			PollType:  latest.PollType,
+			ExpiresAt: latest.ExpiresAt,
+			CreatedAt: latest.CreatedAt,
+		}}
+	}
+	return summaries, nil
+}
+
+func (r *fakePollRepository) GetPollByID(ctx context.Context, pollID uuid.UUID) (*domain.Poll, error) {
+	return nil, domain.ErrPollNotFound
+}
+
+func (r *fakePollRepository) GetPollByIDWithVotes(ctx context.Context, pollID uuid.UUID) (*domain.Poll, error) {
+	return nil, domain.ErrPollNotFound
+}
+
+func (r *fakePollRepository) GetPollByIDWithUserVote(ctx context.Context, pollID, userID uuid.UUID) (*domain.Poll, error) {
+	return nil, domain.ErrPollNotFound
+}
+
+// sortedPolls returns the conversation's polls newest first, ties broken by poll ID
+func (r *fakePollRepository) sortedPolls(conversationID uuid.UUID) []*domain.Poll {
+	var polls []*domain.Poll
+	for _, p := range r.polls {
+		if p.ConversationID == conversationID {
+			polls = append(polls, p)
+		}
+	}
+	sort.Slice(polls, func(i, j int) bool {
+		if !polls[i].CreatedAt.Equal(polls[j].CreatedAt) {
+			return polls[i].CreatedAt.After(polls[j].CreatedAt)
+		}
+		return polls[i].PollID.String() > polls[j].PollID.String()
+	})
+	return polls
+}
+
+func (r *fakePollRepository) GetPollsByConversation(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*domain.Poll, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	polls := r.sortedPolls(conversationID)
+	total := len(polls)
+	if offset >= total {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return polls[offset:end], total, nil
+}
+
+func (r *fakePollRepository) GetPollsByConversationAfter(ctx context.Context, conversationID uuid.UUID, cursor *pagination.Cursor, limit int) ([]*domain.Poll, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var page []*domain.Poll
+	for _, p := range r.sortedPolls(conversationID) {
+		if cursor != nil && !p.CreatedAt.Before(cursor.CreatedAt) &&
+			!(p.CreatedAt.Equal(cursor.CreatedAt) && p.PollID.String() < cursor.ID.String()) {
+			continue
+		}
+		page = append(page, p)
+		if len(page) == limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+func (r *fakePollRepository) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]*domain.PollOption, error) {
+	return []*domain.PollOption{}, nil
+}
+
+func (r *fakePollRepository) GetPollOptionsWithVotes(ctx context.Context, pollID uuid.UUID) ([]*domain.PollOption, error) {
+	return []*domain.PollOption{}, nil
+}
+
+func (r *fakePollRepository) CastVotes(ctx context.Context, pollID, userID uuid.UUID, optionIDs []uuid.UUID) error {
+	return nil
+}
+
+func (r *fakePollRepository) ChangeVote(ctx context.Context, pollID, userID uuid.UUID, newOptionIDs []uuid.UUID) error {
+	return nil
+}
+
+func (r *fakePollRepository) GetUserVotes(ctx context.Context, pollID, userID uuid.UUID) ([]*domain.PollVote, error) {
+	return nil, nil
+}
+
+func (r *fakePollRepository) ClosePoll(ctx context.Context, pollID uuid.UUID) error { return nil }
+
+func (r *fakePollRepository) DeletePoll(ctx context.Context, pollID uuid.UUID) error { return nil }
+
+func (r *fakePollRepository) IsPollCreator(ctx context.Context, pollID, userID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (r *fakePollRepository) GetPollsByCreator(ctx context.Context, creatorID uuid.UUID, limit, offset int) ([]*domain.Poll, int, error) {
+	return nil, 0, nil
+}
+
+type fakeConversationRepository struct {
+	participants []uuid.UUID
+}
+
+func (r *fakeConversationRepository) GetParticipants(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	return r.participants, nil
+}
+
+type fakeUserRepository struct{}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	return &domain.User{UserID: userID, Username: "creator"}, nil
+}
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	channels []string
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.channels = append(p.channels, channel)
+	return nil
+}
+
+func (p *fakePublisher) published(channel string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCreatePoll_IncrementsActivePollCount(t *testing.T) {
+	logger.InitDefault("test")
+
+	creatorID := uuid.New()
+	conversationID := uuid.New()
+	publisher := &fakePublisher{}
+	service := NewService(
+		newFakePollRepository(),
+		&fakeConversationRepository{participants: []uuid.UUID{creatorID}},
+		&fakeUserRepository{},
+		publisher,
+	)
+	ctx := context.Background()
+
+	count, latest, err := service.GetActivePollSummary(ctx, conversationID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Nil(t, latest)
+
+	output, err := service.CreatePoll(ctx, &CreatePollInput{
+		ConversationID: conversationID,
+		CreatorID:      creatorID,
+		Question:       "Lunch?",
+		PollType:       domain.PollTypeSingle,
+		Options:        []string{"Pizza", "Sushi"},
+	})
+	require.NoError(t, err)
+
+	count, latest, err = service.GetActivePollSummary(ctx, conversationID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.NotNil(t, latest)
+	assert.Equal(t, output.Poll.PollID, latest.PollID)
+	assert.Equal(t, "Lunch?", latest.Question)
+
+	// poll_created is also emitted on the conversation channel
+	assert.Eventually(t, func() bool {
+		return publisher.published("chat:" + conversationID.String())
+	}, time.Second, 10*time.Millisecond)
+}
+
+// fakeMembership answers IsMember from a fixed set of members
+type fakeMembership map[uuid.UUID]bool
+
+func (f fakeMembership) IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error) {
+	return f[userID], nil
+}
+
+func TestCreatePoll_ChecksMembershipThroughChecker(t *testing.T) {
+	logger.InitDefault("test")
+	member, outsider := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		creator uuid.UUID
+		wantErr error
+	}{
+		{name: "member", creator: member},
+		{name: "outsider", creator: outsider, wantErr: domain.ErrNotParticipant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The participant list would admit everyone; only the checker decides
+			service := NewService(newFakePollRepository(), &fakeConversationRepository{participants: []uuid.UUID{member, outsider}}, &fakeUserRepository{}, &fakePublisher{})
+			service.SetMembershipChecker(fakeMembership{member: true})
+
+			_, err := service.CreatePoll(context.Background(), &CreatePollInput{
+				ConversationID: uuid.New(),
+				CreatorID:      tt.creator,
+				Question:       "Lunch?",
+				PollType:       domain.PollTypeSingle,
+				Options:        []string{"Pizza", "Sushi"},
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetPolls_RejectsDeepOffset(t *testing.T) {
+	logger.InitDefault("test")
+	pagination.SetMaxOffset(100)
+	defer pagination.SetMaxOffset(pagination.DefaultMaxOffset)
+
+	service := NewService(newFakePollRepository(), &fakeConversationRepository{}, &fakeUserRepository{}, &fakePublisher{})
+
+	_, err := service.GetPolls(context.Background(), &GetPollsInput{
+		ConversationID: uuid.New(),
+		Page:           7,
+		PageSize:       20,
+	})
+	assert.ErrorIs(t, err, pagination.ErrOffsetTooLarge)
+}
+
+func TestGetPolls_KeysetPagesAreStable(t *testing.T) {
+	logger.InitDefault("test")
+
+	repo := newFakePollRepository()
+	service := NewService(repo, &fakeConversationRepository{}, &fakeUserRepository{}, &fakePublisher{})
+	ctx := context.Background()
+	conversationID := uuid.New()
+
+	// Several polls share a timestamp so ordering depends on the poll ID tie-breaker
+	base := time.Now().Truncate(time.Second)
+	for i := 0; i < 7; i++ {
+		poll := &domain.Poll{
+			PollID:         uuid.New(),
+			ConversationID: conversationID,
+			Question:       "Q",
+			CreatedAt:      base.Add(-time.Duration(i/3) * time.Minute),
+		}
+		repo.polls[poll.PollID] = poll
+	}
+
+	first, err := service.GetPolls(ctx, &GetPollsInput{ConversationID: conversationID, PageSize: 3})
+	require.NoError(t, err)
+	require.True(t, first.HasMore)
+	require.NotEmpty(t, first.NextCursor)
+
+	var seen []uuid.UUID
+	for _, p := range first.Polls {
+		seen = append(seen, p.PollID)
+	}
+
+	cursor := first.NextCursor
+	for cursor != "" {
+		// A poll created after paging started must not shift later pages
+		newer := &domain.Poll{PollID: uuid.New(), ConversationID: conversationID, CreatedAt: time.Now().Add(time.Hour)}
+		repo.polls[newer.PollID] = newer
+
+		page, err := service.GetPolls(ctx, &GetPollsInput{ConversationID: conversationID, PageSize: 3, Cursor: cursor})
+		require.NoError(t, err)
+		for _, p := range page.Polls {
+			seen = append(seen, p.PollID)
+		}
+		cursor = page.NextCursor
+	}
+
+	var expected []uuid.UUID
+	for _, p := range repo.sortedPolls(conversationID) {
+		if p.CreatedAt.Before(base.Add(time.Second)) {
+			expected = append(expected, p.PollID)
+		}
+	}
+	assert.Equal(t, expected, seen, "every original poll is returned exactly once, in order")
+
+	_, err = service.GetPolls(ctx, &GetPollsInput{ConversationID: conversationID, Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+// hangingPublisher blocks every publish until its context ends and reports why
+type hangingPublisher struct {
+	done chan error
+}
+
+func (p *hangingPublisher) Publish(ctx context.Context, channel string, message interface{}) error {
+	<-ctx.Done()
+	p.done <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestCreatePoll_PublishIsBoundedByTimeout(t *testing.T) {
+	logger.InitDefault("test")
+
+	creatorID := uuid.New()
+	publisher := &hangingPublisher{done: make(chan error, 2)}
+	service := NewService(
+		newFakePollRepository(),
+		&fakeConversationRepository{participants: []uuid.UUID{creatorID}},
+		&fakeUserRepository{},
+		publisher,
+	)
+	service.publishTimeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := service.CreatePoll(ctx, &CreatePollInput{
+		ConversationID: uuid.New(),
+		CreatorID:      creatorID,
+		Question:       "Lunch?",
+		PollType:       domain.PollTypeSingle,
+		Options:        []string{"Pizza", "Sushi"},
+	})
+	require.NoError(t, err)
+	cancel()
+
+	select {
+	case err := <-publisher.done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "the publish outlives the request but not its timeout")
+	case <-time.After(2 * time.Second):
+		t.Fatal("publish goroutine did not give up after its timeout")
+	}
+}
+
+func TestCreatePoll_QueuedPublishesDrainOnShutdown(t *testing.T) {
+	logger.InitDefault("test")
+
+	creatorID := uuid.New()
+	publisher := &fakePublisher{}
+	service := NewService(
+		newFakePollRepository(),
+		&fakeConversationRepository{participants: []uuid.UUID{creatorID}},
+		&fakeUserRepository{},
+		publisher,
+	)
+	pool := workerpool.New("poll_publish_test", 1, 8)
+	service.SetPublishPool(pool)
+
+	conversationIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, conversationID := range conversationIDs {
+		_, err := service.CreatePoll(context.Background(), &CreatePollInput{
+			ConversationID: conversationID,
+			CreatorID:      creatorID,
+			Question:       "Lunch?",
+			PollType:       domain.PollTypeSingle,
+			Options:        []string{"Pizza", "Sushi"},
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	for _, conversationID := range conversationIDs {
+		assert.True(t, publisher.published("chat:"+conversationID.String()), "queued events go out before Shutdown returns")
+	}
+}
+
+func TestCreatePoll_FullPublishQueueDoesNotBlock(t *testing.T) {
+	logger.InitDefault("test")
+
+	creatorID := uuid.New()
+	publisher := &hangingPublisher{done: make(chan error, 4)}
+	service := NewService(
+		newFakePollRepository(),
+		&fakeConversationRepository{participants: []uuid.UUID{creatorID}},
+		&fakeUserRepository{},
+		publisher,
+	)
+	service.publishTimeout = 100 * time.Millisecond
+	pool := workerpool.New("poll_publish_full_test", 1, 1)
+	service.SetPublishPool(pool)
+
+	// One publish hangs on the worker and one waits in the queue; the rest are shed
+	for i := 0; i < 4; i++ {
+		_, err := service.CreatePoll(context.Background(), &CreatePollInput{
+			ConversationID: uuid.New(),
+			CreatorID:      creatorID,
+			Question:       "Lunch?",
+			PollType:       domain.PollTypeSingle,
+			Options:        []string{"Pizza", "Sushi"},
+		})
+		require.NoError(t, err, "the request succeeds even when its event is dropped")
+	}
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.LessOrEqual(t, len(publisher.done), 2, "at most a worker and a queue slot of publishes ran")
+}
@@ -0,0 +1,87 @@
+package poll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/backoff"
+	"secureconnect-backend/pkg/logger"
+)
+
+const (
+	// voteLockTTL bounds how long a crashed voter can hold the lock
+	voteLockTTL = 10 * time.Second
+	// voteLockWait is how long a vote waits for another one by the same user
+	voteLockWait = 3 * time.Second
+)
+
+// Locker provides a distributed lock
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error)
+	Unlock(ctx context.Context, key, token string) error
+}
+
+// errVoteLockHeld is retried until the lock is free or voteLockWait passes
+var errVoteLockHeld = errors.New("vote lock held")
+
+// SetVoteLocker serializes the votes of each user in each poll through locker.
+// Without it, concurrent first votes are still stopped by the database, but
+// the loser gets ALREADY_VOTED only after its vote was validated.
+func (s *Service) SetVoteLocker(locker Locker) {
+	s.voteLocker = locker
+}
+
+// lockVote takes the user's vote lock for the poll, waiting while another of
+// their votes holds it, and returns the function releasing it. If the lock
+// cannot be reached the vote goes ahead unlocked. A vote that keeps waiting
+// gets domain.ErrVoteInProgress.
+func (s *Service) lockVote(ctx context.Context, pollID, userID uuid.UUID) (func(), error) {
+	if s.voteLocker == nil {
+		return func() {}, nil
+	}
+
+	key := fmt.Sprintf("poll_vote:%s:%s", pollID, userID)
+	var token string
+	err := backoff.Retry(ctx, backoff.Config{
+		Base:       20 * time.Millisecond,
+		Max:        200 * time.Millisecond,
+		Jitter:     true,
+		MaxElapsed: voteLockWait,
+	}, func(ctx context.Context) error {
+		t, ok, err := s.voteLocker.TryLock(ctx, key, voteLockTTL)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		if !ok {
+			return errVoteLockHeld
+		}
+		token = t
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		return func() {
+			if err := s.voteLocker.Unlock(context.WithoutCancel(ctx), key, token); err != nil {
+				logger.Warn("Failed to release vote lock",
+					zap.String("poll_id", pollID.String()),
+					zap.Error(err))
+			}
+		}, nil
+	case errors.Is(err, backoff.ErrExhausted):
+		return nil, domain.ErrVoteInProgress
+	case ctx.Err() != nil:
+		return nil, err
+	}
+
+	logger.Warn("Vote lock unavailable, voting without it",
+		zap.String("poll_id", pollID.String()),
+		zap.Error(err))
+	return func() {}, nil
+}
@@ -0,0 +1,132 @@
+package poll
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// racyVoteRepository stores votes without the database's voter backstop and
+// widens the gap between reading whether a user voted and casting the vote
+type racyVoteRepository struct {
+	*fakePollRepository
+	poll   *domain.Poll
+	option uuid.UUID
+	votes  map[uuid.UUID]int
+}
+
+func (r *racyVoteRepository) GetPollByIDWithUserVote(ctx context.Context, pollID, userID uuid.UUID) (*domain.Poll, error) {
+	r.mu.Lock()
+	poll := *r.poll
+	poll.UserVoted = r.votes[userID] > 0
+	r.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	return &poll, nil
+}
+
+func (r *racyVoteRepository) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]*domain.PollOption, error) {
+	return []*domain.PollOption{{OptionID: r.option, PollID: pollID}}, nil
+}
+
+func (r *racyVoteRepository) CastVotes(ctx context.Context, pollID, userID uuid.UUID, optionIDs []uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.votes[userID] += len(optionIDs)
+	return nil
+}
+
+// keyedLocker is an in-process lock per key
+type keyedLocker struct {
+	mu   sync.Mutex
+	held map[string]string
+}
+
+func (l *keyedLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[key]; ok {
+		return "", false, nil
+	}
+	token := uuid.New().String()
+	l.held[key] = token
+	return token, true, nil
+}
+
+func (l *keyedLocker) Unlock(ctx context.Context, key, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] == token {
+		delete(l.held, key)
+	}
+	return nil
+}
+
+func TestVote_ConcurrentSubmitsCastOneVote(t *testing.T) {
+	logger.InitDefault("test")
+
+	voter := uuid.New()
+	repo := &racyVoteRepository{
+		fakePollRepository: newFakePollRepository(),
+		poll:               &domain.Poll{PollID: uuid.New(), ConversationID: uuid.New(), PollType: domain.PollTypeSingle},
+		option:             uuid.New(),
+		votes:              map[uuid.UUID]int{},
+	}
+	service := NewService(repo, &fakeConversationRepository{}, &fakeUserRepository{}, &fakePublisher{})
+	service.SetVoteLocker(&keyedLocker{held: map[string]string{}})
+
+	const submits = 10
+	errs := make(chan error, submits)
+	var wg sync.WaitGroup
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Vote(context.Background(), &VoteInput{PollID: repo.poll.PollID, UserID: voter, OptionIDs: []uuid.UUID{repo.option}})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, domain.ErrAlreadyVoted)
+	}
+	assert.Equal(t, 1, succeeded)
+	require.Equal(t, 1, repo.votes[voter], "a single-choice poll got more than one vote from the user")
+}
+
+func TestVote_WaitsForHeldLockUntilContextEnds(t *testing.T) {
+	logger.InitDefault("test")
+
+	repo := &racyVoteRepository{
+		fakePollRepository: newFakePollRepository(),
+		poll:               &domain.Poll{PollID: uuid.New(), PollType: domain.PollTypeSingle},
+		option:             uuid.New(),
+		votes:              map[uuid.UUID]int{},
+	}
+	voter := uuid.New()
+	locker := &keyedLocker{held: map[string]string{
+		"poll_vote:" + repo.poll.PollID.String() + ":" + voter.String(): "stuck",
+	}}
+	service := NewService(repo, &fakeConversationRepository{}, &fakeUserRepository{}, &fakePublisher{})
+	service.SetVoteLocker(locker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := service.Vote(ctx, &VoteInput{PollID: repo.poll.PollID, UserID: voter, OptionIDs: []uuid.UUID{repo.option}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, repo.votes[voter])
+}
@@ -24,6 +24,9 @@ type FileRepository interface {
 	GetUserStorageUsage(ctx context.Context, userID uuid.UUID) (int64, error)
 	CheckFileAccess(ctx context.Context, fileID uuid.UUID, userID uuid.UUID) (bool, error)
 	GetExpiredUploads(ctx context.Context, expiryDuration time.Duration) ([]*domain.File, error)
+	GetCompletedFiles(ctx context.Context, userID uuid.UUID) ([]*domain.File, error)
+	UpdateFileSize(ctx context.Context, fileID uuid.UUID, size int64) error
+	ListUsersWithFiles(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
 }
 
 // ObjectStorage interface
@@ -33,6 +36,8 @@ type ObjectStorage interface {
 	PresignedPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error)
 	PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error)
 	RemoveObject(ctx context.Context, bucketName, objectName string, opts minio.RemoveObjectOptions) error
+	StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+	PresignedPostPolicy(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error)
 }
 
 // MinioAdapter implements ObjectStorage
@@ -60,6 +65,14 @@ func (m *MinioAdapter) RemoveObject(ctx context.Context, bucketName, objectName
 	return m.Client.RemoveObject(ctx, bucketName, objectName, opts)
 }
 
+func (m *MinioAdapter) StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	return m.Client.StatObject(ctx, bucketName, objectName, opts)
+}
+
+func (m *MinioAdapter) PresignedPostPolicy(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error) {
+	return m.Client.PresignedPostPolicy(ctx, policy)
+}
+
 // Service handles file storage operations
 type Service struct {
 	storage    ObjectStorage
@@ -176,8 +189,114 @@ func (s *Service) GenerateUploadURL(ctx context.Context, userID uuid.UUID, input
 	}, nil
 }
 
-// CompleteUpload marks file upload as completed
+// GeneratePresignedPostOutput contains a presigned POST policy for browser uploads
+type GeneratePresignedPostOutput struct {
+	FileID     uuid.UUID         `json:"file_id"`
+	UploadURL  string            `json:"upload_url"`
+	FormFields map[string]string `json:"form_fields"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+// GeneratePresignedPost creates a presigned POST policy so a browser can upload
+// directly to MinIO. Unlike a presigned PUT, the policy makes MinIO itself reject
+// uploads with a different Content-Type or more than maxSize bytes. The file is
+// recorded as uploading with maxSize reserved against the quota; CompleteUpload
+// records the actual size.
+func (s *Service) GeneratePresignedPost(ctx context.Context, userID uuid.UUID, contentType string, maxSize int64) (*GeneratePresignedPostOutput, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("max size must be positive")
+	}
+
+	used, quota, err := s.GetUserQuota(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check storage quota: %w", err)
+	}
+	if used+maxSize > quota {
+		return nil, fmt.Errorf("storage quota exceeded: %d bytes used, %d bytes quota, %d bytes requested",
+			used, quota, maxSize)
+	}
+
+	fileID := uuid.New()
+	objectKey := fmt.Sprintf("users/%s/%s", userID, fileID)
+	expiresAt := time.Now().Add(constants.PresignedURLExpiry)
+
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(s.bucketName); err != nil {
+		return nil, err
+	}
+	if err := policy.SetKey(objectKey); err != nil {
+		return nil, err
+	}
+	if err := policy.SetExpires(expiresAt.UTC()); err != nil {
+		return nil, err
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return nil, err
+	}
+	if err := policy.SetContentLengthRange(1, maxSize); err != nil {
+		return nil, err
+	}
+
+	var postURL *url.URL
+	var formFields map[string]string
+	err = s.resilience.Execute(ctx, "presigned_post_policy", func() error {
+		var err error
+		postURL, formFields, err = s.storage.PresignedPostPolicy(ctx, policy)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate presigned POST policy: %w", err)
+	}
+
+	now := time.Now()
+	file := &domain.File{
+		FileID:           fileID,
+		UserID:           userID,
+		FileName:         fileID.String(),
+		FileSize:         maxSize,
+		ContentType:      contentType,
+		MinIOObjectKey:   objectKey,
+		Status:           "uploading",
+		StorageQuotaUsed: maxSize,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		return nil, fmt.Errorf("failed to save file metadata: %w", err)
+	}
+
+	return &GeneratePresignedPostOutput{
+		FileID:     fileID,
+		UploadURL:  postURL.String(),
+		FormFields: formFields,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// CompleteUpload marks file upload as completed. The recorded size is replaced
+// with the size of the stored object, so presigned POST uploads stop counting
+// their whole reservation against the quota.
 func (s *Service) CompleteUpload(ctx context.Context, fileID uuid.UUID) error {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("file not found: %w", err)
+	}
+
+	var info minio.ObjectInfo
+	err = s.resilience.Execute(ctx, "stat_object", func() error {
+		var err error
+		info, err = s.storage.StatObject(ctx, s.bucketName, file.MinIOObjectKey, minio.StatObjectOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stat uploaded object: %w", err)
+	}
+
+	if info.Size != file.FileSize {
+		if err := s.fileRepo.UpdateFileSize(ctx, fileID, info.Size); err != nil {
+			return err
+		}
+	}
 	return s.fileRepo.UpdateStatus(ctx, fileID, "completed")
 }
 
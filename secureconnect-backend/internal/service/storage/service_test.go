@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"net/url"
 	"testing"
 	"time"
@@ -10,8 +11,10 @@ import (
 	"github.com/minio/minio-go/v7"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
 )
 
 // Mocks
@@ -47,6 +50,35 @@ func (m *MockFileRepository) CheckFileAccess(ctx context.Context, fileID uuid.UU
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockFileRepository) GetExpiredUploads(ctx context.Context, expiryDuration time.Duration) ([]*domain.File, error) {
+	args := m.Called(ctx, expiryDuration)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.File), args.Error(1)
+}
+
+func (m *MockFileRepository) GetCompletedFiles(ctx context.Context, userID uuid.UUID) ([]*domain.File, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.File), args.Error(1)
+}
+
+func (m *MockFileRepository) UpdateFileSize(ctx context.Context, fileID uuid.UUID, size int64) error {
+	args := m.Called(ctx, fileID, size)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ListUsersWithFiles(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
 type MockObjectStorage struct {
 	mock.Mock
 }
@@ -82,7 +114,21 @@ func (m *MockObjectStorage) RemoveObject(ctx context.Context, bucketName, object
 	return args.Error(0)
 }
 
+func (m *MockObjectStorage) StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	args := m.Called(ctx, bucketName, objectName, opts)
+	return args.Get(0).(minio.ObjectInfo), args.Error(1)
+}
+
+func (m *MockObjectStorage) PresignedPostPolicy(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error) {
+	args := m.Called(ctx, policy)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*url.URL), args.Get(1).(map[string]string), args.Error(2)
+}
+
 func TestNewService_BucketExists(t *testing.T) {
+	logger.InitDefault("test")
 	mockStorage := new(MockObjectStorage)
 	mockRepo := new(MockFileRepository)
 
@@ -99,6 +145,7 @@ func TestNewService_BucketExists(t *testing.T) {
 }
 
 func TestGenerateUploadURL(t *testing.T) {
+	logger.InitDefault("test")
 	mockStorage := new(MockObjectStorage)
 	mockRepo := new(MockFileRepository)
 
@@ -137,3 +184,119 @@ func TestGenerateUploadURL(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
+
+// postPolicyConditions decodes the conditions of a POST policy document
+func postPolicyConditions(t *testing.T, policy *minio.PostPolicy) [][]interface{} {
+	var doc struct {
+		Conditions [][]interface{} `json:"conditions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(policy.String()), &doc))
+	return doc.Conditions
+}
+
+func TestGeneratePresignedPost_PolicyEnforcesTypeAndSize(t *testing.T) {
+	logger.InitDefault("test")
+	mockStorage := new(MockObjectStorage)
+	mockRepo := new(MockFileRepository)
+	mockStorage.On("BucketExists", mock.Anything, "test-bucket").Return(true, nil)
+	service, err := NewService(mockStorage, "test-bucket", mockRepo)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	postURL, _ := url.Parse("http://minio/test-bucket")
+	fields := map[string]string{"policy": "encoded", "x-amz-signature": "sig"}
+
+	var captured *minio.PostPolicy
+	mockStorage.On("PresignedPostPolicy", ctx, mock.AnythingOfType("*minio.PostPolicy")).
+		Run(func(args mock.Arguments) { captured = args.Get(1).(*minio.PostPolicy) }).
+		Return(postURL, fields, nil)
+	mockRepo.On("GetUserStorageUsage", ctx, userID).Return(int64(0), nil)
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(f *domain.File) bool {
+		return f.Status == "uploading" && f.StorageQuotaUsed == 2048 && f.ContentType == "image/png"
+	})).Return(nil)
+
+	output, err := service.GeneratePresignedPost(ctx, userID, "image/png", 2048)
+	require.NoError(t, err)
+	assert.Equal(t, postURL.String(), output.UploadURL)
+	assert.Equal(t, fields, output.FormFields)
+	require.NotNil(t, captured)
+
+	conditions := postPolicyConditions(t, captured)
+	assert.Contains(t, conditions, []interface{}{"content-length-range", float64(1), float64(2048)})
+	assert.Contains(t, conditions, []interface{}{"eq", "$Content-Type", "image/png"})
+	assert.Contains(t, conditions, []interface{}{"eq", "$key", "users/" + userID.String() + "/" + output.FileID.String()})
+	assert.Contains(t, conditions, []interface{}{"eq", "$bucket", "test-bucket"})
+
+	mockStorage.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGeneratePresignedPost_QuotaExceeded(t *testing.T) {
+	logger.InitDefault("test")
+	mockStorage := new(MockObjectStorage)
+	mockRepo := new(MockFileRepository)
+	mockStorage.On("BucketExists", mock.Anything, "test-bucket").Return(true, nil)
+	service, err := NewService(mockStorage, "test-bucket", mockRepo)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	mockRepo.On("GetUserStorageUsage", ctx, userID).Return(int64(10*1024*1024*1024), nil)
+
+	_, err = service.GeneratePresignedPost(ctx, userID, "image/png", 1024)
+	assert.ErrorContains(t, err, "quota exceeded")
+	mockStorage.AssertNotCalled(t, "PresignedPostPolicy", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCompleteUpload_RecordsStoredSize(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name     string
+		recorded int64
+		stored   int64 // -1 when nothing was uploaded
+		wantErr  bool
+	}{
+		{name: "presigned POST smaller than its limit", recorded: 2048, stored: 300},
+		{name: "size as recorded", recorded: 300, stored: 300},
+		{name: "nothing uploaded", recorded: 2048, stored: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockObjectStorage)
+			mockRepo := new(MockFileRepository)
+			mockStorage.On("BucketExists", mock.Anything, "test-bucket").Return(true, nil)
+			service, err := NewService(mockStorage, "test-bucket", mockRepo)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			file := &domain.File{FileID: uuid.New(), UserID: uuid.New(), FileSize: tt.recorded, MinIOObjectKey: "users/upload", Status: "uploading"}
+			mockRepo.On("GetByID", ctx, file.FileID).Return(file, nil)
+			if tt.stored < 0 {
+				mockStorage.On("StatObject", ctx, "test-bucket", file.MinIOObjectKey, mock.Anything).
+					Return(minio.ObjectInfo{}, minio.ErrorResponse{Code: "NoSuchKey", StatusCode: 404})
+			} else {
+				mockStorage.On("StatObject", ctx, "test-bucket", file.MinIOObjectKey, mock.Anything).Return(minio.ObjectInfo{Size: tt.stored}, nil)
+				mockRepo.On("UpdateStatus", ctx, file.FileID, "completed").Return(nil).Once()
+			}
+			if tt.stored >= 0 && tt.stored != tt.recorded {
+				mockRepo.On("UpdateFileSize", ctx, file.FileID, tt.stored).Return(nil).Once()
+			}
+
+			err = service.CompleteUpload(ctx, file.FileID)
+			if tt.wantErr {
+				assert.Error(t, err)
+				mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+			if tt.stored == tt.recorded {
+				mockRepo.AssertNotCalled(t, "UpdateFileSize", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
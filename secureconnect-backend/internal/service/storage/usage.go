@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+const (
+	// usageReconcileLockKey guards the reconciliation so only one storage-service instance runs it
+	usageReconcileLockKey = "storage:usage-reconcile"
+
+	// usageReconcilePageSize is how many users are read from CockroachDB at a time
+	usageReconcilePageSize = 200
+)
+
+// UsageRecalculation reports what RecalculateUsage found and corrected
+type UsageRecalculation struct {
+	UserID         uuid.UUID `json:"user_id"`
+	PreviousBytes  int64     `json:"previous_bytes"`
+	UsedBytes      int64     `json:"used_bytes"`
+	FilesResized   int       `json:"files_resized"`   // recorded size differed from the object
+	FilesMissing   int       `json:"files_missing"`   // object no longer exists in MinIO
+	DriftCorrected bool      `json:"drift_corrected"` // PreviousBytes != UsedBytes
+}
+
+// RecalculateUsage repairs a user's quota usage. Usage is the sum of the
+// recorded sizes of completed files, so it drifts when an object is removed
+// from MinIO out-of-band or a recorded size is wrong. Each completed file is
+// checked against its object: sizes are corrected and files whose object is
+// gone are marked deleted.
+func (s *Service) RecalculateUsage(ctx context.Context, userID uuid.UUID) (*UsageRecalculation, error) {
+	previous, err := s.fileRepo.GetUserStorageUsage(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	files, err := s.fileRepo.GetCompletedFiles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UsageRecalculation{UserID: userID, PreviousBytes: previous}
+	for _, file := range files {
+		var info minio.ObjectInfo
+		missing := false
+		err := s.resilience.Execute(ctx, "stat_object", func() error {
+			var err error
+			info, err = s.storage.StatObject(ctx, s.bucketName, file.MinIOObjectKey, minio.StatObjectOptions{})
+			if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				missing = true
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat object for file %s: %w", file.FileID, err)
+		}
+
+		switch {
+		case missing:
+			if err := s.fileRepo.UpdateStatus(ctx, file.FileID, "deleted"); err != nil {
+				return nil, err
+			}
+			result.FilesMissing++
+		case info.Size != file.FileSize:
+			if err := s.fileRepo.UpdateFileSize(ctx, file.FileID, info.Size); err != nil {
+				return nil, err
+			}
+			result.FilesResized++
+			result.UsedBytes += info.Size
+		default:
+			result.UsedBytes += file.FileSize
+		}
+	}
+
+	if result.UsedBytes != previous {
+		result.DriftCorrected = true
+		drift := result.UsedBytes - previous
+		if drift < 0 {
+			drift = -drift
+		}
+		metrics.StorageUsageDriftCorrectedTotal.Inc()
+		metrics.StorageUsageDriftBytesTotal.Add(float64(drift))
+		logger.Info("Corrected storage usage drift",
+			zap.String("user_id", userID.String()),
+			zap.Int64("previous_bytes", previous),
+			zap.Int64("used_bytes", result.UsedBytes),
+			zap.Int("files_resized", result.FilesResized),
+			zap.Int("files_missing", result.FilesMissing))
+	}
+
+	return result, nil
+}
+
+// Locker provides a distributed lock
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error)
+	Unlock(ctx context.Context, key, token string) error
+}
+
+// UsageReconcilerConfig controls the periodic usage reconciliation
+type UsageReconcilerConfig struct {
+	// Interval between runs
+	Interval time.Duration
+	// LockTTL bounds how long a crashed run can hold the lock
+	LockTTL time.Duration
+}
+
+// UsageReconcileResult summarizes one reconciliation run
+type UsageReconcileResult struct {
+	Users     int  // users checked
+	Corrected int  // users whose usage was corrected
+	Skipped   bool // another instance holds the lock
+}
+
+// UsageReconciler periodically runs RecalculateUsage for every user with files
+type UsageReconciler struct {
+	service *Service
+	locker  Locker
+	config  UsageReconcilerConfig
+}
+
+// NewUsageReconciler creates a new usage reconciler
+func NewUsageReconciler(service *Service, locker Locker, config UsageReconcilerConfig) *UsageReconciler {
+	if config.Interval <= 0 {
+		config.Interval = 24 * time.Hour
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = time.Hour
+	}
+	return &UsageReconciler{
+		service: service,
+		locker:  locker,
+		config:  config,
+	}
+}
+
+// Start runs the reconciliation every interval until ctx is cancelled
+func (r *UsageReconciler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Run(ctx); err != nil {
+					logger.Error("Storage usage reconciliation failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Run reconciles every user once if the distributed lock can be taken. A
+// failure for one user is logged and does not stop the others.
+func (r *UsageReconciler) Run(ctx context.Context) (*UsageReconcileResult, error) {
+	token, ok, err := r.locker.TryLock(ctx, usageReconcileLockKey, r.config.LockTTL)
+	if err != nil {
+		metrics.StorageUsageReconcileRunsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	if !ok {
+		metrics.StorageUsageReconcileRunsTotal.WithLabelValues("skipped").Inc()
+		return &UsageReconcileResult{Skipped: true}, nil
+	}
+	defer func() {
+		if err := r.locker.Unlock(context.Background(), usageReconcileLockKey, token); err != nil {
+			logger.Warn("Failed to release usage reconciliation lock", zap.Error(err))
+		}
+	}()
+
+	result := &UsageReconcileResult{}
+	after := uuid.Nil
+	for {
+		page, err := r.service.fileRepo.ListUsersWithFiles(ctx, after, usageReconcilePageSize)
+		if err != nil {
+			metrics.StorageUsageReconcileRunsTotal.WithLabelValues("error").Inc()
+			return result, err
+		}
+
+		for _, userID := range page {
+			recalculated, err := r.service.RecalculateUsage(ctx, userID)
+			if err != nil {
+				logger.Warn("Failed to recalculate storage usage",
+					zap.String("user_id", userID.String()),
+					zap.Error(err))
+				continue
+			}
+			result.Users++
+			if recalculated.DriftCorrected {
+				result.Corrected++
+			}
+		}
+
+		if len(page) < usageReconcilePageSize {
+			break
+		}
+		after = page[len(page)-1]
+	}
+
+	metrics.StorageUsageReconcileRunsTotal.WithLabelValues("success").Inc()
+	logger.Info("Storage usage reconciliation finished",
+		zap.Int("users", result.Users),
+		zap.Int("corrected", result.Corrected))
+	return result, nil
+}
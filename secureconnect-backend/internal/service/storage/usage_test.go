@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+)
+
+// storedFile is a completed file as recorded and as found in object storage
+type storedFile struct {
+	recorded int64
+	actual   int64 // -1 when the object is gone
+}
+
+func TestRecalculateUsage(t *testing.T) {
+	logger.InitDefault("test")
+
+	tests := []struct {
+		name          string
+		recordedUsage int64
+		files         []storedFile
+		want          UsageRecalculation
+	}{
+		{
+			name:          "no drift",
+			recordedUsage: 1000,
+			files:         []storedFile{{recorded: 1000, actual: 1000}},
+			want:          UsageRecalculation{PreviousBytes: 1000, UsedBytes: 1000},
+		},
+		{
+			name:          "a missing object and a larger object correct the usage",
+			recordedUsage: 5500,
+			files: []storedFile{
+				{recorded: 1000, actual: 1000},
+				{recorded: 500, actual: 2500},
+				{recorded: 4000, actual: -1},
+			},
+			want: UsageRecalculation{PreviousBytes: 5500, UsedBytes: 3500, FilesResized: 1, FilesMissing: 1, DriftCorrected: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockObjectStorage)
+			mockRepo := new(MockFileRepository)
+			mockStorage.On("BucketExists", mock.Anything, "test-bucket").Return(true, nil)
+			service, err := NewService(mockStorage, "test-bucket", mockRepo)
+			require.NoError(t, err)
+			ctx := context.Background()
+			userID := uuid.New()
+
+			var files []*domain.File
+			for _, f := range tt.files {
+				file := &domain.File{FileID: uuid.New(), UserID: userID, FileSize: f.recorded, MinIOObjectKey: uuid.NewString()}
+				files = append(files, file)
+				switch {
+				case f.actual < 0:
+					mockStorage.On("StatObject", ctx, "test-bucket", file.MinIOObjectKey, mock.Anything).
+						Return(minio.ObjectInfo{}, minio.ErrorResponse{Code: "NoSuchKey", StatusCode: 404})
+					mockRepo.On("UpdateStatus", ctx, file.FileID, "deleted").Return(nil).Once()
+				case f.actual != f.recorded:
+					mockStorage.On("StatObject", ctx, "test-bucket", file.MinIOObjectKey, mock.Anything).Return(minio.ObjectInfo{Size: f.actual}, nil)
+					mockRepo.On("UpdateFileSize", ctx, file.FileID, f.actual).Return(nil).Once()
+				default:
+					mockStorage.On("StatObject", ctx, "test-bucket", file.MinIOObjectKey, mock.Anything).Return(minio.ObjectInfo{Size: f.actual}, nil)
+				}
+			}
+			mockRepo.On("GetUserStorageUsage", ctx, userID).Return(tt.recordedUsage, nil)
+			mockRepo.On("GetCompletedFiles", ctx, userID).Return(files, nil)
+
+			result, err := service.RecalculateUsage(ctx, userID)
+			require.NoError(t, err)
+
+			tt.want.UserID = userID
+			assert.Equal(t, &tt.want, result)
+			mockRepo.AssertExpectations(t)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
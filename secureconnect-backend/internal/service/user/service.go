@@ -8,38 +8,89 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 
 	"secureconnect-backend/internal/domain"
 	"secureconnect-backend/internal/repository/cockroach"
+	"secureconnect-backend/pkg/audit"
 	"secureconnect-backend/pkg/constants"
 	"secureconnect-backend/pkg/email"
-	"secureconnect-backend/pkg/env"
+	"secureconnect-backend/pkg/password"
+	"secureconnect-backend/pkg/sanitize"
 )
 
 // Service handles user business logic
 type Service struct {
-	userRepo              *cockroach.UserRepository
-	blockedUserRepo       *cockroach.BlockedUserRepository
+	userRepo              UserRepository
+	hasher                password.Hasher
+	blockedUserRepo       BlockedUserRepository
 	emailVerificationRepo *cockroach.EmailVerificationRepository
 	emailService          *email.Service
+	passwordValidator     PasswordValidator
+	auditLogger           *audit.AuditLogger // nil until SetAuditLogger
+	stripGmailAliases     bool
+}
+
+// UserRepository interface
+type UserRepository interface {
+	GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error)
+	Update(ctx context.Context, user *domain.User) error
+	UpdateStatus(ctx context.Context, userID uuid.UUID, status string) error
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	EmailExists(ctx context.Context, email string) (bool, error)
+	GetFriends(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]*domain.User, error)
+	GetFriendRequests(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]*domain.User, error)
+	CreateFriendRequest(ctx context.Context, requestingUserID, targetUserID uuid.UUID) error
+	UpdateFriendshipStatus(ctx context.Context, userID, friendID uuid.UUID, status string) error
+	DeleteFriendship(ctx context.Context, userID, friendID uuid.UUID) error
+	GetFriendship(ctx context.Context, userID, friendID uuid.UUID) (string, error)
+}
+
+// BlockedUserRepository stores blocks. Blocking ends any friendship or friend
+// request between the two users in the same transaction and returns its
+// status, or "" when there was none.
+type BlockedUserRepository interface {
+	BlockUserAndEndFriendship(ctx context.Context, blockerID, blockedID uuid.UUID, reason *string) (string, error)
+	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	GetBlockedUsers(ctx context.Context, blockerID uuid.UUID, limit int, offset int) ([]*domain.User, error)
+}
+
+// PasswordValidator checks a new password against the password policy
+type PasswordValidator interface {
+	ValidatePasswordStrength(password, username, email string) error
 }
 
 // NewService creates a new user service
 func NewService(
-	userRepo *cockroach.UserRepository,
-	blockedUserRepo *cockroach.BlockedUserRepository,
+	userRepo UserRepository,
+	blockedUserRepo BlockedUserRepository,
 	emailVerificationRepo *cockroach.EmailVerificationRepository,
 	emailService *email.Service,
 ) *Service {
 	return &Service{
 		userRepo:              userRepo,
+		hasher:                password.DefaultHasher(),
 		blockedUserRepo:       blockedUserRepo,
 		emailVerificationRepo: emailVerificationRepo,
 		emailService:          emailService,
 	}
 }
 
+// SetPasswordHasher sets the algorithm used for new password hashes
+func (s *Service) SetPasswordHasher(hasher password.Hasher) {
+	s.hasher = hasher
+}
+
+// SetPasswordValidator checks new passwords in ChangePassword with validator
+func (s *Service) SetPasswordValidator(validator PasswordValidator) {
+	s.passwordValidator = validator
+}
+
+// SetEmailNormalization folds Gmail dot and plus-tag aliases of a new email
+// into one address, matching the auth service's setting
+func (s *Service) SetEmailNormalization(stripGmailAliases bool) {
+	s.stripGmailAliases = stripGmailAliases
+}
+
 // GetProfile retrieves user profile by ID
 func (s *Service) GetProfile(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
 	user, err := s.userRepo.GetByID(ctx, userID)
@@ -92,28 +143,23 @@ func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassw
 	}
 
 	// Verify old password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+	if ok, err := s.hasher.Verify(user.PasswordHash, oldPassword); err != nil || !ok {
 		return fmt.Errorf("invalid old password")
 	}
 
+	if s.passwordValidator != nil {
+		if err := s.passwordValidator.ValidatePasswordStrength(newPassword, user.Username, user.Email); err != nil {
+			return err
+		}
+	}
+
 	// Hash new password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	passwordHash, err := s.hasher.Hash(newPassword)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	update := &domain.User{
-		UserID:       user.UserID,
-		Email:        user.Email,
-		Username:     user.Username,
-		PasswordHash: string(passwordHash),
-		DisplayName:  user.DisplayName,
-		AvatarURL:    user.AvatarURL,
-		Status:       user.Status,
-		UpdatedAt:    time.Now(),
-	}
-
-	return s.userRepo.Update(ctx, update)
+	return s.userRepo.UpdatePasswordHash(ctx, user.UserID, passwordHash)
 }
 
 // InitiateEmailChange initiates email change process
@@ -124,11 +170,12 @@ func (s *Service) InitiateEmailChange(ctx context.Context, userID uuid.UUID, new
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	if ok, err := s.hasher.Verify(user.PasswordHash, password); err != nil || !ok {
 		return fmt.Errorf("invalid password")
 	}
 
 	// Validate new email is not already taken
+	newEmail = sanitize.NormalizeEmail(newEmail, s.stripGmailAliases)
 	exists, err := s.userRepo.EmailExists(ctx, newEmail)
 	if err != nil {
 		return fmt.Errorf("failed to check email existence: %w", err)
@@ -162,7 +209,6 @@ func (s *Service) InitiateEmailChange(ctx context.Context, userID uuid.UUID, new
 		Username: userInfo.Username,
 		Token:    token,
 		NewEmail: newEmail,
-		AppURL:   env.GetString("APP_URL", "http://localhost:9090"),
 	})
 	if err != nil {
 		// Log error but don't fail - token is still created
@@ -239,25 +285,55 @@ func (s *Service) GetBlockedUsers(ctx context.Context, userID uuid.UUID, limit i
 	return s.blockedUserRepo.GetBlockedUsers(ctx, userID, limit, offset)
 }
 
-// BlockUser blocks another user
-func (s *Service) BlockUser(ctx context.Context, requestingUserID, targetUserID uuid.UUID, reason string) error {
+// BlockUserInput contains block data
+type BlockUserInput struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	Reason    string
+	IP        string // Recorded in the audit log
+	UserAgent string
+}
+
+// BlockUser blocks another user. Blocking implies no relationship, so an
+// existing friendship or pending friend request in either direction is
+// removed in the same transaction. Blocking an already blocked user only
+// updates the reason.
+func (s *Service) BlockUser(ctx context.Context, input *BlockUserInput) error {
 	// Cannot block yourself
-	if requestingUserID == targetUserID {
+	if input.BlockerID == input.BlockedID {
 		return fmt.Errorf("cannot block yourself")
 	}
 
 	// Check if target user exists
-	_, err := s.userRepo.GetByID(ctx, targetUserID)
+	_, err := s.userRepo.GetByID(ctx, input.BlockedID)
 	if err != nil {
 		return fmt.Errorf("target user not found: %w", err)
 	}
 
 	var reasonPtr *string
-	if reason != "" {
-		reasonPtr = &reason
+	if input.Reason != "" {
+		reasonPtr = &input.Reason
+	}
+
+	endedStatus, err := s.blockedUserRepo.BlockUserAndEndFriendship(ctx, input.BlockerID, input.BlockedID, reasonPtr)
+	if err != nil {
+		return err
 	}
 
-	return s.blockedUserRepo.BlockUser(ctx, requestingUserID, targetUserID, reasonPtr)
+	s.recordAudit(audit.EventUserBlock, func(al *audit.AuditLogger) error {
+		return al.LogUserBlock(ctx, input.BlockerID, input.BlockedID, input.IP, input.UserAgent, input.Reason)
+	})
+	switch endedStatus {
+	case "accepted":
+		s.recordAudit(audit.EventFriendRemove, func(al *audit.AuditLogger) error {
+			return al.LogFriendRemove(ctx, input.BlockerID, input.BlockedID, input.IP, input.UserAgent)
+		})
+	case "pending":
+		s.recordAudit(audit.EventFriendReject, func(al *audit.AuditLogger) error {
+			return al.LogFriendReject(ctx, input.BlockerID, input.BlockedID, input.IP, input.UserAgent)
+		})
+	}
+	return nil
 }
 
 // UnblockUser unblocks a user
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/audit"
+	"secureconnect-backend/pkg/logger"
+)
+
+// allUsers finds every user; other UserRepository methods are not used
+type allUsers struct {
+	UserRepository
+}
+
+func (allUsers) GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	return &domain.User{UserID: userID}, nil
+}
+
+// fakeRelationships mirrors the friendships and blocked_users tables
+type fakeRelationships struct {
+	BlockedUserRepository
+	friendships map[[2]uuid.UUID]string
+	blocks      map[[2]uuid.UUID]string
+}
+
+func (f *fakeRelationships) BlockUserAndEndFriendship(ctx context.Context, blockerID, blockedID uuid.UUID, reason *string) (string, error) {
+	var ended string
+	for _, key := range [][2]uuid.UUID{{blockerID, blockedID}, {blockedID, blockerID}} {
+		if status, ok := f.friendships[key]; ok {
+			ended = status
+			delete(f.friendships, key)
+		}
+	}
+	f.blocks[[2]uuid.UUID{blockerID, blockedID}] = ""
+	if reason != nil {
+		f.blocks[[2]uuid.UUID{blockerID, blockedID}] = *reason
+	}
+	return ended, nil
+}
+
+type fakeAuditStore struct {
+	events []*audit.AuditEvent
+}
+
+func (f *fakeAuditStore) Save(ctx context.Context, event *audit.AuditEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeAuditStore) Find(ctx context.Context, query audit.EventQuery) (*audit.EventPage, error) {
+	return &audit.EventPage{}, nil
+}
+
+func (f *fakeAuditStore) eventTypes() []audit.AuditEventType {
+	var types []audit.AuditEventType
+	for _, event := range f.events {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+func TestBlockUser(t *testing.T) {
+	logger.InitDefault("test")
+	alice, bob := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		friendship string // status of an existing bob -> alice friendship, "" for none
+		reason     string
+		wantEvents []audit.AuditEventType
+	}{
+		{
+			name:       "no relationship",
+			wantEvents: []audit.AuditEventType{audit.EventUserBlock},
+		},
+		{
+			name:       "removes an existing friendship whoever created it",
+			friendship: "accepted",
+			reason:     "spam",
+			wantEvents: []audit.AuditEventType{audit.EventUserBlock, audit.EventFriendRemove},
+		},
+		{
+			name:       "cancels a pending request",
+			friendship: "pending",
+			wantEvents: []audit.AuditEventType{audit.EventUserBlock, audit.EventFriendReject},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relationships := &fakeRelationships{friendships: map[[2]uuid.UUID]string{}, blocks: map[[2]uuid.UUID]string{}}
+			if tt.friendship != "" {
+				relationships.friendships[[2]uuid.UUID{bob, alice}] = tt.friendship
+			}
+			store := &fakeAuditStore{}
+			auditLogger := audit.NewAuditLogger(nil)
+			auditLogger.SetStore(store)
+			service := NewService(allUsers{}, relationships, nil, nil)
+			service.SetAuditLogger(auditLogger)
+
+			err := service.BlockUser(context.Background(), &BlockUserInput{BlockerID: alice, BlockedID: bob, Reason: tt.reason, IP: "192.0.2.1"})
+			require.NoError(t, err)
+
+			assert.Empty(t, relationships.friendships)
+			require.Contains(t, relationships.blocks, [2]uuid.UUID{alice, bob})
+			assert.Equal(t, tt.reason, relationships.blocks[[2]uuid.UUID{alice, bob}])
+			assert.Equal(t, tt.wantEvents, store.eventTypes())
+			for _, event := range store.events[1:] {
+				assert.Equal(t, bob.String(), event.Resource)
+				assert.Equal(t, "192.0.2.1", event.IPAddress)
+			}
+		})
+	}
+}
+
+func TestBlockUser_Again(t *testing.T) {
+	logger.InitDefault("test")
+	relationships := &fakeRelationships{friendships: map[[2]uuid.UUID]string{}, blocks: map[[2]uuid.UUID]string{}}
+	service := NewService(allUsers{}, relationships, nil, nil)
+	alice, bob := uuid.New(), uuid.New()
+
+	require.NoError(t, service.BlockUser(context.Background(), &BlockUserInput{BlockerID: alice, BlockedID: bob, Reason: "spam"}))
+	require.NoError(t, service.BlockUser(context.Background(), &BlockUserInput{BlockerID: alice, BlockedID: bob, Reason: "abuse"}))
+
+	assert.Equal(t, "abuse", relationships.blocks[[2]uuid.UUID{alice, bob}], "blocking again only updates the reason")
+}
+
+func TestBlockUser_Self(t *testing.T) {
+	relationships := &fakeRelationships{friendships: map[[2]uuid.UUID]string{}, blocks: map[[2]uuid.UUID]string{}}
+	service := NewService(allUsers{}, relationships, nil, nil)
+	alice := uuid.New()
+
+	assert.Error(t, service.BlockUser(context.Background(), &BlockUserInput{BlockerID: alice, BlockedID: alice}))
+	assert.Empty(t, relationships.blocks)
+}
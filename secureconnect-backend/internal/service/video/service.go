@@ -3,6 +3,7 @@ package video
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"secureconnect-backend/internal/domain"
@@ -26,9 +27,9 @@ type CallRepository interface {
 	GetUserCalls(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Call, error)
 }
 
-// ConversationRepository defines interface for conversation membership verification
-type ConversationRepository interface {
-	IsParticipant(ctx context.Context, conversationID, userID uuid.UUID) (bool, error)
+// MembershipChecker defines interface for conversation membership verification
+type MembershipChecker interface {
+	IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error)
 }
 
 // UserRepository defines interface for getting user information
@@ -36,26 +37,68 @@ type UserRepository interface {
 	GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error)
 }
 
+// CallMuteRepository finds participants who muted calls in a conversation
+type CallMuteRepository interface {
+	// GetCallMutedUserIDs returns none for direct conversations, which always ring
+	GetCallMutedUserIDs(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// ActiveCallTracker counts each user's ringing or active outgoing calls
+type ActiveCallTracker interface {
+	Acquire(ctx context.Context, userID, callID uuid.UUID, limit int, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, userID, callID uuid.UUID) error
+}
+
 // Service handles video call business logic
 type Service struct {
-	callRepo         CallRepository
-	conversationRepo ConversationRepository
-	userRepo         UserRepository
-	pushService      *push.Service
+	callRepo    CallRepository
+	membership  MembershipChecker
+	userRepo    UserRepository
+	pushService *push.Service
+	callMutes   CallMuteRepository // nil rings every callee
+
+	activeCalls    ActiveCallTracker // nil disables the concurrent call limit
+	maxActiveCalls int
+	activeCallTTL  time.Duration
+
+	ringMu      sync.Mutex
+	ringing     map[uuid.UUID]*ringingCall // calls this instance rang that nobody answered yet
+	ringTimeout time.Duration              // 0 rings until the call is ended
 	// TODO: Add Pion WebRTC SFU in future
 	// sfu *webrtc.SFU
 }
 
 // NewService creates a new video service
-func NewService(callRepo CallRepository, conversationRepo ConversationRepository, userRepo UserRepository, pushService *push.Service) *Service {
+func NewService(callRepo CallRepository, membership MembershipChecker, userRepo UserRepository, pushService *push.Service) *Service {
 	return &Service{
-		callRepo:         callRepo,
-		conversationRepo: conversationRepo,
-		userRepo:         userRepo,
-		pushService:      pushService,
+		callRepo:    callRepo,
+		membership:  membership,
+		userRepo:    userRepo,
+		pushService: pushService,
+		ringing:     make(map[uuid.UUID]*ringingCall),
 	}
 }
 
+// SetCallMutes skips call notifications to participants who muted calls in
+// the conversation
+func (s *Service) SetCallMutes(callMutes CallMuteRepository) {
+	s.callMutes = callMutes
+}
+
+// SetActiveCallLimit caps the calls each user can have ringing or active at
+// once. Calls stop counting when they end, or after ttl if they never do.
+func (s *Service) SetActiveCallLimit(tracker ActiveCallTracker, limit int, ttl time.Duration) {
+	s.activeCalls = tracker
+	s.maxActiveCalls = limit
+	s.activeCallTTL = ttl
+}
+
+// SetRingTimeout ends calls nobody joins within timeout, freeing the caller's
+// slot under the active call limit
+func (s *Service) SetRingTimeout(timeout time.Duration) {
+	s.ringTimeout = timeout
+}
+
 // CallType represents type of call
 type CallType string
 
@@ -86,6 +129,10 @@ func (s *Service) InitiateCall(ctx context.Context, input *InitiateCallInput) (*
 	// Generate call ID
 	callID := uuid.New()
 
+	if err := s.acquireActiveCall(ctx, input.CallerID, callID); err != nil {
+		return nil, err
+	}
+
 	// Create call record in database
 	call := &domain.Call{
 		CallID:         callID,
@@ -97,11 +144,13 @@ func (s *Service) InitiateCall(ctx context.Context, input *InitiateCallInput) (*
 	}
 
 	if err := s.callRepo.Create(ctx, call); err != nil {
+		s.releaseActiveCall(ctx, input.CallerID, callID)
 		return nil, fmt.Errorf("failed to create call record: %w", err)
 	}
 
 	// Add caller as first participant
 	if err := s.callRepo.AddParticipant(ctx, callID, input.CallerID); err != nil {
+		s.releaseActiveCall(ctx, input.CallerID, callID)
 		return nil, fmt.Errorf("failed to add caller: %w", err)
 	}
 
@@ -123,7 +172,7 @@ func (s *Service) InitiateCall(ctx context.Context, input *InitiateCallInput) (*
 			Timestamp:      time.Now().Unix(),
 		}
 
-		if err := s.pushService.SendCallNotification(ctx, pushData, input.CalleeIDs); err != nil {
+		if err := s.pushService.SendCallNotification(ctx, pushData, s.calleesToRing(ctx, input)); err != nil {
 			logger.Warn("Failed to send call notification",
 				zap.String("call_id", callID.String()),
 				zap.Error(err))
@@ -133,9 +182,12 @@ func (s *Service) InitiateCall(ctx context.Context, input *InitiateCallInput) (*
 	// HOTFIX: Enforce participant limit for Mesh topology
 	// P2P Mesh degrades significantly > 4 users.
 	if len(input.CalleeIDs)+1 > 4 {
+		s.releaseActiveCall(ctx, input.CallerID, callID)
 		return nil, fmt.Errorf("call capacity limit reached (max 4 participants)")
 	}
 
+	s.startRinging(callID, input.CallerID, input.CalleeIDs)
+
 	// TODO: Initialize SFU room
 
 	return &InitiateCallOutput{
@@ -147,6 +199,151 @@ func (s *Service) InitiateCall(ctx context.Context, input *InitiateCallInput) (*
 	}, nil
 }
 
+// acquireActiveCall counts callID against the caller's concurrent call
+// limit, returning domain.ErrTooManyActiveCalls when it is reached. Calls are
+// allowed if the count cannot be checked.
+func (s *Service) acquireActiveCall(ctx context.Context, callerID, callID uuid.UUID) error {
+	if s.activeCalls == nil || s.maxActiveCalls <= 0 {
+		return nil
+	}
+	acquired, err := s.activeCalls.Acquire(ctx, callerID, callID, s.maxActiveCalls, s.activeCallTTL)
+	if err != nil {
+		logger.Warn("Failed to check active call limit, allowing call",
+			zap.String("caller_id", callerID.String()),
+			zap.Error(err))
+		return nil
+	}
+	if !acquired {
+		return domain.ErrTooManyActiveCalls
+	}
+	return nil
+}
+
+// releaseActiveCall stops counting callID against the caller's limit. A
+// failure is logged; the call then stops counting after the TTL.
+func (s *Service) releaseActiveCall(ctx context.Context, callerID, callID uuid.UUID) {
+	if s.activeCalls == nil {
+		return
+	}
+	if err := s.activeCalls.Release(ctx, callerID, callID); err != nil {
+		logger.Warn("Failed to release active call",
+			zap.String("call_id", callID.String()),
+			zap.Error(err))
+	}
+}
+
+// ringingCall is a call waiting for its callees to join or reject it
+type ringingCall struct {
+	callerID uuid.UUID
+	pending  map[uuid.UUID]bool // callees who have not rejected the call
+	timer    *time.Timer
+}
+
+// ringTimeoutGrace bounds ending a call whose ring timed out
+const ringTimeoutGrace = 10 * time.Second
+
+// startRinging tracks callID until a callee joins, every callee rejects it or
+// the ring timeout passes
+func (s *Service) startRinging(callID, callerID uuid.UUID, calleeIDs []uuid.UUID) {
+	call := &ringingCall{callerID: callerID, pending: make(map[uuid.UUID]bool, len(calleeIDs))}
+	for _, id := range calleeIDs {
+		call.pending[id] = true
+	}
+
+	s.ringMu.Lock()
+	defer s.ringMu.Unlock()
+	if s.ringTimeout > 0 {
+		call.timer = time.AfterFunc(s.ringTimeout, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), ringTimeoutGrace)
+			defer cancel()
+			if s.stopRinging(callID) != nil {
+				s.endUnanswered(ctx, callID, callerID, "ring_timeout")
+			}
+		})
+	}
+	s.ringing[callID] = call
+}
+
+// stopRinging stops tracking callID and returns it, or nil if it was not ringing here
+func (s *Service) stopRinging(callID uuid.UUID) *ringingCall {
+	s.ringMu.Lock()
+	defer s.ringMu.Unlock()
+	call := s.ringing[callID]
+	if call == nil {
+		return nil
+	}
+	if call.timer != nil {
+		call.timer.Stop()
+	}
+	delete(s.ringing, callID)
+	return call
+}
+
+// endUnanswered ends a call nobody joined and frees the caller's slot
+func (s *Service) endUnanswered(ctx context.Context, callID, callerID uuid.UUID, reason string) {
+	if err := s.callRepo.EndCall(ctx, callID); err != nil {
+		logger.Warn("Failed to end unanswered call",
+			zap.String("call_id", callID.String()),
+			zap.String("reason", reason),
+			zap.Error(err))
+	}
+	s.releaseActiveCall(ctx, callerID, callID)
+	logger.Info("Unanswered call ended",
+		zap.String("call_id", callID.String()),
+		zap.String("reason", reason))
+}
+
+// RejectCall declines a ringing call. The call ends, freeing the caller's
+// slot, once every callee has rejected it. Calls are tracked by the instance
+// that rang them; elsewhere they end at the ring timeout or after the active
+// call TTL.
+func (s *Service) RejectCall(ctx context.Context, callID, userID uuid.UUID) error {
+	s.ringMu.Lock()
+	call := s.ringing[callID]
+	if call == nil || !call.pending[userID] {
+		s.ringMu.Unlock()
+		return domain.ErrCallNotRinging
+	}
+	delete(call.pending, userID)
+	last := len(call.pending) == 0
+	s.ringMu.Unlock()
+
+	if last && s.stopRinging(callID) != nil {
+		s.endUnanswered(ctx, callID, call.callerID, "rejected")
+	}
+	return nil
+}
+
+// calleesToRing drops callees who muted calls in the conversation. If the
+// mutes cannot be read every callee is rung rather than missing the call.
+func (s *Service) calleesToRing(ctx context.Context, input *InitiateCallInput) []uuid.UUID {
+	if s.callMutes == nil {
+		return input.CalleeIDs
+	}
+	mutedIDs, err := s.callMutes.GetCallMutedUserIDs(ctx, input.ConversationID)
+	if err != nil {
+		logger.Warn("Failed to get call mutes, ringing all callees",
+			zap.String("conversation_id", input.ConversationID.String()),
+			zap.Error(err))
+		return input.CalleeIDs
+	}
+	if len(mutedIDs) == 0 {
+		return input.CalleeIDs
+	}
+
+	muted := make(map[uuid.UUID]bool, len(mutedIDs))
+	for _, id := range mutedIDs {
+		muted[id] = true
+	}
+	callees := make([]uuid.UUID, 0, len(input.CalleeIDs))
+	for _, id := range input.CalleeIDs {
+		if !muted[id] {
+			callees = append(callees, id)
+		}
+	}
+	return callees
+}
+
 // EndCall terminates a call session
 func (s *Service) EndCall(ctx context.Context, callID uuid.UUID, userID uuid.UUID) error {
 	// Get call information before ending
@@ -167,6 +364,8 @@ func (s *Service) EndCall(ctx context.Context, callID uuid.UUID, userID uuid.UUI
 	if err := s.callRepo.EndCall(ctx, callID); err != nil {
 		return fmt.Errorf("failed to end call: %w", err)
 	}
+	s.stopRinging(callID)
+	s.releaseActiveCall(ctx, call.CallerID, callID)
 
 	// Mark user as left
 	if err := s.callRepo.RemoveParticipant(ctx, callID, userID); err != nil {
@@ -240,7 +439,7 @@ func (s *Service) JoinCall(ctx context.Context, callID uuid.UUID, userID uuid.UU
 	}
 
 	// Verify user is a participant in the conversation
-	isParticipant, err := s.conversationRepo.IsParticipant(ctx, call.ConversationID, userID)
+	isParticipant, err := s.membership.IsMember(ctx, userID, call.ConversationID)
 	if err != nil {
 		return fmt.Errorf("failed to verify conversation membership: %w", err)
 	}
@@ -259,6 +458,9 @@ func (s *Service) JoinCall(ctx context.Context, callID uuid.UUID, userID uuid.UU
 			return fmt.Errorf("failed to update status: %w", err)
 		}
 	}
+	if userID != call.CallerID {
+		s.stopRinging(callID)
+	}
 
 	// TODO: Add user to SFU room
 
@@ -325,6 +527,10 @@ func (s *Service) LeaveCall(ctx context.Context, callID uuid.UUID, userID uuid.U
 		if err := s.callRepo.EndCall(ctx, callID); err != nil {
 			return fmt.Errorf("failed to end call: %w", err)
 		}
+		s.stopRinging(callID)
+		if call != nil {
+			s.releaseActiveCall(ctx, call.CallerID, callID)
+		}
 	}
 
 	// TODO: Remove from SFU
@@ -2,14 +2,18 @@ package video
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"secureconnect-backend/internal/domain"
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/push"
 )
 
 // MockCallRepository is a mock implementation of CallRepository
@@ -79,20 +83,20 @@ func (m *MockCallRepository) GetUserCalls(ctx context.Context, userID uuid.UUID,
 	return args.Get(0).([]*domain.Call), args.Error(1)
 }
 
-// MockConversationRepository is a mock implementation of ConversationRepository
-type MockConversationRepository struct {
+// MockMembershipChecker is a mock implementation of MembershipChecker
+type MockMembershipChecker struct {
 	mock.Mock
 }
 
-func (m *MockConversationRepository) IsParticipant(ctx context.Context, conversationID, userID uuid.UUID) (bool, error) {
-	args := m.Called(ctx, conversationID, userID)
+func (m *MockMembershipChecker) IsMember(ctx context.Context, userID, conversationID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID, conversationID)
 	return args.Bool(0), args.Error(1)
 }
 
 // TestInitiateCall tests the InitiateCall method
 func TestInitiateCall(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -127,7 +131,7 @@ func TestInitiateCall(t *testing.T) {
 // TestEndCall tests the EndCall method
 func TestEndCall(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -149,7 +153,7 @@ func TestEndCall(t *testing.T) {
 // TestJoinCall tests the JoinCall method
 func TestJoinCall(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -163,7 +167,7 @@ func TestJoinCall(t *testing.T) {
 
 	// Setup expectations
 	mockCallRepo.On("GetByID", mock.Anything, callID).Return(existingCall, nil)
-	mockConvRepo.On("IsParticipant", mock.Anything, mock.AnythingOfType("uuid.UUID"), userID).Return(true, nil)
+	mockConvRepo.On("IsMember", mock.Anything, userID, mock.AnythingOfType("uuid.UUID")).Return(true, nil)
 	mockCallRepo.On("AddParticipant", mock.Anything, callID, userID).Return(nil)
 	mockCallRepo.On("UpdateStatus", mock.Anything, callID, "active").Return(nil)
 
@@ -179,7 +183,7 @@ func TestJoinCall(t *testing.T) {
 // TestJoinCall_CallEnded tests joining an ended call
 func TestJoinCall_CallEnded(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -206,7 +210,7 @@ func TestJoinCall_CallEnded(t *testing.T) {
 // TestLeaveCall tests the LeaveCall method
 func TestLeaveCall(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -246,7 +250,7 @@ func TestLeaveCall(t *testing.T) {
 // TestLeaveCall_LastParticipant tests leaving when you're the last participant
 func TestLeaveCall_LastParticipant(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -279,7 +283,7 @@ func TestLeaveCall_LastParticipant(t *testing.T) {
 // TestGetUserCallHistory tests retrieving call history
 func TestGetUserCallHistory(t *testing.T) {
 	mockCallRepo := new(MockCallRepository)
-	mockConvRepo := new(MockConversationRepository)
+	mockConvRepo := new(MockMembershipChecker)
 	mockUserRepo := new(MockUserRepository)
 	service := NewService(mockCallRepo, mockConvRepo, mockUserRepo, nil)
 
@@ -304,3 +308,213 @@ func TestGetUserCallHistory(t *testing.T) {
 	assert.Len(t, result, 1)
 	mockCallRepo.AssertExpectations(t)
 }
+
+// fakeCallMutes returns the call-muted participants of each conversation
+type fakeCallMutes map[uuid.UUID][]uuid.UUID
+
+func (f fakeCallMutes) GetCallMutedUserIDs(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	return f[conversationID], nil
+}
+
+// userTokens gives every user one active push token, their user ID
+type userTokens struct {
+	push.TokenRepository
+}
+
+func (userTokens) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*push.Token, error) {
+	return []*push.Token{{UserID: userID, Token: userID.String(), Active: true}}, nil
+}
+
+// recordingProvider records the tokens notifications were sent to
+type recordingProvider struct {
+	push.Provider
+	tokens []string
+}
+
+func (p *recordingProvider) Send(ctx context.Context, notification *push.Notification, tokens []string) (*push.SendResult, error) {
+	p.tokens = append(p.tokens, tokens...)
+	return &push.SendResult{SuccessCount: len(tokens)}, nil
+}
+
+func TestInitiateCall_SkipsCallMutedParticipants(t *testing.T) {
+	logger.InitDefault("test")
+	mockCallRepo := new(MockCallRepository)
+	mockUserRepo := new(MockUserRepository)
+	provider := &recordingProvider{}
+	service := NewService(mockCallRepo, new(MockMembershipChecker), mockUserRepo, push.NewService(provider, userTokens{}))
+
+	groupID := uuid.New()
+	callerID, mutedID, ringingID := uuid.New(), uuid.New(), uuid.New()
+	service.SetCallMutes(fakeCallMutes{groupID: {mutedID}})
+
+	mockCallRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Call")).Return(nil)
+	mockCallRepo.On("AddParticipant", mock.Anything, mock.AnythingOfType("uuid.UUID"), callerID).Return(nil)
+	mockUserRepo.On("GetByID", mock.Anything, callerID).Return(&domain.User{UserID: callerID, Username: "caller"}, nil)
+
+	_, err := service.InitiateCall(context.Background(), &InitiateCallInput{
+		CallType:       CallTypeAudio,
+		ConversationID: groupID,
+		CallerID:       callerID,
+		CalleeIDs:      []uuid.UUID{mutedID, ringingID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{ringingID.String()}, provider.tokens, "only the participant who did not mute calls is notified")
+
+	// A conversation without call mutes, such as a direct call, rings everyone
+	provider.tokens = nil
+	_, err = service.InitiateCall(context.Background(), &InitiateCallInput{
+		CallType:       CallTypeAudio,
+		ConversationID: uuid.New(),
+		CallerID:       callerID,
+		CalleeIDs:      []uuid.UUID{mutedID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{mutedID.String()}, provider.tokens)
+}
+
+// fakeActiveCalls counts calls per user like the Redis tracker
+type fakeActiveCalls struct {
+	mu    sync.Mutex
+	calls map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func (f *fakeActiveCalls) Acquire(ctx context.Context, userID, callID uuid.UUID, limit int, ttl time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[uuid.UUID]map[uuid.UUID]bool{}
+	}
+	if f.calls[userID] == nil {
+		f.calls[userID] = map[uuid.UUID]bool{}
+	}
+	if !f.calls[userID][callID] && len(f.calls[userID]) >= limit {
+		return false, nil
+	}
+	f.calls[userID][callID] = true
+	return true, nil
+}
+
+func (f *fakeActiveCalls) Release(ctx context.Context, userID, callID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.calls[userID], callID)
+	return nil
+}
+
+func (f *fakeActiveCalls) count(userID uuid.UUID) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls[userID])
+}
+
+func TestInitiateCall_EnforcesActiveCallLimit(t *testing.T) {
+	logger.InitDefault("test")
+	mockCallRepo := new(MockCallRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockCallRepo, new(MockMembershipChecker), mockUserRepo, push.NewService(&recordingProvider{}, userTokens{}))
+	activeCalls := &fakeActiveCalls{}
+	service.SetActiveCallLimit(activeCalls, 2, time.Hour)
+
+	callerID := uuid.New()
+	mockCallRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Call")).Return(nil)
+	mockCallRepo.On("AddParticipant", mock.Anything, mock.AnythingOfType("uuid.UUID"), callerID).Return(nil)
+	mockUserRepo.On("GetByID", mock.Anything, callerID).Return(&domain.User{UserID: callerID, Username: "caller"}, nil)
+
+	initiate := func() (*InitiateCallOutput, error) {
+		return service.InitiateCall(context.Background(), &InitiateCallInput{
+			CallType:       CallTypeVideo,
+			ConversationID: uuid.New(),
+			CallerID:       callerID,
+			CalleeIDs:      []uuid.UUID{uuid.New()},
+		})
+	}
+
+	first, err := initiate()
+	require.NoError(t, err)
+	_, err = initiate()
+	require.NoError(t, err)
+
+	_, err = initiate()
+	assert.ErrorIs(t, err, domain.ErrTooManyActiveCalls)
+	mockCallRepo.AssertNumberOfCalls(t, "Create", 2)
+
+	// Ending a call frees a slot
+	mockCallRepo.On("GetByID", mock.Anything, first.CallID).Return(&domain.Call{CallID: first.CallID, CallerID: callerID}, nil)
+	mockCallRepo.On("EndCall", mock.Anything, first.CallID).Return(nil)
+	mockCallRepo.On("RemoveParticipant", mock.Anything, first.CallID, callerID).Return(nil)
+	mockCallRepo.On("GetParticipants", mock.Anything, first.CallID).Return([]*domain.CallParticipant{}, nil)
+	require.NoError(t, service.EndCall(context.Background(), first.CallID, callerID))
+	assert.Equal(t, 1, activeCalls.count(callerID))
+
+	_, err = initiate()
+	assert.NoError(t, err)
+}
+
+func TestRejectCall_ReleasesSlotOnceEveryCalleeRejects(t *testing.T) {
+	logger.InitDefault("test")
+	mockCallRepo := new(MockCallRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockCallRepo, new(MockMembershipChecker), mockUserRepo, push.NewService(&recordingProvider{}, userTokens{}))
+	activeCalls := &fakeActiveCalls{}
+	service.SetActiveCallLimit(activeCalls, 1, time.Hour)
+
+	callerID, firstCallee, secondCallee := uuid.New(), uuid.New(), uuid.New()
+	mockCallRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Call")).Return(nil)
+	mockCallRepo.On("AddParticipant", mock.Anything, mock.AnythingOfType("uuid.UUID"), callerID).Return(nil)
+	mockUserRepo.On("GetByID", mock.Anything, callerID).Return(&domain.User{UserID: callerID, Username: "caller"}, nil)
+
+	output, err := service.InitiateCall(context.Background(), &InitiateCallInput{
+		CallType:       CallTypeVideo,
+		ConversationID: uuid.New(),
+		CallerID:       callerID,
+		CalleeIDs:      []uuid.UUID{firstCallee, secondCallee},
+	})
+	require.NoError(t, err)
+	mockCallRepo.On("EndCall", mock.Anything, output.CallID).Return(nil)
+
+	assert.ErrorIs(t, service.RejectCall(context.Background(), output.CallID, callerID), domain.ErrCallNotRinging)
+
+	require.NoError(t, service.RejectCall(context.Background(), output.CallID, firstCallee))
+	mockCallRepo.AssertNotCalled(t, "EndCall", mock.Anything, output.CallID)
+	assert.Equal(t, 1, activeCalls.count(callerID))
+	assert.ErrorIs(t, service.RejectCall(context.Background(), output.CallID, firstCallee), domain.ErrCallNotRinging)
+
+	require.NoError(t, service.RejectCall(context.Background(), output.CallID, secondCallee))
+	mockCallRepo.AssertCalled(t, "EndCall", mock.Anything, output.CallID)
+	assert.Equal(t, 0, activeCalls.count(callerID))
+}
+
+func TestRingTimeout_ReleasesSlot(t *testing.T) {
+	logger.InitDefault("test")
+	mockCallRepo := new(MockCallRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewService(mockCallRepo, new(MockMembershipChecker), mockUserRepo, push.NewService(&recordingProvider{}, userTokens{}))
+	activeCalls := &fakeActiveCalls{}
+	service.SetActiveCallLimit(activeCalls, 1, time.Hour)
+	service.SetRingTimeout(20 * time.Millisecond)
+
+	callerID, calleeID := uuid.New(), uuid.New()
+	ended := make(chan uuid.UUID, 1)
+	mockCallRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Call")).Return(nil)
+	mockCallRepo.On("AddParticipant", mock.Anything, mock.AnythingOfType("uuid.UUID"), callerID).Return(nil)
+	mockCallRepo.On("EndCall", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil).
+		Run(func(args mock.Arguments) { ended <- args.Get(1).(uuid.UUID) })
+	mockUserRepo.On("GetByID", mock.Anything, callerID).Return(&domain.User{UserID: callerID, Username: "caller"}, nil)
+
+	output, err := service.InitiateCall(context.Background(), &InitiateCallInput{
+		CallType:       CallTypeVideo,
+		ConversationID: uuid.New(),
+		CallerID:       callerID,
+		CalleeIDs:      []uuid.UUID{calleeID},
+	})
+	require.NoError(t, err)
+
+	select {
+	case callID := <-ended:
+		assert.Equal(t, output.CallID, callID)
+	case <-time.After(time.Second):
+		t.Fatal("unanswered call was not ended")
+	}
+	require.Eventually(t, func() bool { return activeCalls.count(callerID) == 0 }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, service.RejectCall(context.Background(), output.CallID, calleeID), domain.ErrCallNotRinging)
+}
@@ -2,9 +2,8 @@ package audit
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/geoip"
 	"time"
 
 	"github.com/google/uuid"
@@ -57,31 +56,66 @@ const (
 
 // AuditEvent represents an audit log entry
 type AuditEvent struct {
-	EventID   uuid.UUID      `json:"event_id"`
-	UserID    *uuid.UUID     `json:"user_id,omitempty"`
-	EventType AuditEventType `json:"event_type"`
-	Resource  string         `json:"resource,omitempty"`
-	Action    string         `json:"action,omitempty"`
-	IPAddress string         `json:"ip_address,omitempty"`
-	UserAgent string         `json:"user_agent,omitempty"`
-	Success   bool           `json:"success"`
-	ErrorCode string         `json:"error_code,omitempty"`
-	Details   string         `json:"details,omitempty"`
-	Timestamp time.Time      `json:"timestamp"`
+	EventID   uuid.UUID       `json:"event_id"`
+	UserID    *uuid.UUID      `json:"user_id,omitempty"`
+	EventType AuditEventType  `json:"event_type"`
+	Resource  string          `json:"resource,omitempty"`
+	Action    string          `json:"action,omitempty"`
+	IPAddress string          `json:"ip_address,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	Success   bool            `json:"success"`
+	ErrorCode string          `json:"error_code,omitempty"`
+	Details   string          `json:"details,omitempty"`
+	Geo       *geoip.Location `json:"geo,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+
+	// Hash chain fields, set only when the hash chain is enabled
+	Sequence  int64  `json:"sequence,omitempty"`
+	PrevHash  string `json:"prev_hash,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
 // AuditLogger handles audit logging
 type AuditLogger struct {
 	redisClient *redis.Client
+	store       Store
+	chain       *hashChain
+	geo         geoip.Resolver
 }
 
-// NewAuditLogger creates a new audit logger
+// NewAuditLogger creates a new audit logger that stores events in Redis
 func NewAuditLogger(redisClient *redis.Client) *AuditLogger {
 	return &AuditLogger{
 		redisClient: redisClient,
+		store:       NewRedisStore(redisClient),
 	}
 }
 
+// SetStore replaces the Redis lists events are stored in and read from. The
+// hash chain, when enabled, stays in Redis.
+func (al *AuditLogger) SetStore(store Store) {
+	al.store = store
+}
+
+// SetGeoIP enables geo enrichment of login events. The resolver should be
+// cached (see geoip.NewCache) since it is consulted on the request path.
+func (al *AuditLogger) SetGeoIP(resolver geoip.Resolver) {
+	al.geo = resolver
+}
+
+// lookupGeo returns the location of ipAddress, or nil when unknown or not configured
+func (al *AuditLogger) lookupGeo(ipAddress string) *geoip.Location {
+	if al.geo == nil || ipAddress == "" {
+		return nil
+	}
+	loc, err := al.geo.Lookup(ipAddress)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
 // Log logs an audit event
 func (al *AuditLogger) Log(ctx context.Context, event *AuditEvent) error {
 	// Set timestamp
@@ -92,28 +126,15 @@ func (al *AuditLogger) Log(ctx context.Context, event *AuditEvent) error {
 		event.EventID = uuid.New()
 	}
 
-	// Serialize event to JSON
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal audit event: %w", err)
-	}
-
-	// Store in Redis list
-	key := fmt.Sprintf("audit:events:%s", event.Timestamp.Format("2006-01-02"))
-	member := fmt.Sprintf("%s:%s", event.EventID, eventJSON)
-
-	err = al.redisClient.LPush(ctx, key, member).Err()
-	if err != nil {
-		return fmt.Errorf("failed to store audit event: %w", err)
-	}
-
-	// Set expiry for audit logs (keep for 90 days)
-	err = al.redisClient.Expire(ctx, key, constants.AuditLogRetention).Err()
-	if err != nil {
-		return fmt.Errorf("failed to set audit log expiry: %w", err)
+	if al.chain != nil {
+		return al.logChained(ctx, event)
 	}
+	return al.store.Save(ctx, event)
+}
 
-	return nil
+// dailyEventsKey returns the Redis list holding events logged on the day of t
+func dailyEventsKey(t time.Time) string {
+	return fmt.Sprintf("audit:events:%s", t.Format("2006-01-02"))
 }
 
 // LogLoginSuccess logs a successful login
@@ -124,6 +145,7 @@ func (al *AuditLogger) LogLoginSuccess(ctx context.Context, userID uuid.UUID, ip
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
 		Success:   true,
+		Geo:       al.lookupGeo(ipAddress),
 	})
 }
 
@@ -137,6 +159,7 @@ func (al *AuditLogger) LogLoginFailed(ctx context.Context, identifier, ipAddress
 		Success:   false,
 		ErrorCode: errorCode,
 		Details:   details,
+		Geo:       al.lookupGeo(ipAddress),
 	})
 }
 
@@ -151,6 +174,18 @@ func (al *AuditLogger) LogLogout(ctx context.Context, userID uuid.UUID, ipAddres
 	})
 }
 
+// LogRegister logs a new account registration
+func (al *AuditLogger) LogRegister(ctx context.Context, userID uuid.UUID, ipAddress, userAgent string) error {
+	return al.Log(ctx, &AuditEvent{
+		UserID:    &userID,
+		EventType: EventRegister,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Success:   true,
+		Geo:       al.lookupGeo(ipAddress),
+	})
+}
+
 // LogPasswordChange logs a password change
 func (al *AuditLogger) LogPasswordChange(ctx context.Context, userID uuid.UUID, ipAddress, userAgent string, success bool, errorCode string) error {
 	return al.Log(ctx, &AuditEvent{
@@ -371,70 +406,19 @@ func (al *AuditLogger) LogAdminAction(ctx context.Context, adminID uuid.UUID, ac
 	})
 }
 
-// GetEvents retrieves audit events for a user
-func (al *AuditLogger) GetEvents(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]*AuditEvent, error) {
-	// Get keys for all days in the range
-	now := time.Now().UTC()
-	keys := make([]string, 0)
-	for i := 0; i < 90; i++ {
-		date := now.AddDate(0, 0, -i)
-		key := fmt.Sprintf("audit:events:%s", date.Format("2006-01-02"))
-		keys = append(keys, key)
-	}
-
-	// Get events from Redis
-	var events []*AuditEvent
-	for _, key := range keys {
-		members, err := al.redisClient.LRange(ctx, key, int64(offset), int64(offset+limit)).Result()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get audit events: %w", err)
-		}
-
-		for _, member := range members {
-			var event AuditEvent
-			err := json.Unmarshal([]byte(member), &event)
-			if err != nil {
-				continue
-			}
-			if event.UserID != nil && *event.UserID == userID {
-				events = append(events, &event)
-			}
-		}
-	}
-
-	return events, nil
+// FindEvents retrieves a page of audit events matching query, newest first
+func (al *AuditLogger) FindEvents(ctx context.Context, query EventQuery) (*EventPage, error) {
+	return al.store.Find(ctx, query)
 }
 
-// GetEventsByType retrieves audit events by type
-func (al *AuditLogger) GetEventsByType(ctx context.Context, eventType AuditEventType, limit int, offset int) ([]*AuditEvent, error) {
-	// Get keys for all days in the range
-	now := time.Now().UTC()
-	keys := make([]string, 0)
-	for i := 0; i < 90; i++ {
-		date := now.AddDate(0, 0, -i)
-		key := fmt.Sprintf("audit:events:%s", date.Format("2006-01-02"))
-		keys = append(keys, key)
-	}
-
-	// Get events from Redis
-	var events []*AuditEvent
-	for _, key := range keys {
-		members, err := al.redisClient.LRange(ctx, key, int64(offset), int64(offset+limit)).Result()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get audit events: %w", err)
-		}
-
-		for _, member := range members {
-			var event AuditEvent
-			err := json.Unmarshal([]byte(member), &event)
-			if err != nil {
-				continue
-			}
-			if event.EventType == eventType {
-				events = append(events, &event)
-			}
-		}
-	}
+// GetEvents retrieves a user's audit events, newest first
+func (al *AuditLogger) GetEvents(ctx context.Context, userID uuid.UUID, query EventQuery) (*EventPage, error) {
+	query.UserID = userID
+	return al.store.Find(ctx, query)
+}
 
-	return events, nil
+// GetEventsByType retrieves audit events of one type, newest first
+func (al *AuditLogger) GetEventsByType(ctx context.Context, eventType AuditEventType, query EventQuery) (*EventPage, error) {
+	query.EventType = eventType
+	return al.store.Find(ctx, query)
 }
@@ -0,0 +1,257 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// chainEventsKey holds chained events in write order (oldest first)
+	chainEventsKey = "audit:chain:events"
+
+	// chainHeadKey holds the sequence and hash of the last chained event
+	chainHeadKey = "audit:chain:head"
+
+	// chainAppendRetries bounds optimistic retries when another writer moves the head
+	chainAppendRetries = 5
+)
+
+// ErrChainBroken is returned by VerifyChain when the stored chain does not match
+// what recomputing it produces
+var ErrChainBroken = errors.New("audit chain verification failed")
+
+// ChainHead identifies the last event appended to the hash chain
+type ChainHead struct {
+	Sequence int64  `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// chainStore persists chained events. Append must make reading the head,
+// linking the event and writing it atomic with respect to other writers,
+// otherwise two events could claim the same predecessor. link may run more
+// than once when another writer moves the head, and nothing is written when
+// it fails.
+type chainStore interface {
+	Append(ctx context.Context, link func(head ChainHead) (string, ChainHead, error)) error
+	Events(ctx context.Context) ([]string, error)
+	Head(ctx context.Context) (ChainHead, error)
+}
+
+// hashChain links events into a tamper-evident chain
+type hashChain struct {
+	store   chainStore
+	hmacKey []byte
+
+	// mu serializes appends from this process so they rarely conflict in the store
+	mu sync.Mutex
+}
+
+// EnableHashChain makes every logged event carry the hash of its predecessor and,
+// when hmacKey is non-empty, an HMAC-SHA256 signature of its own hash. Chaining
+// serializes all audit writes through a single head, so it is opt-in.
+func (al *AuditLogger) EnableHashChain(hmacKey []byte) {
+	al.chain = &hashChain{
+		store:   &redisChainStore{client: al.redisClient},
+		hmacKey: hmacKey,
+	}
+}
+
+// logChained links event to the chain head and stores it
+func (al *AuditLogger) logChained(ctx context.Context, event *AuditEvent) error {
+	c := al.chain
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.store.Append(ctx, func(head ChainHead) (string, ChainHead, error) {
+		event.Sequence = head.Sequence + 1
+		event.PrevHash = head.Hash
+
+		hash, err := computeEventHash(event)
+		if err != nil {
+			return "", ChainHead{}, err
+		}
+		event.Hash = hash
+		event.Signature = c.sign(hash)
+
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			return "", ChainHead{}, fmt.Errorf("failed to marshal audit event: %w", err)
+		}
+
+		// Save before the head moves, so a chained event is always in the
+		// store. A retried link saves the event again with its new position.
+		if err := al.store.Save(ctx, event); err != nil {
+			return "", ChainHead{}, err
+		}
+		return string(eventJSON), ChainHead{Sequence: event.Sequence, Hash: hash}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store audit event: %w", err)
+	}
+	return nil
+}
+
+// VerifyChain recomputes the hash chain for events logged between from and to
+// (inclusive) and returns ErrChainBroken if any event was modified, inserted or
+// removed. Zero times leave that side of the range open.
+func (al *AuditLogger) VerifyChain(ctx context.Context, from, to time.Time) error {
+	if al.chain == nil {
+		return errors.New("audit hash chain is not enabled")
+	}
+	c := al.chain
+
+	members, err := c.store.Events(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load audit chain: %w", err)
+	}
+	head, err := c.store.Head(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load audit chain head: %w", err)
+	}
+
+	var prev *AuditEvent
+	for i, member := range members {
+		var event AuditEvent
+		if err := json.Unmarshal([]byte(member), &event); err != nil {
+			return fmt.Errorf("%w: entry %d is unreadable: %v", ErrChainBroken, i, err)
+		}
+
+		inRange := (from.IsZero() || !event.Timestamp.Before(from)) &&
+			(to.IsZero() || !event.Timestamp.After(to))
+		if inRange {
+			if err := c.verifyLink(prev, &event); err != nil {
+				return err
+			}
+		}
+		prev = &event
+	}
+
+	// Truncating the tail leaves a valid prefix, so the last event must match the head
+	if head.Sequence > 0 {
+		if prev == nil || prev.Sequence != head.Sequence || prev.Hash != head.Hash {
+			return fmt.Errorf("%w: chain ends before head sequence %d", ErrChainBroken, head.Sequence)
+		}
+	}
+	return nil
+}
+
+// verifyLink checks event's own hash and signature and its link to prev
+func (c *hashChain) verifyLink(prev, event *AuditEvent) error {
+	hash, err := computeEventHash(event)
+	if err != nil {
+		return err
+	}
+	if hash != event.Hash {
+		return fmt.Errorf("%w: event %s (sequence %d) was modified", ErrChainBroken, event.EventID, event.Sequence)
+	}
+	if len(c.hmacKey) > 0 && !hmac.Equal([]byte(c.sign(hash)), []byte(event.Signature)) {
+		return fmt.Errorf("%w: event %s (sequence %d) has an invalid signature", ErrChainBroken, event.EventID, event.Sequence)
+	}
+
+	var wantSeq int64 = 1
+	var wantPrev string
+	if prev != nil {
+		wantSeq = prev.Sequence + 1
+		wantPrev = prev.Hash
+	}
+	if event.Sequence != wantSeq || event.PrevHash != wantPrev {
+		return fmt.Errorf("%w: event %s (sequence %d) does not follow sequence %d",
+			ErrChainBroken, event.EventID, event.Sequence, wantSeq-1)
+	}
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of hash, or "" when no key is configured
+func (c *hashChain) sign(hash string) string {
+	if len(c.hmacKey) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.hmacKey)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// computeEventHash hashes the event contents, including PrevHash and Sequence,
+// with Hash and Signature left out
+func computeEventHash(event *AuditEvent) (string, error) {
+	unsigned := *event
+	unsigned.Hash = ""
+	unsigned.Signature = ""
+
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// redisChainStore keeps the chain in a Redis list guarded by a WATCHed head key
+type redisChainStore struct {
+	client *redis.Client
+}
+
+func (s *redisChainStore) Append(ctx context.Context, link func(head ChainHead) (string, ChainHead, error)) error {
+	txf := func(tx *redis.Tx) error {
+		head, err := readChainHead(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		member, newHead, err := link(head)
+		if err != nil {
+			return err
+		}
+		headJSON, err := json.Marshal(newHead)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.RPush(ctx, chainEventsKey, member)
+			pipe.Set(ctx, chainHeadKey, headJSON, 0)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < chainAppendRetries; i++ {
+		err := s.client.Watch(ctx, txf, chainHeadKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.New("audit chain head kept changing")
+}
+
+func (s *redisChainStore) Events(ctx context.Context) ([]string, error) {
+	return s.client.LRange(ctx, chainEventsKey, 0, -1).Result()
+}
+
+func (s *redisChainStore) Head(ctx context.Context) (ChainHead, error) {
+	return readChainHead(ctx, s.client)
+}
+
+func readChainHead(ctx context.Context, client redis.Cmdable) (ChainHead, error) {
+	var head ChainHead
+	data, err := client.Get(ctx, chainHeadKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return head, nil
+	}
+	if err != nil {
+		return head, err
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return head, fmt.Errorf("invalid audit chain head: %w", err)
+	}
+	return head, nil
+}
@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/geoip"
+)
+
+// memoryChainStore is an in-process chainStore
+type memoryChainStore struct {
+	events []string
+	head   ChainHead
+}
+
+func (s *memoryChainStore) Append(ctx context.Context, link func(head ChainHead) (string, ChainHead, error)) error {
+	member, head, err := link(s.head)
+	if err != nil {
+		return err
+	}
+	s.events = append(s.events, member)
+	s.head = head
+	return nil
+}
+
+func (s *memoryChainStore) Events(ctx context.Context) ([]string, error) {
+	return s.events, nil
+}
+
+func (s *memoryChainStore) Head(ctx context.Context) (ChainHead, error) {
+	return s.head, nil
+}
+
+// logLogins chains n login events and checks the chain verifies
+func logLogins(t *testing.T, al *AuditLogger, n int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		require.NoError(t, al.LogLoginSuccess(ctx, uuid.New(), "10.0.0.1", "test-agent"))
+	}
+	require.NoError(t, al.VerifyChain(ctx, time.Time{}, time.Time{}))
+}
+
+// editEvent rewrites the stored event at index i
+func editEvent(t *testing.T, store *memoryChainStore, i int, edit func(e *AuditEvent)) {
+	t.Helper()
+	var event AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(store.events[i]), &event))
+	edit(&event)
+	data, err := json.Marshal(&event)
+	require.NoError(t, err)
+	store.events[i] = string(data)
+}
+
+func TestVerifyChain_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, store *memoryChainStore)
+	}{
+		{
+			name: "modified event",
+			tamper: func(t *testing.T, store *memoryChainStore) {
+				editEvent(t, store, 2, func(e *AuditEvent) { e.IPAddress = "203.0.113.9" })
+			},
+		},
+		{
+			name: "modified event with recomputed hash",
+			tamper: func(t *testing.T, store *memoryChainStore) {
+				editEvent(t, store, 2, func(e *AuditEvent) {
+					e.Success = false
+					e.Hash, _ = computeEventHash(e)
+				})
+			},
+		},
+		{
+			name: "deleted event",
+			tamper: func(t *testing.T, store *memoryChainStore) {
+				store.events = append(store.events[:1], store.events[2:]...)
+			},
+		},
+		{
+			name: "truncated tail",
+			tamper: func(t *testing.T, store *memoryChainStore) {
+				store.events = store.events[:4]
+			},
+		},
+		{
+			name: "inserted event",
+			tamper: func(t *testing.T, store *memoryChainStore) {
+				store.events = append(store.events[:3], append([]string{store.events[1]}, store.events[3:]...)...)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryChainStore{}
+			al := &AuditLogger{store: newMemoryStore(), chain: &hashChain{store: store, hmacKey: []byte("test-hmac-key")}}
+			logLogins(t, al, 5)
+
+			tt.tamper(t, store)
+			assert.ErrorIs(t, al.VerifyChain(context.Background(), time.Time{}, time.Time{}), ErrChainBroken)
+		})
+	}
+}
+
+func TestVerifyChain_RangeAnchorsOnPredecessor(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryChainStore{}
+	al := &AuditLogger{store: newMemoryStore(), chain: &hashChain{store: store}}
+	logLogins(t, al, 5)
+
+	var third AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(store.events[2]), &third))
+	assert.NoError(t, al.VerifyChain(ctx, third.Timestamp, time.Time{}))
+
+	// Without a key the chain still catches edits inside the range
+	editEvent(t, store, 3, func(e *AuditEvent) { e.Details = "forged" })
+	assert.ErrorIs(t, al.VerifyChain(ctx, third.Timestamp, time.Time{}), ErrChainBroken)
+}
+
+func TestLogLogin_EnrichesWithGeo(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryChainStore{}
+	al := &AuditLogger{store: newMemoryStore(), chain: &hashChain{store: store}}
+	al.SetGeoIP(&geoip.Fake{Locations: map[string]*geoip.Location{
+		"81.2.69.142": {CountryCode: "GB", City: "London", ASN: 20712},
+	}})
+
+	require.NoError(t, al.LogLoginSuccess(ctx, uuid.New(), "81.2.69.142", "test-agent"))
+	require.NoError(t, al.LogLoginFailed(ctx, "alice@example.com", "10.0.0.1", "test-agent", "invalid_credentials", ""))
+
+	var success, failed AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(store.events[0]), &success))
+	require.NoError(t, json.Unmarshal([]byte(store.events[1]), &failed))
+	require.NotNil(t, success.Geo)
+	assert.Equal(t, "GB", success.Geo.CountryCode)
+	assert.Equal(t, "London", success.Geo.City)
+	assert.Equal(t, uint(20712), success.Geo.ASN)
+	assert.Nil(t, failed.Geo)
+
+	// Geo data is covered by the chain hash
+	assert.NoError(t, al.VerifyChain(ctx, time.Time{}, time.Time{}))
+}
+
+// conflictingChainStore links every event twice, as when another writer moves
+// the head between the read and the write
+type conflictingChainStore struct {
+	memoryChainStore
+}
+
+func (s *conflictingChainStore) Append(ctx context.Context, link func(head ChainHead) (string, ChainHead, error)) error {
+	if _, _, err := link(ChainHead{Sequence: s.head.Sequence + 100}); err != nil {
+		return err
+	}
+	return s.memoryChainStore.Append(ctx, link)
+}
+
+// failingStore cannot save events
+type failingStore struct {
+	memoryStore
+}
+
+func (s *failingStore) Save(ctx context.Context, event *AuditEvent) error {
+	return errors.New("store unavailable")
+}
+
+func TestLogChained_StoreAndChainAgree(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a failed save leaves the chain unchanged", func(t *testing.T) {
+		chain := &memoryChainStore{}
+		al := &AuditLogger{store: &failingStore{}, chain: &hashChain{store: chain}}
+
+		assert.Error(t, al.LogLoginSuccess(ctx, uuid.New(), "10.0.0.1", "test-agent"))
+		assert.Empty(t, chain.events)
+		assert.Zero(t, chain.head.Sequence)
+	})
+
+	t.Run("a retried append saves the committed position once", func(t *testing.T) {
+		chain := &conflictingChainStore{}
+		store := newMemoryStore()
+		al := &AuditLogger{store: store, chain: &hashChain{store: chain}}
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, al.LogLoginSuccess(ctx, uuid.New(), "10.0.0.1", "test-agent"))
+		}
+		require.Len(t, store.events, 2)
+		for i, event := range store.events {
+			assert.Equal(t, int64(i+1), event.Sequence)
+		}
+		require.NoError(t, al.VerifyChain(ctx, time.Time{}, time.Time{}))
+	})
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/pagination"
+)
+
+// ErrSearchNotSupported is returned when the configured store cannot page
+// with cursors or export
+var ErrSearchNotSupported = errors.New("audit store does not support search")
+
+// PageRequest asks for up to Limit events older than Cursor, or the newest
+// events when Cursor is empty
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// SearchPage is one page of a cursor search. NextCursor is empty on the
+// last page.
+type SearchPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// Searcher pages through audit events newest first with keyset cursors over
+// (timestamp, event_id), so pages stay stable while new events are logged,
+// and streams whole result sets for export. The filter's Limit and Offset
+// are ignored.
+type Searcher interface {
+	Search(ctx context.Context, filter EventQuery, page PageRequest) (*SearchPage, error)
+	Export(ctx context.Context, filter EventQuery, fn func(*AuditEvent) error) error
+}
+
+// EncodeEventCursor returns the cursor that continues after event
+func EncodeEventCursor(event *AuditEvent) string {
+	return pagination.EncodeCursor(event.Timestamp, event.EventID)
+}
+
+// eventCursor returns the position of event in newest-first order
+func eventCursor(event *AuditEvent) *pagination.Cursor {
+	return &pagination.Cursor{CreatedAt: event.Timestamp, ID: event.EventID}
+}
+
+// olderThan reports whether event sorts after cursor in newest-first order
+func olderThan(event *AuditEvent, cursor *pagination.Cursor) bool {
+	if !event.Timestamp.Equal(cursor.CreatedAt) {
+		return event.Timestamp.Before(cursor.CreatedAt)
+	}
+	return strings.Compare(event.EventID.String(), cursor.ID.String()) < 0
+}
+
+// Search collects the matching events in range, orders them by timestamp
+// and event ID, and returns the page after the cursor
+func (s *RedisStore) Search(ctx context.Context, filter EventQuery, page PageRequest) (*SearchPage, error) {
+	var cursor *pagination.Cursor
+	if page.Cursor != "" {
+		decoded, err := pagination.DecodeCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = decoded
+	}
+
+	var matches []*AuditEvent
+	err := s.Export(ctx, filter, func(event *AuditEvent) error {
+		if cursor == nil || olderThan(event, cursor) {
+			matches = append(matches, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Lists are in insertion order, which can differ from timestamp order
+	sort.Slice(matches, func(i, j int) bool {
+		return olderThan(matches[j], eventCursor(matches[i]))
+	})
+
+	result := &SearchPage{Events: matches}
+	if page.Limit > 0 && len(matches) > page.Limit {
+		result.Events = matches[:page.Limit]
+		result.NextCursor = EncodeEventCursor(result.Events[page.Limit-1])
+	}
+	if result.Events == nil {
+		result.Events = []*AuditEvent{}
+	}
+	return result, nil
+}
+
+// Export calls fn for every matching event, one daily list at a time
+func (s *RedisStore) Export(ctx context.Context, filter EventQuery, fn func(*AuditEvent) error) error {
+	filter.Offset, filter.Limit = 0, 0
+	newest := time.Now().UTC()
+	if !filter.To.IsZero() && filter.To.Before(newest) {
+		newest = filter.To.UTC()
+	}
+	oldest := newest.Add(-constants.AuditLogRetention)
+	if !filter.From.IsZero() && filter.From.After(oldest) {
+		oldest = filter.From.UTC()
+	}
+
+	for day := newest; !beforeDay(day, oldest); day = day.AddDate(0, 0, -1) {
+		members, err := s.client.LRange(ctx, dailyEventsKey(day), 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get audit events: %w", err)
+		}
+		// Members are newest first
+		for _, member := range members {
+			event, ok := decodeEventMember(member)
+			if !ok || !filter.matches(event) {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SearchEvents pages through audit events with cursors when the store
+// supports it
+func (al *AuditLogger) SearchEvents(ctx context.Context, filter EventQuery, page PageRequest) (*SearchPage, error) {
+	searcher, ok := al.store.(Searcher)
+	if !ok {
+		return nil, ErrSearchNotSupported
+	}
+	return searcher.Search(ctx, filter, page)
+}
+
+// ExportEvents streams every matching audit event to fn when the store
+// supports it
+func (al *AuditLogger) ExportEvents(ctx context.Context, filter EventQuery, fn func(*AuditEvent) error) error {
+	searcher, ok := al.store.(Searcher)
+	if !ok {
+		return ErrSearchNotSupported
+	}
+	return searcher.Export(ctx, filter, fn)
+}
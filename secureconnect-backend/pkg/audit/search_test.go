@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/pagination"
+)
+
+func TestRedisStore_SearchFiltersOnEveryCriterion(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	alice, bob := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+	save := func(userID uuid.UUID, eventType AuditEventType, ip string, success bool, at time.Time) uuid.UUID {
+		event := &AuditEvent{EventID: uuid.New(), UserID: &userID, EventType: eventType, IPAddress: ip, Success: success, Timestamp: at}
+		require.NoError(t, store.Save(ctx, event))
+		return event.EventID
+	}
+	want := save(alice, EventLoginFailed, "10.0.0.1", false, now.Add(-time.Hour))
+	save(alice, EventLoginFailed, "10.0.0.1", false, now.AddDate(0, 0, -3)) // before from
+	save(alice, EventLoginFailed, "10.0.0.2", false, now.Add(-time.Hour))   // other address
+	save(alice, EventLoginFailed, "10.0.0.1", true, now.Add(-time.Hour))    // succeeded
+	save(alice, EventLogout, "10.0.0.1", false, now.Add(-time.Hour))        // other type
+	save(bob, EventLoginFailed, "10.0.0.1", false, now.Add(-time.Hour))     // other user
+
+	failed := false
+	page, err := store.Search(ctx, EventQuery{
+		UserID:    alice,
+		EventType: EventLoginFailed,
+		Success:   &failed,
+		IPAddress: "10.0.0.1",
+		From:      now.AddDate(0, 0, -1),
+		To:        now,
+	}, PageRequest{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, want, page.Events[0].EventID)
+	assert.Empty(t, page.NextCursor, "a single page has no next cursor")
+}
+
+func TestRedisStore_SearchPagesAreStable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	// Five events, two of them logged at the same instant
+	now := time.Now().UTC().Truncate(time.Second)
+	var saved []uuid.UUID
+	for _, at := range []time.Time{now.Add(-4 * time.Minute), now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute)} {
+		event := &AuditEvent{EventID: uuid.New(), EventType: EventLoginSuccess, Timestamp: at}
+		require.NoError(t, store.Save(ctx, event))
+		saved = append(saved, event.EventID)
+	}
+
+	first, err := store.Search(ctx, EventQuery{}, PageRequest{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first.Events, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	// Events logged between pages must not shift or repeat later pages
+	require.NoError(t, store.Save(ctx, &AuditEvent{EventID: uuid.New(), EventType: EventLoginSuccess, Timestamp: now}))
+
+	seen := map[uuid.UUID]bool{}
+	for _, event := range first.Events {
+		seen[event.EventID] = true
+	}
+	cursor := first.NextCursor
+	previous := first.Events[len(first.Events)-1]
+	for cursor != "" {
+		page, err := store.Search(ctx, EventQuery{}, PageRequest{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		for _, event := range page.Events {
+			assert.False(t, seen[event.EventID], "event repeated across pages")
+			assert.True(t, olderThan(event, eventCursor(previous)), "pages continue newest first")
+			seen[event.EventID] = true
+			previous = event
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Len(t, seen, len(saved), "every event is returned exactly once")
+	for _, id := range saved {
+		assert.True(t, seen[id])
+	}
+
+	_, err = store.Search(ctx, EventQuery{}, PageRequest{Limit: 2, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+func TestRedisStore_ExportStopsOnError(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.Save(ctx, &AuditEvent{EventID: uuid.New(), EventType: EventLogout, Timestamp: time.Now().UTC()}))
+	}
+
+	stop := errors.New("client went away")
+	var calls int
+	err := store.Export(ctx, EventQuery{}, func(*AuditEvent) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestAuditLogger_SearchNeedsSearchableStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	al := NewAuditLogger(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	al.SetStore(newMemoryStore())
+
+	_, err := al.SearchEvents(context.Background(), EventQuery{}, PageRequest{Limit: 10})
+	assert.ErrorIs(t, err, ErrSearchNotSupported)
+}
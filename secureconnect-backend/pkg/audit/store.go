@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"secureconnect-backend/pkg/constants"
+)
+
+// EventQuery selects and pages through audit events, newest first. Zero
+// values leave a filter open.
+type EventQuery struct {
+	UserID    uuid.UUID
+	EventType AuditEventType
+	Success   *bool
+	IPAddress string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// matches reports whether event passes the query's filters and time range
+func (q EventQuery) matches(event *AuditEvent) bool {
+	if q.UserID != uuid.Nil && (event.UserID == nil || *event.UserID != q.UserID) {
+		return false
+	}
+	if q.EventType != "" && event.EventType != q.EventType {
+		return false
+	}
+	if q.Success != nil && event.Success != *q.Success {
+		return false
+	}
+	if q.IPAddress != "" && event.IPAddress != q.IPAddress {
+		return false
+	}
+	return (q.From.IsZero() || !event.Timestamp.Before(q.From)) &&
+		(q.To.IsZero() || !event.Timestamp.After(q.To))
+}
+
+// EventPage is one page of matching events and the number of matches overall
+type EventPage struct {
+	Events []*AuditEvent `json:"events"`
+	Total  int           `json:"total"`
+}
+
+// Store persists audit events and finds them by query. Saving an event with
+// the EventID of an earlier one replaces it, which lets a chained append be
+// retried without duplicating the event.
+type Store interface {
+	Save(ctx context.Context, event *AuditEvent) error
+	Find(ctx context.Context, query EventQuery) (*EventPage, error)
+}
+
+// RedisStore keeps audit events in one Redis list per day, expired after
+// constants.AuditLogRetention. Lookups scan every day in range, so prefer a
+// database-backed Store where events are queried often.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a Redis-backed audit store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Save appends event to the list for the day it was logged
+func (s *RedisStore) Save(ctx context.Context, event *AuditEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	key := dailyEventsKey(event.Timestamp)
+	member := fmt.Sprintf("%s:%s", event.EventID, eventJSON)
+	if err := s.client.LPush(ctx, key, member).Err(); err != nil {
+		return fmt.Errorf("failed to store audit event: %w", err)
+	}
+	if err := s.client.Expire(ctx, key, constants.AuditLogRetention).Err(); err != nil {
+		return fmt.Errorf("failed to set audit log expiry: %w", err)
+	}
+	return nil
+}
+
+// Find walks the daily lists from newest to oldest, paging over the events
+// that match. Every match in range is counted for the total, and the walk
+// stops once it passes query.From. An event saved more than once is read from
+// its latest save.
+func (s *RedisStore) Find(ctx context.Context, query EventQuery) (*EventPage, error) {
+	newest := time.Now().UTC()
+	if !query.To.IsZero() && query.To.Before(newest) {
+		newest = query.To.UTC()
+	}
+	oldest := newest.Add(-constants.AuditLogRetention)
+	if !query.From.IsZero() && query.From.After(oldest) {
+		oldest = query.From.UTC()
+	}
+
+	page := &EventPage{Events: []*AuditEvent{}}
+	seen := make(map[uuid.UUID]bool)
+	for day := newest; !beforeDay(day, oldest); day = day.AddDate(0, 0, -1) {
+		members, err := s.client.LRange(ctx, dailyEventsKey(day), 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get audit events: %w", err)
+		}
+
+		for _, member := range members {
+			event, ok := decodeEventMember(member)
+			if !ok || seen[event.EventID] {
+				continue
+			}
+			seen[event.EventID] = true
+			if !query.From.IsZero() && event.Timestamp.Before(query.From) {
+				return page, nil
+			}
+			if !query.matches(event) {
+				continue
+			}
+			page.Total++
+			if page.Total > query.Offset && (query.Limit <= 0 || len(page.Events) < query.Limit) {
+				page.Events = append(page.Events, event)
+			}
+		}
+	}
+	return page, nil
+}
+
+// decodeEventMember parses a list member of the form "<event_id>:<event JSON>"
+func decodeEventMember(member string) (*AuditEvent, bool) {
+	_, eventJSON, ok := strings.Cut(member, ":")
+	if !ok {
+		return nil, false
+	}
+	var event AuditEvent
+	if err := json.Unmarshal([]byte(eventJSON), &event); err != nil {
+		return nil, false
+	}
+	return &event, true
+}
+
+// beforeDay reports whether day is on an earlier date than oldest
+func beforeDay(day, oldest time.Time) bool {
+	return day.Format("2006-01-02") < oldest.Format("2006-01-02")
+}
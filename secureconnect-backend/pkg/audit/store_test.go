@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-process Store
+type memoryStore struct {
+	events []*AuditEvent // oldest first
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{}
+}
+
+func (s *memoryStore) Save(ctx context.Context, event *AuditEvent) error {
+	for i, e := range s.events {
+		if e.EventID == event.EventID {
+			s.events[i] = event
+			return nil
+		}
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryStore) Find(ctx context.Context, query EventQuery) (*EventPage, error) {
+	page := &EventPage{}
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if e := s.events[i]; query.matches(e) {
+			page.Total++
+			if page.Total > query.Offset && (query.Limit <= 0 || len(page.Events) < query.Limit) {
+				page.Events = append(page.Events, e)
+			}
+		}
+	}
+	return page, nil
+}
+
+func TestAuditLogger_UsesConfiguredStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	al := NewAuditLogger(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	store := newMemoryStore()
+	al.SetStore(store)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	require.NoError(t, al.LogLoginSuccess(ctx, userID, "10.0.0.1", "agent"))
+	require.Len(t, store.events, 1)
+	assert.Empty(t, mr.Keys(), "nothing is written to Redis")
+
+	page, err := al.GetEvents(ctx, userID, EventQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, EventLoginSuccess, page.Events[0].EventType)
+}
+
+func TestRedisStore_Pagination(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	alice, bob := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+	save := func(userID uuid.UUID, eventType AuditEventType, at time.Time) {
+		require.NoError(t, store.Save(ctx, &AuditEvent{EventID: uuid.New(), UserID: &userID, EventType: eventType, Timestamp: at}))
+	}
+	save(alice, EventLoginSuccess, now.AddDate(0, 0, -2))
+	save(bob, EventLoginSuccess, now.AddDate(0, 0, -1))
+	save(alice, EventLogout, now.AddDate(0, 0, -1))
+	save(alice, EventLoginSuccess, now)
+
+	page, err := store.Find(ctx, EventQuery{UserID: alice, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 3)
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, EventLoginSuccess, page.Events[0].EventType, "newest first")
+	assert.Equal(t, EventLogout, page.Events[1].EventType)
+
+	page, err = store.Find(ctx, EventQuery{UserID: alice, Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, 3, page.Total, "total counts every match, not just the page")
+	assert.Equal(t, EventLogout, page.Events[0].EventType)
+
+	page, err = store.Find(ctx, EventQuery{EventType: EventLoginSuccess, From: now.AddDate(0, 0, -1).Add(-time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, alice, *page.Events[0].UserID)
+	assert.Equal(t, bob, *page.Events[1].UserID)
+}
+
+func TestRedisStore_Filters(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	save := func(ip string, success bool, at time.Time) {
+		require.NoError(t, store.Save(ctx, &AuditEvent{EventID: uuid.New(), EventType: EventLoginFailed, IPAddress: ip, Success: success, Timestamp: at}))
+	}
+	save("10.0.0.1", false, now.AddDate(0, 0, -3))
+	save("10.0.0.1", false, now.Add(-time.Hour))
+	save("10.0.0.2", false, now.Add(-time.Hour))
+	save("10.0.0.1", true, now)
+
+	failed := false
+	page, err := store.Find(ctx, EventQuery{IPAddress: "10.0.0.1", Success: &failed})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+
+	page, err = store.Find(ctx, EventQuery{IPAddress: "10.0.0.1", Success: &failed, From: now.AddDate(0, 0, -1)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+
+	page, err = store.Find(ctx, EventQuery{To: now.AddDate(0, 0, -2)})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, now.AddDate(0, 0, -3).Unix(), page.Events[0].Timestamp.Unix())
+}
+
+func TestRedisStore_ResaveReplacesEvent(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	event := &AuditEvent{EventID: uuid.New(), EventType: EventLoginSuccess, Timestamp: time.Now().UTC(), Sequence: 1}
+	require.NoError(t, store.Save(ctx, event))
+	event.Sequence = 2
+	require.NoError(t, store.Save(ctx, event))
+
+	page, err := store.Find(ctx, EventQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, int64(2), page.Events[0].Sequence, "the latest save wins")
+}
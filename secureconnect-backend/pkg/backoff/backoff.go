@@ -0,0 +1,146 @@
+// Package backoff computes exponential backoff delays and retries operations
+// with them.
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// random returns a random fraction in [0, 1); replaced in tests
+var random = rand.Float64
+
+// ErrExhausted is matched by the error Retry returns once MaxAttempts or
+// MaxElapsed are used up. The error also wraps the last failure.
+var ErrExhausted = errors.New("retries exhausted")
+
+// Config describes an exponential backoff
+type Config struct {
+	// Base is the delay before the first retry
+	Base time.Duration
+	// Max caps a single delay; 0 means no cap
+	Max time.Duration
+	// Factor multiplies the delay after each retry; 0 or less means 2
+	Factor float64
+	// Jitter waits a random delay between zero and the computed one ("full
+	// jitter"), so clients failing together do not retry in lockstep
+	Jitter bool
+	// MaxAttempts caps the calls made by Retry, including the first one;
+	// 0 or less retries until the context is done
+	MaxAttempts int
+	// MaxElapsed caps the total time Retry spends; 0 means no limit
+	MaxElapsed time.Duration
+}
+
+// Delay returns the delay before the given retry (retry >= 1) without
+// jitter: Base * Factor^(retry-1), capped at Max
+func (c Config) Delay(retry int) time.Duration {
+	if retry < 1 || c.Base <= 0 {
+		return 0
+	}
+	factor := c.Factor
+	if factor <= 0 {
+		factor = 2
+	}
+	delay := float64(c.Base) * math.Pow(factor, float64(retry-1))
+	if c.Max > 0 && delay > float64(c.Max) {
+		return c.Max
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// Next returns the delay to wait before the given retry, jittered when
+// Jitter is set
+func (c Config) Next(retry int) time.Duration {
+	delay := c.Delay(retry)
+	if c.Jitter {
+		delay = time.Duration(random() * float64(delay))
+	}
+	return delay
+}
+
+// permanentError stops Retry; see Permanent
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Retry returns it at once instead of retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retry calls fn until it succeeds or returns a Permanent error, the
+// attempts or time budget are used up, or ctx is done. A permanent error is
+// returned unwrapped. Running out of attempts returns an error matching
+// ErrExhausted, and a done ctx one matching ctx.Err(); both also wrap the
+// last failure.
+func Retry(ctx context.Context, cfg Config, fn func(ctx context.Context) error) error {
+	return RetryNotify(ctx, cfg, fn, nil)
+}
+
+// RetryNotify is Retry that calls notify with each failure that is about to
+// be retried, the number of the upcoming retry and the delay before it
+func RetryNotify(ctx context.Context, cfg Config, fn func(ctx context.Context) error, notify func(err error, retry int, delay time.Duration)) error {
+	start := time.Now()
+	var lastErr error
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return aborted(err, lastErr)
+		}
+
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		lastErr = err
+
+		if cfg.MaxAttempts > 0 && attempt >= cfg.MaxAttempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrExhausted, attempt, err)
+		}
+		delay := cfg.Next(attempt)
+		if cfg.MaxElapsed > 0 {
+			remaining := cfg.MaxElapsed - time.Since(start)
+			if remaining <= 0 {
+				return fmt.Errorf("%w within %v (%d attempts): %w", ErrExhausted, cfg.MaxElapsed, attempt, err)
+			}
+			delay = min(delay, remaining)
+		}
+		if notify != nil {
+			notify(err, attempt, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return aborted(ctx.Err(), lastErr)
+		case <-timer.C:
+		}
+	}
+}
+
+// aborted is the error returned when ctx ends the retries
+func aborted(ctxErr, lastErr error) error {
+	if lastErr == nil {
+		return ctxErr
+	}
+	return fmt.Errorf("%w: %w", ctxErr, lastErr)
+}
@@ -0,0 +1,142 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelay(t *testing.T) {
+	cfg := Config{Base: time.Second, Max: 30 * time.Second}
+
+	assert.Equal(t, time.Duration(0), cfg.Delay(0))
+	assert.Equal(t, time.Second, cfg.Delay(1))
+	assert.Equal(t, 2*time.Second, cfg.Delay(2))
+	assert.Equal(t, 16*time.Second, cfg.Delay(5))
+	assert.Equal(t, 30*time.Second, cfg.Delay(6))
+	assert.Equal(t, 30*time.Second, cfg.Delay(1000), "huge retries stay capped")
+
+	cfg = Config{Base: 100 * time.Millisecond, Factor: 3}
+	assert.Equal(t, 900*time.Millisecond, cfg.Delay(3))
+	assert.Equal(t, time.Duration(1<<63-1), cfg.Delay(1000), "no cap saturates instead of overflowing")
+}
+
+func TestNext_JitterBounds(t *testing.T) {
+	cfg := Config{Base: time.Second, Max: 30 * time.Second, Jitter: true}
+
+	for _, retry := range []int{1, 3, 6} {
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 50; i++ {
+			delay := cfg.Next(retry)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, cfg.Delay(retry))
+			seen[delay] = true
+		}
+		assert.Greater(t, len(seen), 1, "retry %d delays should be randomized", retry)
+	}
+
+	// The bounds are reached at the extremes of the random source
+	defer func(orig func() float64) { random = orig }(random)
+	random = func() float64 { return 0 }
+	assert.Equal(t, time.Duration(0), cfg.Next(3))
+	random = func() float64 { return 0.5 }
+	assert.Equal(t, 2*time.Second, cfg.Next(3))
+
+	cfg.Jitter = false
+	assert.Equal(t, 4*time.Second, cfg.Next(3), "without jitter the full delay is used")
+}
+
+func TestRetry_SucceedsAfterFailures(t *testing.T) {
+	cfg := Config{Base: time.Millisecond, MaxAttempts: 5}
+	var retries []int
+
+	calls := 0
+	err := RetryNotify(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}, func(err error, retry int, delay time.Duration) {
+		retries = append(retries, retry)
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []int{1, 2}, retries)
+}
+
+func TestRetry_StopsAtMaxAttempts(t *testing.T) {
+	cfg := Config{Base: time.Millisecond, MaxAttempts: 3}
+	failure := errors.New("unavailable")
+
+	calls := 0
+	err := Retry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_StopsAtMaxElapsed(t *testing.T) {
+	cfg := Config{Base: 20 * time.Millisecond, Max: 20 * time.Millisecond, MaxElapsed: 50 * time.Millisecond}
+
+	calls := 0
+	start := time.Now()
+	err := Retry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		return errors.New("unavailable")
+	})
+
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.Less(t, calls, 10)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetry_PermanentErrorStops(t *testing.T) {
+	failure := errors.New("bad request")
+
+	calls := 0
+	err := Retry(context.Background(), Config{Base: time.Millisecond, MaxAttempts: 5}, func(ctx context.Context) error {
+		calls++
+		return Permanent(failure)
+	})
+
+	assert.Equal(t, failure, err, "the permanent error is returned unwrapped")
+	assert.NotErrorIs(t, err, ErrExhausted)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_ContextCancellationAborts(t *testing.T) {
+	cfg := Config{Base: time.Hour}
+	failure := errors.New("unavailable")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	start := time.Now()
+	err := Retry(ctx, cfg, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return failure
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, calls)
+	assert.Less(t, time.Since(start), time.Second, "the hour-long backoff is abandoned")
+
+	calls = 0
+	err = Retry(ctx, cfg, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls, "nothing is attempted once the context is done")
+}
@@ -3,22 +3,36 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"secureconnect-backend/pkg/constants"
+	"secureconnect-backend/pkg/password"
+	"secureconnect-backend/pkg/tlsconfig"
+	"secureconnect-backend/pkg/urlguard"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	Redis     RedisConfig
-	Cassandra CassandraConfig
-	MinIO     MinIOConfig
-	SMTP      SMTPConfig
-	JWT       JWTConfig
-	Log       LogConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Redis        RedisConfig
+	Cassandra    CassandraConfig
+	MinIO        MinIOConfig
+	SMTP         SMTPConfig
+	JWT          JWTConfig
+	Auth         AuthConfig
+	Conversation ConversationConfig
+	Video        VideoConfig
+	Audit        AuditConfig
+	Client       ClientConfig
+	JSON         JSONConfig
+	Gateway      GatewayConfig
+	Log          LogConfig
+	TLS          TLSConfig
 }
 
 // ServerConfig holds server configuration
@@ -26,14 +40,24 @@ type ServerConfig struct {
 	Port        int
 	Environment string // development, staging, production
 	ServiceName string
+	// ShutdownTimeout bounds how long the server waits for in-flight requests on shutdown
+	ShutdownTimeout time.Duration
+	// AppURL is the public base URL used in links sent by email (verification, reset, welcome)
+	AppURL string
+	// TrustedURLHosts are the hosts that links in emails and redirect targets may
+	// point to. Defaults to the host of AppURL.
+	TrustedURLHosts []string
 }
 
+// defaultAppURL is used outside production when APP_URL is not set
+const defaultAppURL = "http://localhost:9090"
+
 // DatabaseConfig holds CockroachDB configuration
 type DatabaseConfig struct {
 	Host     string
 	Port     int
 	User     string
-	Password string
+	Password string `log:"secret"`
 	Database string
 	SSLMode  string
 	MaxConns int
@@ -44,7 +68,7 @@ type DatabaseConfig struct {
 type RedisConfig struct {
 	Host     string
 	Port     int
-	Password string
+	Password string `log:"secret"`
 	DB       int
 	PoolSize int
 	Timeout  time.Duration
@@ -63,24 +87,155 @@ type SMTPConfig struct {
 	Host     string
 	Port     int
 	Username string
-	Password string
+	Password string `log:"secret"`
 	From     string
 }
 
 // MinIOConfig holds MinIO configuration
 type MinIOConfig struct {
 	Endpoint  string
-	AccessKey string
-	SecretKey string
+	AccessKey string `log:"secret"`
+	SecretKey string `log:"secret"`
 	UseSSL    bool
 	Bucket    string
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret             string
+	Secret             string `log:"secret"`
 	AccessTokenExpiry  time.Duration
 	RefreshTokenExpiry time.Duration
+
+	// Algorithm is HS256 (shared secret) or RS256 (key pair)
+	Algorithm string
+	// PrivateKeyFile is the RS256 signing key, needed by auth-service only
+	PrivateKeyFile string
+	// PublicKeyFile is the RS256 verification key for services that do not sign
+	PublicKeyFile string
+	// KeyID names the signing key in the kid header of issued tokens
+	KeyID string
+	// PreviousSecrets are retired HS256 keys ("kid:secret") still accepted during a rotation
+	PreviousSecrets []string `log:"secret"`
+	// PreviousPublicKeyFiles are retired RS256 public keys still accepted during a rotation
+	PreviousPublicKeyFiles []string
+}
+
+// AuthConfig holds registration policy configuration
+type AuthConfig struct {
+	// AllowedEmailDomains restricts registration to these domains; empty allows all
+	AllowedEmailDomains []string
+	// BlockDisposableEmails rejects registrations from known throwaway email providers
+	BlockDisposableEmails bool
+	// NormalizeGmailAliases folds Gmail dot and plus-tag variants into one address
+	NormalizeGmailAliases bool
+	// TokenCleanupInterval is how often used and expired email tokens are deleted
+	TokenCleanupInterval time.Duration
+	// TokenRetention is how long used and expired email tokens are kept
+	TokenRetention time.Duration
+	// PasswordAlgorithm hashes new passwords; "bcrypt" or "argon2id".
+	// Hashes made with the other algorithm still verify and are upgraded on login.
+	PasswordAlgorithm string
+	// BcryptCost is the work factor for new bcrypt hashes; 0 uses bcrypt's default
+	BcryptCost int
+	// PasswordMinCharClasses is how many of lowercase, uppercase, digits and
+	// symbols a new password must mix
+	PasswordMinCharClasses int
+	// PasswordDenylistFile lists common passwords to reject; empty uses the built-in list
+	PasswordDenylistFile string
+	// TOTPIssuer labels accounts in authenticator apps
+	TOTPIssuer string
+	// LockoutEmailEnabled emails users when failed logins lock their account
+	LockoutEmailEnabled bool
+	// SessionWindow is how long a refresh token lives after each use; 0 keeps
+	// the fixed JWT_REFRESH_EXPIRY lifetime
+	SessionWindow time.Duration
+	// RememberMeWindow replaces SessionWindow for logins that chose "remember me"
+	RememberMeWindow time.Duration
+	// SessionAbsoluteMax caps a sliding session's lifetime from login
+	SessionAbsoluteMax time.Duration
+}
+
+// ConversationConfig holds organization-wide conversation policy
+type ConversationConfig struct {
+	// E2EEDefault is whether new conversations use end-to-end encryption unless they choose otherwise
+	E2EEDefault bool
+	// AllowE2EEDowngrade lets conversation admins turn E2EE off once it is on
+	AllowE2EEDowngrade bool
+	// MembershipCacheTTL bounds how long a cached membership check is trusted
+	MembershipCacheTTL time.Duration
+	// EventStreamEnabled routes conversation events through a replayable Redis Stream instead of pub/sub
+	EventStreamEnabled bool
+	// EventStreamMaxLen caps the events kept per conversation stream
+	EventStreamMaxLen int
+	// EventStreamTTL expires a conversation stream with no new events
+	EventStreamTTL time.Duration
+	// InitialMessageEnabled lets conversations be created together with their first message
+	InitialMessageEnabled bool
+	// ChatServiceURL is where the auth service sends initial messages
+	ChatServiceURL string
+	// MaxPerUser caps how many conversations a user can belong to; 0 disables the limit
+	MaxPerUser int
+}
+
+// VideoConfig holds call limits
+type VideoConfig struct {
+	// MaxActiveCallsPerUser caps the ringing or active calls a user can have
+	// started at once; 0 disables the limit
+	MaxActiveCallsPerUser int
+	// ActiveCallTTL is how long a call counts against the limit if it is never ended
+	ActiveCallTTL time.Duration
+	// RingTimeout ends calls nobody joins in time; 0 rings until the call is ended
+	RingTimeout time.Duration
+}
+
+// AuditConfig holds audit log configuration
+type AuditConfig struct {
+	// Store is where events are kept and queried: "cockroach" or "redis"
+	Store string
+	// HashChain links every audit event to the hash of the previous one
+	HashChain bool
+	// HMACKey signs chained events when set
+	HMACKey string `log:"secret"`
+	// GeoIPCityDBPath and GeoIPASNDBPath point at MaxMind databases used to enrich login events
+	GeoIPCityDBPath string
+	GeoIPASNDBPath  string
+}
+
+// ClientConfig holds the public runtime settings served to apps at GET /v1/config.
+// Nothing in it may be secret.
+type ClientConfig struct {
+	// MinClientVersion is the oldest app version allowed to connect; older apps must upgrade.
+	// The gateway rereads it on SIGHUP, so it can be kept in MIN_CLIENT_VERSION_FILE.
+	MinClientVersion string
+	// UpgradeURL is where outdated apps are sent to upgrade
+	UpgradeURL string
+	// AllowMissingClientVersion lets requests without X-Client-Version through
+	AllowMissingClientVersion bool
+	// FeatureFlags lists the features enabled for clients
+	FeatureFlags []string
+	// ICEServers lists public STUN/TURN URLs; TURN credentials are issued per call
+	ICEServers []string
+	// MaintenanceMode tells clients to show MaintenanceMessage instead of connecting
+	MaintenanceMode    bool
+	MaintenanceMessage string
+}
+
+// JSONConfig bounds the JSON request bodies handlers accept
+type JSONConfig struct {
+	MaxBodyBytes int64
+	MaxDepth     int
+	MaxArrayLen  int
+	// DisallowUnknownFields rejects bodies with fields the endpoint does not define
+	DisallowUnknownFields bool
+}
+
+// GatewayConfig holds API gateway configuration
+type GatewayConfig struct {
+	// RateLimitBypassCIDRs are source networks of internal callers that are
+	// never rate limited. Matched against the trusted-proxy-resolved client IP.
+	RateLimitBypassCIDRs []string
+	// InternalAPIKey lets callers sending it in X-Internal-API-Key skip rate limiting
+	InternalAPIKey string `log:"secret"`
 }
 
 // LogConfig holds logging configuration
@@ -89,15 +244,33 @@ type LogConfig struct {
 	Format   string // json, text
 	Output   string // stdout, file
 	FilePath string
+	// AccessLevel is the level of access log entries for successful requests
+	AccessLevel string
+}
+
+// TLSConfig holds the TLS policy for outbound connections such as SMTP and webhooks
+type TLSConfig struct {
+	// MinVersion is the lowest TLS version accepted from peers: 1.2 or 1.3
+	MinVersion string
+	// CipherSuites are the allowed TLS 1.2 cipher suites; empty uses Go's secure defaults
+	CipherSuites []string
+}
+
+// Policy returns the policy for building TLS client configs
+func (c TLSConfig) Policy() tlsconfig.Policy {
+	return tlsconfig.Policy{MinVersion: c.MinVersion, CipherSuites: c.CipherSuites}
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:        getEnvAsInt("PORT", 8080),
-			Environment: getEnv("ENV", "development"),
-			ServiceName: getEnv("SERVICE_NAME", "secureconnect"),
+			Port:            getEnvAsInt("PORT", 8080),
+			Environment:     getEnv("ENV", "development"),
+			ServiceName:     getEnv("SERVICE_NAME", "secureconnect"),
+			ShutdownTimeout: getEnvAsDuration("SHUTDOWN_TIMEOUT", constants.GracefulShutdownTimeout),
+			AppURL:          strings.TrimRight(getEnv("APP_URL", ""), "/"),
+			TrustedURLHosts: getEnvAsSlice("TRUSTED_URL_HOSTS", nil),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -138,18 +311,96 @@ func Load() (*Config, error) {
 			Bucket:    getEnv("MINIO_BUCKET", "secureconnect"),
 		},
 		JWT: JWTConfig{
-			Secret:             getEnv("JWT_SECRET", ""),
-			AccessTokenExpiry:  time.Duration(getEnvAsInt("JWT_ACCESS_EXPIRY", 15)) * time.Minute,
-			RefreshTokenExpiry: time.Duration(getEnvAsInt("JWT_REFRESH_EXPIRY", 720)) * time.Hour,
+			Secret:                 getEnv("JWT_SECRET", ""),
+			AccessTokenExpiry:      time.Duration(getEnvAsInt("JWT_ACCESS_EXPIRY", 15)) * time.Minute,
+			RefreshTokenExpiry:     time.Duration(getEnvAsInt("JWT_REFRESH_EXPIRY", 720)) * time.Hour,
+			Algorithm:              getEnv("JWT_ALGORITHM", "HS256"),
+			PrivateKeyFile:         getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			PublicKeyFile:          getEnv("JWT_PUBLIC_KEY_FILE", ""),
+			KeyID:                  getEnv("JWT_KEY_ID", ""),
+			PreviousSecrets:        getEnvAsSlice("JWT_PREVIOUS_SECRETS", nil),
+			PreviousPublicKeyFiles: getEnvAsSlice("JWT_PREVIOUS_PUBLIC_KEY_FILES", nil),
+		},
+		Auth: AuthConfig{
+			AllowedEmailDomains:    getEnvAsSlice("ALLOWED_EMAIL_DOMAINS", nil),
+			BlockDisposableEmails:  getEnvAsBool("BLOCK_DISPOSABLE_EMAILS", false),
+			NormalizeGmailAliases:  getEnvAsBool("NORMALIZE_GMAIL_ALIASES", false),
+			TokenCleanupInterval:   getEnvAsDuration("EMAIL_TOKEN_CLEANUP_INTERVAL", time.Hour),
+			TokenRetention:         getEnvAsDuration("EMAIL_TOKEN_RETENTION", 24*time.Hour),
+			PasswordAlgorithm:      getEnv("AUTH_PASSWORD_ALGO", password.AlgorithmBcrypt),
+			BcryptCost:             getEnvAsInt("AUTH_BCRYPT_COST", 0),
+			PasswordMinCharClasses: getEnvAsInt("AUTH_PASSWORD_MIN_CHAR_CLASSES", 2),
+			PasswordDenylistFile:   getEnv("AUTH_PASSWORD_DENYLIST_FILE", ""),
+			TOTPIssuer:             getEnv("AUTH_TOTP_ISSUER", "SecureConnect"),
+			LockoutEmailEnabled:    getEnvAsBool("AUTH_LOCKOUT_EMAIL_ENABLED", true),
+			SessionWindow:          getEnvAsDuration("AUTH_SESSION_WINDOW", 0),
+			RememberMeWindow:       getEnvAsDuration("AUTH_REMEMBER_ME_WINDOW", 30*24*time.Hour),
+			SessionAbsoluteMax:     getEnvAsDuration("AUTH_SESSION_ABSOLUTE_MAX", 90*24*time.Hour),
+		},
+		Conversation: ConversationConfig{
+			E2EEDefault:           getEnvAsBool("E2EE_DEFAULT_ENABLED", true),
+			AllowE2EEDowngrade:    getEnvAsBool("E2EE_ALLOW_DOWNGRADE", true),
+			MembershipCacheTTL:    getEnvAsDuration("MEMBERSHIP_CACHE_TTL", 30*time.Second),
+			EventStreamEnabled:    getEnvAsBool("CHAT_EVENT_STREAM_ENABLED", false),
+			EventStreamMaxLen:     getEnvAsInt("CHAT_EVENT_STREAM_MAX_LEN", 10000),
+			EventStreamTTL:        getEnvAsDuration("CHAT_EVENT_STREAM_TTL", 7*24*time.Hour),
+			InitialMessageEnabled: getEnvAsBool("CONVERSATION_INITIAL_MESSAGE_ENABLED", true),
+			ChatServiceURL:        getEnv("CHAT_SERVICE_URL", "http://localhost:8082"),
+			MaxPerUser:            getEnvAsInt("CONVERSATION_MAX_PER_USER", 0),
+		},
+		Video: VideoConfig{
+			MaxActiveCallsPerUser: getEnvAsInt("VIDEO_MAX_ACTIVE_CALLS_PER_USER", 3),
+			ActiveCallTTL:         getEnvAsDuration("VIDEO_ACTIVE_CALL_TTL", 4*time.Hour),
+			RingTimeout:           getEnvAsDuration("VIDEO_RING_TIMEOUT", 60*time.Second),
+		},
+		Audit: AuditConfig{
+			Store:           getEnv("AUDIT_STORE", "redis"),
+			HashChain:       getEnvAsBool("AUDIT_HASH_CHAIN", false),
+			HMACKey:         getEnvOrFile("AUDIT_HMAC_KEY", ""),
+			GeoIPCityDBPath: getEnv("GEOIP_CITY_DB_PATH", ""),
+			GeoIPASNDBPath:  getEnv("GEOIP_ASN_DB_PATH", ""),
+		},
+		Client: ClientConfig{
+			MinClientVersion:          getEnvOrFile("MIN_CLIENT_VERSION", "1.0.0"),
+			UpgradeURL:                getEnv("CLIENT_UPGRADE_URL", ""),
+			AllowMissingClientVersion: getEnvAsBool("CLIENT_VERSION_ALLOW_MISSING", true),
+			FeatureFlags:              getEnvAsSlice("FEATURE_FLAGS", nil),
+			ICEServers:                getEnvAsSlice("ICE_SERVER_URLS", []string{"stun:stun.l.google.com:19302"}),
+			MaintenanceMode:           getEnvAsBool("MAINTENANCE_MODE", false),
+			MaintenanceMessage:        getEnv("MAINTENANCE_MESSAGE", ""),
+		},
+		JSON: JSONConfig{
+			MaxBodyBytes:          int64(getEnvAsInt("JSON_MAX_BODY_BYTES", 1<<20)),
+			MaxDepth:              getEnvAsInt("JSON_MAX_DEPTH", 32),
+			MaxArrayLen:           getEnvAsInt("JSON_MAX_ARRAY_LENGTH", 1000),
+			DisallowUnknownFields: getEnvAsBool("JSON_DISALLOW_UNKNOWN_FIELDS", false),
+		},
+		Gateway: GatewayConfig{
+			RateLimitBypassCIDRs: getEnvAsSlice("GATEWAY_RATE_LIMIT_BYPASS_CIDRS", nil),
+			InternalAPIKey:       getEnvOrFile("GATEWAY_INTERNAL_API_KEY", ""),
 		},
 		Log: LogConfig{
-			Level:    getEnv("LOG_LEVEL", "info"),
-			Format:   getEnv("LOG_FORMAT", "json"),
-			Output:   getEnv("LOG_OUTPUT", "stdout"),
-			FilePath: getEnv("LOG_FILE_PATH", "/logs/app.log"),
+			Level:       getEnv("LOG_LEVEL", "info"),
+			Format:      getEnv("LOG_FORMAT", "json"),
+			Output:      getEnv("LOG_OUTPUT", "stdout"),
+			FilePath:    getEnv("LOG_FILE_PATH", "/logs/app.log"),
+			AccessLevel: getEnv("ACCESS_LOG_LEVEL", "info"),
+		},
+		TLS: TLSConfig{
+			MinVersion:   getEnv("TLS_MIN_VERSION", tlsconfig.DefaultMinVersion),
+			CipherSuites: getEnvAsSlice("TLS_CIPHER_SUITES", nil),
 		},
 	}
 
+	if cfg.Server.AppURL == "" && cfg.Server.Environment != "production" {
+		cfg.Server.AppURL = defaultAppURL
+	}
+	if len(cfg.Server.TrustedURLHosts) == 0 && cfg.Server.AppURL != "" {
+		if u, err := url.Parse(cfg.Server.AppURL); err == nil && u.Host != "" {
+			cfg.Server.TrustedURLHosts = []string{u.Host}
+		}
+	}
+
 	// Validate critical configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -160,24 +411,78 @@ func Load() (*Config, error) {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	// Validate JWT secret in production
-	if c.Server.Environment == "production" {
-		if c.JWT.Secret == "" {
-			return fmt.Errorf("JWT_SECRET must be set in production")
+	switch c.JWT.Algorithm {
+	case "HS256":
+		// Validate JWT secret in production
+		if c.Server.Environment == "production" {
+			if c.JWT.Secret == "" {
+				return fmt.Errorf("JWT_SECRET must be set in production")
+			}
+			if len(c.JWT.Secret) < 32 {
+				return fmt.Errorf("JWT_SECRET must be at least 32 characters in production")
+			}
 		}
-		if len(c.JWT.Secret) < 32 {
-			return fmt.Errorf("JWT_SECRET must be at least 32 characters in production")
+	case "RS256":
+		if c.JWT.PrivateKeyFile == "" && c.JWT.PublicKeyFile == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE or JWT_PUBLIC_KEY_FILE must be set when JWT_ALGORITHM is RS256")
+		}
+	default:
+		return fmt.Errorf("JWT_ALGORITHM must be HS256 or RS256, got %q", c.JWT.Algorithm)
+	}
+
+	// Links in emails and redirects are built from APP_URL, so production
+	// never falls back to localhost
+	if c.Server.AppURL == "" && c.Server.Environment == "production" {
+		return fmt.Errorf("APP_URL must be set in production")
+	}
+	if c.Server.AppURL != "" {
+		if err := validateAppURL(c.Server.AppURL, c.Server.Environment == "production"); err != nil {
+			return err
 		}
+		if err := urlguard.NewAllowlist(c.Server.TrustedURLHosts).Validate(c.Server.AppURL); err != nil {
+			return fmt.Errorf("APP_URL host must be listed in TRUSTED_URL_HOSTS: %w", err)
+		}
+	}
+
+	if err := password.ValidateBcryptCost(c.Auth.BcryptCost); err != nil {
+		return fmt.Errorf("AUTH_BCRYPT_COST: %w", err)
+	}
+	if _, err := password.NewHasher(c.Auth.PasswordAlgorithm, c.Auth.BcryptCost); err != nil {
+		return fmt.Errorf("AUTH_PASSWORD_ALGO: %w", err)
+	}
+	if c.Auth.SessionWindow > 0 && (c.Auth.RememberMeWindow < c.Auth.SessionWindow || c.Auth.SessionAbsoluteMax < c.Auth.RememberMeWindow) {
+		return fmt.Errorf("AUTH_SESSION_WINDOW <= AUTH_REMEMBER_ME_WINDOW <= AUTH_SESSION_ABSOLUTE_MAX must hold when sliding sessions are enabled")
+	}
+	if _, err := tlsconfig.New(c.TLS.Policy()); err != nil {
+		return fmt.Errorf("TLS_MIN_VERSION/TLS_CIPHER_SUITES: %w", err)
+	}
+	if c.Audit.Store != "cockroach" && c.Audit.Store != "redis" {
+		return fmt.Errorf("AUDIT_STORE must be cockroach or redis, got %q", c.Audit.Store)
 	}
 
 	// Warn about weak secrets even in development
-	if c.JWT.Secret == "" || c.JWT.Secret == "super-secret-key-change-in-production" {
+	if c.JWT.Algorithm == "HS256" && (c.JWT.Secret == "" || c.JWT.Secret == "super-secret-key-change-in-production") {
 		fmt.Println("⚠️  WARNING: Using default/weak JWT secret. This is INSECURE for production!")
 	}
 
 	return nil
 }
 
+// validateAppURL checks that appURL is an absolute URL, using https in production
+func validateAppURL(appURL string, production bool) error {
+	u, err := url.Parse(appURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("APP_URL must be an absolute URL, got %q", appURL)
+	}
+	if production && u.Scheme != "https" {
+		return fmt.Errorf("APP_URL must use https in production")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("APP_URL must use http or https, got %q", u.Scheme)
+	}
+	return nil
+}
+
 // Helper functions
 
 func getEnv(key, defaultValue string) string {
@@ -225,6 +530,18 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_ProductionAppURL(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv("APP_URL", "")
+		_, err := Load()
+		assert.ErrorContains(t, err, "APP_URL must be set", "production must not fall back to localhost")
+	})
+
+	t.Run("plain http", func(t *testing.T) {
+		t.Setenv("APP_URL", "http://app.example.com")
+		_, err := Load()
+		assert.ErrorContains(t, err, "https")
+	})
+
+	t.Run("relative", func(t *testing.T) {
+		t.Setenv("APP_URL", "app.example.com")
+		_, err := Load()
+		assert.ErrorContains(t, err, "absolute")
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("APP_URL", "https://app.example.com/")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "https://app.example.com", cfg.Server.AppURL)
+	})
+}
+
+func TestLoad_DevelopmentDefaultsAppURL(t *testing.T) {
+	t.Setenv("ENV", "development")
+	t.Setenv("APP_URL", "")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, defaultAppURL, cfg.Server.AppURL)
+}
+
+func TestLoad_TrustedURLHosts(t *testing.T) {
+	t.Setenv("ENV", "development")
+
+	t.Run("defaults to the app URL host", func(t *testing.T) {
+		t.Setenv("APP_URL", "https://app.example.com")
+		t.Setenv("TRUSTED_URL_HOSTS", "")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.example.com"}, cfg.Server.TrustedURLHosts)
+	})
+
+	t.Run("app URL on the allowlist", func(t *testing.T) {
+		t.Setenv("APP_URL", "https://app.example.com")
+		t.Setenv("TRUSTED_URL_HOSTS", "app.example.com,www.example.com")
+		_, err := Load()
+		assert.NoError(t, err)
+	})
+
+	t.Run("app URL off the allowlist", func(t *testing.T) {
+		t.Setenv("APP_URL", "https://phish.example.net")
+		t.Setenv("TRUSTED_URL_HOSTS", "app.example.com")
+		_, err := Load()
+		assert.ErrorContains(t, err, "TRUSTED_URL_HOSTS")
+	})
+}
+
+func TestLoad_TLSPolicy(t *testing.T) {
+	t.Setenv("ENV", "development")
+
+	t.Run("defaults to TLS 1.2", func(t *testing.T) {
+		t.Setenv("TLS_MIN_VERSION", "")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "1.2", cfg.TLS.MinVersion)
+	})
+
+	t.Run("downgrade rejected", func(t *testing.T) {
+		t.Setenv("TLS_MIN_VERSION", "1.1")
+		_, err := Load()
+		assert.ErrorContains(t, err, "TLS_MIN_VERSION")
+	})
+
+	t.Run("insecure cipher suite rejected", func(t *testing.T) {
+		t.Setenv("TLS_CIPHER_SUITES", "TLS_RSA_WITH_RC4_128_SHA")
+		_, err := Load()
+		assert.ErrorContains(t, err, "TLS_CIPHER_SUITES")
+	})
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// redacted replaces the value of secret settings in logs
+const redacted = "[REDACTED]"
+
+// LogEffective logs every setting in cfg once at startup so misconfiguration
+// is visible in the logs. Fields tagged `log:"secret"` are only reported as
+// redacted or empty; tag any new credential the same way.
+func LogEffective(cfg *Config) {
+	logger.Info("Effective configuration", effectiveFields(cfg)...)
+}
+
+// effectiveFields flattens cfg into fields named section.setting
+func effectiveFields(cfg *Config) []zap.Field {
+	var fields []zap.Field
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+		prefix := snakeCase(root.Type().Field(i).Name)
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			key := prefix + "." + snakeCase(field.Name)
+			fields = append(fields, zap.Any(key, redact(field, section.Field(j))))
+		}
+	}
+	return fields
+}
+
+// redact returns the value to log for a config field: secrets become a
+// placeholder when set and stay empty when unset
+func redact(field reflect.StructField, value reflect.Value) interface{} {
+	if field.Tag.Get("log") != "secret" {
+		return value.Interface()
+	}
+	if value.IsZero() {
+		return ""
+	}
+	return redacted
+}
+
+// snakeCase converts a Go field name such as HMACKey or ShutdownTimeout to hmac_key or shutdown_timeout
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			startsWord := i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+			if startsWord {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
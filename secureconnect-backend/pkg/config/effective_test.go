@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+func TestLogEffective_RedactsSecrets(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = previous })
+
+	secrets := []string{"jwt-secret-value", "db-password", "redis-password", "smtp-password", "minio-access", "minio-secret", "hmac-key"}
+	cfg := &Config{
+		Server:   ServerConfig{Port: 8080, ShutdownTimeout: 30 * time.Second},
+		Database: DatabaseConfig{Host: "crdb", Password: secrets[1]},
+		Redis:    RedisConfig{Host: "redis", Password: secrets[2], PoolSize: 10},
+		SMTP:     SMTPConfig{Username: "mailer", Password: secrets[3]},
+		MinIO:    MinIOConfig{AccessKey: secrets[4], SecretKey: secrets[5]},
+		JWT:      JWTConfig{Secret: secrets[0], AccessTokenExpiry: 15 * time.Minute},
+		Audit:    AuditConfig{HashChain: true, HMACKey: secrets[6]},
+		Client:   ClientConfig{FeatureFlags: []string{"polls"}},
+	}
+
+	LogEffective(cfg)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+
+	for key, value := range fields {
+		for _, secret := range secrets {
+			assert.NotContains(t, fmt.Sprint(value), secret, key)
+		}
+	}
+	assert.Equal(t, redacted, fields["jwt.secret"])
+	assert.Equal(t, redacted, fields["database.password"])
+	assert.Equal(t, redacted, fields["audit.hmac_key"])
+
+	assert.EqualValues(t, 8080, fields["server.port"])
+	assert.Equal(t, 30*time.Second, fields["server.shutdown_timeout"])
+	assert.EqualValues(t, 10, fields["redis.pool_size"])
+	assert.Equal(t, "mailer", fields["smtp.username"])
+	assert.Equal(t, true, fields["audit.hash_chain"])
+}
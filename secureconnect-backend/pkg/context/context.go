@@ -57,3 +57,11 @@ func WithTimeout(parent context.Context, timeout time.Duration) (context.Context
 func WithDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
 	return context.WithDeadline(parent, deadline)
 }
+
+// Detached creates a context for work that outlives parent, such as a
+// publish started in a goroutine before a handler returns. It keeps parent's
+// values but not its cancellation, and is cancelled after timeout so the
+// work cannot hang forever.
+func Detached(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(parent), timeout)
+}
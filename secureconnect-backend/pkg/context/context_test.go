@@ -0,0 +1,28 @@
+package context
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestDetached(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request-id"))
+	ctx, cancel := Detached(parent, 50*time.Millisecond)
+	defer cancel()
+
+	cancelParent()
+	assert.NoError(t, ctx.Err(), "cancelling the request does not cancel detached work")
+	assert.Equal(t, "request-id", ctx.Value(ctxKey{}))
+
+	select {
+	case <-ctx.Done():
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("detached context was not bounded by its timeout")
+	}
+}
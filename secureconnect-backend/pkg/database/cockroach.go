@@ -6,6 +6,7 @@ import (
 	"os"
 	"secureconnect-backend/pkg/constants"
 	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -116,3 +117,19 @@ func getEnvPortOrDefault(key string, defaultValue int) int {
 	}
 	return value
 }
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	return getEnvPortOrDefault(key, defaultValue)
+}
+
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"secureconnect-backend/pkg/backoff"
+)
+
+// RetryConfig holds the exponential backoff settings used when connecting
+// to a backing store at startup
+type RetryConfig struct {
+	MaxRetries int           // Total connection attempts, including the first one
+	BaseDelay  time.Duration // Largest delay before the second attempt, doubled for each subsequent attempt
+	MaxDelay   time.Duration // Upper bound for a single delay
+	MaxElapsed time.Duration // Upper bound for the total time spent retrying; 0 means no limit
+}
+
+// DefaultRetryConfig returns the retry settings historically used by the video service
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: 5,
+		BaseDelay:  1 * time.Second,
+		MaxDelay:   30 * time.Second,
+		MaxElapsed: 2 * time.Minute,
+	}
+}
+
+// RetryConfigFromEnv builds a RetryConfig from environment variables,
+// falling back to DefaultRetryConfig for anything unset or invalid
+//
+//	DB_CONNECT_MAX_RETRIES  (e.g. 5)
+//	DB_CONNECT_BASE_DELAY   (e.g. 1s)
+//	DB_CONNECT_MAX_DELAY    (e.g. 30s)
+//	DB_CONNECT_MAX_ELAPSED  (e.g. 2m)
+func RetryConfigFromEnv() RetryConfig {
+	defaults := DefaultRetryConfig()
+	return RetryConfig{
+		MaxRetries: getEnvIntOrDefault("DB_CONNECT_MAX_RETRIES", defaults.MaxRetries),
+		BaseDelay:  getEnvDurationOrDefault("DB_CONNECT_BASE_DELAY", defaults.BaseDelay),
+		MaxDelay:   getEnvDurationOrDefault("DB_CONNECT_MAX_DELAY", defaults.MaxDelay),
+		MaxElapsed: getEnvDurationOrDefault("DB_CONNECT_MAX_ELAPSED", defaults.MaxElapsed),
+	}
+}
+
+// Backoff returns the jittered exponential backoff used between connection
+// attempts
+func (c RetryConfig) Backoff() backoff.Config {
+	return backoff.Config{
+		Base:        c.BaseDelay,
+		Max:         c.MaxDelay,
+		Jitter:      true, // Replicas restarting together do not retry in lockstep
+		MaxAttempts: max(c.MaxRetries, 1),
+		MaxElapsed:  c.MaxElapsed,
+	}
+}
+
+// ConnectWithRetry calls connect until it succeeds, the attempts or the
+// MaxElapsed budget are exhausted, or ctx is cancelled. name is only used for
+// log messages.
+func ConnectWithRetry[T any](ctx context.Context, name string, cfg RetryConfig, connect func(ctx context.Context) (T, error)) (T, error) {
+	policy := cfg.Backoff()
+
+	var conn T
+	attempts := 0
+	err := backoff.RetryNotify(ctx, policy, func(ctx context.Context) error {
+		attempts++
+		var err error
+		conn, err = connect(ctx)
+		return err
+	}, func(err error, retry int, delay time.Duration) {
+		log.Printf("⚠️  %s connection attempt %d/%d failed: %v. Retrying in %v...", name, retry, policy.MaxAttempts, err, delay)
+	})
+	if err != nil {
+		var zero T
+		if ctx.Err() != nil {
+			return zero, fmt.Errorf("%s connection aborted: %w", name, err)
+		}
+		return zero, fmt.Errorf("failed to connect to %s: %w", name, err)
+	}
+
+	if attempts > 1 {
+		log.Printf("✅ Connected to %s (attempt %d/%d)", name, attempts, policy.MaxAttempts)
+	}
+	return conn, nil
+}
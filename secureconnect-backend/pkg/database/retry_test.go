@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryConfigBackoff(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: 30 * time.Second, MaxElapsed: time.Minute}
+	policy := cfg.Backoff()
+
+	assert.True(t, policy.Jitter)
+	assert.Equal(t, 10, policy.MaxAttempts)
+	assert.Equal(t, time.Minute, policy.MaxElapsed)
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 16*time.Second, policy.Delay(5))
+	assert.Equal(t, 30*time.Second, policy.Delay(6))
+
+	assert.Equal(t, 1, RetryConfig{}.Backoff().MaxAttempts, "connect is tried at least once")
+}
+
+func TestRetryConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_CONNECT_MAX_RETRIES", "8")
+	t.Setenv("DB_CONNECT_BASE_DELAY", "250ms")
+	t.Setenv("DB_CONNECT_MAX_DELAY", "invalid")
+	t.Setenv("DB_CONNECT_MAX_ELAPSED", "45s")
+
+	cfg := RetryConfigFromEnv()
+
+	assert.Equal(t, 8, cfg.MaxRetries)
+	assert.Equal(t, 250*time.Millisecond, cfg.BaseDelay)
+	assert.Equal(t, DefaultRetryConfig().MaxDelay, cfg.MaxDelay)
+	assert.Equal(t, 45*time.Second, cfg.MaxElapsed)
+}
+
+func TestConnectWithRetry_SucceedsAfterFailures(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 5, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	attempts := 0
+
+	conn, err := ConnectWithRetry(context.Background(), "test", cfg, func(ctx context.Context) (string, error) {
+		attempts++
+		if attempts < 3 {
+			return "", errors.New("connection refused")
+		}
+		return "connected", nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "connected", conn)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestConnectWithRetry_ExhaustsAttempts(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	connErr := errors.New("connection refused")
+	attempts := 0
+
+	conn, err := ConnectWithRetry(context.Background(), "test", cfg, func(ctx context.Context) (*int, error) {
+		attempts++
+		return nil, connErr
+	})
+
+	assert.Nil(t, conn)
+	assert.ErrorIs(t, err, connErr)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestConnectWithRetry_ContextCancelled(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+
+	_, err := ConnectWithRetry(ctx, "test", cfg, func(ctx context.Context) (int, error) {
+		attempts++
+		cancel()
+		return 0, errors.New("connection refused")
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestConnectWithRetry_StopsAtMaxElapsed(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 100, BaseDelay: 20 * time.Millisecond, MaxDelay: 20 * time.Millisecond, MaxElapsed: 50 * time.Millisecond}
+	connErr := errors.New("connection refused")
+	attempts := 0
+
+	start := time.Now()
+	_, err := ConnectWithRetry(context.Background(), "test", cfg, func(ctx context.Context) (int, error) {
+		attempts++
+		return 0, connErr
+	})
+
+	assert.ErrorIs(t, err, connErr)
+	assert.Less(t, attempts, 100)
+	assert.Less(t, time.Since(start), time.Second)
+}
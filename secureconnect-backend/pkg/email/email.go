@@ -4,13 +4,16 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"html"
 	"io"
 	"net/smtp"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 
 	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/urlguard"
 )
 
 const (
@@ -24,6 +27,7 @@ const (
 	EmailTypeVerification  EmailType = "verification"
 	EmailTypePasswordReset EmailType = "password_reset"
 	EmailTypeWelcome       EmailType = "welcome"
+	EmailTypeAccountLocked EmailType = "account_locked"
 	EmailTypeNotification  EmailType = "notification"
 )
 
@@ -56,12 +60,23 @@ type WelcomeEmailData struct {
 	AppURL   string
 }
 
+// AccountLockedEmailData contains data for the notice sent when repeated
+// failed logins lock an account
+type AccountLockedEmailData struct {
+	Username    string
+	IP          string // Address of the attempt that locked the account
+	AttemptedAt time.Time
+	LockedUntil time.Time
+	AppURL      string
+}
+
 // Sender defines the interface for sending emails
 type Sender interface {
 	Send(ctx context.Context, email *Email) error
 	SendVerification(ctx context.Context, to string, data *VerificationEmailData) error
 	SendPasswordReset(ctx context.Context, to string, data *PasswordResetEmailData) error
 	SendWelcome(ctx context.Context, to string, data *WelcomeEmailData) error
+	SendAccountLocked(ctx context.Context, to string, data *AccountLockedEmailData) error
 }
 
 // maskToken returns a safe masked version of a token for logging
@@ -110,6 +125,15 @@ func (m *MockSender) SendWelcome(ctx context.Context, to string, data *WelcomeEm
 	return nil
 }
 
+// SendAccountLocked sends an account locked notice (mock implementation)
+func (m *MockSender) SendAccountLocked(ctx context.Context, to string, data *AccountLockedEmailData) error {
+	logger.Info("Mock account locked email sent",
+		zap.String("to", to),
+		zap.String("username", data.Username),
+		zap.String("ip", data.IP))
+	return nil
+}
+
 // SMTPConfig holds SMTP configuration
 type SMTPConfig struct {
 	Host     string
@@ -117,6 +141,9 @@ type SMTPConfig struct {
 	Username string
 	Password string
 	From     string
+	// TLS is the policy for the STARTTLS handshake; nil uses Go's defaults.
+	// ServerName is always set to Host.
+	TLS *tls.Config
 }
 
 // SMTPSender sends emails via SMTP server
@@ -159,18 +186,23 @@ func (s *SMTPSender) Send(ctx context.Context, email *Email) error {
 	}
 	defer client.Close()
 
-	// Start TLS if available
-	if ok, _ := client.Extension("STARTTLS"); ok {
-		tlsConfig := &tls.Config{
-			ServerName:         s.config.Host,
-			InsecureSkipVerify: false,
-		}
-		if err := client.StartTLS(tlsConfig); err != nil {
-			logger.Error("Failed to start TLS",
-				zap.String("host", s.config.Host),
-				zap.Error(err))
-			return fmt.Errorf("failed to start TLS: %w", err)
-		}
+	// Require STARTTLS so a server or attacker that drops the extension
+	// cannot downgrade the session to plaintext
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		logger.Error("SMTP server does not offer STARTTLS",
+			zap.String("host", s.config.Host))
+		return fmt.Errorf("SMTP server %s does not offer STARTTLS", s.config.Host)
+	}
+	tlsConfig := &tls.Config{}
+	if s.config.TLS != nil {
+		tlsConfig = s.config.TLS.Clone()
+	}
+	tlsConfig.ServerName = s.config.Host
+	if err := client.StartTLS(tlsConfig); err != nil {
+		logger.Error("Failed to start TLS",
+			zap.String("host", s.config.Host),
+			zap.Error(err))
+		return fmt.Errorf("failed to start TLS: %w", err)
 	}
 
 	// Authenticate
@@ -250,6 +282,17 @@ func (s *SMTPSender) SendWelcome(ctx context.Context, to string, data *WelcomeEm
 	return s.Send(ctx, email)
 }
 
+// SendAccountLocked sends an account locked notice via SMTP
+func (s *SMTPSender) SendAccountLocked(ctx context.Context, to string, data *AccountLockedEmailData) error {
+	email := &Email{
+		To:      to,
+		Subject: "Your Account Was Temporarily Locked - SecureConnect",
+		HTML:    s.buildAccountLockedHTML(data),
+		Text:    s.buildAccountLockedText(data),
+	}
+	return s.Send(ctx, email)
+}
+
 // buildVerificationText builds plain text version of verification email
 func (s *SMTPSender) buildVerificationText(data *VerificationEmailData) string {
 	return fmt.Sprintf(`Hi %s,
@@ -435,9 +478,75 @@ func (s *SMTPSender) buildWelcomeHTML(data *WelcomeEmailData) string {
 </html>`, data.Username, data.AppURL, time.Now().Year())
 }
 
+// buildAccountLockedText builds plain text version of account locked email
+func (s *SMTPSender) buildAccountLockedText(data *AccountLockedEmailData) string {
+	return fmt.Sprintf(`Hi %s,
+
+We detected multiple failed sign-in attempts and temporarily locked your account.
+
+Last attempt: %s from %s
+Locked until: %s
+
+If this was you, you can sign in again once the lock expires. If it wasn't, someone may be trying to access your account. We recommend resetting your password:
+
+%s/forgot-password
+
+Best regards,
+The SecureConnect Team`, data.Username,
+		data.AttemptedAt.UTC().Format(time.RFC1123), data.IP,
+		data.LockedUntil.UTC().Format(time.RFC1123), data.AppURL)
+}
+
+// buildAccountLockedHTML builds HTML version of account locked email
+func (s *SMTPSender) buildAccountLockedHTML(data *AccountLockedEmailData) string {
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Your Account Was Temporarily Locked - SecureConnect</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; }
+        .container { background: #f9f9f9; padding: 40px 20px; border-radius: 8px; }
+        .header { text-align: center; margin-bottom: 30px; }
+        .logo { font-size: 24px; font-weight: bold; color: #4a90e2; }
+        .content { background: #ffffff; padding: 30px; border-radius: 8px; }
+        .button { display: inline-block; padding: 12px 30px; background: #4a90e2; color: #ffffff; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+        .button:hover { background: #3a7bc9; }
+        .footer { text-align: center; margin-top: 30px; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <div class="logo">SecureConnect</div>
+        </div>
+        <div class="content">
+            <h2>Your Account Was Temporarily Locked</h2>
+            <p>Hi %s,</p>
+            <p>We detected multiple failed sign-in attempts and temporarily locked your account.</p>
+            <p><strong>Last attempt:</strong> %s from %s<br><strong>Locked until:</strong> %s</p>
+            <p>If this was you, you can sign in again once the lock expires. If it wasn't, someone may be trying to access your account. We recommend resetting your password:</p>
+            <p style="text-align: center;">
+                <a href="%s/forgot-password" class="button">Reset Password</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>&copy; %d SecureConnect. All rights reserved.</p>
+        </div>
+    </div>
+</body>
+</html>`, html.EscapeString(data.Username),
+		data.AttemptedAt.UTC().Format(time.RFC1123), html.EscapeString(data.IP),
+		data.LockedUntil.UTC().Format(time.RFC1123), data.AppURL, time.Now().Year())
+}
+
 // Service handles email sending operations
 type Service struct {
-	sender Sender
+	sender    Sender
+	appURL    string
+	allowlist *urlguard.Allowlist
 }
 
 // NewService creates a new email service
@@ -447,17 +556,69 @@ func NewService(sender Sender) *Service {
 	}
 }
 
+// SetAppURL sets the base URL used for links in emails whose data leaves AppURL empty
+func (s *Service) SetAppURL(appURL string) {
+	s.appURL = strings.TrimRight(appURL, "/")
+}
+
+// SetURLAllowlist makes every Send* method refuse to email links to hosts
+// that are not on allowlist
+func (s *Service) SetURLAllowlist(allowlist *urlguard.Allowlist) {
+	s.allowlist = allowlist
+}
+
+// checkLinkURL validates the base URL of links in an email against the allowlist
+func (s *Service) checkLinkURL(appURL string) error {
+	if s.allowlist == nil {
+		return nil
+	}
+	if err := s.allowlist.Validate(appURL); err != nil {
+		logger.Warn("Refusing to send email with untrusted link", zap.Error(err))
+		return fmt.Errorf("refusing to send email: %w", err)
+	}
+	return nil
+}
+
 // SendVerificationEmail sends a verification email
 func (s *Service) SendVerificationEmail(ctx context.Context, to string, data *VerificationEmailData) error {
+	if data.AppURL == "" {
+		data.AppURL = s.appURL
+	}
+	if err := s.checkLinkURL(data.AppURL); err != nil {
+		return err
+	}
 	return s.sender.SendVerification(ctx, to, data)
 }
 
 // SendPasswordResetEmail sends a password reset email
 func (s *Service) SendPasswordResetEmail(ctx context.Context, to string, data *PasswordResetEmailData) error {
+	if data.AppURL == "" {
+		data.AppURL = s.appURL
+	}
+	if err := s.checkLinkURL(data.AppURL); err != nil {
+		return err
+	}
 	return s.sender.SendPasswordReset(ctx, to, data)
 }
 
 // SendWelcomeEmail sends a welcome email
 func (s *Service) SendWelcomeEmail(ctx context.Context, to string, data *WelcomeEmailData) error {
+	if data.AppURL == "" {
+		data.AppURL = s.appURL
+	}
+	if err := s.checkLinkURL(data.AppURL); err != nil {
+		return err
+	}
 	return s.sender.SendWelcome(ctx, to, data)
 }
+
+// SendAccountLockedEmail tells the user their account was locked by failed logins
+func (s *Service) SendAccountLockedEmail(ctx context.Context, to string, data *AccountLockedEmailData) error {
+	if data.AppURL == "" {
+		data.AppURL = s.appURL
+	}
+	if err := s.checkLinkURL(data.AppURL); err != nil {
+		return err
+	}
+	return s.sender.SendAccountLocked(ctx, to, data)
+}
@@ -0,0 +1,115 @@
+package geoip
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheSize is the number of addresses kept by NewCache when size is not positive
+	DefaultCacheSize = 10000
+
+	// DefaultCacheTTL is how long a resolved address is reused
+	DefaultCacheTTL = time.Hour
+
+	// DefaultLookupTimeout bounds how long Lookup waits on a cache miss
+	DefaultLookupTimeout = 50 * time.Millisecond
+)
+
+type cacheEntry struct {
+	loc       *Location
+	expiresAt time.Time
+}
+
+// Cache memoizes another Resolver. A miss waits at most the lookup timeout;
+// slower lookups finish in the background and are served from the cache on
+// the next call, so callers on the request path are never held up.
+type Cache struct {
+	resolver Resolver
+	size     int
+	ttl      time.Duration
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]chan struct{}
+}
+
+// NewCache wraps resolver with a bounded TTL cache
+func NewCache(resolver Resolver, size int, ttl time.Duration) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &Cache{
+		resolver: resolver,
+		size:     size,
+		ttl:      ttl,
+		timeout:  DefaultLookupTimeout,
+		entries:  make(map[string]cacheEntry),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Lookup returns the cached location for ip, resolving it on a miss. Lookup
+// errors are treated as an unknown location.
+func (c *Cache) Lookup(ip string) (*Location, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[ip]; ok && time.Now().Before(entry.expiresAt) {
+		c.mu.Unlock()
+		return entry.loc, nil
+	}
+	done, ok := c.inflight[ip]
+	if !ok {
+		done = make(chan struct{})
+		c.inflight[ip] = done
+		go c.resolve(ip, done)
+	}
+	c.mu.Unlock()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[ip].loc, nil
+}
+
+func (c *Cache) resolve(ip string, done chan struct{}) {
+	loc, err := c.resolver.Lookup(ip)
+	if err != nil {
+		loc = nil
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= c.size {
+		c.evictLocked()
+	}
+	c.entries[ip] = cacheEntry{loc: loc, expiresAt: time.Now().Add(c.ttl)}
+	delete(c.inflight, ip)
+	c.mu.Unlock()
+	close(done)
+}
+
+// evictLocked drops expired entries, or an arbitrary half of the cache if none have expired
+func (c *Cache) evictLocked() {
+	now := time.Now()
+	for ip, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, ip)
+		}
+	}
+	for ip := range c.entries {
+		if len(c.entries) < c.size/2 {
+			break
+		}
+		delete(c.entries, ip)
+	}
+}
@@ -0,0 +1,66 @@
+package geoip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowResolver takes delay to answer every lookup
+type slowResolver struct {
+	delay time.Duration
+	loc   *Location
+}
+
+func (r *slowResolver) Lookup(ip string) (*Location, error) {
+	time.Sleep(r.delay)
+	return r.loc, nil
+}
+
+func TestCache_ResolvesKnownIPOnce(t *testing.T) {
+	fake := &Fake{Locations: map[string]*Location{
+		"81.2.69.142": {CountryCode: "GB", Country: "United Kingdom", City: "London", ASN: 20712, ASOrg: "Andrews & Arnold Ltd"},
+	}}
+	cache := NewCache(fake, 0, time.Minute)
+
+	loc, err := cache.Lookup("81.2.69.142")
+	require.NoError(t, err)
+	require.NotNil(t, loc)
+	assert.Equal(t, "GB", loc.CountryCode)
+	assert.Equal(t, "London", loc.City)
+	assert.Equal(t, uint(20712), loc.ASN)
+
+	again, _ := cache.Lookup("81.2.69.142")
+	assert.Same(t, loc, again)
+	assert.Equal(t, 1, fake.Calls())
+
+	unknown, err := cache.Lookup("198.51.100.7")
+	assert.NoError(t, err)
+	assert.Nil(t, unknown)
+}
+
+func TestCache_SlowLookupDoesNotBlock(t *testing.T) {
+	resolver := &slowResolver{delay: 300 * time.Millisecond, loc: &Location{CountryCode: "DE"}}
+	cache := NewCache(resolver, 0, time.Minute)
+
+	start := time.Now()
+	loc, _ := cache.Lookup("203.0.113.5")
+	assert.Nil(t, loc)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+
+	// The lookup completes in the background and is served from the cache afterwards
+	assert.Eventually(t, func() bool {
+		loc, _ := cache.Lookup("203.0.113.5")
+		return loc != nil && loc.CountryCode == "DE"
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestOpen_NoDatabaseIsNoop(t *testing.T) {
+	resolver, err := Open("", "")
+	require.NoError(t, err)
+	loc, err := resolver.Lookup("81.2.69.142")
+	assert.NoError(t, err)
+	assert.Nil(t, loc)
+}
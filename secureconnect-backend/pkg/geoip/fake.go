@@ -0,0 +1,26 @@
+package geoip
+
+import "sync"
+
+// Fake resolves addresses from a fixed table, for tests
+type Fake struct {
+	Locations map[string]*Location
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Lookup returns the configured location for ip, or nil
+func (f *Fake) Lookup(ip string) (*Location, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.Locations[ip], nil
+}
+
+// Calls returns how many lookups reached the fake
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
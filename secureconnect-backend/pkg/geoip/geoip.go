@@ -0,0 +1,114 @@
+// Package geoip resolves IP addresses to coarse location and network owner
+// information for audit and security events.
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is the geo context attached to security events
+type Location struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         uint   `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+}
+
+// Resolver looks up the location of an IP address. Implementations return
+// (nil, nil) when the address is unknown.
+type Resolver interface {
+	Lookup(ip string) (*Location, error)
+}
+
+// Noop is used when no GeoIP database is configured
+type Noop struct{}
+
+// Lookup always returns no location
+func (Noop) Lookup(ip string) (*Location, error) {
+	return nil, nil
+}
+
+// MaxMind resolves addresses from local MaxMind GeoIP2/GeoLite2 databases
+type MaxMind struct {
+	city *geoip2.Reader
+	asn  *geoip2.Reader
+}
+
+// Open loads the City and ASN databases at the given paths. Either path may be
+// empty; when both are, a Noop resolver is returned.
+func Open(cityDBPath, asnDBPath string) (Resolver, error) {
+	if cityDBPath == "" && asnDBPath == "" {
+		return Noop{}, nil
+	}
+
+	m := &MaxMind{}
+	if cityDBPath != "" {
+		reader, err := geoip2.Open(cityDBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GeoIP city database: %w", err)
+		}
+		m.city = reader
+	}
+	if asnDBPath != "" {
+		reader, err := geoip2.Open(asnDBPath)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("failed to open GeoIP ASN database: %w", err)
+		}
+		m.asn = reader
+	}
+	return m, nil
+}
+
+// Lookup resolves ip against the loaded databases
+func (m *MaxMind) Lookup(ip string) (*Location, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || !isPublic(parsed) {
+		return nil, nil
+	}
+
+	loc := &Location{}
+	if m.city != nil {
+		record, err := m.city.City(parsed)
+		if err != nil {
+			return nil, fmt.Errorf("GeoIP city lookup failed: %w", err)
+		}
+		loc.CountryCode = record.Country.IsoCode
+		loc.Country = record.Country.Names["en"]
+		loc.City = record.City.Names["en"]
+	}
+	if m.asn != nil {
+		record, err := m.asn.ASN(parsed)
+		if err != nil {
+			return nil, fmt.Errorf("GeoIP ASN lookup failed: %w", err)
+		}
+		loc.ASN = record.AutonomousSystemNumber
+		loc.ASOrg = record.AutonomousSystemOrganization
+	}
+
+	if *loc == (Location{}) {
+		return nil, nil
+	}
+	return loc, nil
+}
+
+// Close releases the database readers
+func (m *MaxMind) Close() error {
+	if m.city != nil {
+		m.city.Close()
+	}
+	if m.asn != nil {
+		m.asn.Close()
+	}
+	return nil
+}
+
+// isPublic reports whether ip can have a meaningful geo location
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsMulticast()
+}
@@ -0,0 +1,171 @@
+package jsonbind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Defaults for Options
+const (
+	DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+	DefaultMaxDepth     = 32
+	DefaultMaxArrayLen  = 1000
+)
+
+var (
+	// ErrEmptyBody is returned when the request has no body
+	ErrEmptyBody = errors.New("request body is empty")
+	// ErrBodyTooLarge is returned when the body exceeds MaxBodyBytes
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrTooDeep is returned when objects or arrays nest deeper than MaxDepth
+	ErrTooDeep = errors.New("request body is nested too deeply")
+	// ErrArrayTooLong is returned when an array has more than MaxArrayLen elements
+	ErrArrayTooLong = errors.New("request body contains an array that is too long")
+	// ErrInvalidJSON is returned when the body is not a single well-formed JSON value
+	ErrInvalidJSON = errors.New("invalid JSON body")
+)
+
+// Options bound the cost of decoding a request body
+type Options struct {
+	MaxBodyBytes          int64
+	MaxDepth              int
+	MaxArrayLen           int
+	DisallowUnknownFields bool
+}
+
+// DefaultOptions returns the built-in limits; unknown fields are ignored
+func DefaultOptions() Options {
+	return Options{
+		MaxBodyBytes: DefaultMaxBodyBytes,
+		MaxDepth:     DefaultMaxDepth,
+		MaxArrayLen:  DefaultMaxArrayLen,
+	}
+}
+
+// options are used by Bind. Services set them from config.JSONConfig at startup.
+var options atomic.Pointer[Options]
+
+func init() {
+	SetOptions(DefaultOptions())
+}
+
+// CurrentOptions returns the options used by Bind
+func CurrentOptions() Options {
+	return *options.Load()
+}
+
+// SetOptions overrides the options used by Bind; non-positive limits restore their defaults
+func SetOptions(opts Options) {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	if opts.MaxArrayLen <= 0 {
+		opts.MaxArrayLen = DefaultMaxArrayLen
+	}
+	options.Store(&opts)
+}
+
+// Bind decodes the request body into obj and runs its `binding` validation.
+// It replaces c.ShouldBindJSON, which reads bodies of any size and shape.
+func Bind(c *gin.Context, obj interface{}) error {
+	opts := CurrentOptions()
+	if c.Request.Body == nil {
+		return ErrEmptyBody
+	}
+	// MaxBytesReader also tells the server to close the connection once the limit is hit
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, opts.MaxBodyBytes)
+	return decode(body, obj, opts)
+}
+
+// Decode reads a JSON value from r into obj under opts and validates it
+func Decode(r io.Reader, obj interface{}, opts Options) error {
+	return decode(io.LimitReader(r, opts.MaxBodyBytes+1), obj, opts)
+}
+
+func decode(r io.Reader, obj interface{}, opts Options) error {
+	data, err := io.ReadAll(r)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || int64(len(data)) > opts.MaxBodyBytes {
+		return ErrBodyTooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ErrEmptyBody
+	}
+
+	// Check the shape before allocating into obj so oversized structures are
+	// rejected while only token state is held
+	if err := checkStructure(data, opts); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("%w: unexpected data after JSON value", ErrInvalidJSON)
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// checkStructure walks the tokens of data and enforces MaxDepth and MaxArrayLen
+func checkStructure(data []byte, opts Options) error {
+	type frame struct {
+		array bool
+		count int
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	stack := make([]frame, 0, 8)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+
+		delim, isDelim := tok.(json.Delim)
+		if isDelim && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		// Object keys are tokens too, but only array elements are counted
+		if n := len(stack); n > 0 && stack[n-1].array {
+			stack[n-1].count++
+			if stack[n-1].count > opts.MaxArrayLen {
+				return fmt.Errorf("%w (max %d elements)", ErrArrayTooLong, opts.MaxArrayLen)
+			}
+		}
+
+		if isDelim {
+			if len(stack) >= opts.MaxDepth {
+				return fmt.Errorf("%w (max depth %d)", ErrTooDeep, opts.MaxDepth)
+			}
+			stack = append(stack, frame{array: delim == '['})
+		}
+	}
+}
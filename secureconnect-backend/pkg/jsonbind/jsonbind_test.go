@@ -0,0 +1,84 @@
+package jsonbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindRequest struct {
+	Name string   `json:"name" binding:"required"`
+	Tags []string `json:"tags"`
+}
+
+func bindBody(t *testing.T, body string, opts Options) (*bindRequest, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	previous := CurrentOptions()
+	SetOptions(opts)
+	t.Cleanup(func() { SetOptions(previous) })
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	var req bindRequest
+	err := Bind(c, &req)
+	return &req, err
+}
+
+func TestBind_AcceptsValidPayload(t *testing.T) {
+	req, err := bindBody(t, `{"name":"alice","tags":["a","b"]}`, DefaultOptions())
+	require.NoError(t, err)
+	assert.Equal(t, "alice", req.Name)
+	assert.Equal(t, []string{"a", "b"}, req.Tags)
+}
+
+func TestBind_RejectsOversizedBody(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxBodyBytes = 64
+	_, err := bindBody(t, `{"name":"`+strings.Repeat("x", 100)+`"}`, opts)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+}
+
+func TestBind_UnknownFields(t *testing.T) {
+	body := `{"name":"alice","is_admin":true}`
+	req, err := bindBody(t, body, DefaultOptions())
+	require.NoError(t, err, "unknown fields are ignored by default")
+	assert.Equal(t, "alice", req.Name)
+
+	opts := DefaultOptions()
+	opts.DisallowUnknownFields = true
+	_, err = bindBody(t, body, opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is_admin")
+}
+
+func TestBind_CapsNestingAndArrayLength(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxDepth = 4
+	opts.MaxArrayLen = 3
+
+	_, err := bindBody(t, `{"name":"a","tags":[[[["x"]]]]}`, opts)
+	assert.ErrorIs(t, err, ErrTooDeep)
+
+	_, err = bindBody(t, `{"name":"a","tags":["1","2","3","4"]}`, opts)
+	assert.ErrorIs(t, err, ErrArrayTooLong)
+
+	_, err = bindBody(t, `{"name":"a","tags":["1","2","3"]}`, opts)
+	assert.NoError(t, err)
+}
+
+func TestBind_RunsBindingValidation(t *testing.T) {
+	_, err := bindBody(t, `{"tags":[]}`, DefaultOptions())
+	assert.Error(t, err, "required field is still enforced")
+
+	_, err = bindBody(t, ``, DefaultOptions())
+	assert.ErrorIs(t, err, ErrEmptyBody)
+
+	_, err = bindBody(t, `{"name":"a"} {"name":"b"}`, DefaultOptions())
+	assert.ErrorIs(t, err, ErrInvalidJSON)
+}
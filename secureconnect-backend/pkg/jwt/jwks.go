@@ -0,0 +1,169 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is an RSA public key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys tokens can be verified with: the signing key
+// followed by any retired keys. HMAC secrets are never published, so the set
+// is empty for HS256 managers.
+func (m *JWTManager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if m.publicKey == nil {
+		return set
+	}
+	set.Keys = append(set.Keys, rsaJWK(m.keyID, m.publicKey))
+	for _, key := range m.previousKeys {
+		set.Keys = append(set.Keys, rsaJWK(key.ID, key.PublicKey))
+	}
+	return set
+}
+
+// rsaJWK describes an RS256 verification key
+func rsaJWK(kid string, key *rsa.PublicKey) JWK {
+	n, e := rsaComponents(key)
+	return JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: AlgorithmRS256,
+		KeyID:     kid,
+		Modulus:   n,
+		Exponent:  e,
+	}
+}
+
+// KeyID returns the RFC 7638 thumbprint of key, used as its kid
+func KeyID(key *rsa.PublicKey) string {
+	n, e := rsaComponents(key)
+	// Members in lexicographic order, no whitespace
+	thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, e, n)))
+	return base64.RawURLEncoding.EncodeToString(thumbprint[:])
+}
+
+// rsaComponents returns key's modulus and exponent, base64url encoded
+func rsaComponents(key *rsa.PublicKey) (n, e string) {
+	return base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+}
+
+// LoadRSAPrivateKey reads a PEM encoded RSA private key (PKCS #1 or #8)
+func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+	return key, nil
+}
+
+// LoadRSAPublicKey reads a PEM encoded RSA public key or certificate
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+	}
+	return key, nil
+}
+
+// KeyConfig selects the algorithm and keys a JWTManager uses
+type KeyConfig struct {
+	// Algorithm is HS256 (the default) or RS256
+	Algorithm string
+	// Secret is the shared HS256 key
+	Secret string
+	// PrivateKeyFile is the RS256 signing key; only the token issuer needs it
+	PrivateKeyFile string
+	// PublicKeyFile is the RS256 verification key for services that do not sign
+	PublicKeyFile string
+
+	// KeyID names the signing key in issued tokens; RS256 defaults to the key thumbprint
+	KeyID string
+	// PreviousSecrets are retired HS256 keys as "kid:secret" entries
+	PreviousSecrets []string
+	// PreviousPublicKeyFiles are retired RS256 public keys
+	PreviousPublicKeyFiles []string
+}
+
+// NewJWTManagerFromConfig creates a manager for the configured algorithm,
+// accepting tokens signed with the previous keys as well
+func NewJWTManagerFromConfig(cfg KeyConfig, accessTokenDuration, refreshTokenDuration time.Duration) (*JWTManager, error) {
+	var manager *JWTManager
+	var previous []VerificationKey
+	switch cfg.Algorithm {
+	case "", AlgorithmHS256:
+		manager = NewJWTManager(cfg.Secret, accessTokenDuration, refreshTokenDuration)
+		for _, entry := range cfg.PreviousSecrets {
+			kid, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || kid == "" || secret == "" {
+				return nil, errors.New(`previous JWT secrets must be "kid:secret" entries`)
+			}
+			previous = append(previous, VerificationKey{ID: kid, Secret: secret})
+		}
+	case AlgorithmRS256:
+		var privateKey *rsa.PrivateKey
+		var publicKey *rsa.PublicKey
+		var err error
+		if cfg.PrivateKeyFile != "" {
+			if privateKey, err = LoadRSAPrivateKey(cfg.PrivateKeyFile); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.PublicKeyFile != "" {
+			if publicKey, err = LoadRSAPublicKey(cfg.PublicKeyFile); err != nil {
+				return nil, err
+			}
+		}
+		if manager, err = NewRSAJWTManager(privateKey, publicKey, accessTokenDuration, refreshTokenDuration); err != nil {
+			return nil, err
+		}
+		for _, path := range cfg.PreviousPublicKeyFiles {
+			key, err := LoadRSAPublicKey(strings.TrimSpace(path))
+			if err != nil {
+				return nil, err
+			}
+			previous = append(previous, VerificationKey{PublicKey: key})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	if cfg.KeyID != "" {
+		manager.SetKeyID(cfg.KeyID)
+	}
+	if err := manager.AddVerificationKeys(previous...); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
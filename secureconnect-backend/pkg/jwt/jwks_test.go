@@ -0,0 +1,141 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestRSAJWTManager_SignsWithKeyID(t *testing.T) {
+	key := newRSAKey(t)
+	issuer, err := NewRSAJWTManager(key, nil, 15*time.Minute, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmRS256, issuer.Algorithm())
+
+	userID := uuid.New()
+	token, err := issuer.GenerateAccessToken(userID, "test@example.com", "testuser", "user")
+	require.NoError(t, err)
+
+	parsed, _, err := gojwt.NewParser().ParseUnverified(token, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Header["alg"])
+	assert.Equal(t, KeyID(&key.PublicKey), parsed.Header["kid"])
+
+	// A service holding only the public key can validate but not sign
+	verifier, err := NewRSAJWTManager(nil, &key.PublicKey, 15*time.Minute, 24*time.Hour)
+	require.NoError(t, err)
+	claims, err := verifier.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+
+	_, err = verifier.GenerateRefreshToken(userID)
+	assert.ErrorIs(t, err, ErrSigningKeyUnavailable)
+}
+
+func TestRSAJWTManager_RejectsOtherKeysAndAlgorithms(t *testing.T) {
+	key := newRSAKey(t)
+	verifier, err := NewRSAJWTManager(nil, &key.PublicKey, 15*time.Minute, 24*time.Hour)
+	require.NoError(t, err)
+
+	other, err := NewRSAJWTManager(newRSAKey(t), nil, 15*time.Minute, 24*time.Hour)
+	require.NoError(t, err)
+	token, err := other.GenerateAccessToken(uuid.New(), "a@example.com", "a", "user")
+	require.NoError(t, err)
+	_, err = verifier.ValidateToken(token)
+	assert.Error(t, err)
+
+	// An HMAC token signed with the public key as secret must not pass
+	hmacToken, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, &Claims{UserID: uuid.New()}).
+		SignedString(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	require.NoError(t, err)
+	_, err = verifier.ValidateToken(hmacToken)
+	assert.Error(t, err)
+}
+
+func TestJWKS_PublishesPublicKey(t *testing.T) {
+	assert.Empty(t, NewJWTManager("test-secret", time.Minute, time.Hour).JWKS().Keys, "HMAC secrets are never published")
+
+	key := newRSAKey(t)
+	manager, err := NewRSAJWTManager(key, nil, time.Minute, time.Hour)
+	require.NoError(t, err)
+
+	set := manager.JWKS()
+	require.Len(t, set.Keys, 1)
+	jwk := set.Keys[0]
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, "RS256", jwk.Algorithm)
+	assert.Equal(t, KeyID(&key.PublicKey), jwk.KeyID)
+
+	n, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+	require.NoError(t, err)
+	assert.Equal(t, 0, new(big.Int).SetBytes(n).Cmp(key.N))
+	assert.Equal(t, "AQAB", jwk.Exponent)
+}
+
+func TestNewJWTManagerFromConfig(t *testing.T) {
+	key := newRSAKey(t)
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{
+		Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0o600))
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o644))
+
+	hmac, err := NewJWTManagerFromConfig(KeyConfig{Secret: "test-secret"}, time.Minute, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmHS256, hmac.Algorithm())
+
+	issuer, err := NewJWTManagerFromConfig(KeyConfig{Algorithm: AlgorithmRS256, PrivateKeyFile: privatePath}, time.Minute, time.Hour)
+	require.NoError(t, err)
+	verifier, err := NewJWTManagerFromConfig(KeyConfig{Algorithm: AlgorithmRS256, PublicKeyFile: publicPath}, time.Minute, time.Hour)
+	require.NoError(t, err)
+
+	token, err := issuer.GenerateAccessToken(uuid.New(), "a@example.com", "a", "user")
+	require.NoError(t, err)
+	_, err = verifier.ValidateToken(token)
+	assert.NoError(t, err)
+
+	_, err = NewJWTManagerFromConfig(KeyConfig{Algorithm: "none"}, time.Minute, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestRSAKeyRotation_PublishesAndAcceptsPreviousKey(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	old, err := NewRSAJWTManager(oldKey, nil, time.Minute, time.Hour)
+	require.NoError(t, err)
+	oldToken, err := old.GenerateAccessToken(uuid.New(), "a@example.com", "a", "user")
+	require.NoError(t, err)
+
+	rotated, err := NewRSAJWTManager(newKey, nil, time.Minute, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, rotated.AddVerificationKeys(VerificationKey{PublicKey: &oldKey.PublicKey}))
+
+	_, err = rotated.ValidateToken(oldToken)
+	assert.NoError(t, err)
+
+	set := rotated.JWKS()
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, KeyID(&newKey.PublicKey), set.Keys[0].KeyID, "the signing key comes first")
+	assert.Equal(t, KeyID(&oldKey.PublicKey), set.Keys[1].KeyID)
+}
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"crypto/rsa"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,17 +17,53 @@ type Claims struct {
 	Username string    `json:"username"`
 	Role     string    `json:"role"` // user, admin
 	Audience string    `json:"aud"`  // Audience claim for token validation
+	// AuthTime is when the user logged in. Refresh tokens carry it forward
+	// so a sliding session can be capped at an absolute lifetime.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// RememberMe marks refresh tokens of sessions that chose the long window
+	RememberMe bool `json:"remember_me,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Signing algorithms supported by JWTManager
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// ErrSigningKeyUnavailable is returned when a verification-only manager is asked to sign
+var ErrSigningKeyUnavailable = errors.New("jwt manager has no signing key")
+
+// ErrUnknownKeyID is returned for tokens whose kid names neither the signing
+// key nor a retired one
+var ErrUnknownKeyID = errors.New("token signed with an unknown key")
+
 // JWTManager handles JWT token operations
 type JWTManager struct {
 	secretKey            string
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
+
+	// RS256 keys; privateKey is nil for verification-only managers
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+
+	// keyID names the signing key in the kid header; HS256 tokens carry none until SetKeyID
+	keyID string
+	// previousKeys still verify tokens signed before a key rotation
+	previousKeys []VerificationKey
+}
+
+// VerificationKey is a retired signing key that still verifies tokens issued
+// with it until they expire. Secret is used by HS256 managers and PublicKey
+// by RS256 ones.
+type VerificationKey struct {
+	ID        string
+	Secret    string
+	PublicKey *rsa.PublicKey
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a new JWT manager that signs with a shared HMAC secret (HS256)
 func NewJWTManager(secretKey string, accessTokenDuration, refreshTokenDuration time.Duration) *JWTManager {
 	return &JWTManager{
 		secretKey:            secretKey,
@@ -34,6 +72,141 @@ func NewJWTManager(secretKey string, accessTokenDuration, refreshTokenDuration t
 	}
 }
 
+// NewRSAJWTManager creates a JWT manager that signs with privateKey and
+// verifies with publicKey (RS256). privateKey may be nil for services that
+// only validate tokens; publicKey defaults to privateKey's public half.
+// Tokens carry a kid header derived from the public key.
+func NewRSAJWTManager(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, accessTokenDuration, refreshTokenDuration time.Duration) (*JWTManager, error) {
+	if publicKey == nil {
+		if privateKey == nil {
+			return nil, errors.New("an RSA private or public key is required")
+		}
+		publicKey = &privateKey.PublicKey
+	}
+	if privateKey != nil && !privateKey.PublicKey.Equal(publicKey) {
+		return nil, errors.New("RSA public key does not match the private key")
+	}
+	return &JWTManager{
+		accessTokenDuration:  accessTokenDuration,
+		refreshTokenDuration: refreshTokenDuration,
+		privateKey:           privateKey,
+		publicKey:            publicKey,
+		keyID:                KeyID(publicKey),
+	}, nil
+}
+
+// Algorithm returns the JWS algorithm tokens are signed with
+func (m *JWTManager) Algorithm() string {
+	if m.publicKey != nil {
+		return AlgorithmRS256
+	}
+	return AlgorithmHS256
+}
+
+// SetKeyID names the signing key. Tokens then carry it in the kid header so
+// verifiers can pick the matching key after a rotation. RS256 managers
+// default to the public key's thumbprint.
+func (m *JWTManager) SetKeyID(kid string) {
+	m.keyID = kid
+}
+
+// AddVerificationKeys accepts tokens signed with retired keys, so rotating
+// the signing key does not invalidate tokens already issued. HS256 keys need
+// an ID; RS256 keys default to their thumbprint.
+func (m *JWTManager) AddVerificationKeys(keys ...VerificationKey) error {
+	for _, key := range keys {
+		if m.publicKey == nil {
+			if key.ID == "" || key.Secret == "" {
+				return errors.New("HS256 verification keys need an ID and a secret")
+			}
+		} else {
+			if key.PublicKey == nil {
+				return errors.New("RS256 verification keys need a public key")
+			}
+			if key.ID == "" {
+				key.ID = KeyID(key.PublicKey)
+			}
+		}
+		if key.ID == m.keyID {
+			return fmt.Errorf("verification key %q has the signing key's ID", key.ID)
+		}
+		m.previousKeys = append(m.previousKeys, key)
+	}
+	return nil
+}
+
+// sign signs claims with the manager's key, naming it in the kid header
+func (m *JWTManager) sign(claims *Claims) (string, error) {
+	if m.publicKey != nil && m.privateKey == nil {
+		return "", ErrSigningKeyUnavailable
+	}
+
+	method, key := jwt.SigningMethod(jwt.SigningMethodHS256), interface{}([]byte(m.secretKey))
+	if m.publicKey != nil {
+		method, key = jwt.SigningMethodRS256, m.privateKey
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if m.keyID != "" {
+		token.Header["kid"] = m.keyID
+	}
+	return token.SignedString(key)
+}
+
+// verificationKey returns the key token must be verified with, rejecting
+// tokens signed with another algorithm. A token naming a kid is checked
+// against that key only and rejected when the kid is unknown; only tokens
+// without a kid are tried against every key.
+func (m *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	if m.publicKey == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	} else if token.Method != jwt.SigningMethodRS256 {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	// Managers without key IDs predate rotation and ignore the header
+	if m.keyID == "" && len(m.previousKeys) == 0 {
+		return m.signingVerificationKey(), nil
+	}
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		if kid == m.keyID {
+			return m.signingVerificationKey(), nil
+		}
+		for _, key := range m.previousKeys {
+			if key.ID == kid {
+				return m.material(key), nil
+			}
+		}
+		return nil, ErrUnknownKeyID
+	}
+
+	if len(m.previousKeys) == 0 {
+		return m.signingVerificationKey(), nil
+	}
+	set := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{m.signingVerificationKey()}}
+	for _, key := range m.previousKeys {
+		set.Keys = append(set.Keys, m.material(key))
+	}
+	return set, nil
+}
+
+// signingVerificationKey returns the key that verifies newly signed tokens
+func (m *JWTManager) signingVerificationKey() jwt.VerificationKey {
+	if m.publicKey != nil {
+		return m.publicKey
+	}
+	return []byte(m.secretKey)
+}
+
+// material returns a retired key in the form the manager's algorithm verifies with
+func (m *JWTManager) material(key VerificationKey) jwt.VerificationKey {
+	if m.publicKey != nil {
+		return key.PublicKey
+	}
+	return []byte(key.Secret)
+}
+
 // GenerateAccessToken creates a new access token (short-lived: 15 minutes)
 func (m *JWTManager) GenerateAccessToken(userID uuid.UUID, email, username, role string) (string, error) {
 	claims := &Claims{
@@ -52,8 +225,7 @@ func (m *JWTManager) GenerateAccessToken(userID uuid.UUID, email, username, role
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(m.secretKey))
+	tokenString, err := m.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -61,21 +233,49 @@ func (m *JWTManager) GenerateAccessToken(userID uuid.UUID, email, username, role
 	return tokenString, nil
 }
 
+// RefreshTokenOptions ties a refresh token to the login it continues
+type RefreshTokenOptions struct {
+	// AuthTime is when the user logged in; zero means now
+	AuthTime time.Time
+	// RememberMe is carried so later refreshes keep the same window
+	RememberMe bool
+	// ExpiresAt overrides the manager's refresh token duration when set
+	ExpiresAt time.Time
+}
+
 // GenerateRefreshToken creates a new refresh token (long-lived: 30 days)
 func (m *JWTManager) GenerateRefreshToken(userID uuid.UUID) (string, error) {
+	return m.GenerateRefreshTokenWithOptions(userID, RefreshTokenOptions{})
+}
+
+// GenerateRefreshTokenWithOptions creates a refresh token carrying the login
+// time and "remember me" choice, expiring at opts.ExpiresAt when set
+func (m *JWTManager) GenerateRefreshTokenWithOptions(userID uuid.UUID, opts RefreshTokenOptions) (string, error) {
+	now := time.Now()
+	authTime := opts.AuthTime
+	if authTime.IsZero() {
+		authTime = now
+	}
+	expiresAt := opts.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(m.refreshTokenDuration)
+	}
+
 	claims := &Claims{
-		UserID:   userID,
-		Audience: "secureconnect-api", // Canonical audience for API
+		UserID:     userID,
+		Audience:   "secureconnect-api", // Canonical audience for API
+		AuthTime:   jwt.NewNumericDate(authTime),
+		RememberMe: opts.RememberMe,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.refreshTokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "secureconnect-auth",
 			Subject:   userID.String(),
+			ID:        uuid.New().String(), // Tracked so each refresh token can be used once
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(m.secretKey))
+	tokenString, err := m.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -85,13 +285,7 @@ func (m *JWTManager) GenerateRefreshToken(userID uuid.UUID) (string, error) {
 
 // ValidateToken validates and parses JWT token
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKey)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -4,8 +4,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewJWTManager(t *testing.T) {
@@ -174,3 +176,99 @@ func TestRefreshTokenClaims(t *testing.T) {
 	assert.Empty(t, claims.Username) // Or username
 	assert.NotZero(t, claims.ExpiresAt)
 }
+
+func TestRefreshTokenWithOptions(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 24*time.Hour)
+	authTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	expiresAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+
+	token, err := manager.GenerateRefreshTokenWithOptions(uuid.New(), RefreshTokenOptions{AuthTime: authTime, RememberMe: true, ExpiresAt: expiresAt})
+	assert.NoError(t, err)
+
+	claims, err := manager.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.True(t, authTime.Equal(claims.AuthTime.Time))
+	assert.True(t, expiresAt.Equal(claims.ExpiresAt.Time))
+	assert.True(t, claims.RememberMe)
+}
+
+func TestKeyRotation_OldTokensStillValidate(t *testing.T) {
+	old := NewJWTManager("old-secret-key-for-testing-purposes", 15*time.Minute, 24*time.Hour)
+	old.SetKeyID("2026-01")
+	userID := uuid.New()
+	oldToken, err := old.GenerateAccessToken(userID, "test@example.com", "testuser", "user")
+	assert.NoError(t, err)
+
+	// Issued before key IDs were configured
+	legacy := NewJWTManager("legacy-secret-key-for-testing-purposes", 15*time.Minute, 24*time.Hour)
+	legacyToken, err := legacy.GenerateAccessToken(userID, "test@example.com", "testuser", "user")
+	assert.NoError(t, err)
+
+	rotated := NewJWTManager("new-secret-key-for-testing-purposes", 15*time.Minute, 24*time.Hour)
+	rotated.SetKeyID("2026-02")
+	assert.NoError(t, rotated.AddVerificationKeys(
+		VerificationKey{ID: "2026-01", Secret: "old-secret-key-for-testing-purposes"},
+		VerificationKey{ID: "legacy", Secret: "legacy-secret-key-for-testing-purposes"},
+	))
+
+	claims, err := rotated.ValidateToken(oldToken)
+	assert.NoError(t, err, "tokens signed with the previous key stay valid")
+	assert.Equal(t, userID, claims.UserID)
+
+	_, err = rotated.ValidateToken(legacyToken)
+	assert.NoError(t, err, "tokens without a kid are tried against every key")
+
+	newToken, err := rotated.GenerateAccessToken(userID, "test@example.com", "testuser", "user")
+	assert.NoError(t, err)
+	_, err = rotated.ValidateToken(newToken)
+	assert.NoError(t, err)
+
+	// Once the old key is dropped its tokens are rejected
+	retired := NewJWTManager("new-secret-key-for-testing-purposes", 15*time.Minute, 24*time.Hour)
+	retired.SetKeyID("2026-02")
+	_, err = retired.ValidateToken(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+}
+
+func TestValidateToken_UnknownKeyID(t *testing.T) {
+	userID := uuid.New()
+	verifier := NewJWTManager("new-secret-key-for-testing-purposes", 15*time.Minute, 24*time.Hour)
+	verifier.SetKeyID("2026-02")
+	assert.NoError(t, verifier.AddVerificationKeys(VerificationKey{ID: "2026-01", Secret: "old-secret-key-for-testing-purposes"}))
+
+	tests := []struct {
+		name    string
+		secret  string
+		kid     string
+		wantErr error
+	}{
+		{name: "current key", secret: "new-secret-key-for-testing-purposes", kid: "2026-02"},
+		{name: "retired key", secret: "old-secret-key-for-testing-purposes", kid: "2026-01"},
+		{name: "no kid is tried against every key", secret: "old-secret-key-for-testing-purposes"},
+		{name: "unknown kid with a valid secret", secret: "old-secret-key-for-testing-purposes", kid: "2025-12", wantErr: ErrUnknownKeyID},
+		{name: "known kid with another key's secret", secret: "old-secret-key-for-testing-purposes", kid: "2026-02", wantErr: jwt.ErrTokenSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := NewJWTManager(tt.secret, 15*time.Minute, 24*time.Hour)
+			signer.SetKeyID(tt.kid)
+			token, err := signer.GenerateAccessToken(userID, "test@example.com", "testuser", "user")
+			require.NoError(t, err)
+
+			_, err = verifier.ValidateToken(token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAddVerificationKeys_RejectsInvalidKeys(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 24*time.Hour)
+	manager.SetKeyID("current")
+
+	assert.Error(t, manager.AddVerificationKeys(VerificationKey{Secret: "no-id"}))
+	assert.Error(t, manager.AddVerificationKeys(VerificationKey{ID: "current", Secret: "clash"}))
+}
@@ -115,6 +115,12 @@ func WithRequestID(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, requestIDKey, requestID)
 }
 
+// RequestIDFromContext returns the request ID added by WithRequestID, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
 // FromContext creates a logger with context fields
 func FromContext(ctx context.Context) *zap.Logger {
 	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
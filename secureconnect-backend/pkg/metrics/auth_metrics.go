@@ -29,6 +29,11 @@ var (
 		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 	})
 
+	AuthEmailTokensPrunedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_email_tokens_pruned_total",
+		Help: "Total number of used or expired email verification and reset tokens deleted",
+	})
+
 	// Account lockout metrics
 	AuthAccountLockedTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "auth_account_locked_total",
@@ -56,6 +61,17 @@ var (
 		Help: "Total number of refresh tokens blacklisted",
 	})
 
+	AuthRefreshTokenReuseTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_refresh_token_reuse_total",
+		Help: "Total number of already rotated refresh tokens presented again, each revoking the user's tokens",
+	})
+
+	// Password hashing metrics
+	AuthPasswordRehashedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_password_rehashed_total",
+		Help: "Total number of password hashes upgraded to the configured algorithm on login",
+	})
+
 	// Logout metrics
 	AuthLogoutTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "auth_logout_total",
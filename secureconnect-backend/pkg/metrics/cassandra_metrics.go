@@ -49,6 +49,17 @@ var (
 		Help: "Current number of idle Cassandra connections",
 	})
 
+	// Session recovery metrics
+	CassandraReconnectTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cassandra_reconnect_total",
+		Help: "Total number of Cassandra session rebuild attempts",
+	}, []string{"result"})
+
+	CassandraSessionHealthy = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cassandra_session_healthy",
+		Help: "Whether the Cassandra session is healthy (1) or not (0)",
+	})
+
 	// Error metrics
 	CassandraQueryErrorTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "cassandra_query_error_total",
@@ -171,6 +182,20 @@ func SetCassandraConnectionsIdle(count int) {
 	CassandraConnectionsIdle.Set(float64(count))
 }
 
+// RecordCassandraReconnect records a Cassandra session rebuild attempt
+func RecordCassandraReconnect(result string) {
+	CassandraReconnectTotal.WithLabelValues(result).Inc()
+}
+
+// SetCassandraSessionHealthy records whether the Cassandra session is healthy
+func SetCassandraSessionHealthy(healthy bool) {
+	if healthy {
+		CassandraSessionHealthy.Set(1)
+	} else {
+		CassandraSessionHealthy.Set(0)
+	}
+}
+
 // RecordCassandraQueryError records a Cassandra query error
 func RecordCassandraQueryError(operation, table, errorType string) {
 	CassandraQueryErrorTotal.WithLabelValues(operation, table, errorType).Inc()
@@ -29,6 +29,14 @@ var (
 		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 	}, []string{"step"}) // "persist", "publish", "notify"
 
+	// MessageDeliveryLatency measures the time from a message's server
+	// timestamp until it is handed to a recipient
+	MessageDeliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "message_delivery_latency_seconds",
+		Help:    "Time from a message's server timestamp until delivery to a recipient",
+		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"channel"}) // "socket", "push"
+
 	// Authorization metrics
 	ChatMessageSendUnauthorizedTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "chat_message_send_unauthorized_total",
@@ -95,6 +103,21 @@ var (
 		Help: "Current number of participants per conversation",
 	}, []string{"conversation_id"})
 
+	ChatMembershipCacheTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_membership_cache_total",
+		Help: "Total number of conversation membership checks by cache result",
+	}, []string{"result"}) // hit, miss, error
+
+	ChatLinkPreviewRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_link_preview_requests_total",
+		Help: "Total number of link preview requests by outcome",
+	}, []string{"result"}) // fetched, cache_hit, failed, blocked, rate_limited, shed
+
+	ChatModerationChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_moderation_checks_total",
+		Help: "Total number of outbound message moderation checks by result",
+	}, []string{"result"}) // allowed, blocked, error, timeout
+
 	// WebSocket connection metrics
 	ChatWebSocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "chat_websocket_connections",
@@ -112,4 +135,43 @@ var (
 		Name: "chat_websocket_errors_total",
 		Help: "Total number of WebSocket errors",
 	}, []string{"error_type"})
+
+	// Redis pub/sub subscription metrics
+	ChatPubSubSubscriptionsDown = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_pubsub_subscriptions_down",
+		Help: "Current number of conversation subscriptions waiting to be re-established",
+	})
+
+	ChatPubSubResubscribeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_pubsub_resubscribe_total",
+		Help: "Total number of Redis pub/sub resubscription attempts",
+	}, []string{"result"})
+
+	ChatPubSubReplayedMessagesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_pubsub_replayed_messages_total",
+		Help: "Total number of messages replayed from history after a subscription gap",
+	})
+
+	// Message retention purge metrics
+	ChatRetentionPurgedMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_retention_purged_messages_total",
+		Help: "Total number of messages purged (or, in dry-run mode, that would be purged) by the retention job",
+	}, []string{"mode"})
+
+	ChatRetentionPurgeRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_retention_purge_runs_total",
+		Help: "Total number of retention purge runs by result",
+	}, []string{"result"})
+
+	// Presence debounce metrics
+	ChatPresenceFlapsSuppressedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_presence_flaps_suppressed_total",
+		Help: "Total number of disconnect/reconnect flaps whose leave and join events were suppressed",
+	})
+
+	// Typing indicator metrics
+	ChatTypingIndicatorsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_typing_indicators_active",
+		Help: "Current number of users shown as typing, per conversation they type in",
+	})
 )
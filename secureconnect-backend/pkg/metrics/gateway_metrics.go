@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// API gateway reverse-proxy and rate limiting metrics
+var (
+	GatewayProxyInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_proxy_in_flight_requests",
+		Help: "Number of requests currently being proxied to each downstream service",
+	}, []string{"service"})
+
+	GatewayProxyRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_proxy_rejected_total",
+		Help: "Total number of requests rejected because a service's concurrency limit was reached",
+	}, []string{"service"})
+
+	GatewayProxyRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_proxy_retries_total",
+		Help: "Total number of proxied requests retried after a connection error or a 502, 503 or 504 from the service",
+	}, []string{"service", "reason"})
+
+	GatewayRateLimitBypassedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_rate_limit_bypassed_total",
+		Help: "Total number of requests from trusted internal callers that skipped rate limiting",
+	}, []string{"reason"})
+
+	GatewayLoadShedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_load_shed_total",
+		Help: "Total number of requests rejected with 503 while the gateway was overloaded",
+	}, []string{"priority"})
+)
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Push provider health metrics
+var (
+	PushProviderUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "push_provider_up",
+		Help: "Whether the last push provider health probe succeeded (1) or failed (0)",
+	}, []string{"provider"})
+
+	PushProviderCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "push_provider_circuit_state",
+		Help: "State of the push provider circuit breaker (0=closed, 1=half_open, 2=open)",
+	}, []string{"provider"})
+
+	PushProviderProbeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "push_provider_probe_total",
+		Help: "Total number of push provider health probes",
+	}, []string{"provider", "result"})
+)
+
+// SetPushProviderUp records the result of the latest push provider probe
+func SetPushProviderUp(provider string, up bool) {
+	if up {
+		PushProviderUp.WithLabelValues(provider).Set(1)
+		PushProviderProbeTotal.WithLabelValues(provider, "success").Inc()
+	} else {
+		PushProviderUp.WithLabelValues(provider).Set(0)
+		PushProviderProbeTotal.WithLabelValues(provider, "failure").Inc()
+	}
+}
+
+// SetPushProviderCircuitState records the push provider circuit breaker state
+// using the same encoding as the MinIO circuit breaker gauge
+func SetPushProviderCircuitState(provider, state string) {
+	var value float64
+	switch state {
+	case "half_open":
+		value = 1
+	case "open":
+		value = 2
+	}
+	PushProviderCircuitState.WithLabelValues(provider).Set(value)
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Signaling metrics for watching how much data the video-service relays between peers
+var (
+	SignalingRelayedMessagesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "signaling_relayed_messages_total",
+		Help: "Total number of signaling messages relayed between call participants",
+	})
+
+	SignalingRelayedBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "signaling_relayed_bytes_total",
+		Help: "Total size in bytes of signaling messages relayed between call participants",
+	})
+
+	// SignalingCallRelayedBytes is observed once per call, when its last participant disconnects
+	SignalingCallRelayedBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "signaling_call_relayed_bytes",
+		Help:    "Signaling bytes relayed over the lifetime of a call",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 8), // 1 KiB .. 16 MiB
+	})
+
+	SignalingConnectionsDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "signaling_connections_dropped_total",
+		Help: "Total number of signaling connections closed for abusing the relay",
+	}, []string{"reason"}) // message_too_large, budget_exceeded
+)
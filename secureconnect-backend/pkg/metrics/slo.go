@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	httpRequestsTotalMetric   = "http_requests_total"
+	httpRequestDurationMetric = "http_request_duration_seconds"
+)
+
+// DependencyGauge describes the gauge reporting a dependency's health
+type DependencyGauge struct {
+	Metric string
+	// Inverted is set for gauges that report 1 when the dependency is unhealthy (e.g. redis_degraded_mode)
+	Inverted bool
+}
+
+// LatencySummary holds request latency percentiles in seconds
+type LatencySummary struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// SLOSummary is a point-in-time view of a service's request error rate, latency and dependency health.
+// Counters are cumulative since process start.
+type SLOSummary struct {
+	Service      string          `json:"service"`
+	Requests     uint64          `json:"requests"`
+	Errors       uint64          `json:"errors"`
+	ErrorRate    float64         `json:"error_rate"`
+	Latency      LatencySummary  `json:"latency_seconds"`
+	Dependencies map[string]bool `json:"dependencies"`
+	Healthy      bool            `json:"healthy"`
+	GeneratedAt  time.Time       `json:"generated_at"`
+}
+
+// SLOAggregator computes SLO summaries from the metrics already collected in a Prometheus registry
+type SLOAggregator struct {
+	gatherer     prometheus.Gatherer
+	service      string
+	dependencies map[string]DependencyGauge
+}
+
+// NewSLOAggregator creates an aggregator for service reading from gatherer.
+// dependencies maps a dependency name to the gauge reporting its health.
+func NewSLOAggregator(gatherer prometheus.Gatherer, service string, dependencies map[string]DependencyGauge) *SLOAggregator {
+	return &SLOAggregator{
+		gatherer:     gatherer,
+		service:      service,
+		dependencies: dependencies,
+	}
+}
+
+// Summary gathers the registry and computes the current SLO summary.
+// Requests answered with a 5xx status count as errors.
+func (a *SLOAggregator) Summary() (*SLOSummary, error) {
+	families, err := a.gatherer.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	summary := &SLOSummary{
+		Service:      a.service,
+		Dependencies: make(map[string]bool, len(a.dependencies)),
+		Healthy:      true,
+		GeneratedAt:  time.Now().UTC(),
+	}
+
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, mf := range families {
+		byName[mf.GetName()] = mf
+	}
+
+	if mf, ok := byName[httpRequestsTotalMetric]; ok {
+		for _, m := range mf.GetMetric() {
+			if !a.matchesService(m) {
+				continue
+			}
+			count := uint64(m.GetCounter().GetValue())
+			summary.Requests += count
+			if status, err := strconv.Atoi(labelValue(m, "status")); err == nil && status >= 500 {
+				summary.Errors += count
+			}
+		}
+	}
+	if summary.Requests > 0 {
+		summary.ErrorRate = float64(summary.Errors) / float64(summary.Requests)
+	}
+
+	if mf, ok := byName[httpRequestDurationMetric]; ok {
+		buckets, total := a.mergeHistograms(mf)
+		summary.Latency = LatencySummary{
+			P50: histogramQuantile(0.50, buckets, total),
+			P95: histogramQuantile(0.95, buckets, total),
+			P99: histogramQuantile(0.99, buckets, total),
+		}
+	}
+
+	for name, dep := range a.dependencies {
+		healthy := false
+		if mf, ok := byName[dep.Metric]; ok && len(mf.GetMetric()) > 0 {
+			value := mf.GetMetric()[0].GetGauge().GetValue()
+			healthy = value > 0
+			if dep.Inverted {
+				healthy = value == 0
+			}
+		}
+		summary.Dependencies[name] = healthy
+		if !healthy {
+			summary.Healthy = false
+		}
+	}
+
+	return summary, nil
+}
+
+// matchesService filters out series recorded for another service on a shared registry
+func (a *SLOAggregator) matchesService(m *dto.Metric) bool {
+	service := labelValue(m, "service")
+	return service == "" || a.service == "" || service == a.service
+}
+
+type bucket struct {
+	upperBound float64
+	count      uint64
+}
+
+// mergeHistograms sums the cumulative bucket counts of every series of the histogram family
+func (a *SLOAggregator) mergeHistograms(mf *dto.MetricFamily) ([]bucket, uint64) {
+	counts := make(map[float64]uint64)
+	var total uint64
+	for _, m := range mf.GetMetric() {
+		if !a.matchesService(m) {
+			continue
+		}
+		h := m.GetHistogram()
+		total += h.GetSampleCount()
+		for _, b := range h.GetBucket() {
+			counts[b.GetUpperBound()] += b.GetCumulativeCount()
+		}
+	}
+
+	buckets := make([]bucket, 0, len(counts))
+	for upper, count := range counts {
+		buckets = append(buckets, bucket{upperBound: upper, count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].upperBound < buckets[j].upperBound })
+	return buckets, total
+}
+
+// histogramQuantile estimates quantile q by linear interpolation within the bucket
+// containing it, the same way PromQL's histogram_quantile does. Observations above the
+// highest finite bucket are reported as that bucket's upper bound.
+func histogramQuantile(q float64, buckets []bucket, total uint64) float64 {
+	if total == 0 || len(buckets) == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	lowerBound, lowerCount := 0.0, uint64(0)
+	for _, b := range buckets {
+		if float64(b.count) >= rank {
+			if math.IsInf(b.upperBound, 1) {
+				return lowerBound
+			}
+			inBucket := b.count - lowerCount
+			if inBucket == 0 {
+				return b.upperBound
+			}
+			return lowerBound + (b.upperBound-lowerBound)*(rank-float64(lowerCount))/float64(inBucket)
+		}
+		lowerBound, lowerCount = b.upperBound, b.count
+	}
+	return lowerBound
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, lp := range m.GetLabel() {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
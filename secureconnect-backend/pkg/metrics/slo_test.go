@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOAggregatorSummary(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	labels := prometheus.Labels{"service": "test-service"}
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: httpRequestsTotalMetric, ConstLabels: labels,
+	}, []string{"method", "endpoint", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: httpRequestDurationMetric, ConstLabels: labels, Buckets: prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+	cassandraHealthy := prometheus.NewGauge(prometheus.GaugeOpts{Name: "cassandra_session_healthy"})
+	redisDegraded := prometheus.NewGauge(prometheus.GaugeOpts{Name: "redis_degraded_mode"})
+	registry.MustRegister(requests, duration, cassandraHealthy, redisDegraded)
+
+	// 100 requests: 90 fast successes, 5 client errors, 5 slow server errors
+	for i := 0; i < 90; i++ {
+		requests.WithLabelValues("GET", "/v1/messages", "200").Inc()
+		duration.WithLabelValues("GET", "/v1/messages").Observe(0.02)
+	}
+	for i := 0; i < 5; i++ {
+		requests.WithLabelValues("POST", "/v1/messages", "400").Inc()
+		duration.WithLabelValues("POST", "/v1/messages").Observe(0.02)
+	}
+	for i := 0; i < 5; i++ {
+		requests.WithLabelValues("POST", "/v1/messages", "503").Inc()
+		duration.WithLabelValues("POST", "/v1/messages").Observe(2)
+	}
+	cassandraHealthy.Set(1)
+	redisDegraded.Set(1)
+
+	aggregator := NewSLOAggregator(registry, "test-service", map[string]DependencyGauge{
+		"cassandra": {Metric: "cassandra_session_healthy"},
+		"redis":     {Metric: "redis_degraded_mode", Inverted: true},
+	})
+
+	summary, err := aggregator.Summary()
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(100), summary.Requests)
+	assert.Equal(t, uint64(5), summary.Errors)
+	assert.InDelta(t, 0.05, summary.ErrorRate, 1e-9)
+
+	// 0.02s falls in the (0.01, 0.025] bucket, 2s in (1, 2.5]
+	assert.Greater(t, summary.Latency.P50, 0.01)
+	assert.LessOrEqual(t, summary.Latency.P50, 0.025)
+	assert.LessOrEqual(t, summary.Latency.P95, 0.025)
+	assert.Greater(t, summary.Latency.P99, 1.0)
+	assert.LessOrEqual(t, summary.Latency.P99, 2.5)
+
+	assert.True(t, summary.Dependencies["cassandra"])
+	assert.False(t, summary.Dependencies["redis"])
+	assert.False(t, summary.Healthy)
+}
+
+func TestSLOAggregatorSummary_NoTraffic(t *testing.T) {
+	aggregator := NewSLOAggregator(prometheus.NewRegistry(), "idle", nil)
+
+	summary, err := aggregator.Summary()
+	require.NoError(t, err)
+
+	assert.Zero(t, summary.Requests)
+	assert.Zero(t, summary.ErrorRate)
+	assert.Zero(t, summary.Latency.P99)
+	assert.True(t, summary.Healthy)
+}
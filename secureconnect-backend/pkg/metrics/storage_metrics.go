@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Storage usage reconciliation metrics
+var (
+	StorageUsageDriftCorrectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "storage_usage_drift_corrected_total",
+		Help: "Total number of users whose recorded storage usage differed from their stored objects and was corrected",
+	})
+
+	StorageUsageDriftBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "storage_usage_drift_bytes_total",
+		Help: "Total absolute difference in bytes between recorded and actual storage usage that was corrected",
+	})
+
+	StorageUsageReconcileRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_usage_reconcile_runs_total",
+		Help: "Total number of storage usage reconciliation runs by result",
+	}, []string{"result"}) // success, skipped, error
+)
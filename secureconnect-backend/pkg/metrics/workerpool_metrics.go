@@ -0,0 +1,14 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Worker pool metrics for async work such as event publishes
+var (
+	WorkerPoolTasksShedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "worker_pool_tasks_shed_total",
+		Help: "Total number of tasks dropped by a worker pool because its queue was full or it was shut down",
+	}, []string{"pool", "reason"})
+)
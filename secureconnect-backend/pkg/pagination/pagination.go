@@ -1,8 +1,17 @@
 package pagination
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secureconnect-backend/pkg/env"
 )
 
 // PaginationParams represents pagination query parameters
@@ -30,8 +39,55 @@ const (
 	DefaultLimit = 20
 	MaxLimit     = 100
 	MinLimit     = 1
+
+	// DefaultMaxOffset is the deepest offset accepted before clients must switch to cursors
+	DefaultMaxOffset = 10000
+)
+
+var (
+	// ErrOffsetTooLarge is returned when an offset exceeds the maximum pagination window
+	ErrOffsetTooLarge = errors.New("offset exceeds the maximum pagination window, use cursor-based pagination instead")
+
+	// ErrNegativeOffset is returned for offsets below zero
+	ErrNegativeOffset = errors.New("offset must not be negative")
+
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
 )
 
+// maxOffset bounds OFFSET scans; deep offsets make the database read and discard every skipped row.
+// Configurable with PAGINATION_MAX_OFFSET.
+var maxOffset atomic.Int64
+
+func init() {
+	maxOffset.Store(int64(env.GetInt("PAGINATION_MAX_OFFSET", DefaultMaxOffset)))
+}
+
+// MaxOffset returns the maximum offset accepted by ValidateOffset
+func MaxOffset() int {
+	return int(maxOffset.Load())
+}
+
+// SetMaxOffset overrides the maximum offset; values <= 0 restore the default
+func SetMaxOffset(n int) {
+	if n <= 0 {
+		n = DefaultMaxOffset
+	}
+	maxOffset.Store(int64(n))
+}
+
+// ValidateOffset rejects negative offsets and offsets beyond the maximum
+// pagination window
+func ValidateOffset(offset int) error {
+	if offset < 0 {
+		return ErrNegativeOffset
+	}
+	if max := MaxOffset(); offset > max {
+		return fmt.Errorf("%w (max offset %d)", ErrOffsetTooLarge, max)
+	}
+	return nil
+}
+
 // ParsePaginationParams parses pagination parameters from query string
 func ParsePaginationParams(pageStr, limitStr string, sortBy, sortOrder string) (*PaginationParams, error) {
 	page := DefaultPage
@@ -67,6 +123,9 @@ func ParsePaginationParams(pageStr, limitStr string, sortBy, sortOrder string) (
 
 	// Calculate offset
 	offset := (page - 1) * limit
+	if err := ValidateOffset(offset); err != nil {
+		return nil, err
+	}
 
 	// Validate sort order
 	if sortOrder != "asc" && sortOrder != "desc" {
@@ -127,3 +186,39 @@ func GetSortClause(sortBy, sortOrder string) string {
 	}
 	return fmt.Sprintf("ORDER BY %s %s", sortBy, order)
 }
+
+// Cursor identifies the last row of a page for keyset pagination over
+// (created_at, id) in descending order
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// EncodeCursor returns an opaque cursor for the row with the given created_at and id
+func EncodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor
+func DecodeCursor(cursor string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, idStr, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	ns, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: time.Unix(0, ns).UTC(), ID: id}, nil
+}
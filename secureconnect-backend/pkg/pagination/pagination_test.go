@@ -0,0 +1,43 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOffset(t *testing.T) {
+	SetMaxOffset(1000)
+	defer SetMaxOffset(DefaultMaxOffset)
+
+	assert.NoError(t, ValidateOffset(0))
+	assert.NoError(t, ValidateOffset(1000))
+	assert.ErrorIs(t, ValidateOffset(1001), ErrOffsetTooLarge)
+	assert.ErrorIs(t, ValidateOffset(-1), ErrNegativeOffset)
+
+	// Page 60 with limit 20 is offset 1180
+	_, err := ParsePaginationParams("60", "20", "created_at", "desc")
+	assert.ErrorIs(t, err, ErrOffsetTooLarge)
+
+	params, err := ParsePaginationParams("51", "20", "created_at", "desc")
+	require.NoError(t, err)
+	assert.Equal(t, 1000, params.Offset)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	id := uuid.New()
+
+	cursor, err := DecodeCursor(EncodeCursor(createdAt, id))
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(createdAt))
+	assert.Equal(t, id, cursor.ID)
+
+	for _, bad := range []string{"", "!!!", EncodeCursor(createdAt, id)[:10]} {
+		_, err := DecodeCursor(bad)
+		assert.ErrorIs(t, err, ErrInvalidCursor, bad)
+	}
+}
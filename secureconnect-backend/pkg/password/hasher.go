@@ -0,0 +1,253 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// ErrUnknownHashFormat is returned when a stored hash matches no supported algorithm
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Hasher hashes and verifies passwords
+type Hasher interface {
+	// Hash returns an encoded hash that records its algorithm and parameters
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. A mismatch is not an error.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash should be replaced with a fresh Hash
+	NeedsRehash(hash string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher creates a bcrypt hasher; a cost outside bcrypt's range uses the default
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{Cost: cost}
+}
+
+// ValidateBcryptCost returns an error when cost is outside bcrypt's range.
+// 0 is accepted and means the default cost.
+func ValidateBcryptCost(cost int) error {
+	if cost != 0 && (cost < bcrypt.MinCost || cost > bcrypt.MaxCost) {
+		return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	return nil
+}
+
+// Hash implements Hasher
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify implements Hasher
+func (h *BcryptHasher) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash implements Hasher
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// Argon2idParams are the argon2id cost parameters
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follows the OWASP recommendation of 64 MiB, 3 passes
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2idHasher hashes passwords with argon2id, encoded in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type Argon2idHasher struct {
+	Params Argon2idParams
+}
+
+// NewArgon2idHasher creates an argon2id hasher
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{Params: params}
+}
+
+// Hash implements Hasher
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	p := h.Params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify implements Hasher
+func (h *Argon2idHasher) Verify(hash, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash implements Hasher
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Params.Memory || params.Iterations != h.Params.Iterations ||
+		params.Parallelism != h.Params.Parallelism ||
+		uint32(len(salt)) != h.Params.SaltLength || uint32(len(key)) != h.Params.KeyLength
+}
+
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+	return params, salt, key, nil
+}
+
+// DetectAlgorithm returns the algorithm of an encoded hash from its prefix
+func DetectAlgorithm(hash string) (string, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return AlgorithmArgon2id, nil
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return AlgorithmBcrypt, nil
+	default:
+		return "", ErrUnknownHashFormat
+	}
+}
+
+// MigratingHasher hashes new passwords with a target algorithm while still
+// verifying hashes made by any supported algorithm. Hashes not made by the
+// target with its current parameters report NeedsRehash, so callers can
+// upgrade them the next time the password is presented.
+type MigratingHasher struct {
+	target    string
+	verifiers map[string]Hasher
+}
+
+// NewMigratingHasher creates a hasher that writes with target and reads
+// bcrypt and argon2id hashes
+func NewMigratingHasher(target string, bcryptHasher *BcryptHasher, argon2idHasher *Argon2idHasher) (*MigratingHasher, error) {
+	h := &MigratingHasher{
+		target: target,
+		verifiers: map[string]Hasher{
+			AlgorithmBcrypt:   bcryptHasher,
+			AlgorithmArgon2id: argon2idHasher,
+		},
+	}
+	if _, ok := h.verifiers[target]; !ok {
+		return nil, fmt.Errorf("unsupported password algorithm %q", target)
+	}
+	return h, nil
+}
+
+// NewHasher creates a MigratingHasher for target with default argon2id
+// parameters and the given bcrypt cost; 0 uses bcrypt's default. Bcrypt
+// hashes made with another cost are rehashed on login.
+func NewHasher(target string, bcryptCost int) (*MigratingHasher, error) {
+	if err := ValidateBcryptCost(bcryptCost); err != nil {
+		return nil, err
+	}
+	return NewMigratingHasher(target, NewBcryptHasher(bcryptCost), NewArgon2idHasher(DefaultArgon2idParams()))
+}
+
+// DefaultHasher writes bcrypt hashes and reads both algorithms
+func DefaultHasher() *MigratingHasher {
+	return &MigratingHasher{
+		target: AlgorithmBcrypt,
+		verifiers: map[string]Hasher{
+			AlgorithmBcrypt:   NewBcryptHasher(0),
+			AlgorithmArgon2id: NewArgon2idHasher(DefaultArgon2idParams()),
+		},
+	}
+}
+
+// Hash implements Hasher using the target algorithm
+func (h *MigratingHasher) Hash(password string) (string, error) {
+	return h.verifiers[h.target].Hash(password)
+}
+
+// Verify implements Hasher using the algorithm the hash was made with
+func (h *MigratingHasher) Verify(hash, password string) (bool, error) {
+	algorithm, err := DetectAlgorithm(hash)
+	if err != nil {
+		return false, err
+	}
+	return h.verifiers[algorithm].Verify(hash, password)
+}
+
+// NeedsRehash implements Hasher
+func (h *MigratingHasher) NeedsRehash(hash string) bool {
+	algorithm, err := DetectAlgorithm(hash)
+	if err != nil || algorithm != h.target {
+		return true
+	}
+	return h.verifiers[algorithm].NeedsRehash(hash)
+}
@@ -0,0 +1,76 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keeps tests fast
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestMigratingHasher_VerifiesBothAlgorithms(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	argon2idHasher := NewArgon2idHasher(testArgon2idParams)
+	hasher, err := NewMigratingHasher(AlgorithmArgon2id, bcryptHasher, argon2idHasher)
+	require.NoError(t, err)
+
+	for _, h := range []Hasher{bcryptHasher, argon2idHasher} {
+		hash, err := h.Hash("correct horse")
+		require.NoError(t, err)
+
+		ok, err := hasher.Verify(hash, "correct horse")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = hasher.Verify(hash, "wrong horse")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
+
+func TestMigratingHasher_NeedsRehash(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	hasher, err := NewMigratingHasher(AlgorithmArgon2id, bcryptHasher, NewArgon2idHasher(testArgon2idParams))
+	require.NoError(t, err)
+
+	bcryptHash, err := bcryptHasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, hasher.NeedsRehash(bcryptHash), "bcrypt hashes move to the target")
+
+	targetHash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.False(t, hasher.NeedsRehash(targetHash))
+
+	stronger, err := NewMigratingHasher(AlgorithmArgon2id, bcryptHasher, NewArgon2idHasher(Argon2idParams{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}))
+	require.NoError(t, err)
+	assert.True(t, stronger.NeedsRehash(targetHash), "changed parameters trigger a rehash")
+
+	_, err = NewHasher("md5", 0)
+	assert.Error(t, err)
+	_, err = hasher.Verify("plaintext", "plaintext")
+	assert.ErrorIs(t, err, ErrUnknownHashFormat)
+}
+
+func TestNewHasher_BcryptCost(t *testing.T) {
+	weak, err := NewHasher(AlgorithmBcrypt, bcrypt.MinCost)
+	require.NoError(t, err)
+	hash, err := weak.Hash("correct horse")
+	require.NoError(t, err)
+	cost, err := bcrypt.Cost([]byte(hash))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+	assert.False(t, weak.NeedsRehash(hash))
+
+	stronger, err := NewHasher(AlgorithmBcrypt, bcrypt.MinCost+1)
+	require.NoError(t, err)
+	ok, err := stronger.Verify(hash, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok, "hashes made with another cost still verify")
+	assert.True(t, stronger.NeedsRehash(hash), "and are upgraded to the configured cost")
+
+	_, err = NewHasher(AlgorithmBcrypt, bcrypt.MaxCost+1)
+	assert.Error(t, err)
+}
@@ -318,3 +318,28 @@ func (a *APNsProvider) SendSilentNotification(ctx context.Context, data map[stri
 
 	return resp, nil
 }
+
+// apnsProbeDeviceToken is a syntactically valid token that no device owns
+const apnsProbeDeviceToken = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// HealthCheck implements HealthChecker by pushing to a token no device owns.
+// APNs rejects it with BadDeviceToken once the connection and credentials are
+// accepted, so that response counts as healthy.
+func (a *APNsProvider) HealthCheck(ctx context.Context) error {
+	if a.client == nil {
+		return fmt.Errorf("APNs client is not initialized")
+	}
+
+	res, err := a.client.PushWithContext(ctx, &apns2.Notification{
+		DeviceToken: apnsProbeDeviceToken,
+		Topic:       a.bundleID,
+		Payload:     []byte(`{"aps":{"content-available":1}}`),
+	})
+	if err != nil {
+		return fmt.Errorf("APNs probe failed: %w", err)
+	}
+	if res.Sent() || res.Reason == apns2.ReasonBadDeviceToken {
+		return nil
+	}
+	return fmt.Errorf("APNs probe rejected: status=%d reason=%s", res.StatusCode, res.Reason)
+}
@@ -306,3 +306,18 @@ func (f *FCMProvider) UnsubscribeFromTopic(ctx context.Context, tokens []string,
 
 	return nil
 }
+
+// HealthCheck implements HealthChecker by validating a message with a dry run
+func (f *FCMProvider) HealthCheck(ctx context.Context) error {
+	if f.app == nil {
+		return fmt.Errorf("FCM app is not initialized")
+	}
+	client, err := f.app.Messaging(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get messaging client: %w", err)
+	}
+	if _, err := client.SendDryRun(ctx, healthProbeMessage()); err != nil {
+		return fmt.Errorf("FCM dry run failed: %w", err)
+	}
+	return nil
+}
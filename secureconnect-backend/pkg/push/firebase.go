@@ -298,3 +298,15 @@ func (f *FirebaseProvider) IsInitialized() bool {
 func (f *FirebaseProvider) GetProjectID() string {
 	return f.projectID
 }
+
+// HealthCheck implements HealthChecker by validating a message with a dry run,
+// which exercises the credentials and the FCM API without delivering anything
+func (f *FirebaseProvider) HealthCheck(ctx context.Context) error {
+	if !f.initialized {
+		return fmt.Errorf("firebase provider is not initialized")
+	}
+	if _, err := f.client.SendDryRun(ctx, healthProbeMessage()); err != nil {
+		return fmt.Errorf("firebase dry run failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package push
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/resilience"
+)
+
+const (
+	// DefaultHealthProbeInterval is how often the push provider is probed
+	DefaultHealthProbeInterval = time.Minute
+
+	// healthProbeTimeout bounds a single provider probe
+	healthProbeTimeout = 10 * time.Second
+
+	// healthProbeFailureThreshold is the number of consecutive failed probes that opens the circuit
+	healthProbeFailureThreshold = 3
+)
+
+// HealthChecker is implemented by providers that can verify their credentials and
+// connectivity without delivering a notification
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// healthProbeMessage is validated (never delivered) by FCM dry-run probes
+func healthProbeMessage() *messaging.Message {
+	return &messaging.Message{
+		Topic: "health-check",
+		Data:  map[string]string{"probe": "1"},
+	}
+}
+
+// HealthStatus is a snapshot of the push provider health
+type HealthStatus struct {
+	Provider            string                         `json:"provider"`
+	Healthy             bool                           `json:"healthy"`
+	CircuitState        resilience.CircuitBreakerState `json:"circuit_state"`
+	ConsecutiveFailures int                            `json:"consecutive_failures"`
+	LastError           string                         `json:"last_error,omitempty"`
+	LastCheckedAt       time.Time                      `json:"last_checked_at"`
+}
+
+// HealthProbe periodically probes a push provider and tracks its health.
+// After healthProbeFailureThreshold consecutive failures the circuit opens; it
+// goes half-open on the next successful probe and closes after a second one.
+type HealthProbe struct {
+	name    string
+	checker HealthChecker
+
+	mu     sync.RWMutex
+	status HealthStatus
+}
+
+// NewHealthProbe creates a probe for the named provider
+func NewHealthProbe(name string, checker HealthChecker) *HealthProbe {
+	return &HealthProbe{
+		name:    name,
+		checker: checker,
+		status: HealthStatus{
+			Provider:     name,
+			Healthy:      true,
+			CircuitState: resilience.CircuitBreakerClosed,
+		},
+	}
+}
+
+// Start runs an initial probe and then probes every interval until ctx is cancelled
+func (p *HealthProbe) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHealthProbeInterval
+	}
+
+	go func() {
+		p.Check(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check probes the provider once and updates the health status and metrics
+func (p *HealthProbe) Check(ctx context.Context) HealthStatus {
+	probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	err := p.checker.HealthCheck(probeCtx)
+
+	p.mu.Lock()
+	previous := p.status.CircuitState
+	p.status.LastCheckedAt = time.Now()
+	if err != nil {
+		p.status.Healthy = false
+		p.status.LastError = err.Error()
+		p.status.ConsecutiveFailures++
+		if p.status.ConsecutiveFailures >= healthProbeFailureThreshold {
+			p.status.CircuitState = resilience.CircuitBreakerOpen
+		}
+	} else {
+		p.status.Healthy = true
+		p.status.LastError = ""
+		p.status.ConsecutiveFailures = 0
+		switch p.status.CircuitState {
+		case resilience.CircuitBreakerOpen:
+			p.status.CircuitState = resilience.CircuitBreakerHalfOpen
+		case resilience.CircuitBreakerHalfOpen:
+			p.status.CircuitState = resilience.CircuitBreakerClosed
+		}
+	}
+	status := p.status
+	p.mu.Unlock()
+
+	metrics.SetPushProviderUp(p.name, status.Healthy)
+	metrics.SetPushProviderCircuitState(p.name, string(status.CircuitState))
+
+	if err != nil {
+		logger.Warn("Push provider health probe failed",
+			zap.String("provider", p.name),
+			zap.Int("consecutive_failures", status.ConsecutiveFailures),
+			zap.String("circuit_state", string(status.CircuitState)),
+			zap.Error(err))
+	} else if previous != status.CircuitState {
+		logger.Info("Push provider recovering",
+			zap.String("provider", p.name),
+			zap.String("circuit_state", string(status.CircuitState)))
+	}
+
+	return status
+}
+
+// Status returns the latest health status
+func (p *HealthProbe) Status() HealthStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
@@ -0,0 +1,63 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+	"secureconnect-backend/pkg/resilience"
+)
+
+// toggleProvider is a fake provider whose health can be switched on and off
+type toggleProvider struct {
+	healthy bool
+}
+
+func (p *toggleProvider) HealthCheck(ctx context.Context) error {
+	if !p.healthy {
+		return errors.New("credentials expired")
+	}
+	return nil
+}
+
+func TestHealthProbe_TracksProviderHealth(t *testing.T) {
+	logger.InitDefault("test")
+	ctx := context.Background()
+
+	provider := &toggleProvider{healthy: true}
+	probe := NewHealthProbe("fake", provider)
+	up := func() float64 { return testutil.ToFloat64(metrics.PushProviderUp.WithLabelValues("fake")) }
+
+	status := probe.Check(ctx)
+	assert.True(t, status.Healthy)
+	assert.Equal(t, resilience.CircuitBreakerClosed, status.CircuitState)
+	assert.Equal(t, 1.0, up())
+
+	// Failures mark the provider down and open the circuit at the threshold
+	provider.healthy = false
+	for i := 1; i < healthProbeFailureThreshold; i++ {
+		status = probe.Check(ctx)
+		assert.False(t, status.Healthy)
+		assert.Equal(t, resilience.CircuitBreakerClosed, status.CircuitState)
+	}
+	status = probe.Check(ctx)
+	assert.Equal(t, resilience.CircuitBreakerOpen, status.CircuitState)
+	assert.Equal(t, "credentials expired", status.LastError)
+	assert.Equal(t, 0.0, up())
+
+	// Recovery goes through half-open before closing
+	provider.healthy = true
+	status = probe.Check(ctx)
+	assert.True(t, status.Healthy)
+	assert.Equal(t, resilience.CircuitBreakerHalfOpen, status.CircuitState)
+	assert.Equal(t, 1.0, up())
+
+	status = probe.Check(ctx)
+	assert.Equal(t, resilience.CircuitBreakerClosed, status.CircuitState)
+	assert.Equal(t, probe.Status(), status)
+}
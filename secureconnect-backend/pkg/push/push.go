@@ -70,6 +70,15 @@ type Token struct {
 	UpdatedAt int64     `json:"updated_at"`
 }
 
+// MaskToken hides a token for display, keeping only its last 6 characters so
+// support can tell devices apart
+func MaskToken(token string) string {
+	if len(token) < 12 {
+		return "********"
+	}
+	return "********" + token[len(token)-6:]
+}
+
 // TokenRepository defines interface for storing and retrieving push tokens
 type TokenRepository interface {
 	Store(ctx context.Context, token *Token) error
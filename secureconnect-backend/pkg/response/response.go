@@ -54,6 +54,23 @@ func Error(c *gin.Context, statusCode int, errorCode, errorMessage string) {
 	})
 }
 
+// ErrorWithData sends an error response that also carries data the client
+// needs to recover, such as where to upgrade
+func ErrorWithData(c *gin.Context, statusCode int, errorCode, errorMessage string, data interface{}) {
+	c.JSON(statusCode, Response{
+		Success: false,
+		Data:    data,
+		Error: &ErrorDetail{
+			Code:    errorCode,
+			Message: errorMessage,
+		},
+		Meta: Meta{
+			Timestamp: time.Now().UTC(),
+			RequestID: getRequestID(c),
+		},
+	})
+}
+
 // ValidationError sends a validation error response (400)
 func ValidationError(c *gin.Context, message string) {
 	Error(c, 400, "VALIDATION_ERROR", message)
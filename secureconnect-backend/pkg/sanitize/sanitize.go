@@ -35,6 +35,31 @@ func SanitizeEmail(email string) string {
 	return email
 }
 
+// NormalizeEmail returns the canonical form used to store and compare emails:
+// trimmed and lowercased. With stripGmailAliases, dots and "+tag" suffixes are
+// removed from Gmail local parts and googlemail.com is folded into gmail.com.
+func NormalizeEmail(email string, stripGmailAliases bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !stripGmailAliases {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if domain != "gmail.com" && domain != "googlemail.com" {
+		return email
+	}
+
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	local = strings.ReplaceAll(local, ".", "")
+	return local + "@gmail.com"
+}
+
 // SanitizeUsername sanitizes username input
 func SanitizeUsername(username string) string {
 	// Trim whitespace
@@ -0,0 +1,106 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// Sequence shuts a service down in a fixed order so nothing touches a data
+// store after it is closed:
+//
+//  1. servers stop accepting connections and drain in-flight requests
+//  2. the background context is cancelled, stopping health checks and jobs
+//  3. data stores are closed, last registered first
+//
+// Mains register each dependency as it is created instead of deferring Close.
+type Sequence struct {
+	mu      sync.Mutex
+	servers []step
+	cancels []context.CancelFunc
+	closers []step
+}
+
+type step struct {
+	name string
+	run  func() error
+}
+
+// NewSequence creates an empty shutdown sequence
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+// Background returns a context for background goroutines that is cancelled
+// once the servers have drained
+func (s *Sequence) Background(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	s.mu.Lock()
+	s.cancels = append(s.cancels, cancel)
+	s.mu.Unlock()
+	return ctx
+}
+
+// OnStop registers a step that stops accepting requests and drains those in flight
+func (s *Sequence) OnStop(name string, fn func() error) {
+	s.mu.Lock()
+	s.servers = append(s.servers, step{name: name, run: fn})
+	s.mu.Unlock()
+}
+
+// StopServer registers server to be shut down with Graceful
+func (s *Sequence) StopServer(server *http.Server, timeout time.Duration, tracker *InFlightTracker) {
+	s.OnStop("http server", func() error {
+		return Graceful(server, timeout, tracker)
+	})
+}
+
+// OnClose registers a data store to be closed after servers and background
+// goroutines have stopped
+func (s *Sequence) OnClose(name string, fn func() error) {
+	s.mu.Lock()
+	s.closers = append(s.closers, step{name: name, run: fn})
+	s.mu.Unlock()
+}
+
+// Run executes the sequence. Every step runs even if an earlier one fails;
+// the failures are logged and returned together.
+func (s *Sequence) Run() error {
+	s.mu.Lock()
+	servers := s.servers
+	cancels := s.cancels
+	closers := s.closers
+	s.servers, s.cancels, s.closers = nil, nil, nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, st := range servers {
+		errs = append(errs, runStep("stop", st))
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		errs = append(errs, runStep("close", closers[i]))
+	}
+	return errors.Join(errs...)
+}
+
+func runStep(action string, st step) error {
+	if err := st.run(); err != nil {
+		logger.Error("Shutdown step failed",
+			zap.String("action", action),
+			zap.String("name", st.name),
+			zap.Error(err))
+		return fmt.Errorf("%s %s: %w", action, st.name, err)
+	}
+	logger.Debug("Shutdown step finished", zap.String("action", action), zap.String("name", st.name))
+	return nil
+}
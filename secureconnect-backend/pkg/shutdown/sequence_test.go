@@ -0,0 +1,86 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// recorder collects the order in which shutdown steps ran
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.steps...)
+}
+
+func TestSequence_RunsServersThenBackgroundThenStores(t *testing.T) {
+	logger.InitDefault("test")
+	rec := &recorder{}
+	seq := NewSequence()
+
+	ctx := seq.Background(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		rec.add("background")
+		close(stopped)
+	}()
+
+	seq.OnClose("cockroach", func() error {
+		rec.add("cockroach")
+		return nil
+	})
+	seq.OnClose("redis", func() error {
+		<-stopped
+		assert.Error(t, ctx.Err(), "background context must be cancelled before stores close")
+		rec.add("redis")
+		return nil
+	})
+	seq.OnStop("server", func() error {
+		assert.NoError(t, ctx.Err(), "background work keeps running while requests drain")
+		rec.add("server")
+		return nil
+	})
+
+	require.NoError(t, seq.Run())
+	assert.Equal(t, []string{"server", "background", "redis", "cockroach"}, rec.get())
+}
+
+func TestSequence_ContinuesAfterFailure(t *testing.T) {
+	logger.InitDefault("test")
+	rec := &recorder{}
+	seq := NewSequence()
+	ctx := seq.Background(context.Background())
+
+	seq.OnStop("server", func() error {
+		rec.add("server")
+		return errors.New("shutdown timed out")
+	})
+	seq.OnClose("redis", func() error {
+		rec.add("redis")
+		return nil
+	})
+
+	err := seq.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stop server")
+	assert.Equal(t, []string{"server", "redis"}, rec.get())
+	assert.Error(t, ctx.Err(), "background context is cancelled even when a server fails to stop")
+}
@@ -0,0 +1,113 @@
+// Package shutdown provides graceful HTTP server shutdown with in-flight request tracking.
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// InFlightRequest describes a request that is currently being served
+type InFlightRequest struct {
+	Method    string
+	Path      string
+	RemoteIP  string
+	StartedAt time.Time
+}
+
+// InFlightTracker records the requests currently being served so they can be
+// reported when a shutdown times out
+type InFlightTracker struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]InFlightRequest
+}
+
+// NewInFlightTracker creates a new in-flight request tracker
+func NewInFlightTracker() *InFlightTracker {
+	return &InFlightTracker{
+		active: make(map[uint64]InFlightRequest),
+	}
+}
+
+// Middleware returns a Gin middleware that registers each request for its duration
+func (t *InFlightTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := t.add(InFlightRequest{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			RemoteIP:  c.ClientIP(),
+			StartedAt: time.Now(),
+		})
+		defer t.remove(id)
+
+		c.Next()
+	}
+}
+
+func (t *InFlightTracker) add(req InFlightRequest) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.active[t.nextID] = req
+	return t.nextID
+}
+
+func (t *InFlightTracker) remove(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.active, id)
+}
+
+// Snapshot returns the requests currently in flight, oldest first
+func (t *InFlightTracker) Snapshot() []InFlightRequest {
+	t.mu.Lock()
+	requests := make([]InFlightRequest, 0, len(t.active))
+	for _, req := range t.active {
+		requests = append(requests, req)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].StartedAt.Before(requests[j].StartedAt) })
+	return requests
+}
+
+// Graceful stops accepting new connections and waits up to timeout for in-flight
+// requests to finish. Requests still running when the timeout is hit are logged
+// and their connections are closed.
+func Graceful(server *http.Server, timeout time.Duration, tracker *InFlightTracker) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+
+	if tracker != nil {
+		pending := tracker.Snapshot()
+		logger.Warn("Shutdown timeout reached with requests still in flight",
+			zap.Duration("timeout", timeout),
+			zap.Int("in_flight", len(pending)))
+		for _, req := range pending {
+			logger.Warn("Aborting in-flight request",
+				zap.String("method", req.Method),
+				zap.String("path", req.Path),
+				zap.String("remote_ip", req.RemoteIP),
+				zap.Duration("elapsed", time.Since(req.StartedAt)))
+		}
+	}
+
+	// Force-close whatever is left so the process can release its resources
+	if cerr := server.Close(); cerr != nil {
+		logger.Error("Failed to close server", zap.Error(cerr))
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package shutdown
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+)
+
+// startSlowServer serves a /slow endpoint that takes handlerDuration to respond
+func startSlowServer(t *testing.T, handlerDuration time.Duration, tracker *InFlightTracker) (*http.Server, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(tracker.Middleware())
+	router.GET("/slow", func(c *gin.Context) {
+		select {
+		case <-time.After(handlerDuration):
+			c.Status(http.StatusOK)
+		case <-c.Request.Context().Done():
+		}
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: router}
+	go server.Serve(ln)
+	return server, "http://" + ln.Addr().String() + "/slow"
+}
+
+// startRequest issues a request in the background and waits until the server has picked it up
+func startRequest(t *testing.T, url string, tracker *InFlightTracker) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = assert.AnError
+			}
+		}
+		result <- err
+	}()
+
+	require.Eventually(t, func() bool { return len(tracker.Snapshot()) == 1 }, time.Second, 5*time.Millisecond)
+	return result
+}
+
+func TestGraceful_RequestFinishesWithinTimeout(t *testing.T) {
+	logger.InitDefault("test")
+	tracker := NewInFlightTracker()
+	server, url := startSlowServer(t, 200*time.Millisecond, tracker)
+
+	result := startRequest(t, url, tracker)
+
+	err := Graceful(server, 2*time.Second, tracker)
+	assert.NoError(t, err)
+	assert.NoError(t, <-result, "request should complete before the server stops")
+	assert.Empty(t, tracker.Snapshot())
+}
+
+func TestGraceful_RequestAbortedAfterTimeout(t *testing.T) {
+	logger.InitDefault("test")
+	tracker := NewInFlightTracker()
+	server, url := startSlowServer(t, 10*time.Second, tracker)
+
+	result := startRequest(t, url, tracker)
+
+	start := time.Now()
+	err := Graceful(server, 100*time.Millisecond, tracker)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	select {
+	case reqErr := <-result:
+		assert.Error(t, reqErr, "request should be cut off once the timeout is hit")
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not terminated after shutdown timeout")
+	}
+}
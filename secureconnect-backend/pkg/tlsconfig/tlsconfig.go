@@ -0,0 +1,95 @@
+// Package tlsconfig builds the TLS settings used for outbound connections,
+// so SMTP, webhook and other clients enforce one minimum protocol version
+// and cipher suite policy.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultMinVersion is the minimum TLS version used when none is configured
+const DefaultMinVersion = "1.2"
+
+var (
+	// ErrUnsupportedVersion is returned for a minimum version below TLS 1.2 or not recognized
+	ErrUnsupportedVersion = errors.New("unsupported TLS minimum version")
+	// ErrUnsupportedCipherSuite is returned for a cipher suite that is unknown, insecure or not configurable
+	ErrUnsupportedCipherSuite = errors.New("unsupported TLS cipher suite")
+)
+
+// versions maps the accepted minimum versions to their protocol numbers.
+// TLS 1.0 and 1.1 are deliberately absent.
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Policy is the TLS policy for outbound connections
+type Policy struct {
+	// MinVersion is "1.2" or "1.3"; empty means DefaultMinVersion
+	MinVersion string
+	// CipherSuites are the IANA names of the TLS 1.2 cipher suites to offer;
+	// empty means Go's secure defaults. TLS 1.3 suites are not configurable.
+	CipherSuites []string
+}
+
+// New returns a client TLS config that enforces p. Peers that only offer an
+// older protocol version or none of the allowed cipher suites fail the
+// handshake. Callers set ServerName on a Clone when it differs per connection.
+func New(p Policy) (*tls.Config, error) {
+	minVersion := p.MinVersion
+	if minVersion == "" {
+		minVersion = DefaultMinVersion
+	}
+	version, ok := versions[strings.TrimPrefix(strings.ToLower(minVersion), "tls")]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q, must be 1.2 or 1.3", ErrUnsupportedVersion, minVersion)
+	}
+
+	suites, err := cipherSuiteIDs(p.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:   version,
+		CipherSuites: suites,
+	}, nil
+}
+
+// cipherSuiteIDs resolves names to the IDs of Go's secure TLS 1.2 cipher
+// suites. Nil names give nil, which leaves the choice to Go.
+func cipherSuiteIDs(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		suite, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedCipherSuite, name)
+		}
+		if !supportsTLS12(suite) {
+			return nil, fmt.Errorf("%w: %q is a TLS 1.3 suite, which Go always enables", ErrUnsupportedCipherSuite, name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
+}
+
+func supportsTLS12(suite *tls.CipherSuite) bool {
+	for _, v := range suite.SupportedVersions {
+		if v == tls.VersionTLS12 {
+			return true
+		}
+	}
+	return false
+}
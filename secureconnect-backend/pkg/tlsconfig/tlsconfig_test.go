@@ -0,0 +1,75 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_MinVersion(t *testing.T) {
+	cfg, err := New(Policy{})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Nil(t, cfg.CipherSuites)
+
+	cfg, err = New(Policy{MinVersion: "1.3"})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+
+	for _, version := range []string{"1.0", "1.1", "ssl3", "2"} {
+		_, err := New(Policy{MinVersion: version})
+		assert.ErrorIs(t, err, ErrUnsupportedVersion, version)
+	}
+}
+
+func TestNew_CipherSuites(t *testing.T) {
+	cfg, err := New(Policy{CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, cfg.CipherSuites)
+
+	for _, name := range []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_AES_128_GCM_SHA256", "NOT_A_SUITE"} {
+		_, err := New(Policy{CipherSuites: []string{name}})
+		assert.ErrorIs(t, err, ErrUnsupportedCipherSuite, name)
+	}
+}
+
+func TestNew_RejectsOlderPeer(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	server.StartTLS()
+	defer server.Close()
+
+	cfg, err := New(Policy{})
+	require.NoError(t, err)
+	cfg.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "protocol version")
+}
+
+func TestNew_AcceptsCompliantPeer(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	cfg, err := New(Policy{})
+	require.NoError(t, err)
+	cfg.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
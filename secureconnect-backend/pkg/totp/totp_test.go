@@ -0,0 +1,59 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 seed from RFC 6238 Appendix B, "12345678901234567890"
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP_RFC6238Vectors(t *testing.T) {
+	key, err := decodeSecret(rfcSecret)
+	require.NoError(t, err)
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, want := range vectors {
+		assert.Equal(t, want, hotp(key, Step(time.Unix(unix, 0)), 8), "T=%d", unix)
+	}
+}
+
+func TestValidate_AcceptsOneStepOfSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	for _, offset := range []time.Duration{-Period, 0, Period} {
+		code, err := Code(secret, now.Add(offset))
+		require.NoError(t, err)
+		step, ok := Validate(secret, code, now)
+		assert.True(t, ok, "offset %s", offset)
+		assert.Equal(t, Step(now.Add(offset)), step)
+	}
+
+	stale, err := Code(secret, now.Add(-2*Period))
+	require.NoError(t, err)
+	_, ok := Validate(secret, stale, now)
+	assert.False(t, ok, "codes two steps old are rejected")
+
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("SecureConnect", "alice@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/SecureConnect:alice@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=SecureConnect")
+}
@@ -0,0 +1,41 @@
+package urlguard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowlist_Validate(t *testing.T) {
+	allow := NewAllowlist([]string{" App.Example.com ", "localhost:9090", ""})
+
+	allowed := []string{
+		"https://app.example.com/reset-password?token=abc",
+		"https://APP.example.com:443/verify-email",
+		"http://localhost:9090/login",
+	}
+	for _, u := range allowed {
+		assert.NoError(t, allow.Validate(u), u)
+	}
+
+	rejected := []string{
+		"https://evil.example.net/reset-password?token=abc",
+		"https://app.example.com.evil.net/",
+		"https://sub.app.example.com/",
+		"https://app.example.com@evil.net/",
+		"https://app.example.com:8443/",
+		"http://localhost:8080/",
+		"javascript:alert(1)",
+		"//app.example.com/",
+		"/reset-password",
+	}
+	for _, u := range rejected {
+		assert.ErrorIs(t, allow.Validate(u), ErrUntrustedURL, u)
+	}
+}
+
+func TestAllowlist_EmptyRejectsEverything(t *testing.T) {
+	allow := NewAllowlist(nil)
+	assert.True(t, allow.Empty())
+	assert.ErrorIs(t, allow.Validate("https://app.example.com/"), ErrUntrustedURL)
+}
@@ -0,0 +1,185 @@
+// Package webhook delivers signed JSON events to third-party HTTP endpoints.
+//
+// Every request carries a SignatureHeader of the form "t=<unix>,v1=<hex>",
+// where v1 is the HMAC-SHA256 of "<unix>.<body>" keyed with the endpoint's
+// secret. Receivers should recompute it with Verify and reject old timestamps
+// to prevent replays.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the request timestamp and HMAC signature
+	SignatureHeader = "X-SecureConnect-Signature"
+	// EventHeader names the event in the body
+	EventHeader = "X-SecureConnect-Event"
+
+	// DefaultTimeout bounds a single delivery
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultTolerance is how old a signature Verify accepts
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidURL is returned for endpoints that are not absolute http(s) URLs
+	ErrInvalidURL = errors.New("invalid webhook url")
+	// ErrPrivateAddress is returned when an endpoint resolves to a loopback,
+	// private or link-local address
+	ErrPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+	// ErrInvalidSignature is returned by Verify for missing, stale or wrong signatures
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Sign returns the SignatureHeader value for body sent at timestamp
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(secret, unix, body)
+}
+
+// Verify checks a SignatureHeader value against body and rejects signatures
+// older than tolerance
+func Verify(secret []byte, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+	sec, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(signature), []byte(computeSignature(secret, unix, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func computeSignature(secret []byte, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateURL returns nil if rawURL is an absolute http(s) URL without user
+// info. Whether the host is reachable is only checked when delivering.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: %q is not an absolute URL", ErrInvalidURL, rawURL)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrInvalidURL, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: %q contains user info", ErrInvalidURL, rawURL)
+	}
+	return nil
+}
+
+// Sender posts signed events. Connections to loopback, private and
+// link-local addresses are refused at dial time, so a webhook cannot be
+// pointed at internal services, including through DNS rebinding or redirects.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender whose deliveries time out after timeout
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, false)
+}
+
+func newSender(timeout time.Duration, allowPrivate bool) *Sender {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivateAddress
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+// SetTLSConfig sets the TLS policy for deliveries, such as the minimum
+// version and cipher suites. Without it Go's defaults apply.
+func (s *Sender) SetTLSConfig(cfg *tls.Config) {
+	s.client.Transport.(*http.Transport).TLSClientConfig = cfg
+}
+
+// Send posts payload as JSON to endpoint, signed with secret. Any response
+// other than 2xx is an error.
+func (s *Sender) Send(ctx context.Context, endpoint string, secret []byte, event string, payload interface{}) error {
+	if err := ValidateURL(endpoint); err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SecureConnect-Webhook/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// refusePrivateAddress is a net.Dialer Control func rejecting internal addresses
+func refusePrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
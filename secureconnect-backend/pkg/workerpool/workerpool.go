@@ -0,0 +1,122 @@
+// Package workerpool runs fire-and-forget tasks on a fixed number of workers
+// fed by a bounded queue, so a burst of work cannot spawn unbounded goroutines.
+package workerpool
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+// Reasons a task is shed, used as the metric's reason label
+const (
+	ShedQueueFull = "queue_full"
+	ShedShutdown  = "shutdown"
+)
+
+// Pool runs submitted tasks on a fixed number of workers. Workers start with
+// the first Submit, so an unused pool holds no goroutines.
+type Pool struct {
+	name    string
+	workers int
+	tasks   chan func()
+
+	start sync.Once
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New creates a pool named name, used in logs and metrics, with the given
+// number of workers and a queue of queueSize tasks waiting for one. Values
+// below 1 are raised to 1.
+func New(name string, workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	return &Pool{
+		name:    name,
+		workers: workers,
+		tasks:   make(chan func(), queueSize),
+	}
+}
+
+// Submit queues task and reports whether it was accepted. It never blocks:
+// when the queue is full or the pool is shut down the task is dropped and
+// counted in metrics.WorkerPoolTasksShedTotal.
+func (p *Pool) Submit(task func()) bool {
+	p.start.Do(p.startWorkers)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		metrics.WorkerPoolTasksShedTotal.WithLabelValues(p.name, ShedShutdown).Inc()
+		return false
+	}
+	select {
+	case p.tasks <- task:
+		return true
+	default:
+		metrics.WorkerPoolTasksShedTotal.WithLabelValues(p.name, ShedQueueFull).Inc()
+		return false
+	}
+}
+
+// Shutdown stops accepting tasks and waits for the queued and running ones to
+// finish. It returns ctx.Err() if ctx ends first; the remaining tasks keep
+// running in the background.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	// Start the workers if no task ever did, so the WaitGroup is settled first
+	p.start.Do(p.startWorkers)
+
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) startWorkers() {
+	p.wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				p.run(task)
+			}
+		}()
+	}
+}
+
+// run executes task, so a panicking task does not take its worker down
+func (p *Pool) run(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Worker pool task panicked",
+				zap.String("pool", p.name),
+				zap.Any("panic", r))
+		}
+	}()
+	task()
+}
@@ -0,0 +1,89 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"secureconnect-backend/pkg/logger"
+	"secureconnect-backend/pkg/metrics"
+)
+
+func TestPool_ShedsWhenQueueFull(t *testing.T) {
+	pool := New("test-shed", 1, 2)
+	shed := metrics.WorkerPoolTasksShedTotal.WithLabelValues("test-shed", ShedQueueFull)
+	before := testutil.ToFloat64(shed)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	require.True(t, pool.Submit(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	// The only worker is busy, so two tasks fill the queue and the third is shed
+	var ran atomic.Int32
+	assert.True(t, pool.Submit(func() { ran.Add(1) }))
+	assert.True(t, pool.Submit(func() { ran.Add(1) }))
+	assert.False(t, pool.Submit(func() { ran.Add(1) }))
+	assert.Equal(t, before+1, testutil.ToFloat64(shed))
+
+	close(release)
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, int32(2), ran.Load(), "queued tasks still run")
+}
+
+func TestPool_ShutdownDrainsQueuedTasks(t *testing.T) {
+	pool := New("test-drain", 2, 16)
+
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		require.True(t, pool.Submit(func() {
+			time.Sleep(10 * time.Millisecond)
+			ran.Add(1)
+		}))
+	}
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, int32(10), ran.Load(), "Shutdown returns once every queued task ran")
+
+	shed := metrics.WorkerPoolTasksShedTotal.WithLabelValues("test-drain", ShedShutdown)
+	before := testutil.ToFloat64(shed)
+	assert.False(t, pool.Submit(func() {}), "no tasks are accepted after Shutdown")
+	assert.Equal(t, before+1, testutil.ToFloat64(shed))
+	assert.NoError(t, pool.Shutdown(context.Background()), "Shutdown can be called again")
+}
+
+func TestPool_ShutdownGivesUpAtDeadline(t *testing.T) {
+	pool := New("test-deadline", 1, 1)
+	release := make(chan struct{})
+	defer close(release)
+	require.True(t, pool.Submit(func() { <-release }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestPool_PanickingTaskKeepsWorker(t *testing.T) {
+	logger.InitDefault("test")
+	pool := New("test-panic", 1, 4)
+
+	var ran atomic.Bool
+	require.True(t, pool.Submit(func() { panic("boom") }))
+	require.True(t, pool.Submit(func() { ran.Store(true) }))
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.True(t, ran.Load())
+}
+
+func TestPool_ShutdownWithoutTasks(t *testing.T) {
+	pool := New("test-idle", 4, 4)
+	assert.NoError(t, pool.Shutdown(context.Background()))
+}
@@ -0,0 +1,38 @@
+-- SecureConnect Audit Events Migration
+-- Stores audit events queried by user or event type over a time range.
+-- Rows expire after 90 days, matching constants.AuditLogRetention.
+-- Version: 1.1
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    event_id UUID PRIMARY KEY,
+    user_id UUID, -- No foreign key: events outlive deleted accounts
+    event_type STRING NOT NULL,
+    resource STRING NOT NULL DEFAULT '',
+    action STRING NOT NULL DEFAULT '',
+    ip_address STRING NOT NULL DEFAULT '',
+    user_agent STRING NOT NULL DEFAULT '',
+    success BOOL NOT NULL,
+    error_code STRING NOT NULL DEFAULT '',
+    details STRING NOT NULL DEFAULT '',
+    geo JSONB,
+    timestamp TIMESTAMPTZ NOT NULL,
+    -- Hash chain fields, set only when AUDIT_HASH_CHAIN is enabled
+    sequence INT8 NOT NULL DEFAULT 0,
+    prev_hash STRING NOT NULL DEFAULT '',
+    hash STRING NOT NULL DEFAULT '',
+    signature STRING NOT NULL DEFAULT ''
+) WITH (ttl_expire_after = '90 days');
+
+-- Admin searches filter by user, type or address, or list every event in a
+-- time range. They page newest first by (timestamp, event_id), so each index
+-- ends with both columns in that order.
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_keyset ON audit_events (user_id, timestamp DESC, event_id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_type_keyset ON audit_events (event_type, timestamp DESC, event_id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_ip_keyset ON audit_events (ip_address, timestamp DESC, event_id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_keyset ON audit_events (timestamp DESC, event_id DESC) STORING (success);
+
+-- Superseded by the keyset indexes above (version 1.0)
+DROP INDEX IF EXISTS audit_events@idx_audit_events_user_time;
+DROP INDEX IF EXISTS audit_events@idx_audit_events_type_time;
+DROP INDEX IF EXISTS audit_events@idx_audit_events_ip_time;
+DROP INDEX IF EXISTS audit_events@idx_audit_events_time;
@@ -5,6 +5,7 @@
 -- ==========================================
 -- DROP EXISTING TABLES (for clean re-init)
 -- ==========================================
+DROP TABLE IF EXISTS poll_voters CASCADE;
 DROP TABLE IF EXISTS poll_votes CASCADE;
 DROP TABLE IF EXISTS poll_options CASCADE;
 DROP TABLE IF EXISTS polls CASCADE;
@@ -12,6 +13,8 @@ DROP TABLE IF EXISTS one_time_pre_keys CASCADE;
 DROP TABLE IF EXISTS signed_pre_keys CASCADE;
 DROP TABLE IF EXISTS identity_keys CASCADE;
 DROP TABLE IF EXISTS files CASCADE;
+DROP TABLE IF EXISTS message_search CASCADE;
+DROP TABLE IF EXISTS pinned_messages CASCADE;
 DROP TABLE IF EXISTS conversation_participants CASCADE;
 DROP TABLE IF EXISTS conversation_settings CASCADE;
 DROP TABLE IF EXISTS conversations CASCADE;
@@ -38,11 +41,23 @@ CREATE TABLE users (
     
     -- Indexes for search
     INDEX idx_users_email (email),
+    UNIQUE INDEX idx_users_email_lower (lower(email)),
     INDEX idx_users_username (username),
     INDEX idx_users_status (status),
     INDEX idx_users_created (created_at DESC)
 );
 
+-- Two-factor authentication (TOTP). confirmed_at is NULL until the user
+-- proves their authenticator works; only confirmed rows are enforced at login.
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    secret STRING NOT NULL,
+    recovery_code_hashes STRING[] NOT NULL DEFAULT ARRAY[],
+    last_used_step INT8 NOT NULL DEFAULT 0, -- Stops a code from being replayed within its window
+    created_at TIMESTAMPTZ DEFAULT now(),
+    confirmed_at TIMESTAMPTZ
+);
+
 -- ==========================================
 -- 2. E2EE KEYS TABLES (Signal Protocol)
 -- ==========================================
@@ -112,6 +127,8 @@ CREATE TABLE conversation_participants (
     user_id UUID REFERENCES users(user_id) ON DELETE CASCADE,
     role STRING DEFAULT 'member', -- admin, member
     joined_at TIMESTAMPTZ DEFAULT now(),
+    muted_until TIMESTAMPTZ, -- set by admins; the participant cannot post until then
+    calls_muted BOOLEAN NOT NULL DEFAULT FALSE, -- set by the participant; group calls do not ring them
     PRIMARY KEY (conversation_id, user_id),
     INDEX idx_participants_user (user_id),
     INDEX idx_participants_conv (conversation_id)
@@ -124,7 +141,36 @@ CREATE TABLE conversation_settings (
     ai_enabled BOOLEAN DEFAULT FALSE, -- Only when E2EE=false or Edge AI
     recording_enabled BOOLEAN DEFAULT FALSE,
     message_retention_days INT DEFAULT 30,
-    updated_at TIMESTAMPTZ DEFAULT now()
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    e2ee_disabled_by UUID REFERENCES users(user_id) ON DELETE SET NULL, -- Last admin who turned E2EE off
+    e2ee_disabled_at TIMESTAMPTZ,
+    search_indexing BOOLEAN -- NULL: index unless E2EE; E2EE conversations are never indexed
+);
+
+-- Message Search Index (plaintext messages only; E2EE conversations are never indexed)
+CREATE TABLE message_search (
+    conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    message_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    content STRING NOT NULL,
+    message_type STRING NOT NULL DEFAULT 'text',
+    sent_at TIMESTAMPTZ NOT NULL,
+    edited_at TIMESTAMPTZ,
+    seq INT8 NOT NULL DEFAULT 0,
+    content_tsv TSVECTOR AS (to_tsvector('simple', content)) STORED,
+    PRIMARY KEY (conversation_id, message_id),
+    INVERTED INDEX idx_message_search_content (content_tsv)
+);
+
+-- Pinned Messages (message IDs only; content stays in Cassandra)
+CREATE TABLE pinned_messages (
+    conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    message_id UUID NOT NULL,
+    pinned_by UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    pinned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    display_order INT NOT NULL DEFAULT 0, -- Ascending; new pins take min - 1
+    message_sent_at TIMESTAMPTZ, -- The message's Cassandra clustering key, for batch reads
+    PRIMARY KEY (conversation_id, message_id)
 );
 
 -- ==========================================
@@ -204,7 +250,8 @@ CREATE TABLE email_verification_tokens (
     used_at TIMESTAMPTZ,
     INDEX idx_email_verification_tokens_token (token),
     INDEX idx_email_verification_tokens_user_id (user_id),
-    INDEX idx_email_verification_tokens_expires_at (expires_at)
+    INDEX idx_email_verification_tokens_expires_at (expires_at),
+    INDEX idx_email_verification_tokens_used_at (used_at)
 );
 
 -- ==========================================
@@ -257,6 +304,14 @@ CREATE TABLE poll_votes (
     CONSTRAINT poll_votes_unique UNIQUE (poll_id, user_id, option_id)
 );
 
+-- Poll Voters (one row per user who voted; backstops concurrent first votes)
+CREATE TABLE poll_voters (
+    poll_id UUID NOT NULL REFERENCES polls(poll_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    voted_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (poll_id, user_id)
+);
+
 -- Function to update updated_at timestamp on polls table
 CREATE OR REPLACE FUNCTION update_polls_updated_at()
 RETURNS TRIGGER AS $$
@@ -0,0 +1,17 @@
+-- SecureConnect Conversation Bots Migration
+-- Webhook bots registered to a conversation. Bots receive new plaintext
+-- messages and post replies with a token; only its SHA-256 hash is stored.
+-- Version: 1.0
+
+CREATE TABLE IF NOT EXISTS conversation_bots (
+    bot_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    webhook_url STRING NOT NULL,
+    secret STRING NOT NULL,
+    token_hash STRING NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(user_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE INDEX idx_conversation_bots_token (token_hash),
+    INDEX idx_conversation_bots_conversation (conversation_id)
+);
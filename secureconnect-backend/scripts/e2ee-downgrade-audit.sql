@@ -0,0 +1,6 @@
+-- SecureConnect E2EE Downgrade Audit Migration
+-- Records which admin last disabled end-to-end encryption for a conversation and when.
+-- Version: 1.0
+
+ALTER TABLE conversation_settings ADD COLUMN IF NOT EXISTS e2ee_disabled_by UUID REFERENCES users(user_id) ON DELETE SET NULL;
+ALTER TABLE conversation_settings ADD COLUMN IF NOT EXISTS e2ee_disabled_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- SecureConnect Email Token Cleanup Migration
+-- Indexes used_at so the auth-service cleanup job can find used tokens without
+-- a full table scan (expires_at is already indexed).
+-- Version: 1.0
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_used_at
+ON email_verification_tokens (used_at);
@@ -46,7 +46,8 @@ CREATE TABLE IF NOT EXISTS email_verification_tokens (
     used_at TIMESTAMPTZ,
     INDEX idx_email_verification_tokens_token (token),
     INDEX idx_email_verification_tokens_user_id (user_id),
-    INDEX idx_email_verification_tokens_expires_at (expires_at)
+    INDEX idx_email_verification_tokens_expires_at (expires_at),
+    INDEX idx_email_verification_tokens_used_at (used_at)
 );
 
 -- ==========================================
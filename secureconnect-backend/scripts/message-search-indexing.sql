@@ -0,0 +1,6 @@
+-- SecureConnect Message Search Indexing Migration
+-- Lets a conversation opt in or out of server-side message search indexing.
+-- NULL keeps the default: indexed unless the conversation is end-to-end encrypted.
+-- Version: 1.0
+
+ALTER TABLE conversation_settings ADD COLUMN IF NOT EXISTS search_indexing BOOLEAN;
@@ -0,0 +1,21 @@
+-- SecureConnect Message Search Migration
+-- Server-side full-text search index over plaintext messages. Cassandra stays
+-- the message store; the chat service copies messages here as they are saved.
+-- E2EE conversations are never indexed: their content is ciphertext, and the
+-- search query skips them even before a settings change has been synced.
+-- Requires CockroachDB v23.1+ for full-text search.
+-- Version: 1.0
+
+CREATE TABLE IF NOT EXISTS message_search (
+    conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    message_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    content STRING NOT NULL,
+    message_type STRING NOT NULL DEFAULT 'text',
+    sent_at TIMESTAMPTZ NOT NULL,
+    edited_at TIMESTAMPTZ,
+    seq INT8 NOT NULL DEFAULT 0,
+    content_tsv TSVECTOR AS (to_tsvector('simple', content)) STORED,
+    PRIMARY KEY (conversation_id, message_id),
+    INVERTED INDEX idx_message_search_content (content_tsv)
+);
@@ -0,0 +1,5 @@
+-- SecureConnect Participant Call Mute Migration
+-- Lets participants stop group calls in a conversation from ringing them.
+-- Version: 1.0
+
+ALTER TABLE conversation_participants ADD COLUMN IF NOT EXISTS calls_muted BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- SecureConnect Participant Mute Migration
+-- Lets group admins mute a participant so they cannot post until muted_until.
+-- Version: 1.0
+
+ALTER TABLE conversation_participants ADD COLUMN IF NOT EXISTS muted_until TIMESTAMPTZ;
@@ -0,0 +1,42 @@
+-- SecureConnect Pinned Messages Migration
+-- Messages pinned in a conversation, with who pinned them and when. Messages
+-- stay in Cassandra; only their IDs are stored here. The chat service caps
+-- the number of pins per conversation (CHAT_MAX_PINNED_MESSAGES). Pins are
+-- listed by ascending display_order, which admins can change.
+-- Version: 1.2
+
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    message_id UUID NOT NULL,
+    pinned_by UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    pinned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    display_order INT NOT NULL DEFAULT 0, -- Ascending; new pins take min - 1
+    message_sent_at TIMESTAMPTZ, -- The message's Cassandra clustering key, for batch reads
+    PRIMARY KEY (conversation_id, message_id)
+);
+
+-- 1.1: pin ordering. Existing pins keep their newest-first order; conversations
+-- that already have an order are left alone, so this can be re-run.
+ALTER TABLE pinned_messages ADD COLUMN IF NOT EXISTS display_order INT NOT NULL DEFAULT 0;
+
+UPDATE pinned_messages p
+SET display_order = o.rank
+FROM (
+    SELECT conversation_id, message_id,
+        row_number() OVER (PARTITION BY conversation_id ORDER BY pinned_at DESC, message_id DESC) AS rank
+    FROM pinned_messages
+) o
+WHERE p.conversation_id = o.conversation_id AND p.message_id = o.message_id
+    AND NOT EXISTS (
+        SELECT 1 FROM pinned_messages q
+        WHERE q.conversation_id = p.conversation_id AND q.display_order <> 0
+    );
+
+-- 1.2: pins are removed with the user who pinned them, and record the
+-- message's sent_at so the pinned list is read from Cassandra in one query.
+-- Pins stored before this have no message_sent_at and are read one by one.
+ALTER TABLE pinned_messages ADD COLUMN IF NOT EXISTS message_sent_at TIMESTAMPTZ;
+
+ALTER TABLE pinned_messages DROP CONSTRAINT IF EXISTS pinned_messages_pinned_by_fkey;
+ALTER TABLE pinned_messages ADD CONSTRAINT pinned_messages_pinned_by_fkey
+    FOREIGN KEY (pinned_by) REFERENCES users(user_id) ON DELETE CASCADE;
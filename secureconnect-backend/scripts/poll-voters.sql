@@ -0,0 +1,18 @@
+-- SecureConnect Poll Voters Migration
+-- Records each user who voted in a poll. The first vote inserts the row, so
+-- two concurrent first votes from one user cannot both be cast.
+-- Version: 1.0
+
+CREATE TABLE IF NOT EXISTS poll_voters (
+    poll_id UUID NOT NULL REFERENCES polls(poll_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    voted_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (poll_id, user_id)
+);
+
+-- Backfill the users who already voted
+INSERT INTO poll_voters (poll_id, user_id, voted_at)
+SELECT poll_id, user_id, min(voted_at)
+FROM poll_votes
+GROUP BY poll_id, user_id
+ON CONFLICT (poll_id, user_id) DO NOTHING;
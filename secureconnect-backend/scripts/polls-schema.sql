@@ -66,6 +66,18 @@ CREATE TABLE IF NOT EXISTS poll_votes (
     CONSTRAINT poll_votes_unique UNIQUE (poll_id, user_id, option_id)
 );
 
+-- ==========================================
+-- 3b. POLL VOTERS TABLE
+-- ==========================================
+-- One row per user who voted, inserted with their first vote. Its primary
+-- key stops two concurrent first votes from the same user both being cast.
+CREATE TABLE IF NOT EXISTS poll_voters (
+    poll_id UUID NOT NULL REFERENCES polls(poll_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    voted_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (poll_id, user_id)
+);
+
 -- ==========================================
 -- 4. FUNCTIONS AND TRIGGERS
 -- ==========================================
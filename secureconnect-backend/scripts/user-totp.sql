@@ -0,0 +1,13 @@
+-- SecureConnect Two-Factor Authentication Migration
+-- Stores each user's TOTP secret and hashed recovery codes. A row with
+-- confirmed_at NULL is an enrollment the user has not confirmed yet.
+-- Version: 1.0
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    secret STRING NOT NULL,
+    recovery_code_hashes STRING[] NOT NULL DEFAULT ARRAY[],
+    last_used_step INT8 NOT NULL DEFAULT 0, -- Stops a code from being replayed within its window
+    created_at TIMESTAMPTZ DEFAULT now(),
+    confirmed_at TIMESTAMPTZ
+);
@@ -0,0 +1,48 @@
+-- SecureConnect Email Normalization Migration
+-- Lowercases stored emails and enforces case-insensitive uniqueness so that
+-- User@example.com and user@example.com cannot become separate accounts.
+-- Version: 1.0
+
+-- ==========================================
+-- 1. FIND CASE-VARIANT DUPLICATES
+-- ==========================================
+-- Resolve any rows returned here (merge or rename the accounts) before running step 2;
+-- the unique index cannot be built while duplicates exist.
+SELECT lower(email) AS normalized_email, count(*) AS accounts, array_agg(user_id) AS user_ids
+FROM users
+GROUP BY lower(email)
+HAVING count(*) > 1;
+
+-- ==========================================
+-- 2. NORMALIZE STORED EMAILS
+-- ==========================================
+UPDATE users SET email = lower(trim(email)), updated_at = now()
+WHERE email != lower(trim(email));
+
+-- ==========================================
+-- 3. CASE-INSENSITIVE UNIQUE INDEX
+-- ==========================================
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email));
+
+-- ==========================================
+-- 4. GMAIL ALIASES (only with NORMALIZE_GMAIL_ALIASES=true)
+-- ==========================================
+-- Applies sanitize.NormalizeEmail's Gmail folding to stored emails: dots and
+-- "+tag" suffixes are removed from the local part and googlemail.com becomes
+-- gmail.com. Skip this section when the setting is off.
+--
+-- Resolve any rows returned here before running the UPDATE below.
+SELECT replace(split_part(split_part(email, '@', 1), '+', 1), '.', '') || '@gmail.com' AS normalized_email,
+       count(*) AS accounts, array_agg(user_id) AS user_ids
+FROM users
+WHERE split_part(email, '@', 2) IN ('gmail.com', 'googlemail.com')
+GROUP BY 1
+HAVING count(*) > 1;
+
+UPDATE users
+SET email = replace(split_part(split_part(email, '@', 1), '+', 1), '.', '') || '@gmail.com', updated_at = now()
+WHERE split_part(email, '@', 2) IN ('gmail.com', 'googlemail.com')
+  AND email != replace(split_part(split_part(email, '@', 1), '+', 1), '.', '') || '@gmail.com';
+
+-- The Redis directory (directory:email:*) keys new registrations in this
+-- folded form as well; entries written before this update keep the old form.